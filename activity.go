@@ -0,0 +1,111 @@
+package zmultifield
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// MemberActivity is a member with the time of its last update.
+type MemberActivity struct {
+	Member     string
+	LastActive time.Time
+}
+
+// activityKey returns the key of the sorted set holding the time of every member's last update in
+// epoch milliseconds.
+func (mfs *MultiFieldSet) activityKey() string {
+	return mfs.derivedKey("activity")
+}
+
+// recordActivity sets the last activity of a member to now. Like history it is best effort:
+// failures are reported to the error hooks but don't fail the update.
+func (mfs *MultiFieldSet) recordActivity(ctx context.Context, member string) {
+	if !mfs.trackActivity {
+		return
+	}
+
+	err := mfs.write(ctx, func(client redis.UniversalClient) error {
+		return client.ZAdd(ctx, mfs.activityKey(), &redis.Z{Score: float64(time.Now().UnixMilli()), Member: member}).Err()
+	})
+	if err != nil {
+		mfs.runOnError(ctx, "RecordActivity", member, err)
+	}
+}
+
+// GetActiveMembers returns the members updated at or after since, most recently active first, e.g.
+// for an "active this week" view. It requires TrackActivity.
+func (mfs *MultiFieldSet) GetActiveMembers(ctx context.Context, since time.Time) (_ []MemberActivity, err error) {
+	defer mfs.observeRead("GetActiveMembers", time.Now(), &err)
+
+	min := strconv.FormatInt(since.UnixMilli(), 10)
+	members, err := mfs.activityRange(ctx, min, "+inf", true)
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "GetActiveMembers", "", err)
+	}
+	return members, nil
+}
+
+// GetIdleMembers returns the members last updated before since, least recently active first, e.g.
+// to target a re-engagement campaign. Members that haven't been updated since TrackActivity was
+// enabled are not included. It requires TrackActivity.
+func (mfs *MultiFieldSet) GetIdleMembers(ctx context.Context, since time.Time) (_ []MemberActivity, err error) {
+	defer mfs.observeRead("GetIdleMembers", time.Now(), &err)
+
+	max := "(" + strconv.FormatInt(since.UnixMilli(), 10)
+	members, err := mfs.activityRange(ctx, "-inf", max, false)
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "GetIdleMembers", "", err)
+	}
+	return members, nil
+}
+
+// activityRange reads the members whose last activity lies within [min, max] and drops those no
+// longer in the set, such as popped or evicted members, whose entries are left in the index.
+func (mfs *MultiFieldSet) activityRange(ctx context.Context, min, max string, newestFirst bool) ([]MemberActivity, error) {
+	if !mfs.trackActivity {
+		return nil, ErrActivityDisabled
+	}
+
+	var entries []redis.Z
+	var cmds []*redis.FloatCmd
+	err := mfs.read(ctx, func(client redis.UniversalClient) error {
+		var err error
+		rangeBy := &redis.ZRangeBy{Min: min, Max: max}
+		if newestFirst {
+			entries, err = client.ZRevRangeByScoreWithScores(ctx, mfs.activityKey(), rangeBy).Result()
+		} else {
+			entries, err = client.ZRangeByScoreWithScores(ctx, mfs.activityKey(), rangeBy).Result()
+		}
+		if err != nil || len(entries) == 0 {
+			return err
+		}
+		cmds = make([]*redis.FloatCmd, len(entries))
+		_, err = client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, z := range entries {
+				cmds[i] = pipe.ZScore(ctx, mfs.key, memberName(z))
+			}
+			return nil
+		})
+		if err == redis.Nil {
+			err = nil
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]MemberActivity, 0, len(entries))
+	for i, z := range entries {
+		if err := cmds[i].Err(); err == redis.Nil {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		members = append(members, MemberActivity{Member: memberName(z), LastActive: time.UnixMilli(int64(z.Score))})
+	}
+	return members, nil
+}
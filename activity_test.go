@@ -0,0 +1,77 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+func TestActivity(t *testing.T) {
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{TrackActivity: true})
+	ctx := context.Background()
+
+	for _, member := range []string{"alice", "bob", "carol", "dave"} {
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 10}, member); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+	}
+	// bob was last active ten days ago and carol a month ago
+	now := time.Now()
+	err := mfs.client.ZAdd(ctx, mfs.activityKey(),
+		&redis.Z{Score: float64(now.AddDate(0, 0, -10).UnixMilli()), Member: "bob"},
+		&redis.Z{Score: float64(now.AddDate(0, -1, 0).UnixMilli()), Member: "carol"},
+	).Err()
+	if err != nil {
+		t.Fatalf("ZAdd() error = %v", err)
+	}
+	// dave leaves the set without going through RemoveMember
+	if _, err := mfs.client.ZRem(ctx, mfs.GetKey(), "dave").Result(); err != nil {
+		t.Fatalf("ZRem() error = %v", err)
+	}
+
+	weekAgo := now.AddDate(0, 0, -7)
+	active, err := mfs.GetActiveMembers(ctx, weekAgo)
+	if err != nil {
+		t.Fatalf("GetActiveMembers() error = %v", err)
+	}
+	if len(active) != 1 || active[0].Member != "alice" || active[0].LastActive.Before(weekAgo) {
+		t.Errorf("GetActiveMembers() = %+v, expected only alice", active)
+	}
+
+	idle, err := mfs.GetIdleMembers(ctx, weekAgo)
+	if err != nil {
+		t.Fatalf("GetIdleMembers() error = %v", err)
+	}
+	if len(idle) != 2 || idle[0].Member != "carol" || idle[1].Member != "bob" {
+		t.Errorf("GetIdleMembers() = %+v, expected carol then bob", idle)
+	}
+
+	// An update makes a member active again
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 1}, "bob"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	if active, err := mfs.GetActiveMembers(ctx, weekAgo); err != nil || len(active) != 2 || active[0].Member != "bob" {
+		t.Errorf("GetActiveMembers() = %+v, %v, expected bob first", active, err)
+	}
+
+	if _, err := mfs.RemoveMember(ctx, "bob"); err != nil {
+		t.Fatalf("RemoveMember() error = %v", err)
+	}
+	if _, err := mfs.client.ZScore(ctx, mfs.activityKey(), "bob").Result(); err != redis.Nil {
+		t.Errorf("ZScore() of a removed member error = %v, expected redis.Nil", err)
+	}
+}
+
+func TestActivity_Disabled(t *testing.T) {
+	mfs := newTestSet(t)
+	ctx := context.Background()
+	if _, err := mfs.GetActiveMembers(ctx, time.Now()); !errors.Is(err, ErrActivityDisabled) {
+		t.Errorf("GetActiveMembers() error = %v, expected ErrActivityDisabled", err)
+	}
+	if _, err := mfs.GetIdleMembers(ctx, time.Now()); !errors.Is(err, ErrActivityDisabled) {
+		t.Errorf("GetIdleMembers() error = %v, expected ErrActivityDisabled", err)
+	}
+}
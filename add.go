@@ -0,0 +1,47 @@
+package zmultifield
+
+import (
+	"context"
+	"math/big"
+	"time"
+)
+
+// AddMember writes a member with the given display values, adding it or overwriting its current
+// scores. Unlike IncreaseScore, values are taken as they are for every field regardless of its
+// UpdateType, and fields that aren't listed get their default score. It returns the new zscore.
+func (mfs *MultiFieldSet) AddMember(ctx context.Context, member string, values map[string]float64) (_ *big.Int, err error) {
+	defer mfs.observeUpdate("AddMember", time.Now(), &err)
+
+	result, err := mfs.addMember(ctx, member, values)
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "AddMember", member, err)
+	}
+	return result.ZScore, nil
+}
+
+// addMember encodes values and commits them like an update.
+func (mfs *MultiFieldSet) addMember(ctx context.Context, member string, values map[string]float64) (*UpdateResult, error) {
+	display := make([]FieldScore, 0, len(values))
+	for name, value := range values {
+		v, err := toInt64(name, value)
+		if err != nil {
+			return nil, err
+		}
+		display = append(display, FieldScore{Name: name, Score: big.NewInt(v)})
+	}
+	scores, err := mfs.displayScoresToRaw(display)
+	if err != nil {
+		return nil, err
+	}
+	mfs.stampUpdatedAt(scores)
+	mfs.stampSalt(scores, member)
+
+	zscore := mfs.scoresToZScore(scores)
+	event := &UpdateEvent{
+		Set:       mfs.name,
+		Member:    member,
+		Deltas:    values,
+		NewScores: mfs.zscoreToAllFieldScores(zscore),
+	}
+	return mfs.commitUpdate(ctx, event, scores, zscore, writeAlways, false)
+}
@@ -0,0 +1,58 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestAddMember(t *testing.T) {
+	client, _ := newTestClient(t)
+	mfs, err := New(MultiFieldSetOptions{
+		Name: "test",
+		Fields: []Field{
+			{Name: "points", Sort: Descending, MaxValue: 1000, UpdateType: Incremental},
+			{Name: "level", Sort: Descending, MaxValue: 50, UpdateType: Replace},
+			{Name: "deaths", Sort: Ascending, MaxValue: 100, UpdateType: Incremental},
+		},
+		Client:               client,
+		MaintainFieldIndexes: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create MultiFieldSet: %v", err)
+	}
+	ctx := context.Background()
+
+	var after *UpdateEvent
+	mfs.AfterUpdate(func(ctx context.Context, event *UpdateEvent) {
+		after = event
+	})
+
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 500, "deaths": 7}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	if _, err := mfs.AddMember(ctx, "alice", map[string]float64{"points": 40, "level": 12}); err != nil {
+		t.Fatalf("AddMember() error = %v", err)
+	}
+
+	scores, err := mfs.GetScores(ctx, "alice")
+	if err != nil {
+		t.Fatalf("GetScores() error = %v", err)
+	}
+	if scores[0].Int64() != 40 || scores[1].Int64() != 12 || scores[2].Int64() != 0 {
+		t.Errorf("GetScores() = %+v, expected points 40, level 12 and deaths reset to 0", scores)
+	}
+	if after == nil || after.Member != "alice" || after.NewScores[0].Int64() != 40 {
+		t.Errorf("AfterUpdate event = %+v, expected alice with 40 points", after)
+	}
+	if rank, err := mfs.GetFieldRank(ctx, "level", "alice"); err != nil || rank != 0 {
+		t.Errorf("GetFieldRank(level) = %d, %v, expected 0", rank, err)
+	}
+
+	if _, err := mfs.AddMember(ctx, "bob", map[string]float64{"points": 5000}); !errors.Is(err, ErrScoreOutOfRange) {
+		t.Errorf("AddMember() out of range error = %v, expected %v", err, ErrScoreOutOfRange)
+	}
+	if _, err := mfs.AddMember(ctx, "bob", map[string]float64{"kills": 1}); !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("AddMember() unknown field error = %v, expected %v", err, ErrFieldNotFound)
+	}
+}
@@ -0,0 +1,145 @@
+package zmultifield
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// AggregateOptions configures Aggregate.
+type AggregateOptions struct {
+	// Filter restricts the members aggregated. The zero Filter aggregates every visible member.
+	Filter Filter
+	// ReadOptions apply to the walk, e.g. InDimension to aggregate a single dimension.
+	ReadOptions []ReadOption
+	// BatchSize is the number of members read per round trip and mapped together. Defaults to
+	// scanBatchSize.
+	BatchSize int64
+	// Parallelism is the number of batches mapped concurrently. Defaults to 1, mapping batches on
+	// a single goroutine while the next one is read.
+	Parallelism int
+}
+
+// aggregateBatch is a batch of members handed to a mapping worker.
+type aggregateBatch[T any] struct {
+	members []MemberScores
+	values  []T
+	err     error
+	done    chan struct{}
+}
+
+// Aggregate walks the members of mfs in leaderboard order, in batches, and folds them into a single
+// result: mapFn is applied to each member, possibly concurrently, and reduceFn combines the mapped
+// values one at a time, starting from the zero value of R, on the calling goroutine and in
+// leaderboard order, so it needs no locking. It is meant for reports over the whole set, such as
+// weekly summaries, without exporting it first.
+//
+// The walk is not a snapshot: a member updated while the set is walked may be seen twice or not at
+// all. The first error returned by mapFn stops the walk and is returned.
+func Aggregate[T, R any](ctx context.Context, mfs *MultiFieldSet, mapFn func(member MemberScores) (T, error), reduceFn func(acc R, value T) R, opts AggregateOptions) (result R, err error) {
+	defer mfs.observeRead("Aggregate", time.Now(), &err)
+
+	o := newReadOptions(opts.ReadOptions)
+	ctx = o.context(ctx)
+	key, c, err := mfs.resolveFilter(opts.Filter, o)
+	if err != nil {
+		return result, mfs.runOnError(ctx, "Aggregate", "", err)
+	}
+	if c.empty {
+		return result, nil
+	}
+	size := opts.BatchSize
+	if size <= 0 {
+		size = scanBatchSize
+	}
+	workers := opts.Parallelism
+	if workers < 1 {
+		workers = 1
+	}
+
+	walkCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Batches are handed to the workers and, in the order they were read, to the reducer
+	jobs := make(chan *aggregateBatch[T])
+	ordered := make(chan *aggregateBatch[T], workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for b := range jobs {
+				b.values = make([]T, 0, len(b.members))
+				for _, m := range b.members {
+					value, err := mapFn(m)
+					if err != nil {
+						b.err = err
+						break
+					}
+					b.values = append(b.values, value)
+				}
+				close(b.done)
+			}
+		}()
+	}
+
+	var walkErr error
+	go func() {
+		defer close(ordered)
+		defer close(jobs)
+		var members []MemberScores
+		send := func() bool {
+			b := &aggregateBatch[T]{members: members, done: make(chan struct{})}
+			members = nil
+			select {
+			case jobs <- b:
+			case <-walkCtx.Done():
+				return false
+			}
+			select {
+			case ordered <- b:
+				return true
+			case <-walkCtx.Done():
+				return false
+			}
+		}
+		walkErr = mfs.walkFiltered(walkCtx, key, c, size, func(z redis.Z) bool {
+			members = append(members, mfs.decodeEntry(z))
+			return int64(len(members)) < size || send()
+		})
+		if walkErr == nil && len(members) > 0 {
+			send()
+		}
+	}()
+
+	var mapErr error
+	for b := range ordered {
+		<-b.done
+		if b.err != nil {
+			mapErr = b.err
+			cancel()
+			break
+		}
+		for _, value := range b.values {
+			result = reduceFn(result, value)
+		}
+	}
+	// Wait for the walk and the workers to stop, so mapFn is never called after returning
+	for range ordered {
+	}
+	wg.Wait()
+
+	if mapErr != nil {
+		return result, mfs.runOnError(ctx, "Aggregate", "", mapErr)
+	}
+	if walkErr == nil {
+		// A walk stopped by the caller's context may have ended without an error
+		walkErr = ctx.Err()
+	}
+	if walkErr != nil {
+		return result, mfs.runOnError(ctx, "Aggregate", "", walkErr)
+	}
+	return result, nil
+}
@@ -0,0 +1,67 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestAggregate(t *testing.T) {
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{HideMembers: true})
+	ctx := context.Background()
+
+	var total int64
+	for i := 0; i < 25; i++ {
+		points := float64(i * 10)
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": points, "deaths": float64(i % 3)}, fmt.Sprintf("player%d", i)); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+		total += int64(points)
+	}
+	if err := mfs.Hide(ctx, "player24"); err != nil {
+		t.Fatalf("Hide() error = %v", err)
+	}
+	total -= 240
+
+	points := func(m MemberScores) (int64, error) {
+		return m.Scores[0].Score.Int64(), nil
+	}
+	sum := func(acc, value int64) int64 { return acc + value }
+
+	for _, parallelism := range []int{1, 4} {
+		got, err := Aggregate(ctx, mfs, points, sum, AggregateOptions{BatchSize: 4, Parallelism: parallelism})
+		if err != nil {
+			t.Fatalf("Aggregate() error = %v", err)
+		}
+		if got != total {
+			t.Errorf("Aggregate() with parallelism %d = %d, expected %d", parallelism, got, total)
+		}
+	}
+
+	// Values are reduced in leaderboard order
+	names := func(m MemberScores) (string, error) { return m.Member, nil }
+	first := func(acc, value string) string {
+		if acc == "" {
+			return value
+		}
+		return acc
+	}
+	top, err := Aggregate(ctx, mfs, names, first, AggregateOptions{BatchSize: 3, Parallelism: 3, Filter: F("deaths").EQ(1)})
+	if err != nil {
+		t.Fatalf("Aggregate() error = %v", err)
+	}
+	if top != "player22" {
+		t.Errorf("Aggregate() = %q, expected player22", top)
+	}
+
+	errStop := errors.New("stop")
+	failing := func(m MemberScores) (int64, error) { return 0, errStop }
+	if _, err := Aggregate(ctx, mfs, failing, sum, AggregateOptions{BatchSize: 2}); !errors.Is(err, errStop) {
+		t.Errorf("Aggregate() error = %v, expected the map error", err)
+	}
+
+	if _, err := Aggregate(ctx, mfs, points, sum, AggregateOptions{Filter: F("missing").EQ(1)}); !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("Aggregate() error = %v, expected ErrFieldNotFound", err)
+	}
+}
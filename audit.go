@@ -0,0 +1,353 @@
+package zmultifield
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// auditBatchSize is the number of audit entries read at a time, and applied per transaction by
+// Replay.
+const auditBatchSize = 1000
+
+// auditLua is the prelude of the audited write script variants. It takes the main set and the
+// audit stream from the last two keys and shadows redis.call, so every write the script makes to
+// the main set appends an entry to the stream: "set" with the member's new zscore, "del" for a
+// removed member or "clear" when the main set is deleted. Commands are replicated as effects, so
+// replicas apply the stream IDs generated by the primary.
+const auditLua = `local main, stream = KEYS[n - 1], KEYS[n]
+n = n - 2
+if redis.replicate_commands then
+	redis.replicate_commands()
+end
+local call = redis.call
+local function record(...)
+	return call('XADD', stream, '*', ...)
+end
+local function recordScore(member)
+	local score = call('ZSCORE', main, member)
+	if score then
+		record('op', 'set', 'member', member, 'score', score)
+	else
+		record('op', 'del', 'member', member)
+	end
+end
+local zaddFlags = {NX = true, XX = true, GT = true, LT = true, CH = true, INCR = true}
+local function audited(...)
+	local args = {...}
+	local command = string.upper(args[1])
+	if command == 'DEL' or command == 'UNLINK' then
+		for i = 2, #args do
+			if args[i] == main then
+				local reply = call(...)
+				record('op', 'clear')
+				return reply
+			end
+		end
+	end
+	if args[2] ~= main then
+		return call(...)
+	end
+	if command == 'ZADD' then
+		local first = 3
+		while args[first] and zaddFlags[string.upper(args[first])] do
+			first = first + 1
+		end
+		local reply = call(...)
+		for i = first + 1, #args, 2 do
+			recordScore(args[i])
+		end
+		return reply
+	elseif command == 'ZINCRBY' then
+		local reply = call(...)
+		recordScore(args[4])
+		return reply
+	end
+
+	local removed = {}
+	if command == 'ZREM' then
+		for i = 3, #args do
+			if call('ZSCORE', main, args[i]) then
+				removed[#removed + 1] = args[i]
+			end
+		end
+	elseif command == 'ZREMRANGEBYRANK' then
+		removed = call('ZRANGE', main, args[3], args[4])
+	elseif command == 'ZREMRANGEBYSCORE' then
+		removed = call('ZRANGEBYSCORE', main, args[3], args[4])
+	end
+	local reply = call(...)
+	if command == 'ZPOPMIN' or command == 'ZPOPMAX' then
+		for i = 1, #reply, 2 do
+			removed[#removed + 1] = reply[i]
+		end
+	end
+	for _, member in ipairs(removed) do
+		record('op', 'del', 'member', member)
+	end
+	return reply
+end
+local redis = setmetatable({call = audited}, {__index = redis})
+`
+
+// trimScript removes the members ranked at ARGV[1] and below from the main set, KEYS[1]. Audited
+// optimistic updates enforce MaxMembers with it, as the evicted members are only known once their
+// transaction runs.
+var trimScript = newWriteScript(`
+return redis.call('ZREMRANGEBYRANK', KEYS[1], ARGV[1], -1)
+`)
+
+// StreamID is the ID of an entry in a Redis stream, such as the audit stream of a set.
+type StreamID string
+
+// auditKey returns the key of the stream recording the writes to the main set of an Audit set.
+func (mfs *MultiFieldSet) auditKey() string {
+	return mfs.derivedKey("audit")
+}
+
+// auditGuard returns the scriptGuard recording the writes of scripts in the audit stream if the set
+// is Audit, without checking the frozen flag.
+func (mfs *MultiFieldSet) auditGuard() scriptGuard {
+	if !mfs.audit {
+		return scriptGuard{}
+	}
+	return scriptGuard{main: mfs.key, audit: mfs.auditKey()}
+}
+
+// recordAudit queues the append of an audit entry with the given field/value pairs on pipe, if the
+// set is Audit.
+func (mfs *MultiFieldSet) recordAudit(ctx context.Context, pipe redis.Pipeliner, values ...interface{}) {
+	if mfs.audit {
+		pipe.XAdd(ctx, &redis.XAddArgs{Stream: mfs.auditKey(), Values: values})
+	}
+}
+
+// recordSet queues the audit entry of a member written with the given zscore.
+func (mfs *MultiFieldSet) recordSet(ctx context.Context, pipe redis.Pipeliner, member string, zscore float64) {
+	mfs.recordAudit(ctx, pipe, "op", "set", "member", member, "score", strconv.FormatFloat(zscore, 'f', -1, 64))
+}
+
+// recordDel queues the audit entries of removed members.
+func (mfs *MultiFieldSet) recordDel(ctx context.Context, pipe redis.Pipeliner, members ...string) {
+	for _, member := range members {
+		mfs.recordAudit(ctx, pipe, "op", "del", "member", member)
+	}
+}
+
+// auditOp is an entry of the audit stream decoded by Replay.
+type auditOp struct {
+	op     string
+	member string
+	zscore float64
+}
+
+// Replay applies the entries of the set's audit stream recorded after from to the set, e.g. to
+// recover a corrupted set, and returns the ID of the last entry applied, from which a later Replay
+// catches up. If from is empty the set is rebuilt: its members are deleted, along with the field
+// indexes, and the whole stream is replayed. The set must be Audit.
+//
+// Entries are applied in transactions of up to 1000, so readers may see a partly replayed set, and
+// aren't recorded in the stream again. Only the scores are replayed: dimension sets, metadata and
+// histories are left as they are. Rebuild, Restore and Copy aren't recorded in the stream, so
+// replaying entries from before them doesn't reproduce them. If an error stops the replay, the
+// returned ID is that of the last entry applied, so it can be resumed from there.
+func (mfs *MultiFieldSet) Replay(ctx context.Context, from StreamID) (StreamID, error) {
+	last, err := mfs.replay(ctx, mfs, from)
+	if err != nil {
+		return last, mfs.runOnError(ctx, "Replay", "", err)
+	}
+	return last, nil
+}
+
+// ReplayFrom applies the entries of the audit stream of source recorded after from to the set, like
+// Replay does with the set's own stream, e.g. to build a new set from the history of another. If
+// from is empty the set is emptied first. source must be Audit and compatible with the set: same
+// field layout and, for salted sets, the same salt secret, or ReplayFrom fails with
+// ErrIncompatibleSets. The entries applied aren't recorded in the set's own audit stream.
+func (mfs *MultiFieldSet) ReplayFrom(ctx context.Context, source *MultiFieldSet, from StreamID) (StreamID, error) {
+	if !mfs.compatibleWith(source) || (mfs.salt != nil && !bytes.Equal(mfs.saltSecret, source.saltSecret)) {
+		return from, mfs.runOnError(ctx, "ReplayFrom", "", fmt.Errorf("%w: %s", ErrIncompatibleSets, source.name))
+	}
+	last, err := mfs.replay(ctx, source, from)
+	if err != nil {
+		return last, mfs.runOnError(ctx, "ReplayFrom", "", err)
+	}
+	return last, nil
+}
+
+// replay implements Replay and ReplayFrom.
+func (mfs *MultiFieldSet) replay(ctx context.Context, source *MultiFieldSet, from StreamID) (StreamID, error) {
+	if !source.audit {
+		return from, ErrAuditDisabled
+	}
+	start, rebuild := "-", from == ""
+	if !rebuild {
+		var err error
+		if start, err = nextStreamID(from); err != nil {
+			return from, err
+		}
+	}
+
+	last := from
+	for {
+		var entries []redis.XMessage
+		err := source.primary(ctx, func(client redis.UniversalClient) error {
+			var err error
+			entries, err = client.XRangeN(ctx, source.auditKey(), start, "+", auditBatchSize).Result()
+			return err
+		})
+		if err != nil {
+			return last, err
+		}
+		if len(entries) == 0 && !rebuild {
+			return last, nil
+		}
+		if err := mfs.applyAudit(ctx, entries, rebuild); err != nil {
+			return last, err
+		}
+		rebuild = false
+		if len(entries) == 0 {
+			return last, nil
+		}
+		last = StreamID(entries[len(entries)-1].ID)
+		if len(entries) < auditBatchSize {
+			return last, nil
+		}
+		if start, err = nextStreamID(last); err != nil {
+			return last, err
+		}
+	}
+}
+
+// applyAudit applies audit entries to the set in one transaction, after deleting its members and
+// field indexes if clear is set.
+func (mfs *MultiFieldSet) applyAudit(ctx context.Context, entries []redis.XMessage, clear bool) error {
+	ops := make([]auditOp, len(entries))
+	for i, entry := range entries {
+		op, err := parseAuditEntry(entry)
+		if err != nil {
+			return err
+		}
+		ops[i] = op
+	}
+	keys := []string{mfs.key}
+	if mfs.maintainFieldIndexes {
+		for _, field := range mfs.fields {
+			keys = append(keys, mfs.fieldIndexKey(field))
+		}
+	}
+
+	return mfs.write(ctx, func(client redis.UniversalClient) error {
+		return mfs.txPipelined(ctx, client, func(pipe redis.Pipeliner) error {
+			if clear {
+				pipe.Del(ctx, keys...)
+			}
+			for _, op := range ops {
+				switch op.op {
+				case "clear":
+					pipe.Del(ctx, keys...)
+				case "del":
+					for _, key := range keys {
+						pipe.ZRem(ctx, key, op.member)
+					}
+				case "set":
+					zscore, err := mfs.zscoreOf(op.member, op.zscore)
+					if err != nil {
+						return err
+					}
+					pipe.ZAdd(ctx, mfs.key, &redis.Z{Score: op.zscore, Member: op.member})
+					if mfs.maintainFieldIndexes {
+						for _, field := range mfs.fields {
+							raw := mfs.extractFieldScore(field, zscore)
+							pipe.ZAdd(ctx, mfs.fieldIndexKey(field), &redis.Z{Score: float64(raw.Int64()), Member: op.member})
+						}
+					}
+				}
+			}
+			return nil
+		})
+	})
+}
+
+// parseAuditEntry decodes an entry of the audit stream.
+func parseAuditEntry(entry redis.XMessage) (auditOp, error) {
+	op, _ := entry.Values["op"].(string)
+	member, _ := entry.Values["member"].(string)
+	switch op {
+	case "clear":
+		return auditOp{op: op}, nil
+	case "del":
+		return auditOp{op: op, member: member}, nil
+	case "set":
+		score, _ := entry.Values["score"].(string)
+		zscore, err := strconv.ParseFloat(score, 64)
+		if err != nil {
+			return auditOp{}, fmt.Errorf("audit entry %s has invalid score %q", entry.ID, score)
+		}
+		return auditOp{op: op, member: member, zscore: zscore}, nil
+	}
+	return auditOp{}, fmt.Errorf("audit entry %s has unknown op %q", entry.ID, op)
+}
+
+// nextStreamID returns the smallest stream ID after id, so XRANGE can start after an entry on
+// servers without exclusive ranges.
+func nextStreamID(id StreamID) (string, error) {
+	ms, seq, found := strings.Cut(string(id), "-")
+	millis, err := strconv.ParseUint(ms, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid stream ID %q", id)
+	}
+	if !found {
+		seq = "0"
+	}
+	n, err := strconv.ParseUint(seq, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid stream ID %q", id)
+	}
+	if n == math.MaxUint64 {
+		return strconv.FormatUint(millis+1, 10) + "-0", nil
+	}
+	return strconv.FormatUint(millis, 10) + "-" + strconv.FormatUint(n+1, 10), nil
+}
+
+// eraseFromAudit deletes every entry of the audit stream naming member and reports whether there
+// were any, so EraseMember leaves no trace of the member in the stream.
+func (mfs *MultiFieldSet) eraseFromAudit(ctx context.Context, member string) (bool, error) {
+	var erased bool
+	for start := "-"; ; {
+		var entries []redis.XMessage
+		err := mfs.primary(ctx, func(client redis.UniversalClient) error {
+			var err error
+			entries, err = client.XRangeN(ctx, mfs.auditKey(), start, "+", auditBatchSize).Result()
+			return err
+		})
+		if err != nil {
+			return erased, err
+		}
+		var ids []string
+		for _, entry := range entries {
+			if entry.Values["member"] == member {
+				ids = append(ids, entry.ID)
+			}
+		}
+		if len(ids) > 0 {
+			if err := mfs.write(ctx, func(client redis.UniversalClient) error {
+				return client.XDel(ctx, mfs.auditKey(), ids...).Err()
+			}); err != nil {
+				return erased, err
+			}
+			erased = true
+		}
+		if len(entries) < auditBatchSize {
+			return erased, nil
+		}
+		if start, err = nextStreamID(StreamID(entries[len(entries)-1].ID)); err != nil {
+			return erased, err
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// auditedMembers returns every member of mfs with its zscore, failing the test on error.
+func auditedMembers(t *testing.T, mfs *MultiFieldSet) []redis.Z {
+	t.Helper()
+	members, err := mfs.client.ZRangeWithScores(context.Background(), mfs.key, 0, -1).Result()
+	if err != nil {
+		t.Fatalf("ZRangeWithScores() error = %v", err)
+	}
+	return members
+}
+
+// auditedIndex returns every member of the index of field with its raw value.
+func auditedIndex(t *testing.T, mfs *MultiFieldSet, field string) []redis.Z {
+	t.Helper()
+	members, err := mfs.client.ZRangeWithScores(context.Background(), mfs.fieldIndexKey(mfs.GetFieldByName(field)), 0, -1).Result()
+	if err != nil {
+		t.Fatalf("ZRangeWithScores() error = %v", err)
+	}
+	return members
+}
+
+func TestReplay(t *testing.T) {
+	for _, strategy := range []UpdateStrategy{UpdateScripted, UpdateOptimistic} {
+		mfs := newTestSetWithOptions(t, MultiFieldSetOptions{
+			Audit:                true,
+			MaintainFieldIndexes: true,
+			Quarantinable:        true,
+			MaxMembers:           4,
+			UpdateStrategy:       strategy,
+		})
+		ctx := context.Background()
+
+		for member, points := range map[string]float64{"alice": 50, "bob": 40, "carol": 30, "dave": 20, "erin": 10} {
+			if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": points, "deaths": 1}, member); err != nil {
+				t.Fatalf("IncreaseScore() error = %v", err)
+			}
+		}
+		if _, _, err := mfs.TransferScore(ctx, "alice", "bob", map[string]float64{"points": 5}); err != nil {
+			t.Fatalf("TransferScore() error = %v", err)
+		}
+		if _, err := mfs.PopBottomMember(ctx); err != nil {
+			t.Fatalf("PopBottomMember() error = %v", err)
+		}
+		if _, err := mfs.RemoveMember(ctx, "carol"); err != nil {
+			t.Fatalf("RemoveMember() error = %v", err)
+		}
+		if err := mfs.Quarantine(ctx, "bob", "cheating"); err != nil {
+			t.Fatalf("Quarantine() error = %v", err)
+		}
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 7}, "frank"); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+		want, index := auditedMembers(t, mfs), auditedIndex(t, mfs, "points")
+		if len(want) != 2 {
+			t.Fatalf("%v: members = %v, expected alice and frank", strategy, want)
+		}
+
+		// Corrupt the set, then rebuild it from the whole stream
+		mfs.client.ZAdd(ctx, mfs.key, &redis.Z{Score: 1, Member: "mallory"})
+		mfs.client.ZRem(ctx, mfs.key, "alice")
+		last, err := mfs.Replay(ctx, "")
+		if err != nil {
+			t.Fatalf("%v: Replay() error = %v", strategy, err)
+		}
+		if got := auditedMembers(t, mfs); !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: members after Replay() = %v, expected %v", strategy, got, want)
+		}
+		if got := auditedIndex(t, mfs, "points"); !reflect.DeepEqual(got, index) {
+			t.Errorf("%v: points index after Replay() = %v, expected %v", strategy, got, index)
+		}
+
+		// Catching up from the last entry applies only the writes made since
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 1}, "alice"); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+		want = auditedMembers(t, mfs)
+		mfs.client.ZAdd(ctx, mfs.key, &redis.Z{Score: 1, Member: "alice"})
+		next, err := mfs.Replay(ctx, last)
+		if err != nil {
+			t.Fatalf("%v: Replay() error = %v", strategy, err)
+		}
+		if next == last {
+			t.Errorf("%v: Replay() = %v, expected a later entry", strategy, next)
+		}
+		if got := auditedMembers(t, mfs); !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: members after catching up = %v, expected %v", strategy, got, want)
+		}
+		if again, err := mfs.Replay(ctx, next); err != nil || again != next {
+			t.Errorf("%v: Replay() with nothing new = %v, %v, expected %v", strategy, again, err, next)
+		}
+	}
+}
+
+func TestReplay_ClearAndErase(t *testing.T) {
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{Audit: true})
+	ctx := context.Background()
+
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 10}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	if err := mfs.Clear(ctx); err != nil {
+		t.Fatalf("Clear() error = %v", err)
+	}
+	for _, member := range []string{"bob", "carol"} {
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 20}, member); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+	}
+
+	report, err := mfs.EraseMember(ctx, "bob")
+	if err != nil {
+		t.Fatalf("EraseMember() error = %v", err)
+	}
+	found := false
+	for _, key := range report.RemovedFrom {
+		found = found || key == mfs.auditKey()
+	}
+	if !found {
+		t.Errorf("EraseMember() removed from %v, expected the audit stream", report.RemovedFrom)
+	}
+	entries, err := mfs.client.XRange(ctx, mfs.auditKey(), "-", "+").Result()
+	if err != nil {
+		t.Fatalf("XRange() error = %v", err)
+	}
+	for _, entry := range entries {
+		if entry.Values["member"] == "bob" {
+			t.Errorf("audit entry %v names an erased member", entry)
+		}
+	}
+
+	if _, err := mfs.Replay(ctx, ""); err != nil {
+		t.Fatalf("Replay() error = %v", err)
+	}
+	got := auditedMembers(t, mfs)
+	if len(got) != 1 || got[0].Member != "carol" {
+		t.Errorf("members after Replay() = %v, expected only carol", got)
+	}
+}
+
+func TestReplayFrom(t *testing.T) {
+	source := newTestSetWithOptions(t, MultiFieldSetOptions{Audit: true})
+	ctx := context.Background()
+	dest, err := New(MultiFieldSetOptions{
+		Name: "rebuilt",
+		Fields: []Field{
+			{Name: "points", Sort: Descending, MaxValue: 1000, UpdateType: Incremental},
+			{Name: "deaths", Sort: Ascending, MaxValue: 100, UpdateType: Incremental},
+		},
+		Client:               source.client,
+		MaintainFieldIndexes: true,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	members := []MemberScores{
+		{Member: "alice", Scores: []FieldScore{{Name: "points", Value: 10}}},
+		{Member: "bob", Scores: []FieldScore{{Name: "points", Value: 20}}},
+	}
+	if _, err := source.BulkLoad(ctx, SliceIterator(members), 10, 1, nil); err != nil {
+		t.Fatalf("BulkLoad() error = %v", err)
+	}
+	last, err := dest.ReplayFrom(ctx, source, "")
+	if err != nil {
+		t.Fatalf("ReplayFrom() error = %v", err)
+	}
+	if got, want := auditedMembers(t, dest), auditedMembers(t, source); !reflect.DeepEqual(got, want) {
+		t.Errorf("members after ReplayFrom() = %v, expected %v", got, want)
+	}
+
+	if _, err := source.IncreaseScore(ctx, map[string]float64{"points": 5}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	if _, err := dest.ReplayFrom(ctx, source, last); err != nil {
+		t.Fatalf("ReplayFrom() error = %v", err)
+	}
+	if got, want := auditedMembers(t, dest), auditedMembers(t, source); !reflect.DeepEqual(got, want) {
+		t.Errorf("members after catching up = %v, expected %v", got, want)
+	}
+	if got := auditedIndex(t, dest, "points"); len(got) != 2 {
+		t.Errorf("points index = %v, expected both members", got)
+	}
+
+	if _, err := source.ReplayFrom(ctx, dest, ""); !errors.Is(err, ErrAuditDisabled) {
+		t.Errorf("ReplayFrom() a set without Audit error = %v, expected ErrAuditDisabled", err)
+	}
+	other, err := New(MultiFieldSetOptions{Name: "other", Fields: []Field{{Name: "points", MaxValue: 10}}, Client: source.client})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := other.ReplayFrom(ctx, source, ""); !errors.Is(err, ErrIncompatibleSets) {
+		t.Errorf("ReplayFrom() an incompatible set error = %v, expected ErrIncompatibleSets", err)
+	}
+}
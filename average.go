@@ -0,0 +1,92 @@
+package zmultifield
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// defaultMaxSamples bounds the sample count of an Average field when MaxSamples is not set.
+const defaultMaxSamples = 1<<16 - 1
+
+// averageCountName returns the name of the hidden field counting the samples of the Average field
+// name.
+func averageCountName(name string) string {
+	return name + ".count"
+}
+
+// averageCountDef returns the definition of the hidden count field of the Average field f.
+func averageCountDef(f Field) Field {
+	maxSamples := f.MaxSamples
+	if maxSamples == 0 {
+		maxSamples = defaultMaxSamples
+	}
+	return Field{
+		Name:       averageCountName(f.Name),
+		Sort:       Ascending,
+		MaxValue:   maxSamples,
+		UpdateType: Replace,
+	}
+}
+
+// withAverageCounts adds a count field for every Average field to fields, below the user fields of
+// layout, rejecting user fields with the same names.
+func withAverageCounts(fields []Field, layout Layout) ([]Field, error) {
+	names := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		names[f.Name] = true
+	}
+
+	var counts []Field
+	for _, f := range fields {
+		if f.UpdateType != Average {
+			continue
+		}
+		if f.MaxSamples < 0 || f.MaxSamples != math.Trunc(f.MaxSamples) {
+			return nil, fmt.Errorf("field %s: MaxSamples must be a positive integer", f.Name)
+		}
+		count := averageCountDef(f)
+		if names[count.Name] {
+			return nil, fmt.Errorf("field name %s is reserved by the Average field %s", count.Name, f.Name)
+		}
+		counts = append(counts, count)
+	}
+	if len(counts) == 0 {
+		return fields, nil
+	}
+	if layout.LeastSignificantFirst {
+		return append(counts, fields...), nil
+	}
+	return append(append([]Field(nil), fields...), counts...), nil
+}
+
+// addSample folds value into the running average of an Average field in the raw scores, rounding
+// the mean to the nearest integer. Once the count reaches MaxSamples it stops growing, and the mean
+// becomes a moving average over about the last MaxSamples values.
+func (mf *multiField) addSample(scores []*big.Int, value int64) error {
+	sample := big.NewInt(value)
+	if sample.Cmp(mf.minDisplay()) < 0 || sample.Cmp(mf.maxDisplay()) > 0 {
+		return outOfRangeError(mf, mf.toRaw(sample))
+	}
+
+	count := new(big.Int).Set(scores[mf.count.position])
+	if count.Cmp(mf.count.maxAbsolute) < 0 {
+		count.Add(count, big.NewInt(1))
+	}
+
+	// mean += (sample - mean) / count
+	mean := mf.toDisplay(scores[mf.position])
+	step := divRound(sample.Sub(sample, mean), count)
+	scores[mf.position] = mf.toRaw(mean.Add(mean, step))
+	scores[mf.count.position] = count
+	return nil
+}
+
+// divRound returns a / b rounded to the nearest integer, halves away from zero, for a positive b.
+func divRound(a, b *big.Int) *big.Int {
+	q, r := new(big.Int).QuoRem(a, b, new(big.Int))
+	if new(big.Int).Lsh(new(big.Int).Abs(r), 1).Cmp(b) >= 0 {
+		q.Add(q, big.NewInt(int64(a.Sign())))
+	}
+	return q
+}
@@ -0,0 +1,119 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAverage(t *testing.T) {
+	ctx := context.Background()
+	client, _ := newTestClient(t)
+	mfs, err := New(MultiFieldSetOptions{
+		Name: "test",
+		Fields: []Field{
+			{Name: "avgScore", Sort: Descending, MaxValue: 1000, UpdateType: Average},
+			{Name: "wins", Sort: Descending, MaxValue: 100, UpdateType: Incremental},
+		},
+		Client: client,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	for _, score := range []float64{100, 200, 400} {
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"avgScore": score}, "alice"); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+	}
+	// Updates to other fields don't count as samples
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"avgScore": 250, "wins": 1}, "bob"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"wins": 1}, "bob"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+
+	members, err := mfs.GetTopMembers(ctx, 10)
+	if err != nil {
+		t.Fatalf("GetTopMembers() error = %v", err)
+	}
+	// The count is hidden from the returned scores
+	if len(members) != 2 || members[0].Member != "bob" || len(members[0].Scores) != 2 {
+		t.Fatalf("GetTopMembers() = %v, expected bob then alice with two fields", members)
+	}
+	if avg := FieldValue(members[1].Scores, "avgScore"); avg != 233 {
+		t.Errorf("alice's average = %v, expected 233", avg)
+	}
+	if avg := FieldValue(members[0].Scores, "avgScore"); avg != 250 {
+		t.Errorf("bob's average = %v, expected 250", avg)
+	}
+
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"avgScore": 5000}, "alice"); !errors.Is(err, ErrScoreOutOfRange) {
+		t.Errorf("IncreaseScore() above the maximum error = %v, expected ErrScoreOutOfRange", err)
+	}
+
+	// Resetting the average starts counting again
+	if _, err := mfs.ResetFields(ctx, "alice", "avgScore"); err != nil {
+		t.Fatalf("ResetFields() error = %v", err)
+	}
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"avgScore": 10}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	if scores, err := mfs.GetScores(ctx, "alice"); err != nil || FieldValue(scores, "avgScore") != 10 {
+		t.Errorf("GetScores() after ResetFields = %v, %v, expected an average of 10", scores, err)
+	}
+
+	w := mfs.NewBufferedWriter(BufferedWriterOptions{Window: time.Hour})
+	defer w.Close(ctx)
+	if err := w.Add(ctx, map[string]float64{"avgScore": 10}, "alice"); !errors.Is(err, ErrAverageUnsupported) {
+		t.Errorf("BufferedWriter.Add() error = %v, expected ErrAverageUnsupported", err)
+	}
+}
+
+func TestAverage_MaxSamples(t *testing.T) {
+	ctx := context.Background()
+	client, _ := newTestClient(t)
+	mfs, err := New(MultiFieldSetOptions{
+		Name:   "test",
+		Fields: []Field{{Name: "avg", Sort: Ascending, MaxValue: 1000, UpdateType: Average, MaxSamples: 3}},
+		Client: client,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	// Past three samples each new value moves the mean a third of the way
+	for _, value := range []float64{30, 30, 30, 90} {
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"avg": value}, "alice"); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+	}
+	if scores, err := mfs.GetScores(ctx, "alice"); err != nil || FieldValue(scores, "avg") != 50 {
+		t.Errorf("GetScores() = %v, %v, expected a moving average of 50", scores, err)
+	}
+}
+
+func TestAverage_Validation(t *testing.T) {
+	client, _ := newTestClient(t)
+	for name, fields := range map[string][]Field{
+		"fractional samples": {{Name: "avg", MaxValue: 100, UpdateType: Average, MaxSamples: 1.5}},
+		"reserved name": {
+			{Name: "avg", MaxValue: 100, UpdateType: Average},
+			{Name: "avg.count", MaxValue: 100, UpdateType: Incremental},
+		},
+	} {
+		if _, err := New(MultiFieldSetOptions{Name: "test", Fields: fields, Client: client}); err == nil {
+			t.Errorf("New() with %s succeeded, expected an error", name)
+		}
+	}
+
+	fields := []Field{{Name: "avg", MaxValue: 100, UpdateType: Average}}
+	if _, err := NewLex(LexSetOptions{Name: "lex", Fields: fields, Client: client}); !errors.Is(err, ErrAverageUnsupported) {
+		t.Errorf("NewLex() error = %v, expected ErrAverageUnsupported", err)
+	}
+	if _, err := NewWeighted(WeightedSetOptions{Name: "weighted", Fields: fields, Client: client}); !errors.Is(err, ErrAverageUnsupported) {
+		t.Errorf("NewWeighted() error = %v, expected ErrAverageUnsupported", err)
+	}
+}
@@ -0,0 +1,391 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// applyDeltasScript applies coalesced deltas to a batch of members atomically. Like the merge
+// script it decodes with arithmetic, which is exact below 2^53. A member whose new value doesn't
+// fit a field is skipped and reported; the other members are still written.
+//
+// KEYS[1] is the main set and KEYS[2..n] the field indexes, if maintained, in field order.
+// ARGV[1] is the default zscore and ARGV[2] the number of fields, followed by four values per field:
+// 2^shift, 2^bits, the maximum raw value and the update mode returned by rawUpdate. Then come the
+// members, each followed by one raw operand per field, empty when the field is unchanged. The
+// script returns a flat list of member, field position and raw value for every skipped member.
+var applyDeltasScript = newWriteScript(bitOrLua + `
+local nfields = tonumber(ARGV[2])
+local fields = {}
+for i = 3, 2 + nfields * 4, 4 do
+	fields[#fields + 1] = {
+		base = tonumber(ARGV[i]),
+		size = tonumber(ARGV[i + 1]),
+		max = tonumber(ARGV[i + 2]),
+		mode = ARGV[i + 3],
+	}
+end
+
+local skipped = {}
+for m = 3 + nfields * 4, #ARGV, nfields + 1 do
+	local member = ARGV[m]
+	local zscore = tonumber(redis.call('ZSCORE', KEYS[1], member) or ARGV[1])
+	local updated = zscore
+	local raws = {}
+	local ok = true
+	for f, field in ipairs(fields) do
+		local raw = math.floor(zscore / field.base) % field.size
+		local operand = ARGV[m + f]
+		if operand ~= '' then
+			local value = tonumber(operand)
+			local newRaw = value
+			if field.mode == 'inc' then
+				newRaw = raw + value
+			elseif field.mode == 'max' then
+				newRaw = math.max(raw, value)
+			elseif field.mode == 'min' then
+				newRaw = math.min(raw, value)
+			elseif field.mode == 'or' then
+				newRaw = bor(raw, value)
+			end
+			if newRaw < 0 or newRaw > field.max then
+				skipped[#skipped + 1] = member
+				skipped[#skipped + 1] = f - 1
+				skipped[#skipped + 1] = string.format('%.17g', newRaw)
+				ok = false
+				break
+			end
+			updated = updated + (newRaw - raw) * field.base
+			raw = newRaw
+		end
+		raws[f] = raw
+	end
+	if ok then
+		redis.call('ZADD', KEYS[1], string.format('%.17g', updated), member)
+		for k = 2, #KEYS do
+			redis.call('ZADD', KEYS[k], string.format('%.17g', raws[k - 1]), member)
+		end
+	end
+end
+return skipped
+`)
+
+// BufferedWriterOptions configures a BufferedWriter.
+type BufferedWriterOptions struct {
+	// Window is how often buffered updates are flushed. Defaults to one second.
+	Window time.Duration
+	// MaxPending is the number of distinct members buffered before Add flushes synchronously,
+	// slowing down callers until Redis catches up. Defaults to 10000.
+	MaxPending int
+	// BatchSize is the number of members written per script call. Defaults to 500.
+	BatchSize int
+}
+
+// BufferedWriter coalesces updates to the same member in memory and writes them in batches.
+// Incremental fields are summed, Replace fields keep the last value and KeepMax, KeepMin and BitOr
+// fields the largest, smallest and ORed values. Notifications, history and after-update hooks
+// are not run for buffered updates; members that fail to apply are reported to the error hooks
+// with op "BufferedWriter".
+//
+// Sets whose updates the flush can't enforce are rejected rather than silently bypassed: on a set
+// with before-update hooks, such as a Quarantinable one, or with UpdateOnlyExisting, MaxMembers or
+// dimensions, Add fails with ErrBufferingUnsupported.
+type BufferedWriter struct {
+	mfs  *MultiFieldSet
+	opts BufferedWriterOptions
+
+	mu      sync.Mutex
+	pending map[string]*bufferedUpdate
+	closed  bool
+
+	// flushMu serializes flushes so callers blocked on a full buffer wait for the one in progress
+	flushMu sync.Mutex
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// bufferedUpdate holds the coalesced deltas of one member, indexed by field position.
+type bufferedUpdate struct {
+	deltas []int64
+	set    []bool
+}
+
+// NewBufferedWriter creates a BufferedWriter for the set and starts flushing it every Window.
+// Close must be called to flush the remaining updates and stop the background goroutine.
+func (mfs *MultiFieldSet) NewBufferedWriter(opts BufferedWriterOptions) *BufferedWriter {
+	if opts.Window <= 0 {
+		opts.Window = time.Second
+	}
+	if opts.MaxPending <= 0 {
+		opts.MaxPending = 10000
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 500
+	}
+
+	w := &BufferedWriter{
+		mfs:     mfs,
+		opts:    opts,
+		pending: make(map[string]*bufferedUpdate),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// run flushes the buffer every Window until the writer is closed.
+func (w *BufferedWriter) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.opts.Window)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			// Flush already reports errors to the error hooks
+			_ = w.Flush(context.Background())
+		}
+	}
+}
+
+// Add buffers an update with the same semantics as IncreaseScore. If MaxPending members are
+// already buffered, Add flushes before returning.
+func (w *BufferedWriter) Add(ctx context.Context, fields map[string]float64, member string) error {
+	if !w.mfs.canBuffer() {
+		return ErrBufferingUnsupported
+	}
+	positions := make(map[int]int64, len(fields))
+	for name, value := range fields {
+		field := w.mfs.GetFieldByName(name)
+		if field == nil {
+			return fieldNotFoundError(name)
+		}
+		delta, err := toInt64(name, value)
+		if err != nil {
+			return err
+		}
+		if _, _, err := field.rawUpdate(delta); err != nil {
+			return err
+		}
+		positions[field.position] = delta
+	}
+
+	for {
+		w.mu.Lock()
+		if w.closed {
+			w.mu.Unlock()
+			return ErrWriterClosed
+		}
+		update, ok := w.pending[member]
+		if ok || len(w.pending) < w.opts.MaxPending {
+			if !ok {
+				update = w.newUpdate()
+				w.pending[member] = update
+			}
+			for pos, value := range positions {
+				w.apply(update, pos, value)
+			}
+			w.mu.Unlock()
+			return nil
+		}
+		w.mu.Unlock()
+
+		if err := w.Flush(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+// canBuffer reports whether the set's updates can be buffered, that is whether it has none of the
+// per-update checks and side effects the apply-deltas script skips.
+func (mfs *MultiFieldSet) canBuffer() bool {
+	if mfs.updateOnlyExisting || mfs.maxMembers > 0 || len(mfs.dimensions) > 0 {
+		return false
+	}
+	mfs.hooks.mu.RLock()
+	defer mfs.hooks.mu.RUnlock()
+	return len(mfs.hooks.beforeUpdate) == 0
+}
+
+// newUpdate returns an empty bufferedUpdate for the set's fields.
+func (w *BufferedWriter) newUpdate() *bufferedUpdate {
+	n := len(w.mfs.fields)
+	return &bufferedUpdate{deltas: make([]int64, n), set: make([]bool, n)}
+}
+
+// apply coalesces value into the field at pos.
+func (w *BufferedWriter) apply(update *bufferedUpdate, pos int, value int64) {
+	if update.set[pos] {
+		value = coalesce(w.mfs.fields[pos].UpdateType, update.deltas[pos], value)
+	}
+	update.deltas[pos] = value
+	update.set[pos] = true
+}
+
+// Flush writes the buffered updates. Updates in a batch that failed before reaching Redis, or
+// that Redis refused, are buffered again so the next flush retries them, along with the batches
+// after it. A batch that may have been applied, e.g. one that timed out, isn't buffered again, so
+// it can't be applied twice; the error is returned.
+func (w *BufferedWriter) Flush(ctx context.Context) error {
+	w.flushMu.Lock()
+	defer w.flushMu.Unlock()
+
+	w.mu.Lock()
+	pending := w.pending
+	w.pending = make(map[string]*bufferedUpdate)
+	w.mu.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	members := make([]string, 0, len(pending))
+	for member := range pending {
+		members = append(members, member)
+	}
+
+	for start := 0; start < len(members); start += w.opts.BatchSize {
+		end := start + w.opts.BatchSize
+		if end > len(members) {
+			end = len(members)
+		}
+		if err := w.flushBatch(ctx, members[start:end], pending); err != nil {
+			if notApplied(err) || errors.Is(err, ErrFrozen) {
+				w.requeue(members[start:], pending)
+			} else {
+				w.requeue(members[end:], pending)
+			}
+			return w.mfs.runOnError(ctx, "BufferedWriter", "", err)
+		}
+	}
+	return nil
+}
+
+// flushBatch writes the updates of members with the apply-deltas script.
+func (w *BufferedWriter) flushBatch(ctx context.Context, members []string, pending map[string]*bufferedUpdate) error {
+	mfs := w.mfs
+	updates := make([]*bufferedUpdate, len(members))
+	for i, member := range members {
+		updates[i] = pending[member]
+	}
+	keys, args := mfs.applyDeltasArgs(members, updates)
+
+	var skipped []interface{}
+	err := mfs.writeOnce(ctx, func(client redis.UniversalClient) error {
+		var err error
+		skipped, err = applyDeltasScript.Run(ctx, client, mfs.writeGuard(), keys, args...).Slice()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	mfs.reportSkipped(ctx, "BufferedWriter", skipped)
+	return nil
+}
+
+// applyDeltasArgs returns the keys and arguments of the apply-deltas script for members and their
+// updates, in the same order.
+func (mfs *MultiFieldSet) applyDeltasArgs(members []string, updates []*bufferedUpdate) ([]string, []interface{}) {
+	keys := []string{mfs.key}
+	args := []interface{}{mfs.defaultZScore.String(), len(mfs.fields)}
+	for _, field := range mfs.fields {
+		if mfs.maintainFieldIndexes {
+			keys = append(keys, mfs.fieldIndexKey(field))
+		}
+		mode, _, _ := field.rawUpdate(0)
+		args = append(args,
+			uint64(1)<<field.shiftValue,
+			uint64(1)<<field.bits,
+			field.maxAbsolute.String(),
+			mode,
+		)
+	}
+
+	var stamp int64
+	if mfs.updatedAt != nil {
+		stamp = updatedAtRaw().Int64()
+	}
+	for i, member := range members {
+		update := updates[i]
+		args = append(args, member)
+		for pos := range mfs.fields {
+			switch {
+			case mfs.fields[pos] == mfs.updatedAt:
+				args = append(args, stamp)
+			case mfs.fields[pos] == mfs.salt:
+				args = append(args, mfs.saltRaw(member).Int64())
+			case update.set[pos]:
+				_, operand, err := mfs.fields[pos].rawUpdate(update.deltas[pos])
+				if err != nil {
+					// Fields of unknown update types are left unchanged
+					args = append(args, "")
+					continue
+				}
+				args = append(args, operand.String())
+			default:
+				args = append(args, "")
+			}
+		}
+	}
+	return keys, args
+}
+
+// reportSkipped reports the members skipped by the apply-deltas script to the error hooks with op.
+func (mfs *MultiFieldSet) reportSkipped(ctx context.Context, op string, skipped []interface{}) {
+	for i := 0; i+2 < len(skipped); i += 3 {
+		member, _ := skipped[i].(string)
+		pos, _ := skipped[i+1].(int64)
+		raw, _ := strconv.ParseFloat(skipped[i+2].(string), 64)
+		field := mfs.fields[pos]
+		mfs.runOnError(ctx, op, member, outOfRangeError(field, big.NewInt(int64(raw))))
+	}
+}
+
+// requeue merges the unwritten updates of members back into the buffer, keeping newer Replace
+// values.
+func (w *BufferedWriter) requeue(members []string, pending map[string]*bufferedUpdate) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, member := range members {
+		old := pending[member]
+		current, ok := w.pending[member]
+		if !ok {
+			w.pending[member] = old
+			continue
+		}
+		for pos := range old.deltas {
+			if !old.set[pos] {
+				continue
+			}
+			if current.set[pos] {
+				current.deltas[pos] = coalesce(w.mfs.fields[pos].UpdateType, old.deltas[pos], current.deltas[pos])
+			} else {
+				current.deltas[pos] = old.deltas[pos]
+			}
+			current.set[pos] = true
+		}
+	}
+}
+
+// Close stops the background flushes and writes the remaining updates. Add fails with
+// ErrWriterClosed afterwards.
+func (w *BufferedWriter) Close(ctx context.Context) error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.mu.Unlock()
+
+	close(w.stop)
+	<-w.done
+	return w.Flush(ctx)
+}
@@ -0,0 +1,186 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func newBufferedTestSet(t *testing.T, opts MultiFieldSetOptions) *MultiFieldSet {
+	t.Helper()
+	client, _ := newTestClient(t)
+	opts.Name = "buffered"
+	opts.Fields = []Field{
+		{Name: "points", Sort: Descending, MaxValue: 1000, UpdateType: Incremental},
+		{Name: "level", Sort: Descending, MaxValue: 50, UpdateType: Replace},
+	}
+	opts.Client = client
+	mfs, err := New(opts)
+	if err != nil {
+		t.Fatalf("Failed to create MultiFieldSet: %v", err)
+	}
+	return mfs
+}
+
+func TestBufferedWriter(t *testing.T) {
+	mfs := newBufferedTestSet(t, MultiFieldSetOptions{MaintainFieldIndexes: true})
+	ctx := context.Background()
+
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 100}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+
+	var failed []string
+	mfs.OnError(func(ctx context.Context, op string, member string, err error) {
+		if op == "BufferedWriter" && errors.Is(err, ErrScoreOutOfRange) {
+			failed = append(failed, member)
+		}
+	})
+
+	w := mfs.NewBufferedWriter(BufferedWriterOptions{Window: time.Hour})
+	updates := []struct {
+		member string
+		fields map[string]float64
+	}{
+		{"alice", map[string]float64{"points": 10, "level": 3}},
+		{"alice", map[string]float64{"points": 5, "level": 7}},
+		{"bob", map[string]float64{"points": 1}},
+		{"cheater", map[string]float64{"points": 900}},
+		{"cheater", map[string]float64{"points": 900}},
+	}
+	for _, u := range updates {
+		if err := w.Add(ctx, u.fields, u.member); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+	if err := w.Add(ctx, map[string]float64{"kills": 1}, "alice"); !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("Add(kills) error = %v, expected %v", err, ErrFieldNotFound)
+	}
+
+	if exists, _ := mfs.MemberExists(ctx, "bob"); exists {
+		t.Errorf("bob was written before the buffer was flushed")
+	}
+	if err := w.Close(ctx); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	scores, err := mfs.GetScores(ctx, "alice")
+	if err != nil {
+		t.Fatalf("GetScores() error = %v", err)
+	}
+	if scores[0].Int64() != 115 || scores[1].Int64() != 7 {
+		t.Errorf("alice scores = %+v, expected 115 points and level 7", scores)
+	}
+	if rank, err := mfs.GetFieldRank(ctx, "points", "bob"); err != nil || rank != 1 {
+		t.Errorf("GetFieldRank(points, bob) = %d, %v, expected 1", rank, err)
+	}
+	if exists, _ := mfs.MemberExists(ctx, "cheater"); exists {
+		t.Errorf("out of range member was written")
+	}
+	if len(failed) != 1 || failed[0] != "cheater" {
+		t.Errorf("reported failures = %v, expected cheater", failed)
+	}
+
+	if err := w.Add(ctx, map[string]float64{"points": 1}, "alice"); !errors.Is(err, ErrWriterClosed) {
+		t.Errorf("Add() after Close error = %v, expected %v", err, ErrWriterClosed)
+	}
+}
+
+func TestBufferedWriter_Backpressure(t *testing.T) {
+	mfs := newBufferedTestSet(t, MultiFieldSetOptions{})
+	ctx := context.Background()
+
+	w := mfs.NewBufferedWriter(BufferedWriterOptions{Window: time.Hour, MaxPending: 2})
+	defer w.Close(ctx)
+
+	for _, member := range []string{"alice", "bob", "carol"} {
+		if err := w.Add(ctx, map[string]float64{"points": 1}, member); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	// the third member didn't fit, so the first two were flushed synchronously
+	if count, _ := mfs.GetCardinality(ctx); count != 2 {
+		t.Errorf("GetCardinality() = %d, expected 2 after the buffer filled up", count)
+	}
+}
+
+func TestBufferedWriter_FailedFlush(t *testing.T) {
+	client, mr := newTestClient(t)
+	mfs, err := New(MultiFieldSetOptions{
+		Name:   "buffered",
+		Fields: []Field{{Name: "points", Sort: Descending, MaxValue: 1000, UpdateType: Incremental}},
+		Client: client,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	ctx := context.Background()
+	w := mfs.NewBufferedWriter(BufferedWriterOptions{Window: time.Hour})
+	defer w.Close(ctx)
+	points := func() float64 {
+		t.Helper()
+		scores, err := mfs.GetScores(ctx, "alice")
+		if err != nil {
+			t.Fatalf("GetScores() error = %v", err)
+		}
+		return FieldValue(scores, "points")
+	}
+
+	// A batch Redis refused is buffered again and written by the next flush
+	if err := w.Add(ctx, map[string]float64{"points": 10}, "alice"); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	mr.SetError("LOADING Redis is loading the dataset in memory")
+	if err := w.Flush(ctx); err == nil {
+		t.Fatal("Flush() succeeded while Redis was loading")
+	}
+	mr.SetError("")
+	if err := w.Flush(ctx); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if got := points(); got != 10 {
+		t.Errorf("points after a refused flush = %v, expected 10", got)
+	}
+
+	// A batch that timed out may have been applied, so it isn't written again
+	client.AddHook(&timeoutAfterWrite{armed: true})
+	if err := w.Add(ctx, map[string]float64{"points": 5}, "alice"); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := w.Flush(ctx); !errors.Is(err, errReadTimeout) {
+		t.Fatalf("Flush() error = %v, expected the timeout", err)
+	}
+	if err := w.Flush(ctx); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if got := points(); got != 15 {
+		t.Errorf("points after a timed out flush = %v, expected 15", got)
+	}
+}
+
+func TestBufferedWriter_Unsupported(t *testing.T) {
+	ctx := context.Background()
+	for name, opts := range map[string]MultiFieldSetOptions{
+		"UpdateOnlyExisting": {UpdateOnlyExisting: true},
+		"MaxMembers":         {MaxMembers: 10},
+		"Quarantinable":      {Quarantinable: true},
+		"Dimensions":         {Dimensions: []Dimension{{Name: "country"}}},
+	} {
+		w := newBufferedTestSet(t, opts).NewBufferedWriter(BufferedWriterOptions{Window: time.Hour})
+		if err := w.Add(ctx, map[string]float64{"points": 1}, "alice"); !errors.Is(err, ErrBufferingUnsupported) {
+			t.Errorf("%s: Add() error = %v, expected ErrBufferingUnsupported", name, err)
+		}
+		w.Close(ctx)
+	}
+
+	// Hooks registered after the writer was created count too
+	mfs := newBufferedTestSet(t, MultiFieldSetOptions{})
+	w := mfs.NewBufferedWriter(BufferedWriterOptions{Window: time.Hour})
+	defer w.Close(ctx)
+	mfs.BeforeUpdate(func(ctx context.Context, event *UpdateEvent) error { return nil })
+	if err := w.Add(ctx, map[string]float64{"points": 1}, "alice"); !errors.Is(err, ErrBufferingUnsupported) {
+		t.Errorf("Add() with a before-update hook error = %v, expected ErrBufferingUnsupported", err)
+	}
+}
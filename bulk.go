@@ -0,0 +1,178 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// BulkLoadFailure reports a member that BulkLoad could not write.
+type BulkLoadFailure struct {
+	Member string
+	Err    error
+}
+
+// BulkLoadProgress is passed to the progress callback of BulkLoad after every batch.
+type BulkLoadProgress struct {
+	Loaded int64
+	Failed int64
+}
+
+// BulkLoadResult summarizes a BulkLoad.
+type BulkLoadResult struct {
+	Loaded   int64
+	Failures []BulkLoadFailure
+}
+
+// encodedMember is a member ready to be written: its zscore and raw field values.
+type encodedMember struct {
+	member string
+	raws   []*big.Int
+	zscore *big.Int
+}
+
+// encodeMembers encodes members from their display scores, returning the members that could not
+// be encoded separately.
+func (mfs *MultiFieldSet) encodeMembers(members []MemberScores) ([]encodedMember, []BulkLoadFailure) {
+	encoded := make([]encodedMember, 0, len(members))
+	var failures []BulkLoadFailure
+	for _, m := range members {
+		raws, err := mfs.displayScoresToRaw(m.Scores)
+		if err != nil {
+			failures = append(failures, BulkLoadFailure{Member: m.Member, Err: err})
+			continue
+		}
+		mfs.stampSalt(raws, m.Member)
+		encoded = append(encoded, encodedMember{member: m.Member, raws: raws, zscore: mfs.scoresToZScore(raws)})
+	}
+	return encoded, failures
+}
+
+// writeEncoded writes encoded members in one pipeline: the zscore to the first key and the raw
+// field values to the following ones. If the set is Freezable the pipeline is a transaction
+// checking the frozen flag, and if the first key is the main set of an Audit set a transaction
+// recording the writes.
+func (mfs *MultiFieldSet) writeEncoded(ctx context.Context, keys []string, encoded []encodedMember) error {
+	audited := mfs.audit && keys[0] == mfs.key
+	fn := func(pipe redis.Pipeliner) error {
+		for _, e := range encoded {
+			pipe.ZAdd(ctx, keys[0], &redis.Z{Score: float64(e.zscore.Int64()), Member: e.member})
+			if audited {
+				mfs.recordSet(ctx, pipe, e.member, float64(e.zscore.Int64()))
+			}
+			for i := 1; i < len(keys); i++ {
+				pipe.ZAdd(ctx, keys[i], &redis.Z{Score: float64(e.raws[i-1].Int64()), Member: e.member})
+			}
+		}
+		return nil
+	}
+	return mfs.write(ctx, func(client redis.UniversalClient) error {
+		if mfs.freezable || audited {
+			return mfs.txPipelined(ctx, client, fn)
+		}
+		_, err := client.Pipelined(ctx, fn)
+		return err
+	})
+}
+
+// writeBatch encodes and writes a batch of members, failing if any member can't be encoded.
+func (mfs *MultiFieldSet) writeBatch(ctx context.Context, keys []string, members []MemberScores) error {
+	encoded, failures := mfs.encodeMembers(members)
+	if len(failures) > 0 {
+		return failures[0].Err
+	}
+	return mfs.writeEncoded(ctx, keys, encoded)
+}
+
+// BulkLoad writes the members yielded by src in pipelined batches of batchSize, using up to
+// concurrency batches in flight, and is much faster than calling IncreaseScore per member.
+// Scores replace any existing scores and fields missing from a member get their default score.
+//
+// Members that can't be encoded, or whose batch fails to write, are reported in the result's
+// Failures rather than stopping the load; an error is only returned if src fails or ctx is done.
+// If progress is not nil it is called after every batch, never concurrently.
+func (mfs *MultiFieldSet) BulkLoad(ctx context.Context, src Iterator, batchSize, concurrency int, progress func(BulkLoadProgress)) (*BulkLoadResult, error) {
+	if batchSize < 1 || concurrency < 1 {
+		return nil, errors.New("batch size and concurrency must be positive")
+	}
+
+	keys := []string{mfs.key}
+	if mfs.maintainFieldIndexes {
+		for _, field := range mfs.fields {
+			keys = append(keys, mfs.fieldIndexKey(field))
+		}
+	}
+
+	var mu sync.Mutex
+	result := &BulkLoadResult{}
+	record := func(loaded int64, failures []BulkLoadFailure) {
+		mu.Lock()
+		defer mu.Unlock()
+		result.Loaded += loaded
+		result.Failures = append(result.Failures, failures...)
+		if progress != nil {
+			progress(BulkLoadProgress{Loaded: result.Loaded, Failed: int64(len(result.Failures))})
+		}
+	}
+
+	batches := make(chan []MemberScores)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				encoded, failures := mfs.encodeMembers(batch)
+				if err := mfs.writeEncoded(ctx, keys, encoded); err != nil {
+					mfs.runOnError(ctx, "BulkLoad", "", err)
+					for _, e := range encoded {
+						failures = append(failures, BulkLoadFailure{Member: e.member, Err: err})
+					}
+					encoded = nil
+				}
+				record(int64(len(encoded)), failures)
+			}
+		}()
+	}
+
+	err := mfs.dispatchBatches(ctx, src, batchSize, batches)
+	close(batches)
+	wg.Wait()
+	if err != nil {
+		return result, mfs.runOnError(ctx, "BulkLoad", "", err)
+	}
+	return result, nil
+}
+
+// dispatchBatches reads src into batches of batchSize and sends them to batches.
+func (mfs *MultiFieldSet) dispatchBatches(ctx context.Context, src Iterator, batchSize int, batches chan<- []MemberScores) error {
+	batch := make([]MemberScores, 0, batchSize)
+	send := func() error {
+		select {
+		case batches <- batch:
+			batch = make([]MemberScores, 0, batchSize)
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	for src.Next(ctx) {
+		batch = append(batch, src.Value())
+		if len(batch) == batchSize {
+			if err := send(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := src.Err(); err != nil {
+		return err
+	}
+	if len(batch) > 0 {
+		return send()
+	}
+	return ctx.Err()
+}
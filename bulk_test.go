@@ -0,0 +1,63 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+)
+
+func TestBulkLoad(t *testing.T) {
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{MaintainFieldIndexes: true})
+	ctx := context.Background()
+
+	members := make([]MemberScores, 0, 25)
+	for i := 0; i < 24; i++ {
+		members = append(members, MemberScores{
+			Member: fmt.Sprintf("player%02d", i),
+			Scores: []FieldScore{{Name: "points", Score: big.NewInt(int64(i * 10))}},
+		})
+	}
+	members = append(members, MemberScores{
+		Member: "cheater",
+		Scores: []FieldScore{{Name: "points", Score: big.NewInt(5000)}},
+	})
+
+	var calls int
+	var last BulkLoadProgress
+	result, err := mfs.BulkLoad(ctx, SliceIterator(members), 5, 3, func(p BulkLoadProgress) {
+		calls++
+		last = p
+	})
+	if err != nil {
+		t.Fatalf("BulkLoad() error = %v", err)
+	}
+	if result.Loaded != 24 {
+		t.Errorf("Loaded = %d, expected 24", result.Loaded)
+	}
+	if len(result.Failures) != 1 || result.Failures[0].Member != "cheater" || !errors.Is(result.Failures[0].Err, ErrScoreOutOfRange) {
+		t.Errorf("Failures = %+v, expected cheater out of range", result.Failures)
+	}
+	if calls != 5 || last.Loaded != 24 || last.Failed != 1 {
+		t.Errorf("progress called %d times, last %+v, expected 5 calls ending at 24 loaded and 1 failed", calls, last)
+	}
+
+	top, err := mfs.GetTopMembers(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetTopMembers() error = %v", err)
+	}
+	if len(top) != 1 || top[0].Member != "player23" || top[0].Scores[0].Int64() != 230 {
+		t.Errorf("GetTopMembers() = %+v, expected player23 with 230 points", top)
+	}
+	if rank, err := mfs.GetFieldRank(ctx, "points", "player22"); err != nil || rank != 1 {
+		t.Errorf("GetFieldRank(points, player22) = %d, %v, expected 1", rank, err)
+	}
+}
+
+func TestBulkLoad_InvalidArguments(t *testing.T) {
+	mfs := newTestSet(t)
+	if _, err := mfs.BulkLoad(context.Background(), SliceIterator(nil), 0, 1, nil); err == nil {
+		t.Errorf("BulkLoad() accepted a batch size of 0")
+	}
+}
@@ -0,0 +1,188 @@
+package zmultifield
+
+import (
+	"container/list"
+	"context"
+	"math/big"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// CacheOptions enables an in-process read-through cache for GetRank, GetScores and GetTopMembers.
+//
+// Every write made through the set clears the cache, since any update can shift the ranks of
+// other members. Writes made by other processes are only seen once cached entries expire, unless
+// WatchInvalidations is running. Redis client-side caching (RESP3 tracking) isn't available with
+// the go-redis v8 client, so the set's own notifications serve that purpose instead.
+type CacheOptions struct {
+	// TTL is how long an entry is served from the cache. Defaults to one second.
+	TTL time.Duration
+	// MaxEntries is the number of entries kept before the least recently used one is evicted.
+	// Defaults to 10000.
+	MaxEntries int
+}
+
+// readCache is a TTL and LRU bounded cache of read results. A nil *readCache caches nothing.
+type readCache struct {
+	ttl        time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	gen     uint64
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+// cacheEntry is a cached read result, stored in the LRU list.
+type cacheEntry struct {
+	key     string
+	value   interface{}
+	expires time.Time
+}
+
+// newReadCache creates a readCache from opts, applying the defaults.
+func newReadCache(opts *CacheOptions) *readCache {
+	c := &readCache{
+		ttl:        opts.TTL,
+		maxEntries: opts.MaxEntries,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+	if c.ttl <= 0 {
+		c.ttl = time.Second
+	}
+	if c.maxEntries <= 0 {
+		c.maxEntries = 10000
+	}
+	return c
+}
+
+// get returns the cached value for key, if it is present and hasn't expired.
+func (c *readCache) get(key string) (interface{}, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if time.Now().After(entry.expires) {
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	return entry.value, true
+}
+
+// generation returns the current generation, which must be read before fetching a value to put.
+func (c *readCache) generation() uint64 {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.gen
+}
+
+// put caches value under key unless the cache was invalidated since gen was read, in which case
+// the value may predate a write and is dropped.
+func (c *readCache) put(key string, value interface{}, gen uint64) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gen != c.gen {
+		return
+	}
+	entry := &cacheEntry{key: key, value: value, expires: time.Now().Add(c.ttl)}
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.maxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// invalidate drops every entry and starts a new generation.
+func (c *readCache) invalidate() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	c.entries = make(map[string]*list.Element)
+	c.lru.Init()
+}
+
+// rankCacheKey, scoresCacheKey and topCacheKey return the cache keys of the cached reads.
+func rankCacheKey(member string) string   { return "rank:" + member }
+func scoresCacheKey(member string) string { return "scores:" + member }
+func topCacheKey(limit int64) string      { return "top:" + strconv.FormatInt(limit, 10) }
+
+// InvalidateCache drops every cached read, e.g. after writing to the set's keys directly.
+func (mfs *MultiFieldSet) InvalidateCache() {
+	mfs.cache.invalidate()
+}
+
+// WatchInvalidations subscribes to the set's notification channel and clears the cache whenever
+// another process updates the set, so cached reads don't wait for the TTL to see remote writes.
+// It requires both Cache and Notifications to be configured. Calling the returned function stops
+// watching.
+func (mfs *MultiFieldSet) WatchInvalidations(ctx context.Context) (stop func(), err error) {
+	if mfs.cache == nil {
+		return nil, ErrCacheDisabled
+	}
+	sub, err := mfs.Subscribe(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range sub.Events() {
+			mfs.cache.invalidate()
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			sub.Close()
+			<-done
+		})
+	}, nil
+}
+
+// copyFieldScores returns a deep copy of scores, so callers can't modify cached values.
+func copyFieldScores(scores []FieldScore) []FieldScore {
+	copied := make([]FieldScore, len(scores))
+	for i, score := range scores {
+		copied[i] = score
+		if score.Score != nil {
+			copied[i].Score = new(big.Int).Set(score.Score)
+		}
+	}
+	return copied
+}
+
+// copyMembers returns a deep copy of members, so callers can't modify cached values.
+func copyMembers(members []MemberScores) []MemberScores {
+	copied := make([]MemberScores, len(members))
+	for i, member := range members {
+		copied[i] = MemberScores{Member: member.Member, Scores: copyFieldScores(member.Scores)}
+	}
+	return copied
+}
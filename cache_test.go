@@ -0,0 +1,146 @@
+package zmultifield
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+func TestCacheInvalidatedByLocalWrites(t *testing.T) {
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{Cache: &CacheOptions{TTL: time.Hour}})
+	ctx := context.Background()
+
+	for member, points := range map[string]float64{"alice": 30, "bob": 20} {
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": points}, member); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+	}
+	if rank, err := mfs.GetRank(ctx, "bob"); err != nil || rank != 1 {
+		t.Fatalf("GetRank() = %d, %v, expected 1", rank, err)
+	}
+	if top, err := mfs.GetTopMembers(ctx, 1); err != nil || top[0].Member != "alice" {
+		t.Fatalf("GetTopMembers() = %+v, %v, expected alice first", top, err)
+	}
+
+	// A write behind the set's back is served stale until the cache is invalidated
+	carol := mfs.scoresToZScore([]*big.Int{big.NewInt(1023 - 600), big.NewInt(0)})
+	if err := mfs.client.ZAdd(ctx, mfs.key, &redis.Z{Score: float64(carol.Int64()), Member: "carol"}).Err(); err != nil {
+		t.Fatalf("ZAdd() error = %v", err)
+	}
+	if rank, _ := mfs.GetRank(ctx, "bob"); rank != 1 {
+		t.Errorf("cached GetRank() = %d, expected 1", rank)
+	}
+	mfs.InvalidateCache()
+	if rank, _ := mfs.GetRank(ctx, "bob"); rank != 2 {
+		t.Errorf("GetRank() after InvalidateCache = %d, expected 2", rank)
+	}
+	if top, _ := mfs.GetTopMembers(ctx, 1); top[0].Member != "carol" {
+		t.Errorf("GetTopMembers() after InvalidateCache = %+v, expected carol first", top)
+	}
+
+	// Writes through the set are seen immediately
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 700}, "bob"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	if rank, _ := mfs.GetRank(ctx, "bob"); rank != 0 {
+		t.Errorf("GetRank() after update = %d, expected 0", rank)
+	}
+	if top, _ := mfs.GetTopMembers(ctx, 1); top[0].Member != "bob" {
+		t.Errorf("GetTopMembers() after update = %+v, expected bob first", top)
+	}
+	scores, err := mfs.GetScores(ctx, "bob")
+	if err != nil || scores[0].Score.Int64() != 720 {
+		t.Fatalf("GetScores() = %v, %v, expected 720 points", scores, err)
+	}
+
+	// Cached values are copies, so callers can't corrupt them
+	scores[0].Score.SetInt64(1)
+	if scores, _ := mfs.GetScores(ctx, "bob"); scores[0].Score.Int64() != 720 {
+		t.Errorf("cached GetScores() = %v after modifying a result, expected 720 points", scores)
+	}
+}
+
+func TestCacheExpiryAndEviction(t *testing.T) {
+	cache := newReadCache(&CacheOptions{TTL: 10 * time.Millisecond, MaxEntries: 2})
+
+	cache.put("a", 1, cache.generation())
+	cache.put("b", 2, cache.generation())
+	cache.get("a")
+	cache.put("c", 3, cache.generation())
+	if _, ok := cache.get("b"); ok {
+		t.Error("least recently used entry was not evicted")
+	}
+	if v, ok := cache.get("a"); !ok || v != 1 {
+		t.Errorf("get(a) = %v, %v, expected 1", v, ok)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := cache.get("a"); ok {
+		t.Error("expired entry was served")
+	}
+
+	// A value read before an invalidation may predate the write and must not be cached
+	gen := cache.generation()
+	cache.invalidate()
+	cache.put("a", 1, gen)
+	if _, ok := cache.get("a"); ok {
+		t.Error("value from an older generation was cached")
+	}
+}
+
+func TestWatchInvalidations(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+	newSet := func() *MultiFieldSet {
+		mfs, err := New(MultiFieldSetOptions{
+			Name:          "test",
+			Fields:        []Field{{Name: "points", Sort: Descending, MaxValue: 1000, UpdateType: Incremental}},
+			Client:        client,
+			Notifications: &NotificationOptions{},
+			Cache:         &CacheOptions{TTL: time.Hour},
+		})
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		return mfs
+	}
+	local, remote := newSet(), newSet()
+
+	for _, mfs := range []*MultiFieldSet{local, remote} {
+		stop, err := mfs.WatchInvalidations(ctx)
+		if err != nil {
+			t.Fatalf("WatchInvalidations() error = %v", err)
+		}
+		defer stop()
+	}
+
+	if _, err := local.IncreaseScore(ctx, map[string]float64{"points": 10}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	if _, err := remote.GetRank(ctx, "alice"); err != nil {
+		t.Fatalf("GetRank() error = %v", err)
+	}
+	if _, err := local.IncreaseScore(ctx, map[string]float64{"points": 20}, "bob"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		rank, err := remote.GetRank(ctx, "alice")
+		if err == nil && rank == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("remote GetRank() = %d, %v, expected 1 after the notification", rank, err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	uncached := newTestSet(t)
+	if _, err := uncached.WatchInvalidations(ctx); err != ErrCacheDisabled {
+		t.Errorf("WatchInvalidations() without a cache error = %v, expected ErrCacheDisabled", err)
+	}
+}
@@ -0,0 +1,36 @@
+package zmultifield
+
+import (
+	"context"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// cleanupEvicted deletes the metadata and history of members evicted by MaxMembers and removes
+// them from their dimension sets. The eviction itself has already happened, so this is best
+// effort: failures are reported to the error hooks with op "Evict".
+func (mfs *MultiFieldSet) cleanupEvicted(ctx context.Context, members []string) {
+	keys := make([]string, 0, 2*len(members))
+	for _, member := range members {
+		keys = append(keys, mfs.metaKey(member), mfs.historyKey(member))
+	}
+	var dimensions map[string][]string
+	if len(mfs.dimensions) > 0 {
+		var err error
+		if dimensions, err = mfs.memberDimensionKeys(ctx, members); err != nil {
+			mfs.runOnError(ctx, "Evict", members[0], err)
+			return
+		}
+	}
+	err := mfs.write(ctx, func(client redis.UniversalClient) error {
+		_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			mfs.removeFromDimensions(ctx, pipe, dimensions)
+			pipe.Del(ctx, keys...)
+			return nil
+		})
+		return err
+	})
+	if err != nil {
+		mfs.runOnError(ctx, "Evict", members[0], err)
+	}
+}
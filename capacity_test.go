@@ -0,0 +1,55 @@
+package zmultifield
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMaxMembers(t *testing.T) {
+	for _, indexes := range []bool{false, true} {
+		mfs := newTestSetWithOptions(t, MultiFieldSetOptions{MaxMembers: 2, MaintainFieldIndexes: indexes})
+		ctx := context.Background()
+
+		for member, points := range map[string]float64{"alice": 30, "bob": 20} {
+			if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": points}, member); err != nil {
+				t.Fatalf("IncreaseScore() error = %v", err)
+			}
+		}
+		if err := mfs.SetMemberMeta(ctx, "bob", map[string]string{"name": "Bob"}); err != nil {
+			t.Fatalf("SetMemberMeta() error = %v", err)
+		}
+
+		// carol beats bob, who is evicted along with his metadata
+		result, err := mfs.IncreaseScoreWithRank(ctx, map[string]float64{"points": 25}, "carol")
+		if err != nil {
+			t.Fatalf("IncreaseScoreWithRank() error = %v", err)
+		}
+		if result.NewRank != 1 {
+			t.Errorf("indexes=%v: carol rank = %d, expected 1", indexes, result.NewRank)
+		}
+		if exists, _ := mfs.MemberExists(ctx, "bob"); exists {
+			t.Errorf("indexes=%v: bob was not evicted", indexes)
+		}
+		if meta, _ := mfs.GetMemberMeta(ctx, "bob"); len(meta) != 0 {
+			t.Errorf("indexes=%v: bob metadata = %v, expected it to be deleted", indexes, meta)
+		}
+
+		// dave doesn't make the cut and is evicted by his own update
+		result, err = mfs.IncreaseScoreWithRank(ctx, map[string]float64{"points": 5}, "dave")
+		if err != nil {
+			t.Fatalf("IncreaseScoreWithRank() error = %v", err)
+		}
+		if result.NewRank != -1 {
+			t.Errorf("indexes=%v: dave rank = %d, expected -1", indexes, result.NewRank)
+		}
+
+		if count, _ := mfs.GetCardinality(ctx); count != 2 {
+			t.Errorf("indexes=%v: GetCardinality() = %d, expected 2", indexes, count)
+		}
+		if indexes {
+			if _, err := mfs.GetFieldRank(ctx, "points", "dave"); err != ErrMemberNotFound {
+				t.Errorf("GetFieldRank(dave) error = %v, expected %v", err, ErrMemberNotFound)
+			}
+		}
+	}
+}
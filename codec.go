@@ -0,0 +1,286 @@
+package zmultifield
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Codec packs field values into a single zscore and unpacks them again, using the same layout as a
+// MultiFieldSet with the same fields. It needs no Redis client, so other services can decode stored
+// zscores offline, e.g. from a dump of the sorted set.
+type Codec struct {
+	fields        []*multiField
+	visible       []*multiField // fields returned when decoding, all but masked ones
+	fitsUint64    bool
+	defaultZScore *big.Int
+	maxScore      float64 // largest zscore the schema can produce, capped at 2^53
+}
+
+// NewCodec creates a Codec for fields, ordered from most to least significant as in
+// MultiFieldSetOptions.Fields.
+func NewCodec(fields []Field) (*Codec, error) {
+	return NewCodecWithLayout(fields, Layout{})
+}
+
+// NewCodecWithLayout creates a Codec for fields that packs them according to layout.
+func NewCodecWithLayout(fields []Field, layout Layout) (*Codec, error) {
+	if len(fields) == 0 {
+		return nil, errors.New("at least one field is required")
+	}
+	fields, err := layout.orderFields(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create multiFields from Fields
+	multiFields := make([]*multiField, len(fields))
+	for i, f := range fields {
+		if err := validateMinValue(f); err != nil {
+			return nil, err
+		}
+		if err := validateUpdateType(f); err != nil {
+			return nil, err
+		}
+		multiFields[i] = newMultiField(f)
+		if layout.DescendingAsIs {
+			multiFields[i].storeAsIs()
+		}
+	}
+	totalShifts := layout.assignShifts(multiFields)
+
+	c := &Codec{
+		fields:     multiFields,
+		visible:    multiFields,
+		fitsUint64: totalShifts <= 64,
+	}
+
+	// Calculate default zscore
+	defaultScores := make([]*big.Int, len(multiFields))
+	for i, field := range multiFields {
+		defaultScores[i] = field.defaultScore()
+	}
+	c.defaultZScore = c.scoresToZScore(defaultScores)
+
+	c.maxScore = maxExactFloat
+	if maxZScore := c.maxZScore(); maxZScore.BitLen() <= 53 {
+		c.maxScore = float64(maxZScore.Int64())
+	}
+
+	return c, nil
+}
+
+// Encode packs display values, as returned by Decode, into a zscore. Fields missing from values get
+// their default score.
+func (c *Codec) Encode(values map[string]int64) (*big.Int, error) {
+	scores := make([]FieldScore, 0, len(values))
+	for name, value := range values {
+		scores = append(scores, FieldScore{Name: name, Score: big.NewInt(value)})
+	}
+	raws, err := c.displayScoresToRaw(scores)
+	if err != nil {
+		return nil, err
+	}
+	return c.scoresToZScore(raws), nil
+}
+
+// EncodeUint64 is like Encode but returns the zscore as a uint64. It fails if the fields are wider
+// than 64 bits in total.
+func (c *Codec) EncodeUint64(values map[string]int64) (uint64, error) {
+	zscore, err := c.Encode(values)
+	if err != nil {
+		return 0, err
+	}
+	if !zscore.IsUint64() {
+		return 0, fmt.Errorf("zscore %v does not fit in a uint64", zscore)
+	}
+	return zscore.Uint64(), nil
+}
+
+// Decode unpacks a zscore into display values keyed by field name. A nil zscore decodes to the
+// default values.
+func (c *Codec) Decode(zscore *big.Int) map[string]int64 {
+	values := make(map[string]int64, len(c.visible))
+	for _, score := range c.zscoreToAllFieldScores(zscore) {
+		values[score.Name] = score.Int64()
+	}
+	return values
+}
+
+// DecodeUint64 is like Decode for a zscore held in a uint64, e.g. a score read from Redis.
+func (c *Codec) DecodeUint64(zscore uint64) map[string]int64 {
+	return c.Decode(new(big.Int).SetUint64(zscore))
+}
+
+// Bounds returns the smallest and largest display values the named field can hold.
+func (c *Codec) Bounds(name string) (min, max int64, err error) {
+	field := c.GetFieldByName(name)
+	if field == nil {
+		return 0, 0, fieldNotFoundError(name)
+	}
+	min = int64(field.MinValue)
+	return min, min + field.maxAbsolute.Int64(), nil
+}
+
+// The packed layout is capped at 53 bits by the main field, so for every realistic schema the
+// zscore fits in a uint64. The functions below pack and unpack with plain shifts and masks and
+// are used in place of the big.Int arithmetic whenever the set's total bit width is at most 64.
+
+// extract64 returns the raw value of the field stored in zscore.
+func (mf *multiField) extract64(zscore uint64) uint64 {
+	return (zscore >> mf.shiftValue) & mf.mask64
+}
+
+// display64 converts a raw field value into the value shown to callers, inverting descending fields.
+func (mf *multiField) display64(raw uint64) uint64 {
+	if mf.inverted {
+		return mf.maxAbsolute64 - raw
+	}
+	return raw
+}
+
+// scoresToZScore64 packs raw field scores into a uint64 zscore. ok is false if the set doesn't
+// fit in 64 bits or a score is negative or too wide, in which case the big.Int path must be used.
+func (c *Codec) scoresToZScore64(scores []*big.Int) (zscore uint64, ok bool) {
+	if !c.fitsUint64 {
+		return 0, false
+	}
+	for i, score := range scores {
+		if !score.IsUint64() {
+			return 0, false
+		}
+		raw := score.Uint64()
+		field := c.fields[i]
+		if raw > field.mask64 {
+			return 0, false
+		}
+		zscore |= raw << field.shiftValue
+	}
+	return zscore, true
+}
+
+// zscore64ToAllFieldScores converts a uint64 zscore to a slice of field scores.
+func (c *Codec) zscore64ToAllFieldScores(zscore uint64) []FieldScore {
+	scores := make([]FieldScore, len(c.visible))
+	values := make([]big.Int, len(c.visible))
+	for i, field := range c.visible {
+		values[i].SetUint64(field.display64(field.extract64(zscore)))
+		if field.offset != nil {
+			values[i].Add(&values[i], field.offset)
+		}
+		scores[i] = FieldScore{
+			Name:  field.Name,
+			Score: &values[i],
+		}
+	}
+	return scores
+}
+
+// GetFieldsInfo returns information about all fields.
+func (c *Codec) GetFieldsInfo() []FieldInfo {
+	info := make([]FieldInfo, len(c.fields))
+	for i, field := range c.fields {
+		info[i] = field.getInfo()
+	}
+	return info
+}
+
+// scoresToZScore combines individual field scores into a single zscore.
+func (c *Codec) scoresToZScore(scores []*big.Int) *big.Int {
+	if zscore, ok := c.scoresToZScore64(scores); ok {
+		return new(big.Int).SetUint64(zscore)
+	}
+
+	zscore := big.NewInt(0)
+	for i, score := range scores {
+		// Shift the score by the field's shift value
+		shiftedScore := new(big.Int).Lsh(score, uint(c.fields[i].shiftValue))
+		// Add to the zscore
+		zscore.Add(zscore, shiftedScore)
+	}
+	return zscore
+}
+
+// extractFieldScore extracts a field's score from a zscore.
+func (c *Codec) extractFieldScore(field *multiField, zscore *big.Int) *big.Int {
+	if zscore == nil {
+		return field.defaultScore()
+	}
+	if c.fitsUint64 && zscore.IsUint64() {
+		return new(big.Int).SetUint64(field.extract64(zscore.Uint64()))
+	}
+
+	// Apply mask to isolate the field bits
+	fieldScore := new(big.Int).And(zscore, field.mask)
+	// Shift right to get the actual value
+	fieldScore.Rsh(fieldScore, uint(field.shiftValue))
+	return fieldScore
+}
+
+// getFieldScores extracts all field scores from a zscore.
+func (c *Codec) getFieldScores(zscore *big.Int) []*big.Int {
+	scores := make([]*big.Int, len(c.fields))
+	for i, field := range c.fields {
+		scores[i] = c.extractFieldScore(field, zscore)
+	}
+	return scores
+}
+
+// maskField leaves field out of decoded scores. It is still packed and unpacked as usual.
+func (c *Codec) maskField(field *multiField) {
+	visible := c.visible
+	c.visible = nil
+	for _, f := range visible {
+		if f != field {
+			c.visible = append(c.visible, f)
+		}
+	}
+}
+
+// GetFieldByName returns a field by name or nil if not found.
+func (c *Codec) GetFieldByName(name string) *multiField {
+	for _, field := range c.fields {
+		if field.Name == name {
+			return field
+		}
+	}
+	return nil
+}
+
+// zscoreToAllFieldScores converts a zscore to a slice of field scores.
+func (c *Codec) zscoreToAllFieldScores(zscore *big.Int) []FieldScore {
+	if c.fitsUint64 && zscore != nil && zscore.IsUint64() {
+		return c.zscore64ToAllFieldScores(zscore.Uint64())
+	}
+
+	scores := make([]FieldScore, len(c.visible))
+	for i, field := range c.visible {
+		scores[i] = FieldScore{
+			Name:  field.Name,
+			Score: field.toDisplay(c.extractFieldScore(field, zscore)),
+		}
+	}
+	return scores
+}
+
+// displayScoresToRaw converts display scores, as returned by GetScores, into raw field scores.
+// Fields that aren't listed keep their default score, and computed fields are skipped.
+func (c *Codec) displayScoresToRaw(scores []FieldScore) ([]*big.Int, error) {
+	raws := c.getFieldScores(nil)
+	for _, score := range scores {
+		if score.Score == nil {
+			continue
+		}
+		field := c.GetFieldByName(score.Name)
+		if field == nil {
+			return nil, fieldNotFoundError(score.Name)
+		}
+
+		raw := field.toRaw(score.Score)
+		if raw.Sign() < 0 || raw.Cmp(field.maxAbsolute) > 0 {
+			return nil, outOfRangeError(field, raw)
+		}
+		raws[field.position] = raw
+	}
+	return raws, nil
+}
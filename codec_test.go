@@ -0,0 +1,242 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+func TestCodec_FastPathMatchesBigInt(t *testing.T) {
+	mfs, err := New(MultiFieldSetOptions{
+		Name: "codec",
+		Fields: []Field{
+			{Name: "field1", Sort: Descending, MaxValue: 100000, UpdateType: Incremental},
+			{Name: "field2", Sort: Ascending, MaxValue: 50000, UpdateType: Incremental},
+			{Name: "field3", Sort: Descending, MaxValue: 1000, UpdateType: Incremental},
+		},
+		Client: newMockRedisClient(),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create MultiFieldSet: %v", err)
+	}
+	if !mfs.fitsUint64 {
+		t.Fatalf("fitsUint64 = false, expected true")
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for n := 0; n < 1000; n++ {
+		scores := make([]*big.Int, len(mfs.fields))
+		expected := big.NewInt(0)
+		for i, field := range mfs.fields {
+			scores[i] = big.NewInt(rng.Int63n(field.maxAbsolute.Int64() + 1))
+			expected.Add(expected, new(big.Int).Lsh(scores[i], uint(field.shiftValue)))
+		}
+
+		zscore := mfs.scoresToZScore(scores)
+		if zscore.Cmp(expected) != 0 {
+			t.Fatalf("scoresToZScore(%v) = %v, expected %v", scores, zscore, expected)
+		}
+
+		var decoded MemberScores
+		mfs.DecodeInto(&decoded, "member", float64(zscore.Int64()))
+		for i, field := range mfs.fields {
+			raw := mfs.extractFieldScore(field, zscore)
+			if raw.Cmp(scores[i]) != 0 {
+				t.Fatalf("extractFieldScore(%s) = %v, expected %v", field.Name, raw, scores[i])
+			}
+
+			display := new(big.Int).Set(raw)
+			if field.Sort == Descending {
+				display.Sub(field.maxAbsolute, raw)
+			}
+			if decoded.Scores[i].Score.Cmp(display) != 0 {
+				t.Fatalf("DecodeInto %s = %v, expected %v", field.Name, decoded.Scores[i].Score, display)
+			}
+		}
+	}
+}
+
+func TestCodec_EncodeDecodeWithoutRedis(t *testing.T) {
+	codec, err := NewCodec([]Field{
+		{Name: "points", Sort: Descending, MaxValue: 1000, UpdateType: Incremental},
+		{Name: "deaths", Sort: Ascending, MaxValue: 100, UpdateType: Incremental},
+	})
+	if err != nil {
+		t.Fatalf("NewCodec failed: %v", err)
+	}
+
+	zscore, err := codec.Encode(map[string]int64{"points": 250, "deaths": 3})
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	// points is stored as 1023-250 above the 7 bits of deaths
+	if expected := int64((1023-250)<<7 + 3); zscore.Int64() != expected {
+		t.Errorf("Encode = %v, expected %d", zscore, expected)
+	}
+
+	values := codec.DecodeUint64(zscore.Uint64())
+	if values["points"] != 250 || values["deaths"] != 3 {
+		t.Errorf("DecodeUint64 = %v, expected points 250 and deaths 3", values)
+	}
+
+	defaults := codec.Decode(nil)
+	if defaults["points"] != 0 || defaults["deaths"] != 0 {
+		t.Errorf("Decode(nil) = %v, expected zero values", defaults)
+	}
+
+	if min, max, err := codec.Bounds("deaths"); err != nil || min != 0 || max != 127 {
+		t.Errorf("Bounds(deaths) = %d, %d, %v, expected 0, 127, nil", min, max, err)
+	}
+}
+
+func TestCodec_MatchesMultiFieldSet(t *testing.T) {
+	mfs := newTestSet(t)
+	codec, err := NewCodec([]Field{
+		{Name: "points", Sort: Descending, MaxValue: 1000, UpdateType: Incremental},
+		{Name: "deaths", Sort: Ascending, MaxValue: 100, UpdateType: Incremental},
+	})
+	if err != nil {
+		t.Fatalf("NewCodec failed: %v", err)
+	}
+
+	zscore, err := mfs.IncreaseScore(context.Background(), map[string]float64{"points": 42, "deaths": 7}, "alice")
+	if err != nil {
+		t.Fatalf("IncreaseScore failed: %v", err)
+	}
+	encoded, err := codec.Encode(map[string]int64{"points": 42, "deaths": 7})
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if encoded.Cmp(zscore) != 0 {
+		t.Errorf("Encode = %v, expected the stored zscore %v", encoded, zscore)
+	}
+}
+
+func TestCodec_EncodeErrors(t *testing.T) {
+	if _, err := NewCodec(nil); err == nil {
+		t.Error("NewCodec(nil) succeeded, expected an error")
+	}
+
+	codec, err := NewCodec([]Field{{Name: "points", Sort: Ascending, MaxValue: 100, UpdateType: Incremental}})
+	if err != nil {
+		t.Fatalf("NewCodec failed: %v", err)
+	}
+	if _, err := codec.Encode(map[string]int64{"missing": 1}); !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("Encode with unknown field = %v, expected ErrFieldNotFound", err)
+	}
+	if _, err := codec.Encode(map[string]int64{"points": 500}); !errors.Is(err, ErrScoreOutOfRange) {
+		t.Errorf("Encode out of range = %v, expected ErrScoreOutOfRange", err)
+	}
+	if _, _, err := codec.Bounds("missing"); !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("Bounds with unknown field = %v, expected ErrFieldNotFound", err)
+	}
+}
+
+func TestCodec_MinValue(t *testing.T) {
+	codec, err := NewCodec([]Field{
+		{Name: "rating", Sort: Descending, MinValue: 1000, MaxValue: 5000, UpdateType: Replace},
+		{Name: "delta", Sort: Ascending, MinValue: -50, MaxValue: 50, UpdateType: Incremental},
+	})
+	if err != nil {
+		t.Fatalf("NewCodec failed: %v", err)
+	}
+	// The span of 4000 takes 12 bits instead of the 13 of 5000
+	if info := codec.GetFieldsInfo(); info[0].Bits != 12 || info[1].Bits != 7 {
+		t.Errorf("Bits = %d, %d, expected 12 and 7", info[0].Bits, info[1].Bits)
+	}
+	if min, max, err := codec.Bounds("rating"); err != nil || min != 1000 || max != 1000+4095 {
+		t.Errorf("Bounds(rating) = %d, %d, %v, expected 1000, 5095, nil", min, max, err)
+	}
+
+	zscore, err := codec.Encode(map[string]int64{"rating": 1500, "delta": -20})
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if values := codec.Decode(zscore); values["rating"] != 1500 || values["delta"] != -20 {
+		t.Errorf("Decode = %v, expected rating 1500 and delta -20", values)
+	}
+	if defaults := codec.Decode(nil); defaults["rating"] != 1000 || defaults["delta"] != -50 {
+		t.Errorf("Decode(nil) = %v, expected the minimums", defaults)
+	}
+
+	_, err = codec.Encode(map[string]int64{"rating": 999})
+	var rangeErr *ScoreOutOfRangeError
+	if !errors.As(err, &rangeErr) || rangeErr.Value.Int64() != 999 || rangeErr.Min.Int64() != 1000 {
+		t.Errorf("Encode below MinValue = %v, expected a ScoreOutOfRangeError with min 1000", err)
+	}
+
+	for _, field := range []Field{
+		{Name: "f", MinValue: 10, MaxValue: 10},
+		{Name: "f", MinValue: 1.5, MaxValue: 10},
+	} {
+		if _, err := NewCodec([]Field{field}); err == nil {
+			t.Errorf("NewCodec(%+v) succeeded, expected an error", field)
+		}
+	}
+}
+
+func TestMinValueUpdatesAndRanges(t *testing.T) {
+	client, _ := newTestClient(t)
+	mfs, err := New(MultiFieldSetOptions{
+		Name: "ratings",
+		Fields: []Field{
+			{Name: "rating", Sort: Descending, MinValue: 1000, MaxValue: 5000, UpdateType: Replace},
+			{Name: "wins", Sort: Descending, MinValue: 10, MaxValue: 100, UpdateType: Incremental},
+		},
+		Client: client,
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	ctx := context.Background()
+
+	for member, rating := range map[string]float64{"alice": 1800, "bob": 1200, "carol": 3000} {
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"rating": rating, "wins": 1}, member); err != nil {
+			t.Fatalf("IncreaseScore(%s) failed: %v", member, err)
+		}
+	}
+	scores, err := mfs.GetScores(ctx, "alice")
+	if err != nil || FieldValue(scores, "rating") != 1800 || FieldValue(scores, "wins") != 11 {
+		t.Errorf("GetScores(alice) = %v, %v, expected rating 1800 and wins 11", scores, err)
+	}
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"rating": 900}, "alice"); !errors.Is(err, ErrScoreOutOfRange) {
+		t.Errorf("IncreaseScore below MinValue = %v, expected ErrScoreOutOfRange", err)
+	}
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"wins": -2}, "bob"); !errors.Is(err, ErrScoreOutOfRange) {
+		t.Errorf("IncreaseScore below MinValue = %v, expected ErrScoreOutOfRange", err)
+	}
+
+	members, err := mfs.GetTopMembers(ctx, 3)
+	if err != nil || len(members) != 3 || members[0].Member != "carol" || members[2].Member != "bob" {
+		t.Errorf("GetTopMembers() = %v, %v, expected carol first and bob last", members, err)
+	}
+	inRange, err := mfs.GetMembersByFieldRange(ctx, "rating", 1500, 2000, 0, 0)
+	if err != nil || len(inRange) != 1 || inRange[0].Member != "alice" {
+		t.Errorf("GetMembersByFieldRange(1500, 2000) = %v, %v, expected alice", inRange, err)
+	}
+	if n, err := mfs.Count(ctx, F("rating").LT(1500)); err != nil || n != 1 {
+		t.Errorf("Count(rating < 1500) = %d, %v, expected 1", n, err)
+	}
+	if n, err := mfs.CountByFieldAtLeast(ctx, "rating", 0); err != nil || n != 3 {
+		t.Errorf("CountByFieldAtLeast(rating, 0) = %d, %v, expected 3", n, err)
+	}
+
+	// Missing members have the minimums, not the raw defaults
+	scores, err = mfs.GetScores(ctx, "nobody")
+	if err != nil || FieldValue(scores, "rating") != 1000 || FieldValue(scores, "wins") != 10 {
+		t.Errorf("GetScores(nobody) = %v, %v, expected rating 1000 and wins 10", scores, err)
+	}
+	if score, err := mfs.GetScoreForField(ctx, "rating", "nobody"); err != nil || score.Int64() != 1000 {
+		t.Errorf("GetScoreForField(rating, nobody) = %v, %v, expected 1000", score, err)
+	}
+
+	bound, err := mfs.MaxScoreWithFields(map[string]float64{"rating": 1050, "wins": 20})
+	if err != nil {
+		t.Fatalf("MaxScoreWithFields() error = %v", err)
+	}
+	if values := mfs.CalculateScoresFromZScore(bound); values["rating"].Int64() != 1050 || values["wins"].Int64() != 20 {
+		t.Errorf("MaxScoreWithFields(rating 1050, wins 20) decodes as %v", values)
+	}
+}
@@ -0,0 +1,128 @@
+package zmultifield
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Comparison is the result of Compare.
+type Comparison struct {
+	// OnlyInSet lists the members of the set that are missing from the other set, in the set's order.
+	OnlyInSet []string
+	// OnlyInOther lists the members of the other set that are missing from the set, in the other
+	// set's order.
+	OnlyInOther []string
+	// Mismatched lists the members of both sets whose decoded scores differ, in the set's order.
+	Mismatched []ScoreMismatch
+}
+
+// ScoreMismatch describes a member whose decoded scores differ between two sets.
+type ScoreMismatch struct {
+	Member string
+	// Values holds the member's display values in the set compared from.
+	Values map[string]int64
+	// OtherValues holds its display values in the other set.
+	OtherValues map[string]int64
+}
+
+// Equal reports whether both sets hold the same members with the same scores.
+func (c *Comparison) Equal() bool {
+	return len(c.OnlyInSet) == 0 && len(c.OnlyInOther) == 0 && len(c.Mismatched) == 0
+}
+
+// Compare lists the members present in only one of the set and other and the members whose
+// decoded scores differ, e.g. to verify a dual-write migration or reconcile a replica. Scores are
+// compared on the display values of the fields both sets have, so sets with different layouts or
+// additional fields can be compared; sets without a common field fail with ErrIncompatibleSets.
+// Both sets are walked in batches, so members written during the comparison may be reported.
+func (mfs *MultiFieldSet) Compare(ctx context.Context, other *MultiFieldSet) (_ *Comparison, err error) {
+	defer mfs.observeRead("Compare", time.Now(), &err)
+
+	var common []string
+	for _, field := range mfs.fields {
+		if other.GetFieldByName(field.Name) != nil {
+			common = append(common, field.Name)
+		}
+	}
+	if len(common) == 0 {
+		return nil, mfs.runOnError(ctx, "Compare", "", fmt.Errorf("%w: %s has no field in common", ErrIncompatibleSets, other.name))
+	}
+
+	c := &Comparison{}
+	err = mfs.compareBatches(ctx, other, func(member string, zscore, otherZScore *big.Int) {
+		if otherZScore == nil {
+			c.OnlyInSet = append(c.OnlyInSet, member)
+			return
+		}
+		values, otherValues := mfs.Decode(zscore), other.Decode(otherZScore)
+		for _, name := range common {
+			if values[name] != otherValues[name] {
+				c.Mismatched = append(c.Mismatched, ScoreMismatch{Member: member, Values: values, OtherValues: otherValues})
+				return
+			}
+		}
+	})
+	if err == nil {
+		err = other.compareBatches(ctx, mfs, func(member string, _, zscore *big.Int) {
+			if zscore == nil {
+				c.OnlyInOther = append(c.OnlyInOther, member)
+			}
+		})
+	}
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "Compare", "", err)
+	}
+	return c, nil
+}
+
+// compareBatches walks the set in batches of scanBatchSize, looks up the members of each batch in
+// other with one pipeline and calls fn with every member's zscore in both sets, nil if the member
+// is missing from other.
+func (mfs *MultiFieldSet) compareBatches(ctx context.Context, other *MultiFieldSet, fn func(member string, zscore, otherZScore *big.Int)) error {
+	for start := int64(0); ; start += scanBatchSize {
+		var results []redis.Z
+		err := mfs.read(ctx, func(client redis.UniversalClient) error {
+			var err error
+			results, err = client.ZRangeWithScores(ctx, mfs.key, start, start+scanBatchSize-1).Result()
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		cmds := make([]*redis.FloatCmd, len(results))
+		if len(results) > 0 {
+			err = other.read(ctx, func(client redis.UniversalClient) error {
+				_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+					for i, z := range results {
+						cmds[i] = pipe.ZScore(ctx, other.key, memberName(z))
+					}
+					return nil
+				})
+				if err == redis.Nil {
+					err = nil
+				}
+				return err
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		for i, z := range results {
+			var otherZScore *big.Int
+			if cmds[i].Err() == nil {
+				otherZScore = new(big.Int).SetInt64(int64(cmds[i].Val()))
+			}
+			fn(memberName(z), new(big.Int).SetInt64(int64(z.Score)), otherZScore)
+		}
+
+		if len(results) < scanBatchSize {
+			return nil
+		}
+	}
+}
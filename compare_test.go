@@ -0,0 +1,77 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	client, _ := newTestClient(t)
+	fields := []Field{
+		{Name: "points", Sort: Descending, MaxValue: 1000, UpdateType: Incremental},
+		{Name: "deaths", Sort: Ascending, MaxValue: 100, UpdateType: Incremental},
+	}
+	old, err := New(MultiFieldSetOptions{Name: "old", Fields: fields, Client: client})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	// The migrated set packs the same fields differently
+	migrated, err := New(MultiFieldSetOptions{Name: "new", Fields: fields, Client: client, Layout: Layout{LeastSignificantFirst: true}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	ctx := context.Background()
+
+	for _, w := range []struct {
+		mfs    *MultiFieldSet
+		member string
+		points float64
+	}{
+		{old, "alice", 10}, {migrated, "alice", 10},
+		{old, "bob", 20}, {migrated, "bob", 25},
+		{old, "carol", 30},
+		{migrated, "dave", 40},
+	} {
+		if _, err := w.mfs.IncreaseScore(ctx, map[string]float64{"points": w.points, "deaths": 1}, w.member); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+	}
+
+	c, err := old.Compare(ctx, migrated)
+	if err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+	if c.Equal() {
+		t.Error("Equal() = true, expected false")
+	}
+	if len(c.OnlyInSet) != 1 || c.OnlyInSet[0] != "carol" {
+		t.Errorf("OnlyInSet = %v, expected [carol]", c.OnlyInSet)
+	}
+	if len(c.OnlyInOther) != 1 || c.OnlyInOther[0] != "dave" {
+		t.Errorf("OnlyInOther = %v, expected [dave]", c.OnlyInOther)
+	}
+	if len(c.Mismatched) != 1 || c.Mismatched[0].Member != "bob" ||
+		c.Mismatched[0].Values["points"] != 20 || c.Mismatched[0].OtherValues["points"] != 25 {
+		t.Errorf("Mismatched = %+v, expected bob with 20 and 25 points", c.Mismatched)
+	}
+
+	if c, err := old.Compare(ctx, old); err != nil || !c.Equal() {
+		t.Errorf("Compare() with itself = %+v, %v, expected equal", c, err)
+	}
+}
+
+func TestCompareIncompatible(t *testing.T) {
+	client, _ := newTestClient(t)
+	mfs, err := New(MultiFieldSetOptions{Name: "a", Client: client, Fields: []Field{{Name: "points", MaxValue: 10}}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	other, err := New(MultiFieldSetOptions{Name: "b", Client: client, Fields: []Field{{Name: "kills", MaxValue: 10}}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := mfs.Compare(context.Background(), other); !errors.Is(err, ErrIncompatibleSets) {
+		t.Errorf("Compare() error = %v, expected ErrIncompatibleSets", err)
+	}
+}
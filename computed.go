@@ -0,0 +1,85 @@
+package zmultifield
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ComputedField is a virtual field computed from a member's stored fields at read time, e.g. a
+// kill/death ratio. It takes no bits in the score, so it can't be updated, sorted or filtered on.
+// Reads returning scores, such as GetScores, GetMembers and GetTopMembers, list computed fields
+// after the stored ones, with a nil Score and the computed Value.
+type ComputedField struct {
+	Name string
+	// Compute returns the field's value from the member's stored field scores. Values that aren't
+	// finite, such as the result of a division by zero, are read as 0.
+	Compute func(scores []FieldScore) float64
+}
+
+// Ratio returns a field computing numerator / denominator, e.g. Ratio("kdRatio", "kills",
+// "deaths") or Ratio("winRate", "wins", "games"). A denominator of 0 counts as 1, so a player
+// without deaths has a kill/death ratio equal to their kills.
+func Ratio(name, numerator, denominator string) ComputedField {
+	return ComputedField{
+		Name: name,
+		Compute: func(scores []FieldScore) float64 {
+			d := FieldValue(scores, denominator)
+			if d == 0 {
+				d = 1
+			}
+			return FieldValue(scores, numerator) / d
+		},
+	}
+}
+
+// FieldValue returns the value of the named field in scores, or 0 if it isn't listed.
+func FieldValue(scores []FieldScore, name string) float64 {
+	for _, score := range scores {
+		if score.Name == name {
+			return score.Float64()
+		}
+	}
+	return 0
+}
+
+// validateComputedFields checks that every computed field has a unique name, distinct from the
+// stored fields of codec, and a Compute function.
+func validateComputedFields(codec *Codec, computed []ComputedField) error {
+	seen := make(map[string]bool, len(computed))
+	for _, field := range computed {
+		if field.Name == "" {
+			return errors.New("computed field name is required")
+		}
+		if field.Compute == nil {
+			return fmt.Errorf("computed field %s has no Compute function", field.Name)
+		}
+		if seen[field.Name] || codec.GetFieldByName(field.Name) != nil {
+			return fmt.Errorf("duplicate field name %s", field.Name)
+		}
+		seen[field.Name] = true
+	}
+	return nil
+}
+
+// computeInto sets the computed fields at the end of scores from the stored fields before them.
+func (mfs *MultiFieldSet) computeInto(scores []FieldScore) {
+	stored := scores[:len(scores)-len(mfs.computed)]
+	for i, field := range mfs.computed {
+		value := field.Compute(stored)
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			value = 0
+		}
+		scores[len(stored)+i] = FieldScore{Name: field.Name, Value: value}
+	}
+}
+
+// withComputed returns stored scores followed by the set's computed fields.
+func (mfs *MultiFieldSet) withComputed(scores []FieldScore) []FieldScore {
+	if len(mfs.computed) == 0 {
+		return scores
+	}
+	scores = append(scores, make([]FieldScore, len(mfs.computed))...)
+	mfs.computeInto(scores)
+	return scores
+}
@@ -0,0 +1,76 @@
+package zmultifield
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestComputedFields(t *testing.T) {
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{
+		ComputedFields: []ComputedField{Ratio("ratio", "points", "deaths")},
+		Cache:          &CacheOptions{},
+	})
+	ctx := context.Background()
+
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 30, "deaths": 4}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 20}, "bob"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+
+	// Twice, the second time from the cache
+	for i := 0; i < 2; i++ {
+		scores, err := mfs.GetScores(ctx, "alice")
+		if err != nil {
+			t.Fatalf("GetScores() error = %v", err)
+		}
+		if len(scores) != 3 || scores[2].Name != "ratio" || scores[2].Score != nil || scores[2].Float64() != 7.5 {
+			t.Errorf("GetScores() = %v, expected ratio 7.5 after the stored fields", scores)
+		}
+	}
+	// Missing members get the ratio of the default scores
+	if scores, err := mfs.GetScores(ctx, "nobody"); err != nil || len(scores) != 3 || FieldValue(scores, "ratio") != FieldValue(scores, "points") {
+		t.Errorf("GetScores(nobody) = %v, %v, expected the default points as ratio", scores, err)
+	}
+
+	members, err := mfs.GetTopMembers(ctx, 10)
+	if err != nil {
+		t.Fatalf("GetTopMembers() error = %v", err)
+	}
+	// bob has no deaths, so his ratio is his points
+	if len(members) != 2 || FieldValue(members[0].Scores, "ratio") != 7.5 || FieldValue(members[1].Scores, "ratio") != 20 {
+		t.Errorf("GetTopMembers() = %v, expected ratios 7.5 and 20", members)
+	}
+
+	data, err := json.Marshal(members[0])
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if got, want := string(data), `{"member":"alice","scores":{"deaths":4,"points":30,"ratio":7.5}}`; got != want {
+		t.Errorf("Marshal() = %s, expected %s", got, want)
+	}
+
+	// Scores read with computed fields can be written back
+	result, err := mfs.BulkLoad(ctx, SliceIterator(members), 10, 1, nil)
+	if err != nil || len(result.Failures) > 0 {
+		t.Errorf("BulkLoad() = %+v, %v, expected no failures", result, err)
+	}
+}
+
+func TestComputedFieldsValidation(t *testing.T) {
+	client, _ := newTestClient(t)
+	fields := []Field{{Name: "points", Sort: Descending, MaxValue: 1000, UpdateType: Incremental}}
+
+	for name, computed := range map[string][]ComputedField{
+		"stored name": {Ratio("points", "points", "points")},
+		"duplicate":   {Ratio("ratio", "points", "points"), Ratio("ratio", "points", "points")},
+		"no name":     {{Compute: func([]FieldScore) float64 { return 0 }}},
+		"no function": {{Name: "ratio"}},
+	} {
+		if _, err := New(MultiFieldSetOptions{Name: "test", Fields: fields, Client: client, ComputedFields: computed}); err == nil {
+			t.Errorf("New() with %s succeeded, expected an error", name)
+		}
+	}
+}
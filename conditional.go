@@ -0,0 +1,45 @@
+package zmultifield
+
+import (
+	"context"
+	"math/big"
+	"time"
+)
+
+// writeMode selects when a member is written, like ZADD's NX and XX flags. It is passed to the
+// write script as is.
+type writeMode string
+
+const (
+	// writeAlways adds or updates the member.
+	writeAlways writeMode = ""
+	// writeIfAbsent only adds new members (NX).
+	writeIfAbsent writeMode = "NX"
+	// writeIfExists only updates existing members (XX).
+	writeIfExists writeMode = "XX"
+)
+
+// writeIfZScore only updates the member if its zscore is still zscore.
+func writeIfZScore(zscore *big.Int) writeMode {
+	return writeMode("=" + zscore.String())
+}
+
+// String returns the mode as used by the write script.
+func (m writeMode) String() string {
+	return string(m)
+}
+
+// InitializeMember adds a member with default scores if it isn't in the set yet and reports
+// whether it was added. Unlike ResetMember it never overwrites an existing member.
+func (mfs *MultiFieldSet) InitializeMember(ctx context.Context, member string) (_ bool, err error) {
+	defer mfs.observeUpdate("InitializeMember", time.Now(), &err)
+
+	scores := mfs.getFieldScores(nil)
+	mfs.stampUpdatedAt(scores)
+	mfs.stampSalt(scores, member)
+	added, err := mfs.writeMember(ctx, member, scores, mfs.scoresToZScore(scores), writeIfAbsent)
+	if err != nil {
+		return false, mfs.runOnError(ctx, "InitializeMember", member, err)
+	}
+	return added, nil
+}
@@ -0,0 +1,56 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestInitializeMember(t *testing.T) {
+	for _, indexes := range []bool{false, true} {
+		mfs := newTestSetWithOptions(t, MultiFieldSetOptions{MaintainFieldIndexes: indexes})
+		ctx := context.Background()
+
+		added, err := mfs.InitializeMember(ctx, "alice")
+		if err != nil || !added {
+			t.Fatalf("indexes=%v: InitializeMember() = %v, %v, expected true, nil", indexes, added, err)
+		}
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 10}, "alice"); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+
+		added, err = mfs.InitializeMember(ctx, "alice")
+		if err != nil || added {
+			t.Fatalf("indexes=%v: second InitializeMember() = %v, %v, expected false, nil", indexes, added, err)
+		}
+		points, err := mfs.GetScoreForField(ctx, "points", "alice")
+		if err != nil {
+			t.Fatalf("GetScoreForField() error = %v", err)
+		}
+		if points.Int64() != 10 {
+			t.Errorf("indexes=%v: points = %v, expected 10 to survive InitializeMember", indexes, points)
+		}
+	}
+}
+
+func TestUpdateOnlyExisting(t *testing.T) {
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{UpdateOnlyExisting: true})
+	ctx := context.Background()
+
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 10}, "alice"); !errors.Is(err, ErrMemberNotFound) {
+		t.Fatalf("IncreaseScore() on a new member error = %v, expected %v", err, ErrMemberNotFound)
+	}
+	if exists, _ := mfs.MemberExists(ctx, "alice"); exists {
+		t.Fatalf("IncreaseScore() added a new member")
+	}
+
+	if _, err := mfs.InitializeMember(ctx, "alice"); err != nil {
+		t.Fatalf("InitializeMember() error = %v", err)
+	}
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 10}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore() on an existing member error = %v", err)
+	}
+	if points, _ := mfs.GetScoreForField(ctx, "points", "alice"); points.Int64() != 10 {
+		t.Errorf("points = %v, expected 10", points)
+	}
+}
@@ -0,0 +1,450 @@
+// Package config builds MultiFieldSets from declarative YAML or JSON definitions, so leaderboard
+// schemas can live in configuration rather than in Go code.
+//
+// A configuration lists sets by name with their fields and options:
+//
+//	sets:
+//	  - name: leaderboard
+//	    namespace: prod
+//	    maintain_field_indexes: true
+//	    history:
+//	      max_entries: 100
+//	      ttl: 720h
+//	    fields:
+//	      - name: points
+//	        sort: desc
+//	        max_value: inf
+//	      - name: deaths
+//	        sort: asc
+//	        max_value: 1000
+//
+// Load applies overrides from environment variables prefixed with ZMF, see ApplyEnv.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	zmultifield "github.com/Rohan-Muslekar/ZMultiField"
+	"github.com/go-redis/redis/v8"
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultEnvPrefix is the prefix of the environment variables applied by Load.
+const DefaultEnvPrefix = "ZMF"
+
+// Format is the encoding of a configuration.
+type Format int
+
+const (
+	// YAML is a YAML document.
+	YAML Format = iota
+	// JSON is a JSON document.
+	JSON
+)
+
+// Config is a set of MultiFieldSet definitions.
+type Config struct {
+	Sets []SetConfig `yaml:"sets" json:"sets"`
+}
+
+// SetConfig defines one MultiFieldSet. Options map to the fields of MultiFieldSetOptions of the
+// same name.
+type SetConfig struct {
+	Name                 string        `yaml:"name" json:"name"`
+	Namespace            string        `yaml:"namespace" json:"namespace"`
+	Fields               []FieldConfig `yaml:"fields" json:"fields"`
+	MaintainFieldIndexes bool          `yaml:"maintain_field_indexes" json:"maintain_field_indexes"`
+	UpdateOnlyExisting   bool          `yaml:"update_only_existing" json:"update_only_existing"`
+	TrackUpdatedAt       bool          `yaml:"track_updated_at" json:"track_updated_at"`
+	MaxMembers           int64         `yaml:"max_members" json:"max_members"`
+	RankThresholds       []int64       `yaml:"rank_thresholds" json:"rank_thresholds"`
+	// KeyBuilder is "default" or "hashtag", for Redis Cluster deployments. Empty means "default".
+	KeyBuilder    string               `yaml:"key_builder" json:"key_builder"`
+	Layout        LayoutConfig         `yaml:"layout" json:"layout"`
+	History       *HistoryConfig       `yaml:"history" json:"history"`
+	Notifications *NotificationsConfig `yaml:"notifications" json:"notifications"`
+}
+
+// FieldConfig defines one field of a set.
+type FieldConfig struct {
+	Name string `yaml:"name" json:"name"`
+	// Sort is "asc" or "desc".
+	Sort string `yaml:"sort" json:"sort"`
+	// MaxValue is the field's maximum, or "inf" for an unbounded field.
+	MaxValue MaxValue `yaml:"max_value" json:"max_value"`
+	// MinValue is the field's minimum, see zmultifield.Field.MinValue.
+	MinValue float64 `yaml:"min_value" json:"min_value"`
+	// Priority places the field explicitly, see zmultifield.Field.Priority.
+	Priority int `yaml:"priority" json:"priority"`
+	// MaxSamples sizes the count of an Average field, see zmultifield.Field.MaxSamples.
+	MaxSamples float64 `yaml:"max_samples" json:"max_samples"`
+	// Update is "incremental", "replace", "keep_max", "keep_min", "bit_or" or "average". Empty
+	// means "incremental".
+	Update   string   `yaml:"update" json:"update"`
+	HalfLife Duration `yaml:"half_life" json:"half_life"`
+}
+
+// LayoutConfig mirrors zmultifield.Layout.
+type LayoutConfig struct {
+	LeastSignificantFirst bool `yaml:"least_significant_first" json:"least_significant_first"`
+	DescendingAsIs        bool `yaml:"descending_as_is" json:"descending_as_is"`
+}
+
+// HistoryConfig mirrors zmultifield.HistoryOptions.
+type HistoryConfig struct {
+	MaxEntries int64    `yaml:"max_entries" json:"max_entries"`
+	TTL        Duration `yaml:"ttl" json:"ttl"`
+}
+
+// NotificationsConfig mirrors zmultifield.NotificationOptions.
+type NotificationsConfig struct {
+	Channel string `yaml:"channel" json:"channel"`
+	TopN    int64  `yaml:"top_n" json:"top_n"`
+}
+
+// MaxValue is a field maximum written as a number or as "inf" for an unbounded field. A missing
+// value is zero, which Validate rejects, so unbounded fields are always explicit.
+type MaxValue float64
+
+// parse converts a number or "inf" into a MaxValue.
+func (m *MaxValue) parse(v interface{}) error {
+	switch v := v.(type) {
+	case int:
+		*m = MaxValue(v)
+	case float64:
+		*m = MaxValue(v)
+	case string:
+		if v != "inf" {
+			return fmt.Errorf("invalid max_value %q, expected a number or \"inf\"", v)
+		}
+		*m = MaxValue(math.Inf(1))
+	default:
+		return fmt.Errorf("invalid max_value %v, expected a number or \"inf\"", v)
+	}
+	return nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (m *MaxValue) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var v interface{}
+	if err := unmarshal(&v); err != nil {
+		return err
+	}
+	return m.parse(v)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (m *MaxValue) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	return m.parse(v)
+}
+
+// Duration is a time.Duration written as a string such as "24h" or "90m".
+type Duration time.Duration
+
+// parse converts a duration string into a Duration.
+func (d *Duration) parse(s string) error {
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	return d.parse(s)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	return d.parse(s)
+}
+
+// Load reads the configuration file at path, choosing the format by its extension (.yaml, .yml or
+// .json), applies environment overrides with DefaultEnvPrefix and validates it.
+func Load(path string) (*Config, error) {
+	var format Format
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		format = YAML
+	case ".json":
+		format = JSON
+	default:
+		return nil, fmt.Errorf("unknown configuration format %q", ext)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := decode(data, format)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.ApplyEnv(DefaultEnvPrefix, os.LookupEnv); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Parse decodes and validates a configuration without applying environment overrides.
+func Parse(data []byte, format Format) (*Config, error) {
+	cfg, err := decode(data, format)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// decode unmarshals data, rejecting unknown keys so typos in option names aren't ignored.
+func decode(data []byte, format Format) (*Config, error) {
+	cfg := &Config{}
+	switch format {
+	case YAML:
+		if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+			return nil, err
+		}
+	case JSON:
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(cfg); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown configuration format %d", format)
+	}
+	return cfg, nil
+}
+
+// ApplyEnv overrides options with environment variables, looked up with lookup, e.g. to point
+// the same configuration at another namespace per deployment. <prefix>_NAMESPACE applies to every
+// set; <prefix>_<SET>_<OPTION> applies to one set, where SET is the set name in upper case with
+// every other character than letters and digits replaced by an underscore and OPTION is one of
+// NAMESPACE, MAX_MEMBERS, MAINTAIN_FIELD_INDEXES, UPDATE_ONLY_EXISTING or TRACK_UPDATED_AT.
+func (c *Config) ApplyEnv(prefix string, lookup func(key string) (string, bool)) error {
+	if namespace, ok := lookup(prefix + "_NAMESPACE"); ok {
+		for i := range c.Sets {
+			c.Sets[i].Namespace = namespace
+		}
+	}
+
+	for i := range c.Sets {
+		set := &c.Sets[i]
+		setPrefix := prefix + "_" + envName(set.Name) + "_"
+		if v, ok := lookup(setPrefix + "NAMESPACE"); ok {
+			set.Namespace = v
+		}
+		if v, ok := lookup(setPrefix + "MAX_MEMBERS"); ok {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return fmt.Errorf("%sMAX_MEMBERS: %w", setPrefix, err)
+			}
+			set.MaxMembers = n
+		}
+		for _, option := range []struct {
+			name  string
+			value *bool
+		}{
+			{"MAINTAIN_FIELD_INDEXES", &set.MaintainFieldIndexes},
+			{"UPDATE_ONLY_EXISTING", &set.UpdateOnlyExisting},
+			{"TRACK_UPDATED_AT", &set.TrackUpdatedAt},
+		} {
+			if v, ok := lookup(setPrefix + option.name); ok {
+				b, err := strconv.ParseBool(v)
+				if err != nil {
+					return fmt.Errorf("%s%s: %w", setPrefix, option.name, err)
+				}
+				*option.value = b
+			}
+		}
+	}
+	return nil
+}
+
+// envName converts a set name into the form used in environment variable names.
+func envName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
+
+// Validate checks every set definition and returns the first problem found, prefixed with the
+// set's name.
+func (c *Config) Validate() error {
+	if len(c.Sets) == 0 {
+		return errors.New("no sets defined")
+	}
+	seen := make(map[string]bool, len(c.Sets))
+	for i, set := range c.Sets {
+		if set.Name == "" {
+			return fmt.Errorf("sets[%d]: name is required", i)
+		}
+		if seen[set.Name] {
+			return fmt.Errorf("set %s: defined more than once", set.Name)
+		}
+		seen[set.Name] = true
+		if _, err := set.Options(nil); err != nil {
+			return fmt.Errorf("set %s: %w", set.Name, err)
+		}
+	}
+	return nil
+}
+
+// Options converts the definition into MultiFieldSetOptions using client, so callers can add
+// settings that can't be configured declaratively, such as metrics or a read client, before
+// calling zmultifield.New.
+func (s SetConfig) Options(client redis.UniversalClient) (zmultifield.MultiFieldSetOptions, error) {
+	opts := zmultifield.MultiFieldSetOptions{
+		Name:                 s.Name,
+		Namespace:            s.Namespace,
+		Client:               client,
+		MaintainFieldIndexes: s.MaintainFieldIndexes,
+		UpdateOnlyExisting:   s.UpdateOnlyExisting,
+		TrackUpdatedAt:       s.TrackUpdatedAt,
+		MaxMembers:           s.MaxMembers,
+		RankThresholds:       s.RankThresholds,
+		Layout: zmultifield.Layout{
+			LeastSignificantFirst: s.Layout.LeastSignificantFirst,
+			DescendingAsIs:        s.Layout.DescendingAsIs,
+		},
+	}
+	if s.MaxMembers < 0 {
+		return opts, errors.New("max_members must not be negative")
+	}
+
+	switch s.KeyBuilder {
+	case "", "default":
+	case "hashtag":
+		opts.KeyBuilder = zmultifield.HashTagKeyBuilder{}
+	default:
+		return opts, fmt.Errorf("unknown key_builder %q, expected \"default\" or \"hashtag\"", s.KeyBuilder)
+	}
+
+	if s.History != nil {
+		if s.History.MaxEntries < 0 || s.History.TTL < 0 {
+			return opts, errors.New("history max_entries and ttl must not be negative")
+		}
+		opts.History = &zmultifield.HistoryOptions{MaxEntries: s.History.MaxEntries, TTL: time.Duration(s.History.TTL)}
+	}
+	if s.Notifications != nil {
+		opts.Notifications = &zmultifield.NotificationOptions{Channel: s.Notifications.Channel, TopN: s.Notifications.TopN}
+	}
+
+	if len(s.Fields) == 0 {
+		return opts, errors.New("at least one field is required")
+	}
+	names := make(map[string]bool, len(s.Fields))
+	for _, f := range s.Fields {
+		field, err := f.field()
+		if err != nil {
+			return opts, err
+		}
+		if names[field.Name] {
+			return opts, fmt.Errorf("field %s: defined more than once", field.Name)
+		}
+		names[field.Name] = true
+		opts.Fields = append(opts.Fields, field)
+	}
+	return opts, nil
+}
+
+// field converts the definition into a zmultifield.Field.
+func (f FieldConfig) field() (zmultifield.Field, error) {
+	field := zmultifield.Field{
+		Name:       f.Name,
+		MaxValue:   float64(f.MaxValue),
+		MinValue:   f.MinValue,
+		Priority:   f.Priority,
+		MaxSamples: f.MaxSamples,
+		HalfLife:   time.Duration(f.HalfLife),
+	}
+	if f.Name == "" {
+		return field, errors.New("field name is required")
+	}
+
+	switch f.Sort {
+	case "asc":
+		field.Sort = zmultifield.Ascending
+	case "desc":
+		field.Sort = zmultifield.Descending
+	default:
+		return field, fmt.Errorf("field %s: unknown sort %q, expected \"asc\" or \"desc\"", f.Name, f.Sort)
+	}
+
+	switch f.Update {
+	case "", "incremental":
+		field.UpdateType = zmultifield.Incremental
+	case "replace":
+		field.UpdateType = zmultifield.Replace
+	case "keep_max":
+		field.UpdateType = zmultifield.KeepMax
+	case "keep_min":
+		field.UpdateType = zmultifield.KeepMin
+	case "bit_or":
+		field.UpdateType = zmultifield.BitOr
+	case "average":
+		field.UpdateType = zmultifield.Average
+	default:
+		return field, fmt.Errorf("field %s: unknown update %q, expected \"incremental\", \"replace\", \"keep_max\", \"keep_min\", \"bit_or\" or \"average\"", f.Name, f.Update)
+	}
+
+	if !(field.MaxValue > 0) {
+		return field, fmt.Errorf("field %s: max_value must be positive or \"inf\"", f.Name)
+	}
+	if field.HalfLife < 0 {
+		return field, fmt.Errorf("field %s: half_life must not be negative", f.Name)
+	}
+	return field, nil
+}
+
+// Build creates every configured set with client and returns them by name.
+func (c *Config) Build(client redis.UniversalClient) (map[string]*zmultifield.MultiFieldSet, error) {
+	sets := make(map[string]*zmultifield.MultiFieldSet, len(c.Sets))
+	for _, s := range c.Sets {
+		opts, err := s.Options(client)
+		if err != nil {
+			return nil, fmt.Errorf("set %s: %w", s.Name, err)
+		}
+		set, err := zmultifield.New(opts)
+		if err != nil {
+			return nil, fmt.Errorf("set %s: %w", s.Name, err)
+		}
+		sets[s.Name] = set
+	}
+	return sets, nil
+}
@@ -0,0 +1,183 @@
+package config
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	zmultifield "github.com/Rohan-Muslekar/ZMultiField"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+const testYAML = `
+sets:
+  - name: weekly-points
+    namespace: dev
+    maintain_field_indexes: true
+    max_members: 100
+    history:
+      max_entries: 10
+      ttl: 24h
+    fields:
+      - name: points
+        sort: desc
+        max_value: inf
+      - name: deaths
+        sort: asc
+        max_value: 1000
+        update: replace
+  - name: clans
+    key_builder: hashtag
+    fields:
+      - name: score
+        sort: desc
+        max_value: 65535
+        half_life: 168h
+`
+
+func TestParseYAML(t *testing.T) {
+	cfg, err := Parse([]byte(testYAML), YAML)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(cfg.Sets) != 2 {
+		t.Fatalf("Parse() sets = %d, expected 2", len(cfg.Sets))
+	}
+
+	opts, err := cfg.Sets[0].Options(nil)
+	if err != nil {
+		t.Fatalf("Options() error = %v", err)
+	}
+	if opts.Name != "weekly-points" || opts.Namespace != "dev" || !opts.MaintainFieldIndexes || opts.MaxMembers != 100 {
+		t.Errorf("Options() = %+v", opts)
+	}
+	if opts.History == nil || opts.History.MaxEntries != 10 || opts.History.TTL != 24*time.Hour {
+		t.Errorf("Options().History = %+v, expected 10 entries for 24h", opts.History)
+	}
+	points, deaths := opts.Fields[0], opts.Fields[1]
+	if points.Sort != zmultifield.Descending || !math.IsInf(points.MaxValue, 1) || points.UpdateType != zmultifield.Incremental {
+		t.Errorf("points = %+v, expected an unbounded incremental descending field", points)
+	}
+	if deaths.Sort != zmultifield.Ascending || deaths.MaxValue != 1000 || deaths.UpdateType != zmultifield.Replace {
+		t.Errorf("deaths = %+v, expected an ascending replace field with max 1000", deaths)
+	}
+
+	opts, err = cfg.Sets[1].Options(nil)
+	if err != nil {
+		t.Fatalf("Options() error = %v", err)
+	}
+	if _, ok := opts.KeyBuilder.(zmultifield.HashTagKeyBuilder); !ok || opts.Fields[0].HalfLife != 168*time.Hour {
+		t.Errorf("Options() = %+v, expected a hash tag key builder and a one week half-life", opts)
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	data := `{"sets": [{"name": "scores", "fields": [{"name": "score", "sort": "desc", "max_value": 100}]}]}`
+	cfg, err := Parse([]byte(data), JSON)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if cfg.Sets[0].Name != "scores" || cfg.Sets[0].Fields[0].MaxValue != 100 {
+		t.Errorf("Parse() = %+v", cfg.Sets[0])
+	}
+}
+
+func TestValidate(t *testing.T) {
+	field := "{name: score, sort: desc, max_value: 100}"
+	tests := []struct {
+		name   string
+		config string
+		errMsg string
+	}{
+		{"no sets", "sets: []", "no sets defined"},
+		{"missing name", "sets: [{fields: [" + field + "]}]", "name is required"},
+		{"duplicate set", "sets: [{name: a, fields: [" + field + "]}, {name: a, fields: [" + field + "]}]", "defined more than once"},
+		{"no fields", "sets: [{name: a}]", "at least one field"},
+		{"duplicate field", "sets: [{name: a, fields: [" + field + ", " + field + "]}]", "field score: defined more than once"},
+		{"bad sort", "sets: [{name: a, fields: [{name: x, sort: up, max_value: 1}]}]", "unknown sort"},
+		{"bad update", "sets: [{name: a, fields: [{name: x, sort: asc, max_value: 1, update: add}]}]", "unknown update"},
+		{"missing max", "sets: [{name: a, fields: [{name: x, sort: asc}]}]", "max_value must be positive"},
+		{"bad max", "sets: [{name: a, fields: [{name: x, sort: asc, max_value: lots}]}]", "invalid max_value"},
+		{"bad key builder", "sets: [{name: a, key_builder: slot, fields: [" + field + "]}]", "unknown key_builder"},
+		{"unknown option", "sets: [{name: a, max_memberz: 3, fields: [" + field + "]}]", "max_memberz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.config), YAML)
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Parse() error = %v, expected it to contain %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	cfg, err := Parse([]byte(testYAML), YAML)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	env := map[string]string{
+		"APP_NAMESPACE":                            "prod",
+		"APP_CLANS_NAMESPACE":                      "clans-prod",
+		"APP_WEEKLY_POINTS_MAX_MEMBERS":            "500",
+		"APP_WEEKLY_POINTS_MAINTAIN_FIELD_INDEXES": "false",
+	}
+	lookup := func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+	if err := cfg.ApplyEnv("APP", lookup); err != nil {
+		t.Fatalf("ApplyEnv() error = %v", err)
+	}
+
+	points, clans := cfg.Sets[0], cfg.Sets[1]
+	if points.Namespace != "prod" || points.MaxMembers != 500 || points.MaintainFieldIndexes {
+		t.Errorf("weekly-points = %+v, expected namespace prod, 500 members and no indexes", points)
+	}
+	if clans.Namespace != "clans-prod" {
+		t.Errorf("clans namespace = %q, expected the per-set override", clans.Namespace)
+	}
+
+	env["APP_CLANS_TRACK_UPDATED_AT"] = "sometimes"
+	if err := cfg.ApplyEnv("APP", lookup); err == nil || !strings.Contains(err.Error(), "APP_CLANS_TRACK_UPDATED_AT") {
+		t.Errorf("ApplyEnv() with an invalid bool error = %v, expected it to name the variable", err)
+	}
+}
+
+func TestLoadAndBuild(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sets.yaml")
+	if err := os.WriteFile(path, []byte(testYAML), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	t.Setenv("ZMF_NAMESPACE", "test")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	sets, err := cfg.Build(client)
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	points := sets["weekly-points"]
+	if points == nil || points.GetKey() != "test:weekly-points" {
+		t.Fatalf("Build() = %v, expected weekly-points under the test namespace", sets)
+	}
+	if _, err := points.IncreaseScore(context.Background(), map[string]float64{"points": 5}, "alice"); err != nil {
+		t.Errorf("IncreaseScore() error = %v", err)
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "sets.toml")); err == nil {
+		t.Error("Load() of a .toml file succeeded, expected an unknown format error")
+	}
+}
@@ -0,0 +1,490 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// CopyOptions configures CopyTo.
+type CopyOptions struct {
+	// BatchSize is the number of members or entries copied per round trip. Defaults to
+	// scanBatchSize.
+	BatchSize int64
+	// Interval is the pause between batches, throttling the load on both instances. Zero copies
+	// as fast as possible.
+	Interval time.Duration
+	// SkipVerify skips the verification pass.
+	SkipVerify bool
+}
+
+// CopyResult reports what CopyTo copied.
+type CopyResult struct {
+	// Members is the number of members of the main set copied by the first pass.
+	Members int64 `json:"members"`
+	// Keys is the number of set-wide companion keys copied, such as field indexes and dimension
+	// sets.
+	Keys int64 `json:"keys"`
+	// Repaired is the number of members the verification pass found missing or with different
+	// scores in the destination, because they were updated during the copy, and copied again.
+	Repaired int64 `json:"repaired"`
+	// Removed is the number of members the verification pass removed from the destination
+	// because they are no longer in the set.
+	Removed int64 `json:"removed"`
+}
+
+// copyTarget returns a set with the schema and options of mfs named name and connected to
+// client, from which the keys written by CopyTo are derived.
+func (mfs *MultiFieldSet) copyTarget(client redis.UniversalClient, name string) *MultiFieldSet {
+	base := mfs.keyFunc(mfs.namespace, name)
+	return &MultiFieldSet{
+		Codec:                mfs.Codec,
+		name:                 name,
+		namespace:            mfs.namespace,
+		baseKey:              base,
+		keyFunc:              mfs.keyFunc,
+		key:                  mfs.keyBuilder.Key(base),
+		keyBuilder:           mfs.keyBuilder,
+		client:               client,
+		readClient:           client,
+		retryPolicy:          mfs.retryPolicy,
+		history:              mfs.history,
+		updatedAt:            mfs.updatedAt,
+		maintainFieldIndexes: mfs.maintainFieldIndexes,
+		writeQueue:           mfs.writeQueue,
+		hideMembers:          mfs.hideMembers,
+		trackActivity:        mfs.trackActivity,
+		freezable:            mfs.freezable,
+		dimensions:           mfs.dimensions,
+	}
+}
+
+// CopyTo copies the set, with its members' metadata, histories and dimension values and its
+// companion keys, to the set named destName on another Redis instance, e.g. to migrate to a new
+// cluster. The destination keys are derived with the set's namespace, KeyFunc and KeyBuilder.
+// Snapshots and extractions are not copied.
+//
+// The set is read in batches while it keeps serving traffic, so members updated during the copy
+// may be copied with stale scores. Unless opts.SkipVerify is set, a verification pass then walks
+// both sets, copies again the members whose scores differ and removes from the destination the
+// members no longer in the set. Copying onto an existing destination overwrites its members and
+// replaces its companion keys, so CopyTo can be called again to catch up before switching over.
+func (mfs *MultiFieldSet) CopyTo(ctx context.Context, dest redis.UniversalClient, destName string, opts CopyOptions) (*CopyResult, error) {
+	if dest == nil || destName == "" {
+		return nil, mfs.runOnError(ctx, "CopyTo", "", errors.New("destination client and name are required"))
+	}
+	target := mfs.copyTarget(dest, destName)
+	if dest == mfs.client && target.key == mfs.key {
+		return nil, mfs.runOnError(ctx, "CopyTo", "", errors.New("cannot copy a set onto itself"))
+	}
+	c := &setCopier{src: mfs, dst: target, batch: opts.BatchSize, interval: opts.Interval}
+	if c.batch <= 0 {
+		c.batch = scanBatchSize
+	}
+
+	result, err := c.run(ctx, !opts.SkipVerify)
+	if err != nil {
+		return result, mfs.runOnError(ctx, "CopyTo", "", err)
+	}
+	return result, nil
+}
+
+// setCopier copies a set to another set, possibly on another Redis instance.
+type setCopier struct {
+	src, dst *MultiFieldSet
+	batch    int64
+	interval time.Duration
+}
+
+// run copies the members, then the companion keys, then optionally verifies the copy.
+func (c *setCopier) run(ctx context.Context, verify bool) (*CopyResult, error) {
+	result := &CopyResult{}
+	for start := int64(0); ; start += c.batch {
+		var results []redis.Z
+		err := c.src.read(ctx, func(client redis.UniversalClient) error {
+			var err error
+			results, err = client.ZRangeWithScores(ctx, c.src.key, start, start+c.batch-1).Result()
+			return err
+		})
+		if err != nil {
+			return result, err
+		}
+		if err := c.copyMembers(ctx, results); err != nil {
+			return result, err
+		}
+		result.Members += int64(len(results))
+		if int64(len(results)) < c.batch {
+			break
+		}
+		if err := c.pause(ctx); err != nil {
+			return result, err
+		}
+	}
+
+	n, err := c.copyCompanionKeys(ctx)
+	result.Keys = n
+	if err != nil || !verify {
+		return result, err
+	}
+	result.Repaired, result.Removed, err = c.verify(ctx)
+	return result, err
+}
+
+// pause waits for the throttling interval between batches.
+func (c *setCopier) pause(ctx context.Context) error {
+	if c.interval <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(c.interval)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// copyMembers writes members with their zscores to the destination set, along with their
+// per-member companion keys.
+func (c *setCopier) copyMembers(ctx context.Context, members []redis.Z) error {
+	if len(members) == 0 {
+		return nil
+	}
+	var pairs [][2]string
+	for _, z := range members {
+		member := memberName(z)
+		srcFuncs, dstFuncs := c.src.memberKeyFuncs(), c.dst.memberKeyFuncs()
+		for i := range srcFuncs {
+			pairs = append(pairs, [2]string{srcFuncs[i](member), dstFuncs[i](member)})
+		}
+	}
+	values, err := c.readKeys(ctx, pairs)
+	if err != nil {
+		return err
+	}
+
+	return c.dst.write(ctx, func(client redis.UniversalClient) error {
+		_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.ZAdd(ctx, c.dst.key, zPointers(members)...)
+			for _, v := range values {
+				v.restore(ctx, pipe)
+			}
+			return nil
+		})
+		return err
+	})
+}
+
+// zPointers returns pointers to the entries of zs, as ZADD expects them.
+func zPointers(zs []redis.Z) []*redis.Z {
+	ptrs := make([]*redis.Z, len(zs))
+	for i := range zs {
+		ptrs[i] = &zs[i]
+	}
+	return ptrs
+}
+
+// copiedKey is the content of a source key read by readKeys, to be written to its destination.
+type copiedKey struct {
+	dst  string
+	typ  string
+	ttl  time.Duration
+	data redis.Cmder
+}
+
+// restore queues the commands replacing the destination key with the copied content.
+func (k copiedKey) restore(ctx context.Context, pipe redis.Pipeliner) {
+	pipe.Del(ctx, k.dst)
+	switch cmd := k.data.(type) {
+	case *redis.ZSliceCmd:
+		if len(cmd.Val()) == 0 {
+			return
+		}
+		pipe.ZAdd(ctx, k.dst, zPointers(cmd.Val())...)
+	case *redis.StringStringMapCmd:
+		if len(cmd.Val()) == 0 {
+			return
+		}
+		pipe.HSet(ctx, k.dst, cmd.Val())
+	case *redis.StringSliceCmd:
+		if len(cmd.Val()) == 0 {
+			return
+		}
+		values := make([]interface{}, len(cmd.Val()))
+		for i, v := range cmd.Val() {
+			values[i] = v
+		}
+		if k.typ == "set" {
+			pipe.SAdd(ctx, k.dst, values...)
+		} else {
+			pipe.RPush(ctx, k.dst, values...)
+		}
+	case *redis.StringCmd:
+		if cmd.Err() != nil {
+			return
+		}
+		pipe.Set(ctx, k.dst, cmd.Val(), 0)
+	default:
+		return
+	}
+	if k.ttl > 0 {
+		pipe.PExpire(ctx, k.dst, k.ttl)
+	}
+}
+
+// readKeys reads the type, time to live and content of the source key of each pair in two
+// pipelines. Missing keys are returned with type "none", so their destination is deleted.
+func (c *setCopier) readKeys(ctx context.Context, pairs [][2]string) ([]copiedKey, error) {
+	keys := make([]copiedKey, len(pairs))
+	err := c.src.read(ctx, func(client redis.UniversalClient) error {
+		types := make([]*redis.StatusCmd, len(pairs))
+		ttls := make([]*redis.DurationCmd, len(pairs))
+		_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, p := range pairs {
+				types[i] = pipe.Type(ctx, p[0])
+				ttls[i] = pipe.PTTL(ctx, p[0])
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, p := range pairs {
+				keys[i] = copiedKey{dst: p[1], typ: types[i].Val(), ttl: ttls[i].Val()}
+				switch keys[i].typ {
+				case "zset":
+					keys[i].data = pipe.ZRangeWithScores(ctx, p[0], 0, -1)
+				case "hash":
+					keys[i].data = pipe.HGetAll(ctx, p[0])
+				case "set":
+					keys[i].data = pipe.SMembers(ctx, p[0])
+				case "list":
+					keys[i].data = pipe.LRange(ctx, p[0], 0, -1)
+				case "string":
+					keys[i].data = pipe.Get(ctx, p[0])
+				}
+			}
+			return nil
+		})
+		if err == redis.Nil {
+			// A string key expired between the two pipelines
+			err = nil
+		}
+		return err
+	})
+	return keys, err
+}
+
+// copyCompanionKeys replaces the set-wide companion keys of the destination with those of the
+// source and returns the number of keys copied. Sorted sets, which may be as large as the set
+// itself, are copied in batches; dimension sets are rebuilt under the destination's keys.
+func (c *setCopier) copyCompanionKeys(ctx context.Context) (int64, error) {
+	var pairs [][2]string
+	dstKeys := c.dst.derivedKeys()
+	for i, key := range c.src.derivedKeys() {
+		if len(c.src.dimensions) > 0 && key == c.src.dimensionKeysKey() {
+			continue
+		}
+		pairs = append(pairs, [2]string{key, dstKeys[i]})
+	}
+
+	if len(c.src.dimensions) > 0 {
+		if err := c.dst.clearDimensions(ctx); err != nil {
+			return 0, err
+		}
+		var keys []string
+		err := c.src.read(ctx, func(client redis.UniversalClient) error {
+			var err error
+			keys, err = client.SMembers(ctx, c.src.dimensionKeysKey()).Result()
+			return err
+		})
+		if err != nil {
+			return 0, err
+		}
+		for _, key := range keys {
+			name, value, ok := c.src.parseDimensionKey(key)
+			if !ok {
+				continue
+			}
+			dst := c.dst.dimensionKey(name, value)
+			pairs = append(pairs, [2]string{key, dst})
+			err := c.dst.write(ctx, func(client redis.UniversalClient) error {
+				return client.SAdd(ctx, c.dst.dimensionKeysKey(), dst).Err()
+			})
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	var copied int64
+	for _, p := range pairs {
+		if err := c.copyKey(ctx, p[0], p[1]); err != nil {
+			return copied, err
+		}
+		copied++
+		if err := c.pause(ctx); err != nil {
+			return copied, err
+		}
+	}
+	return copied, nil
+}
+
+// copyKey replaces dst with the content of src, reading sorted sets in batches.
+func (c *setCopier) copyKey(ctx context.Context, src, dst string) error {
+	var typ string
+	err := c.src.read(ctx, func(client redis.UniversalClient) error {
+		var err error
+		typ, err = client.Type(ctx, src).Result()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if typ != "zset" {
+		values, err := c.readKeys(ctx, [][2]string{{src, dst}})
+		if err != nil {
+			return err
+		}
+		return c.dst.write(ctx, func(client redis.UniversalClient) error {
+			_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+				values[0].restore(ctx, pipe)
+				return nil
+			})
+			return err
+		})
+	}
+
+	if err := c.dst.write(ctx, func(client redis.UniversalClient) error {
+		return client.Del(ctx, dst).Err()
+	}); err != nil {
+		return err
+	}
+	for start := int64(0); ; start += c.batch {
+		var results []redis.Z
+		err := c.src.read(ctx, func(client redis.UniversalClient) error {
+			var err error
+			results, err = client.ZRangeWithScores(ctx, src, start, start+c.batch-1).Result()
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if len(results) > 0 {
+			err := c.dst.write(ctx, func(client redis.UniversalClient) error {
+				return client.ZAdd(ctx, dst, zPointers(results)...).Err()
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if int64(len(results)) < c.batch {
+			return nil
+		}
+		if err := c.pause(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+// parseDimensionKey returns the dimension name and value of a key returned by dimensionKey.
+func (mfs *MultiFieldSet) parseDimensionKey(key string) (name, value string, ok bool) {
+	for _, d := range mfs.dimensions {
+		if prefix := mfs.dimensionKey(d.Name, ""); strings.HasPrefix(key, prefix) {
+			return d.Name, key[len(prefix):], true
+		}
+	}
+	return "", "", false
+}
+
+// verify copies again the members whose zscores differ between the source and the destination,
+// then removes from the destination the members that are no longer in the source.
+func (c *setCopier) verify(ctx context.Context) (repaired, removed int64, err error) {
+	var stale []redis.Z
+	err = c.src.compareBatches(ctx, c.dst, func(member string, zscore, otherZScore *big.Int) {
+		if otherZScore == nil || zscore.Cmp(otherZScore) != 0 {
+			stale = append(stale, redis.Z{Member: member, Score: float64(zscore.Int64())})
+		}
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	for start := 0; start < len(stale); start += int(c.batch) {
+		end := start + int(c.batch)
+		if end > len(stale) {
+			end = len(stale)
+		}
+		if err := c.copyMembers(ctx, stale[start:end]); err != nil {
+			return repaired, 0, err
+		}
+		repaired += int64(end - start)
+	}
+
+	var gone []string
+	err = c.dst.compareBatches(ctx, c.src, func(member string, zscore, otherZScore *big.Int) {
+		if otherZScore == nil {
+			gone = append(gone, member)
+		}
+	})
+	if err != nil {
+		return repaired, 0, err
+	}
+	for start := 0; start < len(gone); start += int(c.batch) {
+		end := start + int(c.batch)
+		if end > len(gone) {
+			end = len(gone)
+		}
+		if err := c.removeMembers(ctx, gone[start:end]); err != nil {
+			return repaired, removed, err
+		}
+		removed += int64(end - start)
+	}
+	return repaired, removed, nil
+}
+
+// removeMembers removes members from the destination set, its field indexes, hidden set and
+// dimension sets, and deletes their per-member companion keys.
+func (c *setCopier) removeMembers(ctx context.Context, members []string) error {
+	var dimensionKeys map[string][]string
+	if len(c.dst.dimensions) > 0 {
+		var err error
+		if dimensionKeys, err = c.dst.memberDimensionKeys(ctx, members); err != nil {
+			return err
+		}
+	}
+	zsets := []string{c.dst.key}
+	if c.dst.maintainFieldIndexes {
+		for _, field := range c.dst.fields {
+			zsets = append(zsets, c.dst.fieldIndexKey(field))
+		}
+	}
+	values := make([]interface{}, len(members))
+	for i, member := range members {
+		values[i] = member
+	}
+	return c.dst.write(ctx, func(client redis.UniversalClient) error {
+		_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, key := range zsets {
+				pipe.ZRem(ctx, key, values...)
+			}
+			if c.dst.hideMembers {
+				pipe.SRem(ctx, c.dst.hiddenKey(), values...)
+			}
+			c.dst.removeFromDimensions(ctx, pipe, dimensionKeys)
+			for _, member := range members {
+				for _, keyFunc := range c.dst.memberKeyFuncs() {
+					pipe.Del(ctx, keyFunc(member))
+				}
+			}
+			return nil
+		})
+		return err
+	})
+}
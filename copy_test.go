@@ -0,0 +1,104 @@
+package zmultifield
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestCopyTo(t *testing.T) {
+	opts := MultiFieldSetOptions{MaintainFieldIndexes: true, HideMembers: true, Dimensions: []Dimension{{Name: "country"}}}
+	mfs := newTestSetWithOptions(t, opts)
+	ctx := context.Background()
+
+	for i := 0; i < 7; i++ {
+		member := fmt.Sprintf("player%d", i)
+		if err := mfs.SetMemberMeta(ctx, member, map[string]string{"country": []string{"DE", "FR"}[i%2]}); err != nil {
+			t.Fatalf("SetMemberMeta() error = %v", err)
+		}
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": float64(i * 10), "deaths": float64(i)}, member); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+	}
+	if err := mfs.Hide(ctx, "player6"); err != nil {
+		t.Fatalf("Hide() error = %v", err)
+	}
+
+	// The destination already holds a member that isn't in the set
+	destClient, _ := newTestClient(t)
+	opts.Name, opts.Client, opts.Fields = "copy", destClient, []Field{
+		{Name: "points", Sort: Descending, MaxValue: 1000, UpdateType: Incremental},
+		{Name: "deaths", Sort: Ascending, MaxValue: 100, UpdateType: Incremental},
+	}
+	dest, err := New(opts)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := dest.IncreaseScore(ctx, map[string]float64{"points": 5}, "ghost"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+
+	result, err := mfs.CopyTo(ctx, destClient, "copy", CopyOptions{BatchSize: 2})
+	if err != nil {
+		t.Fatalf("CopyTo() error = %v", err)
+	}
+	if result.Members != 7 || result.Removed != 1 || result.Repaired != 0 {
+		t.Errorf("CopyTo() = %+v, expected 7 members copied and the ghost removed", result)
+	}
+
+	if c, err := mfs.Compare(ctx, dest); err != nil || !c.Equal() {
+		t.Errorf("Compare() = %+v, %v, expected equal sets", c, err)
+	}
+	if meta, err := dest.GetMemberMeta(ctx, "player3"); err != nil || meta["country"] != "FR" {
+		t.Errorf("GetMemberMeta() = %v, %v, expected the copied metadata", meta, err)
+	}
+	if hidden, err := dest.IsHidden(ctx, "player6"); err != nil || !hidden {
+		t.Errorf("IsHidden() = %v, %v, expected true", hidden, err)
+	}
+	if members, err := dest.GetTopMembers(ctx, 10, InDimension("country", "DE")); err != nil || len(members) != 3 {
+		t.Errorf("GetTopMembers() in DE = %v, %v, expected 3 visible members", members, err)
+	}
+	if members, err := dest.GetMembersByFieldRange(ctx, "deaths", 0, 2, 0, 0); err != nil || len(members) != 3 {
+		t.Errorf("GetMembersByFieldRange() = %v, %v, expected 3 members from the copied index", members, err)
+	}
+}
+
+func TestCopyToVerify(t *testing.T) {
+	mfs := newTestSet(t)
+	ctx := context.Background()
+	for _, member := range []string{"alice", "bob"} {
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 10}, member); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+	}
+	destClient, _ := newTestClient(t)
+	if _, err := mfs.CopyTo(ctx, destClient, "copy", CopyOptions{SkipVerify: true}); err != nil {
+		t.Fatalf("CopyTo() error = %v", err)
+	}
+
+	// Updates made after the first pass are caught up by the verification pass
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 5}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	if _, err := mfs.RemoveMember(ctx, "bob"); err != nil {
+		t.Fatalf("RemoveMember() error = %v", err)
+	}
+	c := &setCopier{src: mfs, dst: mfs.copyTarget(destClient, "copy"), batch: scanBatchSize}
+	repaired, removed, err := c.verify(ctx)
+	if err != nil {
+		t.Fatalf("verify() error = %v", err)
+	}
+	if repaired != 1 || removed != 1 {
+		t.Errorf("verify() = %d, %d, expected alice repaired and bob removed", repaired, removed)
+	}
+	if comparison, err := mfs.Compare(ctx, c.dst); err != nil || !comparison.Equal() {
+		t.Errorf("Compare() = %+v, %v, expected equal sets", comparison, err)
+	}
+
+	if _, err := mfs.CopyTo(ctx, mfs.client, "test", CopyOptions{}); err == nil {
+		t.Error("CopyTo() onto itself succeeded, expected an error")
+	}
+	if _, err := mfs.CopyTo(ctx, nil, "copy", CopyOptions{}); err == nil {
+		t.Errorf("CopyTo() without a client succeeded, expected an error")
+	}
+}
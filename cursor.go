@@ -0,0 +1,128 @@
+package zmultifield
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// afterCursorScript returns the members ranked after a (zscore, member) position. The position
+// doesn't need to be in the set any more: ties on the zscore are ordered by member, as Redis does,
+// and the first of them after the member is found by binary search.
+//
+// KEYS[1] is the main set and KEYS[2], if given, the set of hidden members, which are skipped.
+// ARGV[1] is the zscore of the position, or empty to start at the top, ARGV[2] its member and
+// ARGV[3] the number of members to return. It returns a flat member/score list like ZRANGE
+// WITHSCORES.
+var afterCursorScript = redis.NewScript(`
+local start = 0
+if ARGV[1] ~= '' then
+	start = redis.call('ZCOUNT', KEYS[1], '-inf', '(' .. ARGV[1])
+	local stop = redis.call('ZCOUNT', KEYS[1], '-inf', ARGV[1])
+	while start < stop do
+		local mid = math.floor((start + stop) / 2)
+		if redis.call('ZRANGE', KEYS[1], mid, mid)[1] > ARGV[2] then
+			stop = mid
+		else
+			start = mid + 1
+		end
+	end
+end
+
+local limit = tonumber(ARGV[3])
+local hidden = 0
+if #KEYS > 1 then
+	hidden = redis.call('SCARD', KEYS[2])
+end
+local entries = redis.call('ZRANGE', KEYS[1], start, start + limit + hidden - 1, 'WITHSCORES')
+local result = {}
+for i = 1, #entries, 2 do
+	if #result >= 2 * limit then
+		break
+	end
+	if hidden == 0 or redis.call('SISMEMBER', KEYS[2], entries[i]) == 0 then
+		result[#result + 1] = entries[i]
+		result[#result + 1] = entries[i + 1]
+	end
+end
+return result
+`)
+
+// CursorPage is a page of members read with GetMembersAfter.
+type CursorPage struct {
+	Members []MemberScores `json:"members"`
+	// Next is the cursor of the following page, or empty if this page is the last.
+	Next string `json:"next,omitempty"`
+}
+
+// GetMembersAfter returns up to limit members following cursor in leaderboard order, starting at
+// the top for an empty cursor, along with the cursor of the next page. Cursors are opaque tokens
+// holding the zscore and name of the last member of a page, so pages can be walked deeply without
+// offsets and stay stable while members above the cursor change: the next page starts right after
+// that position, even if the member has moved or been removed since. Members tied on the zscore
+// are ordered by name.
+func (mfs *MultiFieldSet) GetMembersAfter(ctx context.Context, cursor string, limit int64) (_ *CursorPage, err error) {
+	defer mfs.observeRead("GetMembersAfter", time.Now(), &err)
+
+	if limit < 1 {
+		return nil, errors.New("limit must be positive")
+	}
+	var zscore, member string
+	if cursor != "" {
+		if zscore, member, err = decodeCursor(cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	keys := []string{mfs.key}
+	if mfs.hideMembers {
+		keys = append(keys, mfs.hiddenKey())
+	}
+	var reply []string
+	err = mfs.read(ctx, func(client redis.UniversalClient) error {
+		reply, err = afterCursorScript.Run(ctx, client, keys, zscore, member, limit).StringSlice()
+		return err
+	})
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "GetMembersAfter", "", err)
+	}
+	results, err := parseZSlice(reply)
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "GetMembersAfter", "", err)
+	}
+
+	members, err := mfs.decodeMembers(results)
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "GetMembersAfter", "", err)
+	}
+	page := &CursorPage{Members: members}
+	if int64(len(results)) == limit {
+		last := results[len(results)-1]
+		page.Next = encodeCursor(new(big.Int).SetInt64(int64(last.Score)), memberName(last))
+	}
+	return page, nil
+}
+
+// encodeCursor returns the cursor of the position of member with zscore.
+func encodeCursor(zscore *big.Int, member string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(zscore.String() + ":" + member))
+}
+
+// decodeCursor returns the zscore and member of a cursor returned by encodeCursor.
+func decodeCursor(cursor string) (zscore, member string, err error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	zscore, member, ok := strings.Cut(string(raw), ":")
+	if _, valid := new(big.Int).SetString(zscore, 10); !ok || !valid {
+		return "", "", ErrInvalidCursor
+	}
+	return zscore, member, nil
+}
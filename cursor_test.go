@@ -0,0 +1,80 @@
+package zmultifield
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestGetMembersAfter(t *testing.T) {
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{HideMembers: true})
+	ctx := context.Background()
+
+	// Several members share a score, so pages have to break ties by name
+	players := map[string]float64{"alice": 30, "bob": 20, "carol": 20, "dave": 20, "erin": 20, "frank": 10}
+	for member, points := range players {
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": points}, member); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+	}
+
+	walk := func() string {
+		t.Helper()
+		var names []string
+		cursor := ""
+		for pages := 0; pages < 10; pages++ {
+			page, err := mfs.GetMembersAfter(ctx, cursor, 2)
+			if err != nil {
+				t.Fatalf("GetMembersAfter() error = %v", err)
+			}
+			for _, m := range page.Members {
+				names = append(names, m.Member)
+			}
+			if page.Next == "" {
+				break
+			}
+			cursor = page.Next
+		}
+		return strings.Join(names, ",")
+	}
+	if got := walk(); got != "alice,bob,carol,dave,erin,frank" {
+		t.Errorf("walked %q, expected every member in order", got)
+	}
+
+	if err := mfs.Hide(ctx, "dave"); err != nil {
+		t.Fatalf("Hide() error = %v", err)
+	}
+	if got := walk(); got != "alice,bob,carol,erin,frank" {
+		t.Errorf("walked %q, expected hidden members to be skipped", got)
+	}
+
+	// The next page starts after the cursor's position even if its member has gone
+	page, err := mfs.GetMembersAfter(ctx, "", 3)
+	if err != nil {
+		t.Fatalf("GetMembersAfter() error = %v", err)
+	}
+	if _, err := mfs.RemoveMember(ctx, "carol"); err != nil {
+		t.Fatalf("RemoveMember() error = %v", err)
+	}
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 100}, "zoe"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	next, err := mfs.GetMembersAfter(ctx, page.Next, 3)
+	if err != nil {
+		t.Fatalf("GetMembersAfter() error = %v", err)
+	}
+	if len(next.Members) != 2 || next.Members[0].Member != "erin" || next.Members[1].Member != "frank" || next.Next != "" {
+		t.Errorf("GetMembersAfter() = %+v, expected erin and frank on the last page", next)
+	}
+}
+
+func TestGetMembersAfterInvalidCursor(t *testing.T) {
+	mfs := newTestSet(t)
+	for _, cursor := range []string{"not base64!", base64.RawURLEncoding.EncodeToString([]byte("abc:alice")), base64.RawURLEncoding.EncodeToString([]byte("alice"))} {
+		if _, err := mfs.GetMembersAfter(context.Background(), cursor, 10); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("GetMembersAfter(%q) error = %v, expected ErrInvalidCursor", cursor, err)
+		}
+	}
+}
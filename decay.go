@@ -0,0 +1,291 @@
+package zmultifield
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// decayBatchSize is the number of members decayed per script call.
+const decayBatchSize = 500
+
+// decayScript decays the decaying fields of a batch of members. Members are re-read inside the
+// script so concurrent updates are never lost. Like the merge script it decodes with arithmetic,
+// which is exact below 2^53. The fraction each value loses to rounding is kept in a hash per field
+// and added back before the next decay, so decaying in many small steps is as exact as decaying
+// once.
+//
+// KEYS[1] is the main set, KEYS[2] the hash of the time each member was last decayed by
+// ApplyDecay, KEYS[3..n+2] the remainder hashes of the n decaying fields and KEYS[n+3..] their
+// indexes, if maintained. ARGV[1] is the number of members, followed by the members, the time to
+// decay by in milliseconds or -1 to decay each member from its last decay time, the current time
+// and the default last decay time in Unix milliseconds, and five values per decaying field: 2^shift,
+// 2^bits, the maximum raw value, 1 if the field is descending and the half-life in milliseconds.
+// The script returns the number of members whose zscore changed.
+var decayScript = newWriteScript(`
+local count = tonumber(ARGV[1])
+local elapsed = tonumber(ARGV[count + 2])
+local now = tonumber(ARGV[count + 3])
+local since = tonumber(ARGV[count + 4])
+local fields = {}
+for i = count + 5, #ARGV, 5 do
+	fields[#fields + 1] = {
+		base = tonumber(ARGV[i]),
+		size = tonumber(ARGV[i + 1]),
+		max = tonumber(ARGV[i + 2]),
+		desc = ARGV[i + 3] == '1',
+		halfLife = tonumber(ARGV[i + 4]),
+	}
+end
+local indexes = 2 + #fields
+
+local changed = 0
+for m = 2, count + 1 do
+	local member = ARGV[m]
+	local current = redis.call('ZSCORE', KEYS[1], member)
+	local e = elapsed
+	if current and e < 0 then
+		e = now - tonumber(redis.call('HGET', KEYS[2], member) or since)
+	end
+	if not current then
+		for f = 1, #fields do
+			redis.call('HDEL', KEYS[2 + f], member)
+		end
+		redis.call('HDEL', KEYS[2], member)
+	elseif e > 0 then
+		local zscore = tonumber(current)
+		local updated = zscore
+		local raws = {}
+		for f, field in ipairs(fields) do
+			local raw = math.floor(zscore / field.base) % field.size
+			local value = raw
+			if field.desc then
+				value = field.max - raw
+			end
+			local exact = (value + tonumber(redis.call('HGET', KEYS[2 + f], member) or 0)) * 0.5 ^ (e / field.halfLife)
+			value = math.floor(exact)
+			if exact > value then
+				redis.call('HSET', KEYS[2 + f], member, string.format('%.17g', exact - value))
+			else
+				redis.call('HDEL', KEYS[2 + f], member)
+			end
+			local newRaw = value
+			if field.desc then
+				newRaw = field.max - value
+			end
+			updated = updated + (newRaw - raw) * field.base
+			raws[f] = newRaw
+		end
+		if updated ~= zscore then
+			redis.call('ZADD', KEYS[1], string.format('%.17g', updated), member)
+			for k = indexes + 1, #KEYS do
+				redis.call('ZADD', KEYS[k], string.format('%.17g', raws[k - indexes]), member)
+			end
+			changed = changed + 1
+		end
+		if elapsed < 0 then
+			redis.call('HSET', KEYS[2], member, now)
+		end
+	end
+end
+return changed
+`)
+
+// decayingFields returns the fields that have a half-life.
+func (mfs *MultiFieldSet) decayingFields() []*multiField {
+	var fields []*multiField
+	for _, field := range mfs.fields {
+		if field.HalfLife > 0 {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// decayKey returns the key holding the time decay was last applied, in Unix milliseconds.
+func (mfs *MultiFieldSet) decayKey() string {
+	return mfs.derivedKey("decay")
+}
+
+// decayTimesKey returns the key of the hash holding the time ApplyDecay last decayed each member,
+// while a call hasn't completed.
+func (mfs *MultiFieldSet) decayTimesKey() string {
+	return mfs.derivedKey("decay:times")
+}
+
+// decayRemainderKey returns the key of the hash holding the fraction of a decaying field each
+// member lost to rounding.
+func (mfs *MultiFieldSet) decayRemainderKey(field *multiField) string {
+	return mfs.derivedKey("decay:remainder:" + field.Name)
+}
+
+// decayKeys returns the companion keys of the decaying fields.
+func (mfs *MultiFieldSet) decayKeys() []string {
+	fields := mfs.decayingFields()
+	if len(fields) == 0 {
+		return nil
+	}
+	keys := []string{mfs.decayKey(), mfs.decayTimesKey()}
+	for _, field := range fields {
+		keys = append(keys, mfs.decayRemainderKey(field))
+	}
+	return keys
+}
+
+// ApplyDecay decays every field with a HalfLife by the time elapsed since the previous call and
+// returns the number of members that changed. The first call only records the starting time.
+// Each member records when it was decayed until the call completes, so calling ApplyDecay again
+// after it failed part way doesn't decay any member twice. Only one process should apply decay to
+// a set at a time.
+func (mfs *MultiFieldSet) ApplyDecay(ctx context.Context) (int64, error) {
+	now := time.Now()
+
+	var last string
+	err := mfs.primary(ctx, func(client redis.UniversalClient) error {
+		var err error
+		last, err = client.Get(ctx, mfs.decayKey()).Result()
+		return err
+	})
+	if err != nil && err != redis.Nil {
+		return 0, mfs.runOnError(ctx, "ApplyDecay", "", err)
+	}
+
+	var changed int64
+	if err == nil {
+		lastMillis, err := strconv.ParseInt(last, 10, 64)
+		if err != nil {
+			return 0, mfs.runOnError(ctx, "ApplyDecay", "", err)
+		}
+		changed, err = mfs.decay(ctx, -1, now.UnixMilli(), lastMillis)
+		if err != nil {
+			return changed, mfs.runOnError(ctx, "ApplyDecay", "", err)
+		}
+	}
+
+	// Every member has now been decayed until now, so their own times can go
+	err = mfs.write(ctx, func(client redis.UniversalClient) error {
+		return mfs.txPipelined(ctx, client, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, mfs.decayKey(), strconv.FormatInt(now.UnixMilli(), 10), 0)
+			pipe.Del(ctx, mfs.decayTimesKey())
+			return nil
+		})
+	})
+	if err != nil {
+		return changed, mfs.runOnError(ctx, "ApplyDecay", "", err)
+	}
+	return changed, nil
+}
+
+// DecayBy decays every field with a HalfLife as if elapsed time had passed and returns the number of
+// members that changed. Each value is multiplied by 0.5^(elapsed/HalfLife) and rounded down; the
+// fraction lost to rounding is kept and carried into the next decay, so many small decays add up
+// to one large decay.
+//
+// The member names are collected before any score changes, so memory use is proportional to the size
+// of the set; members added meanwhile are not decayed.
+func (mfs *MultiFieldSet) DecayBy(ctx context.Context, elapsed time.Duration) (int64, error) {
+	if elapsed <= 0 {
+		return 0, nil
+	}
+	changed, err := mfs.decay(ctx, millis(elapsed), 0, 0)
+	if err != nil {
+		return changed, mfs.runOnError(ctx, "DecayBy", "", err)
+	}
+	return changed, nil
+}
+
+// millis returns d in fractional milliseconds.
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// decay runs decayScript over every member in batches, either by elapsed milliseconds or, if
+// elapsed is negative, from each member's last decay time, defaulting to since, until now.
+func (mfs *MultiFieldSet) decay(ctx context.Context, elapsed float64, now, since int64) (int64, error) {
+	fields := mfs.decayingFields()
+	if len(fields) == 0 {
+		return 0, nil
+	}
+
+	keys := []string{mfs.key, mfs.decayTimesKey()}
+	var fieldArgs []interface{}
+	for _, field := range fields {
+		keys = append(keys, mfs.decayRemainderKey(field))
+		desc := "0"
+		if field.inverted {
+			desc = "1"
+		}
+		fieldArgs = append(fieldArgs,
+			uint64(1)<<field.shiftValue,
+			uint64(1)<<field.bits,
+			field.maxAbsolute.String(),
+			desc,
+			strconv.FormatFloat(millis(field.HalfLife), 'g', -1, 64),
+		)
+	}
+	if mfs.maintainFieldIndexes {
+		for _, field := range fields {
+			keys = append(keys, mfs.fieldIndexKey(field))
+		}
+	}
+
+	var members []string
+	err := mfs.primary(ctx, func(client redis.UniversalClient) error {
+		var err error
+		members, err = client.ZRange(ctx, mfs.key, 0, -1).Result()
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var changed int64
+	for start := 0; start < len(members); start += decayBatchSize {
+		end := start + decayBatchSize
+		if end > len(members) {
+			end = len(members)
+		}
+
+		args := make([]interface{}, 0, 4+end-start+len(fieldArgs))
+		args = append(args, end-start)
+		for _, member := range members[start:end] {
+			args = append(args, member)
+		}
+		args = append(args, strconv.FormatFloat(elapsed, 'g', -1, 64), now, since)
+		args = append(args, fieldArgs...)
+
+		var n int64
+		err := mfs.writeOnce(ctx, func(client redis.UniversalClient) error {
+			var err error
+			n, err = decayScript.Run(ctx, client, mfs.writeGuard(), keys, args...).Int64()
+			return err
+		})
+		if err != nil {
+			return changed, err
+		}
+		changed += n
+	}
+	return changed, nil
+}
+
+// StartDecay calls ApplyDecay every interval in a background goroutine until ctx is done or the
+// returned stop function is called. Errors are reported to the error hooks.
+func (mfs *MultiFieldSet) StartDecay(ctx context.Context, interval time.Duration) (stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			// ApplyDecay already reports errors to the error hooks
+			_, _ = mfs.ApplyDecay(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return cancel
+}
@@ -0,0 +1,154 @@
+package zmultifield
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func newDecayTestSet(t *testing.T, opts MultiFieldSetOptions) *MultiFieldSet {
+	t.Helper()
+	client, _ := newTestClient(t)
+	opts.Name = "trending"
+	opts.Fields = []Field{
+		{Name: "heat", Sort: Descending, MaxValue: 1000, UpdateType: Incremental, HalfLife: time.Hour},
+		{Name: "clicks", Sort: Descending, MaxValue: 1000, UpdateType: Incremental},
+	}
+	opts.Client = client
+	mfs, err := New(opts)
+	if err != nil {
+		t.Fatalf("Failed to create MultiFieldSet: %v", err)
+	}
+	return mfs
+}
+
+func TestDecayBy(t *testing.T) {
+	mfs := newDecayTestSet(t, MultiFieldSetOptions{MaintainFieldIndexes: true})
+	ctx := context.Background()
+
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"heat": 800, "clicks": 7}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"heat": 301}, "bob"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+
+	changed, err := mfs.DecayBy(ctx, 2*time.Hour)
+	if err != nil {
+		t.Fatalf("DecayBy() error = %v", err)
+	}
+	if changed != 2 {
+		t.Errorf("DecayBy() = %d, expected 2", changed)
+	}
+
+	tests := []struct {
+		member, field string
+		expected      int64
+	}{
+		{"alice", "heat", 200},
+		{"alice", "clicks", 7},
+		{"bob", "heat", 75},
+	}
+	for _, test := range tests {
+		score, err := mfs.GetScoreForField(ctx, test.field, test.member)
+		if err != nil {
+			t.Fatalf("GetScoreForField(%s, %s) error = %v", test.field, test.member, err)
+		}
+		if score.Int64() != test.expected {
+			t.Errorf("%s %s = %v, expected %d", test.member, test.field, score, test.expected)
+		}
+	}
+
+	members, err := mfs.GetTopMembersByField(ctx, "heat", 2)
+	if err != nil {
+		t.Fatalf("GetTopMembersByField() error = %v", err)
+	}
+	if len(members) != 2 || members[0].Member != "alice" || members[0].Scores[0].Score.Int64() != 200 {
+		t.Errorf("GetTopMembersByField() = %+v, expected alice with heat 200 first", members)
+	}
+}
+
+func TestApplyDecay(t *testing.T) {
+	mfs := newDecayTestSet(t, MultiFieldSetOptions{})
+	ctx := context.Background()
+
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"heat": 800}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+
+	// the first call only records the starting time
+	if changed, err := mfs.ApplyDecay(ctx); err != nil || changed != 0 {
+		t.Fatalf("ApplyDecay() = %d, %v, expected 0, nil", changed, err)
+	}
+
+	start := time.Now().Add(-time.Hour).UnixMilli()
+	if err := mfs.client.Set(ctx, mfs.decayKey(), start, 0).Err(); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if changed, err := mfs.ApplyDecay(ctx); err != nil || changed != 1 {
+		t.Fatalf("ApplyDecay() = %d, %v, expected 1, nil", changed, err)
+	}
+
+	score, err := mfs.GetScoreForField(ctx, "heat", "alice")
+	if err != nil {
+		t.Fatalf("GetScoreForField() error = %v", err)
+	}
+	if score.Int64() != 399 && score.Int64() != 400 {
+		t.Errorf("heat = %v, expected about 400", score)
+	}
+}
+
+func TestDecayBy_ManyTicks(t *testing.T) {
+	mfs := newDecayTestSet(t, MultiFieldSetOptions{})
+	ctx := context.Background()
+
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"heat": 100}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	// 100 minutes in one minute steps decay like 100 minutes at once: 100 * 0.5^(100/60) = 31.5
+	for i := 0; i < 100; i++ {
+		if _, err := mfs.DecayBy(ctx, time.Minute); err != nil {
+			t.Fatalf("DecayBy() error = %v", err)
+		}
+	}
+	score, err := mfs.GetScoreForField(ctx, "heat", "alice")
+	if err != nil {
+		t.Fatalf("GetScoreForField() error = %v", err)
+	}
+	if score.Int64() != 31 {
+		t.Errorf("heat = %v after 100 ticks, expected 31", score)
+	}
+}
+
+func TestApplyDecay_Resume(t *testing.T) {
+	mfs := newDecayTestSet(t, MultiFieldSetOptions{})
+	ctx := context.Background()
+
+	for _, member := range []string{"alice", "bob"} {
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"heat": 800}, member); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+	}
+	start := time.Now().Add(-time.Hour).UnixMilli()
+	if err := mfs.client.Set(ctx, mfs.decayKey(), start, 0).Err(); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	// A previous call failed after decaying alice. The recorded time is ahead so the milliseconds
+	// until the resumed call don't decay alice either.
+	if err := mfs.client.HSet(ctx, mfs.decayTimesKey(), "alice", time.Now().Add(time.Minute).UnixMilli()).Err(); err != nil {
+		t.Fatalf("HSet() error = %v", err)
+	}
+
+	if changed, err := mfs.ApplyDecay(ctx); err != nil || changed != 1 {
+		t.Fatalf("ApplyDecay() = %d, %v, expected 1, nil", changed, err)
+	}
+	if score, _ := mfs.GetScoreForField(ctx, "heat", "alice"); score.Int64() != 800 {
+		t.Errorf("alice heat = %v, expected the resumed call not to decay it again", score)
+	}
+	if score, _ := mfs.GetScoreForField(ctx, "heat", "bob"); score.Int64() != 399 && score.Int64() != 400 {
+		t.Errorf("bob heat = %v, expected about 400", score)
+	}
+	if n, _ := mfs.client.Exists(ctx, mfs.decayTimesKey()).Result(); n != 0 {
+		t.Error("ApplyDecay() kept the members' decay times after completing")
+	}
+}
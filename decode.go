@@ -0,0 +1,144 @@
+package zmultifield
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// zscorePool recycles the scratch big.Int used to hold each zscore while scanning a set.
+var zscorePool = sync.Pool{
+	New: func() interface{} { return new(big.Int) },
+}
+
+// DecodeInto decodes a zscore as returned by Redis into dst, reusing dst.Scores and the big.Ints
+// it points to when they are large enough. Decoding many members into the same MemberScores values
+// avoids allocating fresh slices and big.Ints for every read. The zscore is decoded as is; it
+// must be one written by the set.
+func (mfs *MultiFieldSet) DecodeInto(dst *MemberScores, member string, zscore float64) {
+	dst.Member = member
+	width := len(mfs.visible) + len(mfs.computed)
+	if cap(dst.Scores) < width {
+		dst.Scores = make([]FieldScore, width)
+	}
+	dst.Scores = dst.Scores[:width]
+
+	var values []big.Int
+	for i, field := range mfs.visible {
+		if dst.Scores[i].Score == nil {
+			if values == nil {
+				values = make([]big.Int, len(mfs.visible))
+			}
+			dst.Scores[i].Score = &values[i]
+		}
+		dst.Scores[i].Name = field.Name
+		mfs.decodeFieldInto(dst.Scores[i].Score, field, zscore)
+	}
+	if len(mfs.computed) > 0 {
+		mfs.computeInto(dst.Scores)
+	}
+}
+
+// decodeFieldInto stores the display value of field from a zscore as returned by Redis in dst.
+func (mfs *MultiFieldSet) decodeFieldInto(dst *big.Int, field *multiField, zscore float64) {
+	if mfs.fitsUint64 && zscore >= 0 {
+		dst.SetUint64(field.display64(field.extract64(uint64(zscore))))
+		if field.offset != nil {
+			dst.Add(dst, field.offset)
+		}
+		return
+	}
+
+	dst.Set(field.toDisplay(mfs.extractFieldScore(field, new(big.Int).SetInt64(int64(zscore)))))
+}
+
+// checkZScore returns an UnrepresentableScoreError if zscore, as read from Redis for member,
+// can't have been written by the set: if it isn't a finite integer, is negative, is above 2^53
+// where float64 scores lose precision, or is above the largest zscore of the schema.
+func (mfs *MultiFieldSet) checkZScore(member string, zscore float64) error {
+	if reason := mfs.checkScore(zscore); reason != "" {
+		return &UnrepresentableScoreError{Member: member, Score: zscore, Reason: reason}
+	}
+	return nil
+}
+
+// zscoreOf converts a zscore read from Redis for member, failing with an
+// UnrepresentableScoreError instead of truncating it.
+func (mfs *MultiFieldSet) zscoreOf(member string, zscore float64) (*big.Int, error) {
+	if err := mfs.checkZScore(member, zscore); err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetInt64(int64(zscore)), nil
+}
+
+// decodeEntry decodes a single sorted set entry whose zscore has already been checked.
+func (mfs *MultiFieldSet) decodeEntry(z redis.Z) MemberScores {
+	var m MemberScores
+	mfs.DecodeInto(&m, memberName(z), z.Score)
+	return m
+}
+
+// decodeMembersInto decodes sorted set entries into dst, reusing its elements, and returns the
+// resized slice. Storage for members that have none yet is allocated in one block per call. It
+// fails with an UnrepresentableScoreError if any entry's zscore can't be decoded exactly.
+func (mfs *MultiFieldSet) decodeMembersInto(dst []MemberScores, results []redis.Z) ([]MemberScores, error) {
+	for _, z := range results {
+		if err := mfs.checkZScore(memberName(z), z.Score); err != nil {
+			return nil, err
+		}
+	}
+	if cap(dst) < len(results) {
+		grown := make([]MemberScores, len(results))
+		copy(grown, dst[:cap(dst)])
+		dst = grown
+	}
+	dst = dst[:len(results)]
+
+	n, width := len(mfs.visible), len(mfs.visible)+len(mfs.computed)
+	var scores []FieldScore
+	var values []big.Int
+	for i, z := range results {
+		if cap(dst[i].Scores) < width {
+			if scores == nil {
+				scores = make([]FieldScore, width*(len(results)-i))
+				values = make([]big.Int, n*(len(results)-i))
+			}
+			dst[i].Scores = scores[:width:width]
+			for j := 0; j < n; j++ {
+				dst[i].Scores[j].Score = &values[j]
+			}
+			scores, values = scores[width:], values[n:]
+		}
+		mfs.DecodeInto(&dst[i], memberName(z), z.Score)
+	}
+	return dst, nil
+}
+
+// GetMembersInto is like GetMembers but decodes the results into dst, reusing its capacity and the
+// big.Ints of its scores. Passing the previous result back in makes repeated bulk reads nearly
+// allocation free on the decode side. Hidden members are skipped.
+func (mfs *MultiFieldSet) GetMembersInto(ctx context.Context, dst []MemberScores, limit, offset int64) (_ []MemberScores, err error) {
+	defer mfs.observeRead("GetMembersInto", time.Now(), &err)
+
+	var results []redis.Z
+	err = mfs.read(ctx, func(client redis.UniversalClient) error {
+		if mfs.hideMembers {
+			results, err = mfs.visibleRange(ctx, client, mfs.key, offset, limit)
+			return err
+		}
+		results, err = client.ZRangeWithScores(ctx, mfs.key, offset, offset+limit-1).Result()
+		return err
+	})
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "GetMembersInto", "", err)
+	}
+
+	members, err := mfs.decodeMembersInto(dst, results)
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "GetMembersInto", "", err)
+	}
+	return members, nil
+}
@@ -0,0 +1,299 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// derivedSets holds the derived sets of a MultiFieldSet by name.
+type derivedSets struct {
+	mu   sync.RWMutex
+	sets map[string]*derivedSet
+}
+
+// derivedSet is a set ranking the members of its source with another field order.
+type derivedSet struct {
+	set   *MultiFieldSet
+	order []string
+}
+
+// derivedSetKey returns the key of the derived set with the given name.
+func (mfs *MultiFieldSet) derivedSetKey(name string) string {
+	return mfs.derivedKey("ordering:" + name)
+}
+
+// DerivedSet returns a set holding the same members as mfs, ranked with the fields named in
+// fieldOrder first, from the most significant, followed by the other fields in their current order,
+// e.g. a "wins-first" board next to a "points-first" one. The derived set is materialized from the
+// current members, which reads the whole set, and then follows every update made through mfs, such
+// as IncreaseScore or AddMember. Members removed from mfs, or written by BulkLoad or Rebuild, reach
+// it on the next RefreshDerivedSet.
+//
+// The returned set is for reading: write through mfs. Calling DerivedSet again with the same name
+// and order materializes the set again and returns it. In Redis Cluster the set needs a
+// HashTagKeyBuilder, as the derived set is swapped in with RENAME.
+func (mfs *MultiFieldSet) DerivedSet(ctx context.Context, name string, fieldOrder []string) (*MultiFieldSet, error) {
+	if name == "" {
+		return nil, mfs.runOnError(ctx, "DerivedSet", "", errors.New("derived set name is required"))
+	}
+
+	mfs.derived.mu.Lock()
+	d, ok := mfs.derived.sets[name]
+	if !ok {
+		codec, err := mfs.reorderedCodec(fieldOrder)
+		if err != nil {
+			mfs.derived.mu.Unlock()
+			return nil, mfs.runOnError(ctx, "DerivedSet", "", err)
+		}
+		d = &derivedSet{set: mfs.derivedView(name, codec), order: append([]string(nil), fieldOrder...)}
+		if mfs.derived.sets == nil {
+			mfs.derived.sets = make(map[string]*derivedSet)
+		}
+		mfs.derived.sets[name] = d
+	}
+	mfs.derived.mu.Unlock()
+
+	if !sameOrder(d.order, fieldOrder) {
+		return nil, mfs.runOnError(ctx, "DerivedSet", "", fmt.Errorf("derived set %s already exists with order %v", name, d.order))
+	}
+	if err := mfs.materialize(ctx, name, d.set); err != nil {
+		return nil, mfs.runOnError(ctx, "DerivedSet", "", err)
+	}
+	return d.set, nil
+}
+
+// RefreshDerivedSet materializes the derived set with the given name again from the current
+// members, e.g. periodically to drop members removed from mfs. Updates written while it runs may
+// be missing from the derived set until their member is updated again. It fails with
+// ErrDerivedSetNotFound if DerivedSet wasn't called with name.
+func (mfs *MultiFieldSet) RefreshDerivedSet(ctx context.Context, name string) error {
+	d := mfs.derivedSet(name)
+	if d == nil {
+		return mfs.runOnError(ctx, "RefreshDerivedSet", "", fmt.Errorf("%w: %s", ErrDerivedSetNotFound, name))
+	}
+	return mfs.runOnError(ctx, "RefreshDerivedSet", "", mfs.materialize(ctx, name, d.set))
+}
+
+// DropDerivedSet stops maintaining the derived set with the given name and deletes it. It fails
+// with ErrDerivedSetNotFound if DerivedSet wasn't called with name.
+func (mfs *MultiFieldSet) DropDerivedSet(ctx context.Context, name string) error {
+	mfs.derived.mu.Lock()
+	d, ok := mfs.derived.sets[name]
+	delete(mfs.derived.sets, name)
+	mfs.derived.mu.Unlock()
+	if !ok {
+		return mfs.runOnError(ctx, "DropDerivedSet", "", fmt.Errorf("%w: %s", ErrDerivedSetNotFound, name))
+	}
+
+	err := mfs.write(ctx, func(client redis.UniversalClient) error {
+		return client.Del(ctx, d.set.key).Err()
+	})
+	return mfs.runOnError(ctx, "DropDerivedSet", "", err)
+}
+
+// derivedSet returns the derived set with the given name, or nil.
+func (mfs *MultiFieldSet) derivedSet(name string) *derivedSet {
+	mfs.derived.mu.RLock()
+	defer mfs.derived.mu.RUnlock()
+	return mfs.derived.sets[name]
+}
+
+// derivedSetList returns the derived sets of mfs.
+func (mfs *MultiFieldSet) derivedSetList() []*MultiFieldSet {
+	mfs.derived.mu.RLock()
+	defer mfs.derived.mu.RUnlock()
+	sets := make([]*MultiFieldSet, 0, len(mfs.derived.sets))
+	for _, d := range mfs.derived.sets {
+		sets = append(sets, d.set)
+	}
+	return sets
+}
+
+// reorderedCodec returns a codec packing the fields of mfs with the fields named in order first,
+// followed by the others in their current order. Hidden fields, such as the salt, stay hidden.
+func (mfs *MultiFieldSet) reorderedCodec(order []string) (*Codec, error) {
+	priorities := make(map[string]int, len(order))
+	for i, name := range order {
+		if field := mfs.GetFieldByName(name); field == nil || !mfs.isVisible(field) {
+			return nil, fieldNotFoundError(name)
+		}
+		if priorities[name] != 0 {
+			return nil, fmt.Errorf("field %s is listed twice", name)
+		}
+		priorities[name] = i + 1
+	}
+
+	current := append([]*multiField(nil), mfs.fields...)
+	sort.Slice(current, func(i, j int) bool {
+		return current[i].shiftValue > current[j].shiftValue
+	})
+	if main := current[0]; main.isMain && len(order) > 0 && order[0] != main.Name {
+		return nil, fmt.Errorf("unbounded field %s must stay the most significant", main.Name)
+	}
+
+	var layout Layout
+	fields := make([]Field, len(current))
+	for i, field := range current {
+		fields[i] = field.Field
+		fields[i].Priority = priorities[field.Name]
+		if field.Sort == Descending && !field.inverted {
+			layout.DescendingAsIs = true
+		}
+	}
+	codec, err := NewCodecWithLayout(fields, layout)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, field := range mfs.fields {
+		if !mfs.isVisible(field) {
+			codec.maskField(codec.GetFieldByName(field.Name))
+		}
+		if field.count != nil {
+			codec.GetFieldByName(field.Name).count = codec.GetFieldByName(field.count.Name)
+		}
+	}
+	return codec, nil
+}
+
+// isVisible reports whether field is returned by reads.
+func (c *Codec) isVisible(field *multiField) bool {
+	for _, f := range c.visible {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// derivedView returns the set reading the derived set with the given name, packed with codec.
+func (mfs *MultiFieldSet) derivedView(name string, codec *Codec) *MultiFieldSet {
+	key := mfs.derivedSetKey(name)
+	view := &MultiFieldSet{
+		Codec:             codec,
+		name:              mfs.name + ":" + name,
+		namespace:         mfs.namespace,
+		baseKey:           key,
+		keyFunc:           mfs.keyFunc,
+		key:               key,
+		keyBuilder:        mfs.keyBuilder,
+		client:            mfs.client,
+		readClient:        mfs.readClient,
+		readPreference:    mfs.readPreference,
+		retryPolicy:       mfs.retryPolicy,
+		metrics:           mfs.metrics,
+		logger:            mfs.logger,
+		slowThreshold:     mfs.slowThreshold,
+		saltSecret:        mfs.saltSecret,
+		optimisticRetries: mfs.optimisticRetries,
+		lockTTL:           mfs.lockTTL,
+		computed:          mfs.computed,
+	}
+	if mfs.updatedAt != nil {
+		view.updatedAt = codec.GetFieldByName(UpdatedAtField)
+	}
+	if mfs.salt != nil {
+		view.salt = codec.GetFieldByName(SaltField)
+	}
+	return view
+}
+
+// sameOrder reports whether a and b list the same fields in the same order.
+func sameOrder(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// rezscore returns the zscore of mfs, nil for a missing member, packed with the layout of d.
+func (mfs *MultiFieldSet) rezscore(d *MultiFieldSet, zscore *big.Int) *big.Int {
+	raws := mfs.getFieldScores(zscore)
+	scores := make([]*big.Int, len(d.fields))
+	for i, field := range d.fields {
+		scores[i] = raws[mfs.GetFieldByName(field.Name).position]
+	}
+	return d.scoresToZScore(scores)
+}
+
+// materialize writes every member of mfs to a staging key packed with the layout of d, then swaps
+// it in.
+func (mfs *MultiFieldSet) materialize(ctx context.Context, name string, d *MultiFieldSet) error {
+	staging := mfs.derivedKey("ordering:" + name + ":staging")
+	if err := mfs.write(ctx, func(client redis.UniversalClient) error {
+		return client.Del(ctx, staging).Err()
+	}); err != nil {
+		return err
+	}
+
+	var count int64
+	for start := int64(0); ; start += scanBatchSize {
+		var results []redis.Z
+		err := mfs.primary(ctx, func(client redis.UniversalClient) error {
+			var err error
+			results, err = client.ZRangeWithScores(ctx, mfs.key, start, start+scanBatchSize-1).Result()
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		members := make([]*redis.Z, 0, len(results))
+		for _, z := range results {
+			zscore, err := mfs.zscoreOf(memberName(z), z.Score)
+			if err != nil {
+				return err
+			}
+			members = append(members, &redis.Z{Score: float64(mfs.rezscore(d, zscore).Int64()), Member: z.Member})
+		}
+		if len(members) > 0 {
+			if err := mfs.write(ctx, func(client redis.UniversalClient) error {
+				return client.ZAdd(ctx, staging, members...).Err()
+			}); err != nil {
+				return err
+			}
+		}
+		count += int64(len(results))
+		if len(results) < scanBatchSize {
+			break
+		}
+	}
+
+	return d.write(ctx, func(client redis.UniversalClient) error {
+		if count == 0 {
+			return client.Del(ctx, d.key).Err()
+		}
+		return client.Rename(ctx, staging, d.key).Err()
+	})
+}
+
+// updateDerived writes the new zscore of member to every derived set.
+func (mfs *MultiFieldSet) updateDerived(ctx context.Context, member string, zscore *big.Int) {
+	sets := mfs.derivedSetList()
+	if len(sets) == 0 || zscore == nil {
+		return
+	}
+	err := mfs.write(ctx, func(client redis.UniversalClient) error {
+		_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, d := range sets {
+				pipe.ZAdd(ctx, d.key, &redis.Z{Score: float64(mfs.rezscore(d, zscore).Int64()), Member: member})
+			}
+			return nil
+		})
+		return err
+	})
+	if err != nil {
+		mfs.runOnError(ctx, "DerivedSet", member, err)
+	}
+}
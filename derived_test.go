@@ -0,0 +1,116 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// topNames returns the members of the top of mfs, best first.
+func topNames(t *testing.T, mfs *MultiFieldSet) []string {
+	t.Helper()
+	members, err := mfs.GetTopMembers(context.Background(), 10)
+	if err != nil {
+		t.Fatalf("GetTopMembers() error = %v", err)
+	}
+	names := make([]string, len(members))
+	for i, m := range members {
+		names[i] = m.Member
+	}
+	return names
+}
+
+func TestDerivedSet(t *testing.T) {
+	ctx := context.Background()
+	client, _ := newTestClient(t)
+	mfs, err := New(MultiFieldSetOptions{
+		Name: "test",
+		Fields: []Field{
+			{Name: "points", Sort: Descending, MaxValue: 1000, UpdateType: Incremental},
+			{Name: "wins", Sort: Descending, MaxValue: 100, UpdateType: Incremental},
+			{Name: "deaths", Sort: Ascending, MaxValue: 100, UpdateType: Incremental},
+		},
+		Client:         client,
+		TrackUpdatedAt: true,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 300, "wins": 2}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 100, "wins": 5, "deaths": 3}, "bob"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+
+	winsFirst, err := mfs.DerivedSet(ctx, "wins-first", []string{"wins"})
+	if err != nil {
+		t.Fatalf("DerivedSet() error = %v", err)
+	}
+	if got := topNames(t, winsFirst); len(got) != 2 || got[0] != "bob" {
+		t.Errorf("derived top = %v, expected bob first", got)
+	}
+	if got := topNames(t, mfs); got[0] != "alice" {
+		t.Errorf("top = %v, expected alice first", got)
+	}
+	scores, err := winsFirst.GetScores(ctx, "bob")
+	if err != nil || FieldValue(scores, "points") != 100 || FieldValue(scores, "wins") != 5 || FieldValue(scores, "deaths") != 3 {
+		t.Errorf("derived GetScores() = %v, %v, expected the scores of bob", scores, err)
+	}
+
+	// Updates reach the derived set as they are written
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"wins": 4}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 10, "wins": 1}, "carol"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	if got := topNames(t, winsFirst); len(got) != 3 || got[0] != "alice" || got[2] != "carol" {
+		t.Errorf("derived top = %v, expected alice first and carol last", got)
+	}
+
+	// Removals reach it on refresh
+	if _, err := mfs.RemoveMember(ctx, "alice"); err != nil {
+		t.Fatalf("RemoveMember() error = %v", err)
+	}
+	if err := mfs.RefreshDerivedSet(ctx, "wins-first"); err != nil {
+		t.Fatalf("RefreshDerivedSet() error = %v", err)
+	}
+	if got := topNames(t, winsFirst); len(got) != 2 || got[0] != "bob" {
+		t.Errorf("refreshed derived top = %v, expected bob and carol", got)
+	}
+
+	if err := mfs.DropDerivedSet(ctx, "wins-first"); err != nil {
+		t.Fatalf("DropDerivedSet() error = %v", err)
+	}
+	if n, err := client.Exists(ctx, winsFirst.key).Result(); err != nil || n != 0 {
+		t.Errorf("derived set still exists after DropDerivedSet(): %v, %v", n, err)
+	}
+	if err := mfs.RefreshDerivedSet(ctx, "wins-first"); !errors.Is(err, ErrDerivedSetNotFound) {
+		t.Errorf("RefreshDerivedSet() after drop error = %v, expected ErrDerivedSetNotFound", err)
+	}
+}
+
+func TestDerivedSet_Validation(t *testing.T) {
+	ctx := context.Background()
+	mfs := newTestSet(t)
+
+	for name, order := range map[string][]string{
+		"unknown field": {"wins"},
+		"duplicate":     {"deaths", "deaths"},
+	} {
+		if _, err := mfs.DerivedSet(ctx, "bad", order); err == nil {
+			t.Errorf("DerivedSet() with a %s succeeded, expected an error", name)
+		}
+	}
+
+	if _, err := mfs.DerivedSet(ctx, "deaths-first", []string{"deaths"}); err != nil {
+		t.Fatalf("DerivedSet() error = %v", err)
+	}
+	if _, err := mfs.DerivedSet(ctx, "deaths-first", []string{"deaths"}); err != nil {
+		t.Errorf("DerivedSet() again error = %v", err)
+	}
+	if _, err := mfs.DerivedSet(ctx, "deaths-first", []string{"points"}); err == nil {
+		t.Errorf("DerivedSet() with another order succeeded, expected an error")
+	}
+}
@@ -0,0 +1,211 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Dimension splits a set into one sorted set per value of a member attribute, e.g. a leaderboard
+// per country or platform, kept up to date by the same writes as the global set.
+type Dimension struct {
+	// Name identifies the dimension in InDimension, e.g. "country".
+	Name string
+	// ValueOf returns the member's value for the dimension, or "" to keep the member out of every
+	// set of the dimension. Defaults to reading the metadata field named Name, as stored with
+	// SetMemberMeta.
+	ValueOf func(ctx context.Context, member string) (string, error)
+}
+
+// validateDimensions checks that dimension names are set and unique.
+func validateDimensions(dimensions []Dimension) error {
+	seen := make(map[string]bool, len(dimensions))
+	for _, d := range dimensions {
+		if d.Name == "" {
+			return errors.New("dimension name is required")
+		}
+		if seen[d.Name] {
+			return fmt.Errorf("duplicate dimension %s", d.Name)
+		}
+		seen[d.Name] = true
+	}
+	return nil
+}
+
+// dimensionKey returns the key of the sorted set holding the members with value for a dimension.
+func (mfs *MultiFieldSet) dimensionKey(name, value string) string {
+	return mfs.derivedKey("dim:" + name + ":" + value)
+}
+
+// memberDimensionsKey returns the key of the hash recording the dimension values a member was last
+// written with, so it can be moved out of sets whose value no longer applies.
+func (mfs *MultiFieldSet) memberDimensionsKey(member string) string {
+	return mfs.derivedKey("dims:" + member)
+}
+
+// dimensionKeysKey returns the key of the set listing every dimension set written so far.
+func (mfs *MultiFieldSet) dimensionKeysKey() string {
+	return mfs.derivedKey("dimkeys")
+}
+
+// hasDimension reports whether the set has a dimension with the given name.
+func (mfs *MultiFieldSet) hasDimension(name string) bool {
+	for _, d := range mfs.dimensions {
+		if d.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// dimensionWrite lists the dimension sets a member is written to and removed from along with its
+// update.
+type dimensionWrite struct {
+	// add holds the keys of the sets of the member's current values.
+	add []string
+	// remove holds the keys of the sets of values the member no longer has.
+	remove []string
+	// values holds dimension names and values in pairs, as recorded for the member.
+	values []interface{}
+	// previous holds the values recorded by the member's previous write the others were resolved
+	// from, in the order of values, so the write can check they haven't changed since.
+	previous []interface{}
+}
+
+// resolveDimensions reads the member's dimension values, through c for those taken from its
+// metadata, and compares them with the values recorded by its previous write.
+func (mfs *MultiFieldSet) resolveDimensions(ctx context.Context, c redis.Cmdable, member string) (*dimensionWrite, error) {
+	var metaFields []string
+	for _, d := range mfs.dimensions {
+		if d.ValueOf == nil {
+			metaFields = append(metaFields, d.Name)
+		}
+	}
+	var meta *redis.SliceCmd
+	var previous *redis.StringStringMapCmd
+	_, err := c.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		if len(metaFields) > 0 {
+			meta = pipe.HMGet(ctx, mfs.metaKey(member), metaFields...)
+		}
+		previous = pipe.HGetAll(ctx, mfs.memberDimensionsKey(member))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	w := &dimensionWrite{}
+	next := 0
+	for _, d := range mfs.dimensions {
+		var value string
+		if d.ValueOf != nil {
+			if value, err = d.ValueOf(ctx, member); err != nil {
+				return nil, err
+			}
+		} else {
+			value, _ = meta.Val()[next].(string)
+			next++
+		}
+		if value != "" {
+			w.add = append(w.add, mfs.dimensionKey(d.Name, value))
+		}
+		if old := previous.Val()[d.Name]; old != "" && old != value {
+			w.remove = append(w.remove, mfs.dimensionKey(d.Name, old))
+		}
+		w.values = append(w.values, d.Name, value)
+		w.previous = append(w.previous, previous.Val()[d.Name])
+	}
+	return w, nil
+}
+
+// memberDimensionKeys returns the dimension sets each of members was last written to.
+func (mfs *MultiFieldSet) memberDimensionKeys(ctx context.Context, members []string) (map[string][]string, error) {
+	cmds := make([]*redis.StringStringMapCmd, len(members))
+	err := mfs.primary(ctx, func(client redis.UniversalClient) error {
+		_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, member := range members {
+				cmds[i] = pipe.HGetAll(ctx, mfs.memberDimensionsKey(member))
+			}
+			return nil
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string][]string, len(members))
+	for i, member := range members {
+		for name, value := range cmds[i].Val() {
+			if value != "" {
+				keys[member] = append(keys[member], mfs.dimensionKey(name, value))
+			}
+		}
+	}
+	return keys, nil
+}
+
+// removeFromDimensions removes members from the dimension sets they were written to and deletes
+// their recorded values, as part of pipe.
+func (mfs *MultiFieldSet) removeFromDimensions(ctx context.Context, pipe redis.Pipeliner, keys map[string][]string) {
+	for member, memberKeys := range keys {
+		for _, key := range memberKeys {
+			pipe.ZRem(ctx, key, member)
+		}
+		pipe.Del(ctx, mfs.memberDimensionsKey(member))
+	}
+}
+
+// clearDimensions deletes every dimension set along with the set listing them.
+func (mfs *MultiFieldSet) clearDimensions(ctx context.Context) error {
+	return mfs.write(ctx, func(client redis.UniversalClient) error {
+		keys, err := client.SMembers(ctx, mfs.dimensionKeysKey()).Result()
+		if err != nil {
+			return err
+		}
+		return client.Del(ctx, append(keys, mfs.dimensionKeysKey())...).Err()
+	})
+}
+
+// InDimension reads the set of members with the given value for a dimension instead of the
+// global set, e.g. InDimension("country", "DE") for the German leaderboard. Ranks and offsets
+// count the members of that set only.
+func InDimension(name, value string) ReadOption {
+	return func(o *readOptions) {
+		o.dimension, o.dimensionValue = name, value
+	}
+}
+
+// readKey returns the key of the sorted set read with o.
+func (mfs *MultiFieldSet) readKey(o readOptions) (string, error) {
+	if o.dimension == "" {
+		return mfs.key, nil
+	}
+	if !mfs.hasDimension(o.dimension) {
+		return "", fmt.Errorf("%w: %s", ErrDimensionNotFound, o.dimension)
+	}
+	return mfs.dimensionKey(o.dimension, o.dimensionValue), nil
+}
+
+// getDimensionRank returns the rank of a member within the dimension set selected by o.
+func (mfs *MultiFieldSet) getDimensionRank(ctx context.Context, o readOptions, member string) (int64, error) {
+	key, err := mfs.readKey(o)
+	if err != nil {
+		return 0, err
+	}
+	var rank int64
+	err = mfs.read(ctx, func(client redis.UniversalClient) error {
+		if mfs.hideMembers {
+			rank, err = mfs.visibleRank(ctx, client, key, member)
+		} else {
+			rank, err = client.ZRank(ctx, key, member).Result()
+		}
+		return err
+	})
+	if err == redis.Nil {
+		return 0, ErrMemberNotFound
+	}
+	return rank, err
+}
@@ -0,0 +1,155 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDimensions(t *testing.T) {
+	for _, strategy := range []UpdateStrategy{UpdateScripted, UpdateOptimistic} {
+		mfs := newTestSetWithOptions(t, MultiFieldSetOptions{
+			UpdateStrategy: strategy,
+			Dimensions: []Dimension{
+				{Name: "country"},
+				{Name: "platform", ValueOf: func(ctx context.Context, member string) (string, error) {
+					if strings.HasPrefix(member, "m") {
+						return "mobile", nil
+					}
+					return "pc", nil
+				}},
+			},
+		})
+		ctx := context.Background()
+
+		countries := map[string]string{"alice": "DE", "bob": "FR", "mallory": "DE", "mike": ""}
+		for member, country := range countries {
+			if country != "" {
+				if err := mfs.SetMemberMeta(ctx, member, map[string]string{"country": country}); err != nil {
+					t.Fatalf("SetMemberMeta() error = %v", err)
+				}
+			}
+		}
+		points := map[string]float64{"alice": 30, "bob": 20, "mallory": 10, "mike": 40}
+		for member, p := range points {
+			if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": p}, member); err != nil {
+				t.Fatalf("IncreaseScore() error = %v", err)
+			}
+		}
+
+		read := func(opts ...ReadOption) string {
+			t.Helper()
+			members, err := mfs.GetTopMembers(ctx, 10, opts...)
+			if err != nil {
+				t.Fatalf("GetTopMembers() error = %v", err)
+			}
+			var names []string
+			for _, m := range members {
+				names = append(names, m.Member)
+			}
+			return strings.Join(names, ",")
+		}
+		if got := read(InDimension("country", "DE")); got != "alice,mallory" {
+			t.Errorf("strategy %d: country DE = %q, expected alice,mallory", strategy, got)
+		}
+		if got := read(InDimension("platform", "mobile")); got != "mike,mallory" {
+			t.Errorf("strategy %d: platform mobile = %q, expected mike,mallory", strategy, got)
+		}
+		if got := read(InDimension("platform", "mobile"), MinField("points", 20)); got != "mike" {
+			t.Errorf("strategy %d: filtered platform mobile = %q, expected mike", strategy, got)
+		}
+		if rank, err := mfs.GetRank(ctx, "mallory", InDimension("country", "DE")); err != nil || rank != 1 {
+			t.Errorf("strategy %d: GetRank() = %d, %v, expected 1", strategy, rank, err)
+		}
+		if _, err := mfs.GetRank(ctx, "bob", InDimension("country", "DE")); !errors.Is(err, ErrMemberNotFound) {
+			t.Errorf("strategy %d: GetRank() error = %v, expected ErrMemberNotFound", strategy, err)
+		}
+
+		// Dimension sets carry the same scores as the global set
+		members, err := mfs.GetTopMembers(ctx, 1, InDimension("country", "FR"))
+		if err != nil || len(members) != 1 || members[0].Scores[0].Score.Int64() != 20 {
+			t.Errorf("strategy %d: country FR = %v, %v, expected bob with 20 points", strategy, members, err)
+		}
+
+		// Members move between dimension sets when their value changes
+		if err := mfs.SetMemberMeta(ctx, "alice", map[string]string{"country": "FR"}); err != nil {
+			t.Fatalf("SetMemberMeta() error = %v", err)
+		}
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 1}, "alice"); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+		if got := read(InDimension("country", "DE")); got != "mallory" {
+			t.Errorf("strategy %d: country DE after move = %q, expected mallory", strategy, got)
+		}
+		if got := read(InDimension("country", "FR")); got != "alice,bob" {
+			t.Errorf("strategy %d: country FR after move = %q, expected alice,bob", strategy, got)
+		}
+
+		if _, err := mfs.RemoveMember(ctx, "mallory"); err != nil {
+			t.Fatalf("RemoveMember() error = %v", err)
+		}
+		if got := read(InDimension("country", "DE")); got != "" {
+			t.Errorf("strategy %d: country DE after removal = %q, expected no members", strategy, got)
+		}
+
+		if _, err := mfs.GetTopMembers(ctx, 10, InDimension("league", "gold")); !errors.Is(err, ErrDimensionNotFound) {
+			t.Errorf("strategy %d: unknown dimension error = %v, expected ErrDimensionNotFound", strategy, err)
+		}
+	}
+}
+
+func TestDimensionsEviction(t *testing.T) {
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{
+		MaxMembers: 2,
+		Dimensions: []Dimension{{Name: "team", ValueOf: func(ctx context.Context, member string) (string, error) {
+			return "red", nil
+		}}},
+	})
+	ctx := context.Background()
+
+	for i, member := range []string{"alice", "bob", "carol"} {
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": float64(30 - 10*i)}, member); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+	}
+	members, err := mfs.GetTopMembers(ctx, 10, InDimension("team", "red"))
+	if err != nil {
+		t.Fatalf("GetTopMembers() error = %v", err)
+	}
+	if len(members) != 2 || members[0].Member != "alice" || members[1].Member != "bob" {
+		t.Errorf("GetTopMembers() = %v, expected alice and bob", members)
+	}
+}
+
+func TestDimensionsClear(t *testing.T) {
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{
+		Dimensions: []Dimension{{Name: "country"}},
+	})
+	ctx := context.Background()
+
+	if err := mfs.SetMemberMeta(ctx, "alice", map[string]string{"country": "DE"}); err != nil {
+		t.Fatalf("SetMemberMeta() error = %v", err)
+	}
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 10}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	if err := mfs.Clear(ctx); err != nil {
+		t.Fatalf("Clear() error = %v", err)
+	}
+	for _, key := range []string{mfs.dimensionKey("country", "DE"), mfs.dimensionKeysKey(), mfs.memberDimensionsKey("alice")} {
+		if n, err := mfs.client.Exists(ctx, key).Result(); err != nil || n != 0 {
+			t.Errorf("key %s still exists after Clear", key)
+		}
+	}
+}
+
+func TestDimensionsValidation(t *testing.T) {
+	client, _ := newTestClient(t)
+	fields := []Field{{Name: "points", Sort: Descending, MaxValue: 1000, UpdateType: Incremental}}
+	for _, dims := range [][]Dimension{{{Name: ""}}, {{Name: "country"}, {Name: "country"}}} {
+		if _, err := New(MultiFieldSetOptions{Name: "test", Fields: fields, Client: client, Dimensions: dims}); err == nil {
+			t.Errorf("New() with dimensions %v succeeded, expected an error", dims)
+		}
+	}
+}
@@ -0,0 +1,345 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// DivisionDimension is the name of the dimension holding the divisions of a set with Divisions,
+// e.g. for InDimension. Use InDivision to read a division.
+const DivisionDimension = "division"
+
+// DivisionOptions configures the divisions of a competitive ladder, see
+// MultiFieldSetOptions.Divisions. Divisions are numbered from 1 for the top division.
+type DivisionOptions struct {
+	// Count is the number of divisions, at least 2.
+	Count int
+	// Size is the number of members every division holds, except the last one, which takes every
+	// new member and has no limit.
+	Size int64
+	// Promote is the number of members Rotate moves from the top of each division to the division
+	// above it.
+	Promote int64
+	// Demote is the number of members Rotate moves from the bottom of each full division to the
+	// division below it. Divisions that aren't full fill up with promotions first.
+	Demote int64
+}
+
+// validate checks that the divisions can be rotated.
+func (o *DivisionOptions) validate() error {
+	switch {
+	case o.Count < 2:
+		return errors.New("at least two divisions are required")
+	case o.Size <= 0:
+		return errors.New("division size must be positive")
+	case o.Promote < 0 || o.Demote < 0 || o.Promote+o.Demote == 0:
+		return errors.New("divisions must promote or demote members")
+	case o.Promote+o.Demote > o.Size:
+		// A member could otherwise be promoted and demoted by the same rotation
+		return errors.New("division size must hold the promoted and demoted members")
+	}
+	return nil
+}
+
+// Divisions partitions the members of a set into divisions of a fixed size, with promotion and
+// demotion between adjacent divisions. Members are kept in their division's sorted set by the same
+// writes as dimension sets, see MultiFieldSetOptions.Dimensions.
+type Divisions struct {
+	mfs  *MultiFieldSet
+	opts DivisionOptions
+}
+
+// DivisionMove describes a member moved to another division by Rotate.
+type DivisionMove struct {
+	Member string
+	From   int
+	To     int
+}
+
+// Divisions returns the set's divisions, or nil if the set has no Divisions.
+func (mfs *MultiFieldSet) Divisions() *Divisions {
+	return mfs.divisions
+}
+
+// divisionDimension returns the dimension keeping the divisions' sorted sets.
+func (d *Divisions) divisionDimension() Dimension {
+	return Dimension{Name: DivisionDimension, ValueOf: d.valueOf}
+}
+
+// valueOf returns the division a member was last written to, or the last division for a new
+// member.
+func (d *Divisions) valueOf(ctx context.Context, member string) (string, error) {
+	var division string
+	err := d.mfs.primary(ctx, func(client redis.UniversalClient) error {
+		var err error
+		division, err = client.HGet(ctx, d.mfs.memberDimensionsKey(member), DivisionDimension).Result()
+		return err
+	})
+	if err == redis.Nil || (err == nil && division == "") {
+		return strconv.Itoa(d.opts.Count), nil
+	}
+	return division, err
+}
+
+// key returns the key of a division's sorted set.
+func (d *Divisions) key(division int) string {
+	return d.mfs.dimensionKey(DivisionDimension, strconv.Itoa(division))
+}
+
+// check fails if division isn't one of the divisions.
+func (d *Divisions) check(division int) error {
+	if division < 1 || division > d.opts.Count {
+		return fmt.Errorf("division %d outside 1 to %d", division, d.opts.Count)
+	}
+	return nil
+}
+
+// InDivision reads a division of a set with Divisions instead of the global set. Ranks and
+// offsets count the members of the division only.
+func InDivision(division int) ReadOption {
+	return InDimension(DivisionDimension, strconv.Itoa(division))
+}
+
+// Get returns the division of a member. Members are placed in a division by their first update,
+// so it fails with ErrMemberNotFound for members that haven't been updated.
+func (d *Divisions) Get(ctx context.Context, member string) (_ int, err error) {
+	defer d.mfs.observeRead("GetDivision", time.Now(), &err)
+
+	var value string
+	err = d.mfs.read(ctx, func(client redis.UniversalClient) error {
+		value, err = client.HGet(ctx, d.mfs.memberDimensionsKey(member), DivisionDimension).Result()
+		return err
+	})
+	if err == redis.Nil || (err == nil && value == "") {
+		return 0, d.mfs.runOnError(ctx, "GetDivision", member, ErrMemberNotFound)
+	} else if err != nil {
+		return 0, d.mfs.runOnError(ctx, "GetDivision", member, err)
+	}
+	division, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, d.mfs.runOnError(ctx, "GetDivision", member, err)
+	}
+	return division, nil
+}
+
+// Members returns the members of a division in leaderboard order, like GetMembers with
+// InDivision.
+func (d *Divisions) Members(ctx context.Context, division int, limit, offset int64, opts ...ReadOption) ([]MemberScores, error) {
+	if err := d.check(division); err != nil {
+		return nil, d.mfs.runOnError(ctx, "GetMembers", "", err)
+	}
+	return d.mfs.GetMembers(ctx, limit, offset, append(opts, InDivision(division))...)
+}
+
+// Count returns the number of members in a division.
+func (d *Divisions) Count(ctx context.Context, division int) (_ int64, err error) {
+	defer d.mfs.observeRead("CountDivision", time.Now(), &err)
+
+	if err := d.check(division); err != nil {
+		return 0, d.mfs.runOnError(ctx, "CountDivision", "", err)
+	}
+	var count int64
+	err = d.mfs.read(ctx, func(client redis.UniversalClient) error {
+		count, err = client.ZCard(ctx, d.key(division)).Result()
+		return err
+	})
+	if err != nil {
+		return 0, d.mfs.runOnError(ctx, "CountDivision", "", err)
+	}
+	return count, nil
+}
+
+// Rotate ends a period of the ladder: between every pair of adjacent divisions, the top Promote
+// members of the lower division move up and the bottom Demote members of the upper one move down,
+// as ranked when Rotate starts. Hidden and quarantined members are neither moved nor counted
+// towards the size of their division. Members keep their scores, and the moves of every division
+// are written in one transaction, retried if a division changes while they are computed. An update
+// racing with Rotate is retried with the member's new division. It runs under the set's
+// administrative lock, e.g. with DivisionsTask, and returns the moves made.
+func (d *Divisions) Rotate(ctx context.Context) ([]DivisionMove, error) {
+	var moves []DivisionMove
+	err := d.mfs.withLock(ctx, "RotateDivisions", func(ctx context.Context) error {
+		return d.mfs.write(ctx, func(client redis.UniversalClient) error {
+			var err error
+			moves, err = d.rotate(ctx, client)
+			return err
+		})
+	})
+	if err != nil {
+		return nil, d.mfs.runOnError(ctx, "RotateDivisions", "", err)
+	}
+	return moves, nil
+}
+
+// rotate computes and writes the moves of Rotate in a transaction watching every division.
+func (d *Divisions) rotate(ctx context.Context, client redis.UniversalClient) ([]DivisionMove, error) {
+	watched := make([]string, 0, d.opts.Count+3)
+	for division := 1; division <= d.opts.Count; division++ {
+		watched = append(watched, d.key(division))
+	}
+	if d.mfs.freezable {
+		watched = append(watched, d.mfs.frozenKey())
+	}
+	if d.mfs.hideMembers {
+		watched = append(watched, d.mfs.hiddenKey())
+	}
+	if d.mfs.quarantinable {
+		watched = append(watched, d.mfs.quarantineKey())
+	}
+
+	for attempt := 0; attempt <= d.mfs.optimisticRetries; attempt++ {
+		var moves []DivisionMove
+		err := client.Watch(ctx, func(tx *redis.Tx) error {
+			if err := d.mfs.checkFrozen(ctx, tx); err != nil {
+				return err
+			}
+			var scores map[string]float64
+			var err error
+			moves, scores, err = d.plan(ctx, tx)
+			if err != nil || len(moves) == 0 {
+				return err
+			}
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				for _, move := range moves {
+					pipe.ZRem(ctx, d.key(move.From), move.Member)
+					pipe.ZAdd(ctx, d.key(move.To), &redis.Z{Score: scores[move.Member], Member: move.Member})
+					pipe.HSet(ctx, d.mfs.memberDimensionsKey(move.Member), DivisionDimension, strconv.Itoa(move.To))
+					pipe.SAdd(ctx, d.mfs.dimensionKeysKey(), d.key(move.To))
+				}
+				return nil
+			})
+			return err
+		}, watched...)
+		if err != redis.TxFailedErr {
+			return moves, err
+		}
+	}
+	return nil, ErrUpdateConflict
+}
+
+// plan reads the divisions through tx and returns the moves of a rotation along with the scores of
+// the moved members.
+func (d *Divisions) plan(ctx context.Context, tx *redis.Tx) ([]DivisionMove, map[string]float64, error) {
+	excluded, hidden, err := d.excluded(ctx, tx)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Read enough members to make up for the excluded ones among them
+	slack := int64(len(excluded))
+
+	count := d.opts.Count
+	cards := make([]*redis.IntCmd, count+1)
+	tops := make([]*redis.ZSliceCmd, count+1)
+	bottoms := make([]*redis.ZSliceCmd, count+1)
+	divisions := make([]*redis.StringCmd, len(hidden))
+	_, err = tx.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for division := 1; division <= count; division++ {
+			cards[division] = pipe.ZCard(ctx, d.key(division))
+			if division > 1 && d.opts.Promote > 0 {
+				tops[division] = pipe.ZRangeWithScores(ctx, d.key(division), 0, d.opts.Promote+slack-1)
+			}
+			if division < count && d.opts.Demote > 0 {
+				bottoms[division] = pipe.ZRangeWithScores(ctx, d.key(division), -(d.opts.Demote + slack), -1)
+			}
+		}
+		for i, member := range hidden {
+			divisions[i] = pipe.HGet(ctx, d.mfs.memberDimensionsKey(member), DivisionDimension)
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return nil, nil, err
+	}
+
+	// Hidden members stay in their division's set, but don't take up a place in it
+	sizes := make([]int64, count+1)
+	for division := 1; division <= count; division++ {
+		sizes[division] = cards[division].Val()
+	}
+	for _, cmd := range divisions {
+		if division, err := strconv.Atoi(cmd.Val()); err == nil && division >= 1 && division <= count {
+			sizes[division]--
+		}
+	}
+	eligible := func(cmd *redis.ZSliceCmd) []redis.Z {
+		var entries []redis.Z
+		if cmd != nil {
+			for _, z := range cmd.Val() {
+				if !excluded[memberName(z)] {
+					entries = append(entries, z)
+				}
+			}
+		}
+		return entries
+	}
+
+	var moves []DivisionMove
+	scores := make(map[string]float64)
+	for upper := 1; upper < count; upper++ {
+		lower := upper + 1
+		var demoted []redis.Z
+		if sizes[upper] >= d.opts.Size {
+			demoted = eligible(bottoms[upper])
+			if int64(len(demoted)) > d.opts.Demote {
+				demoted = demoted[int64(len(demoted))-d.opts.Demote:]
+			}
+		}
+		promoted := eligible(tops[lower])
+		if int64(len(promoted)) > d.opts.Promote {
+			promoted = promoted[:d.opts.Promote]
+		}
+		if room := d.opts.Size - sizes[upper] + int64(len(demoted)); int64(len(promoted)) > room {
+			promoted = promoted[:max(room, 0)]
+		}
+		for _, z := range demoted {
+			moves = append(moves, DivisionMove{Member: memberName(z), From: upper, To: lower})
+			scores[memberName(z)] = z.Score
+		}
+		for _, z := range promoted {
+			moves = append(moves, DivisionMove{Member: memberName(z), From: lower, To: upper})
+			scores[memberName(z)] = z.Score
+		}
+	}
+	return moves, scores, nil
+}
+
+// excluded reads through tx the hidden and quarantined members, which Rotate doesn't move, and
+// returns them along with the hidden members that aren't quarantined, which are still in their
+// division's set.
+func (d *Divisions) excluded(ctx context.Context, tx *redis.Tx) (map[string]bool, []string, error) {
+	var hiddenCmd, quarantinedCmd *redis.StringSliceCmd
+	_, err := tx.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		if d.mfs.hideMembers {
+			hiddenCmd = pipe.SMembers(ctx, d.mfs.hiddenKey())
+		}
+		if d.mfs.quarantinable {
+			quarantinedCmd = pipe.ZRange(ctx, d.mfs.quarantineKey(), 0, -1)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	excluded := make(map[string]bool)
+	if quarantinedCmd != nil {
+		for _, member := range quarantinedCmd.Val() {
+			excluded[member] = true
+		}
+	}
+	var hidden []string
+	if hiddenCmd != nil {
+		for _, member := range hiddenCmd.Val() {
+			if !excluded[member] {
+				hidden = append(hidden, member)
+				excluded[member] = true
+			}
+		}
+	}
+	return excluded, hidden, nil
+}
@@ -0,0 +1,211 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// divisionMembers returns the members of every division, best first.
+func divisionMembers(t *testing.T, d *Divisions) [][]string {
+	t.Helper()
+	var divisions [][]string
+	for division := 1; division <= d.opts.Count; division++ {
+		members, err := d.Members(context.Background(), division, 10, 0)
+		if err != nil {
+			t.Fatalf("Members(%d) error = %v", division, err)
+		}
+		names := []string{}
+		for _, m := range members {
+			names = append(names, m.Member)
+		}
+		divisions = append(divisions, names)
+	}
+	return divisions
+}
+
+func TestDivisions(t *testing.T) {
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{Divisions: &DivisionOptions{Count: 3, Size: 3, Promote: 2, Demote: 1}})
+	ctx := context.Background()
+	d := mfs.Divisions()
+	for i := 1; i <= 7; i++ {
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": float64(10 * i)}, fmt.Sprintf("m%d", i)); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+	}
+	if division, err := d.Get(ctx, "m7"); err != nil || division != 3 {
+		t.Errorf("Get(m7) = %d, %v, expected new members in the last division", division, err)
+	}
+
+	// Empty divisions fill up with promotions, two at a time
+	for i := 0; i < 2; i++ {
+		if _, err := d.Rotate(ctx); err != nil {
+			t.Fatalf("Rotate() error = %v", err)
+		}
+	}
+	if got, want := divisionMembers(t, d), [][]string{{"m7", "m6"}, {"m5"}, {"m4", "m3", "m2", "m1"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("divisions after filling = %v, expected %v", got, want)
+	}
+
+	// Updates keep members in their division
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 100}, "m1"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	if rank, err := mfs.GetRank(ctx, "m1", InDivision(3)); err != nil || rank != 0 {
+		t.Errorf("GetRank(m1) in division 3 = %d, %v, expected 0", rank, err)
+	}
+	if _, err := d.Rotate(ctx); err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+
+	// The full top division demotes its last member to make room for a promotion
+	moves, err := d.Rotate(ctx)
+	if err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+	wantMoves := []DivisionMove{{"m5", 1, 2}, {"m1", 2, 1}, {"m3", 3, 2}}
+	if !reflect.DeepEqual(moves, wantMoves) {
+		t.Errorf("Rotate() = %v, expected %v", moves, wantMoves)
+	}
+	if got, want := divisionMembers(t, d), [][]string{{"m1", "m7", "m6"}, {"m5", "m4", "m3"}, {"m2"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("divisions after rotating = %v, expected %v", got, want)
+	}
+	if division, err := d.Get(ctx, "m5"); err != nil || division != 2 {
+		t.Errorf("Get(m5) = %d, %v, expected 2", division, err)
+	}
+	if count, err := d.Count(ctx, 1); err != nil || count != 3 {
+		t.Errorf("Count(1) = %d, %v, expected 3", count, err)
+	}
+
+	if _, err := d.Get(ctx, "nobody"); !errors.Is(err, ErrMemberNotFound) {
+		t.Errorf("Get() of a missing member error = %v, expected ErrMemberNotFound", err)
+	}
+	if _, err := d.Members(ctx, 4, 10, 0); err == nil {
+		t.Error("Members() of a division out of range succeeded")
+	}
+}
+
+func TestDivisions_Validation(t *testing.T) {
+	client, _ := newTestClient(t)
+	fields := []Field{{Name: "points", Sort: Descending, MaxValue: 1000}}
+	for name, opts := range map[string]DivisionOptions{
+		"single division": {Count: 1, Size: 10, Promote: 1, Demote: 1},
+		"no size":         {Count: 3, Promote: 1, Demote: 1},
+		"no moves":        {Count: 3, Size: 10},
+		"too small":       {Count: 3, Size: 3, Promote: 2, Demote: 2},
+	} {
+		if _, err := New(MultiFieldSetOptions{Name: "test", Fields: fields, Client: client, Divisions: &opts}); err == nil {
+			t.Errorf("New() with %s succeeded", name)
+		}
+	}
+	dimensions := []Dimension{{Name: DivisionDimension}}
+	divisions := &DivisionOptions{Count: 2, Size: 10, Promote: 1}
+	if _, err := New(MultiFieldSetOptions{Name: "test", Fields: fields, Client: client, Dimensions: dimensions, Divisions: divisions}); err == nil {
+		t.Error("New() with a dimension named like the divisions succeeded")
+	}
+
+	if d := newTestSet(t).Divisions(); d != nil {
+		t.Errorf("Divisions() = %v without Divisions, expected nil", d)
+	}
+}
+
+// beforeScript is a redis.Hook calling run once, before the next script is sent.
+type beforeScript struct {
+	run func()
+}
+
+func (h *beforeScript) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	if name := cmd.Name(); (name == "evalsha" || name == "eval") && h.run != nil {
+		run := h.run
+		h.run = nil
+		run()
+	}
+	return ctx, nil
+}
+
+func (h *beforeScript) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	return nil
+}
+
+func (h *beforeScript) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+func (h *beforeScript) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	return nil
+}
+
+func TestDivisions_RotateDuringUpdate(t *testing.T) {
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{Divisions: &DivisionOptions{Count: 2, Size: 2, Promote: 1}})
+	ctx := context.Background()
+	d := mfs.Divisions()
+	for i := 1; i <= 3; i++ {
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": float64(10 * i)}, fmt.Sprintf("m%d", i)); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+	}
+
+	// m3 is promoted after its update resolved its division, but before it is written
+	hook := &beforeScript{}
+	mfs.client.(*redis.Client).AddHook(hook)
+	hook.run = func() {
+		if _, err := d.Rotate(ctx); err != nil {
+			t.Errorf("Rotate() error = %v", err)
+		}
+	}
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 5}, "m3"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+
+	if got, want := divisionMembers(t, d), [][]string{{"m3"}, {"m2", "m1"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("divisions = %v, expected %v", got, want)
+	}
+	if division, err := d.Get(ctx, "m3"); err != nil || division != 1 {
+		t.Errorf("Get(m3) = %d, %v, expected 1", division, err)
+	}
+	members, err := d.Members(ctx, 1, 1, 0)
+	if err != nil || len(members) != 1 || FieldValue(members[0].Scores, "points") != 35 {
+		t.Errorf("Members(1) = %+v, %v, expected m3 with its updated points", members, err)
+	}
+}
+
+func TestDivisions_RotateSkipsHidden(t *testing.T) {
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{HideMembers: true, Divisions: &DivisionOptions{Count: 2, Size: 2, Promote: 1, Demote: 1}})
+	ctx := context.Background()
+	d := mfs.Divisions()
+	for i := 1; i <= 4; i++ {
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": float64(50 - 10*i)}, fmt.Sprintf("m%d", i)); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+	}
+	if err := mfs.Hide(ctx, "m1"); err != nil {
+		t.Fatalf("Hide() error = %v", err)
+	}
+
+	// The hidden best member isn't promoted
+	for _, want := range []DivisionMove{{"m2", 2, 1}, {"m3", 2, 1}} {
+		moves, err := d.Rotate(ctx)
+		if err != nil {
+			t.Fatalf("Rotate() error = %v", err)
+		}
+		if !reflect.DeepEqual(moves, []DivisionMove{want}) {
+			t.Errorf("Rotate() = %v, expected %v", moves, want)
+		}
+	}
+
+	// A hidden member doesn't take a place, so the top division isn't full and demotes no one
+	if err := mfs.Hide(ctx, "m2"); err != nil {
+		t.Fatalf("Hide() error = %v", err)
+	}
+	moves, err := d.Rotate(ctx)
+	if err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+	if want := []DivisionMove{{"m4", 2, 1}}; !reflect.DeepEqual(moves, want) {
+		t.Errorf("Rotate() = %v, expected %v", moves, want)
+	}
+}
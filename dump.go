@@ -0,0 +1,180 @@
+package zmultifield
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// restoreKey returns the key a dump is restored to before it replaces the set.
+func (mfs *MultiFieldSet) restoreKey() string {
+	return mfs.derivedKey("restore")
+}
+
+// DumpKey returns the set's sorted set serialized with DUMP, e.g. to move the leaderboard to
+// another Redis instance with RestoreFromDump, which is much faster than copying the members. The
+// payload is Redis's own format, so it can only be restored by a Redis server of the same or a
+// later version. Companion keys such as metadata, histories, field indexes and dimension sets are
+// not included. It fails with ErrSetEmpty if the set has no members.
+func (mfs *MultiFieldSet) DumpKey(ctx context.Context) ([]byte, error) {
+	var data string
+	err := mfs.primary(ctx, func(client redis.UniversalClient) error {
+		var err error
+		data, err = client.Dump(ctx, mfs.key).Result()
+		return err
+	})
+	if err == redis.Nil {
+		return nil, mfs.runOnError(ctx, "DumpKey", "", ErrSetEmpty)
+	} else if err != nil {
+		return nil, mfs.runOnError(ctx, "DumpKey", "", err)
+	}
+	return []byte(data), nil
+}
+
+// RestoreFromDump replaces the contents of the set with a payload returned by DumpKey, expiring
+// the set after ttl if it is positive. Like Rebuild, it holds the set's administrative lock, swaps
+// the members in at once, empties the dimension sets and records the set's layout. Field indexes
+// are then rebuilt from the restored members, so they are briefly incomplete.
+//
+// The payload is restored to a temporary key first and only swapped in if it holds a sorted set
+// whose lowest and highest zscores the set can decode, otherwise RestoreFromDump fails with
+// ErrInvalidDump or an UnrepresentableScoreError and leaves the set alone. It can't tell a dump of
+// a set with another layout whose zscores fit, so check the source's LayoutSignature when in
+// doubt. In Redis Cluster the set needs a HashTagKeyBuilder.
+func (mfs *MultiFieldSet) RestoreFromDump(ctx context.Context, data []byte, ttl time.Duration) error {
+	err := mfs.withLock(ctx, "RestoreFromDump", func(ctx context.Context) error {
+		return mfs.restoreDump(ctx, data, ttl)
+	})
+	return mfs.runOnError(ctx, "RestoreFromDump", "", err)
+}
+
+// restoreDump restores data to the restore key, checks it and swaps it in.
+func (mfs *MultiFieldSet) restoreDump(ctx context.Context, data []byte, ttl time.Duration) error {
+	staging := mfs.restoreKey()
+	err := mfs.write(ctx, func(client redis.UniversalClient) error {
+		return client.RestoreReplace(ctx, staging, 0, string(data)).Err()
+	})
+	if err != nil {
+		return err
+	}
+	if err := mfs.checkRestored(ctx, staging); err != nil {
+		// Best effort, the next restore replaces the key anyway
+		mfs.client.Del(ctx, staging)
+		return err
+	}
+
+	if err := mfs.swapRestored(ctx, staging); err != nil {
+		return err
+	}
+	if mfs.maintainFieldIndexes {
+		if err := mfs.indexRestored(ctx); err != nil {
+			return err
+		}
+	}
+	if ttl <= 0 {
+		return nil
+	}
+	return mfs.write(ctx, func(client redis.UniversalClient) error {
+		return client.PExpire(ctx, mfs.key, ttl).Err()
+	})
+}
+
+// checkRestored checks that key holds a sorted set whose lowest and highest zscores the set can
+// decode.
+func (mfs *MultiFieldSet) checkRestored(ctx context.Context, key string) error {
+	var typ *redis.StatusCmd
+	var first, last *redis.ZSliceCmd
+	err := mfs.primary(ctx, func(client redis.UniversalClient) error {
+		_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			typ = pipe.Type(ctx, key)
+			first = pipe.ZRangeWithScores(ctx, key, 0, 0)
+			last = pipe.ZRangeWithScores(ctx, key, -1, -1)
+			return nil
+		})
+		if typ != nil && typ.Val() != "zset" {
+			// The ranges fail with WRONGTYPE
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if typ.Val() != "zset" {
+		return fmt.Errorf("%w: restored a %s rather than a sorted set", ErrInvalidDump, typ.Val())
+	}
+	for _, z := range append(first.Val(), last.Val()...) {
+		if err := mfs.checkZScore(memberName(z), z.Score); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// swapRestored replaces the set and its companion keys with the sorted set at staging.
+func (mfs *MultiFieldSet) swapRestored(ctx context.Context, staging string) error {
+	if len(mfs.dimensions) > 0 {
+		if err := mfs.clearDimensions(ctx); err != nil {
+			return err
+		}
+	}
+	err := mfs.write(ctx, func(client redis.UniversalClient) error {
+		return mfs.txPipelined(ctx, client, func(pipe redis.Pipeliner) error {
+			pipe.Del(ctx, mfs.allKeys()...)
+			pipe.Rename(ctx, staging, mfs.key)
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+	return mfs.primary(ctx, func(client redis.UniversalClient) error {
+		return client.Set(ctx, mfs.layoutKey(), mfs.LayoutSignature(), 0).Err()
+	})
+}
+
+// indexRestored writes the field indexes of every member of the set, reading it in batches.
+func (mfs *MultiFieldSet) indexRestored(ctx context.Context) error {
+	for start := int64(0); ; start += scanBatchSize {
+		var results []redis.Z
+		err := mfs.primary(ctx, func(client redis.UniversalClient) error {
+			var err error
+			results, err = client.ZRangeWithScores(ctx, mfs.key, start, start+scanBatchSize-1).Result()
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		indexes := make([][]*redis.Z, len(mfs.fields))
+		for _, z := range results {
+			zscore, err := mfs.zscoreOf(memberName(z), z.Score)
+			if err != nil {
+				return err
+			}
+			for i, field := range mfs.fields {
+				raw := mfs.extractFieldScore(field, zscore)
+				indexes[i] = append(indexes[i], &redis.Z{Score: float64(raw.Int64()), Member: z.Member})
+			}
+		}
+		if len(results) > 0 {
+			err := mfs.write(ctx, func(client redis.UniversalClient) error {
+				_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+					for i, field := range mfs.fields {
+						pipe.ZAdd(ctx, mfs.fieldIndexKey(field), indexes[i]...)
+					}
+					return nil
+				})
+				return err
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if len(results) < scanBatchSize {
+			return nil
+		}
+	}
+}
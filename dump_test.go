@@ -0,0 +1,125 @@
+package zmultifield
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/alicebob/miniredis/v2/server"
+)
+
+// fakeDump registers DUMP and RESTORE commands on srv, which miniredis lacks, serializing sorted
+// sets as JSON maps from members to scores. A payload that isn't a JSON object restores a string.
+func fakeDump(t *testing.T, srv *miniredis.Miniredis) {
+	t.Helper()
+	err := srv.Server().Register("DUMP", func(c *server.Peer, cmd string, args []string) {
+		members, err := srv.ZMembers(args[0])
+		if err != nil {
+			c.WriteNull()
+			return
+		}
+		scores := make(map[string]float64, len(members))
+		for _, member := range members {
+			scores[member], _ = srv.ZScore(args[0], member)
+		}
+		data, _ := json.Marshal(scores)
+		c.WriteBulk(string(data))
+	})
+	if err != nil {
+		t.Fatalf("Register(DUMP) error = %v", err)
+	}
+	err = srv.Server().Register("RESTORE", func(c *server.Peer, cmd string, args []string) {
+		key, payload := args[0], args[2]
+		srv.Del(key)
+		var scores map[string]float64
+		if json.Unmarshal([]byte(payload), &scores) != nil {
+			srv.Set(key, payload)
+		}
+		for member, score := range scores {
+			srv.ZAdd(key, score, member)
+		}
+		c.WriteOK()
+	})
+	if err != nil {
+		t.Fatalf("Register(RESTORE) error = %v", err)
+	}
+}
+
+// newDumpSet creates a set like newTestSetWithOptions on a server supporting DUMP and RESTORE.
+func newDumpSet(t *testing.T, opts MultiFieldSetOptions) (*MultiFieldSet, *miniredis.Miniredis) {
+	t.Helper()
+	client, srv := newTestClient(t)
+	fakeDump(t, srv)
+	opts.Name = "test"
+	opts.Fields = []Field{
+		{Name: "points", Sort: Descending, MaxValue: 1000, UpdateType: Incremental},
+		{Name: "deaths", Sort: Ascending, MaxValue: 100, UpdateType: Incremental},
+	}
+	opts.Client = client
+	mfs, err := New(opts)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return mfs, srv
+}
+
+func TestDumpAndRestore(t *testing.T) {
+	ctx := context.Background()
+	src, _ := newDumpSet(t, MultiFieldSetOptions{})
+	dst, dstServer := newDumpSet(t, MultiFieldSetOptions{MaintainFieldIndexes: true})
+	if _, err := src.DumpKey(ctx); !errors.Is(err, ErrSetEmpty) {
+		t.Errorf("DumpKey() of an empty set error = %v, expected ErrSetEmpty", err)
+	}
+	if _, err := src.IncreaseScore(ctx, map[string]float64{"points": 30, "deaths": 2}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	if _, err := src.IncreaseScore(ctx, map[string]float64{"points": 50, "deaths": 7}, "bob"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	if _, err := dst.IncreaseScore(ctx, map[string]float64{"points": 10}, "carol"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+
+	data, err := src.DumpKey(ctx)
+	if err != nil {
+		t.Fatalf("DumpKey() error = %v", err)
+	}
+	if err := dst.RestoreFromDump(ctx, data, time.Hour); err != nil {
+		t.Fatalf("RestoreFromDump() error = %v", err)
+	}
+
+	members, err := dst.GetTopMembers(ctx, 10)
+	if err != nil || len(members) != 2 || members[0].Member != "bob" || FieldValue(members[1].Scores, "deaths") != 2 {
+		t.Errorf("GetTopMembers() = %v, %v, expected bob and alice", members, err)
+	}
+	if rank, err := dst.GetFieldRank(ctx, "deaths", "alice"); err != nil || rank != 0 {
+		t.Errorf("GetFieldRank(deaths) = %d, %v, expected alice first from the rebuilt index", rank, err)
+	}
+	if ttl := dstServer.TTL(dst.key); ttl != time.Hour {
+		t.Errorf("TTL = %v, expected 1h", ttl)
+	}
+}
+
+func TestRestoreFromDump_Invalid(t *testing.T) {
+	ctx := context.Background()
+	mfs, srv := newDumpSet(t, MultiFieldSetOptions{})
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 10}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+
+	if err := mfs.RestoreFromDump(ctx, []byte("not a sorted set"), 0); !errors.Is(err, ErrInvalidDump) {
+		t.Errorf("RestoreFromDump() of a string error = %v, expected ErrInvalidDump", err)
+	}
+	if err := mfs.RestoreFromDump(ctx, []byte(`{"mallory": 1e18}`), 0); !errors.Is(err, ErrUnrepresentableScore) {
+		t.Errorf("RestoreFromDump() of foreign scores error = %v, expected ErrUnrepresentableScore", err)
+	}
+	if exists, err := mfs.MemberExists(ctx, "alice"); err != nil || !exists {
+		t.Errorf("MemberExists(alice) = %v, %v, expected the set to be left alone", exists, err)
+	}
+	if srv.Exists(mfs.restoreKey()) {
+		t.Errorf("restore key left behind")
+	}
+}
@@ -0,0 +1,165 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErasureReport lists what EraseMember deleted for a member.
+type ErasureReport struct {
+	Set    string
+	Member string
+	// RemovedFrom lists the keys of the shared structures the member was removed from: the main
+	// set, field indexes, dimension sets, the activity index, the hidden set, snapshots,
+	// extractions and the audit stream.
+	RemovedFrom []string
+	// DeletedKeys lists the per-member keys that were deleted: metadata, history, the update rate
+	// counter and the recorded dimension values.
+	DeletedKeys []string
+}
+
+// Empty reports whether nothing was stored for the member.
+func (r *ErasureReport) Empty() bool {
+	return len(r.RemovedFrom) == 0 && len(r.DeletedKeys) == 0
+}
+
+// EraseMember removes every trace of a member the set stores in Redis, e.g. to honor a GDPR erasure
+// request: its scores, field index, dimension set and activity entries, metadata, history, hidden
+// flag and update counter, and its entries in snapshots, extractions and the audit stream, without
+// recording the removal there. Updates for the member still waiting in the WriteQueue are not
+// removed and recreate it when replayed. Erasing a member that isn't stored is not an error and
+// returns an empty report. If the member was erased but its audit entries couldn't be deleted, or
+// the member counts of snapshots or extractions couldn't be updated, the report is returned along
+// with the error.
+func (mfs *MultiFieldSet) EraseMember(ctx context.Context, member string) (*ErasureReport, error) {
+	var snapshots, extractions []string
+	err := mfs.primary(ctx, func(client redis.UniversalClient) error {
+		var err error
+		if snapshots, err = client.ZRange(ctx, mfs.snapshotsKey(), 0, -1).Result(); err != nil {
+			return err
+		}
+		extractions, err = client.ZRange(ctx, mfs.extractionsKey(), 0, -1).Result()
+		return err
+	})
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "EraseMember", member, err)
+	}
+	var dimensions map[string][]string
+	if len(mfs.dimensions) > 0 {
+		if dimensions, err = mfs.memberDimensionKeys(ctx, []string{member}); err != nil {
+			return nil, mfs.runOnError(ctx, "EraseMember", member, err)
+		}
+	}
+
+	// Shared structures the member is removed from, along with the metadata hash whose member
+	// count is decremented when it was
+	type removal struct {
+		key, info string
+		cmd       *redis.IntCmd
+	}
+	removals := []*removal{{key: mfs.key}}
+	if mfs.maintainFieldIndexes {
+		for _, field := range mfs.fields {
+			removals = append(removals, &removal{key: mfs.fieldIndexKey(field)})
+		}
+	}
+	for _, key := range dimensions[member] {
+		removals = append(removals, &removal{key: key})
+	}
+	if mfs.trackActivity {
+		removals = append(removals, &removal{key: mfs.activityKey()})
+	}
+	for _, label := range snapshots {
+		removals = append(removals, &removal{key: mfs.snapshotKey(label), info: mfs.snapshotInfoKey(label)})
+	}
+	for _, label := range extractions {
+		removals = append(removals, &removal{key: mfs.extractionKey(label), info: mfs.extractionInfoKey(label)})
+	}
+	var hidden *redis.IntCmd
+	perMember := []string{mfs.metaKey(member), mfs.historyKey(member), mfs.updateCountKey(member), mfs.memberDimensionsKey(member)}
+	deleted := make([]*redis.IntCmd, len(perMember))
+
+	err = mfs.write(ctx, func(client redis.UniversalClient) error {
+		_, err := client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, r := range removals {
+				r.cmd = pipe.ZRem(ctx, r.key, member)
+			}
+			if mfs.hideMembers {
+				hidden = pipe.SRem(ctx, mfs.hiddenKey(), member)
+			}
+			for i, key := range perMember {
+				deleted[i] = pipe.Del(ctx, key)
+			}
+			return nil
+		})
+		return err
+	})
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "EraseMember", member, err)
+	}
+
+	report := &ErasureReport{Set: mfs.name, Member: member}
+	var infos []string
+	for _, r := range removals {
+		if r.cmd.Val() == 0 {
+			continue
+		}
+		report.RemovedFrom = append(report.RemovedFrom, r.key)
+		if r.info != "" {
+			infos = append(infos, r.info)
+		}
+	}
+	if hidden != nil && hidden.Val() > 0 {
+		report.RemovedFrom = append(report.RemovedFrom, mfs.hiddenKey())
+	}
+	for i, key := range perMember {
+		if deleted[i].Val() > 0 {
+			report.DeletedKeys = append(report.DeletedKeys, key)
+		}
+	}
+	if mfs.audit {
+		erased, err := mfs.eraseFromAudit(ctx, member)
+		if erased {
+			report.RemovedFrom = append(report.RemovedFrom, mfs.auditKey())
+		}
+		if err != nil {
+			return report, mfs.runOnError(ctx, "EraseMember", member, err)
+		}
+	}
+
+	// Keep the member counts of snapshots and extractions in line with their contents
+	if len(infos) > 0 {
+		err = mfs.write(ctx, func(client redis.UniversalClient) error {
+			_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+				for _, info := range infos {
+					pipe.HIncrBy(ctx, info, "members", -1)
+				}
+				return nil
+			})
+			return err
+		})
+		if err != nil {
+			return report, mfs.runOnError(ctx, "EraseMember", member, err)
+		}
+	}
+	return report, nil
+}
+
+// EraseMember runs EraseMember on every registered set and returns the reports by set name. Sets
+// that failed are left out of the map and their errors are joined into the returned error.
+func (r *Registry) EraseMember(ctx context.Context, member string) (map[string]*ErasureReport, error) {
+	reports := make(map[string]*ErasureReport)
+	var errs []error
+	for _, set := range r.snapshot() {
+		report, err := set.EraseMember(ctx, member)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("set %s: %w", set.GetName(), err))
+			continue
+		}
+		reports[set.GetName()] = report
+	}
+	return reports, errors.Join(errs...)
+}
@@ -0,0 +1,63 @@
+package zmultifield
+
+import (
+	"context"
+	"testing"
+)
+
+func TestEraseMember(t *testing.T) {
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{MaintainFieldIndexes: true, HideMembers: true, History: &HistoryOptions{}})
+	ctx := context.Background()
+
+	for member, points := range map[string]float64{"alice": 30, "bob": 20} {
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": points}, member); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+	}
+	if err := mfs.SetMemberMeta(ctx, "alice", map[string]string{"name": "Alice"}); err != nil {
+		t.Fatalf("SetMemberMeta() error = %v", err)
+	}
+	if _, err := mfs.Snapshot(ctx, "week-1"); err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+	if _, err := mfs.ExtractTop(ctx, 2, ExtractOptions{Label: "season-1"}); err != nil {
+		t.Fatalf("ExtractTop() error = %v", err)
+	}
+	if err := mfs.Hide(ctx, "alice"); err != nil {
+		t.Fatalf("Hide() error = %v", err)
+	}
+
+	registry := NewRegistry(mfs.client)
+	if err := registry.Register(ctx, mfs); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	reports, err := registry.EraseMember(ctx, "alice")
+	if err != nil {
+		t.Fatalf("EraseMember() error = %v", err)
+	}
+	report := reports["test"]
+	// Main set, two field indexes, snapshot, extraction and hidden set
+	if report == nil || len(report.RemovedFrom) != 6 {
+		t.Fatalf("report = %+v, expected alice removed from 6 structures", report)
+	}
+	if len(report.DeletedKeys) != 2 || report.DeletedKeys[0] != mfs.metaKey("alice") || report.DeletedKeys[1] != mfs.historyKey("alice") {
+		t.Errorf("DeletedKeys = %v, expected metadata and history", report.DeletedKeys)
+	}
+
+	if exists, err := mfs.MemberExists(ctx, "alice"); err != nil || exists {
+		t.Errorf("MemberExists() = %v, %v, expected alice to be erased", exists, err)
+	}
+	extraction, err := mfs.GetExtraction(ctx, "season-1")
+	if err != nil || len(extraction.Entries) != 1 || extraction.Entries[0].Member != "bob" {
+		t.Errorf("GetExtraction() = %+v, %v, expected only bob", extraction, err)
+	}
+	snapshots, err := mfs.ListSnapshots(ctx)
+	if err != nil || len(snapshots) != 1 || snapshots[0].Members != 1 {
+		t.Errorf("ListSnapshots() = %+v, %v, expected one member left", snapshots, err)
+	}
+
+	report, err = mfs.EraseMember(ctx, "alice")
+	if err != nil || !report.Empty() {
+		t.Errorf("EraseMember() again = %+v, %v, expected an empty report", report, err)
+	}
+}
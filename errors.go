@@ -0,0 +1,236 @@
+package zmultifield
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+var (
+	// ErrFieldNotFound is returned when an operation references a field that is not part of the set.
+	ErrFieldNotFound = errors.New("field not found")
+	// ErrScoreOutOfRange is returned when an update would move a field score outside its allowed range.
+	ErrScoreOutOfRange = errors.New("score out of range")
+	// ErrInvalidValue is returned when a field value or delta is not a finite integer.
+	ErrInvalidValue = errors.New("invalid field value")
+	// ErrMemberNotFound is returned when an operation requires a member that is not in the set.
+	ErrMemberNotFound = errors.New("member not found")
+	// ErrMemberExists is returned when an operation would overwrite a member that is already in the set.
+	ErrMemberExists = errors.New("member already exists")
+	// ErrUnknownUpdateType is returned when a field has an UpdateType that is not supported.
+	ErrUnknownUpdateType = errors.New("unknown update type")
+	// ErrFieldIndexesDisabled is returned by per-field index queries when MaintainFieldIndexes is off.
+	ErrFieldIndexesDisabled = errors.New("field indexes are not maintained")
+	// ErrIncompatibleSets is returned when sets with different field layouts are combined.
+	ErrIncompatibleSets = errors.New("sets have incompatible field layouts")
+	// ErrCrossSlot is returned by New when the keys of a set would hash to different cluster slots.
+	ErrCrossSlot = errors.New("keys hash to different cluster slots")
+	// ErrNotificationsDisabled is returned by Subscribe when the set has no NotificationOptions.
+	ErrNotificationsDisabled = errors.New("notifications are not enabled")
+	// ErrHistoryDisabled is returned by GetHistory when the set has no HistoryOptions.
+	ErrHistoryDisabled = errors.New("history is not enabled")
+	// ErrParticipationDisabled is returned by UniqueParticipants when the set has no
+	// ParticipationOptions.
+	ErrParticipationDisabled = errors.New("participation counting is not enabled")
+	// ErrActivityDisabled is returned by GetActiveMembers and GetIdleMembers when TrackActivity is
+	// not enabled.
+	ErrActivityDisabled = errors.New("activity tracking is not enabled")
+	// ErrCacheDisabled is returned by WatchInvalidations when the set has no CacheOptions.
+	ErrCacheDisabled = errors.New("cache is not enabled")
+	// ErrHidingDisabled is returned by Hide and Unhide when HideMembers is not enabled.
+	ErrHidingDisabled = errors.New("hiding members is not enabled")
+	// ErrDimensionNotFound is returned when a read selects a dimension the set doesn't have.
+	ErrDimensionNotFound = errors.New("dimension not found")
+	// ErrInvalidCursor is returned by GetMembersAfter for a cursor it didn't return.
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrSnapshotNotFound is returned by Diff when a snapshot label doesn't exist.
+	ErrSnapshotNotFound = errors.New("snapshot not found")
+	// ErrAlreadyExtracted is returned by ExtractTop when the label has already been extracted.
+	ErrAlreadyExtracted = errors.New("label already extracted")
+	// ErrExtractionNotFound is returned by GetExtraction when a label hasn't been extracted.
+	ErrExtractionNotFound = errors.New("extraction not found")
+	// ErrSetExists is returned by Registry when a set with the same name is already registered.
+	ErrSetExists = errors.New("set already registered")
+	// ErrSetNotRegistered is returned by Registry when no set with the given name is registered.
+	ErrSetNotRegistered = errors.New("set not registered")
+	// ErrNoTiers is returned by GetTier and ComputeTiers on a set without Tiers.
+	ErrNoTiers = errors.New("no tiers configured")
+	// ErrNoDivisions is returned by DivisionsTask on a set without Divisions.
+	ErrNoDivisions = errors.New("no divisions configured")
+	// ErrDuplicateUpdate is returned by updates made with WithIdempotencyKey when an update with the
+	// same key was already applied. The update is skipped, so callers can treat it as a success.
+	ErrDuplicateUpdate = errors.New("update already applied")
+	// ErrUpdateQueued is returned by IncreaseScore when an update was queued for replay by
+	// ReplayQueued rather than written, see WriteQueueOptions.
+	ErrUpdateQueued = errors.New("update queued for replay")
+	// ErrQueueFull is returned by MemoryQueue.Append when the queue is at capacity.
+	ErrQueueFull = errors.New("write queue is full")
+	// ErrWriterClosed is returned by BufferedWriter.Add after the writer has been closed.
+	ErrWriterClosed = errors.New("buffered writer is closed")
+	// ErrBufferingUnsupported is returned by BufferedWriter.Add on sets whose updates the buffered
+	// writes can't enforce: sets with before-update hooks, such as Quarantinable sets, and sets with
+	// UpdateOnlyExisting, MaxMembers or dimensions.
+	ErrBufferingUnsupported = errors.New("set doesn't support buffered writes")
+	// ErrIncrementTooLarge is returned when an update raises a field by more than GuardOptions
+	// allows. IncrementLimitError also matches it.
+	ErrIncrementTooLarge = errors.New("increment too large")
+	// ErrRateLimited is returned when a member is updated more often than GuardOptions allows.
+	// RateLimitError also matches it.
+	ErrRateLimited = errors.New("update rate limit exceeded")
+	// ErrUpdateConflict is returned by optimistic updates and UpdateIf when they kept conflicting
+	// with concurrent writes. ExpectationError also matches it.
+	ErrUpdateConflict = errors.New("update conflicted with concurrent writes")
+	// ErrFrozen is returned by writes to a set frozen with Freeze.
+	ErrFrozen = errors.New("set is frozen")
+	// ErrFreezingDisabled is returned by Freeze and Unfreeze when Freezable is not enabled.
+	ErrFreezingDisabled = errors.New("freezing is not enabled")
+	// ErrQuarantineDisabled is returned by Quarantine, Unquarantine and GetQuarantined when
+	// Quarantinable is not enabled.
+	ErrQuarantineDisabled = errors.New("quarantine is not enabled")
+	// ErrAuditDisabled is returned by Replay and ReplayFrom when the set replayed from isn't Audit.
+	ErrAuditDisabled = errors.New("audit is not enabled")
+	// ErrQuarantined is returned by updates of a member pulled from the set with Quarantine.
+	ErrQuarantined = errors.New("member is quarantined")
+	// ErrInvalidMember is returned by a MemberSet reading a member its MemberCodec can't decode.
+	ErrInvalidMember = errors.New("invalid member")
+	// ErrUnrepresentableScore is returned by reads that find a zscore the set can't decode exactly,
+	// written by another tool or corrupted. UnrepresentableScoreError also matches it.
+	ErrUnrepresentableScore = errors.New("unrepresentable score")
+	// ErrLocked is returned by Rebuild, Snapshot, DeleteSnapshot and Registry.Migrate when another
+	// administrative operation on the set is running, in this process or another. LockedError also
+	// matches it.
+	ErrLocked = errors.New("set is locked")
+	// ErrLockLost is returned by an administrative operation whose lock expired or was taken over
+	// before it finished. The operation's context was cancelled, so it may be partly applied.
+	ErrLockLost = errors.New("lock lost")
+	// ErrLayoutChanged is returned by CheckLayout when the set packs its fields differently from
+	// the layout recorded in Redis. LayoutChangedError also matches it.
+	ErrLayoutChanged = errors.New("layout changed")
+	// ErrAverageUnsupported is returned when an Average field is updated by something that can't
+	// maintain its count, such as a BufferedWriter, a LexSet or a WeightedSet.
+	ErrAverageUnsupported = errors.New("average fields can't be updated this way")
+	// ErrDerivedSetNotFound is returned when no derived set has the given name.
+	ErrDerivedSetNotFound = errors.New("derived set not found")
+	// ErrNotReplicated is returned by writes made under WaitForReplicas that too few replicas
+	// acknowledged in time. ReplicationError also matches it.
+	ErrNotReplicated = errors.New("write not replicated")
+	// ErrSetEmpty is returned by DumpKey for a set without members.
+	ErrSetEmpty = errors.New("set is empty")
+	// ErrInvalidDump is returned by RestoreFromDump for a payload that doesn't hold a sorted set.
+	ErrInvalidDump = errors.New("invalid dump")
+)
+
+// ScoreOutOfRangeError describes a field score that fell outside the range [Min, Max], where Min
+// is the field's MinValue, or 0 if Min is nil. It matches ErrScoreOutOfRange with errors.Is.
+type ScoreOutOfRangeError struct {
+	Field string
+	Value *big.Int
+	Min   *big.Int
+	Max   *big.Int
+}
+
+// Error implements the error interface.
+func (e *ScoreOutOfRangeError) Error() string {
+	if e.Min != nil && e.Min.Sign() != 0 {
+		return fmt.Sprintf("score %v out of range for field %s (min %v, max %v)", e.Value, e.Field, e.Min, e.Max)
+	}
+	return fmt.Sprintf("score %v out of range for field %s (max %v)", e.Value, e.Field, e.Max)
+}
+
+// Is reports whether target is ErrScoreOutOfRange.
+func (e *ScoreOutOfRangeError) Is(target error) bool {
+	return target == ErrScoreOutOfRange
+}
+
+// UnrepresentableScoreError describes a zscore read from Redis that isn't an integer in the range
+// the set's schema produces. Decoding it would silently return wrong field values. It matches
+// ErrUnrepresentableScore with errors.Is; CheckIntegrity and Repair find and fix such scores.
+type UnrepresentableScoreError struct {
+	Member string
+	Score  float64
+	Reason string
+}
+
+// Error implements the error interface.
+func (e *UnrepresentableScoreError) Error() string {
+	return fmt.Sprintf("unrepresentable score %v for member %s: %s", e.Score, e.Member, e.Reason)
+}
+
+// Is reports whether target is ErrUnrepresentableScore.
+func (e *UnrepresentableScoreError) Is(target error) bool {
+	return target == ErrUnrepresentableScore
+}
+
+// LockedError describes the administrative operation holding a set's lock. It matches ErrLocked
+// with errors.Is. Operation is empty if the lock was released while it was being described.
+type LockedError struct {
+	Operation string
+	// ExpiresIn is how long the lock lasts unless its holder extends it.
+	ExpiresIn time.Duration
+}
+
+// Error implements the error interface.
+func (e *LockedError) Error() string {
+	if e.Operation == "" {
+		return ErrLocked.Error()
+	}
+	return fmt.Sprintf("set is locked by %s, expiring in %v", e.Operation, e.ExpiresIn)
+}
+
+// Is reports whether target is ErrLocked.
+func (e *LockedError) Is(target error) bool {
+	return target == ErrLocked
+}
+
+// LayoutChangedError describes a set whose layout differs from the one recorded in Redis, e.g.
+// because its fields were reordered or resized without rebuilding the data. Both layouts are
+// given as returned by LayoutSignature. It matches ErrLayoutChanged with errors.Is.
+type LayoutChangedError struct {
+	Recorded string
+	Current  string
+}
+
+// Error implements the error interface.
+func (e *LayoutChangedError) Error() string {
+	return fmt.Sprintf("layout changed from %q to %q", e.Recorded, e.Current)
+}
+
+// Is reports whether target is ErrLayoutChanged.
+func (e *LayoutChangedError) Is(target error) bool {
+	return target == ErrLayoutChanged
+}
+
+// fieldNotFoundError returns an error wrapping ErrFieldNotFound for the named field.
+func fieldNotFoundError(name string) error {
+	return fmt.Errorf("%w: %s", ErrFieldNotFound, name)
+}
+
+// ReplicationError describes a write acknowledged by fewer replicas than required by
+// WaitForReplicas. The write was applied on the primary, but may be lost if it fails over. It
+// matches ErrNotReplicated with errors.Is.
+type ReplicationError struct {
+	Replicas     int
+	Acknowledged int
+}
+
+// Error implements the error interface.
+func (e *ReplicationError) Error() string {
+	return fmt.Sprintf("write acknowledged by %d of %d replicas", e.Acknowledged, e.Replicas)
+}
+
+// Is reports whether target is ErrNotReplicated.
+func (e *ReplicationError) Is(target error) bool {
+	return target == ErrNotReplicated
+}
+
+// outOfRangeError returns a ScoreOutOfRangeError for a raw field score, reported as the
+// user-facing value so descending fields read the same way as in GetScores.
+func outOfRangeError(field *multiField, raw *big.Int) error {
+	return &ScoreOutOfRangeError{
+		Field: field.Name,
+		Value: field.toDisplay(raw),
+		Min:   field.minDisplay(),
+		Max:   field.maxDisplay(),
+	}
+}
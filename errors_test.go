@@ -0,0 +1,105 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+)
+
+func TestErrors_FieldNotFound(t *testing.T) {
+	mfs := newTestSet(t)
+
+	_, err := mfs.IncreaseScore(context.Background(), map[string]float64{"missing": 1}, "alice")
+	if !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("IncreaseScore() error = %v, expected ErrFieldNotFound", err)
+	}
+
+	_, err = mfs.MaxScoreWithFields(map[string]float64{"missing": 1})
+	if !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("MaxScoreWithFields() error = %v, expected ErrFieldNotFound", err)
+	}
+}
+
+func TestErrors_ScoreOutOfRange(t *testing.T) {
+	mfs := newTestSet(t)
+	ctx := context.Background()
+
+	// points is descending with 10 bits, so 1024 exceeds the maximum of 1023
+	_, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 1024}, "alice")
+	if !errors.Is(err, ErrScoreOutOfRange) {
+		t.Fatalf("IncreaseScore() error = %v, expected ErrScoreOutOfRange", err)
+	}
+
+	var rangeErr *ScoreOutOfRangeError
+	if !errors.As(err, &rangeErr) {
+		t.Fatalf("IncreaseScore() error = %v, expected *ScoreOutOfRangeError", err)
+	}
+	if rangeErr.Field != "points" || rangeErr.Value.Int64() != 1024 || rangeErr.Max.Int64() != 1023 {
+		t.Errorf("ScoreOutOfRangeError = %+v, expected points 1024 > 1023", rangeErr)
+	}
+
+	// deaths is ascending and cannot go below zero
+	_, err = mfs.IncreaseScore(ctx, map[string]float64{"deaths": -1}, "alice")
+	if !errors.As(err, &rangeErr) || rangeErr.Field != "deaths" || rangeErr.Value.Int64() != -1 {
+		t.Errorf("IncreaseScore() error = %v, expected deaths -1 out of range", err)
+	}
+}
+
+func TestErrors_UnrepresentableScore(t *testing.T) {
+	ctx := context.Background()
+	mfs := corruptTestSet(t, MultiFieldSetOptions{})
+
+	_, err := mfs.GetScores(ctx, "fractional")
+	var scoreErr *UnrepresentableScoreError
+	if !errors.As(err, &scoreErr) || !errors.Is(err, ErrUnrepresentableScore) {
+		t.Fatalf("GetScores() error = %v, expected *UnrepresentableScoreError", err)
+	}
+	if scoreErr.Member != "fractional" || scoreErr.Score != 12.5 || scoreErr.Reason != "score is not an integer" {
+		t.Errorf("UnrepresentableScoreError = %+v, expected fractional 12.5 not an integer", scoreErr)
+	}
+
+	if _, err := mfs.GetScores(ctx, "valid"); err != nil {
+		t.Errorf("GetScores() for a valid member error = %v", err)
+	}
+	if _, err := mfs.GetScoreForField(ctx, "points", "negative"); !errors.Is(err, ErrUnrepresentableScore) {
+		t.Errorf("GetScoreForField() error = %v, expected ErrUnrepresentableScore", err)
+	}
+	if _, err := mfs.GetTopMembers(ctx, 10); !errors.Is(err, ErrUnrepresentableScore) {
+		t.Errorf("GetTopMembers() error = %v, expected ErrUnrepresentableScore", err)
+	}
+	if _, err := mfs.GetMembers(ctx, 0, 0, MinField("points", 1)); !errors.Is(err, ErrUnrepresentableScore) {
+		t.Errorf("GetMembers() with a filter error = %v, expected ErrUnrepresentableScore", err)
+	}
+	err = mfs.Iterate(ctx, Filter{}, func(MemberScores) bool { return true })
+	if !errors.Is(err, ErrUnrepresentableScore) {
+		t.Errorf("Iterate() error = %v, expected ErrUnrepresentableScore", err)
+	}
+	// Updates read the current score and must not build on a corrupted one
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 1}, "infinite"); !errors.Is(err, ErrUnrepresentableScore) {
+		t.Errorf("IncreaseScore() error = %v, expected ErrUnrepresentableScore", err)
+	}
+
+	if _, err := mfs.Repair(ctx, RepairRemove); err != nil {
+		t.Fatalf("Repair() error = %v", err)
+	}
+	if members, err := mfs.GetTopMembers(ctx, 10); err != nil || len(members) != 1 {
+		t.Errorf("GetTopMembers() after Repair = %v, %v, expected the valid member", members, err)
+	}
+}
+
+func TestErrors_UnrepresentableScore_AbovePrecision(t *testing.T) {
+	ctx := context.Background()
+	mfs := newTestSet(t)
+
+	// 2^53 + 2 is an integer as a float64 but may not be the score that was written
+	if err := mfs.client.ZAdd(ctx, mfs.GetKey(), &redis.Z{Score: 1<<53 + 2, Member: "alice"}).Err(); err != nil {
+		t.Fatalf("ZAdd failed: %v", err)
+	}
+	_, err := mfs.GetScores(ctx, "alice")
+	var scoreErr *UnrepresentableScoreError
+	if !errors.As(err, &scoreErr) || scoreErr.Reason != "score exceeds 2^53 and has lost precision" {
+		t.Errorf("GetScores() error = %v, expected a loss of precision", err)
+	}
+}
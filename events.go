@@ -0,0 +1,125 @@
+package zmultifield
+
+import (
+	"context"
+	"time"
+)
+
+// Event types returned by Event.EventType, e.g. to route events to a Kafka topic or NATS subject.
+const (
+	EventTypeUpdate     = "update"
+	EventTypeRankChange = "rank_change"
+	EventTypeSeason     = "season"
+	EventTypeTierChange = "tier_change"
+)
+
+// Event is a change emitted to an EventSink: an UpdateEvent, a RankChangeEvent, a SeasonEvent or a
+// TierChangedEvent.
+// Events are plain structs, so they can be encoded with encoding/json or any other codec.
+type Event interface {
+	// EventType returns one of the EventType constants.
+	EventType() string
+	// EventKey returns the key to partition events by so consumers see them in order: the member
+	// for updates, rank and tier changes, the set's name for season events.
+	EventKey() string
+}
+
+// EventSink receives the events of a set, see MultiFieldSetOptions.EventSink. Emit is called
+// synchronously after the change has been written, so a sink forwarding events to a slow broker
+// should buffer them. An error doesn't fail the change; it is reported to the error hooks.
+type EventSink interface {
+	Emit(ctx context.Context, event Event) error
+}
+
+// EventSinkFunc adapts a function to an EventSink.
+type EventSinkFunc func(ctx context.Context, event Event) error
+
+// Emit calls f.
+func (f EventSinkFunc) Emit(ctx context.Context, event Event) error {
+	return f(ctx, event)
+}
+
+// EventType implements Event.
+func (e UpdateEvent) EventType() string { return EventTypeUpdate }
+
+// EventKey implements Event.
+func (e UpdateEvent) EventKey() string { return e.Member }
+
+// RankChangeEvent describes an update moving a member to another rank. Ranks are 0-based and -1
+// means the member wasn't in the set.
+type RankChangeEvent struct {
+	Set     string
+	Member  string
+	OldRank int64
+	NewRank int64
+}
+
+// EventType implements Event.
+func (e RankChangeEvent) EventType() string { return EventTypeRankChange }
+
+// EventKey implements Event.
+func (e RankChangeEvent) EventKey() string { return e.Member }
+
+// SeasonEventType identifies the milestone described by a SeasonEvent.
+type SeasonEventType string
+
+const (
+	// SeasonFrozen is emitted by Freeze.
+	SeasonFrozen SeasonEventType = "frozen"
+	// SeasonUnfrozen is emitted by Unfreeze.
+	SeasonUnfrozen SeasonEventType = "unfrozen"
+	// SeasonSnapshotted is emitted by Snapshot, with the snapshot's label.
+	SeasonSnapshotted SeasonEventType = "snapshotted"
+	// SeasonExtracted is emitted by ExtractTop, with the extraction's label if it has one.
+	SeasonExtracted SeasonEventType = "extracted"
+	// SeasonCleared is emitted by Clear, e.g. when a window is rotated.
+	SeasonCleared SeasonEventType = "cleared"
+)
+
+// SeasonEvent describes a milestone in the life of a leaderboard, such as the end of a season.
+type SeasonEvent struct {
+	Set   string
+	Type  SeasonEventType
+	Label string
+	Time  time.Time
+}
+
+// EventType implements Event.
+func (e SeasonEvent) EventType() string { return EventTypeSeason }
+
+// EventKey implements Event.
+func (e SeasonEvent) EventKey() string { return e.Set }
+
+// emit sends event to the event sink, if any, reporting a failure to the error hooks.
+func (mfs *MultiFieldSet) emit(ctx context.Context, member string, event Event) {
+	if mfs.eventSink == nil {
+		return
+	}
+	if err := mfs.eventSink.Emit(ctx, event); err != nil {
+		mfs.runOnError(ctx, "Emit", member, err)
+	}
+}
+
+// emitUpdate emits the events of an update that has been written.
+func (mfs *MultiFieldSet) emitUpdate(ctx context.Context, event *UpdateEvent, result *UpdateResult) {
+	if mfs.eventSink == nil {
+		return
+	}
+	mfs.emit(ctx, event.Member, *event)
+	if result.OldRank != result.NewRank {
+		mfs.emit(ctx, event.Member, RankChangeEvent{
+			Set:     mfs.name,
+			Member:  event.Member,
+			OldRank: result.OldRank,
+			NewRank: result.NewRank,
+		})
+	}
+}
+
+// emitSeason emits a SeasonEvent of the given type.
+func (mfs *MultiFieldSet) emitSeason(ctx context.Context, typ SeasonEventType, label string) {
+	if mfs.eventSink == nil {
+		return
+	}
+	mfs.emit(ctx, "", SeasonEvent{Set: mfs.name, Type: typ, Label: label, Time: time.Now()})
+}
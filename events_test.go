@@ -0,0 +1,103 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// recordingSink is an EventSink keeping the events it receives.
+type recordingSink struct {
+	events []Event
+	fail   error
+}
+
+func (s *recordingSink) Emit(ctx context.Context, event Event) error {
+	s.events = append(s.events, event)
+	return s.fail
+}
+
+func TestEventSink_Updates(t *testing.T) {
+	sink := &recordingSink{}
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{EventSink: sink})
+	ctx := context.Background()
+
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 10}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	if len(sink.events) != 2 {
+		t.Fatalf("events = %v, expected an update and a rank change", sink.events)
+	}
+	update, ok := sink.events[0].(UpdateEvent)
+	if !ok || update.EventType() != EventTypeUpdate || update.EventKey() != "alice" || FieldValue(update.NewScores, "points") != 10 {
+		t.Errorf("first event = %#v, expected alice's update", sink.events[0])
+	}
+	if change := sink.events[1]; change != (RankChangeEvent{Set: "test", Member: "alice", OldRank: -1, NewRank: 0}) {
+		t.Errorf("second event = %#v, expected alice entering at rank 0", change)
+	}
+
+	// Staying first doesn't change alice's rank
+	sink.events = nil
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 5}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	if len(sink.events) != 1 || sink.events[0].EventType() != EventTypeUpdate {
+		t.Errorf("events = %v, expected only an update", sink.events)
+	}
+
+	// A failing sink doesn't fail the update
+	failure := errors.New("broker down")
+	sink.fail = failure
+	var hookErr error
+	mfs.OnError(func(ctx context.Context, op string, member string, err error) {
+		if op == "Emit" {
+			hookErr = err
+		}
+	})
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 50}, "bob"); err != nil {
+		t.Fatalf("IncreaseScore() with a failing sink error = %v", err)
+	}
+	if !errors.Is(hookErr, failure) {
+		t.Errorf("OnError got %v, expected the sink's error", hookErr)
+	}
+}
+
+func TestEventSink_Season(t *testing.T) {
+	sink := &recordingSink{}
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{EventSink: sink, Freezable: true})
+	ctx := context.Background()
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 10}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	sink.events = nil
+
+	if err := mfs.Freeze(ctx); err != nil {
+		t.Fatalf("Freeze() error = %v", err)
+	}
+	if _, err := mfs.ExtractTop(ctx, 10, ExtractOptions{Label: "season-1"}); err != nil {
+		t.Fatalf("ExtractTop() error = %v", err)
+	}
+	if _, err := mfs.Snapshot(ctx, "week-1"); err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+	if err := mfs.Unfreeze(ctx); err != nil {
+		t.Fatalf("Unfreeze() error = %v", err)
+	}
+	if err := mfs.Clear(ctx); err != nil {
+		t.Fatalf("Clear() error = %v", err)
+	}
+
+	want := []struct {
+		typ   SeasonEventType
+		label string
+	}{{SeasonFrozen, ""}, {SeasonExtracted, "season-1"}, {SeasonSnapshotted, "week-1"}, {SeasonUnfrozen, ""}, {SeasonCleared, ""}}
+	if len(sink.events) != len(want) {
+		t.Fatalf("events = %v, expected %d season events", sink.events, len(want))
+	}
+	for i, w := range want {
+		e, ok := sink.events[i].(SeasonEvent)
+		if !ok || e.Type != w.typ || e.Label != w.label || e.EventKey() != "test" || e.Time.IsZero() {
+			t.Errorf("event %d = %#v, expected %s %q", i, sink.events[i], w.typ, w.label)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package zmultifield
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ExportColumn describes a field column of an export.
+type ExportColumn struct {
+	Name string
+	// Computed is set for the columns of ComputedFields, whose values are floats rather than
+	// integers.
+	Computed bool
+}
+
+// ExportRow is a member streamed by Export.
+type ExportRow struct {
+	Member string
+	// Rank is the member's 0-based position among the exported members.
+	Rank   int64
+	Scores []FieldScore
+}
+
+// ExportWriter receives the members streamed by Export, e.g. to write them in a columnar format
+// for analytics. The zmfparquet package provides a Parquet implementation.
+type ExportWriter interface {
+	// Begin is called once, before any row, with a column per field, in the order of the scores of
+	// every row.
+	Begin(columns []ExportColumn) error
+	// Write is called for every member, in leaderboard order.
+	Write(row ExportRow) error
+	// End is called once after the last row. It doesn't close the destination of the writer.
+	End() error
+}
+
+// Export streams the visible members of the set, in leaderboard order, to w and returns the number
+// of members written. opts can restrict the export with Where, MinField or InDimension, in which
+// case ranks count matching members only. Members are read in batches, as with Iterate, so members
+// updated during the export may be written twice or not at all.
+func (mfs *MultiFieldSet) Export(ctx context.Context, w ExportWriter, opts ...ReadOption) (n int64, err error) {
+	defer mfs.observeRead("Export", time.Now(), &err)
+
+	o := newReadOptions(opts)
+	ctx = o.context(ctx)
+	key, c, err := mfs.resolveFilter(Filter{}, o)
+	if err != nil {
+		return 0, mfs.runOnError(ctx, "Export", "", err)
+	}
+
+	columns := make([]ExportColumn, 0, len(mfs.visible)+len(mfs.computed))
+	for _, field := range mfs.visible {
+		columns = append(columns, ExportColumn{Name: field.Name})
+	}
+	for _, field := range mfs.computed {
+		columns = append(columns, ExportColumn{Name: field.Name, Computed: true})
+	}
+	if err := w.Begin(columns); err != nil {
+		return 0, err
+	}
+
+	if !c.empty {
+		var writeErr error
+		err = mfs.walkFiltered(ctx, key, c, scanBatchSize, func(z redis.Z) bool {
+			member := mfs.decodeEntry(z)
+			writeErr = w.Write(ExportRow{Member: member.Member, Rank: n, Scores: member.Scores})
+			if writeErr != nil {
+				return false
+			}
+			n++
+			return true
+		})
+		if err != nil {
+			return n, mfs.runOnError(ctx, "Export", "", err)
+		}
+		if writeErr != nil {
+			return n, writeErr
+		}
+	}
+	return n, w.End()
+}
@@ -0,0 +1,66 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// recordingWriter is an ExportWriter keeping what it receives.
+type recordingWriter struct {
+	columns []ExportColumn
+	rows    []ExportRow
+	ended   bool
+	fail    error
+}
+
+func (w *recordingWriter) Begin(columns []ExportColumn) error {
+	w.columns = columns
+	return nil
+}
+
+func (w *recordingWriter) Write(row ExportRow) error {
+	w.rows = append(w.rows, row)
+	return w.fail
+}
+
+func (w *recordingWriter) End() error {
+	w.ended = true
+	return nil
+}
+
+func TestExport(t *testing.T) {
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{ComputedFields: []ComputedField{Ratio("ratio", "points", "deaths")}})
+	ctx := context.Background()
+	for member, points := range map[string]float64{"alice": 30, "bob": 50, "carol": 10} {
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": points, "deaths": 2}, member); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+	}
+
+	w := &recordingWriter{}
+	n, err := mfs.Export(ctx, w)
+	if err != nil || n != 3 || !w.ended {
+		t.Fatalf("Export() = %d, %v, expected 3 members", n, err)
+	}
+	if len(w.columns) != 3 || w.columns[0].Name != "points" || w.columns[2] != (ExportColumn{Name: "ratio", Computed: true}) {
+		t.Errorf("columns = %v, expected points, deaths and the computed ratio", w.columns)
+	}
+	for i, member := range []string{"bob", "alice", "carol"} {
+		if row := w.rows[i]; row.Member != member || row.Rank != int64(i) || len(row.Scores) != 3 {
+			t.Errorf("row %d = %v, expected %s", i, row, member)
+		}
+	}
+
+	// Ranks count the exported members only
+	w = &recordingWriter{}
+	if n, err := mfs.Export(ctx, w, MinField("points", 20)); err != nil || n != 2 || w.rows[1].Member != "alice" || w.rows[1].Rank != 1 {
+		t.Errorf("Export(MinField) = %d, %v, rows %v, expected bob and alice", n, err, w.rows)
+	}
+
+	failure := errors.New("disk full")
+	w = &recordingWriter{fail: failure}
+	if n, err := mfs.Export(ctx, w); !errors.Is(err, failure) || n != 0 || w.ended {
+		t.Errorf("Export() with a failing writer = %d, %v, expected the writer's error", n, err)
+	}
+}
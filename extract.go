@@ -0,0 +1,231 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// extractTopScript reads the top members of the main set and optionally records them under an
+// extraction label and removes them, all in one atomic step.
+//
+// KEYS[1] is the main set, followed by the set of hidden members if ARGV[6] is "1", the field
+// indexes to remove extracted members from and, for labelled extractions, the three keys after
+// them the extraction's sorted set, its metadata hash and the sorted set of labels by extraction
+// time. ARGV[1] is the number of members, ARGV[2] "1" to remove them, ARGV[3] the number of index
+// keys, ARGV[4] the extraction time in epoch milliseconds, or empty for unlabelled extractions,
+// ARGV[5] the label and ARGV[6] "1" to skip hidden members. It returns false if the label was
+// already extracted, and the flat member/score list of the extracted members otherwise.
+var extractTopScript = newWriteScript(`
+local n = tonumber(ARGV[1])
+local hides = ARGV[6] == '1'
+local first = 2
+if hides then
+	first = 3
+end
+local indexes = tonumber(ARGV[3])
+local entriesKey, infoKey, labelsKey = KEYS[first + indexes], KEYS[first + indexes + 1], KEYS[first + indexes + 2]
+local labelled = ARGV[4] ~= ''
+if labelled and redis.call('EXISTS', infoKey) == 1 then
+	return false
+end
+
+local top
+if hides then
+	local hidden = {}
+	for _, member in ipairs(redis.call('SMEMBERS', KEYS[2])) do
+		hidden[member] = true
+	end
+	top = {}
+	local entries = redis.call('ZRANGE', KEYS[1], 0, n + redis.call('SCARD', KEYS[2]) - 1, 'WITHSCORES')
+	for i = 1, #entries, 2 do
+		if #top >= 2 * n then
+			break
+		end
+		if not hidden[entries[i]] then
+			top[#top + 1] = entries[i]
+			top[#top + 1] = entries[i + 1]
+		end
+	end
+else
+	top = redis.call('ZRANGE', KEYS[1], 0, n - 1, 'WITHSCORES')
+end
+if labelled then
+	redis.call('HSET', infoKey, 'extracted_at', ARGV[4], 'members', #top / 2)
+	redis.call('ZADD', labelsKey, ARGV[4], ARGV[5])
+	for i = 1, #top, 2 do
+		redis.call('ZADD', entriesKey, top[i + 1], top[i])
+	end
+end
+if ARGV[2] == '1' then
+	for i = 1, #top, 2 do
+		redis.call('ZREM', KEYS[1], top[i])
+		for k = first, first + indexes - 1 do
+			redis.call('ZREM', KEYS[k], top[i])
+		end
+	end
+end
+return top
+`)
+
+// ExtractOptions configures ExtractTop.
+type ExtractOptions struct {
+	// Label identifies the extraction, e.g. "season-12". A labelled extraction is recorded so it can
+	// be read again with GetExtraction, and each label can only be extracted once: later calls fail
+	// with ErrAlreadyExtracted, so concurrent reward jobs can't grant rewards twice.
+	Label string
+	// Remove removes the extracted members from the set. Their companion data is kept.
+	Remove bool
+}
+
+// Extraction is the result of ExtractTop.
+type Extraction struct {
+	Label       string
+	ExtractedAt time.Time
+	Entries     []LeaderboardEntry
+}
+
+// extractionKey returns the key of the sorted set holding the members of an extraction.
+func (mfs *MultiFieldSet) extractionKey(label string) string {
+	return mfs.derivedKey("extraction:" + label)
+}
+
+// extractionInfoKey returns the key of the metadata hash of an extraction.
+func (mfs *MultiFieldSet) extractionInfoKey(label string) string {
+	return mfs.derivedKey("extraction:" + label + ":info")
+}
+
+// extractionsKey returns the key of the sorted set of extraction labels by extraction time.
+func (mfs *MultiFieldSet) extractionsKey() string {
+	return mfs.derivedKey("extractions")
+}
+
+// ExtractTop atomically reads the top n members with their ranks and decoded scores, e.g. to
+// distribute end-of-season rewards. With a Label the standings are frozen under that label and
+// extracting it again fails with ErrAlreadyExtracted; with Remove the members are taken out of the
+// set. Hidden members are skipped, so they are never rewarded. Extractions are kept, even by Clear,
+// until DeleteExtraction is called. In Redis Cluster a
+// labelled extraction needs a HashTagKeyBuilder, as it is recorded server side.
+func (mfs *MultiFieldSet) ExtractTop(ctx context.Context, n int64, opts ExtractOptions) (*Extraction, error) {
+	if n <= 0 {
+		return nil, mfs.runOnError(ctx, "ExtractTop", "", errors.New("number of members to extract must be positive"))
+	}
+
+	keys := []string{mfs.key}
+	args := []interface{}{n, "0", 0, "", opts.Label, "0"}
+	if mfs.hideMembers {
+		keys = append(keys, mfs.hiddenKey())
+		args[5] = "1"
+	}
+	if opts.Remove {
+		args[1] = "1"
+		if mfs.maintainFieldIndexes {
+			for _, field := range mfs.fields {
+				keys = append(keys, mfs.fieldIndexKey(field))
+			}
+			args[2] = len(mfs.fields)
+		}
+	}
+	extraction := &Extraction{Label: opts.Label, ExtractedAt: time.UnixMilli(time.Now().UnixMilli())}
+	if opts.Label != "" {
+		keys = append(keys, mfs.extractionKey(opts.Label), mfs.extractionInfoKey(opts.Label), mfs.extractionsKey())
+		args[3] = extraction.ExtractedAt.UnixMilli()
+	}
+
+	// Only removing members is a write to the set a freeze refuses
+	var guard scriptGuard
+	if opts.Remove {
+		guard = mfs.writeGuard()
+	}
+	var reply []string
+	err := mfs.write(ctx, func(client redis.UniversalClient) error {
+		var err error
+		reply, err = extractTopScript.Run(ctx, client, guard, keys, args...).StringSlice()
+		return err
+	})
+	if err == redis.Nil {
+		return nil, mfs.runOnError(ctx, "ExtractTop", "", fmt.Errorf("%w: %s", ErrAlreadyExtracted, opts.Label))
+	} else if err != nil {
+		return nil, mfs.runOnError(ctx, "ExtractTop", "", err)
+	}
+
+	results, err := parseZSlice(reply)
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "ExtractTop", "", err)
+	}
+	extraction.Entries, err = mfs.rankedEntries(results)
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "ExtractTop", "", err)
+	}
+	mfs.emitSeason(ctx, SeasonExtracted, opts.Label)
+	return extraction, nil
+}
+
+// GetExtraction returns the members extracted under label by ExtractTop, or ErrExtractionNotFound if
+// the label wasn't extracted.
+func (mfs *MultiFieldSet) GetExtraction(ctx context.Context, label string) (_ *Extraction, err error) {
+	defer mfs.observeRead("GetExtraction", time.Now(), &err)
+
+	var infoCmd *redis.StringStringMapCmd
+	var entriesCmd *redis.ZSliceCmd
+	err = mfs.read(ctx, func(client redis.UniversalClient) error {
+		_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			infoCmd = pipe.HGetAll(ctx, mfs.extractionInfoKey(label))
+			entriesCmd = pipe.ZRangeWithScores(ctx, mfs.extractionKey(label), 0, -1)
+			return nil
+		})
+		return err
+	})
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "GetExtraction", "", err)
+	}
+	info := infoCmd.Val()
+	if len(info) == 0 {
+		return nil, mfs.runOnError(ctx, "GetExtraction", "", fmt.Errorf("%w: %s", ErrExtractionNotFound, label))
+	}
+
+	extractedAt, err := strconv.ParseInt(info["extracted_at"], 10, 64)
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "GetExtraction", "", err)
+	}
+	entries, err := mfs.rankedEntries(entriesCmd.Val())
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "GetExtraction", "", err)
+	}
+	return &Extraction{
+		Label:       label,
+		ExtractedAt: time.UnixMilli(extractedAt),
+		Entries:     entries,
+	}, nil
+}
+
+// DeleteExtraction deletes the extraction recorded under label, allowing the label to be extracted
+// again. Deleting a missing extraction is not an error.
+func (mfs *MultiFieldSet) DeleteExtraction(ctx context.Context, label string) error {
+	err := mfs.write(ctx, func(client redis.UniversalClient) error {
+		_, err := client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Del(ctx, mfs.extractionKey(label), mfs.extractionInfoKey(label))
+			pipe.ZRem(ctx, mfs.extractionsKey(), label)
+			return nil
+		})
+		return err
+	})
+	return mfs.runOnError(ctx, "DeleteExtraction", "", err)
+}
+
+// rankedEntries decodes sorted set entries that start at rank 0.
+func (mfs *MultiFieldSet) rankedEntries(results []redis.Z) ([]LeaderboardEntry, error) {
+	members, err := mfs.decodeMembers(results)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]LeaderboardEntry, len(members))
+	for i, m := range members {
+		entries[i] = LeaderboardEntry{Rank: int64(i), Member: m.Member, Scores: m.Scores}
+	}
+	return entries, nil
+}
@@ -0,0 +1,77 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestExtractTop(t *testing.T) {
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{MaintainFieldIndexes: true})
+	ctx := context.Background()
+
+	for member, points := range map[string]float64{"alice": 30, "bob": 20, "carol": 10} {
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": points}, member); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+	}
+
+	// Only one of several concurrent extractions of a label succeeds
+	var wg sync.WaitGroup
+	extractions := make(chan *Extraction, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			extraction, err := mfs.ExtractTop(ctx, 2, ExtractOptions{Label: "season-1", Remove: true})
+			if err == nil {
+				extractions <- extraction
+			} else if !errors.Is(err, ErrAlreadyExtracted) {
+				t.Errorf("ExtractTop() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	close(extractions)
+	if len(extractions) != 1 {
+		t.Fatalf("%d extractions succeeded, expected 1", len(extractions))
+	}
+	extraction := <-extractions
+	if len(extraction.Entries) != 2 || extraction.Entries[0].Member != "alice" || extraction.Entries[1].Rank != 1 ||
+		extraction.Entries[1].Scores[0].Score.Int64() != 20 {
+		t.Errorf("entries = %+v, expected alice and bob", extraction.Entries)
+	}
+
+	members, err := mfs.GetMembers(ctx, 10, 0)
+	if err != nil {
+		t.Fatalf("GetMembers() error = %v", err)
+	}
+	if len(members) != 1 || members[0].Member != "carol" {
+		t.Errorf("members after extraction = %+v, expected only carol", members)
+	}
+	if rank, err := mfs.GetFieldRank(ctx, "points", "alice"); !errors.Is(err, ErrMemberNotFound) {
+		t.Errorf("GetFieldRank() = %d, %v, expected alice to be removed from the index", rank, err)
+	}
+
+	stored, err := mfs.GetExtraction(ctx, "season-1")
+	if err != nil {
+		t.Fatalf("GetExtraction() error = %v", err)
+	}
+	if len(stored.Entries) != 2 || stored.Entries[1].Member != "bob" || !stored.ExtractedAt.Equal(extraction.ExtractedAt) {
+		t.Errorf("GetExtraction() = %+v, expected %+v", stored, extraction)
+	}
+
+	// Unlabelled extractions without Remove only read
+	extraction, err = mfs.ExtractTop(ctx, 5, ExtractOptions{})
+	if err != nil || len(extraction.Entries) != 1 {
+		t.Errorf("ExtractTop() = %+v, %v, expected carol", extraction, err)
+	}
+
+	if err := mfs.DeleteExtraction(ctx, "season-1"); err != nil {
+		t.Fatalf("DeleteExtraction() error = %v", err)
+	}
+	if _, err := mfs.GetExtraction(ctx, "season-1"); !errors.Is(err, ErrExtractionNotFound) {
+		t.Errorf("GetExtraction() error = %v, expected ErrExtractionNotFound", err)
+	}
+}
@@ -0,0 +1,98 @@
+package zmultifield
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// fastIncrement reports whether an update can be applied with a single ZINCRBY instead of reading
+// the member first. That is the case for counters: sets with a single incremental field that is
+// stored as is in the low bits, so the zscore is the raw value and new members start at zero, and
+// with no option or hook that needs the member's scores before the write or a scripted write.
+func (mfs *MultiFieldSet) fastIncrement(withRanks bool) bool {
+	if len(mfs.fields) != 1 {
+		return false
+	}
+	field := mfs.fields[0]
+	if field.UpdateType != Incremental || field.inverted || field.shiftValue != 0 {
+		return false
+	}
+	if withRanks || mfs.needsRanks() || mfs.updateStrategy != UpdateScripted || mfs.updateOnlyExisting ||
+		mfs.maintainFieldIndexes || mfs.maxMembers > 0 || mfs.guard != nil || len(mfs.dimensions) > 0 || mfs.freezable || mfs.audit {
+		return false
+	}
+	mfs.hooks.mu.RLock()
+	defer mfs.hooks.mu.RUnlock()
+	return len(mfs.hooks.beforeUpdate) == 0
+}
+
+// fastIncrementScript increments a member's zscore with ZINCRBY unless the result would fall
+// outside [0, ARGV[3]], in which case nothing is written. KEYS[1] is the set, ARGV[1] the member and
+// ARGV[2] the increment. The script returns 1 and the new zscore, or 0 and the out-of-range zscore.
+var fastIncrementScript = newWriteScript(`
+local zscore = tonumber(redis.call('ZSCORE', KEYS[1], ARGV[1]) or 0) + tonumber(ARGV[2])
+if zscore < 0 or zscore > tonumber(ARGV[3]) then
+	return {0, string.format('%.17g', zscore)}
+end
+return {1, redis.call('ZINCRBY', KEYS[1], ARGV[2], ARGV[1])}
+`)
+
+// incrementFast applies an update of the single field with a script wrapping ZINCRBY. The
+// increment and its bounds check are atomic, so the member's previous scores are derived from the
+// result, after-update hooks and triggers see every value exactly once, and an update that would
+// take the field out of range writes nothing.
+func (mfs *MultiFieldSet) incrementFast(ctx context.Context, fields map[string]float64, member string) (*UpdateResult, error) {
+	field := mfs.fields[0]
+	inc := new(big.Int)
+	for name, value := range fields {
+		if name != field.Name {
+			return nil, fieldNotFoundError(name)
+		}
+		delta, err := toInt64(name, value)
+		if err != nil {
+			return nil, err
+		}
+		inc.Mul(big.NewInt(delta), field.multiplier)
+	}
+	// An increment that alone exceeds the field can be rejected without writing
+	if inc.Cmp(field.maxAbsolute) > 0 {
+		return nil, outOfRangeError(field, inc)
+	}
+
+	var reply []interface{}
+	err := mfs.writeOnce(ctx, func(client redis.UniversalClient) error {
+		var err error
+		reply, err = fastIncrementScript.Run(ctx, client, mfs.writeGuard(), []string{mfs.key}, member, inc.String(), field.maxAbsolute.String()).Slice()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(reply) != 2 {
+		return nil, fmt.Errorf("unexpected fast increment reply %v", reply)
+	}
+	zscore, err := strconv.ParseFloat(fmt.Sprint(reply[1]), 64)
+	if err != nil {
+		return nil, err
+	}
+
+	finalZScore := new(big.Int).SetInt64(int64(zscore))
+	if written, _ := reply[0].(int64); written == 0 {
+		return nil, outOfRangeError(field, finalZScore)
+	}
+
+	event := &UpdateEvent{
+		Set:       mfs.name,
+		Member:    member,
+		Deltas:    fields,
+		OldScores: mfs.zscoreToAllFieldScores(new(big.Int).Sub(finalZScore, inc)),
+		NewScores: mfs.zscoreToAllFieldScores(finalZScore),
+	}
+	result := &UpdateResult{ZScore: finalZScore, OldRank: -1, NewRank: -1}
+	mfs.finishUpdate(ctx, event, result)
+	return result, nil
+}
@@ -0,0 +1,98 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// newCounterSet creates a set with a single ascending counter field.
+func newCounterSet(t *testing.T) *MultiFieldSet {
+	t.Helper()
+	client, _ := newTestClient(t)
+	mfs, err := New(MultiFieldSetOptions{
+		Name:   "counters",
+		Client: client,
+		Fields: []Field{{Name: "views", Sort: Ascending, MaxValue: 1000, UpdateType: Incremental}},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return mfs
+}
+
+func TestFastIncrement(t *testing.T) {
+	mfs := newCounterSet(t)
+	ctx := context.Background()
+	if !mfs.fastIncrement(false) {
+		t.Fatal("fastIncrement() = false for a counter set")
+	}
+
+	var reached []string
+	if err := mfs.RegisterTrigger("views", 10, func(ctx context.Context, event TriggerEvent) {
+		reached = append(reached, event.Member)
+	}); err != nil {
+		t.Fatalf("RegisterTrigger() error = %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := mfs.IncreaseScore(ctx, map[string]float64{"views": 1}, "home"); err != nil {
+				t.Errorf("IncreaseScore() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	scores, err := mfs.GetScores(ctx, "home")
+	if err != nil {
+		t.Fatalf("GetScores() error = %v", err)
+	}
+	if got := scores[0].Score.Int64(); got != 20 {
+		t.Errorf("views = %d, expected 20", got)
+	}
+	if len(reached) != 1 {
+		t.Errorf("trigger fired %d times, expected once", len(reached))
+	}
+
+	// Out-of-range updates write nothing
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"views": -30}, "home"); !errors.Is(err, ErrScoreOutOfRange) {
+		t.Fatalf("IncreaseScore() error = %v, expected ErrScoreOutOfRange", err)
+	}
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"views": 2000}, "home"); !errors.Is(err, ErrScoreOutOfRange) {
+		t.Fatalf("IncreaseScore() error = %v, expected ErrScoreOutOfRange", err)
+	}
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"clicks": 1}, "home"); !errors.Is(err, ErrFieldNotFound) {
+		t.Fatalf("IncreaseScore() error = %v, expected ErrFieldNotFound", err)
+	}
+	if scores, err := mfs.GetScores(ctx, "home"); err != nil || scores[0].Score.Int64() != 20 {
+		t.Errorf("GetScores() = %v, %v, expected 20 views after failed updates", scores, err)
+	}
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"views": -1}, "away"); !errors.Is(err, ErrScoreOutOfRange) {
+		t.Fatalf("IncreaseScore() error = %v, expected ErrScoreOutOfRange", err)
+	}
+	if _, err := mfs.client.ZScore(ctx, mfs.key, "away").Result(); err != redis.Nil {
+		t.Errorf("ZScore(away) error = %v, expected the refused update not to add the member", err)
+	}
+}
+
+func TestFastIncrementEligibility(t *testing.T) {
+	if newTestSet(t).fastIncrement(false) {
+		t.Error("fastIncrement() = true for a set with two fields")
+	}
+
+	mfs := newCounterSet(t)
+	if mfs.fastIncrement(true) {
+		t.Error("fastIncrement() = true for an update that needs ranks")
+	}
+	mfs.BeforeUpdate(func(ctx context.Context, event *UpdateEvent) error { return nil })
+	if mfs.fastIncrement(false) {
+		t.Error("fastIncrement() = true with a before-update hook")
+	}
+}
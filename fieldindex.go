@@ -0,0 +1,95 @@
+package zmultifield
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// fieldIndexKey returns the key of the sorted set that indexes a single field.
+func (mfs *MultiFieldSet) fieldIndexKey(field *multiField) string {
+	return mfs.derivedKey("field:" + field.Name)
+}
+
+// GetFieldRank returns the zero-based rank of a member when ordered by a single field, following
+// the field's sort order. It requires MaintainFieldIndexes and returns ErrMemberNotFound if the
+// member has never been written with indexes enabled.
+func (mfs *MultiFieldSet) GetFieldRank(ctx context.Context, fieldName string, member string) (_ int64, err error) {
+	defer mfs.observeRead("GetFieldRank", time.Now(), &err)
+
+	field := mfs.GetFieldByName(fieldName)
+	if field == nil {
+		return 0, fieldNotFoundError(fieldName)
+	}
+	if !mfs.maintainFieldIndexes {
+		return 0, ErrFieldIndexesDisabled
+	}
+
+	var rank int64
+	err = mfs.read(ctx, func(client redis.UniversalClient) error {
+		rank, err = client.ZRank(ctx, mfs.fieldIndexKey(field), member).Result()
+		return err
+	})
+	if err == redis.Nil {
+		return 0, ErrMemberNotFound
+	} else if err != nil {
+		return 0, mfs.runOnError(ctx, "GetFieldRank", member, err)
+	}
+	return rank, nil
+}
+
+// getMembersFromIndex reads members from a field index within a raw value range and decodes their
+// current scores from the main set. Results are ordered by the field's raw value.
+func (mfs *MultiFieldSet) getMembersFromIndex(ctx context.Context, field *multiField, rawMin, rawMax string, limit, offset int64) ([]MemberScores, error) {
+	count := limit
+	if count <= 0 {
+		count = -1
+	}
+
+	var names []string
+	var cmds []*redis.FloatCmd
+	err := mfs.read(ctx, func(client redis.UniversalClient) error {
+		var err error
+		names, err = client.ZRangeByScore(ctx, mfs.fieldIndexKey(field), &redis.ZRangeBy{
+			Min:    rawMin,
+			Max:    rawMax,
+			Offset: offset,
+			Count:  count,
+		}).Result()
+		if err != nil || len(names) == 0 {
+			return err
+		}
+
+		cmds = make([]*redis.FloatCmd, len(names))
+		_, err = client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, name := range names {
+				cmds[i] = pipe.ZScore(ctx, mfs.key, name)
+			}
+			return nil
+		})
+		if err == redis.Nil {
+			err = nil
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return []MemberScores{}, nil
+	}
+
+	results := make([]redis.Z, 0, len(names))
+	for i, cmd := range cmds {
+		score, err := cmd.Result()
+		if err == redis.Nil {
+			// Removed from the main set since the index was read
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		results = append(results, redis.Z{Score: score, Member: names[i]})
+	}
+	return mfs.decodeMembers(results)
+}
@@ -0,0 +1,74 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestFieldIndexes(t *testing.T) {
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{MaintainFieldIndexes: true})
+	ctx := context.Background()
+
+	players := map[string]map[string]float64{
+		"alice": {"points": 100, "deaths": 5},
+		"bob":   {"points": 50, "deaths": 1},
+		"carol": {"points": 75, "deaths": 9},
+	}
+	for member, fields := range players {
+		if _, err := mfs.IncreaseScore(ctx, fields, member); err != nil {
+			t.Fatalf("IncreaseScore(%s) error = %v", member, err)
+		}
+	}
+
+	points, err := mfs.client.ZScore(ctx, "test:field:points", "bob").Result()
+	if err != nil {
+		t.Fatalf("ZScore on points index error = %v", err)
+	}
+	if expected := float64(1023 - 50); points != expected {
+		t.Errorf("points index score = %v, expected %v", points, expected)
+	}
+
+	rank, err := mfs.GetFieldRank(ctx, "deaths", "carol")
+	if err != nil {
+		t.Fatalf("GetFieldRank() error = %v", err)
+	}
+	if rank != 2 {
+		t.Errorf("GetFieldRank(deaths, carol) = %d, expected 2", rank)
+	}
+
+	if _, err := mfs.GetFieldRank(ctx, "deaths", "nobody"); !errors.Is(err, ErrMemberNotFound) {
+		t.Errorf("GetFieldRank() for missing member error = %v, expected ErrMemberNotFound", err)
+	}
+
+	top, err := mfs.GetTopMembersByField(ctx, "deaths", 2)
+	if err != nil {
+		t.Fatalf("GetTopMembersByField() error = %v", err)
+	}
+	if len(top) != 2 || top[0].Member != "bob" || top[1].Member != "alice" {
+		t.Errorf("GetTopMembersByField(deaths, 2) = %+v, expected bob, alice", top)
+	}
+
+	inRange, err := mfs.GetMembersByFieldRange(ctx, "deaths", 4, 10, 0, 0)
+	if err != nil {
+		t.Fatalf("GetMembersByFieldRange() error = %v", err)
+	}
+	if len(inRange) != 2 || inRange[0].Member != "alice" || inRange[1].Member != "carol" {
+		t.Errorf("GetMembersByFieldRange(deaths, 4, 10) = %+v, expected alice, carol", inRange)
+	}
+
+	if err := mfs.ResetMember(ctx, "carol"); err != nil {
+		t.Fatalf("ResetMember() error = %v", err)
+	}
+	if rank, _ := mfs.GetFieldRank(ctx, "deaths", "carol"); rank != 0 {
+		t.Errorf("GetFieldRank(deaths, carol) after reset = %d, expected 0", rank)
+	}
+}
+
+func TestFieldIndexes_Disabled(t *testing.T) {
+	mfs := newTestSet(t)
+
+	if _, err := mfs.GetFieldRank(context.Background(), "points", "alice"); !errors.Is(err, ErrFieldIndexesDisabled) {
+		t.Errorf("GetFieldRank() error = %v, expected ErrFieldIndexesDisabled", err)
+	}
+}
@@ -0,0 +1,174 @@
+package zmultifield
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// scanBatchSize is the number of members fetched per round trip when a query has to walk the
+// whole set.
+const scanBatchSize = 1000
+
+// GetMembersByFieldRange returns members whose value for the given field lies within [min, max],
+// ordered the same way as GetMembers. A limit of zero or less returns all matching members.
+// Fractional bounds are rounded inwards and infinite ones clamped to the field's capacity.
+//
+// When the field is the most significant field of the set the range maps to a single contiguous
+// zscore range and is resolved by Redis. For any other field the matching members are spread across
+// the whole set: with MaintainFieldIndexes enabled they are read from the field's index and ordered
+// by the field, otherwise the set is scanned in batches and filtered client-side. Hidden members
+// are skipped.
+func (mfs *MultiFieldSet) GetMembersByFieldRange(ctx context.Context, fieldName string, min, max float64, limit, offset int64) (_ []MemberScores, err error) {
+	defer mfs.observeRead("GetMembersByFieldRange", time.Now(), &err)
+
+	field := mfs.GetFieldByName(fieldName)
+	if field == nil {
+		return nil, fieldNotFoundError(fieldName)
+	}
+
+	rawMin, rawMax, ok := field.rawRangeWithin(min, max)
+	if !ok {
+		return []MemberScores{}, nil
+	}
+
+	hidden, err := mfs.hiddenMembers(ctx)
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "GetMembersByFieldRange", "", err)
+	}
+	readLimit, readOffset := hiddenWindow(hidden, limit, offset)
+
+	var members []MemberScores
+	switch {
+	case field.leading:
+		members, err = mfs.getMembersByLeadingFieldRange(ctx, field, rawMin, rawMax, readLimit, readOffset)
+	case mfs.maintainFieldIndexes:
+		members, err = mfs.getMembersFromIndex(ctx, field, rawMin.String(), rawMax.String(), readLimit, readOffset)
+	default:
+		members, err = mfs.scanMembersByFieldRange(ctx, field, rawMin, rawMax, readLimit, readOffset)
+	}
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "GetMembersByFieldRange", "", err)
+	}
+	return withoutHidden(members, hidden, limit, offset), nil
+}
+
+// rawRange converts a range of integral, finite display values into the range of raw (stored)
+// values, inverting descending fields and clamping to the field's capacity. ok is false if the
+// range is empty. Bounds from callers go through rawRangeWithin.
+func (mf *multiField) rawRange(min, max float64) (rawMin, rawMax *big.Int, ok bool) {
+	lo := mf.toRaw(new(big.Int).SetInt64(int64(min)))
+	hi := mf.toRaw(new(big.Int).SetInt64(int64(max)))
+	if mf.inverted {
+		lo, hi = hi, lo
+	}
+
+	if lo.Sign() < 0 {
+		lo.SetInt64(0)
+	}
+	if hi.Cmp(mf.maxAbsolute) > 0 {
+		hi.Set(mf.maxAbsolute)
+	}
+	if lo.Cmp(hi) > 0 {
+		return nil, nil, false
+	}
+	return lo, hi, true
+}
+
+// zscoreBounds returns the smallest and largest zscores whose bits for this field lie within
+// [rawMin, rawMax] while every less significant field is unconstrained.
+func (mf *multiField) zscoreBounds(rawMin, rawMax *big.Int) (lo, hi *big.Int) {
+	lo = new(big.Int).Lsh(rawMin, uint(mf.shiftValue))
+	hi = new(big.Int).Lsh(rawMax, uint(mf.shiftValue))
+	hi.Add(hi, MaxBin(mf.shiftValue))
+	return lo, hi
+}
+
+// getMembersByLeadingFieldRange resolves a range on the most significant field with a single
+// ZRANGEBYSCORE call.
+func (mfs *MultiFieldSet) getMembersByLeadingFieldRange(ctx context.Context, field *multiField, rawMin, rawMax *big.Int, limit, offset int64) ([]MemberScores, error) {
+	lo, hi := field.zscoreBounds(rawMin, rawMax)
+
+	count := limit
+	if count <= 0 {
+		count = -1
+	}
+
+	var results []redis.Z
+	err := mfs.read(ctx, func(client redis.UniversalClient) error {
+		var err error
+		results, err = client.ZRangeByScoreWithScores(ctx, mfs.key, &redis.ZRangeBy{
+			Min:    lo.String(),
+			Max:    hi.String(),
+			Offset: offset,
+			Count:  count,
+		}).Result()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return mfs.decodeMembers(results)
+}
+
+// scanMembersByFieldRange walks the whole set in batches and keeps the members whose raw value
+// for field lies within [rawMin, rawMax].
+func (mfs *MultiFieldSet) scanMembersByFieldRange(ctx context.Context, field *multiField, rawMin, rawMax *big.Int, limit, offset int64) ([]MemberScores, error) {
+	members := []MemberScores{}
+	var skipped int64
+
+	err := mfs.scanEntries(ctx, func(z redis.Z, zscore *big.Int) bool {
+		raw := mfs.extractFieldScore(field, zscore)
+		if raw.Cmp(rawMin) < 0 || raw.Cmp(rawMax) > 0 {
+			return true
+		}
+		if skipped < offset {
+			skipped++
+			return true
+		}
+		members = append(members, mfs.decodeEntry(z))
+		return limit <= 0 || int64(len(members)) < limit
+	})
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// scanEntries walks the whole set in composite order, fetching scanBatchSize entries per round
+// trip, and calls fn for each entry until fn returns false. The zscore passed to fn is reused
+// between calls and must not be retained. It stops with an UnrepresentableScoreError at the first
+// zscore it can't decode.
+func (mfs *MultiFieldSet) scanEntries(ctx context.Context, fn func(z redis.Z, zscore *big.Int) bool) error {
+	for start := int64(0); ; start += scanBatchSize {
+		var results []redis.Z
+		err := mfs.read(ctx, func(client redis.UniversalClient) error {
+			var err error
+			results, err = client.ZRangeWithScores(ctx, mfs.key, start, start+scanBatchSize-1).Result()
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		zscore := zscorePool.Get().(*big.Int)
+		for _, z := range results {
+			if err := mfs.checkZScore(memberName(z), z.Score); err != nil {
+				zscorePool.Put(zscore)
+				return err
+			}
+			if !fn(z, zscore.SetInt64(int64(z.Score))) {
+				zscorePool.Put(zscore)
+				return nil
+			}
+		}
+		zscorePool.Put(zscore)
+
+		if len(results) < scanBatchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package zmultifield
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestGetMembersByFieldRange(t *testing.T) {
+	mfs := newTestSet(t)
+	ctx := context.Background()
+
+	players := map[string]map[string]float64{
+		"alice": {"points": 100, "deaths": 5},
+		"bob":   {"points": 50, "deaths": 1},
+		"carol": {"points": 75, "deaths": 9},
+		"dave":  {"points": 10, "deaths": 3},
+	}
+	for member, fields := range players {
+		if _, err := mfs.IncreaseScore(ctx, fields, member); err != nil {
+			t.Fatalf("IncreaseScore(%s) error = %v", member, err)
+		}
+	}
+
+	tests := []struct {
+		name          string
+		field         string
+		min, max      float64
+		limit, offset int64
+		expected      []string
+	}{
+		{"leading descending field", "points", 50, 100, 0, 0, []string{"alice", "carol", "bob"}},
+		{"leading field with limit", "points", 0, 1000, 2, 1, []string{"carol", "bob"}},
+		{"trailing ascending field", "deaths", 2, 5, 0, 0, []string{"alice", "dave"}},
+		{"trailing field with offset", "deaths", 0, 10, 2, 1, []string{"carol", "bob"}},
+		{"empty range", "points", 200, 100, 0, 0, []string{}},
+		{"infinite bounds", "points", math.Inf(-1), math.Inf(1), 0, 0, []string{"alice", "carol", "bob", "dave"}},
+		{"infinite trailing bound", "deaths", math.Inf(-1), 3, 0, 0, []string{"bob", "dave"}},
+		{"fractional bounds", "points", 50.5, 100.5, 0, 0, []string{"alice", "carol"}},
+		{"fractional trailing bounds", "deaths", 0.5, 4.9, 0, 0, []string{"bob", "dave"}},
+		{"empty fractional range", "deaths", 1.2, 1.8, 0, 0, []string{}},
+	}
+
+	for _, test := range tests {
+		members, err := mfs.GetMembersByFieldRange(ctx, test.field, test.min, test.max, test.limit, test.offset)
+		if err != nil {
+			t.Fatalf("%s: GetMembersByFieldRange() error = %v", test.name, err)
+		}
+		if len(members) != len(test.expected) {
+			t.Errorf("%s: got %d members, expected %d", test.name, len(members), len(test.expected))
+			continue
+		}
+		for i, m := range members {
+			if m.Member != test.expected[i] {
+				t.Errorf("%s: member %d = %s, expected %s", test.name, i, m.Member, test.expected[i])
+			}
+		}
+	}
+}
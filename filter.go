@@ -0,0 +1,244 @@
+package zmultifield
+
+import (
+	"context"
+	"math"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Filter selects members by the display values of their fields. Filters are built with F and
+// combined with And, e.g. F("points").GTE(100).And(F("deaths").LTE(5)), and used with Where,
+// Count and Iterate. The zero Filter matches every member.
+//
+// A filter is resolved against the set's layout: conditions on the most significant fields, and
+// on the next fields for as long as the previous ones are pinned to a single value with EQ, narrow
+// the zscore range read from Redis, and the remaining conditions are checked on each member of
+// that range while scanning it.
+type Filter struct {
+	conds []fieldCond
+}
+
+// fieldCond restricts the display value of a field to [min, max].
+type fieldCond struct {
+	field    string
+	min, max float64
+}
+
+// FieldRef refers to a field when building a Filter.
+type FieldRef struct {
+	name string
+}
+
+// F returns a reference to the named field, from which filters on its value are built.
+func F(field string) FieldRef {
+	return FieldRef{name: field}
+}
+
+// GTE matches members whose value for the field is at least value.
+func (f FieldRef) GTE(value float64) Filter {
+	return f.Between(value, math.Inf(1))
+}
+
+// GT matches members whose value for the field is above value.
+func (f FieldRef) GT(value float64) Filter {
+	return f.Between(math.Floor(value)+1, math.Inf(1))
+}
+
+// LTE matches members whose value for the field is at most value.
+func (f FieldRef) LTE(value float64) Filter {
+	return f.Between(math.Inf(-1), value)
+}
+
+// LT matches members whose value for the field is below value.
+func (f FieldRef) LT(value float64) Filter {
+	return f.Between(math.Inf(-1), math.Ceil(value)-1)
+}
+
+// EQ matches members whose value for the field is value.
+func (f FieldRef) EQ(value float64) Filter {
+	return f.Between(value, value)
+}
+
+// Between matches members whose value for the field lies within [min, max].
+func (f FieldRef) Between(min, max float64) Filter {
+	return Filter{conds: []fieldCond{{field: f.name, min: min, max: max}}}
+}
+
+// And returns a filter matching members that match f and every one of others.
+func (f Filter) And(others ...Filter) Filter {
+	conds := append([]fieldCond(nil), f.conds...)
+	for _, other := range others {
+		conds = append(conds, other.conds...)
+	}
+	return Filter{conds: conds}
+}
+
+// compiledFilter is a Filter resolved against the layout of a set: the zscore range to read, and
+// the field ranges left to check on each member of it. empty is true if nothing can match.
+type compiledFilter struct {
+	min, max string
+	scanned  []fieldFilter
+	empty    bool
+	// reverse walks the range from the worst member
+	reverse bool
+}
+
+// rangeByScore reads count entries of the zscore range of c from offset, in the order of c.
+func (c compiledFilter) rangeByScore(ctx context.Context, client redis.UniversalClient, key string, offset, count int64) ([]redis.Z, error) {
+	by := &redis.ZRangeBy{Min: c.min, Max: c.max, Offset: offset, Count: count}
+	if c.reverse {
+		return client.ZRevRangeByScoreWithScores(ctx, key, by).Result()
+	}
+	return client.ZRangeByScoreWithScores(ctx, key, by).Result()
+}
+
+// rawRangeWithin is rawRange for arbitrary display bounds: fractional bounds are rounded inwards
+// and infinite ones clamped to the field's capacity.
+func (mf *multiField) rawRangeWithin(min, max float64) (rawMin, rawMax *big.Int, ok bool) {
+	limit, _ := new(big.Float).SetInt(mf.maxDisplay()).Float64()
+	min, max = math.Max(math.Ceil(min), mf.MinValue), math.Min(math.Floor(max), limit)
+	if !(min <= max) {
+		return nil, nil, false
+	}
+	return mf.rawRange(min, max)
+}
+
+// compileFilter resolves f against the layout of the set.
+func (mfs *MultiFieldSet) compileFilter(f Filter) (compiledFilter, error) {
+	ranges := make(map[*multiField]fieldFilter, len(f.conds))
+	for _, cond := range f.conds {
+		field := mfs.GetFieldByName(cond.field)
+		if field == nil {
+			return compiledFilter{}, fieldNotFoundError(cond.field)
+		}
+		rawMin, rawMax, ok := field.rawRangeWithin(cond.min, cond.max)
+		if !ok {
+			return compiledFilter{empty: true}, nil
+		}
+		if r, seen := ranges[field]; seen {
+			if r.rawMin.Cmp(rawMin) > 0 {
+				rawMin = r.rawMin
+			}
+			if r.rawMax.Cmp(rawMax) < 0 {
+				rawMax = r.rawMax
+			}
+			if rawMin.Cmp(rawMax) > 0 {
+				return compiledFilter{empty: true}, nil
+			}
+		}
+		ranges[field] = fieldFilter{field: field, rawMin: rawMin, rawMax: rawMax}
+	}
+	if len(ranges) == 0 {
+		return compiledFilter{min: "-inf", max: "+inf"}, nil
+	}
+
+	fields := append([]*multiField(nil), mfs.fields...)
+	sort.Slice(fields, func(i, j int) bool { return fields[i].shiftValue > fields[j].shiftValue })
+
+	// Walking from the most significant field, each range narrows the zscore range until the
+	// first field that isn't pinned to a single value; the fields after it can only be scanned
+	var c compiledFilter
+	lo, hi := new(big.Int), new(big.Int)
+	narrowing := true
+	for _, field := range fields {
+		r, constrained := ranges[field]
+		if !narrowing {
+			if constrained {
+				c.scanned = append(c.scanned, r)
+			}
+			continue
+		}
+		if !constrained {
+			r = fieldFilter{field: field, rawMin: new(big.Int), rawMax: field.maxAbsolute}
+		}
+		lo.Add(lo, new(big.Int).Lsh(r.rawMin, uint(field.shiftValue)))
+		hi.Add(hi, new(big.Int).Lsh(r.rawMax, uint(field.shiftValue)))
+		if r.rawMin.Cmp(r.rawMax) != 0 {
+			hi.Add(hi, MaxBin(field.shiftValue))
+			narrowing = false
+		}
+	}
+	c.min, c.max = lo.String(), hi.String()
+	return c, nil
+}
+
+// Count returns the number of members matching filter. Hidden members aren't counted, and opts
+// can restrict the count to a dimension with InDimension. Filters that compile to a zscore range
+// alone are counted by Redis, the others by scanning the range.
+func (mfs *MultiFieldSet) Count(ctx context.Context, filter Filter, opts ...ReadOption) (_ int64, err error) {
+	defer mfs.observeRead("Count", time.Now(), &err)
+
+	o := newReadOptions(opts)
+	ctx = o.context(ctx)
+	key, c, err := mfs.resolveFilter(filter, o)
+	if err != nil {
+		return 0, mfs.runOnError(ctx, "Count", "", err)
+	}
+	if c.empty {
+		return 0, nil
+	}
+
+	var count int64
+	if len(c.scanned) == 0 && !mfs.hideMembers {
+		err = mfs.read(ctx, func(client redis.UniversalClient) error {
+			count, err = client.ZCount(ctx, key, c.min, c.max).Result()
+			return err
+		})
+	} else {
+		err = mfs.walkFiltered(ctx, key, c, scanBatchSize, func(z redis.Z) bool {
+			count++
+			return true
+		})
+	}
+	if err != nil {
+		return 0, mfs.runOnError(ctx, "Count", "", err)
+	}
+	return count, nil
+}
+
+// Iterate calls fn for each visible member matching filter in leaderboard order, until fn returns
+// false. Members are read in batches of scanBatchSize, so a member updated during the walk may be
+// seen twice or not at all. opts can restrict the walk to a dimension with InDimension, reverse it
+// with WithOrientation or number the members with WithRanks.
+func (mfs *MultiFieldSet) Iterate(ctx context.Context, filter Filter, fn func(member MemberScores) bool, opts ...ReadOption) (err error) {
+	defer mfs.observeRead("Iterate", time.Now(), &err)
+
+	o := newReadOptions(opts)
+	ctx = o.context(ctx)
+	key, c, err := mfs.resolveFilter(filter, o)
+	if err != nil {
+		return mfs.runOnError(ctx, "Iterate", "", err)
+	}
+	if c.empty {
+		return nil
+	}
+	var rank int64
+	err = mfs.walkFiltered(ctx, key, c, scanBatchSize, func(z redis.Z) bool {
+		member := mfs.decodeEntry(z)
+		if o.ranks {
+			member.Rank = rank
+			rank++
+		}
+		return fn(member)
+	})
+	if err != nil {
+		return mfs.runOnError(ctx, "Iterate", "", err)
+	}
+	return nil
+}
+
+// resolveFilter returns the key read with o and filter combined with the filters of o, compiled
+// against the layout of the set and walked in the orientation of o.
+func (mfs *MultiFieldSet) resolveFilter(filter Filter, o readOptions) (string, compiledFilter, error) {
+	key, err := mfs.readKey(o)
+	if err != nil {
+		return "", compiledFilter{}, err
+	}
+	c, err := mfs.compileFilter(filter.And(o.filter))
+	c.reverse = o.orientation == WorstFirst
+	return key, c, err
+}
@@ -0,0 +1,118 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCompileFilter(t *testing.T) {
+	mfs := newTestSet(t)
+	tests := []struct {
+		name    string
+		filter  Filter
+		scanned int
+		empty   bool
+	}{
+		{"no conditions", Filter{}, 0, false},
+		{"leading field", F("points").GTE(100), 0, false},
+		{"other field", F("deaths").LTE(5), 1, false},
+		{"range then other field", F("points").GTE(100).And(F("deaths").LTE(5)), 1, false},
+		{"pinned then other field", F("points").EQ(100).And(F("deaths").LTE(5)), 0, false},
+		{"disjoint conditions", F("points").GT(100).And(F("points").LT(50)), 0, true},
+		{"beyond capacity", F("deaths").GT(1000), 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := mfs.compileFilter(tt.filter)
+			if err != nil {
+				t.Fatalf("compileFilter() error = %v", err)
+			}
+			if len(c.scanned) != tt.scanned || c.empty != tt.empty {
+				t.Errorf("compileFilter() scanned %d fields, empty %v, expected %d and %v", len(c.scanned), c.empty, tt.scanned, tt.empty)
+			}
+		})
+	}
+
+	if _, err := mfs.compileFilter(F("missing").EQ(1)); !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("compileFilter() error = %v, expected ErrFieldNotFound", err)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{HideMembers: true})
+	ctx := context.Background()
+
+	players := map[string][2]float64{
+		"alice": {50, 1},
+		"bob":   {50, 5},
+		"carol": {30, 9},
+		"dave":  {20, 3},
+		"erin":  {10, 2},
+	}
+	for member, s := range players {
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": s[0], "deaths": s[1]}, member); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		filter   Filter
+		expected string
+	}{
+		{"range on leading field", F("points").Between(15, 30.5), "carol,dave"},
+		{"range on both fields", F("points").GT(10).And(F("deaths").LTE(5)), "alice,bob,dave"},
+		{"pinned leading field", F("points").EQ(50).And(F("deaths").GTE(2)), "bob"},
+		{"other field only", F("deaths").LT(3), "alice,erin"},
+		{"no match", F("points").GTE(60), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			members, err := mfs.GetMembers(ctx, 0, 0, Where(tt.filter))
+			if err != nil {
+				t.Fatalf("GetMembers() error = %v", err)
+			}
+			var names []string
+			for _, m := range members {
+				names = append(names, m.Member)
+			}
+			if got := strings.Join(names, ","); got != tt.expected {
+				t.Errorf("GetMembers() = %q, expected %q", got, tt.expected)
+			}
+
+			count, err := mfs.Count(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("Count() error = %v", err)
+			}
+			if count != int64(len(members)) {
+				t.Errorf("Count() = %d, expected %d", count, len(members))
+			}
+		})
+	}
+
+	// Hidden members are neither counted nor iterated
+	if err := mfs.Hide(ctx, "alice"); err != nil {
+		t.Fatalf("Hide() error = %v", err)
+	}
+	if count, err := mfs.Count(ctx, F("points").EQ(50)); err != nil || count != 1 {
+		t.Errorf("Count() = %d, %v, expected 1", count, err)
+	}
+
+	var seen []string
+	err := mfs.Iterate(ctx, F("deaths").LTE(5), func(member MemberScores) bool {
+		seen = append(seen, member.Member)
+		return len(seen) < 2
+	})
+	if err != nil {
+		t.Fatalf("Iterate() error = %v", err)
+	}
+	if got := strings.Join(seen, ","); got != "bob,dave" {
+		t.Errorf("Iterate() visited %q, expected bob,dave", got)
+	}
+
+	if _, err := mfs.Count(ctx, F("missing").GTE(1)); !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("Count() error = %v, expected ErrFieldNotFound", err)
+	}
+}
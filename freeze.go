@@ -0,0 +1,212 @@
+package zmultifield
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// frozenReply is the error reply of guarded write scripts run while the set is frozen.
+const frozenReply = "FROZEN"
+
+// writeScript is a Lua script that writes to the set, along with variants that check the frozen
+// flag first, replying FROZEN without writing anything if the set is frozen, and that record the
+// writes to the main set in the audit stream. The variants take the frozen flag, then the main set
+// and audit stream, as extra last keys and run the script with KEYS shadowed by the keys before
+// them, so the script itself needs no changes.
+type writeScript struct {
+	plain          *redis.Script
+	guarded        *redis.Script
+	audited        *redis.Script
+	guardedAudited *redis.Script
+}
+
+// scriptGuard holds the extra keys of the writeScript variant to run: the frozen flag to check,
+// and the main set with the audit stream its writes are recorded in. Empty keys select a variant
+// without the check or the recording.
+type scriptGuard struct {
+	frozen string
+	main   string
+	audit  string
+}
+
+// newWriteScript returns the writeScript of the Lua source src.
+func newWriteScript(src string) *writeScript {
+	return &writeScript{
+		plain:          redis.NewScript(src),
+		guarded:        redis.NewScript(wrapWriteScript(src, true, false)),
+		audited:        redis.NewScript(wrapWriteScript(src, false, true)),
+		guardedAudited: redis.NewScript(wrapWriteScript(src, true, true)),
+	}
+}
+
+// wrapWriteScript returns the source of a variant of the write script src.
+func wrapWriteScript(src string, guarded, audited bool) string {
+	var b strings.Builder
+	b.WriteString("local n = #KEYS\n")
+	if audited {
+		b.WriteString(auditLua)
+	}
+	if guarded {
+		b.WriteString(`if redis.call('EXISTS', KEYS[n]) == 1 then
+	return redis.error_reply('` + frozenReply + `')
+end
+n = n - 1
+`)
+	}
+	b.WriteString("return (function(KEYS, ARGV)\n" + src + "\nend)({unpack(KEYS, 1, n)}, ARGV)\n")
+	return b.String()
+}
+
+// variant returns the script variant selected by g along with its keys.
+func (s *writeScript) variant(g scriptGuard, keys []string) (*redis.Script, []string) {
+	script := s.plain
+	keys = keys[:len(keys):len(keys)]
+	if g.frozen != "" {
+		script = s.guarded
+		keys = append(keys, g.frozen)
+	}
+	if g.audit != "" {
+		script = s.audited
+		if g.frozen != "" {
+			script = s.guardedAudited
+		}
+		keys = append(keys, g.main, g.audit)
+	}
+	return script, keys
+}
+
+// Run runs the variant of the script selected by g.
+func (s *writeScript) Run(ctx context.Context, c redis.Scripter, g scriptGuard, keys []string, args ...interface{}) *redis.Cmd {
+	script, keys := s.variant(g, keys)
+	return script.Run(ctx, c, keys, args...)
+}
+
+// Eval runs the variant of the script selected by g with EVAL, e.g. in a transaction where a
+// NOSCRIPT reply can't be retried.
+func (s *writeScript) Eval(ctx context.Context, c redis.Scripter, g scriptGuard, keys []string, args ...interface{}) *redis.Cmd {
+	script, keys := s.variant(g, keys)
+	return script.Eval(ctx, c, keys, args...)
+}
+
+// frozenKey returns the key of the flag set by Freeze.
+func (mfs *MultiFieldSet) frozenKey() string {
+	return mfs.derivedKey("frozen")
+}
+
+// writeGuard returns the scriptGuard of writes to the set: they check the frozen flag if the set
+// is Freezable and are recorded if it is Audited.
+func (mfs *MultiFieldSet) writeGuard() scriptGuard {
+	g := mfs.auditGuard()
+	if mfs.freezable {
+		g.frozen = mfs.frozenKey()
+	}
+	return g
+}
+
+// frozenError converts the reply of a guarded write script run while the set is frozen into
+// ErrFrozen. Some servers prefix error replies without a code with ERR.
+func frozenError(err error) error {
+	if err != nil && strings.TrimPrefix(err.Error(), "ERR ") == frozenReply {
+		return ErrFrozen
+	}
+	return err
+}
+
+// txPipelined runs fn in a MULTI/EXEC transaction on client. If the set is Freezable the frozen
+// flag is watched and checked first, so the transaction fails with ErrFrozen if the set is frozen,
+// even by a Freeze running concurrently.
+func (mfs *MultiFieldSet) txPipelined(ctx context.Context, client redis.UniversalClient, fn func(pipe redis.Pipeliner) error) error {
+	if !mfs.freezable {
+		_, err := client.TxPipelined(ctx, fn)
+		return err
+	}
+	for attempt := 0; attempt <= mfs.optimisticRetries; attempt++ {
+		err := client.Watch(ctx, func(tx *redis.Tx) error {
+			if err := mfs.checkFrozen(ctx, tx); err != nil {
+				return err
+			}
+			_, err := tx.TxPipelined(ctx, fn)
+			return err
+		}, mfs.frozenKey())
+		if err != redis.TxFailedErr {
+			return err
+		}
+	}
+	return ErrUpdateConflict
+}
+
+// checkFrozen returns ErrFrozen if the set is Freezable and frozen, reading the flag through c,
+// normally a transaction watching it.
+func (mfs *MultiFieldSet) checkFrozen(ctx context.Context, c redis.Cmdable) error {
+	if !mfs.freezable {
+		return nil
+	}
+	frozen, err := c.Exists(ctx, mfs.frozenKey()).Result()
+	if err != nil {
+		return err
+	}
+	if frozen > 0 {
+		return ErrFrozen
+	}
+	return nil
+}
+
+// Freeze makes writes to the set fail with ErrFrozen until Unfreeze is called, e.g. during
+// season-end tabulation so the standings can't change while rewards are computed. The flag is
+// stored in Redis, so it applies to every process using the set, and writes check it atomically:
+// a write either completes before Freeze returns or fails.
+//
+// Updates, removals, pops, transfers, renames, merges, resets, decay, bulk loads, ExtractTop with
+// Remove, Hide, Unhide, Clear and Rebuild are refused. Reads, member metadata, snapshots,
+// extractions that don't remove members and EraseMember, which honors erasure requests, still
+// work. Freezing requires Freezable.
+func (mfs *MultiFieldSet) Freeze(ctx context.Context) error {
+	if !mfs.freezable {
+		return mfs.runOnError(ctx, "Freeze", "", ErrFreezingDisabled)
+	}
+	err := mfs.primary(ctx, func(client redis.UniversalClient) error {
+		return client.Set(ctx, mfs.frozenKey(), time.Now().UnixMilli(), 0).Err()
+	})
+	if err != nil {
+		return mfs.runOnError(ctx, "Freeze", "", err)
+	}
+	mfs.emitSeason(ctx, SeasonFrozen, "")
+	return nil
+}
+
+// Unfreeze allows writes to a set frozen by Freeze again. Unfreezing a set that isn't frozen is
+// not an error.
+func (mfs *MultiFieldSet) Unfreeze(ctx context.Context) error {
+	if !mfs.freezable {
+		return mfs.runOnError(ctx, "Unfreeze", "", ErrFreezingDisabled)
+	}
+	err := mfs.primary(ctx, func(client redis.UniversalClient) error {
+		return client.Del(ctx, mfs.frozenKey()).Err()
+	})
+	if err != nil {
+		return mfs.runOnError(ctx, "Unfreeze", "", err)
+	}
+	mfs.emitSeason(ctx, SeasonUnfrozen, "")
+	return nil
+}
+
+// IsFrozen reports whether the set is frozen.
+func (mfs *MultiFieldSet) IsFrozen(ctx context.Context) (_ bool, err error) {
+	defer mfs.observeRead("IsFrozen", time.Now(), &err)
+
+	if !mfs.freezable {
+		return false, nil
+	}
+	var frozen int64
+	err = mfs.primary(ctx, func(client redis.UniversalClient) error {
+		frozen, err = client.Exists(ctx, mfs.frozenKey()).Result()
+		return err
+	})
+	if err != nil {
+		return false, mfs.runOnError(ctx, "IsFrozen", "", err)
+	}
+	return frozen > 0, nil
+}
@@ -0,0 +1,112 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestFreeze(t *testing.T) {
+	for _, strategy := range []UpdateStrategy{UpdateScripted, UpdateOptimistic} {
+		mfs := newTestSetWithOptions(t, MultiFieldSetOptions{Freezable: true, HideMembers: true, UpdateStrategy: strategy})
+		ctx := context.Background()
+
+		for _, member := range []string{"alice", "bob", "carol"} {
+			if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 10}, member); err != nil {
+				t.Fatalf("IncreaseScore() error = %v", err)
+			}
+		}
+		if err := mfs.Freeze(ctx); err != nil {
+			t.Fatalf("Freeze() error = %v", err)
+		}
+		if frozen, err := mfs.IsFrozen(ctx); err != nil || !frozen {
+			t.Errorf("IsFrozen() = %v, %v, expected true", frozen, err)
+		}
+
+		writes := map[string]func() error{
+			"IncreaseScore": func() error {
+				_, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 1}, "alice")
+				return err
+			},
+			"RemoveMember": func() error {
+				_, err := mfs.RemoveMember(ctx, "bob")
+				return err
+			},
+			"PopTopMember": func() error {
+				_, err := mfs.PopTopMember(ctx)
+				return err
+			},
+			"TransferScore": func() error {
+				_, _, err := mfs.TransferScore(ctx, "alice", "bob", map[string]float64{"points": 5})
+				return err
+			},
+			"ResetFields": func() error {
+				_, err := mfs.ResetFields(ctx, "alice", "points")
+				return err
+			},
+			"Hide": func() error {
+				return mfs.Hide(ctx, "carol")
+			},
+			"ExtractTop": func() error {
+				_, err := mfs.ExtractTop(ctx, 1, ExtractOptions{Remove: true})
+				return err
+			},
+			"Clear": func() error {
+				return mfs.Clear(ctx)
+			},
+		}
+		for name, write := range writes {
+			if err := write(); !errors.Is(err, ErrFrozen) {
+				t.Errorf("%v: %s() error = %v, expected ErrFrozen", strategy, name, err)
+			}
+		}
+
+		result, err := mfs.BulkLoad(ctx, SliceIterator([]MemberScores{
+			{Member: "dave", Scores: []FieldScore{{Name: "points", Score: big.NewInt(5)}}},
+		}), 10, 1, nil)
+		if err != nil || len(result.Failures) != 1 || !errors.Is(result.Failures[0].Err, ErrFrozen) {
+			t.Errorf("BulkLoad() = %+v, %v, expected dave to fail with ErrFrozen", result, err)
+		}
+
+		// Reads and extractions that don't remove members still work, and nothing was written
+		members, err := mfs.GetTopMembers(ctx, 10)
+		if err != nil || len(members) != 3 {
+			t.Fatalf("GetTopMembers() = %+v, %v, expected 3 members", members, err)
+		}
+		if scores, err := mfs.GetScores(ctx, "alice"); err != nil || scores[0].Score.Int64() != 10 {
+			t.Errorf("GetScores(alice) = %v, %v, expected 10 points", scores, err)
+		}
+		if _, err := mfs.ExtractTop(ctx, 1, ExtractOptions{}); err != nil {
+			t.Errorf("ExtractTop() error = %v", err)
+		}
+
+		if err := mfs.Unfreeze(ctx); err != nil {
+			t.Fatalf("Unfreeze() error = %v", err)
+		}
+		if frozen, err := mfs.IsFrozen(ctx); err != nil || frozen {
+			t.Errorf("IsFrozen() = %v, %v, expected false", frozen, err)
+		}
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 1}, "alice"); err != nil {
+			t.Errorf("IncreaseScore() after Unfreeze error = %v", err)
+		}
+		if _, err := mfs.PopTopMember(ctx); err != nil {
+			t.Errorf("PopTopMember() after Unfreeze error = %v", err)
+		}
+	}
+}
+
+func TestFreezeDisabled(t *testing.T) {
+	mfs := newTestSet(t)
+	ctx := context.Background()
+
+	if err := mfs.Freeze(ctx); !errors.Is(err, ErrFreezingDisabled) {
+		t.Errorf("Freeze() error = %v, expected ErrFreezingDisabled", err)
+	}
+	if err := mfs.Unfreeze(ctx); !errors.Is(err, ErrFreezingDisabled) {
+		t.Errorf("Unfreeze() error = %v, expected ErrFreezingDisabled", err)
+	}
+	if frozen, err := mfs.IsFrozen(ctx); err != nil || frozen {
+		t.Errorf("IsFrozen() = %v, %v, expected false", frozen, err)
+	}
+}
@@ -0,0 +1,114 @@
+package zmultifield
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// GuardOptions limits how members can be updated, as a first line of defense against score
+// injection. Limits apply to IncreaseScore and its variants and to UpdateIf, but not to
+// administrative writes such as AddMember, ResetMember or merges.
+type GuardOptions struct {
+	// MaxIncrease limits by how much a single update may raise the value of each named field.
+	// Updates exceeding it fail with an IncrementLimitError.
+	MaxIncrease map[string]int64
+	// MaxUpdates limits the number of updates of a member within Window. Updates beyond it fail with
+	// a RateLimitError. The count is kept in a companion key that is checked and incremented in the
+	// same atomic write as the update. Zero means no limit.
+	MaxUpdates int64
+	// Window is the fixed time window MaxUpdates applies to, starting with a member's first update.
+	// It is required when MaxUpdates is set.
+	Window time.Duration
+}
+
+// IncrementLimitError is returned when an update raises a field by more than GuardOptions allows.
+// It matches ErrIncrementTooLarge with errors.Is.
+type IncrementLimitError struct {
+	Member   string
+	Field    string
+	Increase *big.Int
+	Max      int64
+}
+
+// Error implements the error interface.
+func (e *IncrementLimitError) Error() string {
+	return fmt.Sprintf("update raises field %s of member %s by %v (max %d)", e.Field, e.Member, e.Increase, e.Max)
+}
+
+// Is reports whether target is ErrIncrementTooLarge.
+func (e *IncrementLimitError) Is(target error) bool {
+	return target == ErrIncrementTooLarge
+}
+
+// RateLimitError is returned when a member was updated more often than GuardOptions allows. It
+// matches ErrRateLimited with errors.Is.
+type RateLimitError struct {
+	Member     string
+	MaxUpdates int64
+	Window     time.Duration
+}
+
+// Error implements the error interface.
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("member %s exceeded %d updates per %v", e.Member, e.MaxUpdates, e.Window)
+}
+
+// Is reports whether target is ErrRateLimited.
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// validateGuard checks the guard options against the set's fields.
+func (mfs *MultiFieldSet) validateGuard(opts *GuardOptions) error {
+	for name, max := range opts.MaxIncrease {
+		if mfs.GetFieldByName(name) == nil {
+			return fieldNotFoundError(name)
+		}
+		if max < 0 {
+			return fmt.Errorf("max increase of field %s must not be negative", name)
+		}
+	}
+	if opts.MaxUpdates < 0 {
+		return errors.New("max updates must not be negative")
+	}
+	if opts.MaxUpdates > 0 && opts.Window < time.Millisecond {
+		return errors.New("rate limit window must be at least a millisecond")
+	}
+	return nil
+}
+
+// limitsUpdates reports whether updates are rate limited.
+func (mfs *MultiFieldSet) limitsUpdates() bool {
+	return mfs.guard != nil && mfs.guard.MaxUpdates > 0
+}
+
+// updateCountKey returns the key counting a member's updates in the current rate limit window.
+func (mfs *MultiFieldSet) updateCountKey(member string) string {
+	return mfs.derivedKey("updates:" + member)
+}
+
+// rateLimitError returns the RateLimitError for a member.
+func (mfs *MultiFieldSet) rateLimitError(member string) error {
+	return &RateLimitError{Member: member, MaxUpdates: mfs.guard.MaxUpdates, Window: mfs.guard.Window}
+}
+
+// checkIncreases returns an IncrementLimitError if the update described by event raises a field by
+// more than MaxIncrease.
+func (mfs *MultiFieldSet) checkIncreases(event *UpdateEvent) error {
+	if mfs.guard == nil {
+		return nil
+	}
+	for i, score := range event.NewScores {
+		max, ok := mfs.guard.MaxIncrease[score.Name]
+		if !ok {
+			continue
+		}
+		increase := new(big.Int).Sub(score.Score, event.OldScores[i].Score)
+		if increase.Cmp(big.NewInt(max)) > 0 {
+			return &IncrementLimitError{Member: event.Member, Field: score.Name, Increase: increase, Max: max}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGuard(t *testing.T) {
+	for _, strategy := range []UpdateStrategy{UpdateScripted, UpdateOptimistic} {
+		client, server := newTestClient(t)
+		mfs, err := New(MultiFieldSetOptions{
+			Name: "test",
+			Fields: []Field{
+				{Name: "points", Sort: Descending, MaxValue: 1000, UpdateType: Incremental},
+				{Name: "deaths", Sort: Ascending, MaxValue: 100, UpdateType: Incremental},
+			},
+			Client:         client,
+			UpdateStrategy: strategy,
+			Guard:          &GuardOptions{MaxIncrease: map[string]int64{"points": 50}, MaxUpdates: 2, Window: time.Minute},
+		})
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		ctx := context.Background()
+
+		var limitErr *IncrementLimitError
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 51}, "alice"); !errors.As(err, &limitErr) || !errors.Is(err, ErrIncrementTooLarge) {
+			t.Fatalf("IncreaseScore() error = %v, expected an IncrementLimitError", err)
+		}
+		if limitErr.Field != "points" || limitErr.Increase.Int64() != 51 {
+			t.Errorf("error = %+v, expected points raised by 51", limitErr)
+		}
+
+		// Decreases and unguarded fields aren't limited, but every update counts towards the rate
+		for _, fields := range []map[string]float64{{"points": 50, "deaths": 80}, {"points": -40}} {
+			if _, err := mfs.IncreaseScore(ctx, fields, "alice"); err != nil {
+				t.Fatalf("IncreaseScore(%v) error = %v", fields, err)
+			}
+		}
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 1}, "alice"); !errors.Is(err, ErrRateLimited) {
+			t.Fatalf("IncreaseScore() error = %v, expected ErrRateLimited", err)
+		}
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 1}, "bob"); err != nil {
+			t.Errorf("IncreaseScore() for another member error = %v", err)
+		}
+		if points, err := mfs.GetScoreForField(ctx, "points", "alice"); err != nil || points.Int64() != 10 {
+			t.Errorf("GetScoreForField() = %v, %v, expected 10", points, err)
+		}
+
+		server.FastForward(time.Minute)
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 1}, "alice"); err != nil {
+			t.Errorf("IncreaseScore() after the window error = %v", err)
+		}
+	}
+
+	client, _ := newTestClient(t)
+	for _, guard := range []*GuardOptions{
+		{MaxIncrease: map[string]int64{"missing": 1}},
+		{MaxUpdates: 1},
+	} {
+		if _, err := New(MultiFieldSetOptions{Name: "test", Fields: []Field{{Name: "points", MaxValue: 10}}, Client: client, Guard: guard}); err == nil {
+			t.Errorf("New() with guard %+v succeeded", guard)
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package zmultifield
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// visibleRankScript returns the rank of a member among the members that aren't hidden.
+//
+// KEYS[1] is the main set and KEYS[2] the set of hidden members. ARGV[1] is the member. It returns
+// false if the member isn't in the main set or is hidden.
+var visibleRankScript = redis.NewScript(`
+if redis.call('SISMEMBER', KEYS[2], ARGV[1]) == 1 then
+	return false
+end
+local rank = redis.call('ZRANK', KEYS[1], ARGV[1])
+if not rank then
+	return false
+end
+local visible = rank
+for _, member in ipairs(redis.call('SMEMBERS', KEYS[2])) do
+	local r = redis.call('ZRANK', KEYS[1], member)
+	if r and r < rank then
+		visible = visible - 1
+	end
+end
+return visible
+`)
+
+// visibleRangeScript returns a range of the members that aren't hidden, by visible rank.
+//
+// KEYS[1] is the main set and KEYS[2] the set of hidden members. ARGV[1] is the visible offset,
+// ARGV[2] the number of members, or 0 for all, and ARGV[3] "1" to range from the worst member.
+// It returns a flat member/score list like ZRANGE WITHSCORES.
+var visibleRangeScript = redis.NewScript(`
+local rankCmd, rangeCmd = 'ZRANK', 'ZRANGE'
+if ARGV[3] == '1' then
+	rankCmd, rangeCmd = 'ZREVRANK', 'ZREVRANGE'
+end
+local ranks, hidden = {}, {}
+for _, member in ipairs(redis.call('SMEMBERS', KEYS[2])) do
+	local r = redis.call(rankCmd, KEYS[1], member)
+	if r then
+		ranks[#ranks + 1] = r
+		hidden[member] = true
+	end
+end
+table.sort(ranks)
+
+-- Skip the hidden members ranked at or before the start
+local start = tonumber(ARGV[1])
+for _, r in ipairs(ranks) do
+	if r <= start then
+		start = start + 1
+	end
+end
+
+local limit = tonumber(ARGV[2])
+local stop = -1
+if limit > 0 then
+	stop = start + limit + #ranks - 1
+end
+local result = {}
+local entries = redis.call(rangeCmd, KEYS[1], start, stop, 'WITHSCORES')
+for i = 1, #entries, 2 do
+	if limit > 0 and #result >= 2 * limit then
+		break
+	end
+	if not hidden[entries[i]] then
+		result[#result + 1] = entries[i]
+		result[#result + 1] = entries[i + 1]
+	end
+end
+return result
+`)
+
+// visibleCountScript returns the number of members that aren't hidden.
+//
+// KEYS[1] is the main set and KEYS[2] the set of hidden members.
+var visibleCountScript = redis.NewScript(`
+local count = redis.call('ZCARD', KEYS[1])
+for _, member in ipairs(redis.call('SMEMBERS', KEYS[2])) do
+	if redis.call('ZSCORE', KEYS[1], member) then
+		count = count - 1
+	end
+end
+return count
+`)
+
+// hiddenKey returns the key of the set of hidden members.
+func (mfs *MultiFieldSet) hiddenKey() string {
+	return mfs.derivedKey("hidden")
+}
+
+// Hide excludes a member from the reads of the set's members, ranks and counts, such as GetRank,
+// GetRanks, GetMembers, GetMembersByFieldRange, GetPage, ExtractTop and CountByFieldAtLeast,
+// without deleting its data, e.g. for banned or opted-out users. The member keeps receiving
+// updates, and members ranked below it move up by one. Members that aren't in the set yet can be
+// hidden too; removing a member doesn't unhide it.
+func (mfs *MultiFieldSet) Hide(ctx context.Context, member string) error {
+	if !mfs.hideMembers {
+		return mfs.runOnError(ctx, "Hide", member, ErrHidingDisabled)
+	}
+	err := mfs.write(ctx, func(client redis.UniversalClient) error {
+		return mfs.txPipelined(ctx, client, func(pipe redis.Pipeliner) error {
+			return pipe.SAdd(ctx, mfs.hiddenKey(), member).Err()
+		})
+	})
+	return mfs.runOnError(ctx, "Hide", member, err)
+}
+
+// Unhide makes a member hidden with Hide visible again.
+func (mfs *MultiFieldSet) Unhide(ctx context.Context, member string) error {
+	if !mfs.hideMembers {
+		return mfs.runOnError(ctx, "Unhide", member, ErrHidingDisabled)
+	}
+	err := mfs.write(ctx, func(client redis.UniversalClient) error {
+		return mfs.txPipelined(ctx, client, func(pipe redis.Pipeliner) error {
+			return pipe.SRem(ctx, mfs.hiddenKey(), member).Err()
+		})
+	})
+	return mfs.runOnError(ctx, "Unhide", member, err)
+}
+
+// IsHidden reports whether a member is hidden.
+func (mfs *MultiFieldSet) IsHidden(ctx context.Context, member string) (_ bool, err error) {
+	defer mfs.observeRead("IsHidden", time.Now(), &err)
+
+	if !mfs.hideMembers {
+		return false, nil
+	}
+	var hidden bool
+	err = mfs.read(ctx, func(client redis.UniversalClient) error {
+		hidden, err = client.SIsMember(ctx, mfs.hiddenKey(), member).Result()
+		return err
+	})
+	if err != nil {
+		return false, mfs.runOnError(ctx, "IsHidden", member, err)
+	}
+	return hidden, nil
+}
+
+// visibleRank returns the rank of a member among the visible members of the sorted set at key, or
+// redis.Nil if it isn't in the set or is hidden.
+func (mfs *MultiFieldSet) visibleRank(ctx context.Context, client redis.UniversalClient, key, member string) (int64, error) {
+	return visibleRankScript.Run(ctx, client, []string{key, mfs.hiddenKey()}, member).Int64()
+}
+
+// visibleRange returns limit visible members of the sorted set at key starting at the visible rank
+// offset.
+func (mfs *MultiFieldSet) visibleRange(ctx context.Context, client redis.UniversalClient, key string, offset, limit int64) ([]redis.Z, error) {
+	return mfs.runVisibleRange(ctx, client, key, offset, limit, false)
+}
+
+// visibleRevRange is like visibleRange, counting offset from the worst member and returning
+// members worst first.
+func (mfs *MultiFieldSet) visibleRevRange(ctx context.Context, client redis.UniversalClient, key string, offset, limit int64) ([]redis.Z, error) {
+	return mfs.runVisibleRange(ctx, client, key, offset, limit, true)
+}
+
+// runVisibleRange runs visibleRangeScript.
+func (mfs *MultiFieldSet) runVisibleRange(ctx context.Context, client redis.UniversalClient, key string, offset, limit int64, reverse bool) ([]redis.Z, error) {
+	if limit < 0 {
+		limit = 0
+	}
+	reply, err := visibleRangeScript.Run(ctx, client, []string{key, mfs.hiddenKey()}, offset, limit, reverseArg(reverse)).StringSlice()
+	if err != nil {
+		return nil, err
+	}
+	return parseZSlice(reply)
+}
+
+// reverseArg encodes the direction of visibleRangeScript.
+func reverseArg(reverse bool) string {
+	if reverse {
+		return "1"
+	}
+	return "0"
+}
+
+// hiddenMembers returns the set of hidden members, or nil if the set doesn't hide members.
+func (mfs *MultiFieldSet) hiddenMembers(ctx context.Context) (map[string]bool, error) {
+	if !mfs.hideMembers {
+		return nil, nil
+	}
+	var members []string
+	err := mfs.read(ctx, func(client redis.UniversalClient) error {
+		var err error
+		members, err = client.SMembers(ctx, mfs.hiddenKey()).Result()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	hidden := make(map[string]bool, len(members))
+	for _, member := range members {
+		hidden[member] = true
+	}
+	return hidden, nil
+}
+
+// hiddenWindow returns the limit and offset with which to read a range so that, once the hidden
+// members are removed by withoutHidden, it still holds the visible members from offset: the range
+// is read from its start, with room for every hidden member.
+func hiddenWindow(hidden map[string]bool, limit, offset int64) (int64, int64) {
+	if len(hidden) == 0 {
+		return limit, offset
+	}
+	if limit > 0 {
+		limit += offset + int64(len(hidden))
+	}
+	return limit, 0
+}
+
+// withoutHidden removes the hidden members from members, read with the window returned by
+// hiddenWindow, and returns limit of the rest from offset, or all of them if limit is zero or
+// less.
+func withoutHidden(members []MemberScores, hidden map[string]bool, limit, offset int64) []MemberScores {
+	if len(hidden) == 0 {
+		return members
+	}
+	visible := members[:0]
+	for _, m := range members {
+		if !hidden[m.Member] {
+			visible = append(visible, m)
+		}
+	}
+	if offset >= int64(len(visible)) {
+		return []MemberScores{}
+	}
+	visible = visible[offset:]
+	if limit > 0 && int64(len(visible)) > limit {
+		visible = visible[:limit]
+	}
+	return visible
+}
+
+// countHiddenInRange returns the number of hidden members of the main set whose raw value for
+// field lies within [rawMin, rawMax].
+func (mfs *MultiFieldSet) countHiddenInRange(ctx context.Context, hidden map[string]bool, field *multiField, rawMin, rawMax *big.Int) (int64, error) {
+	if len(hidden) == 0 {
+		return 0, nil
+	}
+	members := make([]string, 0, len(hidden))
+	for member := range hidden {
+		members = append(members, member)
+	}
+	var cmds []*redis.FloatCmd
+	err := mfs.read(ctx, func(client redis.UniversalClient) error {
+		cmds = make([]*redis.FloatCmd, len(members))
+		_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, member := range members {
+				cmds[i] = pipe.ZScore(ctx, mfs.key, member)
+			}
+			return nil
+		})
+		if err == redis.Nil {
+			err = nil
+		}
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var count int64
+	zscore := new(big.Int)
+	for i, cmd := range cmds {
+		score, err := cmd.Result()
+		if err == redis.Nil {
+			// Hidden but not in the set
+			continue
+		} else if err != nil {
+			return 0, err
+		}
+		if err := mfs.checkZScore(members[i], score); err != nil {
+			return 0, err
+		}
+		raw := mfs.extractFieldScore(field, zscore.SetInt64(int64(score)))
+		if raw.Cmp(rawMin) >= 0 && raw.Cmp(rawMax) <= 0 {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// queueRank queues the rank of a member in the main set on pipe, among the visible members if the
+// set hides members. The command fails with redis.Nil if the member isn't ranked.
+func (mfs *MultiFieldSet) queueRank(ctx context.Context, pipe redis.Pipeliner, member string) *redis.Cmd {
+	if mfs.hideMembers {
+		return visibleRankScript.Eval(ctx, pipe, []string{mfs.key, mfs.hiddenKey()}, member)
+	}
+	return pipe.Do(ctx, "zrank", mfs.key, member)
+}
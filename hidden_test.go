@@ -0,0 +1,231 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestHide(t *testing.T) {
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{HideMembers: true})
+	ctx := context.Background()
+
+	for member, points := range map[string]float64{"alice": 40, "bob": 30, "carol": 20, "dave": 10} {
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": points}, member); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+	}
+	for _, member := range []string{"alice", "carol", "mallory"} {
+		if err := mfs.Hide(ctx, member); err != nil {
+			t.Fatalf("Hide() error = %v", err)
+		}
+	}
+
+	members, err := mfs.GetTopMembers(ctx, 10)
+	if err != nil {
+		t.Fatalf("GetTopMembers() error = %v", err)
+	}
+	if len(members) != 2 || members[0].Member != "bob" || members[1].Member != "dave" {
+		t.Errorf("GetTopMembers() = %+v, expected bob and dave", members)
+	}
+	if members, err := mfs.GetMembers(ctx, 1, 1); err != nil || len(members) != 1 || members[0].Member != "dave" {
+		t.Errorf("GetMembers(1, 1) = %+v, %v, expected dave", members, err)
+	}
+	if rank, err := mfs.GetRank(ctx, "dave"); err != nil || rank != 1 {
+		t.Errorf("GetRank(dave) = %d, %v, expected 1", rank, err)
+	}
+	if _, err := mfs.GetRank(ctx, "alice"); !errors.Is(err, ErrMemberNotFound) {
+		t.Errorf("GetRank(alice) error = %v, expected ErrMemberNotFound", err)
+	}
+
+	// Hidden members keep their data and reappear when unhidden
+	if hidden, err := mfs.IsHidden(ctx, "alice"); err != nil || !hidden {
+		t.Errorf("IsHidden(alice) = %v, %v, expected true", hidden, err)
+	}
+	if points, err := mfs.GetScoreForField(ctx, "points", "alice"); err != nil || points.Int64() != 40 {
+		t.Errorf("GetScoreForField(alice) = %v, %v, expected 40", points, err)
+	}
+	if err := mfs.Unhide(ctx, "alice"); err != nil {
+		t.Fatalf("Unhide() error = %v", err)
+	}
+	if rank, err := mfs.GetRank(ctx, "dave"); err != nil || rank != 2 {
+		t.Errorf("GetRank(dave) after Unhide = %d, %v, expected 2", rank, err)
+	}
+
+	if err := newTestSet(t).Hide(ctx, "alice"); !errors.Is(err, ErrHidingDisabled) {
+		t.Errorf("Hide() error = %v, expected ErrHidingDisabled", err)
+	}
+}
+
+// newHiddenTestSet returns a set of five members with points 50 to 10, alice to eve, where bob and
+// dave are hidden.
+func newHiddenTestSet(t *testing.T, opts MultiFieldSetOptions) *MultiFieldSet {
+	t.Helper()
+	opts.HideMembers = true
+	mfs := newTestSetWithOptions(t, opts)
+	ctx := context.Background()
+	for member, points := range map[string]float64{"alice": 50, "bob": 40, "carol": 30, "dave": 20, "eve": 10} {
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": points}, member); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+	}
+	for _, member := range []string{"bob", "dave"} {
+		if err := mfs.Hide(ctx, member); err != nil {
+			t.Fatalf("Hide() error = %v", err)
+		}
+	}
+	return mfs
+}
+
+// memberNames returns the names of members.
+func memberNames(members []MemberScores) []string {
+	names := []string{}
+	for _, m := range members {
+		names = append(names, m.Member)
+	}
+	return names
+}
+
+func TestHide_Reads(t *testing.T) {
+	mfs := newHiddenTestSet(t, MultiFieldSetOptions{})
+	ctx := context.Background()
+
+	ranks, err := mfs.GetRanks(ctx, "alice", "bob", "carol", "eve")
+	if err != nil {
+		t.Fatalf("GetRanks() error = %v", err)
+	}
+	if want := map[string]int64{"alice": 0, "carol": 1, "eve": 2}; !reflect.DeepEqual(ranks, want) {
+		t.Errorf("GetRanks() = %v, expected %v", ranks, want)
+	}
+
+	page, err := mfs.GetPage(ctx, 2, 2)
+	if err != nil {
+		t.Fatalf("GetPage() error = %v", err)
+	}
+	if page.TotalMembers != 3 || len(page.Entries) != 1 || page.Entries[0].Member != "eve" || page.Entries[0].Rank != 2 {
+		t.Errorf("GetPage(2, 2) = %+v, expected eve at rank 2 of 3", page)
+	}
+
+	around, err := mfs.GetMembersAround(ctx, "carol", 1)
+	if err != nil {
+		t.Fatalf("GetMembersAround() error = %v", err)
+	}
+	if len(around) != 3 || around[0].Member != "alice" || around[1].Member != "carol" || around[1].Rank != 1 || around[2].Member != "eve" {
+		t.Errorf("GetMembersAround(carol) = %+v, expected alice, carol, eve", around)
+	}
+	if _, err := mfs.GetMembersAround(ctx, "bob", 1); !errors.Is(err, ErrMemberNotFound) {
+		t.Errorf("GetMembersAround(bob) error = %v, expected ErrMemberNotFound", err)
+	}
+
+	tests := []struct {
+		name string
+		read func() ([]MemberScores, error)
+		want []string
+	}{
+		{"GetBottomMembers", func() ([]MemberScores, error) { return mfs.GetBottomMembers(ctx, 2) }, []string{"carol", "eve"}},
+		{"GetMembersReverse", func() ([]MemberScores, error) { return mfs.GetMembersReverse(ctx, 2, 1) }, []string{"carol", "alice"}},
+		{"GetMembersInto", func() ([]MemberScores, error) { return mfs.GetMembersInto(ctx, nil, 2, 1) }, []string{"carol", "eve"}},
+		{"GetTopMembersByField", func() ([]MemberScores, error) { return mfs.GetTopMembersByField(ctx, "points", 2) }, []string{"alice", "carol"}},
+	}
+	for _, test := range tests {
+		members, err := test.read()
+		if err != nil {
+			t.Fatalf("%s() error = %v", test.name, err)
+		}
+		if got := memberNames(members); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s() = %v, expected %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestHide_RangeReads(t *testing.T) {
+	for _, indexes := range []bool{false, true} {
+		mfs := newHiddenTestSet(t, MultiFieldSetOptions{MaintainFieldIndexes: indexes})
+		ctx := context.Background()
+
+		tests := []struct {
+			name string
+			read func() ([]MemberScores, error)
+			want []string
+		}{
+			{"GetMembersByFieldRange(points)", func() ([]MemberScores, error) { return mfs.GetMembersByFieldRange(ctx, "points", 0, 100, 2, 1) }, []string{"carol", "eve"}},
+			{"GetMembersByFieldRange(deaths)", func() ([]MemberScores, error) { return mfs.GetMembersByFieldRange(ctx, "deaths", 0, 0, 2, 1) }, []string{"carol", "eve"}},
+			{"GetMembersByFieldRange(all)", func() ([]MemberScores, error) { return mfs.GetMembersByFieldRange(ctx, "points", 15, 45, 0, 0) }, []string{"carol"}},
+			{"GetMembersInRange", func() ([]MemberScores, error) { return mfs.GetMembersInRange(ctx, 2, 1, "", "") }, []string{"carol", "eve"}},
+			{"GetTopMembersWithMeta", func() ([]MemberScores, error) {
+				joined, err := mfs.GetTopMembersWithMeta(ctx, 2)
+				members := make([]MemberScores, len(joined))
+				for i, m := range joined {
+					members[i] = m.MemberScores
+				}
+				return members, err
+			}, []string{"alice", "carol"}},
+		}
+		for _, test := range tests {
+			members, err := test.read()
+			if err != nil {
+				t.Fatalf("%s error = %v", test.name, err)
+			}
+			if got := memberNames(members); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("indexes %v: %s = %v, expected %v", indexes, test.name, got, test.want)
+			}
+		}
+
+		if n, err := mfs.CountByFieldAtLeast(ctx, "points", 15); err != nil || n != 2 {
+			t.Errorf("indexes %v: CountByFieldAtLeast(points, 15) = %d, %v, expected 2", indexes, n, err)
+		}
+		if n, err := mfs.CountByFieldAtLeast(ctx, "deaths", 0); err != nil || n != 3 {
+			t.Errorf("indexes %v: CountByFieldAtLeast(deaths, 0) = %d, %v, expected 3", indexes, n, err)
+		}
+	}
+}
+
+func TestHide_TopMembersByFieldIndex(t *testing.T) {
+	mfs := newHiddenTestSet(t, MultiFieldSetOptions{MaintainFieldIndexes: true})
+	members, err := mfs.GetTopMembersByField(context.Background(), "points", 3)
+	if err != nil {
+		t.Fatalf("GetTopMembersByField() error = %v", err)
+	}
+	if got, want := memberNames(members), []string{"alice", "carol", "eve"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetTopMembersByField() = %v, expected %v", got, want)
+	}
+}
+
+func TestHide_ExtractTop(t *testing.T) {
+	mfs := newHiddenTestSet(t, MultiFieldSetOptions{MaintainFieldIndexes: true})
+	ctx := context.Background()
+
+	extraction, err := mfs.ExtractTop(ctx, 2, ExtractOptions{Label: "season-1", Remove: true})
+	if err != nil {
+		t.Fatalf("ExtractTop() error = %v", err)
+	}
+	if len(extraction.Entries) != 2 || extraction.Entries[0].Member != "alice" || extraction.Entries[1].Member != "carol" || extraction.Entries[1].Rank != 1 {
+		t.Errorf("ExtractTop() = %+v, expected alice and carol", extraction.Entries)
+	}
+	// The hidden members stay in the set, and the extracted ones leave the indexes too
+	if exists, _ := mfs.MemberExists(ctx, "bob"); !exists {
+		t.Error("ExtractTop() removed hidden member bob")
+	}
+	if _, err := mfs.GetFieldRank(ctx, "points", "carol"); !errors.Is(err, ErrMemberNotFound) {
+		t.Errorf("GetFieldRank(carol) error = %v, expected ErrMemberNotFound", err)
+	}
+}
+
+func TestHide_Rebuild(t *testing.T) {
+	mfs := newHiddenTestSet(t, MultiFieldSetOptions{})
+	ctx := context.Background()
+	members, err := mfs.GetMembers(ctx, 0, 0)
+	if err != nil {
+		t.Fatalf("GetMembers() error = %v", err)
+	}
+	if _, err := mfs.Rebuild(ctx, SliceIterator(append(members, MemberScores{Member: "bob"}))); err != nil {
+		t.Fatalf("Rebuild() error = %v", err)
+	}
+	if hidden, err := mfs.IsHidden(ctx, "bob"); err != nil || !hidden {
+		t.Errorf("IsHidden(bob) after Rebuild = %v, %v, expected true", hidden, err)
+	}
+	if _, err := mfs.GetRank(ctx, "bob"); !errors.Is(err, ErrMemberNotFound) {
+		t.Errorf("GetRank(bob) after Rebuild error = %v, expected ErrMemberNotFound", err)
+	}
+}
@@ -0,0 +1,119 @@
+package zmultifield
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// HistoryOptions enables recording every member's decoded field values after each update.
+// Each member's history is kept in its own sorted set scored by the update time in milliseconds.
+type HistoryOptions struct {
+	// MaxEntries caps the number of entries kept per member, dropping the oldest first.
+	// Zero keeps every entry.
+	MaxEntries int64
+	// TTL expires a member's history after it has not been updated for the given duration.
+	// Zero keeps the history forever.
+	TTL time.Duration
+}
+
+// HistoryPoint is a field value recorded at a point in time.
+type HistoryPoint struct {
+	Time  time.Time
+	Value *big.Int
+}
+
+// historyEntry is the JSON document stored for each recorded update.
+type historyEntry struct {
+	Time   int64               `json:"t"`
+	Scores map[string]*big.Int `json:"v"`
+}
+
+// historyKey returns the key of the sorted set holding a member's history.
+func (mfs *MultiFieldSet) historyKey(member string) string {
+	return mfs.derivedKey("history:" + member)
+}
+
+// recordHistory appends the new scores of an update to the member's history. Like notifications it
+// is best effort: failures are reported to the error hooks but don't fail the update.
+func (mfs *MultiFieldSet) recordHistory(ctx context.Context, event *UpdateEvent) {
+	if mfs.history == nil {
+		return
+	}
+
+	now := time.Now()
+	entry := historyEntry{
+		Time:   now.UnixNano(),
+		Scores: make(map[string]*big.Int, len(event.NewScores)),
+	}
+	for _, score := range event.NewScores {
+		entry.Scores[score.Name] = score.Score
+	}
+	payload, err := json.Marshal(entry)
+	if err != nil {
+		mfs.runOnError(ctx, "RecordHistory", event.Member, err)
+		return
+	}
+
+	key := mfs.historyKey(event.Member)
+	err = mfs.write(ctx, func(client redis.UniversalClient) error {
+		_, err := client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.ZAdd(ctx, key, &redis.Z{Score: float64(now.UnixMilli()), Member: payload})
+			if mfs.history.MaxEntries > 0 {
+				pipe.ZRemRangeByRank(ctx, key, 0, -mfs.history.MaxEntries-1)
+			}
+			if mfs.history.TTL > 0 {
+				pipe.Expire(ctx, key, mfs.history.TTL)
+			}
+			return nil
+		})
+		return err
+	})
+	if err != nil {
+		mfs.runOnError(ctx, "RecordHistory", event.Member, err)
+	}
+}
+
+// GetHistory returns the values a field of a member had after each update between from and to,
+// inclusive, oldest first. It requires HistoryOptions.
+func (mfs *MultiFieldSet) GetHistory(ctx context.Context, member string, fieldName string, from, to time.Time) (_ []HistoryPoint, err error) {
+	defer mfs.observeRead("GetHistory", time.Now(), &err)
+
+	if mfs.history == nil {
+		return nil, ErrHistoryDisabled
+	}
+	if mfs.GetFieldByName(fieldName) == nil {
+		return nil, fieldNotFoundError(fieldName)
+	}
+
+	var payloads []string
+	err = mfs.read(ctx, func(client redis.UniversalClient) error {
+		payloads, err = client.ZRangeByScore(ctx, mfs.historyKey(member), &redis.ZRangeBy{
+			Min: strconv.FormatInt(from.UnixMilli(), 10),
+			Max: strconv.FormatInt(to.UnixMilli(), 10),
+		}).Result()
+		return err
+	})
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "GetHistory", member, err)
+	}
+
+	points := make([]HistoryPoint, 0, len(payloads))
+	for _, payload := range payloads {
+		var entry historyEntry
+		if err := json.Unmarshal([]byte(payload), &entry); err != nil {
+			return nil, mfs.runOnError(ctx, "GetHistory", member, err)
+		}
+		value, ok := entry.Scores[fieldName]
+		if !ok {
+			// Recorded before the field was added to the schema
+			continue
+		}
+		points = append(points, HistoryPoint{Time: time.Unix(0, entry.Time), Value: value})
+	}
+	return points, nil
+}
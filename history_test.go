@@ -0,0 +1,52 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHistory(t *testing.T) {
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{History: &HistoryOptions{MaxEntries: 3}})
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 10}, "alice"); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+	}
+
+	points, err := mfs.GetHistory(ctx, "alice", "points", start.Add(-time.Second), time.Now().Add(time.Second))
+	if err != nil {
+		t.Fatalf("GetHistory() error = %v", err)
+	}
+	if len(points) != 3 {
+		t.Fatalf("GetHistory() returned %d points, expected 3", len(points))
+	}
+	for i, expected := range []int64{20, 30, 40} {
+		if points[i].Value.Int64() != expected {
+			t.Errorf("GetHistory()[%d] = %v, expected %d", i, points[i].Value, expected)
+		}
+	}
+
+	if points, _ := mfs.GetHistory(ctx, "alice", "points", start.Add(-time.Hour), start.Add(-time.Minute)); len(points) != 0 {
+		t.Errorf("GetHistory() before any update returned %d points, expected 0", len(points))
+	}
+
+	if err := mfs.Clear(ctx); err != nil {
+		t.Fatalf("Clear() error = %v", err)
+	}
+	if n, _ := mfs.client.Exists(ctx, mfs.historyKey("alice")).Result(); n != 0 {
+		t.Errorf("history of alice still exists after Clear()")
+	}
+}
+
+func TestHistory_Disabled(t *testing.T) {
+	mfs := newTestSet(t)
+
+	if _, err := mfs.GetHistory(context.Background(), "alice", "points", time.Time{}, time.Now()); !errors.Is(err, ErrHistoryDisabled) {
+		t.Errorf("GetHistory() error = %v, expected ErrHistoryDisabled", err)
+	}
+}
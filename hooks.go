@@ -0,0 +1,114 @@
+package zmultifield
+
+import (
+	"context"
+	"sync"
+)
+
+// UpdateEvent describes a single update applied to a member of a MultiFieldSet.
+type UpdateEvent struct {
+	Set       string
+	Member    string
+	Deltas    map[string]float64
+	OldScores []FieldScore
+	NewScores []FieldScore
+}
+
+// BeforeUpdateHook is called before an update is written to Redis.
+// Returning an error aborts the update and the error is returned to the caller.
+type BeforeUpdateHook func(ctx context.Context, event *UpdateEvent) error
+
+// AfterUpdateHook is called after an update has been written to Redis.
+type AfterUpdateHook func(ctx context.Context, event *UpdateEvent)
+
+// ErrorHook is called whenever an update or read operation fails.
+type ErrorHook func(ctx context.Context, op string, member string, err error)
+
+// RankChangedHook is called when an update moves a member across one of the RankThresholds.
+type RankChangedHook func(ctx context.Context, event RankChangedEvent)
+
+// TierChangedHook is called when a member moves to another of the Tiers.
+type TierChangedHook func(ctx context.Context, event TierChangedEvent)
+
+// hooks holds the hooks registered on a MultiFieldSet.
+type hooks struct {
+	mu           sync.RWMutex
+	beforeUpdate []BeforeUpdateHook
+	afterUpdate  []AfterUpdateHook
+	onError      []ErrorHook
+	rankChanged  []RankChangedHook
+	tierChanged  []TierChangedHook
+	triggers     []trigger
+	// exact is set when a hook relies on the scores before and after every update being exact,
+	// which makes updates write conditionally
+	exact bool
+}
+
+// BeforeUpdate registers a hook that runs before every update.
+func (mfs *MultiFieldSet) BeforeUpdate(hook BeforeUpdateHook) {
+	mfs.hooks.mu.Lock()
+	defer mfs.hooks.mu.Unlock()
+	mfs.hooks.beforeUpdate = append(mfs.hooks.beforeUpdate, hook)
+}
+
+// AfterUpdate registers a hook that runs after every successful update.
+func (mfs *MultiFieldSet) AfterUpdate(hook AfterUpdateHook) {
+	mfs.hooks.mu.Lock()
+	defer mfs.hooks.mu.Unlock()
+	mfs.hooks.afterUpdate = append(mfs.hooks.afterUpdate, hook)
+}
+
+// OnError registers a hook that runs whenever an operation fails.
+func (mfs *MultiFieldSet) OnError(hook ErrorHook) {
+	mfs.hooks.mu.Lock()
+	defer mfs.hooks.mu.Unlock()
+	mfs.hooks.onError = append(mfs.hooks.onError, hook)
+}
+
+// OnRankChanged registers a hook that runs whenever a member crosses one of the RankThresholds.
+func (mfs *MultiFieldSet) OnRankChanged(hook RankChangedHook) {
+	mfs.hooks.mu.Lock()
+	defer mfs.hooks.mu.Unlock()
+	mfs.hooks.rankChanged = append(mfs.hooks.rankChanged, hook)
+}
+
+// OnTierChanged registers a hook that runs whenever a member moves to another of the Tiers.
+func (mfs *MultiFieldSet) OnTierChanged(hook TierChangedHook) {
+	mfs.hooks.mu.Lock()
+	defer mfs.hooks.mu.Unlock()
+	mfs.hooks.tierChanged = append(mfs.hooks.tierChanged, hook)
+}
+
+// runBeforeUpdate runs the before-update hooks, stopping at the first error.
+func (mfs *MultiFieldSet) runBeforeUpdate(ctx context.Context, event *UpdateEvent) error {
+	mfs.hooks.mu.RLock()
+	defer mfs.hooks.mu.RUnlock()
+	for _, hook := range mfs.hooks.beforeUpdate {
+		if err := hook(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runAfterUpdate runs the after-update hooks.
+func (mfs *MultiFieldSet) runAfterUpdate(ctx context.Context, event *UpdateEvent) {
+	mfs.hooks.mu.RLock()
+	defer mfs.hooks.mu.RUnlock()
+	for _, hook := range mfs.hooks.afterUpdate {
+		hook(ctx, event)
+	}
+}
+
+// runOnError runs the error hooks and returns err unchanged.
+func (mfs *MultiFieldSet) runOnError(ctx context.Context, op string, member string, err error) error {
+	if err == nil {
+		return nil
+	}
+	mfs.hooks.mu.RLock()
+	defer mfs.hooks.mu.RUnlock()
+	for _, hook := range mfs.hooks.onError {
+		hook(ctx, op, member, err)
+	}
+	return err
+}
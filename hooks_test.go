@@ -0,0 +1,88 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestHooks_BeforeAndAfterUpdate(t *testing.T) {
+	mfs := newTestSet(t)
+	ctx := context.Background()
+
+	var before, after *UpdateEvent
+	mfs.BeforeUpdate(func(ctx context.Context, event *UpdateEvent) error {
+		before = event
+		return nil
+	})
+	mfs.AfterUpdate(func(ctx context.Context, event *UpdateEvent) {
+		after = event
+	})
+
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 10}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 5, "deaths": 1}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+
+	if before == nil || after == nil {
+		t.Fatalf("hooks were not called")
+	}
+	if after.Member != "alice" || after.Set != "test" {
+		t.Errorf("AfterUpdate event = %+v, expected member alice in set test", after)
+	}
+	if got := after.OldScores[0].Score.Int64(); got != 10 {
+		t.Errorf("OldScores points = %d, expected 10", got)
+	}
+	if got := after.NewScores[0].Score.Int64(); got != 15 {
+		t.Errorf("NewScores points = %d, expected 15", got)
+	}
+	if got := after.NewScores[1].Score.Int64(); got != 1 {
+		t.Errorf("NewScores deaths = %d, expected 1", got)
+	}
+	if after.Deltas["points"] != 5 {
+		t.Errorf("Deltas points = %v, expected 5", after.Deltas["points"])
+	}
+}
+
+func TestHooks_BeforeUpdateAborts(t *testing.T) {
+	mfs := newTestSet(t)
+	ctx := context.Background()
+
+	rejected := errors.New("rejected")
+	mfs.BeforeUpdate(func(ctx context.Context, event *UpdateEvent) error {
+		return rejected
+	})
+
+	afterCalled := false
+	mfs.AfterUpdate(func(ctx context.Context, event *UpdateEvent) {
+		afterCalled = true
+	})
+
+	var hookErr error
+	var hookOp string
+	mfs.OnError(func(ctx context.Context, op string, member string, err error) {
+		hookOp = op
+		hookErr = err
+	})
+
+	_, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 10}, "bob")
+	if !errors.Is(err, rejected) {
+		t.Fatalf("IncreaseScore() error = %v, expected %v", err, rejected)
+	}
+	if afterCalled {
+		t.Errorf("AfterUpdate hook called for aborted update")
+	}
+	if hookOp != "IncreaseScore" || !errors.Is(hookErr, rejected) {
+		t.Errorf("OnError got op %q err %v", hookOp, hookErr)
+	}
+
+	scores, err := mfs.GetScoreForField(ctx, "points", "bob")
+	if err != nil {
+		t.Fatalf("GetScoreForField() error = %v", err)
+	}
+	if scores.Sign() != 0 {
+		t.Errorf("aborted update was written to Redis")
+	}
+}
@@ -0,0 +1,36 @@
+package zmultifield
+
+import (
+	"context"
+	"time"
+)
+
+// defaultIdempotencyTTL is how long idempotency keys are remembered when IdempotencyTTL is not set.
+const defaultIdempotencyTTL = 24 * time.Hour
+
+// idempotencyKeyKey is the context key of an idempotency key.
+type idempotencyKeyKey struct{}
+
+// WithIdempotencyKey returns a context under which updates, such as IncreaseScore, UpdateIf,
+// AddMember or UpdateMulti, are applied at most once per key and set: the key is recorded in the
+// same atomic write as the update and remembered for IdempotencyTTL, and replaying the update
+// fails with ErrDuplicateUpdate without changing the set. Use the ID of the message that carries
+// the update, so redeliveries from at-least-once queues don't double-count increments.
+//
+// An update refused for another reason, such as a rate limit, doesn't record the key, so it can
+// be retried. Updates under a key always write through a script or a transaction.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyKey{}, key)
+}
+
+// idempotencyKey returns the idempotency key of ctx, or "" if it has none.
+func idempotencyKey(ctx context.Context) string {
+	key, _ := ctx.Value(idempotencyKeyKey{}).(string)
+	return key
+}
+
+// idempotencyRecordKey returns the key recording that the update with an idempotency key was
+// applied.
+func (mfs *MultiFieldSet) idempotencyRecordKey(key string) string {
+	return mfs.derivedKey("idempotency:" + key)
+}
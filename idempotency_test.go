@@ -0,0 +1,74 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+	for name, opts := range map[string]MultiFieldSetOptions{
+		"scripted":   {},
+		"optimistic": {UpdateStrategy: UpdateOptimistic},
+		// A single counter field is otherwise incremented with ZINCRBY
+		"counter": {Fields: []Field{{Name: "points", Sort: Descending, MaxValue: 1000, UpdateType: Incremental}}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			client, server := newTestClient(t)
+			opts.Name, opts.Client, opts.IdempotencyTTL = "test", client, time.Hour
+			if opts.Fields == nil {
+				opts.Fields = []Field{
+					{Name: "points", Sort: Descending, MaxValue: 1000, UpdateType: Incremental},
+					{Name: "deaths", Sort: Ascending, MaxValue: 100, UpdateType: Incremental},
+				}
+			}
+			mfs, err := New(opts)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			var applied int
+			mfs.AfterUpdate(func(ctx context.Context, event *UpdateEvent) { applied++ })
+
+			ctx := WithIdempotencyKey(context.Background(), "msg-1")
+			if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 10}, "alice"); err != nil {
+				t.Fatalf("IncreaseScore() error = %v", err)
+			}
+			if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 10}, "alice"); !errors.Is(err, ErrDuplicateUpdate) {
+				t.Errorf("replayed IncreaseScore() error = %v, expected ErrDuplicateUpdate", err)
+			}
+			other := WithIdempotencyKey(context.Background(), "msg-2")
+			if _, err := mfs.IncreaseScore(other, map[string]float64{"points": 5}, "alice"); err != nil {
+				t.Fatalf("IncreaseScore() with another key error = %v", err)
+			}
+			if scores, _ := mfs.GetScores(context.Background(), "alice"); FieldValue(scores, "points") != 15 {
+				t.Errorf("points = %v, expected the replay to be skipped", scores)
+			}
+			if applied != 2 {
+				t.Errorf("after-update hooks ran %d times, expected 2", applied)
+			}
+
+			// Keys are forgotten after the TTL
+			server.FastForward(time.Hour)
+			if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 10}, "alice"); err != nil {
+				t.Errorf("IncreaseScore() after the TTL error = %v", err)
+			}
+		})
+	}
+}
+
+func TestIdempotencyKey_NotRecordedOnFailure(t *testing.T) {
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{Guard: &GuardOptions{MaxUpdates: 1, Window: time.Minute}})
+	ctx := context.Background()
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 1}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+
+	keyed := WithIdempotencyKey(ctx, "msg-1")
+	if _, err := mfs.IncreaseScore(keyed, map[string]float64{"points": 10}, "alice"); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("IncreaseScore() over the rate limit error = %v, expected ErrRateLimited", err)
+	}
+	if exists, _ := mfs.client.Exists(ctx, mfs.idempotencyRecordKey("msg-1")).Result(); exists != 0 {
+		t.Error("a rate limited update recorded its idempotency key")
+	}
+}
@@ -0,0 +1,131 @@
+package zmultifield
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RepairPolicy selects what Repair does with members whose scores CheckIntegrity flags.
+type RepairPolicy int
+
+const (
+	// RepairClamp rounds fractional scores down and clamps scores into the schema's range, so
+	// negative scores become 0 and scores that are too large become the largest valid zscore.
+	RepairClamp RepairPolicy = iota
+	// RepairRemove removes bad members along with their field index entries, history and metadata.
+	RepairRemove
+)
+
+// IntegrityReport describes the members CheckIntegrity or Repair found to have invalid scores.
+type IntegrityReport struct {
+	// Scanned is the number of members whose scores were checked.
+	Scanned int64
+	// Problems lists every member with an invalid score.
+	Problems []VerifyProblem
+	// Repaired is the number of members Repair clamped or removed.
+	Repaired int64
+}
+
+// OK reports whether no invalid scores were found.
+func (r *IntegrityReport) OK() bool {
+	return len(r.Problems) == 0
+}
+
+// CheckIntegrity scans every member of the set and reports the scores that can't have been
+// written by the set: scores that aren't integers, are negative or exceed the schema's bit range.
+// Unlike Verify, which samples both ends of the set, it reads the whole set in batches and is meant
+// for audits, e.g. after an incident.
+func (mfs *MultiFieldSet) CheckIntegrity(ctx context.Context) (*IntegrityReport, error) {
+	report, err := mfs.checkIntegrity(ctx)
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "CheckIntegrity", "", err)
+	}
+	return report, nil
+}
+
+// Repair runs CheckIntegrity and fixes the members it flags according to policy. Members updated
+// concurrently between the scan and the repair may be overwritten, so writes to the set should be
+// paused while it runs.
+func (mfs *MultiFieldSet) Repair(ctx context.Context, policy RepairPolicy) (*IntegrityReport, error) {
+	report, err := mfs.checkIntegrity(ctx)
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "Repair", "", err)
+	}
+	if report.OK() {
+		return report, nil
+	}
+
+	switch policy {
+	case RepairClamp:
+		maxZScore := mfs.maxZScore()
+		for _, problem := range report.Problems {
+			zscore := clampZScore(problem.Score, maxZScore)
+			written, err := mfs.writeMember(ctx, problem.Member, mfs.getFieldScores(zscore), zscore, writeIfExists)
+			if err != nil {
+				return report, mfs.runOnError(ctx, "Repair", problem.Member, err)
+			}
+			if written {
+				report.Repaired++
+			}
+		}
+	case RepairRemove:
+		members := make([]string, len(report.Problems))
+		for i, problem := range report.Problems {
+			members[i] = problem.Member
+		}
+		removed, err := mfs.RemoveMember(ctx, members...)
+		if err != nil {
+			return report, err
+		}
+		report.Repaired = removed
+	default:
+		return report, mfs.runOnError(ctx, "Repair", "", fmt.Errorf("unknown repair policy %d", policy))
+	}
+	return report, nil
+}
+
+// checkIntegrity reads the set in batches of scanBatchSize and checks every score.
+func (mfs *MultiFieldSet) checkIntegrity(ctx context.Context) (*IntegrityReport, error) {
+	report := &IntegrityReport{}
+	for start := int64(0); ; start += scanBatchSize {
+		var batch []redis.Z
+		err := mfs.read(ctx, func(client redis.UniversalClient) error {
+			var err error
+			batch, err = client.ZRangeWithScores(ctx, mfs.key, start, start+scanBatchSize-1).Result()
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, z := range batch {
+			report.Scanned++
+			if reason := mfs.checkScore(z.Score); reason != "" {
+				member := memberName(z)
+				report.Problems = append(report.Problems, VerifyProblem{Member: member, Score: z.Score, Reason: reason})
+			}
+		}
+		if len(batch) < scanBatchSize {
+			return report, nil
+		}
+	}
+}
+
+// clampZScore returns the valid zscore closest to score, rounding fractional scores down.
+func clampZScore(score float64, maxZScore *big.Int) *big.Int {
+	switch {
+	case math.IsNaN(score) || score < 0:
+		return new(big.Int)
+	case math.IsInf(score, 1) || score > maxExactFloat:
+		return new(big.Int).Set(maxZScore)
+	}
+	zscore, _ := big.NewFloat(math.Floor(score)).Int(nil)
+	if zscore.Cmp(maxZScore) > 0 {
+		return zscore.Set(maxZScore)
+	}
+	return zscore
+}
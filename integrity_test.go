@@ -0,0 +1,91 @@
+package zmultifield
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// corruptTestSet returns a set with one valid member and three members whose scores were written
+// around the library.
+func corruptTestSet(t *testing.T, opts MultiFieldSetOptions) *MultiFieldSet {
+	t.Helper()
+	ctx := context.Background()
+	mfs := newTestSetWithOptions(t, opts)
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 10}, "valid"); err != nil {
+		t.Fatalf("IncreaseScore failed: %v", err)
+	}
+	err := mfs.client.ZAdd(ctx, mfs.GetKey(),
+		&redis.Z{Score: 12.5, Member: "fractional"},
+		&redis.Z{Score: -3, Member: "negative"},
+		&redis.Z{Score: math.Inf(1), Member: "infinite"},
+	).Err()
+	if err != nil {
+		t.Fatalf("ZAdd failed: %v", err)
+	}
+	return mfs
+}
+
+func TestCheckIntegrity(t *testing.T) {
+	ctx := context.Background()
+	mfs := corruptTestSet(t, MultiFieldSetOptions{})
+
+	report, err := mfs.CheckIntegrity(ctx)
+	if err != nil {
+		t.Fatalf("CheckIntegrity failed: %v", err)
+	}
+	if report.Scanned != 4 || len(report.Problems) != 3 {
+		t.Fatalf("CheckIntegrity = %+v, expected 4 scanned members and 3 problems", report)
+	}
+	for _, problem := range report.Problems {
+		if problem.Member == "valid" {
+			t.Errorf("CheckIntegrity flagged the valid member: %+v", problem)
+		}
+	}
+}
+
+func TestRepair_Clamp(t *testing.T) {
+	ctx := context.Background()
+	mfs := corruptTestSet(t, MultiFieldSetOptions{MaintainFieldIndexes: true})
+
+	report, err := mfs.Repair(ctx, RepairClamp)
+	if err != nil {
+		t.Fatalf("Repair failed: %v", err)
+	}
+	if report.Repaired != 3 {
+		t.Errorf("Repair = %+v, expected 3 repaired members", report)
+	}
+
+	expected := map[string]float64{"fractional": 12, "negative": 0, "infinite": float64(mfs.maxZScore().Int64())}
+	for member, score := range expected {
+		got, err := mfs.client.ZScore(ctx, mfs.GetKey(), member).Result()
+		if err != nil || got != score {
+			t.Errorf("ZScore(%s) = %v, %v, expected %v", member, got, err, score)
+		}
+	}
+	if rank, err := mfs.GetFieldRank(ctx, "deaths", "infinite"); err != nil || rank != 3 {
+		t.Errorf("GetFieldRank(deaths, infinite) = %d, %v, expected the clamped member in the index", rank, err)
+	}
+
+	if report, err := mfs.CheckIntegrity(ctx); err != nil || !report.OK() {
+		t.Errorf("CheckIntegrity after Repair = %+v, %v, expected no problems", report, err)
+	}
+}
+
+func TestRepair_Remove(t *testing.T) {
+	ctx := context.Background()
+	mfs := corruptTestSet(t, MultiFieldSetOptions{})
+
+	report, err := mfs.Repair(ctx, RepairRemove)
+	if err != nil {
+		t.Fatalf("Repair failed: %v", err)
+	}
+	if report.Repaired != 3 {
+		t.Errorf("Repair = %+v, expected 3 removed members", report)
+	}
+	if count, err := mfs.GetCardinality(ctx); err != nil || count != 1 {
+		t.Errorf("GetCardinality = %d, %v, expected only the valid member to remain", count, err)
+	}
+}
@@ -0,0 +1,46 @@
+package zmultifield
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+)
+
+// maxExactDelta is the largest magnitude of a field value or delta. No field is wider than 53 bits,
+// so anything larger is out of range, and every integer up to it is exact in a float64.
+const maxExactDelta = 1 << 53
+
+// toInt64 converts a field value or delta given as a float64 into an int64, returning an error
+// instead of truncating fractional, non-finite or oversized values.
+func toInt64(field string, value float64) (int64, error) {
+	if math.IsNaN(value) || math.IsInf(value, 0) || value != math.Trunc(value) {
+		return 0, fmt.Errorf("%w: %v for field %s", ErrInvalidValue, value, field)
+	}
+	if math.Abs(value) > maxExactDelta {
+		return 0, fmt.Errorf("%w: %v for field %s", ErrScoreOutOfRange, value, field)
+	}
+	return int64(value), nil
+}
+
+// IncreaseScoreInt is like IncreaseScore but takes integer deltas, so large values can't lose
+// precision on the way in.
+func (mfs *MultiFieldSet) IncreaseScoreInt(ctx context.Context, fields map[string]int64, member string) (_ *big.Int, err error) {
+	defer mfs.observeUpdate("IncreaseScoreInt", time.Now(), &err)
+
+	deltas := make(map[string]float64, len(fields))
+	for name, value := range fields {
+		if value > maxExactDelta || value < -maxExactDelta {
+			return nil, mfs.runOnError(ctx, "IncreaseScoreInt", member,
+				fmt.Errorf("%w: %d for field %s", ErrScoreOutOfRange, value, name))
+		}
+		deltas[name] = float64(value)
+	}
+
+	result, err := mfs.increaseScoreOrQueue(ctx, deltas, member)
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "IncreaseScoreInt", member, err)
+	}
+	return result.ZScore, nil
+}
@@ -0,0 +1,55 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestIncreaseScoreInt(t *testing.T) {
+	ctx := context.Background()
+	mfs := newTestSet(t)
+
+	if _, err := mfs.IncreaseScoreInt(ctx, map[string]int64{"points": 42, "deaths": 3}, "alice"); err != nil {
+		t.Fatalf("IncreaseScoreInt failed: %v", err)
+	}
+	scores, err := mfs.GetScores(ctx, "alice")
+	if err != nil {
+		t.Fatalf("GetScores failed: %v", err)
+	}
+	if scores[0].Int64() != 42 || scores[1].Int64() != 3 {
+		t.Errorf("GetScores = %v, expected points 42 and deaths 3", scores)
+	}
+
+	if _, err := mfs.IncreaseScoreInt(ctx, map[string]int64{"points": 1 << 60}, "alice"); !errors.Is(err, ErrScoreOutOfRange) {
+		t.Errorf("IncreaseScoreInt with a huge delta = %v, expected ErrScoreOutOfRange", err)
+	}
+}
+
+func TestIncreaseScore_RejectsInexactValues(t *testing.T) {
+	ctx := context.Background()
+	mfs := newTestSet(t)
+
+	tests := []struct {
+		name     string
+		value    float64
+		expected error
+	}{
+		{"fractional", 1.5, ErrInvalidValue},
+		{"NaN", math.NaN(), ErrInvalidValue},
+		{"infinite", math.Inf(1), ErrInvalidValue},
+		{"beyond 2^53", 1 << 60, ErrScoreOutOfRange},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": test.value}, "alice"); !errors.Is(err, test.expected) {
+				t.Errorf("IncreaseScore(%v) = %v, expected %v", test.value, err, test.expected)
+			}
+		})
+	}
+
+	if exists, err := mfs.MemberExists(ctx, "alice"); err != nil || exists {
+		t.Errorf("MemberExists = %v, %v, expected rejected updates not to add the member", exists, err)
+	}
+}
@@ -0,0 +1,48 @@
+package zmultifield
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// scoreJSON encodes a score as a JSON number without losing precision.
+func scoreJSON(fs FieldScore) json.Number {
+	if fs.Score == nil {
+		return json.Number(strconv.FormatFloat(fs.Value, 'g', -1, 64))
+	}
+	return json.Number(fs.Score.String())
+}
+
+// MarshalJSON encodes the field score as {"name": "points", "score": 10}.
+func (fs FieldScore) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Name  string      `json:"name"`
+		Score json.Number `json:"score"`
+	}{fs.Name, scoreJSON(fs)})
+}
+
+// scoresJSON encodes scores as an object keyed by field name.
+func scoresJSON(scores []FieldScore) map[string]json.Number {
+	encoded := make(map[string]json.Number, len(scores))
+	for _, fs := range scores {
+		encoded[fs.Name] = scoreJSON(fs)
+	}
+	return encoded
+}
+
+// MarshalJSON encodes the member as {"member": "alice", "scores": {"points": 10, "deaths": 2}}.
+func (ms MemberScores) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Member string                 `json:"member"`
+		Scores map[string]json.Number `json:"scores"`
+	}{ms.Member, scoresJSON(ms.Scores)})
+}
+
+// MarshalJSON encodes the member like MemberScores with an additional "meta" object.
+func (m MemberWithMeta) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Member string                 `json:"member"`
+		Scores map[string]json.Number `json:"scores"`
+		Meta   map[string]string      `json:"meta"`
+	}{m.Member, scoresJSON(m.Scores), m.Meta})
+}
@@ -0,0 +1,41 @@
+package zmultifield
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+)
+
+func TestFieldScore_Accessors(t *testing.T) {
+	fs := FieldScore{Name: "points", Score: big.NewInt(42)}
+	if fs.Int64() != 42 || fs.Float64() != 42 {
+		t.Errorf("Int64(), Float64() = %d, %v, expected 42", fs.Int64(), fs.Float64())
+	}
+	if empty := (FieldScore{}); empty.Int64() != 0 || empty.Float64() != 0 {
+		t.Errorf("nil score accessors = %d, %v, expected 0", empty.Int64(), empty.Float64())
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	large, _ := new(big.Int).SetString("9007199254740993", 10)
+	ms := MemberScores{
+		Member: "alice",
+		Scores: []FieldScore{{Name: "points", Score: large}, {Name: "deaths", Score: big.NewInt(2)}},
+	}
+
+	data, err := json.Marshal(ms)
+	if err != nil {
+		t.Fatalf("Marshal(MemberScores) error = %v", err)
+	}
+	if expected := `{"member":"alice","scores":{"deaths":2,"points":9007199254740993}}`; string(data) != expected {
+		t.Errorf("Marshal(MemberScores) = %s, expected %s", data, expected)
+	}
+
+	data, err = json.Marshal(ms.Scores[1])
+	if err != nil {
+		t.Fatalf("Marshal(FieldScore) error = %v", err)
+	}
+	if expected := `{"name":"deaths","score":2}`; string(data) != expected {
+		t.Errorf("Marshal(FieldScore) = %s, expected %s", data, expected)
+	}
+}
@@ -0,0 +1,180 @@
+package zmultifield
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// clusterSlots is the number of hash slots in a Redis Cluster.
+const clusterSlots = 16384
+
+// DefaultKeyFunc joins a non-empty namespace and the set name with a colon.
+func DefaultKeyFunc(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return namespace + ":" + name
+}
+
+// KeyBuilder derives the Redis keys used by a MultiFieldSet from its base key, which is the set
+// name combined with its namespace.
+type KeyBuilder interface {
+	// Key returns the key of the main sorted set.
+	Key(base string) string
+	// DerivedKey returns the key of an auxiliary structure kept alongside the set, such as a field index.
+	DerivedKey(base string, suffix string) string
+}
+
+// DefaultKeyBuilder uses the base key as is and appends ":suffix" for derived keys.
+// Derived keys generally land in different cluster slots than the main key.
+type DefaultKeyBuilder struct{}
+
+// Key implements KeyBuilder.
+func (DefaultKeyBuilder) Key(base string) string {
+	return base
+}
+
+// DerivedKey implements KeyBuilder.
+func (DefaultKeyBuilder) DerivedKey(base string, suffix string) string {
+	return base + ":" + suffix
+}
+
+// HashTagKeyBuilder wraps the base key in a hash tag ({base} and {base}:suffix) so that the main
+// key and all derived keys hash to the same Redis Cluster slot.
+type HashTagKeyBuilder struct{}
+
+// Key implements KeyBuilder.
+func (HashTagKeyBuilder) Key(base string) string {
+	return "{" + base + "}"
+}
+
+// DerivedKey implements KeyBuilder.
+func (HashTagKeyBuilder) DerivedKey(base string, suffix string) string {
+	return "{" + base + "}:" + suffix
+}
+
+// derivedKey returns the key of an auxiliary structure of the set.
+func (mfs *MultiFieldSet) derivedKey(suffix string) string {
+	return mfs.keyBuilder.DerivedKey(mfs.baseKey, suffix)
+}
+
+// derivedKeys returns every auxiliary key the set may write to with its current options.
+func (mfs *MultiFieldSet) derivedKeys() []string {
+	var keys []string
+	keys = append(keys, mfs.decayKeys()...)
+	if mfs.maintainFieldIndexes {
+		for _, field := range mfs.fields {
+			keys = append(keys, mfs.fieldIndexKey(field))
+		}
+	}
+	if mfs.writeQueue != nil {
+		keys = append(keys, mfs.replayKey())
+	}
+	if mfs.hideMembers {
+		keys = append(keys, mfs.hiddenKey())
+	}
+	if mfs.trackActivity {
+		keys = append(keys, mfs.activityKey())
+	}
+	if mfs.freezable {
+		keys = append(keys, mfs.frozenKey())
+	}
+	if mfs.quarantinable {
+		keys = append(keys, mfs.quarantineKey())
+	}
+	if len(mfs.dimensions) > 0 {
+		keys = append(keys, mfs.dimensionKeysKey())
+	}
+	if len(mfs.tiers) > 0 {
+		keys = append(keys, mfs.tiersKey())
+	}
+	return keys
+}
+
+// sampleKeys returns a sample of every per-member, per-label and staging key the set may use
+// alongside the main key with its current options, followed by the audit stream. derivedKeys
+// leaves them out as they can't be listed or, for the audit stream, as it outlives the contents
+// of the set.
+func (mfs *MultiFieldSet) sampleKeys() []string {
+	const sample = "sample"
+	keys := []string{
+		mfs.metaKey(sample),
+		mfs.idempotencyRecordKey(sample),
+		mfs.snapshotKey(sample),
+		mfs.snapshotInfoKey(sample),
+		mfs.snapshotsKey(),
+		mfs.extractionKey(sample),
+		mfs.extractionInfoKey(sample),
+		mfs.extractionsKey(),
+		mfs.lockKey(),
+		mfs.layoutKey(),
+		mfs.restoreKey(),
+		mfs.derivedKey("rebuild:" + mfs.key),
+	}
+	if mfs.history != nil {
+		keys = append(keys, mfs.historyKey(sample))
+	}
+	if mfs.guard != nil {
+		keys = append(keys, mfs.updateCountKey(sample))
+	}
+	if mfs.quarantinable {
+		keys = append(keys, mfs.quarantineRecordKey(sample))
+	}
+	if len(mfs.dimensions) > 0 {
+		keys = append(keys, mfs.memberDimensionsKey(sample))
+		for _, d := range mfs.dimensions {
+			keys = append(keys, mfs.dimensionKey(d.Name, sample))
+		}
+	}
+	if mfs.audit {
+		keys = append(keys, mfs.auditKey())
+	}
+	return keys
+}
+
+// validateKeySlots checks that every derived key, and a sample of the keys derived per member or
+// label, hashes to the same cluster slot as the main key, so multi-key scripts and transactions
+// don't fail with CROSSSLOT errors.
+func (mfs *MultiFieldSet) validateKeySlots() error {
+	slot := keySlot(mfs.key)
+	for _, key := range append(mfs.derivedKeys(), mfs.sampleKeys()...) {
+		if keySlot(key) != slot {
+			return fmt.Errorf("%w: %q and %q, use HashTagKeyBuilder", ErrCrossSlot, mfs.key, key)
+		}
+	}
+	return nil
+}
+
+// isClusterClient reports whether client talks to a Redis Cluster.
+func isClusterClient(client redis.UniversalClient) bool {
+	_, ok := client.(*redis.ClusterClient)
+	return ok
+}
+
+// keySlot returns the Redis Cluster hash slot of a key, honouring hash tags.
+func keySlot(key string) int {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+	return int(crc16(key) % clusterSlots)
+}
+
+// crc16 implements the CRC16-CCITT (XMODEM) checksum used by Redis Cluster.
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
@@ -0,0 +1,117 @@
+package zmultifield
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestKeySlot(t *testing.T) {
+	tests := []struct {
+		key      string
+		expected int
+	}{
+		{"123456789", 12739},
+		{"foo", 12182},
+		{"{foo}:field:points", 12182},
+		{"{user1000}.following", keySlot("user1000")},
+	}
+
+	for _, test := range tests {
+		if got := keySlot(test.key); got != test.expected {
+			t.Errorf("keySlot(%q) = %d, expected %d", test.key, got, test.expected)
+		}
+	}
+}
+
+func TestKeyBuilder(t *testing.T) {
+	fields := []Field{
+		{Name: "points", Sort: Descending, MaxValue: 1000, UpdateType: Incremental},
+		{Name: "deaths", Sort: Ascending, MaxValue: 100, UpdateType: Incremental},
+	}
+
+	mfs, err := New(MultiFieldSetOptions{
+		Name:                 "board",
+		Fields:               fields,
+		Client:               newMockRedisClient(),
+		MaintainFieldIndexes: true,
+		KeyBuilder:           HashTagKeyBuilder{},
+	})
+	if err != nil {
+		t.Fatalf("New() with HashTagKeyBuilder error = %v", err)
+	}
+	if mfs.GetKey() != "{board}" {
+		t.Errorf("GetKey() = %q, expected {board}", mfs.GetKey())
+	}
+	if key := mfs.fieldIndexKey(mfs.fields[0]); key != "{board}:field:points" {
+		t.Errorf("fieldIndexKey() = %q, expected {board}:field:points", key)
+	}
+
+	_, err = New(MultiFieldSetOptions{
+		Name:                 "board",
+		Fields:               fields,
+		Client:               newMockRedisClient(),
+		MaintainFieldIndexes: true,
+		KeyBuilder:           DefaultKeyBuilder{},
+	})
+	if !errors.Is(err, ErrCrossSlot) {
+		t.Errorf("New() with DefaultKeyBuilder error = %v, expected ErrCrossSlot", err)
+	}
+}
+
+func TestNamespace(t *testing.T) {
+	fields := []Field{{Name: "points", Sort: Descending, MaxValue: 1000, UpdateType: Incremental}}
+
+	tests := []struct {
+		opts     MultiFieldSetOptions
+		expected string
+	}{
+		{MultiFieldSetOptions{}, "board"},
+		{MultiFieldSetOptions{Namespace: "tenant1"}, "tenant1:board"},
+		{MultiFieldSetOptions{Namespace: "tenant1", KeyBuilder: HashTagKeyBuilder{}}, "{tenant1:board}"},
+		{MultiFieldSetOptions{
+			Namespace: "prod",
+			KeyFunc:   func(namespace, name string) string { return name + "@" + namespace },
+		}, "board@prod"},
+	}
+
+	for _, test := range tests {
+		opts := test.opts
+		opts.Name = "board"
+		opts.Fields = fields
+		opts.Client = newMockRedisClient()
+		mfs, err := New(opts)
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		if mfs.GetKey() != test.expected {
+			t.Errorf("GetKey() = %q, expected %q", mfs.GetKey(), test.expected)
+		}
+		if mfs.GetName() != "board" {
+			t.Errorf("GetName() = %q, expected board", mfs.GetName())
+		}
+	}
+}
+
+// flatKeyBuilder hash-tags the set's fixed keys but leaves keys derived per member or label, whose
+// suffix holds a colon, untagged.
+type flatKeyBuilder struct{ HashTagKeyBuilder }
+
+func (b flatKeyBuilder) DerivedKey(base string, suffix string) string {
+	if strings.Contains(suffix, ":") {
+		return base + ":" + suffix
+	}
+	return b.HashTagKeyBuilder.DerivedKey(base, suffix)
+}
+
+func TestKeyBuilder_PerMemberKeys(t *testing.T) {
+	_, err := New(MultiFieldSetOptions{
+		Name:       "board",
+		Fields:     []Field{{Name: "points", Sort: Descending, MaxValue: 1000, UpdateType: Incremental}},
+		Client:     newMockRedisClient(),
+		KeyBuilder: flatKeyBuilder{},
+	})
+	if !errors.Is(err, ErrCrossSlot) {
+		t.Errorf("New() with untagged per-member keys error = %v, expected ErrCrossSlot", err)
+	}
+}
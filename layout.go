@@ -0,0 +1,149 @@
+package zmultifield
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Layout describes how a sorted set packs its fields. The zero value is the layout used by this
+// package; the other settings let a MultiFieldSet attach to sorted sets written by other tooling,
+// e.g. the JavaScript ZMultiField library, so mixed deployments read and write the same scores.
+type Layout struct {
+	// LeastSignificantFirst packs the first field into the lowest bits and the last field into the
+	// highest, reversing the default order. An unbounded field must then be the last field.
+	LeastSignificantFirst bool
+	// DescendingAsIs stores descending fields as their plain value instead of inverting them
+	// against the field's maximum. Larger values of such fields then sort later in Redis, so
+	// rank-based queries no longer honour their sort order; use it only to share sets with writers
+	// that don't invert.
+	DescendingAsIs bool
+}
+
+// orderFields returns fields in packing order: fields with a Priority from the most significant,
+// followed by the others in slice order. With LeastSignificantFirst the order is reversed, the
+// fields without a Priority keeping their slice order at the low end. It fails if a priority is
+// negative or shared by two fields.
+func (l Layout) orderFields(fields []Field) ([]Field, error) {
+	var prioritized, rest []Field
+	byPriority := make(map[int]string)
+	for _, f := range fields {
+		switch {
+		case f.Priority < 0:
+			return nil, fmt.Errorf("field %s has negative priority %d", f.Name, f.Priority)
+		case f.Priority == 0:
+			rest = append(rest, f)
+		case byPriority[f.Priority] != "":
+			return nil, fmt.Errorf("fields %s and %s have the same priority %d", byPriority[f.Priority], f.Name, f.Priority)
+		default:
+			byPriority[f.Priority] = f.Name
+			prioritized = append(prioritized, f)
+		}
+	}
+	if len(prioritized) == 0 {
+		return fields, nil
+	}
+	sort.Slice(prioritized, func(i, j int) bool {
+		return prioritized[i].Priority < prioritized[j].Priority
+	})
+
+	if l.LeastSignificantFirst {
+		for i, j := 0, len(prioritized)-1; i < j; i, j = i+1, j-1 {
+			prioritized[i], prioritized[j] = prioritized[j], prioritized[i]
+		}
+		return append(rest, prioritized...), nil
+	}
+	return append(prioritized, rest...), nil
+}
+
+// assignShifts sets the position and bit shift of every field according to the layout and returns
+// the total number of bits used.
+func (l Layout) assignShifts(fields []*multiField) uint64 {
+	var totalShifts uint64 = 0
+	if l.LeastSignificantFirst {
+		for i, field := range fields {
+			field.setIndex(i, totalShifts)
+			totalShifts += field.bits
+		}
+		fields[len(fields)-1].leading = true
+		return totalShifts
+	}
+
+	for i := len(fields) - 1; i >= 0; i-- {
+		fields[i].setIndex(i, totalShifts)
+		totalShifts += fields[i].bits
+	}
+	fields[0].leading = true
+	return totalShifts
+}
+
+// storeAsIs makes a descending field store its plain value rather than the inverted one.
+func (mf *multiField) storeAsIs() {
+	mf.inverted = false
+	mf.multiplier = big.NewInt(1)
+}
+
+// LayoutSignature describes how the codec packs its fields, from the most significant, e.g.
+// "points 10@7 inverted, deaths 7@0": each field's name, bits and shift, whether it is inverted
+// and its MinValue if any. Codecs with the same signature read and write the same zscores.
+func (c *Codec) LayoutSignature() string {
+	fields := append([]*multiField(nil), c.fields...)
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].shiftValue > fields[j].shiftValue
+	})
+
+	parts := make([]string, len(fields))
+	for i, field := range fields {
+		part := fmt.Sprintf("%s %d@%d", field.Name, field.bits, field.shiftValue)
+		if field.inverted {
+			part += " inverted"
+		}
+		if field.offset != nil {
+			part += fmt.Sprintf(" min=%v", field.offset)
+		}
+		parts[i] = part
+	}
+	return strings.Join(parts, ", ")
+}
+
+// layoutKey returns the key holding the layout recorded by CheckLayout.
+func (mfs *MultiFieldSet) layoutKey() string {
+	return mfs.derivedKey("layout")
+}
+
+// CheckLayout checks that the set packs its fields as recorded in Redis, failing with a
+// LayoutChangedError otherwise, e.g. after the Fields slice was reordered or a MaxValue changed
+// without rebuilding the data. The first call records the set's layout, so calling it at startup
+// pins the layout of the first deployment. Rebuild records the layout it writes.
+func (mfs *MultiFieldSet) CheckLayout(ctx context.Context) error {
+	current := mfs.LayoutSignature()
+	recorded := current
+	err := mfs.primary(ctx, func(client redis.UniversalClient) error {
+		set, err := client.SetNX(ctx, mfs.layoutKey(), current, 0).Result()
+		if err != nil || set {
+			return err
+		}
+		recorded, err = client.Get(ctx, mfs.layoutKey()).Result()
+		return err
+	})
+	if err != nil {
+		return mfs.runOnError(ctx, "CheckLayout", "", err)
+	}
+	if recorded != current {
+		return &LayoutChangedError{Recorded: recorded, Current: current}
+	}
+	return nil
+}
+
+// RecordLayout records the set's layout as the one CheckLayout expects, replacing any recorded
+// before. Call it once the data has been migrated to a new layout by other means than Rebuild.
+func (mfs *MultiFieldSet) RecordLayout(ctx context.Context) error {
+	err := mfs.primary(ctx, func(client redis.UniversalClient) error {
+		return client.Set(ctx, mfs.layoutKey(), mfs.LayoutSignature(), 0).Err()
+	})
+	return mfs.runOnError(ctx, "RecordLayout", "", err)
+}
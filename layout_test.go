@@ -0,0 +1,216 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+)
+
+func TestLayout_LeastSignificantFirst(t *testing.T) {
+	ctx := context.Background()
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{Layout: Layout{LeastSignificantFirst: true}})
+
+	zscore, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 42, "deaths": 7}, "alice")
+	if err != nil {
+		t.Fatalf("IncreaseScore failed: %v", err)
+	}
+	// deaths is now the leading field, stored above the 10 bits of points
+	if expected := int64(7<<10 + (1023 - 42)); zscore.Int64() != expected {
+		t.Errorf("IncreaseScore = %v, expected %d", zscore, expected)
+	}
+
+	scores, err := mfs.GetScores(ctx, "alice")
+	if err != nil {
+		t.Fatalf("GetScores failed: %v", err)
+	}
+	if scores[0].Name != "points" || scores[0].Int64() != 42 || scores[1].Int64() != 7 {
+		t.Errorf("GetScores = %v, expected points 42 and deaths 7 in field order", scores)
+	}
+
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 500, "deaths": 9}, "bob"); err != nil {
+		t.Fatalf("IncreaseScore failed: %v", err)
+	}
+	members, err := mfs.GetMembersByFieldRange(ctx, "deaths", 0, 8, 0, 0)
+	if err != nil {
+		t.Fatalf("GetMembersByFieldRange failed: %v", err)
+	}
+	if len(members) != 1 || members[0].Member != "alice" {
+		t.Errorf("GetMembersByFieldRange(deaths) = %v, expected only alice", members)
+	}
+	members, err = mfs.GetMembersByFieldRange(ctx, "points", 100, 1000, 0, 0)
+	if err != nil {
+		t.Fatalf("GetMembersByFieldRange failed: %v", err)
+	}
+	if len(members) != 1 || members[0].Member != "bob" {
+		t.Errorf("GetMembersByFieldRange(points) = %v, expected only bob", members)
+	}
+}
+
+func TestLayout_DescendingAsIs(t *testing.T) {
+	ctx := context.Background()
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{Layout: Layout{DescendingAsIs: true}})
+
+	zscore, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 42, "deaths": 7}, "alice")
+	if err != nil {
+		t.Fatalf("IncreaseScore failed: %v", err)
+	}
+	if expected := int64(42<<7 + 7); zscore.Int64() != expected {
+		t.Errorf("IncreaseScore = %v, expected %d", zscore, expected)
+	}
+	if points, err := mfs.GetScoreForField(ctx, "points", "alice"); err != nil || points.Int64() != 42 {
+		t.Errorf("GetScoreForField(points) = %v, %v, expected 42", points, err)
+	}
+}
+
+func TestLayout_AttachToExistingSet(t *testing.T) {
+	ctx := context.Background()
+	client, _ := newTestClient(t)
+	layout := Layout{LeastSignificantFirst: true, DescendingAsIs: true}
+	fields := []Field{
+		{Name: "points", Sort: Descending, MaxValue: 1000, UpdateType: Incremental},
+		{Name: "deaths", Sort: Ascending, MaxValue: 100, UpdateType: Incremental},
+	}
+
+	// Written by other tooling: deaths 3 in the high bits, points 250 as-is in the low bits
+	if err := client.ZAdd(ctx, "existing", &redis.Z{Score: 3<<10 + 250, Member: "carol"}).Err(); err != nil {
+		t.Fatalf("ZAdd failed: %v", err)
+	}
+
+	mfs, err := New(MultiFieldSetOptions{
+		Name:    "existing",
+		Fields:  fields,
+		Client:  client,
+		Layout:  layout,
+		KeyFunc: func(namespace, name string) string { return name },
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	zscore, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 10}, "carol")
+	if err != nil {
+		t.Fatalf("IncreaseScore failed: %v", err)
+	}
+
+	codec, err := NewCodecWithLayout(fields, layout)
+	if err != nil {
+		t.Fatalf("NewCodecWithLayout failed: %v", err)
+	}
+	values := codec.Decode(zscore)
+	if values["points"] != 260 || values["deaths"] != 3 {
+		t.Errorf("Decode = %v, expected points 260 and deaths 3", values)
+	}
+}
+
+func TestLayout_Priority(t *testing.T) {
+	fields := []Field{
+		{Name: "deaths", Sort: Ascending, MaxValue: 100, UpdateType: Incremental},
+		{Name: "points", Sort: Descending, MaxValue: 1000, UpdateType: Incremental, Priority: 1},
+		{Name: "wins", Sort: Descending, MaxValue: 10, UpdateType: Incremental, Priority: 2},
+	}
+	reordered := []Field{fields[2], fields[0], fields[1]}
+
+	for _, layout := range []Layout{{}, {LeastSignificantFirst: true}} {
+		codec, err := NewCodecWithLayout(fields, layout)
+		if err != nil {
+			t.Fatalf("NewCodecWithLayout() error = %v", err)
+		}
+		// Prioritized fields come first, the others below them in slice order
+		if got, want := codec.LayoutSignature(), "points 10@11 inverted, wins 4@7 inverted, deaths 7@0"; got != want {
+			t.Errorf("LayoutSignature() with %+v = %q, expected %q", layout, got, want)
+		}
+		other, err := NewCodecWithLayout(reordered, layout)
+		if err != nil {
+			t.Fatalf("NewCodecWithLayout() error = %v", err)
+		}
+		if other.LayoutSignature() != codec.LayoutSignature() {
+			t.Errorf("LayoutSignature() changed when reordering the slice: %q", other.LayoutSignature())
+		}
+	}
+
+	for name, priorities := range map[string][]int{
+		"duplicate": {1, 1, 0},
+		"negative":  {-1, 0, 0},
+	} {
+		invalid := append([]Field(nil), fields...)
+		for i := range invalid {
+			invalid[i].Priority = priorities[i]
+		}
+		if _, err := NewCodec(invalid); err == nil {
+			t.Errorf("NewCodec() with %s priorities succeeded, expected an error", name)
+		}
+	}
+}
+
+func TestCheckLayout(t *testing.T) {
+	ctx := context.Background()
+	client, _ := newTestClient(t)
+	fields := []Field{
+		{Name: "points", Sort: Descending, MaxValue: 1000, UpdateType: Incremental},
+		{Name: "deaths", Sort: Ascending, MaxValue: 100, UpdateType: Incremental},
+	}
+	mfs, err := New(MultiFieldSetOptions{Name: "test", Fields: fields, Client: client})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	// The first check records the layout, later ones compare against it
+	for i := 0; i < 2; i++ {
+		if err := mfs.CheckLayout(ctx); err != nil {
+			t.Fatalf("CheckLayout() error = %v", err)
+		}
+	}
+
+	// A later deployment reorders the fields
+	reordered, err := New(MultiFieldSetOptions{Name: "test", Fields: []Field{fields[1], fields[0]}, Client: client})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	err = reordered.CheckLayout(ctx)
+	var changed *LayoutChangedError
+	if !errors.As(err, &changed) || !errors.Is(err, ErrLayoutChanged) {
+		t.Fatalf("CheckLayout() error = %v, expected a LayoutChangedError", err)
+	}
+	if changed.Recorded != mfs.LayoutSignature() || changed.Current != reordered.LayoutSignature() {
+		t.Errorf("LayoutChangedError = %+v, expected the recorded and current layouts", changed)
+	}
+	if report, err := reordered.Verify(ctx); err != nil || report.OK() {
+		t.Errorf("Verify() = %+v, %v, expected a layout problem", report, err)
+	}
+
+	// Pinning the positions keeps the recorded layout
+	pinned := []Field{fields[1], fields[0]}
+	pinned[0].Priority, pinned[1].Priority = 2, 1
+	stable, err := New(MultiFieldSetOptions{Name: "test", Fields: pinned, Client: client})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := stable.CheckLayout(ctx); err != nil {
+		t.Errorf("CheckLayout() with priorities error = %v", err)
+	}
+
+	// Rebuilding migrates the data, recording the new layout
+	if _, err := reordered.Rebuild(ctx, SliceIterator(nil)); err != nil {
+		t.Fatalf("Rebuild() error = %v", err)
+	}
+	if err := reordered.CheckLayout(ctx); err != nil {
+		t.Errorf("CheckLayout() after Rebuild error = %v", err)
+	}
+	if err := mfs.CheckLayout(ctx); !errors.Is(err, ErrLayoutChanged) {
+		t.Errorf("CheckLayout() with the old layout error = %v, expected ErrLayoutChanged", err)
+	}
+
+	if err := mfs.RecordLayout(ctx); err != nil {
+		t.Fatalf("RecordLayout() error = %v", err)
+	}
+	if err := mfs.CheckLayout(ctx); err != nil {
+		t.Errorf("CheckLayout() after RecordLayout error = %v", err)
+	}
+	if err := mfs.Clear(ctx); err != nil {
+		t.Fatalf("Clear() error = %v", err)
+	}
+	if err := reordered.CheckLayout(ctx); err != nil {
+		t.Errorf("CheckLayout() after Clear error = %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+package zmultifield
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+// exactBits is the number of bits of a zscore that Redis stores exactly in a float64 score.
+const exactBits = 53
+
+// LayoutReport describes how a schema uses the bits of the zscore, as returned by LayoutReport.
+type LayoutReport struct {
+	// Fields lists the bit usage of every field, in field order.
+	Fields []FieldUsage
+	// TotalBits is the number of bits used by all fields together.
+	TotalBits uint64
+	// Headroom is the number of bits left below the 53 bits Redis stores exactly, negative if the
+	// schema needs more. An unbounded field takes up all remaining bits, leaving no headroom.
+	Headroom int
+	// Suggestions lists ways to make the schema fit or use fewer bits.
+	Suggestions []string
+}
+
+// FieldUsage describes the bits used by one field.
+type FieldUsage struct {
+	Name     string
+	Bits     uint64
+	Shift    uint64
+	MaxValue float64
+	// Capacity is the largest value above MinValue the field's bits can hold, which can exceed
+	// MaxValue - MinValue.
+	Capacity uint64
+	// Unbounded is true if the field has no MaxValue and takes up the remaining bits.
+	Unbounded bool
+}
+
+// LayoutReport returns the bit usage of every field, the headroom left below 53 bits and
+// suggestions for improving the schema, e.g. to check a schema before deploying it.
+func (c *Codec) LayoutReport() *LayoutReport {
+	report := &LayoutReport{Fields: make([]FieldUsage, len(c.fields))}
+	for i, field := range c.fields {
+		report.Fields[i] = FieldUsage{
+			Name:      field.Name,
+			Bits:      field.bits,
+			Shift:     field.shiftValue,
+			MaxValue:  field.MaxValue,
+			Capacity:  field.maxAbsolute64,
+			Unbounded: field.isMain,
+		}
+		report.TotalBits += field.bits
+	}
+	report.Headroom = exactBits - int(report.TotalBits)
+
+	for _, field := range c.fields {
+		if field.isMain && !field.leading {
+			report.Suggestions = append(report.Suggestions, fmt.Sprintf(
+				"unbounded field %s takes up all bits above the fields packed below it; make it the most significant field", field.Name))
+		}
+	}
+	if report.Headroom < 0 {
+		widest := c.fields[0]
+		for _, field := range c.fields {
+			if !field.isMain && (widest.isMain || field.bits > widest.bits) {
+				widest = field
+			}
+		}
+		report.Suggestions = append(report.Suggestions, fmt.Sprintf(
+			"the schema needs %d bits, %d more than Redis stores exactly; reduce the MaxValue of the widest field %s (%d bits) or drop a field",
+			report.TotalBits, -report.Headroom, widest.Name, widest.bits))
+	}
+	for _, field := range c.fields {
+		if field.isMain || field.bits < 2 {
+			continue
+		}
+		// A MaxValue just above a power of two spends a whole bit on a few values
+		smaller := float64(uint64(1)<<(field.bits-1) - 1)
+		span := field.MaxValue - field.MinValue
+		if span-smaller <= span/10 {
+			report.Suggestions = append(report.Suggestions, fmt.Sprintf(
+				"lowering the MaxValue of %s from %v to %v saves a bit", field.Name, field.MaxValue, field.MinValue+smaller))
+		}
+	}
+	return report
+}
+
+// AutoSizeFields returns a copy of fields whose MaxValue is sized from observed values, e.g. a
+// sample of an existing data set: each bounded field gets the smallest bit width that holds its
+// largest sampled value plus headroom, a fraction such as 0.5 for 50% growth, and its MaxValue is
+// set to the largest value of that width. Unbounded fields and fields without samples are kept.
+func AutoSizeFields(fields []Field, samples []map[string]int64, headroom float64) ([]Field, error) {
+	if headroom < 0 || math.IsNaN(headroom) || math.IsInf(headroom, 0) {
+		return nil, errors.New("headroom must be a finite non-negative fraction")
+	}
+
+	observed := make(map[string]int64, len(fields))
+	for _, sample := range samples {
+		for name, value := range sample {
+			if value < 0 {
+				return nil, fmt.Errorf("%w: negative sample %d for field %s", ErrInvalidValue, value, name)
+			}
+			if max, ok := observed[name]; !ok || value > max {
+				observed[name] = value
+			}
+		}
+	}
+
+	sized := append([]Field(nil), fields...)
+	for i, field := range sized {
+		max, ok := observed[field.Name]
+		if !ok || math.IsInf(field.MaxValue, 1) {
+			continue
+		}
+		target := math.Max(1, math.Ceil(float64(max)*(1+headroom)))
+		if target >= 1<<exactBits {
+			return nil, fmt.Errorf("%w: sampled values of field %s need more than %d bits", ErrScoreOutOfRange, field.Name, exactBits)
+		}
+		sized[i].MaxValue = float64(uint64(1)<<bits.Len64(uint64(target)) - 1)
+	}
+	return sized, nil
+}
@@ -0,0 +1,102 @@
+package zmultifield
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestLayoutReport(t *testing.T) {
+	codec, err := NewCodec([]Field{
+		{Name: "points", Sort: Descending, MaxValue: 1000},
+		{Name: "level", Sort: Descending, MaxValue: 1025},
+		{Name: "deaths", Sort: Ascending, MaxValue: 100},
+	})
+	if err != nil {
+		t.Fatalf("NewCodec failed: %v", err)
+	}
+
+	report := codec.LayoutReport()
+	if report.TotalBits != 28 || report.Headroom != 25 {
+		t.Errorf("LayoutReport = %d bits with %d headroom, expected 28 and 25", report.TotalBits, report.Headroom)
+	}
+	level := report.Fields[1]
+	if level.Name != "level" || level.Bits != 11 || level.Shift != 7 || level.Capacity != 2047 {
+		t.Errorf("level usage = %+v, expected 11 bits at shift 7 holding up to 2047", level)
+	}
+	if len(report.Suggestions) != 1 || !strings.Contains(report.Suggestions[0], "level from 1025 to 1023") {
+		t.Errorf("Suggestions = %q, expected one about lowering level to 1023", report.Suggestions)
+	}
+}
+
+func TestLayoutReport_Overflow(t *testing.T) {
+	codec, err := NewCodec([]Field{
+		{Name: "a", Sort: Descending, MaxValue: 1 << 40},
+		{Name: "b", Sort: Descending, MaxValue: 1<<20 - 1},
+	})
+	if err != nil {
+		t.Fatalf("NewCodec failed: %v", err)
+	}
+
+	report := codec.LayoutReport()
+	if report.TotalBits != 61 || report.Headroom != -8 {
+		t.Errorf("LayoutReport = %d bits with %d headroom, expected 61 and -8", report.TotalBits, report.Headroom)
+	}
+	found := false
+	for _, s := range report.Suggestions {
+		found = found || strings.Contains(s, "widest field a")
+	}
+	if !found {
+		t.Errorf("Suggestions = %q, expected one about reducing field a", report.Suggestions)
+	}
+}
+
+func TestLayoutReport_Unbounded(t *testing.T) {
+	codec, err := NewCodec([]Field{
+		{Name: "points", Sort: Descending, MaxValue: math.Inf(1)},
+		{Name: "deaths", Sort: Ascending, MaxValue: 127},
+	})
+	if err != nil {
+		t.Fatalf("NewCodec failed: %v", err)
+	}
+
+	report := codec.LayoutReport()
+	if report.TotalBits != 53 || report.Headroom != 0 || !report.Fields[0].Unbounded || len(report.Suggestions) != 0 {
+		t.Errorf("LayoutReport = %+v, expected an unbounded field using all 53 bits without suggestions", report)
+	}
+}
+
+func TestAutoSizeFields(t *testing.T) {
+	fields := []Field{
+		{Name: "points", Sort: Descending, MaxValue: 1000},
+		{Name: "deaths", Sort: Ascending, MaxValue: 100},
+		{Name: "wins", Sort: Descending, MaxValue: math.Inf(1)},
+	}
+	samples := []map[string]int64{
+		{"points": 5000, "wins": 10},
+		{"points": 12000, "wins": 1 << 40},
+	}
+
+	sized, err := AutoSizeFields(fields, samples, 0.5)
+	if err != nil {
+		t.Fatalf("AutoSizeFields failed: %v", err)
+	}
+	// 12000 with 50% headroom is 18000, which needs 15 bits
+	if sized[0].MaxValue != 1<<15-1 {
+		t.Errorf("points MaxValue = %v, expected %v", sized[0].MaxValue, 1<<15-1)
+	}
+	if sized[1].MaxValue != 100 || !math.IsInf(sized[2].MaxValue, 1) {
+		t.Errorf("AutoSizeFields changed fields without samples or bounds: %+v", sized[1:])
+	}
+	if fields[0].MaxValue != 1000 {
+		t.Errorf("AutoSizeFields modified its input")
+	}
+
+	if _, err := AutoSizeFields(fields, []map[string]int64{{"points": -1}}, 0); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("AutoSizeFields with a negative sample error = %v, expected ErrInvalidValue", err)
+	}
+	if _, err := AutoSizeFields(fields, nil, -1); err == nil {
+		t.Error("AutoSizeFields with negative headroom succeeded, expected an error")
+	}
+}
@@ -0,0 +1,357 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// lexUpdateScript applies field updates to a member of a LexSet in one atomic step. Each field is a
+// fixed-width big-endian segment of the member's prefix; nothing is written if any field would
+// leave its range.
+//
+// KEYS[1] is the sorted set and KEYS[2] the hash mapping members to their current prefix. ARGV[1]
+// is the member, followed by five values per field: the segment width in bytes, the update mode
+// ("keep" or a mode returned by rawUpdate), the raw update value, the maximum raw value and the default raw value.
+// The script returns the new prefix.
+var lexUpdateScript = redis.NewScript(bitOrLua + `
+local old = redis.call('HGET', KEYS[2], ARGV[1])
+local segments = {}
+local pos = 1
+for i = 2, #ARGV, 5 do
+	local width = tonumber(ARGV[i])
+	local raw = tonumber(ARGV[i + 4])
+	if old then
+		raw = 0
+		for b = pos, pos + width - 1 do
+			raw = raw * 256 + string.byte(old, b)
+		end
+	end
+	pos = pos + width
+
+	local mode, value = ARGV[i + 1], tonumber(ARGV[i + 2])
+	if mode == 'inc' then
+		raw = raw + value
+	elseif mode == 'set' then
+		raw = value
+	elseif mode == 'max' then
+		raw = math.max(raw, value)
+	elseif mode == 'min' then
+		raw = math.min(raw, value)
+	elseif mode == 'or' then
+		raw = bor(raw, value)
+	end
+	if raw < 0 or raw > tonumber(ARGV[i + 3]) then
+		return redis.error_reply('OUTOFRANGE ' .. ((i + 3) / 5) .. ' ' .. string.format('%.17g', raw))
+	end
+
+	local bytes = {}
+	for b = width, 1, -1 do
+		bytes[b] = string.char(raw % 256)
+		raw = math.floor(raw / 256)
+	end
+	segments[#segments + 1] = table.concat(bytes)
+end
+
+local prefix = table.concat(segments)
+if old then
+	redis.call('ZREM', KEYS[1], old .. ARGV[1])
+end
+redis.call('ZADD', KEYS[1], 0, prefix .. ARGV[1])
+redis.call('HSET', KEYS[2], ARGV[1], prefix)
+return prefix
+`)
+
+// lexRemoveScript removes members of a LexSet together with their prefixes. KEYS are as for
+// lexUpdateScript and ARGV holds the members. The script returns the number of members removed.
+var lexRemoveScript = redis.NewScript(`
+local removed = 0
+for _, member in ipairs(ARGV) do
+	local prefix = redis.call('HGET', KEYS[2], member)
+	if prefix then
+		redis.call('ZREM', KEYS[1], prefix .. member)
+		redis.call('HDEL', KEYS[2], member)
+		removed = removed + 1
+	end
+end
+return removed
+`)
+
+// LexSet is an alternative backend for schemas too wide for a float score. Instead of packing the
+// fields into the score, every entry's member string starts with a fixed-width binary prefix
+// holding the raw field values, all scores are 0, and Redis orders the entries lexicographically.
+// The prefix has one big-endian segment per field, so the width of the schema is unbounded while
+// each field is still limited to 53 bits.
+//
+// A hash next to the sorted set maps each member to its current prefix.
+type LexSet struct {
+	name       string
+	baseKey    string
+	key        string
+	keyBuilder KeyBuilder
+	fields     []*multiField
+	widths     []int
+	prefixLen  int
+	client     redis.UniversalClient
+}
+
+// LexSetOptions defines options for creating a new LexSet.
+type LexSetOptions struct {
+	Name   string
+	Fields []Field
+	Client redis.UniversalClient
+	// Namespace isolates the set's keys, e.g. per tenant or environment.
+	Namespace string
+	// KeyFunc combines Namespace and Name into the base key. Defaults to DefaultKeyFunc.
+	KeyFunc func(namespace, name string) string
+	// KeyBuilder derives the Redis keys used by the set. Defaults to DefaultKeyBuilder.
+	KeyBuilder KeyBuilder
+}
+
+// NewLex creates a new LexSet instance.
+func NewLex(opts LexSetOptions) (*LexSet, error) {
+	if opts.Name == "" {
+		return nil, errors.New("name is required")
+	}
+
+	if len(opts.Fields) == 0 {
+		return nil, errors.New("at least one field is required")
+	}
+
+	if opts.Client == nil {
+		return nil, errors.New("Redis client is required")
+	}
+
+	keyFunc := opts.KeyFunc
+	if keyFunc == nil {
+		keyFunc = DefaultKeyFunc
+	}
+	ls := &LexSet{
+		name:       opts.Name,
+		baseKey:    keyFunc(opts.Namespace, opts.Name),
+		keyBuilder: opts.KeyBuilder,
+		client:     opts.Client,
+	}
+	if ls.keyBuilder == nil {
+		ls.keyBuilder = DefaultKeyBuilder{}
+	}
+	ls.key = ls.keyBuilder.Key(ls.baseKey)
+
+	fields, err := Layout{}.orderFields(opts.Fields)
+	if err != nil {
+		return nil, err
+	}
+	for i, f := range fields {
+		if err := validateMinValue(f); err != nil {
+			return nil, err
+		}
+		if err := validateUpdateType(f); err != nil {
+			return nil, err
+		}
+		if f.UpdateType == Average {
+			return nil, fmt.Errorf("field %s: %w", f.Name, ErrAverageUnsupported)
+		}
+		field := newMultiField(f)
+		field.position = i
+		width := int(field.bits+7) / 8
+		ls.fields = append(ls.fields, field)
+		ls.widths = append(ls.widths, width)
+		ls.prefixLen += width
+	}
+	return ls, nil
+}
+
+// GetName returns the name of the sorted set.
+func (ls *LexSet) GetName() string {
+	return ls.name
+}
+
+// GetKey returns the Redis key of the sorted set.
+func (ls *LexSet) GetKey() string {
+	return ls.key
+}
+
+// prefixKey returns the key of the hash mapping members to their current prefix.
+func (ls *LexSet) prefixKey() string {
+	return ls.keyBuilder.DerivedKey(ls.baseKey, "lex")
+}
+
+// fieldByName returns the named field or nil if there is none.
+func (ls *LexSet) fieldByName(name string) *multiField {
+	for _, field := range ls.fields {
+		if field.Name == name {
+			return field
+		}
+	}
+	return nil
+}
+
+// IncreaseScore updates the given fields of a member according to their UpdateType, like
+// MultiFieldSet.IncreaseScore.
+func (ls *LexSet) IncreaseScore(ctx context.Context, fields map[string]float64, member string) error {
+	for name := range fields {
+		if ls.fieldByName(name) == nil {
+			return fieldNotFoundError(name)
+		}
+	}
+
+	args := []interface{}{member}
+	for i, field := range ls.fields {
+		mode, value := "keep", new(big.Int)
+		if delta, ok := fields[field.Name]; ok {
+			v, err := toInt64(field.Name, delta)
+			if err != nil {
+				return err
+			}
+			if mode, value, err = field.rawUpdate(v); err != nil {
+				return err
+			}
+		}
+		args = append(args, ls.widths[i], mode, value.String(), field.maxAbsolute.String(), field.defaultScore().String())
+	}
+
+	err := lexUpdateScript.Run(ctx, ls.client, []string{ls.key, ls.prefixKey()}, args...).Err()
+	if err != nil {
+		return ls.updateError(err)
+	}
+	return nil
+}
+
+// updateError converts an OUTOFRANGE reply from the update script into a ScoreOutOfRangeError.
+func (ls *LexSet) updateError(err error) error {
+	if !strings.HasPrefix(err.Error(), "OUTOFRANGE ") {
+		return err
+	}
+	var position int
+	var value float64
+	if _, scanErr := fmt.Sscanf(err.Error(), "OUTOFRANGE %d %g", &position, &value); scanErr != nil {
+		return err
+	}
+	raw, _ := big.NewFloat(value).Int(nil)
+	return outOfRangeError(ls.fields[position-1], raw)
+}
+
+// decodePrefix converts a prefix into display field scores.
+func (ls *LexSet) decodePrefix(prefix string) []FieldScore {
+	scores := make([]FieldScore, len(ls.fields))
+	pos := 0
+	for i, field := range ls.fields {
+		value := new(big.Int).SetBytes([]byte(prefix[pos : pos+ls.widths[i]]))
+		pos += ls.widths[i]
+		scores[i] = FieldScore{Name: field.Name, Score: field.toDisplay(value)}
+	}
+	return scores
+}
+
+// encodeSegment returns the big-endian segment of a raw field value.
+func (ls *LexSet) encodeSegment(position int, raw *big.Int) string {
+	return string(raw.FillBytes(make([]byte, ls.widths[position])))
+}
+
+// decodeEntry splits a sorted set entry into the member and its display field scores.
+func (ls *LexSet) decodeEntry(entry string) MemberScores {
+	return MemberScores{Member: entry[ls.prefixLen:], Scores: ls.decodePrefix(entry[:ls.prefixLen])}
+}
+
+// getPrefix returns the current prefix of a member, or ErrMemberNotFound.
+func (ls *LexSet) getPrefix(ctx context.Context, member string) (string, error) {
+	prefix, err := ls.client.HGet(ctx, ls.prefixKey(), member).Result()
+	if err == redis.Nil {
+		return "", ErrMemberNotFound
+	}
+	return prefix, err
+}
+
+// GetScores returns all field scores of a member, or ErrMemberNotFound if the member is not in the set.
+func (ls *LexSet) GetScores(ctx context.Context, member string) ([]FieldScore, error) {
+	prefix, err := ls.getPrefix(ctx, member)
+	if err != nil {
+		return nil, err
+	}
+	return ls.decodePrefix(prefix), nil
+}
+
+// GetRank returns the rank of a member, or ErrMemberNotFound if the member is not in the set.
+func (ls *LexSet) GetRank(ctx context.Context, member string) (int64, error) {
+	prefix, err := ls.getPrefix(ctx, member)
+	if err != nil {
+		return 0, err
+	}
+	rank, err := ls.client.ZRank(ctx, ls.key, prefix+member).Result()
+	if err == redis.Nil {
+		return 0, ErrMemberNotFound
+	}
+	return rank, err
+}
+
+// GetMembers returns members with their scores in rank order, starting at offset.
+func (ls *LexSet) GetMembers(ctx context.Context, limit, offset int64) ([]MemberScores, error) {
+	entries, err := ls.client.ZRange(ctx, ls.key, offset, offset+limit-1).Result()
+	if err != nil {
+		return nil, err
+	}
+	return ls.decodeEntries(entries), nil
+}
+
+// GetTopMembers returns the top n members with their scores.
+func (ls *LexSet) GetTopMembers(ctx context.Context, limit int64) ([]MemberScores, error) {
+	return ls.GetMembers(ctx, limit, 0)
+}
+
+// GetMembersByLeadingFieldRange returns members whose value for the first field lies within
+// [min, max], in rank order, resolved by Redis with ZRANGEBYLEX. A limit of zero or less returns
+// all matching members. Fractional bounds are rounded inwards and infinite ones clamped.
+func (ls *LexSet) GetMembersByLeadingFieldRange(ctx context.Context, min, max float64, limit, offset int64) ([]MemberScores, error) {
+	field := ls.fields[0]
+	rawMin, rawMax, ok := field.rawRangeWithin(min, max)
+	if !ok {
+		return []MemberScores{}, nil
+	}
+
+	// Everything at or above rawMin and below rawMax+1, unless rawMax is the largest value
+	upper := "+"
+	if next := new(big.Int).Add(rawMax, big.NewInt(1)); next.Cmp(field.maxAbsolute) <= 0 {
+		upper = "(" + ls.encodeSegment(0, next)
+	}
+	count := limit
+	if count <= 0 {
+		count = -1
+	}
+	entries, err := ls.client.ZRangeByLex(ctx, ls.key, &redis.ZRangeBy{
+		Min:    "[" + ls.encodeSegment(0, rawMin),
+		Max:    upper,
+		Offset: offset,
+		Count:  count,
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	return ls.decodeEntries(entries), nil
+}
+
+// decodeEntries decodes sorted set entries into members with their scores.
+func (ls *LexSet) decodeEntries(entries []string) []MemberScores {
+	members := make([]MemberScores, len(entries))
+	for i, entry := range entries {
+		members[i] = ls.decodeEntry(entry)
+	}
+	return members
+}
+
+// RemoveMember removes members from the set and returns how many were removed.
+func (ls *LexSet) RemoveMember(ctx context.Context, members ...string) (int64, error) {
+	args := make([]interface{}, len(members))
+	for i, member := range members {
+		args[i] = member
+	}
+	return lexRemoveScript.Run(ctx, ls.client, []string{ls.key, ls.prefixKey()}, args...).Int64()
+}
+
+// Clear deletes every member of the set.
+func (ls *LexSet) Clear(ctx context.Context) error {
+	return ls.client.Del(ctx, ls.key, ls.prefixKey()).Err()
+}
@@ -0,0 +1,109 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// newTestLexSet returns a LexSet whose 80-bit schema doesn't fit in a float score.
+func newTestLexSet(t *testing.T) *LexSet {
+	t.Helper()
+	client, _ := newTestClient(t)
+	ls, err := NewLex(LexSetOptions{
+		Name: "wide",
+		Fields: []Field{
+			{Name: "level", Sort: Descending, MaxValue: 1000000, UpdateType: Replace},
+			{Name: "xp", Sort: Descending, MaxValue: 1000000, UpdateType: Incremental},
+			{Name: "deaths", Sort: Ascending, MaxValue: 1000000, UpdateType: Incremental},
+			{Name: "gold", Sort: Descending, MaxValue: 1000000, UpdateType: Incremental},
+		},
+		Client: client,
+	})
+	if err != nil {
+		t.Fatalf("NewLex failed: %v", err)
+	}
+	return ls
+}
+
+func TestLexSet_OrdersWideSchemas(t *testing.T) {
+	ctx := context.Background()
+	ls := newTestLexSet(t)
+
+	updates := []struct {
+		member string
+		fields map[string]float64
+	}{
+		{"alice", map[string]float64{"level": 10, "xp": 500, "deaths": 3, "gold": 7}},
+		{"bob", map[string]float64{"level": 10, "xp": 500, "deaths": 1}},
+		{"carol", map[string]float64{"level": 12}},
+		{"alice", map[string]float64{"gold": 5}},
+	}
+	for _, u := range updates {
+		if err := ls.IncreaseScore(ctx, u.fields, u.member); err != nil {
+			t.Fatalf("IncreaseScore(%s) failed: %v", u.member, err)
+		}
+	}
+
+	top, err := ls.GetTopMembers(ctx, 10)
+	if err != nil {
+		t.Fatalf("GetTopMembers failed: %v", err)
+	}
+	if len(top) != 3 || top[0].Member != "carol" || top[1].Member != "bob" || top[2].Member != "alice" {
+		t.Fatalf("GetTopMembers = %v, expected carol, bob, alice", top)
+	}
+	if gold := top[2].Scores[3]; gold.Name != "gold" || gold.Int64() != 12 {
+		t.Errorf("alice's gold = %v, expected 12", gold)
+	}
+
+	if rank, err := ls.GetRank(ctx, "alice"); err != nil || rank != 2 {
+		t.Errorf("GetRank(alice) = %d, %v, expected 2", rank, err)
+	}
+	scores, err := ls.GetScores(ctx, "bob")
+	if err != nil {
+		t.Fatalf("GetScores failed: %v", err)
+	}
+	if scores[0].Int64() != 10 || scores[1].Int64() != 500 || scores[2].Int64() != 1 || scores[3].Int64() != 0 {
+		t.Errorf("GetScores(bob) = %v, expected level 10, xp 500, deaths 1, gold 0", scores)
+	}
+
+	members, err := ls.GetMembersByLeadingFieldRange(ctx, 0, 10, 0, 0)
+	if err != nil {
+		t.Fatalf("GetMembersByLeadingFieldRange failed: %v", err)
+	}
+	if len(members) != 2 || members[0].Member != "bob" || members[1].Member != "alice" {
+		t.Errorf("GetMembersByLeadingFieldRange(0, 10) = %v, expected bob and alice", members)
+	}
+
+	if removed, err := ls.RemoveMember(ctx, "bob", "nobody"); err != nil || removed != 1 {
+		t.Errorf("RemoveMember = %d, %v, expected 1", removed, err)
+	}
+	if _, err := ls.GetRank(ctx, "bob"); !errors.Is(err, ErrMemberNotFound) {
+		t.Errorf("GetRank(bob) after removal = %v, expected ErrMemberNotFound", err)
+	}
+}
+
+func TestLexSet_RejectsOutOfRange(t *testing.T) {
+	ctx := context.Background()
+	ls := newTestLexSet(t)
+
+	if err := ls.IncreaseScore(ctx, map[string]float64{"xp": 10}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore failed: %v", err)
+	}
+	err := ls.IncreaseScore(ctx, map[string]float64{"xp": 2000000, "gold": 1}, "alice")
+	var rangeErr *ScoreOutOfRangeError
+	if !errors.As(err, &rangeErr) || rangeErr.Field != "xp" {
+		t.Fatalf("IncreaseScore out of range = %v, expected a ScoreOutOfRangeError for xp", err)
+	}
+	scores, err := ls.GetScores(ctx, "alice")
+	if err != nil {
+		t.Fatalf("GetScores failed: %v", err)
+	}
+	if scores[1].Int64() != 10 || scores[3].Int64() != 0 {
+		t.Errorf("GetScores after rejected update = %v, expected xp 10 and gold 0", scores)
+	}
+
+	if err := ls.IncreaseScore(ctx, map[string]float64{"missing": 1}, "alice"); !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("IncreaseScore with unknown field = %v, expected ErrFieldNotFound", err)
+	}
+}
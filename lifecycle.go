@@ -0,0 +1,324 @@
+package zmultifield
+
+import (
+	"context"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// rebuildBatchSize is the number of members written per pipeline while rebuilding a set.
+const rebuildBatchSize = 1000
+
+// Iterator yields members with their display scores, e.g. to repopulate a set with Rebuild.
+type Iterator interface {
+	// Next advances to the next member and reports whether there is one.
+	Next(ctx context.Context) bool
+	// Value returns the current member.
+	Value() MemberScores
+	// Err returns the error that stopped the iteration, if any.
+	Err() error
+}
+
+// sliceIterator is an Iterator over an in-memory slice.
+type sliceIterator struct {
+	members []MemberScores
+	pos     int
+}
+
+// SliceIterator returns an Iterator over members, e.g. the result of GetMembers.
+func SliceIterator(members []MemberScores) Iterator {
+	return &sliceIterator{members: members, pos: -1}
+}
+
+func (it *sliceIterator) Next(ctx context.Context) bool {
+	if it.pos+1 >= len(it.members) {
+		return false
+	}
+	it.pos++
+	return true
+}
+
+func (it *sliceIterator) Value() MemberScores {
+	return it.members[it.pos]
+}
+
+func (it *sliceIterator) Err() error {
+	return nil
+}
+
+// allKeys returns the main key followed by every companion key of the set.
+func (mfs *MultiFieldSet) allKeys() []string {
+	return append([]string{mfs.key}, mfs.derivedKeys()...)
+}
+
+// Clear deletes every member of the set along with its companion keys, such as field indexes,
+// dimension sets, member metadata, histories and the layout recorded by CheckLayout. The audit
+// stream of an Audit set is kept and records the clear.
+func (mfs *MultiFieldSet) Clear(ctx context.Context) error {
+	err := mfs.primary(ctx, func(client redis.UniversalClient) error {
+		return mfs.checkFrozen(ctx, client)
+	})
+	if err != nil {
+		return mfs.runOnError(ctx, "Clear", "", err)
+	}
+	if len(mfs.dimensions) > 0 {
+		if err := mfs.clearDimensions(ctx); err != nil {
+			return mfs.runOnError(ctx, "Clear", "", err)
+		}
+	}
+	if err := mfs.clearMemberKeys(ctx, mfs.key, mfs.memberKeyFuncs()...); err != nil {
+		return mfs.runOnError(ctx, "Clear", "", err)
+	}
+	if mfs.quarantinable {
+		keyFuncs := append(mfs.memberKeyFuncs(), mfs.quarantineRecordKey)
+		if err := mfs.clearMemberKeys(ctx, mfs.quarantineKey(), keyFuncs...); err != nil {
+			return mfs.runOnError(ctx, "Clear", "", err)
+		}
+	}
+
+	err = mfs.write(ctx, func(client redis.UniversalClient) error {
+		return mfs.txPipelined(ctx, client, func(pipe redis.Pipeliner) error {
+			pipe.Del(ctx, mfs.allKeys()...)
+			mfs.recordAudit(ctx, pipe, "op", "clear")
+			return nil
+		})
+	})
+	if err == nil {
+		// The layout key and derived sets are deleted on their own, as they may not share the slot
+		// of the set's keys
+		keys := []string{mfs.layoutKey()}
+		for _, d := range mfs.derivedSetList() {
+			keys = append(keys, d.key)
+		}
+		err = mfs.write(ctx, func(client redis.UniversalClient) error {
+			for _, key := range keys {
+				if err := client.Del(ctx, key).Err(); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	if err != nil {
+		return mfs.runOnError(ctx, "Clear", "", err)
+	}
+	mfs.emitSeason(ctx, SeasonCleared, "")
+	return nil
+}
+
+// memberKeyFuncs returns the functions deriving the companion keys kept per member with the
+// set's options.
+func (mfs *MultiFieldSet) memberKeyFuncs() []func(member string) string {
+	keyFuncs := []func(string) string{mfs.metaKey}
+	if mfs.history != nil {
+		keyFuncs = append(keyFuncs, mfs.historyKey)
+	}
+	if len(mfs.dimensions) > 0 {
+		keyFuncs = append(keyFuncs, mfs.memberDimensionsKey)
+	}
+	return keyFuncs
+}
+
+// clearMemberKeys deletes the per-member keys returned by keyFuncs for every member currently in
+// the sorted set at key.
+func (mfs *MultiFieldSet) clearMemberKeys(ctx context.Context, key string, keyFuncs ...func(member string) string) error {
+	for start := int64(0); ; start += scanBatchSize {
+		var members []string
+		err := mfs.primary(ctx, func(client redis.UniversalClient) error {
+			var err error
+			members, err = client.ZRange(ctx, key, start, start+scanBatchSize-1).Result()
+			return err
+		})
+		if err != nil || len(members) == 0 {
+			return err
+		}
+
+		keys := make([]string, 0, len(members)*len(keyFuncs))
+		for _, member := range members {
+			for _, keyFunc := range keyFuncs {
+				keys = append(keys, keyFunc(member))
+			}
+		}
+		if err := mfs.write(ctx, func(client redis.UniversalClient) error {
+			return client.Del(ctx, keys...).Err()
+		}); err != nil {
+			return err
+		}
+
+		if len(members) < scanBatchSize {
+			return nil
+		}
+	}
+}
+
+// Rebuild replaces the contents of the set with the members yielded by source and returns the
+// number of members written. Members are staged under temporary keys and swapped in at the end,
+// so readers see either the old or the new contents, never a partial set. Fields missing from a
+// member get their default score. Dimension sets are emptied, and members rejoin them on their
+// next write. Hidden members stay hidden, and quarantined members stay in quarantine with their
+// records, even if source yields them. The set's layout is recorded as the one CheckLayout expects.
+func (mfs *MultiFieldSet) Rebuild(ctx context.Context, source Iterator) (int64, error) {
+	var count int64
+	err := mfs.withLock(ctx, "Rebuild", func(ctx context.Context) error {
+		var err error
+		count, err = mfs.rebuild(ctx, source)
+		return err
+	})
+	if err != nil {
+		return 0, mfs.runOnError(ctx, "Rebuild", "", err)
+	}
+	return count, nil
+}
+
+// rebuild stages the members of source under temporary keys and swaps them in.
+func (mfs *MultiFieldSet) rebuild(ctx context.Context, source Iterator) (int64, error) {
+	// The main set comes first, followed by the field indexes in field order
+	keys := []string{mfs.key}
+	if mfs.maintainFieldIndexes {
+		for _, field := range mfs.fields {
+			keys = append(keys, mfs.fieldIndexKey(field))
+		}
+	}
+	staging := make([]string, len(keys))
+	for i, key := range keys {
+		staging[i] = mfs.derivedKey("rebuild:" + key)
+	}
+
+	// Start from empty staging keys in case a previous rebuild was interrupted
+	if err := mfs.write(ctx, func(client redis.UniversalClient) error {
+		return client.Del(ctx, staging...).Err()
+	}); err != nil {
+		return 0, err
+	}
+
+	count, err := mfs.stageAll(ctx, source, staging)
+	if err != nil {
+		// Best effort, the next rebuild starts by clearing the staging keys anyway
+		mfs.client.Del(ctx, staging...)
+		return 0, err
+	}
+	if len(mfs.dimensions) > 0 {
+		if err := mfs.clearDimensions(ctx); err != nil {
+			return 0, err
+		}
+	}
+
+	err = mfs.write(ctx, func(client redis.UniversalClient) error {
+		return mfs.swapRebuilt(ctx, client, keys, staging, count)
+	})
+	if err != nil {
+		return 0, err
+	}
+	// The set now holds only members written in its layout
+	err = mfs.primary(ctx, func(client redis.UniversalClient) error {
+		return client.Set(ctx, mfs.layoutKey(), mfs.LayoutSignature(), 0).Err()
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// swapRebuilt replaces keys with their staging keys and deletes the other companion keys, except
+// the moderation keys. Members quarantined when the swap happens are removed from the rebuilt
+// keys, so they stay out of the set.
+func (mfs *MultiFieldSet) swapRebuilt(ctx context.Context, client redis.UniversalClient, keys, staging []string, count int64) error {
+	moderation := mfs.moderationKeys()
+	var deleted []string
+	for _, key := range mfs.allKeys() {
+		if !moderation[key] {
+			deleted = append(deleted, key)
+		}
+	}
+	swap := func(pipe redis.Pipeliner, quarantined []string) {
+		pipe.Del(ctx, deleted...)
+		if count == 0 {
+			return
+		}
+		names := make([]interface{}, len(quarantined))
+		for i, member := range quarantined {
+			names[i] = member
+		}
+		for i, key := range keys {
+			pipe.Rename(ctx, staging[i], key)
+			if len(names) > 0 {
+				pipe.ZRem(ctx, key, names...)
+			}
+		}
+	}
+	if !mfs.quarantinable {
+		return mfs.txPipelined(ctx, client, func(pipe redis.Pipeliner) error {
+			swap(pipe, nil)
+			return nil
+		})
+	}
+
+	watched := []string{mfs.quarantineKey()}
+	if mfs.freezable {
+		watched = append(watched, mfs.frozenKey())
+	}
+	for attempt := 0; attempt <= mfs.optimisticRetries; attempt++ {
+		err := client.Watch(ctx, func(tx *redis.Tx) error {
+			if err := mfs.checkFrozen(ctx, tx); err != nil {
+				return err
+			}
+			quarantined, err := tx.ZRange(ctx, mfs.quarantineKey(), 0, -1).Result()
+			if err != nil {
+				return err
+			}
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				swap(pipe, quarantined)
+				return nil
+			})
+			return err
+		}, watched...)
+		if err != redis.TxFailedErr {
+			return err
+		}
+	}
+	return ErrUpdateConflict
+}
+
+// moderationKeys returns the keys recording which members are hidden or quarantined. They are
+// decisions about members rather than data derived from their scores, so Rebuild keeps them.
+func (mfs *MultiFieldSet) moderationKeys() map[string]bool {
+	keys := make(map[string]bool)
+	if mfs.hideMembers {
+		keys[mfs.hiddenKey()] = true
+	}
+	if mfs.quarantinable {
+		keys[mfs.quarantineKey()] = true
+	}
+	return keys
+}
+
+// stageAll writes every member of source to the staging keys in batches.
+func (mfs *MultiFieldSet) stageAll(ctx context.Context, source Iterator, staging []string) (int64, error) {
+	var count int64
+	batch := make([]MemberScores, 0, rebuildBatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := mfs.writeBatch(ctx, staging, batch)
+		batch = batch[:0]
+		return err
+	}
+
+	for source.Next(ctx) {
+		batch = append(batch, source.Value())
+		count++
+		if len(batch) == rebuildBatchSize {
+			if err := flush(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if err := source.Err(); err != nil {
+		return 0, err
+	}
+	if err := flush(); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
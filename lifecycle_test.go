@@ -0,0 +1,112 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestClear(t *testing.T) {
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{MaintainFieldIndexes: true})
+	ctx := context.Background()
+
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 10}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	if err := mfs.Clear(ctx); err != nil {
+		t.Fatalf("Clear() error = %v", err)
+	}
+
+	for _, key := range mfs.allKeys() {
+		if n, _ := mfs.client.Exists(ctx, key).Result(); n != 0 {
+			t.Errorf("key %s still exists after Clear()", key)
+		}
+	}
+}
+
+func TestRebuild(t *testing.T) {
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{MaintainFieldIndexes: true})
+	ctx := context.Background()
+
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 10}, "stale"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+
+	source := SliceIterator([]MemberScores{
+		{Member: "alice", Scores: []FieldScore{{Name: "points", Score: big.NewInt(100)}, {Name: "deaths", Score: big.NewInt(3)}}},
+		{Member: "bob", Scores: []FieldScore{{Name: "points", Score: big.NewInt(200)}}},
+	})
+	count, err := mfs.Rebuild(ctx, source)
+	if err != nil {
+		t.Fatalf("Rebuild() error = %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Rebuild() = %d, expected 2", count)
+	}
+
+	members, err := mfs.GetTopMembers(ctx, 10)
+	if err != nil {
+		t.Fatalf("GetTopMembers() error = %v", err)
+	}
+	if len(members) != 2 || members[0].Member != "bob" || members[1].Member != "alice" {
+		t.Fatalf("GetTopMembers() = %+v, expected bob, alice", members)
+	}
+	if members[1].Scores[1].Score.Int64() != 3 {
+		t.Errorf("alice deaths = %v, expected 3", members[1].Scores[1].Score)
+	}
+	if rank, err := mfs.GetFieldRank(ctx, "deaths", "bob"); err != nil || rank != 0 {
+		t.Errorf("GetFieldRank(deaths, bob) = %d, %v, expected 0", rank, err)
+	}
+
+	// A source with an invalid member leaves the set untouched
+	_, err = mfs.Rebuild(ctx, SliceIterator([]MemberScores{
+		{Member: "carol", Scores: []FieldScore{{Name: "points", Score: big.NewInt(5000)}}},
+	}))
+	if !errors.Is(err, ErrScoreOutOfRange) {
+		t.Errorf("Rebuild() error = %v, expected ErrScoreOutOfRange", err)
+	}
+	if count, _ := mfs.GetCardinality(ctx); count != 2 {
+		t.Errorf("GetCardinality() after failed rebuild = %d, expected 2", count)
+	}
+}
+
+func TestRebuild_KeepsQuarantine(t *testing.T) {
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{Quarantinable: true, MaintainFieldIndexes: true})
+	ctx := context.Background()
+	for member, points := range map[string]float64{"alice": 10, "cheater": 999} {
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": points}, member); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+	}
+	if err := mfs.Quarantine(ctx, "cheater", "impossible score"); err != nil {
+		t.Fatalf("Quarantine() error = %v", err)
+	}
+
+	// The source still has the quarantined member, e.g. rebuilt from an event log
+	_, err := mfs.Rebuild(ctx, SliceIterator([]MemberScores{
+		{Member: "alice", Scores: []FieldScore{{Name: "points", Score: big.NewInt(20)}}},
+		{Member: "cheater", Scores: []FieldScore{{Name: "points", Score: big.NewInt(999)}}},
+	}))
+	if err != nil {
+		t.Fatalf("Rebuild() error = %v", err)
+	}
+
+	records, err := mfs.GetQuarantined(ctx)
+	if err != nil {
+		t.Fatalf("GetQuarantined() error = %v", err)
+	}
+	if len(records) != 1 || records[0].Member != "cheater" || records[0].Reason != "impossible score" {
+		t.Errorf("GetQuarantined() = %+v, expected the cheater's record to survive Rebuild", records)
+	}
+	members, err := mfs.GetTopMembers(ctx, 10)
+	if err != nil {
+		t.Fatalf("GetTopMembers() error = %v", err)
+	}
+	if len(members) != 1 || members[0].Member != "alice" {
+		t.Errorf("GetTopMembers() = %+v, expected the quarantined member to stay out", members)
+	}
+	if _, err := mfs.GetFieldRank(ctx, "points", "cheater"); !errors.Is(err, ErrMemberNotFound) {
+		t.Errorf("GetFieldRank(cheater) error = %v, expected ErrMemberNotFound", err)
+	}
+}
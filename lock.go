@@ -0,0 +1,157 @@
+package zmultifield
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// defaultLockTTL is the lock expiry used when LockTTL is not set.
+const defaultLockTTL = 30 * time.Second
+
+// releaseLockScript deletes the lock in KEYS[1] if it still holds the token ARGV[1], so a holder
+// whose lock expired can't release a lock taken since by someone else.
+var releaseLockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// extendLockScript sets the expiry of the lock in KEYS[1] to ARGV[2] milliseconds if it still
+// holds the token ARGV[1]. It returns 0 if the lock was lost.
+var extendLockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// heldLock is the context key marking the lock of a set as held by the caller, so operations
+// nested in a locked one, such as a Rebuild run by Registry.Migrate, don't wait for themselves.
+type heldLock struct {
+	key string
+}
+
+// lockKey returns the key of the lock taken by administrative operations.
+func (mfs *MultiFieldSet) lockKey() string {
+	return mfs.derivedKey("lock")
+}
+
+// withLock runs fn while holding the set's administrative lock, failing with a LockedError if
+// another operation holds it. The lock is a key set with SET NX PX to the operation's name and a
+// random token, extended while fn runs and released when it returns. If the lock is lost anyway,
+// e.g. because Redis was unreachable for longer than LockTTL, the context passed to fn is
+// cancelled and withLock returns ErrLockLost.
+func (mfs *MultiFieldSet) withLock(ctx context.Context, op string, fn func(ctx context.Context) error) error {
+	key := mfs.lockKey()
+	if ctx.Value(heldLock{key}) != nil {
+		return fn(ctx)
+	}
+
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return err
+	}
+	value := op + " " + hex.EncodeToString(token)
+
+	var acquired bool
+	err := mfs.primary(ctx, func(client redis.UniversalClient) error {
+		var err error
+		acquired, err = client.SetNX(ctx, key, value, mfs.lockTTL).Result()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if !acquired {
+		return mfs.lockedError(ctx)
+	}
+
+	lockCtx, cancel := context.WithCancelCause(context.WithValue(ctx, heldLock{key}, value))
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		mfs.extendLock(lockCtx, value, cancel, stop)
+	}()
+
+	err = fn(lockCtx)
+	close(stop)
+	<-stopped
+	if context.Cause(lockCtx) == ErrLockLost {
+		err = ErrLockLost
+	}
+	cancel(nil)
+
+	// A lock that can't be released expires after LockTTL anyway
+	releaseCtx := context.WithoutCancel(ctx)
+	releaseErr := mfs.primary(releaseCtx, func(client redis.UniversalClient) error {
+		return releaseLockScript.Run(releaseCtx, client, []string{key}, value).Err()
+	})
+	if releaseErr != nil {
+		mfs.runOnError(releaseCtx, "Unlock", "", releaseErr)
+	}
+	return err
+}
+
+// extendLock extends the lock holding value every third of LockTTL until stop is closed. If the
+// lock is taken over, or can't be extended before it expires, it cancels ctx with ErrLockLost.
+func (mfs *MultiFieldSet) extendLock(ctx context.Context, value string, cancel context.CancelCauseFunc, stop <-chan struct{}) {
+	ticker := time.NewTicker(mfs.lockTTL / 3)
+	defer ticker.Stop()
+
+	extended := time.Now()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			held, err := extendLockScript.Run(ctx, mfs.client, []string{mfs.lockKey()}, value, mfs.lockTTL.Milliseconds()).Int64()
+			switch {
+			case err == nil && held == 0:
+				cancel(ErrLockLost)
+				return
+			case err == nil:
+				extended = now
+			case now.Sub(extended) >= mfs.lockTTL:
+				cancel(ErrLockLost)
+				return
+			}
+		}
+	}
+}
+
+// lockedError describes the operation holding the set's lock.
+func (mfs *MultiFieldSet) lockedError(ctx context.Context) error {
+	var value *redis.StringCmd
+	var ttl *redis.DurationCmd
+	err := mfs.primary(ctx, func(client redis.UniversalClient) error {
+		_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			value = pipe.Get(ctx, mfs.lockKey())
+			ttl = pipe.PTTL(ctx, mfs.lockKey())
+			return nil
+		})
+		if err == redis.Nil {
+			err = nil
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	// The lock may have been released in the meantime, leaving the holder unknown
+	holder, _, _ := strings.Cut(value.Val(), " ")
+	locked := &LockedError{Operation: holder}
+	if ttl.Val() > 0 {
+		locked.ExpiresIn = ttl.Val()
+	}
+	return locked
+}
@@ -0,0 +1,107 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLockRefusesConcurrentOperations(t *testing.T) {
+	mfs := newTestSet(t)
+	ctx := context.Background()
+
+	// Another instance is rebuilding the set
+	if err := mfs.client.Set(ctx, mfs.lockKey(), "Rebuild 0123", time.Minute).Err(); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	_, err := mfs.Snapshot(ctx, "week-1")
+	var locked *LockedError
+	if !errors.As(err, &locked) || !errors.Is(err, ErrLocked) {
+		t.Fatalf("Snapshot() error = %v, expected a LockedError", err)
+	}
+	if locked.Operation != "Rebuild" || locked.ExpiresIn <= 0 || locked.ExpiresIn > time.Minute {
+		t.Errorf("LockedError = %+v, expected Rebuild expiring within a minute", locked)
+	}
+	if _, err := mfs.Rebuild(ctx, SliceIterator(nil)); !errors.Is(err, ErrLocked) {
+		t.Errorf("Rebuild() error = %v, expected ErrLocked", err)
+	}
+
+	// Once the lock is gone operations take and release it
+	if err := mfs.client.Del(ctx, mfs.lockKey()).Err(); err != nil {
+		t.Fatalf("Del() error = %v", err)
+	}
+	if _, err := mfs.Snapshot(ctx, "week-1"); err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+	if err := mfs.DeleteSnapshot(ctx, "week-1"); err != nil {
+		t.Fatalf("DeleteSnapshot() error = %v", err)
+	}
+	if n, err := mfs.client.Exists(ctx, mfs.lockKey()).Result(); err != nil || n != 0 {
+		t.Errorf("Exists(lock) = %d, %v, expected the lock to be released", n, err)
+	}
+}
+
+func TestMigrateHoldsLock(t *testing.T) {
+	ctx := context.Background()
+	client, _ := newTestClient(t)
+	registry := NewRegistry(client)
+
+	opts := MultiFieldSetOptions{Name: "a", Fields: []Field{{Name: "points", Sort: Descending, MaxValue: 1000, UpdateType: Incremental}}}
+	if _, err := registry.Create(ctx, opts); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	// The same set as seen by another instance
+	opts.Client = client
+	other, err := New(opts)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	err = registry.Migrate(ctx, func(ctx context.Context, set *MultiFieldSet) error {
+		// Operations called by the migration run under its lock
+		if _, err := set.Rebuild(ctx, SliceIterator(nil)); err != nil {
+			return err
+		}
+		if _, err := set.Snapshot(ctx, "before"); err != nil {
+			return err
+		}
+		if _, err := other.Rebuild(context.Background(), SliceIterator(nil)); !errors.Is(err, ErrLocked) {
+			t.Errorf("concurrent Rebuild() error = %v, expected ErrLocked", err)
+		}
+		return registry.Migrate(context.Background(), func(context.Context, *MultiFieldSet) error {
+			t.Error("concurrent Migrate ran while the set was locked")
+			return nil
+		})
+	})
+	if !errors.Is(err, ErrLocked) {
+		t.Errorf("Migrate() error = %v, expected the concurrent Migrate to fail with ErrLocked", err)
+	}
+	if _, err := other.Rebuild(ctx, SliceIterator(nil)); err != nil {
+		t.Errorf("Rebuild() after Migrate error = %v", err)
+	}
+}
+
+func TestLockLost(t *testing.T) {
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{LockTTL: 30 * time.Millisecond})
+	ctx := context.Background()
+
+	err := mfs.withLock(ctx, "Rebuild", func(ctx context.Context) error {
+		// The lock expires and another instance takes it
+		if err := mfs.client.Set(ctx, mfs.lockKey(), "Snapshot 4567", 0).Err(); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+			return nil
+		}
+	})
+	if !errors.Is(err, ErrLockLost) {
+		t.Errorf("withLock() error = %v, expected ErrLockLost", err)
+	}
+	if value, err := mfs.client.Get(ctx, mfs.lockKey()).Result(); err != nil || value != "Snapshot 4567" {
+		t.Errorf("lock = %q, %v, expected the other holder's lock to be kept", value, err)
+	}
+}
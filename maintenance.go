@@ -0,0 +1,394 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Clock tells the time and waits for it to pass. The Maintenance runner uses the system clock by
+// default; tests can plug in a fake one to run tasks without waiting.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// systemClock is the Clock backed by the time package.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// Schedule decides when a maintenance task runs.
+type Schedule interface {
+	// Next returns the first time strictly after t the task should run.
+	Next(t time.Time) time.Time
+}
+
+// interval is the Schedule returned by Every.
+type interval time.Duration
+
+func (s interval) Next(t time.Time) time.Time {
+	return t.Truncate(time.Duration(s)).Add(time.Duration(s))
+}
+
+// Every returns a Schedule running a task every d. Runs are aligned to multiples of d since the
+// zero time, e.g. on the hour for time.Hour, so every instance of an application agrees on them.
+func Every(d time.Duration) Schedule {
+	if d <= 0 {
+		panic("zmultifield: Every needs a positive interval")
+	}
+	return interval(d)
+}
+
+// cronSchedule is the Schedule returned by Cron, holding a bit per allowed value of each field.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// anyDay records whether the day of the month or the day of the week is a wildcard, in which
+	// case a day must match both fields; otherwise it must match either, as in cron.
+	anyDay bool
+}
+
+// cronFields lists the bounds of the five fields of a cron expression.
+var cronFields = []struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// Cron parses a cron expression with the five fields minute, hour, day of month, month and day of
+// week, e.g. "0 4 * * 1" for Mondays at 04:00. Each field is a wildcard or a comma separated list
+// of values and ranges, optionally with a step: "*/15", "1-5" or "0,30". Sunday is 0 or 7. Times
+// are evaluated in the location of the time passed to Next.
+func Cron(spec string) (Schedule, error) {
+	parts := strings.Fields(spec)
+	if len(parts) != len(cronFields) {
+		return nil, fmt.Errorf("cron expression %q has %d fields, expected 5", spec, len(parts))
+	}
+	var bits [5]uint64
+	for i, part := range parts {
+		var err error
+		bits[i], err = parseCronField(part, cronFields[i].min, cronFields[i].max)
+		if err != nil {
+			return nil, fmt.Errorf("cron expression %q: %s: %w", spec, cronFields[i].name, err)
+		}
+	}
+	// Sunday can be written as 7
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+	return &cronSchedule{
+		minute: bits[0], hour: bits[1], dom: bits[2], month: bits[3], dow: bits[4],
+		anyDay: strings.HasPrefix(parts[2], "*") || strings.HasPrefix(parts[4], "*"),
+	}, nil
+}
+
+// parseCronField returns the bits of the values allowed by a cron field within [min, max].
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(field, ",") {
+		rng, step := item, 1
+		if i := strings.IndexByte(item, '/'); i >= 0 {
+			var err error
+			rng = item[:i]
+			step, err = strconv.Atoi(item[i+1:])
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", item)
+			}
+		}
+
+		lo, hi := min, max
+		if rng != "*" {
+			var err error
+			from, to, isRange := strings.Cut(rng, "-")
+			if lo, err = strconv.Atoi(from); err != nil {
+				return 0, fmt.Errorf("invalid value in %q", item)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(to); err != nil {
+					return 0, fmt.Errorf("invalid value in %q", item)
+				}
+			} else if step > 1 {
+				// "5/15" means from 5 to the maximum every 15
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q is out of the range %d-%d", item, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	// Every expression that can match does so within a leap year cycle
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<int(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.matchDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case s.hour&(1<<t.Hour()) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case s.minute&(1<<t.Minute()) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	// Never matches, e.g. on February 30
+	return time.Time{}
+}
+
+// matchDay reports whether the day of t matches the day of month and day of week fields.
+func (s *cronSchedule) matchDay(t time.Time) bool {
+	dom := s.dom&(1<<t.Day()) != 0
+	dow := s.dow&(1<<int(t.Weekday())) != 0
+	if s.anyDay {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// MaintenanceTask is a job run periodically by a Maintenance runner.
+type MaintenanceTask struct {
+	// Name identifies the task in errors and in the key recording its last run, so it must be
+	// unique within a runner and the same on every instance of an application.
+	Name     string
+	Schedule Schedule
+	// Run runs the task for the run scheduled at the given time. It is called while holding the
+	// set's administrative lock.
+	Run func(ctx context.Context, mfs *MultiFieldSet, scheduled time.Time) error
+}
+
+// PruneTask returns a task removing the members whose value for the given field is below value
+// with RemoveByFieldBelow.
+func PruneTask(name string, schedule Schedule, fieldName string, value float64) MaintenanceTask {
+	return MaintenanceTask{Name: name, Schedule: schedule, Run: func(ctx context.Context, mfs *MultiFieldSet, _ time.Time) error {
+		_, err := mfs.RemoveByFieldBelow(ctx, fieldName, value)
+		return err
+	}}
+}
+
+// PruneIdleTask returns a task removing the members that haven't been updated for idle, as
+// reported by GetIdleMembers. It requires TrackActivity.
+func PruneIdleTask(name string, schedule Schedule, idle time.Duration) MaintenanceTask {
+	return MaintenanceTask{Name: name, Schedule: schedule, Run: func(ctx context.Context, mfs *MultiFieldSet, scheduled time.Time) error {
+		members, err := mfs.GetIdleMembers(ctx, scheduled.Add(-idle))
+		if err != nil || len(members) == 0 {
+			return err
+		}
+		names := make([]string, len(members))
+		for i, m := range members {
+			names[i] = m.Member
+		}
+		_, err = mfs.RemoveMember(ctx, names...)
+		return err
+	}}
+}
+
+// DecayTask returns a task applying the decay of the set's decaying fields with ApplyDecay.
+func DecayTask(name string, schedule Schedule) MaintenanceTask {
+	return MaintenanceTask{Name: name, Schedule: schedule, Run: func(ctx context.Context, mfs *MultiFieldSet, _ time.Time) error {
+		_, err := mfs.ApplyDecay(ctx)
+		return err
+	}}
+}
+
+// SnapshotTask returns a task taking a snapshot with Snapshot, labelled by calling label with the
+// scheduled time of the run, e.g. to keep a snapshot per day.
+func SnapshotTask(name string, schedule Schedule, label func(scheduled time.Time) string) MaintenanceTask {
+	return MaintenanceTask{Name: name, Schedule: schedule, Run: func(ctx context.Context, mfs *MultiFieldSet, scheduled time.Time) error {
+		_, err := mfs.Snapshot(ctx, label(scheduled))
+		return err
+	}}
+}
+
+// RotateTask returns a task closing the current window of a periodic leaderboard, such as a weekly
+// one: it takes a snapshot like SnapshotTask and then empties the set with Clear. Updates made
+// between the two steps are in neither the snapshot nor the new window.
+func RotateTask(name string, schedule Schedule, label func(scheduled time.Time) string) MaintenanceTask {
+	return MaintenanceTask{Name: name, Schedule: schedule, Run: func(ctx context.Context, mfs *MultiFieldSet, scheduled time.Time) error {
+		if _, err := mfs.Snapshot(ctx, label(scheduled)); err != nil {
+			return err
+		}
+		return mfs.Clear(ctx)
+	}}
+}
+
+// TierTask returns a task moving the cutoffs of the percentile tiers with ComputeTiers.
+func TierTask(name string, schedule Schedule) MaintenanceTask {
+	return MaintenanceTask{Name: name, Schedule: schedule, Run: func(ctx context.Context, mfs *MultiFieldSet, _ time.Time) error {
+		_, err := mfs.ComputeTiers(ctx)
+		return err
+	}}
+}
+
+// DivisionsTask returns a task promoting and demoting members between divisions with
+// Divisions.Rotate. It requires Divisions.
+func DivisionsTask(name string, schedule Schedule) MaintenanceTask {
+	return MaintenanceTask{Name: name, Schedule: schedule, Run: func(ctx context.Context, mfs *MultiFieldSet, _ time.Time) error {
+		if mfs.divisions == nil {
+			return ErrNoDivisions
+		}
+		_, err := mfs.divisions.Rotate(ctx)
+		return err
+	}}
+}
+
+// MaintenanceOptions configures a Maintenance runner.
+type MaintenanceOptions struct {
+	Tasks []MaintenanceTask
+	// Clock defaults to the system clock.
+	Clock Clock
+	// Jitter is the maximum random delay added to every run, so instances of an application don't
+	// all compete for the lock at the same instant.
+	Jitter time.Duration
+}
+
+// Maintenance runs maintenance tasks on a set on their schedules, e.g. nightly pruning, hourly
+// decay and a weekly rotation, from within every instance of an application. Each run is elected
+// to a single instance through the set's administrative lock: the instance taking the lock runs
+// the task and records the run, and the others skip it.
+type Maintenance struct {
+	mfs    *MultiFieldSet
+	tasks  []MaintenanceTask
+	clock  Clock
+	jitter time.Duration
+}
+
+// NewMaintenance returns a Maintenance runner for the set. Start it with Start.
+func (mfs *MultiFieldSet) NewMaintenance(opts MaintenanceOptions) (*Maintenance, error) {
+	if len(opts.Tasks) == 0 {
+		return nil, errors.New("at least one maintenance task is required")
+	}
+	seen := make(map[string]bool, len(opts.Tasks))
+	for _, task := range opts.Tasks {
+		if task.Name == "" || task.Schedule == nil || task.Run == nil {
+			return nil, errors.New("maintenance tasks need a name, a schedule and a function")
+		}
+		if seen[task.Name] {
+			return nil, fmt.Errorf("duplicate maintenance task %s", task.Name)
+		}
+		seen[task.Name] = true
+	}
+	if opts.Jitter < 0 {
+		return nil, errors.New("maintenance jitter can't be negative")
+	}
+	clock := opts.Clock
+	if clock == nil {
+		clock = systemClock{}
+	}
+	return &Maintenance{mfs: mfs, tasks: opts.Tasks, clock: clock, jitter: opts.Jitter}, nil
+}
+
+// maintenanceKey returns the key of the hash recording the last run of every maintenance task.
+func (mfs *MultiFieldSet) maintenanceKey() string {
+	return mfs.derivedKey("maintenance")
+}
+
+// Start runs the tasks in a background goroutine until ctx is done or the returned stop function
+// is called. Tasks due at the same time run one after the other. Errors are reported to the error
+// hooks; a failed run isn't retried before the task is next due.
+func (m *Maintenance) Start(ctx context.Context) (stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	go m.loop(ctx)
+	return cancel
+}
+
+// loop waits for the next due task and runs every task due by then.
+func (m *Maintenance) loop(ctx context.Context) {
+	next := make([]time.Time, len(m.tasks))
+	now := m.clock.Now()
+	for i, task := range m.tasks {
+		next[i] = task.Schedule.Next(now)
+	}
+	for {
+		var due time.Time
+		for _, t := range next {
+			if !t.IsZero() && (due.IsZero() || t.Before(due)) {
+				due = t
+			}
+		}
+		if due.IsZero() {
+			// No task will ever run again
+			<-ctx.Done()
+			return
+		}
+
+		wait := due.Sub(m.clock.Now())
+		if m.jitter > 0 {
+			wait += rand.N(m.jitter)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.clock.After(wait):
+		}
+
+		now := m.clock.Now()
+		for i, task := range m.tasks {
+			if next[i].IsZero() || next[i].After(now) {
+				continue
+			}
+			m.run(ctx, task, next[i])
+			if ctx.Err() != nil {
+				return
+			}
+			// Runs missed while a long task was running are skipped
+			next[i] = task.Schedule.Next(now)
+		}
+	}
+}
+
+// run runs task for the given scheduled time unless another instance holds the lock or already
+// ran it.
+func (m *Maintenance) run(ctx context.Context, task MaintenanceTask, scheduled time.Time) {
+	mfs := m.mfs
+	slot := strconv.FormatInt(scheduled.UnixMilli(), 10)
+	err := mfs.withLock(ctx, "Maintenance "+task.Name, func(ctx context.Context) error {
+		var last string
+		err := mfs.primary(ctx, func(client redis.UniversalClient) error {
+			var err error
+			last, err = client.HGet(ctx, mfs.maintenanceKey(), task.Name).Result()
+			return err
+		})
+		if err != nil && err != redis.Nil {
+			return err
+		}
+		if ran, _ := strconv.ParseInt(last, 10, 64); ran >= scheduled.UnixMilli() {
+			return nil
+		}
+
+		if err := task.Run(ctx, mfs, scheduled); err != nil {
+			return err
+		}
+		return mfs.primary(ctx, func(client redis.UniversalClient) error {
+			return client.HSet(ctx, mfs.maintenanceKey(), task.Name, slot).Err()
+		})
+	})
+	if errors.Is(err, ErrLocked) {
+		// Another instance was elected, or an administrative operation is running
+		return
+	}
+	if err != nil {
+		mfs.runOnError(ctx, "Maintenance", "", fmt.Errorf("task %s: %w", task.Name, err))
+	}
+}
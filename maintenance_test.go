@@ -0,0 +1,220 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock whose time only moves when Advance is called.
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+}
+
+type fakeWaiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, fakeWaiter{at: c.now.Add(d), ch: ch})
+	return ch
+}
+
+// Advance moves the time forward by d, waking the waiters that are due.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	waiters := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			waiters = append(waiters, w)
+		} else {
+			w.ch <- c.now
+		}
+	}
+	c.waiters = waiters
+}
+
+// waitForWaiters waits until n goroutines are waiting on the clock.
+func (c *fakeClock) waitForWaiters(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		c.mu.Lock()
+		waiting := len(c.waiters)
+		c.mu.Unlock()
+		if waiting == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("expected %d goroutines waiting on the clock", n)
+}
+
+func TestMaintenance_LeaderElection(t *testing.T) {
+	ctx := context.Background()
+	client, _ := newTestClient(t)
+	clock := &fakeClock{now: time.Date(2026, 1, 1, 0, 30, 0, 0, time.UTC)}
+
+	var runs atomic.Int64
+	var scheduled []time.Time
+	var mu sync.Mutex
+	task := MaintenanceTask{Name: "count", Schedule: Every(time.Hour), Run: func(ctx context.Context, mfs *MultiFieldSet, at time.Time) error {
+		runs.Add(1)
+		mu.Lock()
+		scheduled = append(scheduled, at)
+		mu.Unlock()
+		return nil
+	}}
+
+	// Two instances of an application sharing the set
+	for i := 0; i < 2; i++ {
+		mfs, err := New(MultiFieldSetOptions{
+			Name:   "test",
+			Fields: []Field{{Name: "points", Sort: Descending, MaxValue: 1000}},
+			Client: client,
+		})
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		m, err := mfs.NewMaintenance(MaintenanceOptions{Tasks: []MaintenanceTask{task}, Clock: clock})
+		if err != nil {
+			t.Fatalf("NewMaintenance() error = %v", err)
+		}
+		stop := m.Start(ctx)
+		t.Cleanup(stop)
+	}
+
+	clock.waitForWaiters(t, 2)
+	clock.Advance(30 * time.Minute)
+	clock.waitForWaiters(t, 2)
+	if n := runs.Load(); n != 1 {
+		t.Fatalf("task ran %d times at 01:00, expected once", n)
+	}
+	clock.Advance(time.Hour)
+	clock.waitForWaiters(t, 2)
+	if n := runs.Load(); n != 2 {
+		t.Fatalf("task ran %d times by 02:00, expected twice", n)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !scheduled[0].Equal(time.Date(2026, 1, 1, 1, 0, 0, 0, time.UTC)) || !scheduled[1].Equal(time.Date(2026, 1, 1, 2, 0, 0, 0, time.UTC)) {
+		t.Errorf("runs scheduled at %v, expected 01:00 and 02:00", scheduled)
+	}
+}
+
+func TestMaintenance_Errors(t *testing.T) {
+	ctx := context.Background()
+	mfs := newTestSet(t)
+	clock := &fakeClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	failure := errors.New("disk full")
+
+	var hookErr atomic.Value
+	mfs.OnError(func(ctx context.Context, op string, member string, err error) {
+		if op == "Maintenance" {
+			hookErr.Store(err)
+		}
+	})
+	m, err := mfs.NewMaintenance(MaintenanceOptions{Clock: clock, Jitter: time.Minute, Tasks: []MaintenanceTask{{
+		Name:     "fail",
+		Schedule: Every(time.Hour),
+		Run:      func(context.Context, *MultiFieldSet, time.Time) error { return failure },
+	}}})
+	if err != nil {
+		t.Fatalf("NewMaintenance() error = %v", err)
+	}
+	stop := m.Start(ctx)
+	defer stop()
+
+	clock.waitForWaiters(t, 1)
+	// The jitter delays the run by up to a minute
+	clock.Advance(time.Hour + time.Minute)
+	clock.waitForWaiters(t, 1)
+	if err, _ := hookErr.Load().(error); !errors.Is(err, failure) {
+		t.Errorf("OnError got %v, expected the task's error", err)
+	}
+
+	if _, err := mfs.NewMaintenance(MaintenanceOptions{}); err == nil {
+		t.Errorf("NewMaintenance() without tasks succeeded, expected an error")
+	}
+	if _, err := mfs.NewMaintenance(MaintenanceOptions{Tasks: []MaintenanceTask{DecayTask("a", Every(time.Hour)), DecayTask("a", Every(time.Hour))}}); err == nil {
+		t.Errorf("NewMaintenance() with duplicate tasks succeeded, expected an error")
+	}
+}
+
+func TestRotateTask(t *testing.T) {
+	ctx := context.Background()
+	mfs := newTestSet(t)
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 10}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+
+	week := time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)
+	task := RotateTask("weekly", Every(7*24*time.Hour), func(scheduled time.Time) string {
+		return scheduled.Format("2006-01-02")
+	})
+	if err := task.Run(ctx, mfs, week); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if exists, err := mfs.MemberExists(ctx, "alice"); err != nil || exists {
+		t.Errorf("MemberExists(alice) = %v, %v, expected a new window", exists, err)
+	}
+	if info, err := mfs.snapshotInfo(ctx, "2026-10-12"); err != nil || info.Members != 1 {
+		t.Errorf("snapshot = %v, %v, expected the closed window", info, err)
+	}
+}
+
+func TestCron(t *testing.T) {
+	tests := []struct {
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		// Mondays at 04:00
+		{"0 4 * * 1", time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC), time.Date(2026, 10, 19, 4, 0, 0, 0, time.UTC)},
+		// Every quarter hour during working hours
+		{"*/15 9-17 * * 1-5", time.Date(2026, 10, 16, 17, 50, 0, 0, time.UTC), time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)},
+		{"*/15 9-17 * * 1-5", time.Date(2026, 10, 16, 9, 15, 0, 0, time.UTC), time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC)},
+		// The 1st of the month or any Sunday, written as 7
+		{"0 0 1 * 7", time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)},
+		{"30 12 29 2 *", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2028, 2, 29, 12, 30, 0, 0, time.UTC)},
+		// Never
+		{"0 0 30 2 *", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Time{}},
+	}
+	for _, tt := range tests {
+		schedule, err := Cron(tt.spec)
+		if err != nil {
+			t.Fatalf("Cron(%q) error = %v", tt.spec, err)
+		}
+		if got := schedule.Next(tt.from); !got.Equal(tt.want) {
+			t.Errorf("Cron(%q).Next(%v) = %v, expected %v", tt.spec, tt.from, got, tt.want)
+		}
+	}
+
+	for _, spec := range []string{"* * * *", "60 * * * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		if _, err := Cron(spec); err == nil {
+			t.Errorf("Cron(%q) succeeded, expected an error", spec)
+		}
+	}
+}
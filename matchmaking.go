@@ -0,0 +1,115 @@
+package zmultifield
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// FindOpponents returns up to limit members whose value for each field in tolerances lies within
+// the tolerance of the member's current value, e.g. {"rating": 100} for players within 100 rating
+// points, for skill-based matchmaking. Candidates are returned closest first, by distance between
+// packed scores, so the most significant field weighs most; the member itself is left out. A limit
+// of zero or less returns every candidate. opts can restrict the search to a dimension with
+// InDimension or InDivision, or narrow it further with Where. Hidden members are never returned,
+// and it fails with ErrMemberNotFound if the member isn't in the set.
+//
+// The tolerances are resolved like the bounds of BuildScoreRange, so only some of them narrow the
+// range read from Redis and the others are checked while walking it outwards from the member.
+func (mfs *MultiFieldSet) FindOpponents(ctx context.Context, member string, tolerances map[string]float64, limit int64, opts ...ReadOption) (_ []MemberScores, err error) {
+	defer mfs.observeRead("FindOpponents", time.Now(), &err)
+
+	o := newReadOptions(opts)
+	ctx = o.context(ctx)
+	opponents, err := mfs.findOpponents(ctx, o, member, tolerances, limit)
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "FindOpponents", member, err)
+	}
+	return opponents, nil
+}
+
+// findOpponents implements FindOpponents.
+func (mfs *MultiFieldSet) findOpponents(ctx context.Context, o readOptions, member string, tolerances map[string]float64, limit int64) ([]MemberScores, error) {
+	var score float64
+	err := mfs.read(ctx, func(client redis.UniversalClient) error {
+		var err error
+		score, err = client.ZScore(ctx, mfs.key, member).Result()
+		return err
+	})
+	if err == redis.Nil {
+		return nil, ErrMemberNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	zscore, err := mfs.zscoreOf(member, score)
+	if err != nil {
+		return nil, err
+	}
+
+	var filter Filter
+	for name, tolerance := range tolerances {
+		field := mfs.GetFieldByName(name)
+		if field == nil {
+			return nil, fieldNotFoundError(name)
+		}
+		if !(tolerance >= 0) {
+			return nil, fmt.Errorf("tolerance %v of field %s must not be negative", tolerance, name)
+		}
+		value, _ := new(big.Float).SetInt(field.toDisplay(mfs.extractFieldScore(field, zscore))).Float64()
+		filter = filter.And(F(name).Between(value-tolerance, value+tolerance))
+	}
+	key, c, err := mfs.resolveFilter(filter, o)
+	if err != nil {
+		return nil, err
+	}
+	if c.empty {
+		return []MemberScores{}, nil
+	}
+
+	// The closest candidates are among the closest on each side of the member, so each side is
+	// walked outwards from the member until it has limit of them
+	better, worse := c, c
+	better.max, better.reverse = zscore.String(), true
+	worse.min, worse.reverse = zscore.String(), false
+	var candidates []redis.Z
+	seen := map[string]bool{member: true}
+	for _, side := range []compiledFilter{better, worse} {
+		var found int64
+		err := mfs.walkFiltered(ctx, key, side, scanBatchSize, func(z redis.Z) bool {
+			if seen[memberName(z)] {
+				return true
+			}
+			seen[memberName(z)] = true
+			candidates = append(candidates, z)
+			found++
+			return limit <= 0 || found < limit
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	distances := make(map[string]*big.Int, len(candidates))
+	for _, z := range candidates {
+		d, err := mfs.zscoreOf(memberName(z), z.Score)
+		if err != nil {
+			return nil, err
+		}
+		distances[memberName(z)] = d.Abs(d.Sub(d, zscore))
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return distances[memberName(candidates[i])].Cmp(distances[memberName(candidates[j])]) < 0
+	})
+	if limit > 0 && int64(len(candidates)) > limit {
+		candidates = candidates[:limit]
+	}
+	opponents := make([]MemberScores, len(candidates))
+	for i, z := range candidates {
+		opponents[i] = mfs.decodeEntry(z)
+	}
+	return opponents, nil
+}
@@ -0,0 +1,62 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestFindOpponents(t *testing.T) {
+	mfs := newTestSet(t)
+	ctx := context.Background()
+	for member, scores := range map[string]map[string]float64{
+		"me":       {"points": 500, "deaths": 10},
+		"close":    {"points": 495, "deaths": 12},
+		"above":    {"points": 520, "deaths": 10},
+		"below":    {"points": 450, "deaths": 10},
+		"far":      {"points": 600, "deaths": 10},
+		"reckless": {"points": 510, "deaths": 30},
+	} {
+		if _, err := mfs.IncreaseScore(ctx, scores, member); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+	}
+	tolerances := map[string]float64{"points": 50, "deaths": 5}
+
+	names := func(members []MemberScores) []string {
+		var names []string
+		for _, m := range members {
+			names = append(names, m.Member)
+		}
+		return names
+	}
+	opponents, err := mfs.FindOpponents(ctx, "me", tolerances, 0)
+	if err != nil {
+		t.Fatalf("FindOpponents() error = %v", err)
+	}
+	if got, want := names(opponents), []string{"close", "above", "below"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FindOpponents() = %v, expected %v closest first", got, want)
+	}
+	if FieldValue(opponents[0].Scores, "deaths") != 12 {
+		t.Errorf("FindOpponents() returned %v, expected decoded scores", opponents[0])
+	}
+
+	opponents, err = mfs.FindOpponents(ctx, "me", tolerances, 2)
+	if err != nil {
+		t.Fatalf("FindOpponents() error = %v", err)
+	}
+	if got, want := names(opponents), []string{"close", "above"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FindOpponents() with a limit = %v, expected %v", got, want)
+	}
+
+	if _, err := mfs.FindOpponents(ctx, "nobody", tolerances, 2); !errors.Is(err, ErrMemberNotFound) {
+		t.Errorf("FindOpponents() of a missing member error = %v, expected ErrMemberNotFound", err)
+	}
+	if _, err := mfs.FindOpponents(ctx, "me", map[string]float64{"kills": 5}, 2); !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("FindOpponents() on an unknown field error = %v, expected ErrFieldNotFound", err)
+	}
+	if _, err := mfs.FindOpponents(ctx, "me", map[string]float64{"points": -5}, 2); err == nil {
+		t.Error("FindOpponents() with a negative tolerance succeeded")
+	}
+}
@@ -0,0 +1,171 @@
+package zmultifield
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// memberName returns the member of a sorted set entry as a string. go-redis returns members as
+// strings, but entries built by hand or by other clients may hold other types.
+func memberName(z redis.Z) string {
+	switch m := z.Member.(type) {
+	case string:
+		return m
+	case []byte:
+		return string(m)
+	default:
+		return fmt.Sprint(m)
+	}
+}
+
+// MemberCodec converts members of type M to and from the strings stored in Redis.
+type MemberCodec[M any] interface {
+	// EncodeMember returns the stored form of member.
+	EncodeMember(member M) string
+	// DecodeMember parses a stored member.
+	DecodeMember(s string) (M, error)
+}
+
+// Int64Members stores int64 members, such as numeric user IDs, as base 10 strings.
+type Int64Members struct{}
+
+// EncodeMember implements MemberCodec.
+func (Int64Members) EncodeMember(member int64) string {
+	return strconv.FormatInt(member, 10)
+}
+
+// DecodeMember implements MemberCodec.
+func (Int64Members) DecodeMember(s string) (int64, error) {
+	return strconv.ParseInt(s, 10, 64)
+}
+
+// BytesMembers stores binary members, such as UUIDs in their 16-byte form, as is. Redis strings
+// are binary safe.
+type BytesMembers struct{}
+
+// EncodeMember implements MemberCodec.
+func (BytesMembers) EncodeMember(member []byte) string {
+	return string(member)
+}
+
+// DecodeMember implements MemberCodec.
+func (BytesMembers) DecodeMember(s string) ([]byte, error) {
+	return []byte(s), nil
+}
+
+// MemberScoresOf is a member of type M with its decoded field scores.
+type MemberScoresOf[M any] struct {
+	Member M
+	Scores []FieldScore
+	// Rank is set by reads with WithRanks.
+	Rank int64
+	// Meta is set by reads with WithMeta.
+	Meta map[string]string
+}
+
+// MemberSet is a view of a MultiFieldSet whose members are of type M, converted with a
+// MemberCodec on every call. It covers the common reads and writes; Set returns the underlying
+// set for everything else.
+type MemberSet[M any] struct {
+	set   *MultiFieldSet
+	codec MemberCodec[M]
+}
+
+// WithMembers returns a view of mfs whose members are of type M, e.g.
+// WithMembers[int64](mfs, Int64Members{}) for numeric user IDs.
+func WithMembers[M any](mfs *MultiFieldSet, codec MemberCodec[M]) *MemberSet[M] {
+	return &MemberSet[M]{set: mfs, codec: codec}
+}
+
+// Set returns the underlying MultiFieldSet.
+func (ms *MemberSet[M]) Set() *MultiFieldSet {
+	return ms.set
+}
+
+// IncreaseScore is MultiFieldSet.IncreaseScore for a member of type M.
+func (ms *MemberSet[M]) IncreaseScore(ctx context.Context, fields map[string]float64, member M) (*big.Int, error) {
+	return ms.set.IncreaseScore(ctx, fields, ms.codec.EncodeMember(member))
+}
+
+// GetScores is MultiFieldSet.GetScores for a member of type M.
+func (ms *MemberSet[M]) GetScores(ctx context.Context, member M) ([]FieldScore, error) {
+	return ms.set.GetScores(ctx, ms.codec.EncodeMember(member))
+}
+
+// GetRank is MultiFieldSet.GetRank for a member of type M.
+func (ms *MemberSet[M]) GetRank(ctx context.Context, member M, opts ...ReadOption) (int64, error) {
+	return ms.set.GetRank(ctx, ms.codec.EncodeMember(member), opts...)
+}
+
+// RemoveMember is MultiFieldSet.RemoveMember for members of type M.
+func (ms *MemberSet[M]) RemoveMember(ctx context.Context, members ...M) (int64, error) {
+	encoded := make([]string, len(members))
+	for i, member := range members {
+		encoded[i] = ms.codec.EncodeMember(member)
+	}
+	return ms.set.RemoveMember(ctx, encoded...)
+}
+
+// GetMembers is MultiFieldSet.GetMembers returning members of type M. It fails with
+// ErrInvalidMember if a member can't be decoded.
+func (ms *MemberSet[M]) GetMembers(ctx context.Context, limit, offset int64, opts ...ReadOption) ([]MemberScoresOf[M], error) {
+	members, err := ms.set.GetMembers(ctx, limit, offset, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return ms.decode(members)
+}
+
+// GetTopMembers is MultiFieldSet.GetTopMembers returning members of type M. It fails with
+// ErrInvalidMember if a member can't be decoded.
+func (ms *MemberSet[M]) GetTopMembers(ctx context.Context, limit int64, opts ...ReadOption) ([]MemberScoresOf[M], error) {
+	members, err := ms.set.GetTopMembers(ctx, limit, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return ms.decode(members)
+}
+
+// Iterate is MultiFieldSet.Iterate passing members of type M to fn. It stops with
+// ErrInvalidMember at the first member that can't be decoded.
+func (ms *MemberSet[M]) Iterate(ctx context.Context, filter Filter, fn func(member MemberScoresOf[M]) bool, opts ...ReadOption) error {
+	var decodeErr error
+	err := ms.set.Iterate(ctx, filter, func(m MemberScores) bool {
+		member, err := ms.decodeMember(m.Member)
+		if err != nil {
+			decodeErr = err
+			return false
+		}
+		return fn(MemberScoresOf[M]{Member: member, Scores: m.Scores, Rank: m.Rank, Meta: m.Meta})
+	}, opts...)
+	if err != nil {
+		return err
+	}
+	return decodeErr
+}
+
+// decode converts members read from the set.
+func (ms *MemberSet[M]) decode(members []MemberScores) ([]MemberScoresOf[M], error) {
+	result := make([]MemberScoresOf[M], len(members))
+	for i, m := range members {
+		member, err := ms.decodeMember(m.Member)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = MemberScoresOf[M]{Member: member, Scores: m.Scores, Rank: m.Rank, Meta: m.Meta}
+	}
+	return result, nil
+}
+
+// decodeMember decodes a stored member, wrapping failures in ErrInvalidMember.
+func (ms *MemberSet[M]) decodeMember(s string) (M, error) {
+	member, err := ms.codec.DecodeMember(s)
+	if err != nil {
+		return member, fmt.Errorf("%w %q: %v", ErrInvalidMember, s, err)
+	}
+	return member, nil
+}
@@ -0,0 +1,75 @@
+package zmultifield
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+)
+
+func TestMemberSet(t *testing.T) {
+	mfs := newTestSet(t)
+	ctx := context.Background()
+	users := WithMembers[int64](mfs, Int64Members{})
+
+	for id, points := range map[int64]float64{1001: 30, 42: 20, 7: 10} {
+		if _, err := users.IncreaseScore(ctx, map[string]float64{"points": points}, id); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+	}
+	top, err := users.GetTopMembers(ctx, 10)
+	if err != nil {
+		t.Fatalf("GetTopMembers() error = %v", err)
+	}
+	if len(top) != 3 || top[0].Member != 1001 || top[1].Member != 42 || top[2].Member != 7 {
+		t.Errorf("GetTopMembers() = %+v, expected 1001, 42 and 7", top)
+	}
+	if rank, err := users.GetRank(ctx, 42); err != nil || rank != 1 {
+		t.Errorf("GetRank() = %d, %v, expected 1", rank, err)
+	}
+	if n, err := users.RemoveMember(ctx, 7); err != nil || n != 1 {
+		t.Errorf("RemoveMember() = %d, %v, expected 1", n, err)
+	}
+
+	// Members that aren't numbers fail to decode instead of being skipped or panicking
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 5}, "guest"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	if _, err := users.GetMembers(ctx, 10, 0); !errors.Is(err, ErrInvalidMember) {
+		t.Errorf("GetMembers() error = %v, expected ErrInvalidMember", err)
+	}
+	var seen int
+	err = users.Iterate(ctx, Filter{}, func(m MemberScoresOf[int64]) bool {
+		seen++
+		return true
+	})
+	if !errors.Is(err, ErrInvalidMember) || seen != 2 {
+		t.Errorf("Iterate() visited %d members with error %v, expected 2 and ErrInvalidMember", seen, err)
+	}
+
+	ids := WithMembers[[]byte](mfs, BytesMembers{})
+	id := []byte{0x00, 0xff, 0x10}
+	if _, err := ids.IncreaseScore(ctx, map[string]float64{"points": 100}, id); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	if top, err := ids.GetTopMembers(ctx, 1); err != nil || len(top) != 1 || !bytes.Equal(top[0].Member, id) {
+		t.Errorf("GetTopMembers() = %v, %v, expected the binary member", top, err)
+	}
+}
+
+func TestMemberName(t *testing.T) {
+	for _, tt := range []struct {
+		member   interface{}
+		expected string
+	}{
+		{"alice", "alice"},
+		{[]byte("bob"), "bob"},
+		{int64(42), "42"},
+	} {
+		if got := memberName(redis.Z{Member: tt.member}); got != tt.expected {
+			t.Errorf("memberName(%v) = %q, expected %q", tt.member, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package zmultifield
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// MemberComparison is the result of CompareMembers.
+type MemberComparison struct {
+	// A and B hold the compared members with their scores and 0-based ranks.
+	A MemberScores
+	B MemberScores
+	// Differences holds, for each field, the value of A minus the value of B, in the order of
+	// MemberScores.Scores. Computed fields hold the difference of their Value.
+	Differences []FieldScore
+	// Higher is the member ranking higher, or "" if both have the same packed score. Members with
+	// the same score are still ranked by name, but neither outranks the other on its scores.
+	Higher string
+	// DecidingField is the most significant field whose values differ, the one deciding which
+	// member ranks higher. It is "" if the members tie, or if only masked fields such as the salt
+	// tell them apart.
+	DecidingField string
+}
+
+// CompareMembers compares two members of the set head-to-head, e.g. for a versus screen or to
+// settle a dispute: it returns both members' scores and ranks, the difference of every field and
+// which member ranks higher under the set's field ordering. Both members are read in one
+// pipeline, so the comparison reflects a single point in time. It fails with ErrMemberNotFound if
+// either member isn't in the set or is hidden.
+func (mfs *MultiFieldSet) CompareMembers(ctx context.Context, a, b string) (_ *MemberComparison, err error) {
+	defer mfs.observeRead("CompareMembers", time.Now(), &err)
+
+	c, err := mfs.compareMembers(ctx, a, b)
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "CompareMembers", a, err)
+	}
+	return c, nil
+}
+
+// compareMembers implements CompareMembers.
+func (mfs *MultiFieldSet) compareMembers(ctx context.Context, a, b string) (*MemberComparison, error) {
+	members := []string{a, b}
+	scores := make([]*redis.FloatCmd, len(members))
+	ranks := make([]*redis.Cmd, len(members))
+	err := mfs.read(ctx, func(client redis.UniversalClient) error {
+		_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, member := range members {
+				scores[i] = pipe.ZScore(ctx, mfs.key, member)
+				ranks[i] = mfs.queueRank(ctx, pipe, member)
+			}
+			return nil
+		})
+		return err
+	})
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	entries := make([]MemberScores, len(members))
+	zscores := make([]*big.Int, len(members))
+	for i, member := range members {
+		rank, err := ranks[i].Int64()
+		if err == redis.Nil {
+			return nil, fmt.Errorf("%w: %s", ErrMemberNotFound, member)
+		} else if err != nil {
+			return nil, err
+		}
+		score, err := scores[i].Result()
+		if err == redis.Nil {
+			return nil, fmt.Errorf("%w: %s", ErrMemberNotFound, member)
+		} else if err != nil {
+			return nil, err
+		}
+		if zscores[i], err = mfs.zscoreOf(member, score); err != nil {
+			return nil, err
+		}
+		entries[i] = mfs.decodeEntry(redis.Z{Score: score, Member: member})
+		entries[i].Rank = rank
+	}
+
+	c := &MemberComparison{
+		A:           entries[0],
+		B:           entries[1],
+		Differences: make([]FieldScore, len(entries[0].Scores)),
+	}
+	for i, score := range c.A.Scores {
+		other := c.B.Scores[i]
+		c.Differences[i].Name = score.Name
+		if score.Score == nil {
+			c.Differences[i].Value = score.Value - other.Value
+			continue
+		}
+		c.Differences[i].Score = new(big.Int).Sub(score.Score, other.Score)
+		if c.DecidingField == "" && i < len(mfs.visible) && c.Differences[i].Score.Sign() != 0 {
+			c.DecidingField = score.Name
+		}
+	}
+	// Lower zscores rank higher
+	switch zscores[0].Cmp(zscores[1]) {
+	case -1:
+		c.Higher = a
+	case 1:
+		c.Higher = b
+	}
+	return c, nil
+}
@@ -0,0 +1,83 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCompareMembers(t *testing.T) {
+	mfs := newTestSet(t)
+	ctx := context.Background()
+	for member, fields := range map[string]map[string]float64{
+		"alice": {"points": 100, "deaths": 5},
+		"bob":   {"points": 100, "deaths": 2},
+		"carol": {"points": 300, "deaths": 9},
+		"dave":  {"points": 100, "deaths": 2},
+	} {
+		if _, err := mfs.IncreaseScore(ctx, fields, member); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+	}
+
+	c, err := mfs.CompareMembers(ctx, "alice", "bob")
+	if err != nil {
+		t.Fatalf("CompareMembers() error = %v", err)
+	}
+	if c.Higher != "bob" || c.DecidingField != "deaths" {
+		t.Errorf("Higher, DecidingField = %q, %q, expected bob to win on deaths", c.Higher, c.DecidingField)
+	}
+	if c.A.Member != "alice" || c.A.Rank != 3 || c.B.Member != "bob" || c.B.Rank != 1 {
+		t.Errorf("A, B = %+v, %+v, expected alice at rank 3 and bob at rank 1", c.A, c.B)
+	}
+	if d := FieldValue(c.Differences, "points"); d != 0 {
+		t.Errorf("points difference = %v, expected 0", d)
+	}
+	if d := FieldValue(c.Differences, "deaths"); d != 3 {
+		t.Errorf("deaths difference = %v, expected 3", d)
+	}
+
+	c, err = mfs.CompareMembers(ctx, "carol", "alice")
+	if err != nil {
+		t.Fatalf("CompareMembers() error = %v", err)
+	}
+	if c.Higher != "carol" || c.DecidingField != "points" || FieldValue(c.Differences, "points") != 200 {
+		t.Errorf("CompareMembers(carol, alice) = %+v, expected carol to win on points", c)
+	}
+
+	c, err = mfs.CompareMembers(ctx, "bob", "dave")
+	if err != nil {
+		t.Fatalf("CompareMembers() error = %v", err)
+	}
+	if c.Higher != "" || c.DecidingField != "" {
+		t.Errorf("Higher, DecidingField = %q, %q, expected a tie", c.Higher, c.DecidingField)
+	}
+
+	if _, err := mfs.CompareMembers(ctx, "alice", "nobody"); !errors.Is(err, ErrMemberNotFound) {
+		t.Errorf("CompareMembers() with a missing member error = %v, expected ErrMemberNotFound", err)
+	}
+}
+
+func TestCompareMembers_Hidden(t *testing.T) {
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{HideMembers: true})
+	ctx := context.Background()
+	for member, points := range map[string]float64{"alice": 10, "bob": 20, "carol": 30} {
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": points}, member); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+	}
+	if err := mfs.Hide(ctx, "carol"); err != nil {
+		t.Fatalf("Hide() error = %v", err)
+	}
+
+	c, err := mfs.CompareMembers(ctx, "alice", "bob")
+	if err != nil {
+		t.Fatalf("CompareMembers() error = %v", err)
+	}
+	if c.A.Rank != 1 || c.B.Rank != 0 || c.Higher != "bob" {
+		t.Errorf("CompareMembers() = %+v, expected visible ranks with bob ahead", c)
+	}
+	if _, err := mfs.CompareMembers(ctx, "alice", "carol"); !errors.Is(err, ErrMemberNotFound) {
+		t.Errorf("CompareMembers() with a hidden member error = %v, expected ErrMemberNotFound", err)
+	}
+}
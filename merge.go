@@ -0,0 +1,273 @@
+package zmultifield
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// MergeStrategy defines how field values are combined when the same member appears in more than
+// one source set.
+type MergeStrategy int
+
+const (
+	// MergeSum adds the member's values for each field.
+	MergeSum MergeStrategy = iota + 1
+	// MergeMax keeps the largest value for each field.
+	MergeMax
+	// MergeMin keeps the smallest value for each field.
+	MergeMin
+)
+
+// String returns the name used for the strategy by the merge script.
+func (s MergeStrategy) String() string {
+	switch s {
+	case MergeSum:
+		return "sum"
+	case MergeMax:
+		return "max"
+	case MergeMin:
+		return "min"
+	default:
+		return "unknown"
+	}
+}
+
+// mergeScript decodes every source set, aggregates the display value of each field per member and
+// writes the re-encoded scores to the destination, replacing it. Decoding uses arithmetic rather
+// than bit operations because Lua numbers are doubles and Redis' bit library is limited to 32 bits;
+// both are exact below 2^53. A merged value that doesn't fit its field aborts the merge with an
+// "OUTOFRANGE <position> <value>" error before anything is written.
+//
+// KEYS[1] is the destination and KEYS[2..n] the sources. ARGV[1] is the strategy, followed by seven
+// values per field: 2^shift, 2^bits, the maximum raw value, the field's MinValue, which sums add
+// back once per extra entry, 1 if the field is descending, how the field is merged and a parameter
+// of that kind. The kinds are "value" to aggregate the field with the strategy, "member" to keep
+// the value of the member's first entry, for fields derived from the member such as the salt, "max"
+// to keep the largest value whatever the strategy, for the updatedAt time, and "mean" and "count"
+// for the two halves of an Average field. The parameter is the 1-based position of the count field
+// for "mean", MaxSamples for "count" and unused otherwise. Sums weight means by their counts and
+// add the counts up to MaxSamples; Max and Min keep the chosen mean's count.
+var mergeScript = newWriteScript(`
+local strategy = ARGV[1]
+local fields = {}
+for i = 2, #ARGV, 7 do
+	fields[#fields + 1] = {
+		base = tonumber(ARGV[i]),
+		size = tonumber(ARGV[i + 1]),
+		max = tonumber(ARGV[i + 2]),
+		offset = tonumber(ARGV[i + 3]),
+		desc = ARGV[i + 4] == '1',
+		kind = ARGV[i + 5],
+		param = tonumber(ARGV[i + 6]),
+	}
+end
+
+-- round rounds halves away from zero, like divRound
+local function round(x)
+	if x < 0 then
+		return -math.floor(-x + 0.5)
+	end
+	return math.floor(x + 0.5)
+end
+
+local merged = {}
+local order = {}
+for k = 2, #KEYS do
+	local entries = redis.call('ZRANGE', KEYS[k], 0, -1, 'WITHSCORES')
+	for e = 1, #entries, 2 do
+		local member = entries[e]
+		local zscore = tonumber(entries[e + 1])
+		local entry = {}
+		for f, field in ipairs(fields) do
+			local raw = math.floor(zscore / field.base) % field.size
+			entry[f] = raw
+			if field.desc then
+				entry[f] = field.max - raw
+			end
+		end
+
+		local values = merged[member]
+		if values == nil then
+			merged[member] = entry
+			order[#order + 1] = member
+		else
+			-- Means read the counts before they are merged
+			local counts = {}
+			for f, field in ipairs(fields) do
+				if field.kind == 'mean' then
+					counts[f] = values[field.param]
+				end
+			end
+			for f, field in ipairs(fields) do
+				local current, value = values[f], entry[f]
+				if field.kind == 'member' or field.kind == 'count' then
+					-- Kept, or merged with its mean
+				elseif field.kind == 'max' then
+					values[f] = math.max(current, value)
+				elseif field.kind == 'mean' then
+					local c = field.param
+					local currentCount, count = counts[f], entry[c]
+					if strategy == 'sum' then
+						if currentCount + count > 0 then
+							local mean = ((current + field.offset) * currentCount + (value + field.offset) * count) / (currentCount + count)
+							values[f] = round(mean) - field.offset
+						end
+						values[c] = math.min(currentCount + count, fields[c].param)
+					elseif (strategy == 'max' and value > current) or (strategy == 'min' and value < current) then
+						values[f] = value
+						values[c] = count
+					end
+				elseif strategy == 'sum' then
+					-- Values are stored less MinValue, so the sum of two displays is stored plus MinValue
+					values[f] = current + value + field.offset
+				elseif strategy == 'max' then
+					values[f] = math.max(current, value)
+				else
+					values[f] = math.min(current, value)
+				end
+			end
+		end
+	end
+end
+
+local zscores = {}
+for _, member in ipairs(order) do
+	local zscore = 0
+	for f, field in ipairs(fields) do
+		local value = merged[member][f]
+		if value < 0 or value > field.max then
+			return redis.error_reply('OUTOFRANGE ' .. (f - 1) .. ' ' .. string.format('%.17g', value))
+		end
+		local raw = value
+		if field.desc then
+			raw = field.max - value
+		end
+		zscore = zscore + raw * field.base
+	end
+	zscores[member] = zscore
+end
+
+redis.call('DEL', KEYS[1])
+for _, member in ipairs(order) do
+	redis.call('ZADD', KEYS[1], string.format('%.17g', zscores[member]), member)
+end
+return #order
+`)
+
+// MergeFrom combines the members of several sets into the sorted set at dest, replacing it, and
+// returns the number of members written. Each field is aggregated on its decoded value according to
+// strategy, so descending fields merge correctly where a plain ZUNIONSTORE would sum packed scores.
+// Average fields merge as means weighted by their sample counts under MergeSum, and as the chosen
+// mean with its count under MergeMax and MergeMin. Every source must share this set's field layout,
+// and its salt secret if the set is salted; the salt is not aggregated but kept for each member. The
+// merge runs server-side in a single script.
+func (mfs *MultiFieldSet) MergeFrom(ctx context.Context, others []*MultiFieldSet, dest string, strategy MergeStrategy) (int64, error) {
+	if strategy != MergeSum && strategy != MergeMax && strategy != MergeMin {
+		return 0, fmt.Errorf("unknown merge strategy %d", strategy)
+	}
+
+	keys := make([]string, 0, len(others)+1)
+	keys = append(keys, dest)
+	for _, other := range others {
+		if !mfs.compatibleWith(other) || (mfs.salt != nil && !bytes.Equal(mfs.saltSecret, other.saltSecret)) {
+			return 0, fmt.Errorf("%w: %s", ErrIncompatibleSets, other.name)
+		}
+		keys = append(keys, other.key)
+	}
+
+	counts := make(map[*multiField]bool)
+	for _, field := range mfs.fields {
+		if field.count != nil {
+			counts[field.count] = true
+		}
+	}
+	args := make([]interface{}, 0, 1+7*len(mfs.fields))
+	args = append(args, strategy.String())
+	for _, field := range mfs.fields {
+		desc := "0"
+		if field.inverted {
+			desc = "1"
+		}
+		offset := "0"
+		if field.offset != nil {
+			offset = field.offset.String()
+		}
+		kind, param := "value", int64(0)
+		switch {
+		case field == mfs.salt:
+			// Every source stamps a member with the same salt
+			kind = "member"
+		case field == mfs.updatedAt:
+			kind = "max"
+		case field.count != nil:
+			kind, param = "mean", int64(field.count.position+1)
+		case counts[field]:
+			kind, param = "count", int64(field.MaxValue)
+		}
+		args = append(args,
+			uint64(1)<<field.shiftValue,
+			uint64(1)<<field.bits,
+			field.maxAbsolute.String(),
+			offset,
+			desc,
+			kind,
+			param,
+		)
+	}
+
+	var count int64
+	err := mfs.write(ctx, func(client redis.UniversalClient) error {
+		var err error
+		count, err = mergeScript.Run(ctx, client, mfs.writeGuard(), keys, args...).Int64()
+		return err
+	})
+	if err != nil {
+		return 0, mfs.runOnError(ctx, "MergeFrom", "", mfs.mergeError(err))
+	}
+	return count, nil
+}
+
+// compatibleWith reports whether other packs its fields exactly like mfs.
+func (mfs *MultiFieldSet) compatibleWith(other *MultiFieldSet) bool {
+	if len(mfs.fields) != len(other.fields) {
+		return false
+	}
+	for i, field := range mfs.fields {
+		o := other.fields[i]
+		if field.Name != o.Name || field.Sort != o.Sort || field.inverted != o.inverted || field.bits != o.bits || field.shiftValue != o.shiftValue ||
+			field.MinValue != o.MinValue {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeError converts an OUTOFRANGE reply from the merge script into a ScoreOutOfRangeError.
+func (mfs *MultiFieldSet) mergeError(err error) error {
+	var position int
+	var value string
+	if _, scanErr := fmt.Sscanf(err.Error(), "OUTOFRANGE %d %s", &position, &value); scanErr != nil {
+		return err
+	}
+	if position < 0 || position >= len(mfs.fields) {
+		return err
+	}
+	v, ok := new(big.Int).SetString(value, 10)
+	if !ok {
+		return err
+	}
+	field := mfs.fields[position]
+	if field.offset != nil {
+		v.Add(v, field.offset)
+	}
+	return &ScoreOutOfRangeError{
+		Field: field.Name,
+		Value: v,
+		Min:   field.minDisplay(),
+		Max:   field.maxDisplay(),
+	}
+}
@@ -0,0 +1,241 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMergeFrom(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	fields := []Field{
+		{Name: "points", Sort: Descending, MaxValue: 1000, UpdateType: Incremental},
+		{Name: "deaths", Sort: Ascending, MaxValue: 100, UpdateType: Incremental},
+	}
+	newSet := func(name string) *MultiFieldSet {
+		mfs, err := New(MultiFieldSetOptions{Name: name, Fields: fields, Client: client})
+		if err != nil {
+			t.Fatalf("Failed to create MultiFieldSet: %v", err)
+		}
+		return mfs
+	}
+	day1, day2, total := newSet("day1"), newSet("day2"), newSet("total")
+
+	updates := []struct {
+		set    *MultiFieldSet
+		member string
+		fields map[string]float64
+	}{
+		{day1, "alice", map[string]float64{"points": 100, "deaths": 2}},
+		{day1, "bob", map[string]float64{"points": 40, "deaths": 1}},
+		{day2, "alice", map[string]float64{"points": 20, "deaths": 3}},
+		{day2, "carol", map[string]float64{"points": 90}},
+	}
+	for _, u := range updates {
+		if _, err := u.set.IncreaseScore(ctx, u.fields, u.member); err != nil {
+			t.Fatalf("IncreaseScore(%s) error = %v", u.member, err)
+		}
+	}
+
+	tests := []struct {
+		strategy MergeStrategy
+		expected map[string][2]int64
+	}{
+		{MergeSum, map[string][2]int64{"alice": {120, 5}, "bob": {40, 1}, "carol": {90, 0}}},
+		{MergeMax, map[string][2]int64{"alice": {100, 3}, "bob": {40, 1}, "carol": {90, 0}}},
+		{MergeMin, map[string][2]int64{"alice": {20, 2}, "bob": {40, 1}, "carol": {90, 0}}},
+	}
+
+	for _, test := range tests {
+		count, err := total.MergeFrom(ctx, []*MultiFieldSet{day1, day2}, "total", test.strategy)
+		if err != nil {
+			t.Fatalf("MergeFrom(%s) error = %v", test.strategy, err)
+		}
+		if count != int64(len(test.expected)) {
+			t.Errorf("MergeFrom(%s) = %d, expected %d", test.strategy, count, len(test.expected))
+		}
+		for member, expected := range test.expected {
+			scores, err := total.GetScores(ctx, member)
+			if err != nil {
+				t.Fatalf("GetScores(%s) error = %v", member, err)
+			}
+			if scores[0].Score.Int64() != expected[0] || scores[1].Score.Int64() != expected[1] {
+				t.Errorf("MergeFrom(%s) %s = %v/%v, expected %v", test.strategy, member, scores[0].Score, scores[1].Score, expected)
+			}
+		}
+	}
+}
+
+func TestMergeFrom_Errors(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	a, _ := New(MultiFieldSetOptions{Name: "a", Client: client, Fields: []Field{
+		{Name: "points", Sort: Descending, MaxValue: 1000, UpdateType: Incremental},
+	}})
+	b, _ := New(MultiFieldSetOptions{Name: "b", Client: client, Fields: []Field{
+		{Name: "points", Sort: Descending, MaxValue: 1000, UpdateType: Incremental},
+	}})
+	c, _ := New(MultiFieldSetOptions{Name: "c", Client: client, Fields: []Field{
+		{Name: "points", Sort: Ascending, MaxValue: 1000, UpdateType: Incremental},
+	}})
+
+	if _, err := a.MergeFrom(ctx, []*MultiFieldSet{b, c}, "dest", MergeSum); !errors.Is(err, ErrIncompatibleSets) {
+		t.Errorf("MergeFrom() error = %v, expected ErrIncompatibleSets", err)
+	}
+
+	a.IncreaseScore(ctx, map[string]float64{"points": 1000}, "alice")
+	b.IncreaseScore(ctx, map[string]float64{"points": 1000}, "alice")
+
+	_, err := a.MergeFrom(ctx, []*MultiFieldSet{a, b}, "dest", MergeSum)
+	var rangeErr *ScoreOutOfRangeError
+	if !errors.As(err, &rangeErr) {
+		t.Fatalf("MergeFrom() error = %v, expected *ScoreOutOfRangeError", err)
+	}
+	if rangeErr.Field != "points" || rangeErr.Value.Int64() != 2000 {
+		t.Errorf("ScoreOutOfRangeError = %+v, expected points 2000", rangeErr)
+	}
+}
+
+func TestMergeFrom_MinValue(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	newSet := func(name string) *MultiFieldSet {
+		mfs, err := New(MultiFieldSetOptions{Name: name, Client: client, Fields: []Field{
+			{Name: "rating", Sort: Descending, MinValue: 1000, MaxValue: 5000, UpdateType: Replace},
+			{Name: "delta", Sort: Ascending, MinValue: -50, MaxValue: 50, UpdateType: Incremental},
+		}})
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		return mfs
+	}
+	a, b := newSet("a"), newSet("b")
+	for _, set := range []*MultiFieldSet{a, b} {
+		if _, err := set.IncreaseScore(ctx, map[string]float64{"rating": 1500, "delta": 60}, "alice"); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+	}
+
+	if _, err := a.MergeFrom(ctx, []*MultiFieldSet{a, b}, "dest", MergeSum); err != nil {
+		t.Fatalf("MergeFrom() error = %v", err)
+	}
+	scores, err := client.ZScore(ctx, "dest", "alice").Result()
+	if err != nil {
+		t.Fatalf("ZScore() error = %v", err)
+	}
+	zscore, _ := a.zscoreOf("alice", scores)
+	if values := a.Decode(zscore); values["rating"] != 3000 || values["delta"] != 20 {
+		t.Errorf("merged values = %v, expected rating 3000 and delta 20", values)
+	}
+
+	// 4 * 1500 is past the rating's MaxValue
+	_, err = a.MergeFrom(ctx, []*MultiFieldSet{a, b, a, b}, "dest", MergeSum)
+	var rangeErr *ScoreOutOfRangeError
+	if !errors.As(err, &rangeErr) || rangeErr.Field != "rating" || rangeErr.Value.Int64() != 6000 {
+		t.Errorf("MergeFrom() error = %v, expected rating 6000 out of range", err)
+	}
+}
+
+func TestMergeFrom_UpdatedAt(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	newSet := func(name string) *MultiFieldSet {
+		mfs, err := New(MultiFieldSetOptions{Name: name, Client: client, TrackUpdatedAt: true, Fields: []Field{
+			{Name: "points", Sort: Descending, MaxValue: 1000, UpdateType: Incremental},
+		}})
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		return mfs
+	}
+	a, b, total := newSet("a"), newSet("b"), newSet("total")
+	before := time.Now().Truncate(time.Minute)
+	for _, set := range []*MultiFieldSet{a, b} {
+		if _, err := set.IncreaseScore(ctx, map[string]float64{"points": 10}, "alice"); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+	}
+
+	// The times are never summed, whatever the strategy
+	if _, err := total.MergeFrom(ctx, []*MultiFieldSet{a, b}, total.key, MergeSum); err != nil {
+		t.Fatalf("MergeFrom() error = %v", err)
+	}
+	updatedAt, err := total.GetUpdatedAt(ctx, "alice")
+	if err != nil {
+		t.Fatalf("GetUpdatedAt() error = %v", err)
+	}
+	if updatedAt.Before(before) || updatedAt.After(time.Now()) {
+		t.Errorf("GetUpdatedAt() = %v, expected about %v", updatedAt, before)
+	}
+	if scores, err := total.GetScores(ctx, "alice"); err != nil || scores[0].Score.Int64() != 20 {
+		t.Errorf("GetScores() = %v, %v, expected 20 points", scores, err)
+	}
+}
+
+func TestMergeFrom_Average(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	newSet := func(name string) *MultiFieldSet {
+		mfs, err := New(MultiFieldSetOptions{Name: name, Client: client, Fields: []Field{
+			{Name: "avg", Sort: Descending, MaxValue: 1000, UpdateType: Average, MaxSamples: 7},
+		}})
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		return mfs
+	}
+	a, b := newSet("a"), newSet("b")
+	samples := map[*MultiFieldSet][]float64{a: {10, 20}, b: {30}}
+	for set, values := range samples {
+		for _, value := range values {
+			if _, err := set.IncreaseScore(ctx, map[string]float64{"avg": value}, "alice"); err != nil {
+				t.Fatalf("IncreaseScore() error = %v", err)
+			}
+		}
+	}
+	for i := 0; i < 6; i++ {
+		if _, err := b.IncreaseScore(ctx, map[string]float64{"avg": 30}, "bob"); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+	}
+	if _, err := a.IncreaseScore(ctx, map[string]float64{"avg": 100}, "bob"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	if _, err := a.IncreaseScore(ctx, map[string]float64{"avg": 100}, "bob"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+
+	tests := []struct {
+		strategy MergeStrategy
+		expected map[string][2]int64
+	}{
+		// alice: (15*2 + 30*1) / 3; bob: (100*2 + 30*6) / 8 with the count capped at 7
+		{MergeSum, map[string][2]int64{"alice": {20, 3}, "bob": {48, 7}}},
+		{MergeMax, map[string][2]int64{"alice": {30, 1}, "bob": {100, 2}}},
+		{MergeMin, map[string][2]int64{"alice": {15, 2}, "bob": {30, 6}}},
+	}
+	count := a.GetFieldByName(averageCountName("avg"))
+	for _, test := range tests {
+		if _, err := a.MergeFrom(ctx, []*MultiFieldSet{a, b}, "dest", test.strategy); err != nil {
+			t.Fatalf("MergeFrom(%s) error = %v", test.strategy, err)
+		}
+		for member, expected := range test.expected {
+			score, err := client.ZScore(ctx, "dest", member).Result()
+			if err != nil {
+				t.Fatalf("ZScore(%s) error = %v", member, err)
+			}
+			zscore, _ := a.zscoreOf(member, score)
+			mean, samples := a.Decode(zscore)["avg"], a.extractFieldScore(count, zscore).Int64()
+			if mean != expected[0] || samples != expected[1] {
+				t.Errorf("MergeFrom(%s) %s = mean %v over %d samples, expected %v", test.strategy, member, mean, samples, expected)
+			}
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package zmultifield
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// MemberWithMeta is a member's scores joined with its metadata.
+type MemberWithMeta struct {
+	MemberScores
+	Meta map[string]string
+}
+
+// metaKey returns the key of the hash holding a member's metadata.
+func (mfs *MultiFieldSet) metaKey(member string) string {
+	return mfs.derivedKey("meta:" + member)
+}
+
+// SetMemberMeta stores metadata such as a display name, avatar URL or country for a member.
+// Existing metadata fields not in meta are kept.
+func (mfs *MultiFieldSet) SetMemberMeta(ctx context.Context, member string, meta map[string]string) error {
+	if len(meta) == 0 {
+		return nil
+	}
+	values := make([]interface{}, 0, 2*len(meta))
+	for k, v := range meta {
+		values = append(values, k, v)
+	}
+	err := mfs.write(ctx, func(client redis.UniversalClient) error {
+		return client.HSet(ctx, mfs.metaKey(member), values...).Err()
+	})
+	return mfs.runOnError(ctx, "SetMemberMeta", member, err)
+}
+
+// GetMemberMeta returns a member's metadata, which is empty if none has been set.
+func (mfs *MultiFieldSet) GetMemberMeta(ctx context.Context, member string) (_ map[string]string, err error) {
+	defer mfs.observeRead("GetMemberMeta", time.Now(), &err)
+
+	var meta map[string]string
+	err = mfs.read(ctx, func(client redis.UniversalClient) error {
+		meta, err = client.HGetAll(ctx, mfs.metaKey(member)).Result()
+		return err
+	})
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "GetMemberMeta", member, err)
+	}
+	return meta, nil
+}
+
+// GetTopMembersWithMeta returns the top n members with their metadata. The metadata of all members
+// is fetched in a single pipeline after the range query. Hidden members are skipped.
+func (mfs *MultiFieldSet) GetTopMembersWithMeta(ctx context.Context, limit int64) (_ []MemberWithMeta, err error) {
+	defer mfs.observeRead("GetTopMembersWithMeta", time.Now(), &err)
+
+	var results []redis.Z
+	var metaCmds []*redis.StringStringMapCmd
+	err = mfs.read(ctx, func(client redis.UniversalClient) error {
+		if mfs.hideMembers {
+			results, err = mfs.visibleRange(ctx, client, mfs.key, 0, limit)
+		} else {
+			results, err = client.ZRangeWithScores(ctx, mfs.key, 0, limit-1).Result()
+		}
+		if err != nil || len(results) == 0 {
+			return err
+		}
+		_, err = client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			metaCmds = make([]*redis.StringStringMapCmd, len(results))
+			for i, z := range results {
+				metaCmds[i] = pipe.HGetAll(ctx, mfs.metaKey(memberName(z)))
+			}
+			return nil
+		})
+		return err
+	})
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "GetTopMembersWithMeta", "", err)
+	}
+
+	members, err := mfs.decodeMembers(results)
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "GetTopMembersWithMeta", "", err)
+	}
+	joined := make([]MemberWithMeta, len(members))
+	for i, m := range members {
+		joined[i] = MemberWithMeta{MemberScores: m, Meta: metaCmds[i].Val()}
+	}
+	return joined, nil
+}
+
+// RemoveMember removes members from the set along with their field index entries, dimension sets,
+// activity, history and metadata, and returns the number of members that were in the set. It also
+// deletes quarantined members, which aren't counted.
+func (mfs *MultiFieldSet) RemoveMember(ctx context.Context, members ...string) (int64, error) {
+	if len(members) == 0 {
+		return 0, nil
+	}
+	names := make([]interface{}, len(members))
+	perMember := make([]string, 0, 2*len(members))
+	for i, member := range members {
+		names[i] = member
+		perMember = append(perMember, mfs.historyKey(member), mfs.metaKey(member))
+		if mfs.quarantinable {
+			perMember = append(perMember, mfs.quarantineRecordKey(member))
+		}
+	}
+	var dimensions map[string][]string
+	if len(mfs.dimensions) > 0 {
+		var err error
+		if dimensions, err = mfs.memberDimensionKeys(ctx, members); err != nil {
+			return 0, mfs.runOnError(ctx, "RemoveMember", members[0], err)
+		}
+	}
+
+	var removed *redis.IntCmd
+	err := mfs.write(ctx, func(client redis.UniversalClient) error {
+		return mfs.txPipelined(ctx, client, func(pipe redis.Pipeliner) error {
+			removed = pipe.ZRem(ctx, mfs.key, names...)
+			mfs.recordDel(ctx, pipe, members...)
+			if mfs.maintainFieldIndexes {
+				for _, field := range mfs.fields {
+					pipe.ZRem(ctx, mfs.fieldIndexKey(field), names...)
+				}
+			}
+			if mfs.trackActivity {
+				pipe.ZRem(ctx, mfs.activityKey(), names...)
+			}
+			if mfs.quarantinable {
+				pipe.ZRem(ctx, mfs.quarantineKey(), names...)
+			}
+			mfs.removeFromDimensions(ctx, pipe, dimensions)
+			pipe.Del(ctx, perMember...)
+			return nil
+		})
+	})
+	if err != nil {
+		return 0, mfs.runOnError(ctx, "RemoveMember", members[0], err)
+	}
+	return removed.Val(), nil
+}
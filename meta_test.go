@@ -0,0 +1,78 @@
+package zmultifield
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestMemberMeta(t *testing.T) {
+	mfs := newTestSet(t)
+	ctx := context.Background()
+
+	for member, points := range map[string]float64{"alice": 20, "bob": 10} {
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": points}, member); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+	}
+	if err := mfs.SetMemberMeta(ctx, "alice", map[string]string{"name": "Alice", "country": "NL"}); err != nil {
+		t.Fatalf("SetMemberMeta() error = %v", err)
+	}
+	if err := mfs.SetMemberMeta(ctx, "alice", map[string]string{"country": "BE"}); err != nil {
+		t.Fatalf("SetMemberMeta() error = %v", err)
+	}
+
+	meta, err := mfs.GetMemberMeta(ctx, "alice")
+	if err != nil {
+		t.Fatalf("GetMemberMeta() error = %v", err)
+	}
+	if meta["name"] != "Alice" || meta["country"] != "BE" {
+		t.Errorf("GetMemberMeta() = %v, expected name Alice and country BE", meta)
+	}
+
+	members, err := mfs.GetTopMembersWithMeta(ctx, 10)
+	if err != nil {
+		t.Fatalf("GetTopMembersWithMeta() error = %v", err)
+	}
+	if len(members) != 2 || members[0].Member != "alice" || members[0].Meta["name"] != "Alice" {
+		t.Fatalf("GetTopMembersWithMeta() = %+v, expected alice with metadata first", members)
+	}
+	if len(members[1].Meta) != 0 {
+		t.Errorf("bob metadata = %v, expected none", members[1].Meta)
+	}
+
+	data, err := json.Marshal(members[0])
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if expected := `{"member":"alice","scores":{"deaths":0,"points":20},"meta":{"country":"BE","name":"Alice"}}`; string(data) != expected {
+		t.Errorf("Marshal() = %s, expected %s", data, expected)
+	}
+}
+
+func TestRemoveMember(t *testing.T) {
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{MaintainFieldIndexes: true, History: &HistoryOptions{}})
+	ctx := context.Background()
+
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 10}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	if err := mfs.SetMemberMeta(ctx, "alice", map[string]string{"name": "Alice"}); err != nil {
+		t.Fatalf("SetMemberMeta() error = %v", err)
+	}
+
+	removed, err := mfs.RemoveMember(ctx, "alice", "nobody")
+	if err != nil {
+		t.Fatalf("RemoveMember() error = %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("RemoveMember() = %d, expected 1", removed)
+	}
+
+	keys := append(mfs.allKeys(), mfs.metaKey("alice"), mfs.historyKey("alice"))
+	for _, key := range keys {
+		if n, _ := mfs.client.Exists(ctx, key).Result(); n != 0 {
+			t.Errorf("key %s still exists after RemoveMember()", key)
+		}
+	}
+}
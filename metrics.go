@@ -0,0 +1,47 @@
+package zmultifield
+
+import "time"
+
+// MetricsRecorder receives latency and error observations for operations on a MultiFieldSet.
+// Implementations must be safe for concurrent use.
+type MetricsRecorder interface {
+	// ObserveUpdate is called once per update with its latency and resulting error, if any.
+	ObserveUpdate(set string, duration time.Duration, err error)
+	// ObserveRead is called once per read operation with its latency and resulting error, if any.
+	ObserveRead(set string, op string, duration time.Duration, err error)
+}
+
+// OpMetricsRecorder is a MetricsRecorder that also receives the operation of every update, such as
+// IncreaseScore or UpdateIf. Updates are reported through ObserveUpdateOp instead of ObserveUpdate
+// when the configured recorder implements it.
+type OpMetricsRecorder interface {
+	MetricsRecorder
+	// ObserveUpdateOp is called once per update with its operation, latency and resulting error.
+	ObserveUpdateOp(set string, op string, duration time.Duration, err error)
+}
+
+// noopMetrics is the MetricsRecorder used when none is configured.
+type noopMetrics struct{}
+
+func (noopMetrics) ObserveUpdate(string, time.Duration, error)       {}
+func (noopMetrics) ObserveRead(string, string, time.Duration, error) {}
+
+// observeUpdate records an update started at start, logging it if it was slow. It is meant to be
+// deferred.
+func (mfs *MultiFieldSet) observeUpdate(op string, start time.Time, err *error) {
+	duration := time.Since(start)
+	if recorder, ok := mfs.metrics.(OpMetricsRecorder); ok {
+		recorder.ObserveUpdateOp(mfs.name, op, duration, *err)
+	} else {
+		mfs.metrics.ObserveUpdate(mfs.name, duration, *err)
+	}
+	mfs.logSlow(op, duration, *err)
+}
+
+// observeRead records a read operation started at start, logging it if it was slow. It is meant to
+// be deferred.
+func (mfs *MultiFieldSet) observeRead(op string, start time.Time, err *error) {
+	duration := time.Since(start)
+	mfs.metrics.ObserveRead(mfs.name, op, duration, *err)
+	mfs.logSlow(op, duration, *err)
+}
@@ -0,0 +1,161 @@
+// Package metrics provides a Prometheus collector for ZMultiField sets.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/Rohan-Muslekar/ZMultiField"
+	"github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector records update/read latency and error counts for multi-field sets and reports the
+// number of members in each tracked set. It implements both prometheus.Collector and
+// zmultifield.OpMetricsRecorder.
+type Collector struct {
+	updateLatency *prometheus.HistogramVec
+	readLatency   *prometheus.HistogramVec
+	outOfRange    *prometheus.CounterVec
+	redisErrors   *prometheus.CounterVec
+	members       *prometheus.Desc
+
+	mu      sync.RWMutex
+	sets    []*zmultifield.MultiFieldSet
+	timeout time.Duration
+}
+
+// NewCollector creates a new Collector with metric names prefixed by namespace.
+func NewCollector(namespace string) *Collector {
+	return &Collector{
+		updateLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "zmultifield",
+			Name:      "update_duration_seconds",
+			Help:      "Latency of multi-field set updates.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"set"}),
+		readLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "zmultifield",
+			Name:      "read_duration_seconds",
+			Help:      "Latency of multi-field set reads.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"set", "op"}),
+		outOfRange: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "zmultifield",
+			Name:      "out_of_range_errors_total",
+			Help:      "Number of updates rejected because a field score was out of range.",
+		}, []string{"set"}),
+		redisErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "zmultifield",
+			Name:      "redis_errors_total",
+			Help:      "Number of operations that failed with a Redis error.",
+		}, []string{"set", "op"}),
+		members: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "zmultifield", "members"),
+			"Number of members in the set.",
+			[]string{"set"}, nil,
+		),
+		timeout: time.Second,
+	}
+}
+
+// Track adds a set whose member count is reported on every scrape.
+func (c *Collector) Track(mfs *zmultifield.MultiFieldSet) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sets = append(c.sets, mfs)
+}
+
+// SetScrapeTimeout sets the timeout used when counting members during a scrape.
+func (c *Collector) SetScrapeTimeout(timeout time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.timeout = timeout
+}
+
+// ObserveUpdate implements zmultifield.MetricsRecorder. Errors are counted under the op "update",
+// since the operation is unknown.
+func (c *Collector) ObserveUpdate(set string, duration time.Duration, err error) {
+	c.ObserveUpdateOp(set, "update", duration, err)
+}
+
+// ObserveUpdateOp implements zmultifield.OpMetricsRecorder.
+func (c *Collector) ObserveUpdateOp(set string, op string, duration time.Duration, err error) {
+	c.updateLatency.WithLabelValues(set).Observe(duration.Seconds())
+	c.observeError(set, op, err)
+}
+
+// ObserveRead implements zmultifield.MetricsRecorder.
+func (c *Collector) ObserveRead(set string, op string, duration time.Duration, err error) {
+	c.readLatency.WithLabelValues(set, op).Observe(duration.Seconds())
+	c.observeError(set, op, err)
+}
+
+// observeError classifies err and increments the matching counter.
+func (c *Collector) observeError(set string, op string, err error) {
+	switch {
+	case err == nil:
+	case isOutOfRange(err):
+		c.outOfRange.WithLabelValues(set).Inc()
+	case isRedisError(err):
+		c.redisErrors.WithLabelValues(set, op).Inc()
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.updateLatency.Describe(ch)
+	c.readLatency.Describe(ch)
+	c.outOfRange.Describe(ch)
+	c.redisErrors.Describe(ch)
+	ch <- c.members
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.updateLatency.Collect(ch)
+	c.readLatency.Collect(ch)
+	c.outOfRange.Collect(ch)
+	c.redisErrors.Collect(ch)
+
+	c.mu.RLock()
+	sets := c.sets
+	timeout := c.timeout
+	c.mu.RUnlock()
+
+	for _, mfs := range sets {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		count, err := mfs.MemberCount(ctx)
+		cancel()
+		if err != nil {
+			c.redisErrors.WithLabelValues(mfs.GetName(), "Collect").Inc()
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.members, prometheus.GaugeValue, float64(count), mfs.GetName())
+	}
+}
+
+// isOutOfRange reports whether err was caused by a field score out of range.
+func isOutOfRange(err error) bool {
+	return errors.Is(err, zmultifield.ErrScoreOutOfRange)
+}
+
+// isRedisError reports whether err is an error reply from Redis or a failure to reach it, rather
+// than a validation or state error of the set.
+func isRedisError(err error) bool {
+	if errors.Is(err, redis.Nil) {
+		return false
+	}
+	var replyErr redis.Error
+	var netErr net.Error
+	return errors.As(err, &replyErr) || errors.As(err, &netErr) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,95 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Rohan-Muslekar/ZMultiField"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCollector_ObserveErrors(t *testing.T) {
+	c := NewCollector("test")
+
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	c.ObserveUpdate("board", time.Millisecond, nil)
+	c.ObserveUpdate("board", time.Millisecond, &zmultifield.ScoreOutOfRangeError{Field: "points", Value: big.NewInt(2048), Max: big.NewInt(1023)})
+	c.ObserveRead("board", "GetScores", time.Millisecond, fmt.Errorf("reading scores: %w", refused))
+	c.ObserveRead("board", "GetScoreForField", time.Millisecond, zmultifield.ErrFieldNotFound)
+	// Errors of the set itself are never Redis errors, whether or not they are known to the collector
+	c.ObserveRead("board", "GetScores", time.Millisecond, zmultifield.ErrFrozen)
+	c.ObserveUpdateOp("board", "UpdateIf", time.Millisecond, io.EOF)
+
+	if got := testutil.ToFloat64(c.outOfRange.WithLabelValues("board")); got != 1 {
+		t.Errorf("out of range errors = %v, expected 1", got)
+	}
+	if got := testutil.ToFloat64(c.redisErrors.WithLabelValues("board", "GetScores")); got != 1 {
+		t.Errorf("redis errors for GetScores = %v, expected 1", got)
+	}
+	if got := testutil.ToFloat64(c.redisErrors.WithLabelValues("board", "UpdateIf")); got != 1 {
+		t.Errorf("redis errors for UpdateIf = %v, expected 1", got)
+	}
+	if got := testutil.CollectAndCount(c.redisErrors); got != 2 {
+		t.Errorf("redis error series = %d, expected 2", got)
+	}
+	if got := testutil.CollectAndCount(c.updateLatency); got != 1 {
+		t.Errorf("update latency series = %d, expected 1", got)
+	}
+}
+
+func TestCollector_UpdateOp(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	c := NewCollector("test")
+	mfs, err := zmultifield.New(zmultifield.MultiFieldSetOptions{
+		Name:    "board",
+		Fields:  []zmultifield.Field{{Name: "points", Sort: zmultifield.Descending, MaxValue: 1000, UpdateType: zmultifield.Incremental}},
+		Client:  client,
+		Metrics: c,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	server.SetError("ERR injected")
+	if _, err := mfs.AddMember(context.Background(), "alice", map[string]float64{"points": 10}); err == nil {
+		t.Fatal("AddMember() succeeded, expected the injected error")
+	}
+	if got := testutil.ToFloat64(c.redisErrors.WithLabelValues("board", "AddMember")); got != 1 {
+		t.Errorf("redis errors for AddMember = %v, expected 1", got)
+	}
+}
+
+func TestCollector_CollectIsNotARead(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	c := NewCollector("test")
+	mfs, err := zmultifield.New(zmultifield.MultiFieldSetOptions{
+		Name:    "board",
+		Fields:  []zmultifield.Field{{Name: "points", Sort: zmultifield.Descending, MaxValue: 1000, UpdateType: zmultifield.Incremental}},
+		Client:  client,
+		Metrics: c,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := mfs.AddMember(context.Background(), "alice", map[string]float64{"points": 10}); err != nil {
+		t.Fatalf("AddMember() error = %v", err)
+	}
+	c.Track(mfs)
+
+	if got := testutil.CollectAndCount(c, "test_zmultifield_members"); got != 1 {
+		t.Errorf("member count series = %d, expected 1", got)
+	}
+	if got := testutil.CollectAndCount(c.readLatency); got != 0 {
+		t.Errorf("read latency series after a scrape = %d, expected none", got)
+	}
+}
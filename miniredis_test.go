@@ -0,0 +1,43 @@
+package zmultifield
+
+import (
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// newTestClient starts an in-memory Redis server for the duration of the test and returns a
+// client connected to it.
+func newTestClient(t *testing.T) (*redis.Client, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return client, server
+}
+
+// newTestSet creates a MultiFieldSet with a descending points field and an ascending deaths field
+// backed by an in-memory Redis server.
+func newTestSet(t *testing.T) *MultiFieldSet {
+	t.Helper()
+	return newTestSetWithOptions(t, MultiFieldSetOptions{})
+}
+
+// newTestSetWithOptions is like newTestSet but lets the test override options other than the
+// name, fields and client.
+func newTestSetWithOptions(t *testing.T, opts MultiFieldSetOptions) *MultiFieldSet {
+	t.Helper()
+	client, _ := newTestClient(t)
+	opts.Name = "test"
+	opts.Fields = []Field{
+		{Name: "points", Sort: Descending, MaxValue: 1000, UpdateType: Incremental},
+		{Name: "deaths", Sort: Ascending, MaxValue: 100, UpdateType: Incremental},
+	}
+	opts.Client = client
+	mfs, err := New(opts)
+	if err != nil {
+		t.Fatalf("Failed to create MultiFieldSet: %v", err)
+	}
+	return mfs
+}
@@ -1,6 +1,7 @@
 package zmultifield
 
 import (
+	"fmt"
 	"math"
 	"math/big"
 )
@@ -14,7 +15,15 @@ type multiField struct {
 	mask        *big.Int
 	isMain      bool
 	maxAbsolute *big.Int
-	multiplier  *big.Int // 1 for ascending, -1 for descending
+	multiplier  *big.Int    // 1 for ascending, -1 for descending
+	inverted    bool        // whether the raw value is stored as maxAbsolute minus the display value
+	offset      *big.Int    // MinValue, subtracted from display values before they are stored, or nil
+	leading     bool        // whether the field occupies the most significant bits
+	count       *multiField // hidden sample count of an Average field
+
+	// uint64 copies of the unshifted mask and max absolute value for the fast-path codec
+	mask64        uint64
+	maxAbsolute64 uint64
 }
 
 // newMultiField creates a new multiField from a Field definition.
@@ -30,9 +39,12 @@ func newMultiField(f Field) *multiField {
 		mf.bits = 53 // Max safe integer bits in JavaScript (we'll use the same limit)
 		mf.isMain = true
 	} else {
-		mf.bits = BitCount(f.MaxValue)
+		mf.bits = BitCount(f.MaxValue - f.MinValue)
 		mf.isMain = false
 	}
+	if f.MinValue != 0 {
+		mf.offset = big.NewInt(int64(f.MinValue))
+	}
 
 	// Set the mask
 	mf.mask = MaxBin(mf.bits)
@@ -47,6 +59,7 @@ func newMultiField(f Field) *multiField {
 
 	// Set multiplier based on sort order
 	if f.Sort == Descending {
+		mf.inverted = true
 		mf.multiplier = big.NewInt(-1)
 	} else {
 		mf.multiplier = big.NewInt(1)
@@ -55,14 +68,65 @@ func newMultiField(f Field) *multiField {
 	return mf
 }
 
-// defaultScore returns the default score for the field based on sort order.
+// defaultScore returns the default score for the field based on sort order. KeepMin fields
+// default to their largest value instead, so any update lowers them.
 func (mf *multiField) defaultScore() *big.Int {
-	if mf.Sort == Descending {
+	if mf.inverted != (mf.UpdateType == KeepMin) {
 		return new(big.Int).Set(mf.maxAbsolute)
 	}
 	return big.NewInt(0)
 }
 
+// toDisplay converts a raw field value into the value shown to callers, inverting descending
+// fields and adding MinValue.
+func (mf *multiField) toDisplay(raw *big.Int) *big.Int {
+	value := new(big.Int).Set(raw)
+	if mf.inverted {
+		value.Sub(mf.maxAbsolute, raw)
+	}
+	if mf.offset != nil {
+		value.Add(value, mf.offset)
+	}
+	return value
+}
+
+// toRaw converts a display value into the raw value stored in the zscore. The result is outside
+// [0, maxAbsolute] if the value is out of the field's range.
+func (mf *multiField) toRaw(display *big.Int) *big.Int {
+	raw := new(big.Int).Set(display)
+	if mf.offset != nil {
+		raw.Sub(raw, mf.offset)
+	}
+	if mf.inverted {
+		raw.Sub(mf.maxAbsolute, raw)
+	}
+	return raw
+}
+
+// minDisplay returns the smallest display value of the field, its MinValue.
+func (mf *multiField) minDisplay() *big.Int {
+	return big.NewInt(int64(mf.MinValue))
+}
+
+// maxDisplay returns the largest display value the field's bits can hold.
+func (mf *multiField) maxDisplay() *big.Int {
+	return new(big.Int).Add(mf.maxAbsolute, mf.minDisplay())
+}
+
+// validateMinValue checks that MinValue is an integer below MaxValue.
+func validateMinValue(f Field) error {
+	if f.MinValue == 0 {
+		return nil
+	}
+	if math.IsInf(f.MinValue, 0) || math.IsNaN(f.MinValue) || f.MinValue != math.Trunc(f.MinValue) || math.Abs(f.MinValue) >= 1<<exactBits {
+		return fmt.Errorf("field %s: MinValue must be an integer", f.Name)
+	}
+	if !(f.MinValue < f.MaxValue) {
+		return fmt.Errorf("field %s: MinValue must be below MaxValue", f.Name)
+	}
+	return nil
+}
+
 // updateTypeName returns the string representation of the update type.
 func (mf *multiField) updateTypeName() string {
 	switch mf.UpdateType {
@@ -70,6 +134,14 @@ func (mf *multiField) updateTypeName() string {
 		return "INCREMENTAL"
 	case Replace:
 		return "REPLACE"
+	case KeepMax:
+		return "KEEP_MAX"
+	case KeepMin:
+		return "KEEP_MIN"
+	case BitOr:
+		return "BIT_OR"
+	case Average:
+		return "AVERAGE"
 	default:
 		return "UNKNOWN"
 	}
@@ -95,6 +167,9 @@ func (mf *multiField) setIndex(position int, shiftValue uint64) {
 		mf.bits = 53 - mf.shiftValue
 		mf.maxAbsolute = MaxBin(mf.bits)
 	}
+
+	mf.mask64 = MaxBin(mf.bits).Uint64()
+	mf.maxAbsolute64 = mf.maxAbsolute.Uint64()
 }
 
 // getInfo returns the field information.
@@ -104,6 +179,7 @@ func (mf *multiField) getInfo() FieldInfo {
 		Sort:        mf.Sort,
 		UpdateType:  mf.updateTypeName(),
 		MaxValue:    mf.MaxValue,
+		MinValue:    mf.MinValue,
 		Bits:        mf.bits,
 		ShiftValue:  mf.shiftValue,
 		Mask:        mf.mask,
@@ -3,18 +3,59 @@ package zmultifield
 import (
 	"context"
 	"errors"
-	"fmt"
 	"math/big"
+	"time"
 
 	"github.com/go-redis/redis/v8"
 )
 
 // MultiFieldSet manages a Redis sorted set with multiple fields packed into a single score.
 type MultiFieldSet struct {
-	fields        []*multiField
+	*Codec
+
 	name          string
+	namespace     string
+	baseKey       string
+	keyFunc       func(namespace, name string) string
+	key           string
+	keyBuilder    KeyBuilder
 	client        redis.UniversalClient
-	defaultZScore *big.Int
+	readClient    redis.UniversalClient
+	retryPolicy   *RetryPolicy
+	notifications *NotificationOptions
+	eventSink     EventSink
+	history       *HistoryOptions
+	updatedAt     *multiField
+	salt          *multiField
+	saltSecret    []byte
+	hooks         hooks
+	metrics       MetricsRecorder
+	logger        Logger
+	slowThreshold time.Duration
+
+	readPreference       ReadPreference
+	maintainFieldIndexes bool
+	rankThresholds       []int64
+	maxMembers           int64
+	updateOnlyExisting   bool
+	updateStrategy       UpdateStrategy
+	optimisticRetries    int
+	writeQueue           *writeQueue
+	cache                *readCache
+	guard                *GuardOptions
+	hideMembers          bool
+	dimensions           []Dimension
+	participation        *ParticipationOptions
+	trackActivity        bool
+	freezable            bool
+	quarantinable        bool
+	audit                bool
+	tiers                []Tier
+	divisions            *Divisions
+	idempotencyTTL       time.Duration
+	lockTTL              time.Duration
+	computed             []ComputedField
+	derived              derivedSets
 }
 
 // MultiFieldSetOptions defines options for creating a new MultiFieldSet.
@@ -22,6 +63,113 @@ type MultiFieldSetOptions struct {
 	Name   string
 	Fields []Field
 	Client redis.UniversalClient
+	// Metrics optionally receives latency and error observations.
+	Metrics MetricsRecorder
+	// Logger optionally receives a warning for every operation slower than SlowThreshold, with the
+	// operation, the set's name and number of members, and the latency.
+	Logger Logger
+	// SlowThreshold is the latency above which operations are logged. Defaults to 100ms.
+	SlowThreshold time.Duration
+	// MaintainFieldIndexes additionally stores each field's raw value in its own sorted set on every
+	// update, enabling exact per-field ranks and range queries at the cost of extra writes.
+	MaintainFieldIndexes bool
+	// KeyBuilder derives the Redis keys used by the set. Defaults to DefaultKeyBuilder. When it is
+	// set explicitly, or the client is a cluster client, New verifies that all keys share a slot.
+	KeyBuilder KeyBuilder
+	// Namespace isolates the set's keys, e.g. per tenant or environment.
+	Namespace string
+	// KeyFunc combines Namespace and Name into the base key passed to KeyBuilder.
+	// Defaults to DefaultKeyFunc.
+	KeyFunc func(namespace, name string) string
+	// ReadClient optionally serves read-only queries, typically a client connected to replicas.
+	ReadClient redis.UniversalClient
+	// ReadPreference selects which client serves read-only queries. Defaults to ReadPrimary.
+	ReadPreference ReadPreference
+	// RetryPolicy optionally retries Redis calls that fail with transient errors.
+	RetryPolicy *RetryPolicy
+	// Notifications optionally publishes change notifications to a Redis channel after updates.
+	Notifications *NotificationOptions
+	// EventSink optionally receives an event for every update, rank change and season milestone,
+	// e.g. to forward them to Kafka or NATS. Updates then look up ranks in the same atomic write.
+	EventSink EventSink
+	// History optionally records every member's field values after each update.
+	History *HistoryOptions
+	// RankThresholds enables OnRankChanged hooks for members crossing the given ranks, e.g. 100 for
+	// entering or leaving the top 100. Updates then look up ranks in the same atomic write.
+	RankThresholds []int64
+	// UpdateOnlyExisting makes updates fail with ErrMemberNotFound instead of adding new members.
+	// Members can still be added with InitializeMember.
+	UpdateOnlyExisting bool
+	// MaxMembers caps the size of the set: updates that grow it beyond MaxMembers evict the worst
+	// members in the same atomic write. Zero means no limit.
+	MaxMembers int64
+	// Layout controls how fields are packed into the score. The zero value suits new sets; set it
+	// to attach to sets written by other tooling, such as the JavaScript ZMultiField library.
+	Layout Layout
+	// UpdateStrategy selects how IncreaseScore updates members. Defaults to UpdateScripted.
+	UpdateStrategy UpdateStrategy
+	// OptimisticRetries is the number of times UpdateOptimistic, UpdateIf and updates evaluating
+	// triggers or updating KeepMax, KeepMin and BitOr fields retry an update that conflicted with
+	// a concurrent write before failing with ErrUpdateConflict. Defaults to 10.
+	OptimisticRetries int
+	// TrackUpdatedAt adds an updatedAt field holding the time of the last write in epoch minutes.
+	// It is packed below all other fields and takes 26 bits of the score.
+	TrackUpdatedAt bool
+	// WriteQueue optionally queues updates that fail with transient errors for replay by
+	// ReplayQueued instead of failing them.
+	WriteQueue *WriteQueueOptions
+	// Cache optionally caches GetRank, GetScores and GetTopMembers results in process.
+	Cache *CacheOptions
+	// Guard optionally limits the size and rate of updates.
+	Guard *GuardOptions
+	// HideMembers enables Hide and Unhide. GetRank, GetMembers and GetTopMembers then read through
+	// scripts that skip hidden members.
+	HideMembers bool
+	// Salt adds a salt field below all other fields, holding deterministic noise derived from each
+	// member's name, so members with equal scores are ordered unpredictably and exact values are
+	// harder to infer from ranks. Reads leave the salt out of returned scores.
+	Salt *SaltOptions
+	// Dimensions additionally keeps one sorted set per value of each dimension, e.g. per country,
+	// written in the same atomic step as the global set by IncreaseScore and its variants, UpdateIf,
+	// AddMember, InitializeMember and ResetMember. Other writes, such as BulkLoad, ResetFields,
+	// decay, pops, transfers and merges, only change the global set until the member's next update.
+	// Read them with InDimension.
+	Dimensions []Dimension
+	// Participation optionally counts the unique members updated per day, week or month in
+	// HyperLogLogs, read with UniqueParticipants. Updates count members like they record history.
+	Participation *ParticipationOptions
+	// TrackActivity keeps an index of the time of every member's last update, read with
+	// GetActiveMembers and GetIdleMembers. Updates maintain it like they record history.
+	TrackActivity bool
+	// Freezable enables Freeze and Unfreeze. Every write then checks the frozen flag, so updates
+	// always go through a script or a transaction watching the flag.
+	Freezable bool
+	// Quarantinable enables Quarantine and Unquarantine. Updates then check that the member isn't
+	// quarantined before writing it, which costs a round trip.
+	Quarantinable bool
+	// Audit records every write to the main set in an audit stream, atomically with the write, so
+	// the set can be rebuilt or caught up from it with Replay. Writes then always go through a
+	// script or a transaction appending to the stream, which grows until it is trimmed.
+	Audit bool
+	// Tiers splits the members into the brackets of a ranked ladder, best first, read with GetTier.
+	// Members are in the first tier they qualify for. Percentile tiers move with ComputeTiers.
+	// Updates then read the cutoffs to report members moving to another tier to OnTierChanged hooks
+	// and the EventSink, which costs a round trip when either is set.
+	Tiers []Tier
+	// Divisions partitions the members into divisions of a fixed size, read and rotated through
+	// the Divisions method. Divisions are kept in the DivisionDimension, so they are written like
+	// Dimensions.
+	Divisions *DivisionOptions
+	// IdempotencyTTL is how long the idempotency keys of updates made with WithIdempotencyKey are
+	// remembered. Defaults to 24 hours.
+	IdempotencyTTL time.Duration
+	// LockTTL is how long the lock taken by Rebuild, Snapshot, DeleteSnapshot and Registry.Migrate
+	// outlives a holder that crashed. Holders extend it while they run. Defaults to 30 seconds.
+	LockTTL time.Duration
+	// ComputedFields lists virtual fields computed from the stored ones at read time, such as
+	// Ratio("kdRatio", "kills", "deaths"). They are returned after the stored fields and take no
+	// bits in the score.
+	ComputedFields []ComputedField
 }
 
 // New creates a new MultiFieldSet instance.
@@ -38,32 +186,162 @@ func New(opts MultiFieldSetOptions) (*MultiFieldSet, error) {
 		return nil, errors.New("Redis client is required")
 	}
 
-	// Create multiFields from Fields
-	multiFields := make([]*multiField, len(opts.Fields))
-	for i, f := range opts.Fields {
-		multiFields[i] = newMultiField(f)
+	if opts.ReadPreference != ReadPrimary && opts.ReadClient == nil {
+		return nil, errors.New("read client is required for replica reads")
+	}
+
+	if opts.MaxMembers < 0 {
+		return nil, errors.New("max members must not be negative")
+	}
+
+	fields, err := withAverageCounts(opts.Fields, opts.Layout)
+	if err != nil {
+		return nil, err
+	}
+	if opts.TrackUpdatedAt {
+		var err error
+		if fields, err = withUpdatedAt(fields, opts.Layout); err != nil {
+			return nil, err
+		}
 	}
 
-	// Calculate total shifts and set indices
-	var totalShifts uint64 = 0
-	for i := len(multiFields) - 1; i >= 0; i-- {
-		multiFields[i].setIndex(i, totalShifts)
-		totalShifts += multiFields[i].bits
+	if opts.Salt != nil {
+		var err error
+		if fields, err = withSalt(fields, opts.Salt, opts.Layout); err != nil {
+			return nil, err
+		}
+	}
+
+	codec, err := NewCodecWithLayout(fields, opts.Layout)
+	if err != nil {
+		return nil, err
 	}
 
 	// Initialize MultiFieldSet
 	mfs := &MultiFieldSet{
-		fields: multiFields,
+		Codec:  codec,
 		name:   opts.Name,
 		client: opts.Client,
+
+		retryPolicy:          opts.RetryPolicy,
+		notifications:        opts.Notifications,
+		eventSink:            opts.EventSink,
+		history:              opts.History,
+		rankThresholds:       opts.RankThresholds,
+		maxMembers:           opts.MaxMembers,
+		updateOnlyExisting:   opts.UpdateOnlyExisting,
+		updateStrategy:       opts.UpdateStrategy,
+		optimisticRetries:    opts.OptimisticRetries,
+		readClient:           opts.ReadClient,
+		readPreference:       opts.ReadPreference,
+		maintainFieldIndexes: opts.MaintainFieldIndexes,
+		hideMembers:          opts.HideMembers,
+		trackActivity:        opts.TrackActivity,
+		freezable:            opts.Freezable,
+		quarantinable:        opts.Quarantinable,
+		audit:                opts.Audit,
+		lockTTL:              opts.LockTTL,
+		idempotencyTTL:       opts.IdempotencyTTL,
+	}
+
+	if opts.WriteQueue != nil {
+		if mfs.writeQueue, err = newWriteQueue(opts.WriteQueue); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Cache != nil {
+		mfs.cache = newReadCache(opts.Cache)
+	}
+	if opts.Guard != nil {
+		if err := mfs.validateGuard(opts.Guard); err != nil {
+			return nil, err
+		}
+		mfs.guard = opts.Guard
+	}
+	dimensions := opts.Dimensions
+	if opts.Divisions != nil {
+		if err := opts.Divisions.validate(); err != nil {
+			return nil, err
+		}
+		mfs.divisions = &Divisions{mfs: mfs, opts: *opts.Divisions}
+		dimensions = append(dimensions[:len(dimensions):len(dimensions)], mfs.divisions.divisionDimension())
+	}
+	if err := validateDimensions(dimensions); err != nil {
+		return nil, err
+	}
+	mfs.dimensions = dimensions
+	if opts.Participation != nil {
+		if err := validateParticipation(opts.Participation); err != nil {
+			return nil, err
+		}
+		mfs.participation = opts.Participation
+	}
+	if mfs.quarantinable {
+		mfs.BeforeUpdate(mfs.checkQuarantined)
+	}
+
+	// Derive keys
+	keyFunc := opts.KeyFunc
+	if keyFunc == nil {
+		keyFunc = DefaultKeyFunc
+	}
+	mfs.namespace = opts.Namespace
+	mfs.keyFunc = keyFunc
+	mfs.baseKey = keyFunc(opts.Namespace, opts.Name)
+	mfs.keyBuilder = opts.KeyBuilder
+	if mfs.keyBuilder == nil {
+		mfs.keyBuilder = DefaultKeyBuilder{}
+	}
+	mfs.key = mfs.keyBuilder.Key(mfs.baseKey)
+	if opts.KeyBuilder != nil || isClusterClient(opts.Client) {
+		if err := mfs.validateKeySlots(); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.TrackUpdatedAt {
+		mfs.updatedAt = codec.GetFieldByName(UpdatedAtField)
+	}
+	for _, field := range codec.fields {
+		if field.UpdateType == Average {
+			field.count = codec.GetFieldByName(averageCountName(field.Name))
+			codec.maskField(field.count)
+		}
+	}
+	if opts.Salt != nil {
+		mfs.salt = codec.GetFieldByName(SaltField)
+		mfs.saltSecret = opts.Salt.Secret
+		codec.maskField(mfs.salt)
+	}
+	if err := validateComputedFields(codec, opts.ComputedFields); err != nil {
+		return nil, err
+	}
+	mfs.computed = opts.ComputedFields
+	if err := validateTiers(codec, opts.Tiers); err != nil {
+		return nil, err
+	}
+	mfs.tiers = opts.Tiers
+
+	if mfs.optimisticRetries <= 0 {
+		mfs.optimisticRetries = defaultOptimisticRetries
+	}
+	if mfs.lockTTL <= 0 {
+		mfs.lockTTL = defaultLockTTL
+	}
+	if mfs.idempotencyTTL <= 0 {
+		mfs.idempotencyTTL = defaultIdempotencyTTL
+	}
+
+	mfs.logger, mfs.slowThreshold = opts.Logger, opts.SlowThreshold
+	if mfs.slowThreshold <= 0 {
+		mfs.slowThreshold = defaultSlowThreshold
 	}
 
-	// Calculate default zscore
-	defaultScores := make([]*big.Int, len(multiFields))
-	for i, field := range multiFields {
-		defaultScores[i] = field.defaultScore()
+	if opts.Metrics != nil {
+		mfs.metrics = opts.Metrics
+	} else {
+		mfs.metrics = noopMetrics{}
 	}
-	mfs.defaultZScore = mfs.scoresToZScore(defaultScores)
 
 	return mfs, nil
 }
@@ -73,253 +351,476 @@ func (mfs *MultiFieldSet) GetName() string {
 	return mfs.name
 }
 
-// GetFieldsInfo returns information about all fields.
-func (mfs *MultiFieldSet) GetFieldsInfo() []FieldInfo {
-	info := make([]FieldInfo, len(mfs.fields))
-	for i, field := range mfs.fields {
-		info[i] = field.getInfo()
-	}
-	return info
+// GetNamespace returns the namespace of the sorted set.
+func (mfs *MultiFieldSet) GetNamespace() string {
+	return mfs.namespace
 }
 
-// scoresToZScore combines individual field scores into a single zscore.
-func (mfs *MultiFieldSet) scoresToZScore(scores []*big.Int) *big.Int {
-	zscore := big.NewInt(0)
-	for i, score := range scores {
-		// Shift the score by the field's shift value
-		shiftedScore := new(big.Int).Lsh(score, uint(mfs.fields[i].shiftValue))
-		// Add to the zscore
-		zscore.Add(zscore, shiftedScore)
-	}
-	return zscore
+// GetKey returns the Redis key of the sorted set.
+func (mfs *MultiFieldSet) GetKey() string {
+	return mfs.key
 }
 
-// extractFieldScore extracts a field's score from a zscore.
-func (mfs *MultiFieldSet) extractFieldScore(field *multiField, zscore *big.Int) *big.Int {
-	if zscore == nil {
-		return field.defaultScore()
+// IncreaseScore increases the score for specified fields of a member. Values must be integers:
+// fractional or non-finite values fail with ErrInvalidValue rather than being truncated. Use
+// IncreaseScoreInt to pass int64 values directly.
+func (mfs *MultiFieldSet) IncreaseScore(ctx context.Context, fields map[string]float64, member string) (_ *big.Int, err error) {
+	defer mfs.observeUpdate("IncreaseScore", time.Now(), &err)
+
+	result, err := mfs.increaseScoreOrQueue(ctx, fields, member)
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "IncreaseScore", member, err)
 	}
+	return result.ZScore, nil
+}
 
-	// Apply mask to isolate the field bits
-	fieldScore := new(big.Int).And(zscore, field.mask)
-	// Shift right to get the actual value
-	fieldScore.Rsh(fieldScore, uint(field.shiftValue))
-	return fieldScore
+// increaseScore applies the field updates to a member and writes the new zscore to Redis. The
+// member's ranks are only looked up if withRanks is set or an enabled feature needs them;
+// otherwise they are -1.
+func (mfs *MultiFieldSet) increaseScore(ctx context.Context, fields map[string]float64, member string, withRanks bool) (*UpdateResult, error) {
+	return mfs.increaseScoreWith(ctx, fields, member, withRanks, mfs.exactUpdates() || mfs.keepsValues(fields))
 }
 
-// getFieldScores extracts all field scores from a zscore.
-func (mfs *MultiFieldSet) getFieldScores(zscore *big.Int) []*big.Int {
-	scores := make([]*big.Int, len(mfs.fields))
-	for i, field := range mfs.fields {
-		scores[i] = mfs.extractFieldScore(field, zscore)
+// keepsValues reports whether fields updates a KeepMax, KeepMin, BitOr or Average field. Such
+// updates are only written if the member didn't change since it was read, so a concurrent update
+// can't replace a larger maximum, a wider bitmask or an average with the stale one the update was
+// computed from.
+func (mfs *MultiFieldSet) keepsValues(fields map[string]float64) bool {
+	for name := range fields {
+		if field := mfs.GetFieldByName(name); field != nil && field.UpdateType >= KeepMax {
+			return true
+		}
 	}
-	return scores
+	return false
 }
 
-// GetFieldByName returns a field by name or nil if not found.
-func (mfs *MultiFieldSet) GetFieldByName(name string) *multiField {
-	for _, field := range mfs.fields {
-		if field.Name == name {
-			return field
+// increaseScoreWith is increaseScore, but with conditional set the scripted strategy only writes
+// the member if it still holds the zscore the update was computed from, retrying otherwise, so
+// concurrent updates are never lost. Counters are incremented with ZINCRBY, which never loses
+// updates either.
+func (mfs *MultiFieldSet) increaseScoreWith(ctx context.Context, fields map[string]float64, member string, withRanks, conditional bool) (*UpdateResult, error) {
+	if mfs.updateStrategy == UpdateOptimistic {
+		return mfs.increaseScoreOptimistic(ctx, fields, member, withRanks)
+	}
+	if idempotencyKey(ctx) == "" && mfs.fastIncrement(withRanks) {
+		return mfs.incrementFast(ctx, fields, member)
+	}
+
+	for attempt := 0; attempt <= mfs.optimisticRetries; attempt++ {
+		// Get current scores, missing members start from the default scores
+		currentZScore, err := mfs.memberZScore(ctx, member)
+		if err != nil {
+			return nil, err
+		}
+		mode := writeAlways
+		if mfs.updateOnlyExisting {
+			if currentZScore == nil {
+				return nil, ErrMemberNotFound
+			}
+			mode = writeIfExists
+		}
+		if conditional {
+			// The member must not change between the read and the write, e.g. so triggers see
+			// every value exactly once
+			mode = writeIfAbsent
+			if currentZScore != nil {
+				mode = writeIfZScore(currentZScore)
+			}
+		}
+		scores, finalZScore, event, err := mfs.buildUpdate(member, currentZScore, fields)
+		if err != nil {
+			return nil, err
+		}
+		result, err := mfs.commitUpdate(ctx, event, scores, finalZScore, mode, withRanks)
+		if err != errMemberChanged {
+			return result, err
 		}
 	}
-	return nil
+	return nil, ErrUpdateConflict
 }
 
-// IncreaseScore increases the score for specified fields of a member.
-func (mfs *MultiFieldSet) IncreaseScore(ctx context.Context, fields map[string]float64, member string) (*big.Int, error) {
-	// Get current scores
-	currentZScore, err := mfs.client.ZScore(ctx, mfs.name, member).Result()
-	if err == redis.Nil {
-		// Member doesn't exist, use default scores
-		scores := make([]*big.Int, len(mfs.fields))
-		for i, field := range mfs.fields {
-			scores[i] = field.defaultScore()
-		}
+// buildUpdate applies the field updates to a member's current zscore, which is nil for a new
+// member, and returns the new raw scores and zscore along with the event describing the update.
+func (mfs *MultiFieldSet) buildUpdate(member string, currentZScore *big.Int, fields map[string]float64) ([]*big.Int, *big.Int, *UpdateEvent, error) {
+	scores := mfs.getFieldScores(currentZScore)
 
-		// Update scores
-		for fieldName, incValue := range fields {
-			field := mfs.GetFieldByName(fieldName)
-			if field == nil {
-				return nil, fmt.Errorf("field %s not found", fieldName)
-			}
+	// Update scores
+	if err := mfs.applyUpdates(scores, fields); err != nil {
+		return nil, nil, nil, err
+	}
+	mfs.stampUpdatedAt(scores)
+	mfs.stampSalt(scores, member)
 
-			inc := new(big.Int).SetInt64(int64(incValue))
-			inc.Mul(inc, field.multiplier)
+	// Calculate new zscore
+	finalZScore := mfs.scoresToZScore(scores)
 
-			if field.UpdateType == Incremental {
-				scores[field.position].Add(scores[field.position], inc)
-			} else if field.UpdateType == Replace {
-				scores[field.position] = new(big.Int).Add(field.defaultScore(), inc)
-			} else {
-				return nil, errors.New("unknown update type")
-			}
+	event := &UpdateEvent{
+		Set:       mfs.name,
+		Member:    member,
+		Deltas:    fields,
+		OldScores: mfs.zscoreToAllFieldScores(currentZScore),
+		NewScores: mfs.zscoreToAllFieldScores(finalZScore),
+	}
+	if err := mfs.checkIncreases(event); err != nil {
+		return nil, nil, nil, err
+	}
+	return scores, finalZScore, event, nil
+}
 
-			// Check range
-			if scores[field.position].Sign() < 0 || scores[field.position].Cmp(field.maxAbsolute) > 0 {
-				return nil, fmt.Errorf("score %v out of range for field %s", scores[field.position], field.Name)
-			}
+// commitUpdate runs the before-update hooks, writes the member according to mode and then runs the
+// after-update hooks, rank-changed hooks, notifications and history.
+func (mfs *MultiFieldSet) commitUpdate(ctx context.Context, event *UpdateEvent, scores []*big.Int, finalZScore *big.Int, mode writeMode, withRanks bool) (*UpdateResult, error) {
+	if err := mfs.runBeforeUpdate(ctx, event); err != nil {
+		return nil, err
+	}
+
+	// Update in Redis
+	member := event.Member
+	var err error
+	result := &UpdateResult{ZScore: finalZScore, OldRank: -1, NewRank: -1}
+	written, limited := true, false
+	if withRanks || mfs.needsRanks() || mfs.limitsUpdates() {
+		var w writeResult
+		w, err = mfs.writeMemberWithRanks(ctx, member, scores, finalZScore, mode, mfs.limitsUpdates())
+		result.OldRank, result.NewRank, written, limited = w.oldRank, w.newRank, w.written, w.limited
+		if err == nil && w.duplicate {
+			return nil, ErrDuplicateUpdate
 		}
+	} else {
+		written, err = mfs.writeMember(ctx, member, scores, finalZScore, mode)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if limited {
+		return nil, mfs.rateLimitError(member)
+	}
+	if !written {
+		if mode == writeIfExists {
+			// The member was removed after it was read
+			return nil, ErrMemberNotFound
+		}
+		return nil, errMemberChanged
+	}
 
-		// Calculate new zscore
-		finalZScore := mfs.scoresToZScore(scores)
+	mfs.finishUpdate(ctx, event, result)
+	return result, nil
+}
 
-		// Update in Redis
-		_, err = mfs.client.ZAdd(ctx, mfs.name, &redis.Z{
-			Score:  float64(finalZScore.Int64()),
-			Member: member,
-		}).Result()
+// finishUpdate runs the after-update hooks, rank-changed hooks, triggers, notifications, events,
+// tier changes, history, participation counters and activity index for an update that has been
+// written.
+func (mfs *MultiFieldSet) finishUpdate(ctx context.Context, event *UpdateEvent, result *UpdateResult) {
+	mfs.runAfterUpdate(ctx, event)
+	mfs.runRankChanged(ctx, event.Member, result.OldRank, result.NewRank)
+	mfs.runTriggers(ctx, event)
+	mfs.notify(ctx, event, result.OldRank, result.NewRank)
+	mfs.emitUpdate(ctx, event, result)
+	mfs.updateTier(ctx, event, result)
+	mfs.recordHistory(ctx, event)
+	mfs.countParticipant(ctx, event.Member)
+	mfs.recordActivity(ctx, event.Member)
+	mfs.updateDerived(ctx, event.Member, result.ZScore)
+}
 
-		if err != nil {
-			return nil, err
+// writeMember stores a member's zscore according to mode, keeping the per-field indexes in sync
+// when they are maintained, writing its dimension sets and enforcing MaxMembers. It reports whether the member was written.
+func (mfs *MultiFieldSet) writeMember(ctx context.Context, member string, scores []*big.Int, zscore *big.Int, mode writeMode) (bool, error) {
+	if mfs.maintainFieldIndexes || mfs.maxMembers > 0 || mode != writeAlways || len(mfs.dimensions) > 0 || mfs.freezable ||
+		mfs.audit || idempotencyKey(ctx) != "" {
+		w, err := mfs.writeMemberWithRanks(ctx, member, scores, zscore, mode, false)
+		if err == nil && w.duplicate {
+			err = ErrDuplicateUpdate
 		}
+		return w.written, err
+	}
 
-		return finalZScore, nil
+	err := mfs.write(ctx, func(client redis.UniversalClient) error {
+		return client.ZAdd(ctx, mfs.key, &redis.Z{
+			Score:  float64(zscore.Int64()),
+			Member: member,
+		}).Err()
+	})
+	return err == nil, err
+}
+
+// memberZScore returns the current zscore of a member, or nil if the member doesn't exist.
+func (mfs *MultiFieldSet) memberZScore(ctx context.Context, member string) (*big.Int, error) {
+	var zscore float64
+	err := mfs.primary(ctx, func(client redis.UniversalClient) error {
+		var err error
+		zscore, err = client.ZScore(ctx, mfs.key, member).Result()
+		return err
+	})
+	if err == redis.Nil {
+		return nil, nil
 	} else if err != nil {
 		return nil, err
 	}
+	return mfs.zscoreOf(member, zscore)
+}
 
-	// Member exists, update scores
-	currentBigZScore := new(big.Int).SetInt64(int64(currentZScore))
-	scores := mfs.getFieldScores(currentBigZScore)
-
-	// Update scores
+// applyUpdates applies field updates, according to each field's UpdateType, to the raw field
+// scores in place.
+func (mfs *MultiFieldSet) applyUpdates(scores []*big.Int, fields map[string]float64) error {
 	for fieldName, incValue := range fields {
 		field := mfs.GetFieldByName(fieldName)
 		if field == nil {
-			return nil, fmt.Errorf("field %s not found", fieldName)
+			return fieldNotFoundError(fieldName)
 		}
 
-		inc := new(big.Int).SetInt64(int64(incValue))
-		inc.Mul(inc, field.multiplier)
-
-		if field.UpdateType == Incremental {
-			scores[field.position].Add(scores[field.position], inc)
-		} else if field.UpdateType == Replace {
-			scores[field.position] = new(big.Int).Add(field.defaultScore(), inc)
-		} else {
-			return nil, errors.New("unknown update type")
+		delta, err := toInt64(fieldName, incValue)
+		if err != nil {
+			return err
+		}
+		if field.UpdateType == Average {
+			if err := field.addSample(scores, delta); err != nil {
+				return err
+			}
+			continue
+		}
+		mode, operand, err := field.rawUpdate(delta)
+		if err != nil {
+			return err
 		}
+		scores[field.position] = applyRaw(mode, scores[field.position], operand)
 
 		// Check range
 		if scores[field.position].Sign() < 0 || scores[field.position].Cmp(field.maxAbsolute) > 0 {
-			return nil, fmt.Errorf("score %v out of range for field %s", scores[field.position], field.Name)
+			return outOfRangeError(field, scores[field.position])
 		}
 	}
+	return nil
+}
 
-	// Calculate new zscore
-	finalZScore := mfs.scoresToZScore(scores)
-
-	// Update in Redis
-	_, err = mfs.client.ZAdd(ctx, mfs.name, &redis.Z{
-		Score:  float64(finalZScore.Int64()),
-		Member: member,
-	}).Result()
+// GetRank returns the rank of a member in the sorted set, or ErrMemberNotFound if the member is not in the set.
+// With InDimension it returns the member's rank within that dimension set, bypassing the cache.
+// WithConsistency also bypasses the cache.
+func (mfs *MultiFieldSet) GetRank(ctx context.Context, member string, opts ...ReadOption) (_ int64, err error) {
+	defer mfs.observeRead("GetRank", time.Now(), &err)
 
-	if err != nil {
-		return nil, err
+	o := newReadOptions(opts)
+	ctx = o.context(ctx)
+	if o.dimension != "" {
+		return mfs.getDimensionRank(ctx, o, member)
 	}
+	if cached, ok := mfs.cache.get(rankCacheKey(member)); ok && o.readPreference == nil {
+		return cached.(int64), nil
+	}
+	gen := mfs.cache.generation()
 
-	return finalZScore, nil
-}
-
-// GetRank returns the rank of a member in the sorted set.
-func (mfs *MultiFieldSet) GetRank(ctx context.Context, member string) (int64, error) {
-	return mfs.client.ZRank(ctx, mfs.name, member).Result()
+	var rank int64
+	err = mfs.read(ctx, func(client redis.UniversalClient) error {
+		if mfs.hideMembers {
+			rank, err = mfs.visibleRank(ctx, client, mfs.key, member)
+		} else {
+			rank, err = client.ZRank(ctx, mfs.key, member).Result()
+		}
+		return err
+	})
+	if err == redis.Nil {
+		return 0, ErrMemberNotFound
+	}
+	if err == nil {
+		mfs.cache.put(rankCacheKey(member), rank, gen)
+	}
+	return rank, err
 }
 
 // GetScores returns all field scores for a member.
-func (mfs *MultiFieldSet) GetScores(ctx context.Context, member string) ([]fieldScore, error) {
-	zscoreStr, err := mfs.client.ZScore(ctx, mfs.name, member).Result()
+func (mfs *MultiFieldSet) GetScores(ctx context.Context, member string) (_ []FieldScore, err error) {
+	defer mfs.observeRead("GetScores", time.Now(), &err)
+
+	if cached, ok := mfs.cache.get(scoresCacheKey(member)); ok {
+		return copyFieldScores(cached.([]FieldScore)), nil
+	}
+	gen := mfs.cache.generation()
+
+	var zscoreStr float64
+	err = mfs.read(ctx, func(client redis.UniversalClient) error {
+		zscoreStr, err = client.ZScore(ctx, mfs.key, member).Result()
+		return err
+	})
 	if err == redis.Nil {
 		// Member doesn't exist, return default scores
-		scores := make([]fieldScore, len(mfs.fields))
-		for i, field := range mfs.fields {
-			scores[i] = fieldScore{
-				Name:  field.Name,
-				Score: field.defaultScore(),
-			}
-		}
-		return scores, nil
+		return mfs.withComputed(mfs.zscoreToAllFieldScores(nil)), nil
 	} else if err != nil {
-		return nil, err
+		return nil, mfs.runOnError(ctx, "GetScores", member, err)
 	}
 
-	zscore := new(big.Int).SetInt64(int64(zscoreStr))
-	return mfs.zscoreToAllFieldScores(zscore), nil
-}
-
-// zscoreToAllFieldScores converts a zscore to a slice of field scores.
-func (mfs *MultiFieldSet) zscoreToAllFieldScores(zscore *big.Int) []fieldScore {
-	scores := make([]fieldScore, len(mfs.fields))
-	for i, field := range mfs.fields {
-		fieldVal := mfs.extractFieldScore(field, zscore)
-
-		// Reverse calculation for descending fields for display
-		if field.Sort == Descending {
-			fieldVal = new(big.Int).Sub(field.maxAbsolute, fieldVal)
-		}
-
-		scores[i] = fieldScore{
-			Name:  field.Name,
-			Score: fieldVal,
-		}
+	zscore, err := mfs.zscoreOf(member, zscoreStr)
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "GetScores", member, err)
 	}
-	return scores
+	scores := mfs.withComputed(mfs.zscoreToAllFieldScores(zscore))
+	mfs.cache.put(scoresCacheKey(member), copyFieldScores(scores), gen)
+	return scores, nil
 }
 
 // GetScoreForField returns the score for a specific field of a member.
-func (mfs *MultiFieldSet) GetScoreForField(ctx context.Context, fieldName string, member string) (*big.Int, error) {
+func (mfs *MultiFieldSet) GetScoreForField(ctx context.Context, fieldName string, member string) (_ *big.Int, err error) {
+	defer mfs.observeRead("GetScoreForField", time.Now(), &err)
+
 	field := mfs.GetFieldByName(fieldName)
 	if field == nil {
-		return nil, fmt.Errorf("field %s not found", fieldName)
+		return nil, fieldNotFoundError(fieldName)
 	}
 
-	zscoreStr, err := mfs.client.ZScore(ctx, mfs.name, member).Result()
+	var zscoreStr float64
+	err = mfs.read(ctx, func(client redis.UniversalClient) error {
+		zscoreStr, err = client.ZScore(ctx, mfs.key, member).Result()
+		return err
+	})
 	if err == redis.Nil {
 		// Member doesn't exist, return default score
-		return field.defaultScore(), nil
+		return field.toDisplay(field.defaultScore()), nil
 	} else if err != nil {
-		return nil, err
+		return nil, mfs.runOnError(ctx, "GetScoreForField", member, err)
+	}
+
+	zscore, err := mfs.zscoreOf(member, zscoreStr)
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "GetScoreForField", member, err)
+	}
+	return field.toDisplay(mfs.extractFieldScore(field, zscore)), nil
+}
+
+// GetMembers returns members with their scores from the sorted set. Options such as MinField
+// filter the members, in which case a limit of zero or less returns all matching members,
+// InDimension reads a dimension set instead, and WithOrientation, WithRanks, WithMeta and
+// WithConsistency change how the members are read.
+func (mfs *MultiFieldSet) GetMembers(ctx context.Context, limit, offset int64, opts ...ReadOption) (_ []MemberScores, err error) {
+	defer mfs.observeRead("GetMembers", time.Now(), &err)
+
+	o := newReadOptions(opts)
+	ctx = o.context(ctx)
+	members, err := mfs.getMembers(ctx, o, limit, offset)
+	if err == nil {
+		err = mfs.decorate(ctx, o, members, offset)
+	}
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "GetMembers", "", err)
 	}
+	return members, nil
+}
 
-	zscore := new(big.Int).SetInt64(int64(zscoreStr))
-	fieldVal := mfs.extractFieldScore(field, zscore)
+// getMembers returns limit members from offset as read with o.
+func (mfs *MultiFieldSet) getMembers(ctx context.Context, o readOptions, limit, offset int64) ([]MemberScores, error) {
+	worstFirst := o.orientation == WorstFirst
+	// Hidden members are skipped in reverse by walking the set
+	if o.filtered() || (worstFirst && mfs.hideMembers) {
+		return mfs.getFilteredMembers(ctx, o, limit, offset)
+	}
+	key, err := mfs.readKey(o)
+	if err != nil {
+		return nil, err
+	}
 
-	// Reverse calculation for descending fields for display
-	if field.Sort == Descending {
-		fieldVal = new(big.Int).Sub(field.maxAbsolute, fieldVal)
+	var results []redis.Z
+	err = mfs.read(ctx, func(client redis.UniversalClient) error {
+		switch {
+		case mfs.hideMembers:
+			results, err = mfs.visibleRange(ctx, client, key, offset, limit)
+		case worstFirst:
+			results, err = client.ZRevRangeWithScores(ctx, key, offset, offset+limit-1).Result()
+		default:
+			results, err = client.ZRangeWithScores(ctx, key, offset, offset+limit-1).Result()
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
+	return mfs.decodeMembers(results)
+}
 
-	return fieldVal, nil
+// decodeMembers converts sorted set entries into members with decoded field scores, failing with
+// an UnrepresentableScoreError if a zscore can't be decoded exactly.
+func (mfs *MultiFieldSet) decodeMembers(results []redis.Z) ([]MemberScores, error) {
+	return mfs.decodeMembersInto(nil, results)
 }
 
-// GetMembers returns members with their scores from the sorted set.
-func (mfs *MultiFieldSet) GetMembers(ctx context.Context, limit, offset int64) ([]MemberScores, error) {
-	results, err := mfs.client.ZRangeWithScores(ctx, mfs.name, offset, offset+limit-1).Result()
+// GetTopMembers returns the top n members from the sorted set. Reads with options, such as
+// MinField or InDimension, bypass the cache.
+func (mfs *MultiFieldSet) GetTopMembers(ctx context.Context, limit int64, opts ...ReadOption) ([]MemberScores, error) {
+	if len(opts) > 0 {
+		return mfs.GetMembers(ctx, limit, 0, opts...)
+	}
+	if cached, ok := mfs.cache.get(topCacheKey(limit)); ok {
+		return copyMembers(cached.([]MemberScores)), nil
+	}
+	gen := mfs.cache.generation()
+
+	members, err := mfs.GetMembers(ctx, limit, 0)
 	if err != nil {
 		return nil, err
 	}
+	mfs.cache.put(topCacheKey(limit), copyMembers(members), gen)
+	return members, nil
+}
+
+// GetBottomMembers returns the last n members of the sorted set, in leaderboard order, skipping
+// hidden members.
+func (mfs *MultiFieldSet) GetBottomMembers(ctx context.Context, limit int64) (_ []MemberScores, err error) {
+	defer mfs.observeRead("GetBottomMembers", time.Now(), &err)
+
+	if limit <= 0 {
+		return []MemberScores{}, nil
+	}
 
-	members := make([]MemberScores, len(results))
-	for i, z := range results {
-		zscore := new(big.Int).SetInt64(int64(z.Score))
-		members[i] = MemberScores{
-			Member: z.Member.(string),
-			Scores: mfs.zscoreToAllFieldScores(zscore),
+	var results []redis.Z
+	err = mfs.read(ctx, func(client redis.UniversalClient) error {
+		if mfs.hideMembers {
+			results, err = mfs.visibleRevRange(ctx, client, mfs.key, 0, limit)
+			for i, j := 0, len(results)-1; i < j; i, j = i+1, j-1 {
+				results[i], results[j] = results[j], results[i]
+			}
+			return err
 		}
+		results, err = client.ZRangeWithScores(ctx, mfs.key, -limit, -1).Result()
+		return err
+	})
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "GetBottomMembers", "", err)
 	}
 
+	members, err := mfs.decodeMembers(results)
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "GetBottomMembers", "", err)
+	}
 	return members, nil
 }
 
-// GetTopMembers returns the top n members from the sorted set.
-func (mfs *MultiFieldSet) GetTopMembers(ctx context.Context, limit int64) ([]MemberScores, error) {
-	return mfs.GetMembers(ctx, limit, 0)
+// GetMembersReverse returns members starting from the bottom of the sorted set, worst first.
+// The offset counts from the last member, so pages can be walked without knowing the cardinality.
+// Hidden members are skipped.
+func (mfs *MultiFieldSet) GetMembersReverse(ctx context.Context, limit, offset int64) (_ []MemberScores, err error) {
+	defer mfs.observeRead("GetMembersReverse", time.Now(), &err)
+
+	var results []redis.Z
+	err = mfs.read(ctx, func(client redis.UniversalClient) error {
+		if mfs.hideMembers {
+			results, err = mfs.visibleRevRange(ctx, client, mfs.key, offset, limit)
+			return err
+		}
+		results, err = client.ZRevRangeWithScores(ctx, mfs.key, offset, offset+limit-1).Result()
+		return err
+	})
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "GetMembersReverse", "", err)
+	}
+
+	members, err := mfs.decodeMembers(results)
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "GetMembersReverse", "", err)
+	}
+	return members, nil
 }
 
-// GetMembersInRange returns members with scores within a range.
-func (mfs *MultiFieldSet) GetMembersInRange(ctx context.Context, limit, offset int64, min, max string) ([]MemberScores, error) {
+// GetMembersInRange returns members with scores within a range, skipping hidden members.
+func (mfs *MultiFieldSet) GetMembersInRange(ctx context.Context, limit, offset int64, min, max string) (_ []MemberScores, err error) {
+	defer mfs.observeRead("GetMembersInRange", time.Now(), &err)
+
 	// Convert strings to Redis range format
 	if min == "" {
 		min = "-inf"
@@ -328,45 +829,96 @@ func (mfs *MultiFieldSet) GetMembersInRange(ctx context.Context, limit, offset i
 		max = "+inf"
 	}
 
+	hidden, err := mfs.hiddenMembers(ctx)
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "GetMembersInRange", "", err)
+	}
+	readLimit, readOffset := hiddenWindow(hidden, limit, offset)
 	opt := &redis.ZRangeBy{
 		Min:    min,
 		Max:    max,
-		Offset: offset,
-		Count:  limit,
+		Offset: readOffset,
+		Count:  readLimit,
 	}
 
-	results, err := mfs.client.ZRangeByScoreWithScores(ctx, mfs.name, opt).Result()
+	var results []redis.Z
+	err = mfs.read(ctx, func(client redis.UniversalClient) error {
+		results, err = client.ZRangeByScoreWithScores(ctx, mfs.key, opt).Result()
+		return err
+	})
 	if err != nil {
-		return nil, err
+		return nil, mfs.runOnError(ctx, "GetMembersInRange", "", err)
 	}
 
-	members := make([]MemberScores, len(results))
-	for i, z := range results {
-		zscore := new(big.Int).SetInt64(int64(z.Score))
-		members[i] = MemberScores{
-			Member: z.Member.(string),
-			Scores: mfs.zscoreToAllFieldScores(zscore),
-		}
+	members, err := mfs.decodeMembers(results)
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "GetMembersInRange", "", err)
 	}
-
-	return members, nil
+	return withoutHidden(members, hidden, limit, offset), nil
 }
 
 // ResetMember resets a member's score to the default values.
 func (mfs *MultiFieldSet) ResetMember(ctx context.Context, member string) error {
-	_, err := mfs.client.ZAdd(ctx, mfs.name, &redis.Z{
-		Score:  float64(mfs.defaultZScore.Int64()),
-		Member: member,
-	}).Result()
+	scores := mfs.getFieldScores(nil)
+	mfs.stampUpdatedAt(scores)
+	mfs.stampSalt(scores, member)
+	_, err := mfs.writeMember(ctx, member, scores, mfs.scoresToZScore(scores), writeAlways)
 	return err
 }
 
+// GetCardinality returns the number of members in the sorted set.
+func (mfs *MultiFieldSet) GetCardinality(ctx context.Context) (_ int64, err error) {
+	defer mfs.observeRead("GetCardinality", time.Now(), &err)
+
+	count, err := mfs.MemberCount(ctx)
+	if err != nil {
+		return 0, mfs.runOnError(ctx, "GetCardinality", "", err)
+	}
+	return count, nil
+}
+
+// MemberCount returns the number of members in the sorted set like GetCardinality, but isn't
+// reported to the metrics recorder or the error hooks, so monitoring such as a metrics collector
+// can poll it without being counted as reads of the set.
+func (mfs *MultiFieldSet) MemberCount(ctx context.Context) (int64, error) {
+	var count int64
+	err := mfs.read(ctx, func(client redis.UniversalClient) error {
+		var err error
+		count, err = client.ZCard(ctx, mfs.key).Result()
+		return err
+	})
+	return count, err
+}
+
+// MemberExists reports whether a member is in the sorted set.
+func (mfs *MultiFieldSet) MemberExists(ctx context.Context, member string) (_ bool, err error) {
+	defer mfs.observeRead("MemberExists", time.Now(), &err)
+
+	err = mfs.read(ctx, func(client redis.UniversalClient) error {
+		return client.ZScore(ctx, mfs.key, member).Err()
+	})
+	if err == redis.Nil {
+		return false, nil
+	} else if err != nil {
+		return false, mfs.runOnError(ctx, "MemberExists", member, err)
+	}
+	return true, nil
+}
+
 // GetCountInRange returns the count of members with scores within a range.
 func (mfs *MultiFieldSet) GetCountInRange(ctx context.Context, min, max string) (int64, error) {
-	return mfs.client.ZCount(ctx, mfs.name, min, max).Result()
+	var count int64
+	err := mfs.read(ctx, func(client redis.UniversalClient) error {
+		var err error
+		count, err = client.ZCount(ctx, mfs.key, min, max).Result()
+		return err
+	})
+	return count, err
 }
 
-// MaxScoreWithFields calculates the maximum zscore for given field limits.
+// MaxScoreWithFields calculates the maximum zscore for given field limits. Each limit is a display
+// value and is encoded as the field's value, so KeepMin fields, which default to their largest
+// value, get the limit itself; fields without a limit keep their default score.
 func (mfs *MultiFieldSet) MaxScoreWithFields(limits map[string]float64) (*big.Int, error) {
 	scores := make([]*big.Int, len(mfs.fields))
 
@@ -379,13 +931,10 @@ func (mfs *MultiFieldSet) MaxScoreWithFields(limits map[string]float64) (*big.In
 	for fieldName, limit := range limits {
 		field := mfs.GetFieldByName(fieldName)
 		if field == nil {
-			return nil, fmt.Errorf("field %s not found", fieldName)
+			return nil, fieldNotFoundError(fieldName)
 		}
 
-		inc := new(big.Int).SetInt64(int64(limit))
-		inc.Mul(inc, field.multiplier)
-
-		scores[field.position] = new(big.Int).Add(field.defaultScore(), inc)
+		scores[field.position] = field.toRaw(big.NewInt(int64(limit)))
 	}
 
 	return mfs.scoresToZScore(scores), nil
@@ -397,14 +946,7 @@ func (mfs *MultiFieldSet) CalculateScoresFromZScore(zscore *big.Int) map[string]
 	result := make(map[string]*big.Int)
 
 	for _, field := range mfs.fields {
-		fieldVal := mfs.extractFieldScore(field, zscore)
-
-		// Reverse calculation for descending fields for display
-		if field.Sort == Descending {
-			fieldVal = new(big.Int).Sub(field.maxAbsolute, fieldVal)
-		}
-
-		result[field.Name] = fieldVal
+		result[field.Name] = field.toDisplay(mfs.extractFieldScore(field, zscore))
 	}
 
 	return result
@@ -0,0 +1,69 @@
+package zmultifield
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCardinalityAndExistence(t *testing.T) {
+	mfs := newTestSet(t)
+	ctx := context.Background()
+
+	if count, err := mfs.GetCardinality(ctx); err != nil || count != 0 {
+		t.Errorf("GetCardinality() = %d, %v, expected 0", count, err)
+	}
+	if exists, err := mfs.MemberExists(ctx, "alice"); err != nil || exists {
+		t.Errorf("MemberExists(alice) = %v, %v, expected false", exists, err)
+	}
+
+	for _, member := range []string{"alice", "bob"} {
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 1}, member); err != nil {
+			t.Fatalf("IncreaseScore(%s) error = %v", member, err)
+		}
+	}
+
+	if count, err := mfs.GetCardinality(ctx); err != nil || count != 2 {
+		t.Errorf("GetCardinality() = %d, %v, expected 2", count, err)
+	}
+	if exists, err := mfs.MemberExists(ctx, "alice"); err != nil || !exists {
+		t.Errorf("MemberExists(alice) = %v, %v, expected true", exists, err)
+	}
+}
+
+func TestBottomMembers(t *testing.T) {
+	mfs := newTestSet(t)
+	ctx := context.Background()
+
+	for i, member := range []string{"alice", "bob", "carol", "dave"} {
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": float64(100 - i*10)}, member); err != nil {
+			t.Fatalf("IncreaseScore(%s) error = %v", member, err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		get      func() ([]MemberScores, error)
+		expected []string
+	}{
+		{"GetBottomMembers(2)", func() ([]MemberScores, error) { return mfs.GetBottomMembers(ctx, 2) }, []string{"carol", "dave"}},
+		{"GetBottomMembers(10)", func() ([]MemberScores, error) { return mfs.GetBottomMembers(ctx, 10) }, []string{"alice", "bob", "carol", "dave"}},
+		{"GetMembersReverse(2, 0)", func() ([]MemberScores, error) { return mfs.GetMembersReverse(ctx, 2, 0) }, []string{"dave", "carol"}},
+		{"GetMembersReverse(2, 2)", func() ([]MemberScores, error) { return mfs.GetMembersReverse(ctx, 2, 2) }, []string{"bob", "alice"}},
+	}
+
+	for _, test := range tests {
+		members, err := test.get()
+		if err != nil {
+			t.Fatalf("%s error = %v", test.name, err)
+		}
+		if len(members) != len(test.expected) {
+			t.Errorf("%s returned %d members, expected %d", test.name, len(members), len(test.expected))
+			continue
+		}
+		for i, m := range members {
+			if m.Member != test.expected[i] {
+				t.Errorf("%s[%d] = %s, expected %s", test.name, i, m.Member, test.expected[i])
+			}
+		}
+	}
+}
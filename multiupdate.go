@@ -0,0 +1,215 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// SetUpdate is an update of one member of a set, applied by UpdateMulti.
+type SetUpdate struct {
+	Set    *MultiFieldSet
+	Member string
+	// Fields holds the values applied to the member's fields, as passed to IncreaseScore.
+	Fields map[string]float64
+}
+
+// UpdateMulti applies updates to several sets as one unit, e.g. the same match result to a global,
+// a weekly and a regional leaderboard. It returns the results in the order of updates.
+//
+// When every set uses the same client and, on a cluster client, all their keys hash to the same
+// slot, the updates are written in a single WATCH/MULTI/EXEC transaction: either all of them are
+// applied or, if any fails, none, and conflicting concurrent writes make the transaction retry up
+// to the first set's OptimisticRetries times before it fails with ErrUpdateConflict.
+//
+// Otherwise the updates of each client are computed from individual reads and written in one
+// pipeline without atomicity. Updates that fail don't stop the others; their results are nil and
+// their errors are joined into the returned error.
+//
+// Hooks, triggers, notifications and history run for every applied update as for IncreaseScore.
+// Updates are never queued in the WriteQueue.
+func UpdateMulti(ctx context.Context, updates []SetUpdate) ([]*UpdateResult, error) {
+	if len(updates) == 0 {
+		return nil, nil
+	}
+	type target struct {
+		set    *MultiFieldSet
+		member string
+	}
+	seen := make(map[target]bool, len(updates))
+	for _, u := range updates {
+		if u.Set == nil {
+			return nil, errors.New("set is required")
+		}
+		t := target{u.Set, u.Member}
+		if seen[t] {
+			return nil, fmt.Errorf("member %s of set %s is updated more than once", u.Member, u.Set.name)
+		}
+		seen[t] = true
+	}
+
+	if atomicUpdates(ctx, updates) {
+		return updateMultiAtomic(ctx, updates)
+	}
+	results, errs := updateMultiPipelined(ctx, updates)
+	return results, errors.Join(errs...)
+}
+
+// atomicUpdates reports whether updates can be written in a single transaction.
+func atomicUpdates(ctx context.Context, updates []SetUpdate) bool {
+	client := updates[0].Set.client
+	for _, u := range updates {
+		if u.Set.client != client {
+			return false
+		}
+	}
+	if !isClusterClient(client) {
+		return true
+	}
+	slot := keySlot(updates[0].Set.key)
+	for _, u := range updates {
+		keys := append(u.Set.allKeys(), u.Set.watchedKeys(ctx, u.Member)...)
+		for _, key := range keys {
+			if keySlot(key) != slot {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// updateMultiAtomic writes updates in one WATCH/MULTI/EXEC transaction through the client of the
+// first set, retrying on conflicts.
+func updateMultiAtomic(ctx context.Context, updates []SetUpdate) (_ []*UpdateResult, err error) {
+	first := updates[0].Set
+	var watched []string
+	for _, u := range updates {
+		watched = append(watched, u.Set.watchedKeys(ctx, u.Member)...)
+	}
+	// Every set's cache must be dropped, not only the one of the set running the transaction
+	defer func(start time.Time) {
+		for _, u := range updates {
+			u.Set.cache.invalidate()
+			u.Set.observeUpdate("UpdateMulti", start, &err)
+		}
+	}(time.Now())
+
+	for attempt := 0; attempt <= first.optimisticRetries; attempt++ {
+		prepared := make([]*watchedUpdate, len(updates))
+		failed := -1
+		err := first.write(ctx, func(client redis.UniversalClient) error {
+			return client.Watch(ctx, func(tx *redis.Tx) error {
+				for i, u := range updates {
+					var err error
+					if prepared[i], err = u.Set.prepareWatched(ctx, tx, u.Fields, u.Member, u.Set.needsRanks()); err != nil {
+						failed = i
+						return err
+					}
+				}
+				_, err := tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+					for i, u := range updates {
+						u.Set.queueWatched(ctx, pipe, prepared[i])
+					}
+					return nil
+				})
+				if err == redis.Nil {
+					err = nil
+				}
+				return err
+			}, watched...)
+		})
+		if err == redis.TxFailedErr {
+			continue
+		} else if err != nil {
+			if failed >= 0 {
+				u := updates[failed]
+				return nil, u.Set.runOnError(ctx, "UpdateMulti", u.Member, fmt.Errorf("set %s: %w", u.Set.name, err))
+			}
+			return nil, first.runOnError(ctx, "UpdateMulti", updates[0].Member, err)
+		}
+
+		results := make([]*UpdateResult, len(updates))
+		for i, u := range updates {
+			u.Set.finishWatched(ctx, prepared[i])
+			results[i] = prepared[i].result
+		}
+		return results, nil
+	}
+	return nil, first.runOnError(ctx, "UpdateMulti", updates[0].Member, ErrUpdateConflict)
+}
+
+// updateMultiPipelined computes each update from individual reads and writes the updates of each
+// client in one pipeline. It returns an error per update, nil for those that were applied.
+func updateMultiPipelined(ctx context.Context, updates []SetUpdate) ([]*UpdateResult, []error) {
+	start := time.Now()
+	results := make([]*UpdateResult, len(updates))
+	errs := make([]error, len(updates))
+	defer func() {
+		for i, u := range updates {
+			u.Set.observeUpdate("UpdateMulti", start, &errs[i])
+		}
+	}()
+	fail := func(i int, err error) {
+		u := updates[i]
+		errs[i] = u.Set.runOnError(ctx, "UpdateMulti", u.Member, fmt.Errorf("set %s: %w", u.Set.name, err))
+	}
+
+	// Group the updates by client, keeping their order
+	var clients []redis.UniversalClient
+	groups := make(map[redis.UniversalClient][]int)
+	for i, u := range updates {
+		if _, ok := groups[u.Set.client]; !ok {
+			clients = append(clients, u.Set.client)
+		}
+		groups[u.Set.client] = append(groups[u.Set.client], i)
+	}
+
+	prepared := make([]*watchedUpdate, len(updates))
+	for _, client := range clients {
+		var pending []int
+		for _, i := range groups[client] {
+			u := updates[i]
+			err := u.Set.primary(ctx, func(client redis.UniversalClient) error {
+				var err error
+				prepared[i], err = u.Set.prepareWatched(ctx, client, u.Fields, u.Member, u.Set.needsRanks())
+				return err
+			})
+			if err != nil {
+				fail(i, err)
+				continue
+			}
+			pending = append(pending, i)
+		}
+		if len(pending) == 0 {
+			continue
+		}
+
+		first := updates[pending[0]].Set
+		err := first.write(ctx, func(client redis.UniversalClient) error {
+			_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+				for _, i := range pending {
+					updates[i].Set.queueWatched(ctx, pipe, prepared[i])
+				}
+				return nil
+			})
+			if err == redis.Nil {
+				err = nil
+			}
+			return err
+		})
+		for _, i := range pending {
+			u := updates[i]
+			u.Set.cache.invalidate()
+			if err != nil {
+				fail(i, err)
+				continue
+			}
+			u.Set.finishWatched(ctx, prepared[i])
+			results[i] = prepared[i].result
+		}
+	}
+	return results, errs
+}
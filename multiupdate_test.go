@@ -0,0 +1,120 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// newMultiTestSets creates sets with the test schema sharing client.
+func newMultiTestSets(t *testing.T, client redis.UniversalClient, names ...string) []*MultiFieldSet {
+	t.Helper()
+	sets := make([]*MultiFieldSet, len(names))
+	for i, name := range names {
+		mfs, err := New(MultiFieldSetOptions{
+			Name:   name,
+			Client: client,
+			Fields: []Field{
+				{Name: "points", Sort: Descending, MaxValue: 1000, UpdateType: Incremental},
+				{Name: "deaths", Sort: Ascending, MaxValue: 100, UpdateType: Incremental},
+			},
+		})
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		sets[i] = mfs
+	}
+	return sets
+}
+
+func TestUpdateMultiAtomic(t *testing.T) {
+	client, _ := newTestClient(t)
+	sets := newMultiTestSets(t, client, "global", "weekly")
+	global, weekly := sets[0], sets[1]
+	ctx := context.Background()
+
+	if _, err := weekly.IncreaseScore(ctx, map[string]float64{"points": 990}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	var updated []string
+	for _, mfs := range sets {
+		mfs.AfterUpdate(func(ctx context.Context, event *UpdateEvent) {
+			updated = append(updated, event.Set)
+		})
+	}
+
+	results, err := UpdateMulti(ctx, []SetUpdate{
+		{Set: global, Member: "alice", Fields: map[string]float64{"points": 5}},
+		{Set: weekly, Member: "alice", Fields: map[string]float64{"points": 5}},
+	})
+	if err != nil {
+		t.Fatalf("UpdateMulti() error = %v", err)
+	}
+	if len(results) != 2 || results[0].ZScore == nil || results[1].ZScore == nil {
+		t.Fatalf("UpdateMulti() = %v, expected two results", results)
+	}
+	if len(updated) != 2 {
+		t.Errorf("after-update hooks ran for %v, expected both sets", updated)
+	}
+
+	// An update that fails leaves every set unchanged
+	_, err = UpdateMulti(ctx, []SetUpdate{
+		{Set: global, Member: "alice", Fields: map[string]float64{"points": 100}},
+		{Set: weekly, Member: "alice", Fields: map[string]float64{"points": 100}},
+	})
+	if !errors.Is(err, ErrScoreOutOfRange) {
+		t.Fatalf("UpdateMulti() error = %v, expected ErrScoreOutOfRange", err)
+	}
+	for _, tt := range []struct {
+		mfs      *MultiFieldSet
+		expected int64
+	}{{global, 5}, {weekly, 995}} {
+		scores, err := tt.mfs.GetScores(ctx, "alice")
+		if err != nil {
+			t.Fatalf("GetScores() error = %v", err)
+		}
+		if got := scores[0].Score.Int64(); got != tt.expected {
+			t.Errorf("%s points = %d, expected %d", tt.mfs.GetName(), got, tt.expected)
+		}
+	}
+}
+
+func TestUpdateMultiPipelined(t *testing.T) {
+	first, _ := newTestClient(t)
+	second, _ := newTestClient(t)
+	global := newMultiTestSets(t, first, "global")[0]
+	regional := newMultiTestSets(t, second, "regional")[0]
+	ctx := context.Background()
+
+	// Sets on different clients are updated independently
+	results, err := UpdateMulti(ctx, []SetUpdate{
+		{Set: global, Member: "alice", Fields: map[string]float64{"points": 10}},
+		{Set: regional, Member: "alice", Fields: map[string]float64{"points": 5000}},
+	})
+	if !errors.Is(err, ErrScoreOutOfRange) {
+		t.Fatalf("UpdateMulti() error = %v, expected ErrScoreOutOfRange", err)
+	}
+	if results[0] == nil || results[1] != nil {
+		t.Fatalf("UpdateMulti() = %v, expected only the first update to succeed", results)
+	}
+	scores, err := global.GetScores(ctx, "alice")
+	if err != nil || scores[0].Score.Int64() != 10 {
+		t.Errorf("GetScores() = %v, %v, expected 10 points", scores, err)
+	}
+	if exists, _ := regional.MemberExists(ctx, "alice"); exists {
+		t.Error("failed update was written")
+	}
+}
+
+func TestUpdateMultiDuplicate(t *testing.T) {
+	mfs := newTestSet(t)
+	_, err := UpdateMulti(context.Background(), []SetUpdate{
+		{Set: mfs, Member: "alice", Fields: map[string]float64{"points": 1}},
+		{Set: mfs, Member: "alice", Fields: map[string]float64{"points": 1}},
+	})
+	if err == nil {
+		t.Error("UpdateMulti() with a duplicate update succeeded, expected an error")
+	}
+}
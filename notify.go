@@ -0,0 +1,207 @@
+package zmultifield
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// NotificationType identifies the kind of change described by a Notification.
+type NotificationType string
+
+const (
+	// NotificationMemberUpdated is published after every successful update of a member.
+	NotificationMemberUpdated NotificationType = "member_updated"
+	// NotificationEnteredTopN is published when a member moves into the top N.
+	NotificationEnteredTopN NotificationType = "entered_top_n"
+	// NotificationLeftTopN is published when a member drops out of the top N.
+	NotificationLeftTopN NotificationType = "left_top_n"
+)
+
+// NotificationOptions enables publishing change notifications to a Redis channel after writes.
+type NotificationOptions struct {
+	// Channel is the Pub/Sub channel to publish to. Defaults to the set's "events" derived key.
+	Channel string
+	// TopN enables entered/left top-N notifications for the given N. Zero disables them, which
+	// saves the extra rank lookups done around every update.
+	TopN int64
+}
+
+// Notification is a change published to the notification channel.
+type Notification struct {
+	Type   NotificationType    `json:"type"`
+	Set    string              `json:"set"`
+	Member string              `json:"member"`
+	Rank   int64               `json:"rank"`
+	Scores map[string]*big.Int `json:"scores,omitempty"`
+}
+
+// notificationChannel returns the channel notifications are published to.
+func (mfs *MultiFieldSet) notificationChannel() string {
+	if mfs.notifications.Channel != "" {
+		return mfs.notifications.Channel
+	}
+	return mfs.derivedKey("events")
+}
+
+// tracksTopN reports whether entered/left top-N notifications are enabled.
+func (mfs *MultiFieldSet) tracksTopN() bool {
+	return mfs.notifications != nil && mfs.notifications.TopN > 0
+}
+
+// notify publishes the notifications for an update. oldRank and newRank are the member's ranks
+// around the update and are only looked up when top-N tracking is enabled. Publishing is best
+// effort: failures are reported to the error hooks but don't fail the update, which has already
+// been written.
+func (mfs *MultiFieldSet) notify(ctx context.Context, event *UpdateEvent, oldRank, newRank int64) {
+	if mfs.notifications == nil {
+		return
+	}
+
+	notifications := []Notification{}
+	if mfs.tracksTopN() {
+		topN := mfs.notifications.TopN
+		wasInTop := oldRank >= 0 && oldRank < topN
+		isInTop := newRank >= 0 && newRank < topN
+		if !wasInTop && isInTop {
+			notifications = append(notifications, Notification{Type: NotificationEnteredTopN, Member: event.Member, Rank: newRank})
+			// Whoever now sits just below the top N was pushed out by this member
+			if displaced, err := mfs.memberAtRank(ctx, topN); err == nil && displaced != "" {
+				notifications = append(notifications, Notification{Type: NotificationLeftTopN, Member: displaced, Rank: topN})
+			}
+		} else if wasInTop && !isInTop {
+			notifications = append(notifications, Notification{Type: NotificationLeftTopN, Member: event.Member, Rank: newRank})
+			// Whoever now sits at the last top N position moved up to replace this member
+			if promoted, err := mfs.memberAtRank(ctx, topN-1); err == nil && promoted != "" {
+				notifications = append(notifications, Notification{Type: NotificationEnteredTopN, Member: promoted, Rank: topN - 1})
+			}
+		}
+	}
+
+	updated := Notification{
+		Type:   NotificationMemberUpdated,
+		Member: event.Member,
+		Rank:   newRank,
+		Scores: make(map[string]*big.Int, len(event.NewScores)),
+	}
+	for _, score := range event.NewScores {
+		updated.Scores[score.Name] = score.Score
+	}
+	notifications = append([]Notification{updated}, notifications...)
+
+	channel := mfs.notificationChannel()
+	for _, n := range notifications {
+		n.Set = mfs.name
+		payload, err := json.Marshal(n)
+		if err != nil {
+			mfs.runOnError(ctx, "Notify", n.Member, err)
+			continue
+		}
+		// Publishing writes no data, so it skips the cache invalidation and WAIT of mfs.write
+		err = mfs.primary(ctx, func(client redis.UniversalClient) error {
+			return client.Publish(ctx, channel, payload).Err()
+		})
+		if err != nil {
+			mfs.runOnError(ctx, "Notify", n.Member, err)
+		}
+	}
+}
+
+// memberAtRank returns the member at the given rank, or "" if the set is smaller.
+func (mfs *MultiFieldSet) memberAtRank(ctx context.Context, rank int64) (string, error) {
+	var members []string
+	err := mfs.primary(ctx, func(client redis.UniversalClient) error {
+		var err error
+		members, err = client.ZRange(ctx, mfs.key, rank, rank).Result()
+		return err
+	})
+	if err != nil || len(members) == 0 {
+		return "", err
+	}
+	return members[0], nil
+}
+
+// Subscription delivers decoded notifications published by a MultiFieldSet.
+type Subscription struct {
+	pubsub *redis.PubSub
+	events chan Notification
+	done   chan struct{}
+	once   sync.Once
+}
+
+// Subscribe listens on the set's notification channel until ctx is done or the Subscription is
+// closed. Messages that can't be decoded are skipped. The returned Subscription must be closed when
+// no longer needed.
+func (mfs *MultiFieldSet) Subscribe(ctx context.Context) (*Subscription, error) {
+	if mfs.notifications == nil {
+		return nil, ErrNotificationsDisabled
+	}
+
+	pubsub := mfs.client.Subscribe(ctx, mfs.notificationChannel())
+	// Wait for the subscription to be confirmed so no notification published afterwards is missed
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+
+	sub := &Subscription{
+		pubsub: pubsub,
+		events: make(chan Notification),
+		done:   make(chan struct{}),
+	}
+	go sub.run(ctx)
+	return sub, nil
+}
+
+// run decodes messages until the underlying channel is closed, the subscription is closed or ctx
+// is done, even while a consumer that stopped reading Events leaves a notification undelivered.
+func (s *Subscription) run(ctx context.Context) {
+	defer close(s.events)
+	defer s.Close()
+	messages := s.pubsub.Channel()
+	for {
+		var msg *redis.Message
+		select {
+		case m, ok := <-messages:
+			if !ok {
+				return
+			}
+			msg = m
+		case <-s.done:
+			return
+		case <-ctx.Done():
+			return
+		}
+
+		var n Notification
+		if err := json.Unmarshal([]byte(msg.Payload), &n); err != nil {
+			continue
+		}
+		select {
+		case s.events <- n:
+		case <-s.done:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Events returns the channel on which decoded notifications are delivered. It is closed after
+// Close, or once the context passed to Subscribe is done.
+func (s *Subscription) Events() <-chan Notification {
+	return s.events
+}
+
+// Close stops the subscription.
+func (s *Subscription) Close() error {
+	var err error
+	s.once.Do(func() {
+		close(s.done)
+		err = s.pubsub.Close()
+	})
+	return err
+}
@@ -0,0 +1,106 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNotifications(t *testing.T) {
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{Notifications: &NotificationOptions{TopN: 2}})
+	ctx := context.Background()
+
+	sub, err := mfs.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	defer sub.Close()
+
+	next := func() Notification {
+		t.Helper()
+		select {
+		case n := <-sub.Events():
+			return n
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for notification")
+			return Notification{}
+		}
+	}
+
+	for _, u := range []struct {
+		member string
+		points float64
+	}{{"alice", 100}, {"bob", 50}} {
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": u.points}, u.member); err != nil {
+			t.Fatalf("IncreaseScore(%s) error = %v", u.member, err)
+		}
+		if n := next(); n.Type != NotificationMemberUpdated || n.Member != u.member {
+			t.Errorf("notification = %+v, expected %s updated", n, u.member)
+		}
+		if n := next(); n.Type != NotificationEnteredTopN || n.Member != u.member {
+			t.Errorf("notification = %+v, expected %s entered top N", n, u.member)
+		}
+	}
+
+	// carol overtakes bob and pushes him out of the top 2
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 75}, "carol"); err != nil {
+		t.Fatalf("IncreaseScore(carol) error = %v", err)
+	}
+	updated := next()
+	if updated.Type != NotificationMemberUpdated || updated.Set != "test" || updated.Rank != 1 || updated.Scores["points"].Int64() != 75 {
+		t.Errorf("notification = %+v, expected carol updated at rank 1 with 75 points", updated)
+	}
+	if n := next(); n.Type != NotificationEnteredTopN || n.Member != "carol" {
+		t.Errorf("notification = %+v, expected carol entered top N", n)
+	}
+	if n := next(); n.Type != NotificationLeftTopN || n.Member != "bob" || n.Rank != 2 {
+		t.Errorf("notification = %+v, expected bob left top N at rank 2", n)
+	}
+}
+
+func TestNotifications_Disabled(t *testing.T) {
+	mfs := newTestSet(t)
+
+	if _, err := mfs.Subscribe(context.Background()); !errors.Is(err, ErrNotificationsDisabled) {
+		t.Errorf("Subscribe() error = %v, expected ErrNotificationsDisabled", err)
+	}
+}
+
+func TestSubscription_Stop(t *testing.T) {
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{Notifications: &NotificationOptions{}})
+	closed := func(sub *Subscription) bool {
+		t.Helper()
+		select {
+		case _, ok := <-sub.Events():
+			return !ok
+		case <-time.After(time.Second):
+			return false
+		}
+	}
+
+	// A notification nobody reads doesn't keep the subscription from stopping
+	sub, err := mfs.Subscribe(context.Background())
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	if _, err := mfs.IncreaseScore(context.Background(), map[string]float64{"points": 10}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	sub.Close()
+	if !closed(sub) {
+		t.Errorf("Events() not closed after Close()")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sub, err = mfs.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	defer sub.Close()
+	cancel()
+	if !closed(sub) {
+		t.Errorf("Events() not closed after the context was cancelled")
+	}
+}
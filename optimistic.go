@@ -0,0 +1,242 @@
+package zmultifield
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// defaultOptimisticRetries is the number of retries used when OptimisticRetries is not set.
+const defaultOptimisticRetries = 10
+
+// UpdateStrategy selects how a MultiFieldSet applies updates to existing members.
+type UpdateStrategy int
+
+const (
+	// UpdateScripted writes updates with Lua scripts where atomicity is needed. It is the default.
+	UpdateScripted UpdateStrategy = iota
+	// UpdateOptimistic makes IncreaseScore read and write members inside WATCH/MULTI/EXEC
+	// transactions, retrying when a concurrent write touched the set, for deployments that forbid
+	// Lua. Other operations, such as ResetFields or merges, still use scripts.
+	//
+	// Before-update hooks run again for every retry. Members evicted by MaxMembers are removed
+	// from the field indexes after the transaction rather than within it.
+	UpdateOptimistic
+)
+
+// increaseScoreOptimistic applies the field updates to a member inside a WATCH/MULTI/EXEC
+// transaction on the main key, retrying up to optimisticRetries times on conflicts.
+func (mfs *MultiFieldSet) increaseScoreOptimistic(ctx context.Context, fields map[string]float64, member string, withRanks bool) (*UpdateResult, error) {
+	withRanks = withRanks || mfs.needsRanks()
+	for attempt := 0; attempt <= mfs.optimisticRetries; attempt++ {
+		var u *watchedUpdate
+		err := mfs.writeOnce(ctx, func(client redis.UniversalClient) error {
+			return client.Watch(ctx, func(tx *redis.Tx) error {
+				var err error
+				if u, err = mfs.prepareWatched(ctx, tx, fields, member, withRanks); err != nil {
+					return err
+				}
+				_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+					mfs.queueWatched(ctx, pipe, u)
+					return nil
+				})
+				if err == redis.Nil {
+					err = nil
+				}
+				return err
+			}, mfs.watchedKeys(ctx, member)...)
+		})
+		if err == redis.TxFailedErr {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		mfs.finishWatched(ctx, u)
+		return u.result, nil
+	}
+	return nil, ErrUpdateConflict
+}
+
+// watchedKeys returns the keys an optimistic update of member reads and must watch: the main key,
+// the record of the idempotency key of ctx if there is one, the member's update counter if updates
+// are rate limited and its recorded dimension values if the set has dimensions.
+func (mfs *MultiFieldSet) watchedKeys(ctx context.Context, member string) []string {
+	keys := []string{mfs.key}
+	if key := idempotencyKey(ctx); key != "" {
+		keys = append(keys, mfs.idempotencyRecordKey(key))
+	}
+	if mfs.limitsUpdates() {
+		keys = append(keys, mfs.updateCountKey(member))
+	}
+	if len(mfs.dimensions) > 0 {
+		keys = append(keys, mfs.memberDimensionsKey(member))
+	}
+	if mfs.freezable {
+		keys = append(keys, mfs.frozenKey())
+	}
+	return keys
+}
+
+// watchedUpdate is an update read by prepareWatched and written by queueWatched.
+type watchedUpdate struct {
+	member  string
+	event   *UpdateEvent
+	result  *UpdateResult
+	scores  []*big.Int
+	zscore  *big.Int
+	updates int64
+	dims    *dimensionWrite
+	// record is the key recording the update's idempotency key, if any
+	record string
+
+	withRanks bool
+	newRank   *redis.IntCmd
+	evicted   *redis.StringSliceCmd
+}
+
+// prepareWatched reads a member through c, normally a transaction watching watchedKeys, and
+// computes its update without writing it.
+func (mfs *MultiFieldSet) prepareWatched(ctx context.Context, c redis.Cmdable, fields map[string]float64, member string, withRanks bool) (*watchedUpdate, error) {
+	if err := mfs.checkFrozen(ctx, c); err != nil {
+		return nil, err
+	}
+
+	var currentZScore *big.Int
+	zscore, err := c.ZScore(ctx, mfs.key, member).Result()
+	if err == nil {
+		if currentZScore, err = mfs.zscoreOf(member, zscore); err != nil {
+			return nil, err
+		}
+	} else if err != redis.Nil {
+		return nil, err
+	}
+	if mfs.updateOnlyExisting && currentZScore == nil {
+		return nil, ErrMemberNotFound
+	}
+	var record string
+	if key := idempotencyKey(ctx); key != "" {
+		record = mfs.idempotencyRecordKey(key)
+		if n, err := c.Exists(ctx, record).Result(); err != nil {
+			return nil, err
+		} else if n > 0 {
+			return nil, ErrDuplicateUpdate
+		}
+	}
+
+	scores, finalZScore, event, err := mfs.buildUpdate(member, currentZScore, fields)
+	if err != nil {
+		return nil, err
+	}
+	if err := mfs.runBeforeUpdate(ctx, event); err != nil {
+		return nil, err
+	}
+
+	u := &watchedUpdate{member: member, event: event, scores: scores, zscore: finalZScore, record: record, withRanks: withRanks}
+	if mfs.limitsUpdates() {
+		u.updates, err = c.Get(ctx, mfs.updateCountKey(member)).Int64()
+		if err != nil && err != redis.Nil {
+			return nil, err
+		}
+		if u.updates >= mfs.guard.MaxUpdates {
+			return nil, mfs.rateLimitError(member)
+		}
+	}
+	if len(mfs.dimensions) > 0 {
+		if u.dims, err = mfs.resolveDimensions(ctx, c, member); err != nil {
+			return nil, err
+		}
+	}
+
+	u.result = &UpdateResult{ZScore: finalZScore, OldRank: -1, NewRank: -1}
+	if withRanks && currentZScore != nil {
+		if u.result.OldRank, err = c.ZRank(ctx, mfs.key, member).Result(); err != nil {
+			return nil, err
+		}
+	}
+	return u, nil
+}
+
+// queueWatched queues the writes of an update prepared by prepareWatched on pipe, normally a
+// MULTI/EXEC block.
+func (mfs *MultiFieldSet) queueWatched(ctx context.Context, pipe redis.Pipeliner, u *watchedUpdate) {
+	member := u.member
+	pipe.ZAdd(ctx, mfs.key, &redis.Z{Score: float64(u.zscore.Int64()), Member: member})
+	mfs.recordSet(ctx, pipe, member, float64(u.zscore.Int64()))
+	if mfs.maintainFieldIndexes {
+		for i, field := range mfs.fields {
+			pipe.ZAdd(ctx, mfs.fieldIndexKey(field), &redis.Z{Score: float64(u.scores[i].Int64()), Member: member})
+		}
+	}
+	if u.dims != nil {
+		for _, key := range u.dims.add {
+			pipe.ZAdd(ctx, key, &redis.Z{Score: float64(u.zscore.Int64()), Member: member})
+			pipe.SAdd(ctx, mfs.dimensionKeysKey(), key)
+		}
+		for _, key := range u.dims.remove {
+			pipe.ZRem(ctx, key, member)
+		}
+		pipe.HSet(ctx, mfs.memberDimensionsKey(member), u.dims.values...)
+	}
+	if mfs.limitsUpdates() {
+		pipe.Incr(ctx, mfs.updateCountKey(member))
+		if u.updates == 0 {
+			pipe.PExpire(ctx, mfs.updateCountKey(member), mfs.guard.Window)
+		}
+	}
+	if u.record != "" {
+		pipe.Set(ctx, u.record, 1, mfs.idempotencyTTL)
+	}
+	if mfs.maxMembers > 0 {
+		u.evicted = pipe.ZRange(ctx, mfs.key, mfs.maxMembers, -1)
+		if mfs.audit {
+			trimScript.Eval(ctx, pipe, mfs.auditGuard(), []string{mfs.key}, mfs.maxMembers)
+		} else {
+			pipe.ZRemRangeByRank(ctx, mfs.key, mfs.maxMembers, -1)
+		}
+	}
+	if u.withRanks {
+		u.newRank = pipe.ZRank(ctx, mfs.key, member)
+	}
+}
+
+// finishWatched completes an update once the writes queued by queueWatched have been executed:
+// it cleans up evicted members and runs the hooks, triggers, notifications and history.
+func (mfs *MultiFieldSet) finishWatched(ctx context.Context, u *watchedUpdate) {
+	if u.newRank != nil {
+		u.result.NewRank = u.newRank.Val()
+		if u.newRank.Err() == redis.Nil {
+			u.result.NewRank = -1
+		}
+	}
+	if u.evicted != nil && len(u.evicted.Val()) > 0 {
+		mfs.evictFromIndexes(ctx, u.evicted.Val())
+		mfs.cleanupEvicted(ctx, u.evicted.Val())
+	}
+	mfs.finishUpdate(ctx, u.event, u.result)
+}
+
+// evictFromIndexes removes members evicted by an optimistic update from the field indexes. Like
+// cleanupEvicted it is best effort and reports failures with op "Evict".
+func (mfs *MultiFieldSet) evictFromIndexes(ctx context.Context, members []string) {
+	if !mfs.maintainFieldIndexes {
+		return
+	}
+	names := make([]interface{}, len(members))
+	for i, member := range members {
+		names[i] = member
+	}
+	err := mfs.write(ctx, func(client redis.UniversalClient) error {
+		_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, field := range mfs.fields {
+				pipe.ZRem(ctx, mfs.fieldIndexKey(field), names...)
+			}
+			return nil
+		})
+		return err
+	})
+	if err != nil {
+		mfs.runOnError(ctx, "Evict", members[0], err)
+	}
+}
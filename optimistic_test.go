@@ -0,0 +1,91 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+)
+
+func TestUpdateOptimistic_ConcurrentIncrements(t *testing.T) {
+	ctx := context.Background()
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{
+		UpdateStrategy:       UpdateOptimistic,
+		OptimisticRetries:    100,
+		MaintainFieldIndexes: true,
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 1, "deaths": 1}, "alice"); err != nil {
+				t.Errorf("IncreaseScore failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	scores, err := mfs.GetScores(ctx, "alice")
+	if err != nil {
+		t.Fatalf("GetScores failed: %v", err)
+	}
+	if scores[0].Int64() != 20 || scores[1].Int64() != 20 {
+		t.Errorf("GetScores = %v, expected points 20 and deaths 20", scores)
+	}
+	if rank, err := mfs.GetFieldRank(ctx, "deaths", "alice"); err != nil || rank != 0 {
+		t.Errorf("GetFieldRank(deaths) = %d, %v, expected 0", rank, err)
+	}
+}
+
+func TestUpdateOptimistic_RanksAndEviction(t *testing.T) {
+	ctx := context.Background()
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{UpdateStrategy: UpdateOptimistic, MaxMembers: 2})
+
+	for member, points := range map[string]float64{"alice": 10, "bob": 20} {
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": points}, member); err != nil {
+			t.Fatalf("IncreaseScore failed: %v", err)
+		}
+	}
+	result, err := mfs.IncreaseScoreWithRank(ctx, map[string]float64{"points": 30}, "carol")
+	if err != nil {
+		t.Fatalf("IncreaseScoreWithRank failed: %v", err)
+	}
+	if result.OldRank != -1 || result.NewRank != 0 {
+		t.Errorf("IncreaseScoreWithRank ranks = %d -> %d, expected -1 -> 0", result.OldRank, result.NewRank)
+	}
+	if exists, err := mfs.MemberExists(ctx, "alice"); err != nil || exists {
+		t.Errorf("MemberExists(alice) = %v, %v, expected alice to be evicted", exists, err)
+	}
+}
+
+func TestUpdateOptimistic_Conflict(t *testing.T) {
+	ctx := context.Background()
+	client, _ := newTestClient(t)
+	mfs, err := New(MultiFieldSetOptions{
+		Name:              "test",
+		Fields:            []Field{{Name: "points", Sort: Descending, MaxValue: 1000, UpdateType: Incremental}},
+		Client:            client,
+		UpdateStrategy:    UpdateOptimistic,
+		OptimisticRetries: 3,
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	// Every attempt is invalidated by a concurrent write to the set
+	attempts := 0
+	mfs.BeforeUpdate(func(ctx context.Context, event *UpdateEvent) error {
+		attempts++
+		return client.ZAdd(ctx, mfs.GetKey(), &redis.Z{Score: 1, Member: "other"}).Err()
+	})
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 1}, "alice"); !errors.Is(err, ErrUpdateConflict) {
+		t.Fatalf("IncreaseScore = %v, expected ErrUpdateConflict", err)
+	}
+	if attempts != 4 {
+		t.Errorf("attempts = %d, expected 4", attempts)
+	}
+}
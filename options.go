@@ -0,0 +1,271 @@
+package zmultifield
+
+import (
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Option configures a set created with NewSet. Options are applied in order to a
+// MultiFieldSetOptions, so every setting of New is available, and an Option can set any field the
+// functions below don't cover:
+//
+//	func(o *zmultifield.MultiFieldSetOptions) { o.OptimisticRetries = 3 }
+type Option func(*MultiFieldSetOptions)
+
+// NewSet creates a MultiFieldSet named name on client, configured with opts, e.g.
+//
+//	zmultifield.NewSet("season", client,
+//		zmultifield.WithField(zmultifield.Field{Name: "points", Sort: zmultifield.Descending, MaxValue: 1e6}),
+//		zmultifield.WithNamespace("game"),
+//	)
+//
+// It validates the resulting options exactly as New does.
+func NewSet(name string, client redis.UniversalClient, opts ...Option) (*MultiFieldSet, error) {
+	options := MultiFieldSetOptions{Name: name, Client: client}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return New(options)
+}
+
+// WithField appends fields to the set's fields, from the most significant to the least.
+func WithField(fields ...Field) Option {
+	return func(o *MultiFieldSetOptions) {
+		o.Fields = append(o.Fields, fields...)
+	}
+}
+
+// WithNamespace prefixes the set's keys with namespace, see MultiFieldSetOptions.Namespace.
+func WithNamespace(namespace string) Option {
+	return func(o *MultiFieldSetOptions) {
+		o.Namespace = namespace
+	}
+}
+
+// WithKeyFunc sets the function combining the namespace and name into the base key.
+func WithKeyFunc(keyFunc func(namespace, name string) string) Option {
+	return func(o *MultiFieldSetOptions) {
+		o.KeyFunc = keyFunc
+	}
+}
+
+// WithKeyBuilder sets the KeyBuilder deriving the set's keys, e.g. HashTagKeyBuilder in Redis
+// Cluster.
+func WithKeyBuilder(builder KeyBuilder) Option {
+	return func(o *MultiFieldSetOptions) {
+		o.KeyBuilder = builder
+	}
+}
+
+// WithLayout sets how fields are packed into the score, see Layout.
+func WithLayout(layout Layout) Option {
+	return func(o *MultiFieldSetOptions) {
+		o.Layout = layout
+	}
+}
+
+// WithReadClient serves read-only queries from client according to preference.
+func WithReadClient(client redis.UniversalClient, preference ReadPreference) Option {
+	return func(o *MultiFieldSetOptions) {
+		o.ReadClient, o.ReadPreference = client, preference
+	}
+}
+
+// WithMetrics sends latency and error observations to recorder.
+func WithMetrics(recorder MetricsRecorder) Option {
+	return func(o *MultiFieldSetOptions) {
+		o.Metrics = recorder
+	}
+}
+
+// WithLogger logs the operations slower than threshold to logger, see
+// MultiFieldSetOptions.SlowThreshold.
+func WithLogger(logger Logger, threshold time.Duration) Option {
+	return func(o *MultiFieldSetOptions) {
+		o.Logger, o.SlowThreshold = logger, threshold
+	}
+}
+
+// WithRetryPolicy retries Redis calls that fail with transient errors according to policy.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(o *MultiFieldSetOptions) {
+		o.RetryPolicy = &policy
+	}
+}
+
+// WithUpdateStrategy selects how IncreaseScore updates members.
+func WithUpdateStrategy(strategy UpdateStrategy) Option {
+	return func(o *MultiFieldSetOptions) {
+		o.UpdateStrategy = strategy
+	}
+}
+
+// WithFieldIndexes maintains a sorted set per field, see MultiFieldSetOptions.MaintainFieldIndexes.
+func WithFieldIndexes() Option {
+	return func(o *MultiFieldSetOptions) {
+		o.MaintainFieldIndexes = true
+	}
+}
+
+// WithUpdatedAt adds an updatedAt field, see MultiFieldSetOptions.TrackUpdatedAt.
+func WithUpdatedAt() Option {
+	return func(o *MultiFieldSetOptions) {
+		o.TrackUpdatedAt = true
+	}
+}
+
+// WithMaxMembers caps the size of the set, evicting the worst members.
+func WithMaxMembers(n int64) Option {
+	return func(o *MultiFieldSetOptions) {
+		o.MaxMembers = n
+	}
+}
+
+// WithUpdateOnlyExisting makes updates fail with ErrMemberNotFound instead of adding members.
+func WithUpdateOnlyExisting() Option {
+	return func(o *MultiFieldSetOptions) {
+		o.UpdateOnlyExisting = true
+	}
+}
+
+// WithRankThresholds enables OnRankChanged hooks for members crossing the given ranks.
+func WithRankThresholds(ranks ...int64) Option {
+	return func(o *MultiFieldSetOptions) {
+		o.RankThresholds = append(o.RankThresholds, ranks...)
+	}
+}
+
+// WithHiddenMembers enables Hide and Unhide.
+func WithHiddenMembers() Option {
+	return func(o *MultiFieldSetOptions) {
+		o.HideMembers = true
+	}
+}
+
+// WithDimensions keeps one sorted set per value of each dimension, see
+// MultiFieldSetOptions.Dimensions.
+func WithDimensions(dimensions ...Dimension) Option {
+	return func(o *MultiFieldSetOptions) {
+		o.Dimensions = append(o.Dimensions, dimensions...)
+	}
+}
+
+// WithNotifications publishes change notifications after updates.
+func WithNotifications(opts NotificationOptions) Option {
+	return func(o *MultiFieldSetOptions) {
+		o.Notifications = &opts
+	}
+}
+
+// WithEventSink emits the set's events to sink, see MultiFieldSetOptions.EventSink.
+func WithEventSink(sink EventSink) Option {
+	return func(o *MultiFieldSetOptions) {
+		o.EventSink = sink
+	}
+}
+
+// WithHistory records every member's field values after each update.
+func WithHistory(opts HistoryOptions) Option {
+	return func(o *MultiFieldSetOptions) {
+		o.History = &opts
+	}
+}
+
+// WithParticipation counts the unique members updated per period, see UniqueParticipants.
+func WithParticipation(opts ParticipationOptions) Option {
+	return func(o *MultiFieldSetOptions) {
+		o.Participation = &opts
+	}
+}
+
+// WithActivity keeps an index of every member's last update, see GetActiveMembers.
+func WithActivity() Option {
+	return func(o *MultiFieldSetOptions) {
+		o.TrackActivity = true
+	}
+}
+
+// WithFreezing enables Freeze and Unfreeze.
+func WithFreezing() Option {
+	return func(o *MultiFieldSetOptions) {
+		o.Freezable = true
+	}
+}
+
+// WithQuarantine enables Quarantine and Unquarantine.
+func WithQuarantine() Option {
+	return func(o *MultiFieldSetOptions) {
+		o.Quarantinable = true
+	}
+}
+
+// WithAudit records every write in an audit stream, see MultiFieldSetOptions.Audit and Replay.
+func WithAudit() Option {
+	return func(o *MultiFieldSetOptions) {
+		o.Audit = true
+	}
+}
+
+// WithTiers splits the members into tiers, best first, see MultiFieldSetOptions.Tiers.
+func WithTiers(tiers ...Tier) Option {
+	return func(o *MultiFieldSetOptions) {
+		o.Tiers = append(o.Tiers, tiers...)
+	}
+}
+
+// WithDivisions partitions the members into divisions, see MultiFieldSetOptions.Divisions.
+func WithDivisions(opts DivisionOptions) Option {
+	return func(o *MultiFieldSetOptions) {
+		o.Divisions = &opts
+	}
+}
+
+// WithIdempotencyTTL sets how long the idempotency keys of updates are remembered.
+func WithIdempotencyTTL(ttl time.Duration) Option {
+	return func(o *MultiFieldSetOptions) {
+		o.IdempotencyTTL = ttl
+	}
+}
+
+// WithLockTTL sets how long the lock of administrative operations outlives a crashed holder.
+func WithLockTTL(ttl time.Duration) Option {
+	return func(o *MultiFieldSetOptions) {
+		o.LockTTL = ttl
+	}
+}
+
+// WithComputedFields adds virtual fields computed from the stored ones at read time.
+func WithComputedFields(fields ...ComputedField) Option {
+	return func(o *MultiFieldSetOptions) {
+		o.ComputedFields = append(o.ComputedFields, fields...)
+	}
+}
+
+// WithWriteQueue queues updates that fail with transient errors for replay.
+func WithWriteQueue(opts WriteQueueOptions) Option {
+	return func(o *MultiFieldSetOptions) {
+		o.WriteQueue = &opts
+	}
+}
+
+// WithCache caches GetRank, GetScores and GetTopMembers results in process.
+func WithCache(opts CacheOptions) Option {
+	return func(o *MultiFieldSetOptions) {
+		o.Cache = &opts
+	}
+}
+
+// WithGuard limits the size and rate of updates.
+func WithGuard(opts GuardOptions) Option {
+	return func(o *MultiFieldSetOptions) {
+		o.Guard = &opts
+	}
+}
+
+// WithSalt adds a salt field of deterministic per-member noise, see MultiFieldSetOptions.Salt.
+func WithSalt(opts SaltOptions) Option {
+	return func(o *MultiFieldSetOptions) {
+		o.Salt = &opts
+	}
+}
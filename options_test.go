@@ -0,0 +1,41 @@
+package zmultifield
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNewSet(t *testing.T) {
+	client, _ := newTestClient(t)
+	mfs, err := NewSet("season", client,
+		WithField(Field{Name: "points", Sort: Descending, MaxValue: 1000, UpdateType: Incremental}),
+		WithField(Field{Name: "deaths", Sort: Ascending, MaxValue: 100, UpdateType: Incremental}),
+		WithNamespace("game"),
+		WithKeyBuilder(HashTagKeyBuilder{}),
+		WithMaxMembers(2),
+		WithHiddenMembers(),
+		func(o *MultiFieldSetOptions) { o.OptimisticRetries = 3 },
+	)
+	if err != nil {
+		t.Fatalf("NewSet() error = %v", err)
+	}
+	if got := mfs.GetKey(); got != "{game:season}" {
+		t.Errorf("GetKey() = %q, expected {game:season}", got)
+	}
+	if len(mfs.fields) != 2 || mfs.maxMembers != 2 || !mfs.hideMembers || mfs.optimisticRetries != 3 {
+		t.Errorf("NewSet() didn't apply every option: %+v", mfs)
+	}
+
+	ctx := context.Background()
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 10}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+
+	// Options are validated like New's
+	if _, err := NewSet("season", client); err == nil {
+		t.Error("NewSet() without fields succeeded, expected an error")
+	}
+	if _, err := NewSet("season", client, WithField(Field{Name: "points", MaxValue: 10}), WithMaxMembers(-1)); err == nil {
+		t.Error("NewSet() with negative MaxMembers succeeded, expected an error")
+	}
+}
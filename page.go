@@ -0,0 +1,136 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// LeaderboardEntry is a member of a LeaderboardPage together with its 0-based rank.
+type LeaderboardEntry struct {
+	Rank   int64        `json:"rank"`
+	Member string       `json:"member"`
+	Scores []FieldScore `json:"scores"`
+}
+
+// LeaderboardPage is one page of a leaderboard, as returned by GetPage.
+type LeaderboardPage struct {
+	Entries      []LeaderboardEntry `json:"entries"`
+	TotalMembers int64              `json:"totalMembers"`
+	Page         int64              `json:"page"`
+	PageSize     int64              `json:"pageSize"`
+}
+
+// GetPage returns the 1-based page of the leaderboard with pageSize entries per page, along with
+// the total number of members, using a single pipeline. Hidden members are left out of both.
+func (mfs *MultiFieldSet) GetPage(ctx context.Context, page, pageSize int64) (_ *LeaderboardPage, err error) {
+	defer mfs.observeRead("GetPage", time.Now(), &err)
+
+	if page < 1 || pageSize < 1 {
+		return nil, errors.New("page and page size must be positive")
+	}
+	offset := (page - 1) * pageSize
+
+	var results []redis.Z
+	var total int64
+	err = mfs.read(ctx, func(client redis.UniversalClient) error {
+		if mfs.hideMembers {
+			keys := []string{mfs.key, mfs.hiddenKey()}
+			var rangeCmd, countCmd *redis.Cmd
+			_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+				rangeCmd = visibleRangeScript.Eval(ctx, pipe, keys, offset, pageSize, reverseArg(false))
+				countCmd = visibleCountScript.Eval(ctx, pipe, keys)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			reply, err := rangeCmd.StringSlice()
+			if err != nil {
+				return err
+			}
+			if results, err = parseZSlice(reply); err != nil {
+				return err
+			}
+			total, err = countCmd.Int64()
+			return err
+		}
+
+		var rangeCmd *redis.ZSliceCmd
+		var cardCmd *redis.IntCmd
+		_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			rangeCmd = pipe.ZRangeWithScores(ctx, mfs.key, offset, offset+pageSize-1)
+			cardCmd = pipe.ZCard(ctx, mfs.key)
+			return nil
+		})
+		results, total = rangeCmd.Val(), cardCmd.Val()
+		return err
+	})
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "GetPage", "", err)
+	}
+
+	members, err := mfs.decodeMembers(results)
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "GetPage", "", err)
+	}
+	entries := make([]LeaderboardEntry, len(members))
+	for i, m := range members {
+		entries[i] = LeaderboardEntry{Rank: offset + int64(i), Member: m.Member, Scores: m.Scores}
+	}
+	return &LeaderboardPage{
+		Entries:      entries,
+		TotalMembers: total,
+		Page:         page,
+		PageSize:     pageSize,
+	}, nil
+}
+
+// GetMembersAround returns the member and up to radius members ranked directly above and below it,
+// in leaderboard order, or ErrMemberNotFound if the member is not in the set. Hidden members are
+// skipped, and a hidden member is not found.
+func (mfs *MultiFieldSet) GetMembersAround(ctx context.Context, member string, radius int64) (_ []LeaderboardEntry, err error) {
+	defer mfs.observeRead("GetMembersAround", time.Now(), &err)
+
+	var start int64
+	var results []redis.Z
+	err = mfs.read(ctx, func(client redis.UniversalClient) error {
+		var rank int64
+		var err error
+		if mfs.hideMembers {
+			rank, err = mfs.visibleRank(ctx, client, mfs.key, member)
+		} else {
+			rank, err = client.ZRank(ctx, mfs.key, member).Result()
+		}
+		if err != nil {
+			return err
+		}
+		start = rank - radius
+		if start < 0 {
+			start = 0
+		}
+		if mfs.hideMembers {
+			results, err = mfs.visibleRange(ctx, client, mfs.key, start, rank+radius-start+1)
+		} else {
+			results, err = client.ZRangeWithScores(ctx, mfs.key, start, rank+radius).Result()
+		}
+		return err
+	})
+	if err == redis.Nil {
+		return nil, ErrMemberNotFound
+	} else if err != nil {
+		return nil, mfs.runOnError(ctx, "GetMembersAround", member, err)
+	}
+
+	members, err := mfs.decodeMembers(results)
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "GetMembersAround", member, err)
+	}
+	entries := make([]LeaderboardEntry, len(members))
+	for i, m := range members {
+		entries[i] = LeaderboardEntry{Rank: start + int64(i), Member: m.Member, Scores: m.Scores}
+	}
+	return entries, nil
+}
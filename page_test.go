@@ -0,0 +1,78 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestGetPage(t *testing.T) {
+	mfs := newTestSet(t)
+	ctx := context.Background()
+
+	for i, member := range []string{"alice", "bob", "carol", "dave", "erin"} {
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": float64(100 - i*10)}, member); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+	}
+
+	page, err := mfs.GetPage(ctx, 2, 2)
+	if err != nil {
+		t.Fatalf("GetPage() error = %v", err)
+	}
+	if page.TotalMembers != 5 || page.Page != 2 || page.PageSize != 2 {
+		t.Errorf("GetPage() = %+v, expected 5 members on page 2 of size 2", page)
+	}
+	if len(page.Entries) != 2 {
+		t.Fatalf("GetPage() returned %d entries, expected 2", len(page.Entries))
+	}
+	if e := page.Entries[0]; e.Rank != 2 || e.Member != "carol" || e.Scores[0].Int64() != 80 {
+		t.Errorf("Entries[0] = %+v, expected carol at rank 2 with 80 points", e)
+	}
+	if e := page.Entries[1]; e.Rank != 3 || e.Member != "dave" {
+		t.Errorf("Entries[1] = %+v, expected dave at rank 3", e)
+	}
+
+	last, err := mfs.GetPage(ctx, 3, 2)
+	if err != nil {
+		t.Fatalf("GetPage() error = %v", err)
+	}
+	if len(last.Entries) != 1 || last.Entries[0].Member != "erin" {
+		t.Errorf("GetPage(3) entries = %+v, expected erin", last.Entries)
+	}
+
+	if _, err := mfs.GetPage(ctx, 0, 2); err == nil {
+		t.Errorf("GetPage(0) accepted page 0")
+	}
+}
+
+func TestGetMembersAround(t *testing.T) {
+	mfs := newTestSet(t)
+	ctx := context.Background()
+
+	for i, member := range []string{"alice", "bob", "carol", "dave"} {
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": float64(100 - i*10)}, member); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+	}
+
+	entries, err := mfs.GetMembersAround(ctx, "bob", 2)
+	if err != nil {
+		t.Fatalf("GetMembersAround() error = %v", err)
+	}
+	if len(entries) != 4 || entries[0].Member != "alice" || entries[0].Rank != 0 || entries[3].Rank != 3 {
+		t.Errorf("GetMembersAround(bob, 2) = %+v, expected all four members from rank 0", entries)
+	}
+
+	entries, err = mfs.GetMembersAround(ctx, "carol", 1)
+	if err != nil {
+		t.Fatalf("GetMembersAround() error = %v", err)
+	}
+	if len(entries) != 3 || entries[0].Member != "bob" || entries[0].Rank != 1 || entries[2].Member != "dave" {
+		t.Errorf("GetMembersAround(carol, 1) = %+v, expected bob, carol, dave", entries)
+	}
+
+	if _, err := mfs.GetMembersAround(ctx, "nobody", 1); !errors.Is(err, ErrMemberNotFound) {
+		t.Errorf("GetMembersAround(nobody) error = %v, expected %v", err, ErrMemberNotFound)
+	}
+}
@@ -0,0 +1,82 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPeriodOf(t *testing.T) {
+	at := time.Date(2024, 3, 15, 22, 30, 0, 0, time.UTC) // a Friday
+	tests := []struct {
+		unit  PeriodUnit
+		label string
+		start time.Time
+		end   time.Time
+	}{
+		{Daily, "2024-03-15", time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 16, 0, 0, 0, 0, time.UTC)},
+		{Weekly, "2024-W11", time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 18, 0, 0, 0, 0, time.UTC)},
+		{Monthly, "2024-03", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		p := tt.unit.Of(at)
+		if p.String() != tt.label || !p.Start.Equal(tt.start) || !p.End().Equal(tt.end) {
+			t.Errorf("%d.Of() = %s from %v to %v, expected %s from %v to %v", tt.unit, p, p.Start, p.End(), tt.label, tt.start, tt.end)
+		}
+	}
+
+	// A Sunday belongs to the week that started on the previous Monday
+	if p := Weekly.Of(time.Date(2024, 3, 17, 12, 0, 0, 0, time.UTC)); p.String() != "2024-W11" {
+		t.Errorf("Weekly.Of(Sunday) = %s, expected 2024-W11", p)
+	}
+}
+
+func TestUniqueParticipants(t *testing.T) {
+	opts := MultiFieldSetOptions{Participation: &ParticipationOptions{Periods: []PeriodUnit{Daily, Weekly}, Retention: time.Hour}}
+	mfs := newTestSetWithOptions(t, opts)
+	ctx := context.Background()
+
+	for _, member := range []string{"alice", "bob", "alice", "carol", "bob"} {
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 1}, member); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+	}
+
+	now := time.Now()
+	for _, period := range []Period{Daily.Of(now), Weekly.Of(now)} {
+		if count, err := mfs.UniqueParticipants(ctx, period); err != nil || count != 3 {
+			t.Errorf("UniqueParticipants(%s) = %d, %v, expected 3", period, count, err)
+		}
+	}
+	if count, err := mfs.UniqueParticipants(ctx, Monthly.Of(now)); err != nil || count != 0 {
+		t.Errorf("UniqueParticipants() of an uncounted unit = %d, %v, expected 0", count, err)
+	}
+	yesterday := Daily.Of(now.AddDate(0, 0, -1))
+	if count, err := mfs.UniqueParticipants(ctx, yesterday, Daily.Of(now)); err != nil || count != 3 {
+		t.Errorf("UniqueParticipants() over two days = %d, %v, expected 3", count, err)
+	}
+
+	ttl, err := mfs.client.TTL(ctx, mfs.participantsKey(Daily.Of(now))).Result()
+	if err != nil || ttl <= time.Hour || ttl > 25*time.Hour {
+		t.Errorf("TTL() = %v, %v, expected the end of the day plus the retention", ttl, err)
+	}
+}
+
+func TestUniqueParticipants_Disabled(t *testing.T) {
+	mfs := newTestSet(t)
+	if _, err := mfs.UniqueParticipants(context.Background(), Daily.Of(time.Now())); !errors.Is(err, ErrParticipationDisabled) {
+		t.Errorf("UniqueParticipants() error = %v, expected ErrParticipationDisabled", err)
+	}
+
+	client, _ := newTestClient(t)
+	_, err := New(MultiFieldSetOptions{
+		Name:          "test",
+		Client:        client,
+		Fields:        []Field{{Name: "points", MaxValue: 100}},
+		Participation: &ParticipationOptions{Periods: []PeriodUnit{PeriodUnit(7)}},
+	})
+	if err == nil {
+		t.Error("New() with an unknown period succeeded, expected an error")
+	}
+}
@@ -0,0 +1,115 @@
+package zmultifield
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// popWithIndexesScript pops members from the main set and removes them from every field index.
+// KEYS[1] is the main set and KEYS[2..n] the field indexes. ARGV[1] is ZPOPMIN or ZPOPMAX and
+// ARGV[2] the count. The reply is the same flat member/score list as the pop command.
+var popWithIndexesScript = newWriteScript(`
+local popped = redis.call(ARGV[1], KEYS[1], ARGV[2])
+for i = 1, #popped, 2 do
+	for k = 2, #KEYS do
+		redis.call('ZREM', KEYS[k], popped[i])
+	end
+end
+return popped
+`)
+
+// PopTopMember removes and returns the top member of the sorted set, or ErrMemberNotFound if the
+// set is empty. Together with PopBottomMember it lets a set be used as a priority queue.
+func (mfs *MultiFieldSet) PopTopMember(ctx context.Context) (*MemberScores, error) {
+	return mfs.popOne(ctx, "PopTopMember", "ZPOPMIN")
+}
+
+// PopBottomMember removes and returns the bottom member of the sorted set, or ErrMemberNotFound
+// if the set is empty.
+func (mfs *MultiFieldSet) PopBottomMember(ctx context.Context) (*MemberScores, error) {
+	return mfs.popOne(ctx, "PopBottomMember", "ZPOPMAX")
+}
+
+// PopTopMembers removes and returns up to count members from the top of the sorted set, best first.
+func (mfs *MultiFieldSet) PopTopMembers(ctx context.Context, count int64) ([]MemberScores, error) {
+	return mfs.pop(ctx, "PopTopMembers", "ZPOPMIN", count)
+}
+
+// PopBottomMembers removes and returns up to count members from the bottom of the sorted set,
+// worst first.
+func (mfs *MultiFieldSet) PopBottomMembers(ctx context.Context, count int64) ([]MemberScores, error) {
+	return mfs.pop(ctx, "PopBottomMembers", "ZPOPMAX", count)
+}
+
+// popOne pops a single member with the given command.
+func (mfs *MultiFieldSet) popOne(ctx context.Context, op string, command string) (*MemberScores, error) {
+	members, err := mfs.pop(ctx, op, command, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(members) == 0 {
+		return nil, ErrMemberNotFound
+	}
+	return &members[0], nil
+}
+
+// pop removes up to count members with ZPOPMIN or ZPOPMAX and decodes them, keeping the field
+// indexes in sync when they are maintained.
+func (mfs *MultiFieldSet) pop(ctx context.Context, op string, command string, count int64) ([]MemberScores, error) {
+	if count <= 0 {
+		return []MemberScores{}, nil
+	}
+
+	var results []redis.Z
+	err := mfs.writeOnce(ctx, func(client redis.UniversalClient) error {
+		var err error
+		if mfs.maintainFieldIndexes || mfs.freezable || mfs.audit {
+			results, err = mfs.popWithIndexes(ctx, client, command, count)
+		} else if command == "ZPOPMIN" {
+			results, err = client.ZPopMin(ctx, mfs.key, count).Result()
+		} else {
+			results, err = client.ZPopMax(ctx, mfs.key, count).Result()
+		}
+		return err
+	})
+	if err != nil {
+		return nil, mfs.runOnError(ctx, op, "", err)
+	}
+
+	members, err := mfs.decodeMembers(results)
+	if err != nil {
+		return nil, mfs.runOnError(ctx, op, "", err)
+	}
+	return members, nil
+}
+
+// popWithIndexes runs popWithIndexesScript and parses its reply.
+func (mfs *MultiFieldSet) popWithIndexes(ctx context.Context, client redis.UniversalClient, command string, count int64) ([]redis.Z, error) {
+	keys := []string{mfs.key}
+	if mfs.maintainFieldIndexes {
+		for _, field := range mfs.fields {
+			keys = append(keys, mfs.fieldIndexKey(field))
+		}
+	}
+
+	reply, err := popWithIndexesScript.Run(ctx, client, mfs.writeGuard(), keys, command, count).StringSlice()
+	if err != nil {
+		return nil, err
+	}
+	return parseZSlice(reply)
+}
+
+// parseZSlice parses a flat member/score list, as returned by ZRANGE WITHSCORES from a script.
+func parseZSlice(reply []string) ([]redis.Z, error) {
+	results := make([]redis.Z, 0, len(reply)/2)
+	for i := 0; i+1 < len(reply); i += 2 {
+		score, err := strconv.ParseFloat(reply[i+1], 64)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, redis.Z{Score: score, Member: reply[i]})
+	}
+	return results, nil
+}
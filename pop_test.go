@@ -0,0 +1,54 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestPop(t *testing.T) {
+	for _, indexes := range []bool{false, true} {
+		mfs := newTestSetWithOptions(t, MultiFieldSetOptions{MaintainFieldIndexes: indexes})
+		ctx := context.Background()
+
+		for i, member := range []string{"alice", "bob", "carol", "dave"} {
+			if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": float64(100 - i*10), "deaths": 1}, member); err != nil {
+				t.Fatalf("IncreaseScore(%s) error = %v", member, err)
+			}
+		}
+
+		top, err := mfs.PopTopMember(ctx)
+		if err != nil {
+			t.Fatalf("PopTopMember() error = %v", err)
+		}
+		if top.Member != "alice" || top.Scores[0].Score.Int64() != 100 || top.Scores[1].Score.Int64() != 1 {
+			t.Errorf("PopTopMember() = %s %v/%v, expected alice 100/1", top.Member, top.Scores[0].Score, top.Scores[1].Score)
+		}
+
+		bottom, err := mfs.PopBottomMembers(ctx, 2)
+		if err != nil {
+			t.Fatalf("PopBottomMembers() error = %v", err)
+		}
+		if len(bottom) != 2 || bottom[0].Member != "dave" || bottom[1].Member != "carol" {
+			t.Errorf("PopBottomMembers(2) = %+v, expected dave, carol", bottom)
+		}
+
+		rest, err := mfs.PopTopMembers(ctx, 5)
+		if err != nil {
+			t.Fatalf("PopTopMembers() error = %v", err)
+		}
+		if len(rest) != 1 || rest[0].Member != "bob" {
+			t.Errorf("PopTopMembers(5) = %+v, expected bob", rest)
+		}
+
+		if _, err := mfs.PopBottomMember(ctx); !errors.Is(err, ErrMemberNotFound) {
+			t.Errorf("PopBottomMember() on empty set error = %v, expected ErrMemberNotFound", err)
+		}
+
+		if indexes {
+			if n, _ := mfs.client.ZCard(ctx, mfs.fieldIndexKey(mfs.fields[1])).Result(); n != 0 {
+				t.Errorf("deaths index has %d members after popping everything, expected 0", n)
+			}
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package zmultifield
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// quarantineScript moves a member from the main set to the quarantine set and records why, in one
+// atomic step.
+//
+// KEYS[1] is the main set, KEYS[2] the quarantine set, KEYS[3] the member's quarantine record and
+// KEYS[4..] the field indexes. ARGV[1] is the member, ARGV[2] the reason and ARGV[3] the time in
+// epoch milliseconds. It returns the member's rank before it was moved, or -1 if it isn't in the
+// main set.
+var quarantineScript = newWriteScript(`
+local zscore = redis.call('ZSCORE', KEYS[1], ARGV[1])
+if not zscore then
+	return -1
+end
+local rank = redis.call('ZRANK', KEYS[1], ARGV[1])
+redis.call('ZADD', KEYS[2], zscore, ARGV[1])
+redis.call('ZREM', KEYS[1], ARGV[1])
+for i = 4, #KEYS do
+	redis.call('ZREM', KEYS[i], ARGV[1])
+end
+redis.call('DEL', KEYS[3])
+redis.call('HSET', KEYS[3], 'reason', ARGV[2], 'quarantined_at', ARGV[3], 'rank', rank)
+return rank
+`)
+
+// QuarantineRecord describes a member pulled from the set with Quarantine.
+type QuarantineRecord struct {
+	Member        string
+	Reason        string
+	QuarantinedAt time.Time
+	// Rank is the member's 0-based rank when it was quarantined.
+	Rank   int64
+	Scores []FieldScore
+}
+
+// quarantineKey returns the key of the sorted set holding quarantined members with their zscores.
+func (mfs *MultiFieldSet) quarantineKey() string {
+	return mfs.derivedKey("quarantine")
+}
+
+// quarantineRecordKey returns the key of the hash recording why a member was quarantined.
+func (mfs *MultiFieldSet) quarantineRecordKey(member string) string {
+	return mfs.derivedKey("quarantine:" + member)
+}
+
+// Quarantine moves a member suspected of cheating out of the set into a parallel quarantine set,
+// recording reason and the member's rank. The member disappears from every read, but its scores,
+// metadata and history are kept as evidence until Unquarantine restores it or RemoveMember deletes
+// it. While it is quarantined, updates of the member fail with ErrQuarantined. Quarantine works on
+// frozen sets, so members can be pulled before rewards are granted. It fails with
+// ErrMemberNotFound if the member isn't in the set and requires Quarantinable.
+func (mfs *MultiFieldSet) Quarantine(ctx context.Context, member, reason string) error {
+	if !mfs.quarantinable {
+		return mfs.runOnError(ctx, "Quarantine", member, ErrQuarantineDisabled)
+	}
+	var dimensions map[string][]string
+	if len(mfs.dimensions) > 0 {
+		var err error
+		if dimensions, err = mfs.memberDimensionKeys(ctx, []string{member}); err != nil {
+			return mfs.runOnError(ctx, "Quarantine", member, err)
+		}
+	}
+
+	keys := []string{mfs.key, mfs.quarantineKey(), mfs.quarantineRecordKey(member)}
+	if mfs.maintainFieldIndexes {
+		for _, field := range mfs.fields {
+			keys = append(keys, mfs.fieldIndexKey(field))
+		}
+	}
+	var rank int64
+	err := mfs.write(ctx, func(client redis.UniversalClient) error {
+		var err error
+		rank, err = quarantineScript.Run(ctx, client, mfs.auditGuard(), keys, member, reason, time.Now().UnixMilli()).Int64()
+		return err
+	})
+	if err != nil {
+		return mfs.runOnError(ctx, "Quarantine", member, err)
+	}
+	if rank < 0 {
+		return mfs.runOnError(ctx, "Quarantine", member, ErrMemberNotFound)
+	}
+
+	// The recorded dimension values are kept so Unquarantine can put the member back
+	if len(dimensions[member]) > 0 {
+		err = mfs.write(ctx, func(client redis.UniversalClient) error {
+			_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+				for _, key := range dimensions[member] {
+					pipe.ZRem(ctx, key, member)
+				}
+				return nil
+			})
+			return err
+		})
+	}
+	return mfs.runOnError(ctx, "Quarantine", member, err)
+}
+
+// Unquarantine moves a member pulled with Quarantine back into the set, field indexes and dimension
+// sets with the scores it had, and deletes its quarantine record. It fails with ErrMemberNotFound
+// if the member isn't quarantined and requires Quarantinable.
+func (mfs *MultiFieldSet) Unquarantine(ctx context.Context, member string) error {
+	if !mfs.quarantinable {
+		return mfs.runOnError(ctx, "Unquarantine", member, ErrQuarantineDisabled)
+	}
+	var score float64
+	err := mfs.primary(ctx, func(client redis.UniversalClient) error {
+		var err error
+		score, err = client.ZScore(ctx, mfs.quarantineKey(), member).Result()
+		return err
+	})
+	if err == redis.Nil {
+		return mfs.runOnError(ctx, "Unquarantine", member, ErrMemberNotFound)
+	} else if err != nil {
+		return mfs.runOnError(ctx, "Unquarantine", member, err)
+	}
+	zscore, err := mfs.zscoreOf(member, score)
+	if err != nil {
+		return mfs.runOnError(ctx, "Unquarantine", member, err)
+	}
+	var dimensions map[string][]string
+	if len(mfs.dimensions) > 0 {
+		if dimensions, err = mfs.memberDimensionKeys(ctx, []string{member}); err != nil {
+			return mfs.runOnError(ctx, "Unquarantine", member, err)
+		}
+	}
+
+	err = mfs.write(ctx, func(client redis.UniversalClient) error {
+		return mfs.txPipelined(ctx, client, func(pipe redis.Pipeliner) error {
+			z := &redis.Z{Score: score, Member: member}
+			pipe.ZAdd(ctx, mfs.key, z)
+			mfs.recordSet(ctx, pipe, member, score)
+			if mfs.maintainFieldIndexes {
+				for _, field := range mfs.fields {
+					raw := mfs.extractFieldScore(field, zscore)
+					pipe.ZAdd(ctx, mfs.fieldIndexKey(field), &redis.Z{Score: float64(raw.Int64()), Member: member})
+				}
+			}
+			for _, key := range dimensions[member] {
+				pipe.ZAdd(ctx, key, z)
+			}
+			pipe.ZRem(ctx, mfs.quarantineKey(), member)
+			pipe.Del(ctx, mfs.quarantineRecordKey(member))
+			return nil
+		})
+	})
+	return mfs.runOnError(ctx, "Unquarantine", member, err)
+}
+
+// IsQuarantined reports whether a member is quarantined.
+func (mfs *MultiFieldSet) IsQuarantined(ctx context.Context, member string) (_ bool, err error) {
+	defer mfs.observeRead("IsQuarantined", time.Now(), &err)
+
+	if !mfs.quarantinable {
+		return false, nil
+	}
+	quarantined, err := mfs.isQuarantined(ctx, member)
+	if err != nil {
+		return false, mfs.runOnError(ctx, "IsQuarantined", member, err)
+	}
+	return quarantined, nil
+}
+
+// isQuarantined reports whether member is in the quarantine set, reading the primary.
+func (mfs *MultiFieldSet) isQuarantined(ctx context.Context, member string) (bool, error) {
+	err := mfs.primary(ctx, func(client redis.UniversalClient) error {
+		return client.ZScore(ctx, mfs.quarantineKey(), member).Err()
+	})
+	if err == redis.Nil {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// GetQuarantined returns the records of every quarantined member, in leaderboard order, e.g. for a
+// moderation queue. It requires Quarantinable.
+func (mfs *MultiFieldSet) GetQuarantined(ctx context.Context) (_ []QuarantineRecord, err error) {
+	defer mfs.observeRead("GetQuarantined", time.Now(), &err)
+
+	if !mfs.quarantinable {
+		return nil, mfs.runOnError(ctx, "GetQuarantined", "", ErrQuarantineDisabled)
+	}
+	var results []redis.Z
+	var recordCmds []*redis.StringStringMapCmd
+	err = mfs.read(ctx, func(client redis.UniversalClient) error {
+		results, err = client.ZRangeWithScores(ctx, mfs.quarantineKey(), 0, -1).Result()
+		if err != nil || len(results) == 0 {
+			return err
+		}
+		_, err = client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			recordCmds = make([]*redis.StringStringMapCmd, len(results))
+			for i, z := range results {
+				recordCmds[i] = pipe.HGetAll(ctx, mfs.quarantineRecordKey(memberName(z)))
+			}
+			return nil
+		})
+		return err
+	})
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "GetQuarantined", "", err)
+	}
+
+	records := make([]QuarantineRecord, len(results))
+	for i, z := range results {
+		member := memberName(z)
+		zscore, err := mfs.zscoreOf(member, z.Score)
+		if err != nil {
+			return nil, mfs.runOnError(ctx, "GetQuarantined", member, err)
+		}
+		fields := recordCmds[i].Val()
+		at, _ := strconv.ParseInt(fields["quarantined_at"], 10, 64)
+		rank, _ := strconv.ParseInt(fields["rank"], 10, 64)
+		records[i] = QuarantineRecord{
+			Member:        member,
+			Reason:        fields["reason"],
+			QuarantinedAt: time.UnixMilli(at),
+			Rank:          rank,
+			Scores:        mfs.withComputed(mfs.zscoreToAllFieldScores(zscore)),
+		}
+	}
+	return records, nil
+}
+
+// checkQuarantined is the before-update hook of Quarantinable sets, failing updates of quarantined
+// members with ErrQuarantined. A member quarantined while its update is in flight may be written
+// back to the set; quarantining it again moves it out.
+func (mfs *MultiFieldSet) checkQuarantined(ctx context.Context, event *UpdateEvent) error {
+	quarantined, err := mfs.isQuarantined(ctx, event.Member)
+	if err != nil {
+		return err
+	}
+	if quarantined {
+		return ErrQuarantined
+	}
+	return nil
+}
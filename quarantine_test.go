@@ -0,0 +1,96 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestQuarantine(t *testing.T) {
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{
+		Quarantinable:        true,
+		MaintainFieldIndexes: true,
+		Dimensions:           []Dimension{{Name: "country"}},
+	})
+	ctx := context.Background()
+	if err := mfs.SetMemberMeta(ctx, "mallory", map[string]string{"country": "DE"}); err != nil {
+		t.Fatalf("SetMemberMeta() error = %v", err)
+	}
+	for member, points := range map[string]float64{"alice": 50, "mallory": 900, "bob": 40} {
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": points, "deaths": 3}, member); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+	}
+
+	if err := mfs.Quarantine(ctx, "mallory", "speed hack"); err != nil {
+		t.Fatalf("Quarantine() error = %v", err)
+	}
+	if top, err := mfs.GetTopMembers(ctx, 10); err != nil || len(top) != 2 || top[0].Member != "alice" {
+		t.Errorf("GetTopMembers() = %v, %v, expected alice and bob", top, err)
+	}
+	if _, err := mfs.GetFieldRank(ctx, "deaths", "mallory"); !errors.Is(err, ErrMemberNotFound) {
+		t.Errorf("GetFieldRank() of a quarantined member error = %v, expected ErrMemberNotFound", err)
+	}
+	if members, err := mfs.GetMembers(ctx, 10, 0, InDimension("country", "DE")); err != nil || len(members) != 0 {
+		t.Errorf("GetMembers(InDimension) = %v, %v, expected no member", members, err)
+	}
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 1}, "mallory"); !errors.Is(err, ErrQuarantined) {
+		t.Errorf("IncreaseScore() of a quarantined member error = %v, expected ErrQuarantined", err)
+	}
+	if err := mfs.Quarantine(ctx, "mallory", "again"); !errors.Is(err, ErrMemberNotFound) {
+		t.Errorf("Quarantine() twice error = %v, expected ErrMemberNotFound", err)
+	}
+
+	records, err := mfs.GetQuarantined(ctx)
+	if err != nil || len(records) != 1 {
+		t.Fatalf("GetQuarantined() = %v, %v, expected mallory", records, err)
+	}
+	r := records[0]
+	if r.Member != "mallory" || r.Reason != "speed hack" || r.Rank != 0 || r.QuarantinedAt.IsZero() || FieldValue(r.Scores, "points") != 900 {
+		t.Errorf("record = %+v, expected mallory quarantined at rank 0 for a speed hack", r)
+	}
+	if meta, err := mfs.GetMemberMeta(ctx, "mallory"); err != nil || meta["country"] != "DE" {
+		t.Errorf("GetMemberMeta() = %v, %v, expected the metadata to be kept", meta, err)
+	}
+
+	if err := mfs.Unquarantine(ctx, "mallory"); err != nil {
+		t.Fatalf("Unquarantine() error = %v", err)
+	}
+	if rank, err := mfs.GetRank(ctx, "mallory"); err != nil || rank != 0 {
+		t.Errorf("GetRank() = %d, %v, expected mallory back at rank 0", rank, err)
+	}
+	if rank, err := mfs.GetFieldRank(ctx, "deaths", "mallory"); err != nil || rank < 0 {
+		t.Errorf("GetFieldRank() = %d, %v, expected mallory back in the index", rank, err)
+	}
+	if members, err := mfs.GetMembers(ctx, 10, 0, InDimension("country", "DE")); err != nil || len(members) != 1 {
+		t.Errorf("GetMembers(InDimension) = %v, %v, expected mallory", members, err)
+	}
+	if quarantined, err := mfs.IsQuarantined(ctx, "mallory"); err != nil || quarantined {
+		t.Errorf("IsQuarantined() = %v, %v, expected false", quarantined, err)
+	}
+	if err := mfs.Unquarantine(ctx, "mallory"); !errors.Is(err, ErrMemberNotFound) {
+		t.Errorf("Unquarantine() twice error = %v, expected ErrMemberNotFound", err)
+	}
+}
+
+func TestQuarantine_Remove(t *testing.T) {
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{Quarantinable: true})
+	ctx := context.Background()
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 10}, "mallory"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	if err := mfs.Quarantine(ctx, "mallory", "botting"); err != nil {
+		t.Fatalf("Quarantine() error = %v", err)
+	}
+	if n, err := mfs.RemoveMember(ctx, "mallory"); err != nil || n != 0 {
+		t.Errorf("RemoveMember() = %d, %v, expected the quarantined member to be deleted uncounted", n, err)
+	}
+	if records, err := mfs.GetQuarantined(ctx); err != nil || len(records) != 0 {
+		t.Errorf("GetQuarantined() = %v, %v, expected no record", records, err)
+	}
+
+	plain := newTestSet(t)
+	if err := plain.Quarantine(ctx, "mallory", "botting"); !errors.Is(err, ErrQuarantineDisabled) {
+		t.Errorf("Quarantine() without Quarantinable error = %v, expected ErrQuarantineDisabled", err)
+	}
+}
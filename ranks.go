@@ -0,0 +1,286 @@
+package zmultifield
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// UpdateResult is the outcome of IncreaseScoreWithRank. Ranks are 0-based and -1 means the member
+// wasn't in the set.
+type UpdateResult struct {
+	ZScore  *big.Int
+	OldRank int64
+	NewRank int64
+}
+
+// RankChangedEvent describes a member crossing one of the RankThresholds: with a threshold of 100,
+// Entered is true when the member moved into the top 100 and false when it dropped out.
+type RankChangedEvent struct {
+	Set       string
+	Member    string
+	Threshold int64
+	Entered   bool
+	OldRank   int64
+	NewRank   int64
+}
+
+// IncreaseScoreWithRank is like IncreaseScore but also returns the member's rank before and after
+// the update, read in the same atomic write.
+func (mfs *MultiFieldSet) IncreaseScoreWithRank(ctx context.Context, fields map[string]float64, member string) (_ *UpdateResult, err error) {
+	defer mfs.observeUpdate("IncreaseScoreWithRank", time.Now(), &err)
+
+	result, err := mfs.increaseScore(ctx, fields, member, true)
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "IncreaseScoreWithRank", member, err)
+	}
+	return result, nil
+}
+
+// writeMemberScript writes a member to the main set, the field indexes and its dimension sets, then
+// evicts the worst members beyond the capacity, all in one atomic step. The dimension sets were
+// resolved from the member's recorded dimension values before the script ran, so the write is
+// refused if they changed meanwhile, e.g. because Rotate moved the member to another division.
+//
+// KEYS[1] is the main set, followed by ARGV[7] field indexes, ARGV[8] dimension sets to write the
+// member to and ARGV[9] dimension sets to remove it from. If ARGV[10] is not 0, the hash recording
+// the member's dimension values and the set listing every dimension set come next. The member's
+// update counter follows if updates are rate limited, and the last key records the update's
+// idempotency key if ARGV[11] is not 0. ARGV[1] is the member, ARGV[2] the zscore, ARGV[3] the
+// write mode ("NX", "XX", "=" followed by the expected current zscore, or empty), ARGV[4] the
+// maximum number of members or 0 for no limit, ARGV[5] the maximum number of updates per window or
+// 0 for no limit, ARGV[6] the window in milliseconds, ARGV[10] the number of dimension values to
+// record, ARGV[11] the expiry of the idempotency record in milliseconds or 0 without one, and
+// ARGV[12..] the raw field values in the same order as the index keys followed by the dimension
+// names and values in pairs and the previously recorded values of the same dimensions. It returns
+// the member's rank before and after the write, -1 if it isn't in the set, 1 if the member was
+// written, 0 if the mode prevented it, 2 if the rate limit did, 3 if the idempotency key was
+// already recorded or 4 if the recorded dimension values changed, followed by the evicted members.
+var writeMemberScript = newWriteScript(`
+local old = redis.call('ZRANK', KEYS[1], ARGV[1])
+local last, record = #KEYS, nil
+if tonumber(ARGV[11]) > 0 then
+	record, last = KEYS[last], last - 1
+	if redis.call('EXISTS', record) == 1 then
+		return {old or -1, old or -1, 3}
+	end
+end
+if (ARGV[3] == 'NX' and old) or (ARGV[3] == 'XX' and not old) then
+	return {old or -1, old or -1, 0}
+end
+if string.sub(ARGV[3], 1, 1) == '=' then
+	local current = redis.call('ZSCORE', KEYS[1], ARGV[1])
+	if not current or tonumber(current) ~= tonumber(string.sub(ARGV[3], 2)) then
+		return {old or -1, old or -1, 0}
+	end
+end
+
+local indexes, added, removed, recorded = tonumber(ARGV[7]), tonumber(ARGV[8]), tonumber(ARGV[9]), tonumber(ARGV[10])
+if recorded > 0 then
+	local dimensions = KEYS[2 + indexes + added + removed]
+	for i = 1, recorded do
+		local name = ARGV[10 + indexes + 2 * i]
+		local expected = ARGV[11 + indexes + 2 * recorded + i]
+		if (redis.call('HGET', dimensions, name) or '') ~= expected then
+			return {old or -1, old or -1, 4}
+		end
+	end
+end
+
+local maxUpdates = tonumber(ARGV[5])
+if maxUpdates > 0 then
+	local counter = KEYS[last]
+	if tonumber(redis.call('GET', counter) or '0') >= maxUpdates then
+		return {old or -1, old or -1, 2}
+	end
+	if redis.call('INCR', counter) == 1 then
+		redis.call('PEXPIRE', counter, ARGV[6])
+	end
+end
+
+redis.call('ZADD', KEYS[1], ARGV[2], ARGV[1])
+for i = 1, indexes do
+	redis.call('ZADD', KEYS[1 + i], ARGV[11 + i], ARGV[1])
+end
+local key = 1 + indexes
+for i = 1, added do
+	redis.call('ZADD', KEYS[key + i], ARGV[2], ARGV[1])
+	redis.call('SADD', KEYS[key + added + removed + 2], KEYS[key + i])
+end
+key = key + added
+for i = 1, removed do
+	redis.call('ZREM', KEYS[key + i], ARGV[1])
+end
+key = key + removed
+if recorded > 0 then
+	redis.call('HSET', KEYS[key + 1], unpack(ARGV, 12 + indexes, 11 + indexes + 2 * recorded))
+end
+if record then
+	redis.call('SET', record, 1, 'PX', ARGV[11])
+end
+
+local evicted = {}
+local max = tonumber(ARGV[4])
+if max > 0 then
+	evicted = redis.call('ZRANGE', KEYS[1], max, -1)
+	for start = 1, #evicted, 1000 do
+		local stop = math.min(start + 999, #evicted)
+		for i = 1, 1 + indexes do
+			redis.call('ZREM', KEYS[i], unpack(evicted, start, stop))
+		end
+	end
+end
+
+local result = {old or -1, redis.call('ZRANK', KEYS[1], ARGV[1]) or -1, 1}
+for _, member in ipairs(evicted) do
+	result[#result + 1] = member
+end
+return result
+`)
+
+// writeResult is the outcome of writeMemberWithRanks. Ranks are -1 if the member isn't in the set.
+type writeResult struct {
+	oldRank int64
+	newRank int64
+	written bool
+	limited bool
+	// duplicate is set if the idempotency key of the write was already recorded
+	duplicate bool
+}
+
+// writeMemberWithRanks stores a member like writeMember and returns its rank before and after the
+// write, read atomically with it. With limited, the write counts towards the member's rate limit
+// and is skipped if the limit is exhausted. A write under an idempotency key, see
+// WithIdempotencyKey, records the key and is skipped if it was already recorded. The member's
+// dimension sets are written along with it, resolved again if its recorded dimension values change
+// before the write.
+func (mfs *MultiFieldSet) writeMemberWithRanks(ctx context.Context, member string, scores []*big.Int, zscore *big.Int, mode writeMode, limited bool) (writeResult, error) {
+	keys := []string{mfs.key}
+	args := []interface{}{member, zscore.String(), mode.String(), mfs.maxMembers, 0, 0, 0, 0, 0, 0, 0}
+	if mfs.maintainFieldIndexes {
+		for i, field := range mfs.fields {
+			keys = append(keys, mfs.fieldIndexKey(field))
+			args = append(args, scores[i].String())
+		}
+		args[6] = len(mfs.fields)
+	}
+
+	var result []interface{}
+	err := mfs.writeOnce(ctx, func(client redis.UniversalClient) error {
+		for attempt := 0; attempt <= mfs.optimisticRetries; attempt++ {
+			keys, args := keys, args
+			if len(mfs.dimensions) > 0 {
+				dims, err := mfs.resolveDimensions(ctx, client, member)
+				if err != nil {
+					return err
+				}
+				keys = append(append(append(keys[:len(keys):len(keys)], dims.add...), dims.remove...), mfs.memberDimensionsKey(member), mfs.dimensionKeysKey())
+				args = append(append(args[:len(args):len(args)], dims.values...), dims.previous...)
+				args[7], args[8], args[9] = len(dims.add), len(dims.remove), len(dims.values)/2
+			}
+			if limited {
+				keys = append(keys, mfs.updateCountKey(member))
+				args[4], args[5] = mfs.guard.MaxUpdates, mfs.guard.Window.Milliseconds()
+			}
+			if key := idempotencyKey(ctx); key != "" {
+				keys = append(keys, mfs.idempotencyRecordKey(key))
+				args[10] = mfs.idempotencyTTL.Milliseconds()
+			}
+
+			var err error
+			result, err = writeMemberScript.Run(ctx, client, mfs.writeGuard(), keys, args...).Slice()
+			if err != nil || result[2].(int64) != 4 {
+				return err
+			}
+		}
+		return ErrUpdateConflict
+	})
+	if err != nil {
+		return writeResult{}, err
+	}
+
+	if len(result) > 3 {
+		evicted := make([]string, 0, len(result)-3)
+		for _, m := range result[3:] {
+			evicted = append(evicted, m.(string))
+		}
+		mfs.cleanupEvicted(ctx, evicted)
+	}
+	return writeResult{
+		oldRank:   result[0].(int64),
+		newRank:   result[1].(int64),
+		written:   result[2].(int64) == 1,
+		limited:   result[2].(int64) == 2,
+		duplicate: result[2].(int64) == 3,
+	}, nil
+}
+
+// needsRanks reports whether updates must look up the member's ranks for an enabled feature.
+func (mfs *MultiFieldSet) needsRanks() bool {
+	return mfs.tracksTopN() || len(mfs.rankThresholds) > 0 || mfs.eventSink != nil || len(mfs.tiers) > 0
+}
+
+// runRankChanged runs the rank-changed hooks for every threshold the member crossed.
+func (mfs *MultiFieldSet) runRankChanged(ctx context.Context, member string, oldRank, newRank int64) {
+	if len(mfs.rankThresholds) == 0 {
+		return
+	}
+	mfs.hooks.mu.RLock()
+	defer mfs.hooks.mu.RUnlock()
+	for _, threshold := range mfs.rankThresholds {
+		wasIn := oldRank >= 0 && oldRank < threshold
+		isIn := newRank >= 0 && newRank < threshold
+		if wasIn == isIn {
+			continue
+		}
+		event := RankChangedEvent{
+			Set:       mfs.name,
+			Member:    member,
+			Threshold: threshold,
+			Entered:   isIn,
+			OldRank:   oldRank,
+			NewRank:   newRank,
+		}
+		for _, hook := range mfs.hooks.rankChanged {
+			hook(ctx, event)
+		}
+	}
+}
+
+// GetRanks returns the ranks of several members with one pipelined round trip, e.g. to render a
+// roster. Members that are not in the set or are hidden are left out of the map.
+func (mfs *MultiFieldSet) GetRanks(ctx context.Context, members ...string) (_ map[string]int64, err error) {
+	defer mfs.observeRead("GetRanks", time.Now(), &err)
+
+	ranks := make(map[string]int64, len(members))
+	if len(members) == 0 {
+		return ranks, nil
+	}
+
+	cmds := make([]*redis.Cmd, len(members))
+	err = mfs.read(ctx, func(client redis.UniversalClient) error {
+		_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, member := range members {
+				cmds[i] = mfs.queueRank(ctx, pipe, member)
+			}
+			return nil
+		})
+		return err
+	})
+	if err != nil && err != redis.Nil {
+		return nil, mfs.runOnError(ctx, "GetRanks", "", err)
+	}
+
+	for i, member := range members {
+		rank, err := cmds[i].Int64()
+		if err == redis.Nil {
+			continue
+		} else if err != nil {
+			return nil, mfs.runOnError(ctx, "GetRanks", member, err)
+		}
+		ranks[member] = rank
+	}
+	return ranks, nil
+}
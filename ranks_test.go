@@ -0,0 +1,100 @@
+package zmultifield
+
+import (
+	"context"
+	"math/big"
+	"testing"
+)
+
+func TestIncreaseScoreWithRank(t *testing.T) {
+	for _, indexes := range []bool{false, true} {
+		mfs := newTestSetWithOptions(t, MultiFieldSetOptions{MaintainFieldIndexes: indexes})
+		ctx := context.Background()
+
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 50}, "alice"); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+
+		result, err := mfs.IncreaseScoreWithRank(ctx, map[string]float64{"points": 10}, "bob")
+		if err != nil {
+			t.Fatalf("IncreaseScoreWithRank() error = %v", err)
+		}
+		if result.OldRank != -1 || result.NewRank != 1 {
+			t.Errorf("indexes=%v: new member ranks = %d -> %d, expected -1 -> 1", indexes, result.OldRank, result.NewRank)
+		}
+
+		result, err = mfs.IncreaseScoreWithRank(ctx, map[string]float64{"points": 100}, "bob")
+		if err != nil {
+			t.Fatalf("IncreaseScoreWithRank() error = %v", err)
+		}
+		if result.OldRank != 1 || result.NewRank != 0 {
+			t.Errorf("indexes=%v: ranks = %d -> %d, expected 1 -> 0", indexes, result.OldRank, result.NewRank)
+		}
+		if expected := mfs.scoresToZScore([]*big.Int{big.NewInt(1023 - 110), big.NewInt(0)}); result.ZScore.Cmp(expected) != 0 {
+			t.Errorf("indexes=%v: ZScore = %v, expected %v", indexes, result.ZScore, expected)
+		}
+	}
+}
+
+func TestOnRankChanged(t *testing.T) {
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{RankThresholds: []int64{1, 2}})
+	ctx := context.Background()
+
+	var events []RankChangedEvent
+	mfs.OnRankChanged(func(ctx context.Context, event RankChangedEvent) {
+		events = append(events, event)
+	})
+
+	for member, points := range map[string]float64{"alice": 30, "bob": 20} {
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": points}, member); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+	}
+	events = nil
+
+	// carol jumps from outside the set straight to rank 0, entering both the top 1 and the top 2
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 40}, "carol"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("events = %+v, expected 2", events)
+	}
+	for i, threshold := range []int64{1, 2} {
+		e := events[i]
+		if e.Member != "carol" || e.Threshold != threshold || !e.Entered || e.OldRank != -1 || e.NewRank != 0 || e.Set != "test" {
+			t.Errorf("events[%d] = %+v, expected carol entering the top %d", i, e, threshold)
+		}
+	}
+
+	// bob moving within the set without crossing a threshold emits nothing
+	events = nil
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 1}, "bob"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	if len(events) != 0 {
+		t.Errorf("events = %+v, expected none", events)
+	}
+}
+
+func TestGetRanks(t *testing.T) {
+	mfs := newTestSet(t)
+	ctx := context.Background()
+
+	for member, points := range map[string]float64{"alice": 30, "bob": 20, "carol": 10} {
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": points}, member); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+	}
+
+	ranks, err := mfs.GetRanks(ctx, "carol", "alice", "dave")
+	if err != nil {
+		t.Fatalf("GetRanks() error = %v", err)
+	}
+	if len(ranks) != 2 || ranks["alice"] != 0 || ranks["carol"] != 2 {
+		t.Errorf("GetRanks() = %v, expected alice 0 and carol 2 without dave", ranks)
+	}
+
+	if ranks, err := mfs.GetRanks(ctx); err != nil || len(ranks) != 0 {
+		t.Errorf("GetRanks() without members = %v, %v, expected an empty map", ranks, err)
+	}
+}
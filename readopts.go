@@ -0,0 +1,258 @@
+package zmultifield
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ReadOption customizes a read such as GetMembers or GetTopMembers.
+type ReadOption func(*readOptions)
+
+// readOptions holds the ReadOptions of a call.
+type readOptions struct {
+	filter         Filter
+	dimension      string
+	dimensionValue string
+	orientation    Orientation
+	ranks          bool
+	meta           bool
+	readPreference *ReadPreference
+}
+
+// Orientation is the order in which reads return members.
+type Orientation int
+
+const (
+	// BestFirst returns members in leaderboard order. This is the default.
+	BestFirst Orientation = iota
+	// WorstFirst returns members in reverse leaderboard order, e.g. to find the players at risk
+	// of relegation.
+	WorstFirst
+)
+
+// WithOrientation sets the order in which GetMembers, GetTopMembers, Iterate, Aggregate and Export
+// return members. Offsets, and the ranks of WithRanks, then count from the worst member. GetRank and
+// Count ignore it.
+func WithOrientation(orientation Orientation) ReadOption {
+	return func(o *readOptions) {
+		o.orientation = orientation
+	}
+}
+
+// WithRanks sets the Rank of the members returned by GetMembers, GetTopMembers and Iterate. Like
+// offsets, ranks count the members matching the read's filters and dimension only.
+func WithRanks() ReadOption {
+	return func(o *readOptions) {
+		o.ranks = true
+	}
+}
+
+// WithMeta sets the Meta of the members returned by GetMembers and GetTopMembers to their
+// metadata, fetched in a single pipeline after the range query.
+func WithMeta() ReadOption {
+	return func(o *readOptions) {
+		o.meta = true
+	}
+}
+
+// WithFilter only returns members matching filter. It is the same as Where.
+func WithFilter(filter Filter) ReadOption {
+	return Where(filter)
+}
+
+// WithConsistency overrides the set's ReadPreference for one call, e.g. ReadPrimary to read an
+// update that replicas may not have received yet. Preferences reading from replicas fall back to
+// ReadPrimary if the set has no ReadClient.
+func WithConsistency(pref ReadPreference) ReadOption {
+	return func(o *readOptions) {
+		o.readPreference = &pref
+	}
+}
+
+// MinField only returns members whose value for the named field is at least min, e.g.
+// MinField("gamesPlayed", 10) to keep players with too few games out of the rankings. It is a
+// shorthand for Where(F(field).GTE(min)). Ranks and offsets count matching members only.
+func MinField(field string, min float64) ReadOption {
+	return Where(F(field).GTE(min))
+}
+
+// Where only returns members matching filter. Several Where and MinField options are combined
+// with And. Ranks and offsets count matching members only.
+func Where(filter Filter) ReadOption {
+	return func(o *readOptions) {
+		o.filter = o.filter.And(filter)
+	}
+}
+
+// newReadOptions applies opts.
+func newReadOptions(opts []ReadOption) readOptions {
+	var o readOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// filtered reports whether the options filter members.
+func (o readOptions) filtered() bool {
+	return len(o.filter.conds) > 0
+}
+
+// readPreferenceKey is the context key of a read preference set with WithConsistency.
+type readPreferenceKey struct{}
+
+// context returns ctx carrying the read preference of the options, if any, for mfs.read.
+func (o readOptions) context(ctx context.Context) context.Context {
+	if o.readPreference == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, readPreferenceKey{}, *o.readPreference)
+}
+
+// decorate sets the ranks and metadata of members read from offset according to o.
+func (mfs *MultiFieldSet) decorate(ctx context.Context, o readOptions, members []MemberScores, offset int64) error {
+	if o.ranks {
+		for i := range members {
+			members[i].Rank = offset + int64(i)
+		}
+	}
+	if !o.meta || len(members) == 0 {
+		return nil
+	}
+	metaCmds := make([]*redis.StringStringMapCmd, len(members))
+	err := mfs.read(ctx, func(client redis.UniversalClient) error {
+		_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, m := range members {
+				metaCmds[i] = pipe.HGetAll(ctx, mfs.metaKey(m.Member))
+			}
+			return nil
+		})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	for i := range members {
+		members[i].Meta = metaCmds[i].Val()
+	}
+	return nil
+}
+
+// fieldFilter restricts the raw value of a field to [rawMin, rawMax].
+type fieldFilter struct {
+	field          *multiField
+	rawMin, rawMax *big.Int
+}
+
+// getFilteredMembers returns limit members from offset among those matching the filters of o, in
+// leaderboard order. A limit of zero or less returns all matching members.
+func (mfs *MultiFieldSet) getFilteredMembers(ctx context.Context, o readOptions, limit, offset int64) ([]MemberScores, error) {
+	key, err := mfs.readKey(o)
+	if err != nil {
+		return nil, err
+	}
+	c, err := mfs.compileFilter(o.filter)
+	if err != nil {
+		return nil, err
+	}
+	if c.empty {
+		return []MemberScores{}, nil
+	}
+	c.reverse = o.orientation == WorstFirst
+
+	if len(c.scanned) == 0 && !mfs.hideMembers {
+		count := limit
+		if count <= 0 {
+			count = -1
+		}
+		var results []redis.Z
+		err := mfs.read(ctx, func(client redis.UniversalClient) error {
+			var err error
+			results, err = c.rangeByScore(ctx, client, key, offset, count)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		return mfs.decodeMembers(results)
+	}
+
+	members := []MemberScores{}
+	var skipped int64
+	err = mfs.walkFiltered(ctx, key, c, scanBatchSize, func(z redis.Z) bool {
+		if skipped < offset {
+			skipped++
+			return true
+		}
+		members = append(members, mfs.decodeEntry(z))
+		return limit <= 0 || int64(len(members)) < limit
+	})
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// walkFiltered walks the zscore range of c in the sorted set at key, reading batch entries per
+// round trip, and calls fn for each visible member matching the scanned filters of c, until fn
+// returns false. It walks from the worst member if c is reversed. It stops with an
+// UnrepresentableScoreError at the first zscore it can't decode.
+func (mfs *MultiFieldSet) walkFiltered(ctx context.Context, key string, c compiledFilter, batch int64, fn func(z redis.Z) bool) error {
+	var hidden map[string]bool
+	if mfs.hideMembers {
+		var members []string
+		err := mfs.read(ctx, func(client redis.UniversalClient) error {
+			var err error
+			members, err = client.SMembers(ctx, mfs.hiddenKey()).Result()
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		hidden = make(map[string]bool, len(members))
+		for _, member := range members {
+			hidden[member] = true
+		}
+	}
+
+	for start := int64(0); ; start += batch {
+		var results []redis.Z
+		err := mfs.read(ctx, func(client redis.UniversalClient) error {
+			var err error
+			results, err = c.rangeByScore(ctx, client, key, start, batch)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		zscore := new(big.Int)
+		for _, z := range results {
+			if err := mfs.checkZScore(memberName(z), z.Score); err != nil {
+				return err
+			}
+			if hidden[memberName(z)] || !mfs.matchesFilters(c.scanned, zscore.SetInt64(int64(z.Score))) {
+				continue
+			}
+			if !fn(z) {
+				return nil
+			}
+		}
+		if int64(len(results)) < batch {
+			return nil
+		}
+	}
+}
+
+// matchesFilters reports whether every filtered field of zscore lies within its range.
+func (mfs *MultiFieldSet) matchesFilters(filters []fieldFilter, zscore *big.Int) bool {
+	for _, f := range filters {
+		raw := mfs.extractFieldScore(f.field, zscore)
+		if raw.Cmp(f.rawMin) < 0 || raw.Cmp(f.rawMax) > 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,142 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMinField(t *testing.T) {
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{HideMembers: true})
+	ctx := context.Background()
+
+	players := map[string][2]float64{
+		"alice": {50, 1},
+		"bob":   {40, 5},
+		"carol": {30, 9},
+		"dave":  {20, 7},
+		"erin":  {10, 2},
+	}
+	for member, s := range players {
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": s[0], "deaths": s[1]}, member); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+	}
+
+	names := func(members []MemberScores) []string {
+		var names []string
+		for _, m := range members {
+			names = append(names, m.Member)
+		}
+		return names
+	}
+	tests := []struct {
+		name          string
+		limit, offset int64
+		opts          []ReadOption
+		expected      []string
+	}{
+		{"leading field", 10, 0, []ReadOption{MinField("points", 30)}, []string{"alice", "bob", "carol"}},
+		{"leading field with offset", 1, 1, []ReadOption{MinField("points", 30)}, []string{"bob"}},
+		{"scanned field", 10, 0, []ReadOption{MinField("deaths", 5)}, []string{"bob", "carol", "dave"}},
+		{"scanned field with offset", 2, 1, []ReadOption{MinField("deaths", 5)}, []string{"carol", "dave"}},
+		{"both fields", 0, 0, []ReadOption{MinField("points", 25), MinField("deaths", 5)}, []string{"bob", "carol"}},
+		{"no match", 10, 0, []ReadOption{MinField("points", 5000)}, nil},
+	}
+	for _, tt := range tests {
+		members, err := mfs.GetMembers(ctx, tt.limit, tt.offset, tt.opts...)
+		if err != nil {
+			t.Fatalf("%s: GetMembers() error = %v", tt.name, err)
+		}
+		if got := names(members); strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+			t.Errorf("%s: GetMembers() = %v, expected %v", tt.name, got, tt.expected)
+		}
+	}
+
+	// Hidden members are skipped by filtered reads as well
+	if err := mfs.Hide(ctx, "bob"); err != nil {
+		t.Fatalf("Hide() error = %v", err)
+	}
+	members, err := mfs.GetTopMembers(ctx, 10, MinField("points", 30))
+	if got := names(members); err != nil || len(got) != 2 || got[1] != "carol" {
+		t.Errorf("GetTopMembers() = %v, %v, expected alice and carol", got, err)
+	}
+
+	if _, err := mfs.GetTopMembers(ctx, 10, MinField("missing", 1)); !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("GetTopMembers() error = %v, expected ErrFieldNotFound", err)
+	}
+}
+
+func TestReadOptions_OrientationRanksMeta(t *testing.T) {
+	for _, hide := range []bool{false, true} {
+		mfs := newTestSetWithOptions(t, MultiFieldSetOptions{HideMembers: hide})
+		ctx := context.Background()
+		for member, points := range map[string]float64{"alice": 50, "bob": 40, "carol": 30, "dave": 20} {
+			if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": points}, member); err != nil {
+				t.Fatalf("IncreaseScore() error = %v", err)
+			}
+		}
+		if err := mfs.SetMemberMeta(ctx, "carol", map[string]string{"country": "DE"}); err != nil {
+			t.Fatalf("SetMemberMeta() error = %v", err)
+		}
+
+		members, err := mfs.GetMembers(ctx, 2, 1, WithOrientation(WorstFirst), WithRanks(), WithMeta())
+		if err != nil || len(members) != 2 {
+			t.Fatalf("GetMembers(WorstFirst) = %v, %v, expected 2 members", members, err)
+		}
+		if members[0].Member != "carol" || members[0].Rank != 1 || members[0].Meta["country"] != "DE" {
+			t.Errorf("hide %v: first member = %+v, expected carol at rank 1 with her metadata", hide, members[0])
+		}
+		if members[1].Member != "bob" || members[1].Rank != 2 || len(members[1].Meta) != 0 {
+			t.Errorf("hide %v: second member = %+v, expected bob at rank 2", hide, members[1])
+		}
+
+		filtered, err := mfs.GetTopMembers(ctx, 10, WithFilter(F("points").GTE(30)), WithOrientation(WorstFirst), WithRanks())
+		if err != nil || len(filtered) != 3 || filtered[0].Member != "carol" || filtered[2].Rank != 2 {
+			t.Errorf("hide %v: GetTopMembers(WithFilter, WorstFirst) = %v, %v, expected carol, bob and alice", hide, filtered, err)
+		}
+
+		var iterated []string
+		err = mfs.Iterate(ctx, Filter{}, func(m MemberScores) bool {
+			iterated = append(iterated, m.Member)
+			return true
+		}, WithOrientation(WorstFirst))
+		if err != nil || strings.Join(iterated, ",") != "dave,carol,bob,alice" {
+			t.Errorf("hide %v: Iterate(WorstFirst) = %v, %v, expected dave first", hide, iterated, err)
+		}
+	}
+}
+
+func TestWithConsistency(t *testing.T) {
+	ctx := context.Background()
+	primary, _ := newTestClient(t)
+	replica, _ := newTestClient(t)
+	mfs, err := New(MultiFieldSetOptions{
+		Name:           "board",
+		Fields:         []Field{{Name: "points", Sort: Descending, MaxValue: 1000, UpdateType: Incremental}},
+		Client:         primary,
+		ReadClient:     replica,
+		ReadPreference: ReadReplica,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 10}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+
+	// The replica hasn't received alice yet, the primary has
+	if _, err := mfs.GetRank(ctx, "alice"); !errors.Is(err, ErrMemberNotFound) {
+		t.Errorf("GetRank() on the replica error = %v, expected ErrMemberNotFound", err)
+	}
+	if rank, err := mfs.GetRank(ctx, "alice", WithConsistency(ReadPrimary)); err != nil || rank != 0 {
+		t.Errorf("GetRank(WithConsistency(ReadPrimary)) = %d, %v, expected 0", rank, err)
+	}
+	if members, err := mfs.GetTopMembers(ctx, 10, WithConsistency(ReadPrimary)); err != nil || len(members) != 1 {
+		t.Errorf("GetTopMembers(WithConsistency(ReadPrimary)) = %v, %v, expected alice", members, err)
+	}
+	if n, err := mfs.Count(ctx, Filter{}, WithConsistency(ReadPrimary)); err != nil || n != 1 {
+		t.Errorf("Count(WithConsistency(ReadPrimary)) = %d, %v, expected 1", n, err)
+	}
+}
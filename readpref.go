@@ -0,0 +1,44 @@
+package zmultifield
+
+import (
+	"context"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ReadPreference controls which client serves read-only queries.
+type ReadPreference int
+
+const (
+	// ReadPrimary sends every read to the primary client. This is the default.
+	ReadPrimary ReadPreference = iota
+	// ReadReplica sends reads to the read client, which must be configured.
+	ReadReplica
+	// ReadNearest sends reads to the read client and falls back to the primary client if it fails.
+	ReadNearest
+)
+
+// read runs a read-only operation against the client selected by the read preference, or the one
+// set with WithConsistency, retrying it according to the retry policy. Writes and the read half of
+// read-modify-write updates always use the primary client.
+func (mfs *MultiFieldSet) read(ctx context.Context, fn func(client redis.UniversalClient) error) error {
+	pref := mfs.readPreference
+	if p, ok := ctx.Value(readPreferenceKey{}).(ReadPreference); ok {
+		pref = p
+		if mfs.readClient == nil {
+			pref = ReadPrimary
+		}
+	}
+	switch pref {
+	case ReadReplica:
+		return mfs.retry(ctx, func() error { return fn(mfs.readClient) })
+	case ReadNearest:
+		err := mfs.retry(ctx, func() error { return fn(mfs.readClient) })
+		if err == nil || err == redis.Nil || ctx.Err() != nil {
+			return err
+		}
+		return mfs.primary(ctx, fn)
+	default:
+		return mfs.primary(ctx, fn)
+	}
+}
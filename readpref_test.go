@@ -0,0 +1,63 @@
+package zmultifield
+
+import (
+	"context"
+	"testing"
+)
+
+func TestReadPreference(t *testing.T) {
+	ctx := context.Background()
+	primary, _ := newTestClient(t)
+	replica, replicaServer := newTestClient(t)
+
+	fields := []Field{{Name: "points", Sort: Descending, MaxValue: 1000, UpdateType: Incremental}}
+
+	if _, err := New(MultiFieldSetOptions{Name: "board", Fields: fields, Client: primary, ReadPreference: ReadReplica}); err == nil {
+		t.Errorf("New() with ReadReplica and no read client succeeded, expected error")
+	}
+
+	mfs, err := New(MultiFieldSetOptions{
+		Name:           "board",
+		Fields:         fields,
+		Client:         primary,
+		ReadClient:     replica,
+		ReadPreference: ReadReplica,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 10}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+
+	// The write went to the primary only, so the replica doesn't know alice yet
+	if count, _ := mfs.GetCountInRange(ctx, "-inf", "+inf"); count != 0 {
+		t.Errorf("GetCountInRange() on replica = %d, expected 0", count)
+	}
+	replicaServer.ZAdd("board", 1013, "alice")
+	if count, _ := mfs.GetCountInRange(ctx, "-inf", "+inf"); count != 1 {
+		t.Errorf("GetCountInRange() on replica = %d, expected 1", count)
+	}
+
+	// With ReadNearest a failing read client falls back to the primary
+	nearest, err := New(MultiFieldSetOptions{
+		Name:           "board",
+		Fields:         fields,
+		Client:         primary,
+		ReadClient:     replica,
+		ReadPreference: ReadNearest,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	replicaServer.Close()
+
+	score, err := nearest.GetScoreForField(ctx, "points", "alice")
+	if err != nil {
+		t.Fatalf("GetScoreForField() with ReadNearest error = %v", err)
+	}
+	if score.Int64() != 10 {
+		t.Errorf("GetScoreForField() = %v, expected 10", score)
+	}
+}
@@ -0,0 +1,274 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RegistryHook is called when a set is added to or removed from a Registry.
+type RegistryHook func(ctx context.Context, set *MultiFieldSet)
+
+// Registry owns named MultiFieldSets that share a Redis client, for services that manage many
+// leaderboards. It is safe for concurrent use.
+type Registry struct {
+	client redis.UniversalClient
+
+	mu       sync.RWMutex
+	sets     map[string]*MultiFieldSet
+	onAdd    []RegistryHook
+	onRemove []RegistryHook
+}
+
+// NewRegistry creates an empty Registry whose sets use client unless their options set another.
+func NewRegistry(client redis.UniversalClient) *Registry {
+	return &Registry{client: client, sets: make(map[string]*MultiFieldSet)}
+}
+
+// OnAdd registers a hook that runs whenever a set is added, e.g. to attach update hooks.
+func (r *Registry) OnAdd(hook RegistryHook) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onAdd = append(r.onAdd, hook)
+}
+
+// OnRemove registers a hook that runs whenever a set is removed.
+func (r *Registry) OnRemove(hook RegistryHook) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onRemove = append(r.onRemove, hook)
+}
+
+// Create creates a set with opts, using the registry's client if opts.Client is nil, and adds it.
+// It fails with ErrSetExists if a set with the same name is already registered.
+func (r *Registry) Create(ctx context.Context, opts MultiFieldSetOptions) (*MultiFieldSet, error) {
+	if opts.Client == nil {
+		opts.Client = r.client
+	}
+	set, err := New(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.Register(ctx, set); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// Register adds a set created elsewhere, e.g. by the config package, under its name. It fails
+// with ErrSetExists if a set with the same name is already registered.
+func (r *Registry) Register(ctx context.Context, set *MultiFieldSet) error {
+	r.mu.Lock()
+	if _, ok := r.sets[set.GetName()]; ok {
+		r.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrSetExists, set.GetName())
+	}
+	r.sets[set.GetName()] = set
+	hooks := r.onAdd
+	r.mu.Unlock()
+
+	for _, hook := range hooks {
+		hook(ctx, set)
+	}
+	return nil
+}
+
+// Remove removes the named set from the registry without touching its data and reports whether
+// it was registered.
+func (r *Registry) Remove(ctx context.Context, name string) bool {
+	r.mu.Lock()
+	set, ok := r.sets[name]
+	delete(r.sets, name)
+	hooks := r.onRemove
+	r.mu.Unlock()
+
+	if ok {
+		for _, hook := range hooks {
+			hook(ctx, set)
+		}
+	}
+	return ok
+}
+
+// Get returns the named set and whether it is registered.
+func (r *Registry) Get(name string) (*MultiFieldSet, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	set, ok := r.sets[name]
+	return set, ok
+}
+
+// Names returns the names of the registered sets in sorted order.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.sets))
+	for name := range r.sets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// snapshot returns the registered sets in name order, so bulk operations don't hold the lock
+// while talking to Redis.
+func (r *Registry) snapshot() []*MultiFieldSet {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	sets := make([]*MultiFieldSet, 0, len(r.sets))
+	for _, set := range r.sets {
+		sets = append(sets, set)
+	}
+	sort.Slice(sets, func(i, j int) bool { return sets[i].GetName() < sets[j].GetName() })
+	return sets
+}
+
+// Verify runs Verify on every registered set, e.g. as a health check at startup, and returns the
+// reports by set name. Sets that couldn't be checked are left out of the map and their errors are
+// joined into the returned error.
+func (r *Registry) Verify(ctx context.Context) (map[string]*VerifyReport, error) {
+	reports := make(map[string]*VerifyReport)
+	var errs []error
+	for _, set := range r.snapshot() {
+		report, err := set.Verify(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("set %s: %w", set.GetName(), err))
+			continue
+		}
+		reports[set.GetName()] = report
+	}
+	return reports, errors.Join(errs...)
+}
+
+// Migrate runs migration on every registered set in name order, e.g. to backfill a field or
+// rebuild every set after a schema change. Each set is locked while its migration runs, so
+// instances migrating concurrently skip the sets the others are on with a LockedError; Rebuild
+// and snapshots called by migration run under the same lock. Migrate carries on after failures
+// and returns their errors joined, each prefixed with the set's name; it stops early only when
+// ctx is done.
+func (r *Registry) Migrate(ctx context.Context, migration func(ctx context.Context, set *MultiFieldSet) error) error {
+	var errs []error
+	for _, set := range r.snapshot() {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		err := set.withLock(ctx, "Migrate", func(ctx context.Context) error {
+			return migration(ctx, set)
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("set %s: %w", set.GetName(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// readGroup identifies the sets GetTopAcross can read in one pipeline: those read from the same
+// clients with the same read preference.
+type readGroup struct {
+	client, readClient redis.UniversalClient
+	preference         ReadPreference
+}
+
+// GetTopAcross returns the top limit members of each named set by set name, e.g. for a home
+// screen showing several leaderboards. Reads of sets sharing a client are sent in one pipeline
+// and cached reads are served from each set's cache. Unknown names fail with ErrSetNotRegistered
+// before anything is read. Sets that couldn't be read are left out of the map and their errors
+// are joined into the returned error, each prefixed with the set's name.
+func (r *Registry) GetTopAcross(ctx context.Context, names []string, limit int64) (map[string][]MemberScores, error) {
+	sets := make([]*MultiFieldSet, 0, len(names))
+	for _, name := range names {
+		set, ok := r.Get(name)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrSetNotRegistered, name)
+		}
+		sets = append(sets, set)
+	}
+
+	tops := make(map[string][]MemberScores, len(sets))
+	var errs []error
+	var groups []readGroup
+	pending := make(map[readGroup][]*MultiFieldSet)
+	for _, set := range sets {
+		if _, ok := tops[set.GetName()]; ok {
+			continue
+		}
+		if cached, ok := set.cache.get(topCacheKey(limit)); ok {
+			tops[set.GetName()] = copyMembers(cached.([]MemberScores))
+			continue
+		}
+		// Hidden members are skipped by walking the set, which doesn't fit in a pipeline
+		if set.hideMembers {
+			members, err := set.GetTopMembers(ctx, limit)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("set %s: %w", set.GetName(), err))
+				continue
+			}
+			tops[set.GetName()] = members
+			continue
+		}
+		g := readGroup{client: set.client, readClient: set.readClient, preference: set.readPreference}
+		if _, ok := pending[g]; !ok {
+			groups = append(groups, g)
+		}
+		pending[g] = append(pending[g], set)
+		tops[set.GetName()] = nil
+	}
+
+	for _, g := range groups {
+		group := pending[g]
+		gens := make([]uint64, len(group))
+		for i, set := range group {
+			gens[i] = set.cache.generation()
+		}
+		var cmds []*redis.ZSliceCmd
+		err := group[0].read(ctx, func(client redis.UniversalClient) error {
+			var err error
+			cmds, err = readTops(ctx, client, group, limit)
+			return err
+		})
+		for i, set := range group {
+			setErr := err
+			if setErr == nil {
+				setErr = cmds[i].Err()
+			}
+			var members []MemberScores
+			if setErr == nil {
+				members, setErr = set.decodeMembers(cmds[i].Val())
+			}
+			if setErr != nil {
+				delete(tops, set.GetName())
+				errs = append(errs, fmt.Errorf("set %s: %w", set.GetName(), set.runOnError(ctx, "GetTopAcross", "", setErr)))
+				continue
+			}
+			set.cache.put(topCacheKey(limit), copyMembers(members), gens[i])
+			tops[set.GetName()] = members
+		}
+	}
+	return tops, errors.Join(errs...)
+}
+
+// readTops pipelines the reads of the top limit members of sets.
+func readTops(ctx context.Context, client redis.UniversalClient, sets []*MultiFieldSet, limit int64) ([]*redis.ZSliceCmd, error) {
+	cmds := make([]*redis.ZSliceCmd, len(sets))
+	_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, set := range sets {
+			cmds[i] = pipe.ZRangeWithScores(ctx, set.key, 0, limit-1)
+		}
+		return nil
+	})
+	// A command failing for one set, e.g. on a key of the wrong type, is reported for that set
+	// alone; the pipeline only fails when no command succeeded, e.g. on a connection error
+	if err != nil {
+		for _, cmd := range cmds {
+			if cmd.Err() == nil {
+				return cmds, nil
+			}
+		}
+	}
+	return cmds, err
+}
@@ -0,0 +1,161 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRegistry(t *testing.T) {
+	ctx := context.Background()
+	client, _ := newTestClient(t)
+	registry := NewRegistry(client)
+
+	var added, removed []string
+	registry.OnAdd(func(ctx context.Context, set *MultiFieldSet) { added = append(added, set.GetName()) })
+	registry.OnRemove(func(ctx context.Context, set *MultiFieldSet) { removed = append(removed, set.GetName()) })
+
+	fields := []Field{{Name: "points", Sort: Descending, MaxValue: 1000, UpdateType: Incremental}}
+	for _, name := range []string{"weekly", "daily"} {
+		if _, err := registry.Create(ctx, MultiFieldSetOptions{Name: name, Fields: fields}); err != nil {
+			t.Fatalf("Create(%s) failed: %v", name, err)
+		}
+	}
+	if _, err := registry.Create(ctx, MultiFieldSetOptions{Name: "daily", Fields: fields}); !errors.Is(err, ErrSetExists) {
+		t.Errorf("Create of a duplicate name = %v, expected ErrSetExists", err)
+	}
+
+	if names := registry.Names(); !reflect.DeepEqual(names, []string{"daily", "weekly"}) {
+		t.Errorf("Names() = %v, expected daily and weekly", names)
+	}
+	daily, ok := registry.Get("daily")
+	if !ok {
+		t.Fatal("Get(daily) found nothing")
+	}
+	if _, err := daily.IncreaseScore(ctx, map[string]float64{"points": 5}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore failed: %v", err)
+	}
+
+	if !registry.Remove(ctx, "weekly") || registry.Remove(ctx, "weekly") {
+		t.Error("Remove(weekly) should succeed once")
+	}
+	if _, ok := registry.Get("weekly"); ok {
+		t.Error("Get(weekly) found a removed set")
+	}
+	if !reflect.DeepEqual(added, []string{"weekly", "daily"}) || !reflect.DeepEqual(removed, []string{"weekly"}) {
+		t.Errorf("hooks saw added %v and removed %v", added, removed)
+	}
+}
+
+func TestRegistryVerifyAndMigrate(t *testing.T) {
+	ctx := context.Background()
+	client, _ := newTestClient(t)
+	registry := NewRegistry(client)
+
+	fields := []Field{{Name: "points", Sort: Descending, MaxValue: 1000, UpdateType: Incremental}}
+	for _, name := range []string{"a", "b", "c"} {
+		if _, err := registry.Create(ctx, MultiFieldSetOptions{Name: name, Fields: fields}); err != nil {
+			t.Fatalf("Create(%s) failed: %v", name, err)
+		}
+	}
+	if err := client.Set(ctx, "b", "not a leaderboard", 0).Err(); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	reports, err := registry.Verify(ctx)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if len(reports) != 3 || !reports["a"].OK() || reports["b"].OK() {
+		t.Errorf("Verify() = %v, expected only b to have problems", reports)
+	}
+
+	var migrated []string
+	err = registry.Migrate(ctx, func(ctx context.Context, set *MultiFieldSet) error {
+		migrated = append(migrated, set.GetName())
+		_, err := set.IncreaseScore(ctx, map[string]float64{"points": 1}, "seed")
+		return err
+	})
+	if !reflect.DeepEqual(migrated, []string{"a", "b", "c"}) {
+		t.Errorf("Migrate visited %v, expected every set in name order", migrated)
+	}
+	if err == nil || !strings.Contains(err.Error(), "set b:") {
+		t.Errorf("Migrate() error = %v, expected the failure of set b", err)
+	}
+	if exists, _ := registry.sets["c"].MemberExists(ctx, "seed"); !exists {
+		t.Error("Migrate stopped after the failing set")
+	}
+}
+
+func TestRegistryGetTopAcross(t *testing.T) {
+	ctx := context.Background()
+	client, _ := newTestClient(t)
+	registry := NewRegistry(client)
+
+	fields := []Field{{Name: "points", Sort: Descending, MaxValue: 1000, UpdateType: Incremental}}
+	for _, name := range []string{"daily", "weekly", "hidden", "broken"} {
+		opts := MultiFieldSetOptions{Name: name, Fields: fields, HideMembers: name == "hidden"}
+		if name == "daily" {
+			opts.Cache = &CacheOptions{TTL: time.Hour}
+		}
+		set, err := registry.Create(ctx, opts)
+		if err != nil {
+			t.Fatalf("Create(%s) failed: %v", name, err)
+		}
+		for i, member := range []string{"alice", "bob", "carol"} {
+			if _, err := set.IncreaseScore(ctx, map[string]float64{"points": float64(10 * (i + 1))}, member); err != nil {
+				t.Fatalf("IncreaseScore failed: %v", err)
+			}
+		}
+	}
+	hidden, _ := registry.Get("hidden")
+	if err := hidden.Hide(ctx, "carol"); err != nil {
+		t.Fatalf("Hide failed: %v", err)
+	}
+	// Served from the cache, so the read below must not return alice
+	daily, _ := registry.Get("daily")
+	if _, err := daily.GetTopMembers(ctx, 2); err != nil {
+		t.Fatalf("GetTopMembers failed: %v", err)
+	}
+	if err := client.ZRem(ctx, daily.GetKey(), "carol").Err(); err != nil {
+		t.Fatalf("ZRem failed: %v", err)
+	}
+	if err := client.Del(ctx, "broken").Err(); err != nil {
+		t.Fatalf("Del failed: %v", err)
+	}
+	if err := client.Set(ctx, "broken", "not a leaderboard", 0).Err(); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	tops, err := registry.GetTopAcross(ctx, []string{"daily", "weekly", "hidden", "broken"}, 2)
+	if err == nil || !strings.Contains(err.Error(), "set broken:") {
+		t.Errorf("GetTopAcross() error = %v, expected the failure of set broken", err)
+	}
+	want := map[string][]string{
+		"daily":  {"carol", "bob"},
+		"weekly": {"carol", "bob"},
+		"hidden": {"bob", "alice"},
+	}
+	if len(tops) != len(want) {
+		t.Errorf("GetTopAcross() = %v, expected %d sets", tops, len(want))
+	}
+	for name, members := range want {
+		var got []string
+		for _, m := range tops[name] {
+			got = append(got, m.Member)
+		}
+		if !reflect.DeepEqual(got, members) {
+			t.Errorf("top of %s = %v, expected %v", name, got, members)
+		}
+	}
+	if FieldValue(tops["weekly"][0].Scores, "points") != 30 {
+		t.Errorf("top of weekly = %v, expected carol with 30 points", tops["weekly"][0])
+	}
+
+	if _, err := registry.GetTopAcross(ctx, []string{"daily", "monthly"}, 3); !errors.Is(err, ErrSetNotRegistered) {
+		t.Errorf("GetTopAcross() of an unknown set = %v, expected ErrSetNotRegistered", err)
+	}
+}
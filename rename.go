@@ -0,0 +1,182 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// moveMemberScript renames a member, or merges it into another member, together with its metadata
+// and history, in one atomic step. Like the merge script it decodes with arithmetic, which is exact
+// below 2^53, and aborts with "OUTOFRANGE <position> <value>" before anything is written if a merged
+// value doesn't fit its field. A missing source fails with "MISSING <member>" and renaming onto an
+// existing member with "EXISTS <member>".
+//
+// KEYS[1] is the main set, KEYS[2] and KEYS[3] the metadata hashes of the source and destination,
+// KEYS[4] and KEYS[5] their histories and KEYS[6..n] the field indexes, if maintained, in field
+// order. ARGV[1] and ARGV[2] are the source and destination members, ARGV[3] is "rename" or the
+// merge strategy and ARGV[4] the zscore of a destination that doesn't exist, followed by five
+// values per field: 2^shift, 2^bits, the maximum raw value, 1 if the field is inverted and the raw
+// value replacing the field's, such as the destination's salt, or an empty string. The script
+// returns the destination's new zscore.
+var moveMemberScript = newWriteScript(`
+local src = redis.call('ZSCORE', KEYS[1], ARGV[1])
+if not src then
+	return redis.error_reply('MISSING ' .. ARGV[1])
+end
+local dst = redis.call('ZSCORE', KEYS[1], ARGV[2])
+local rename = ARGV[3] == 'rename'
+if rename and dst then
+	return redis.error_reply('EXISTS ' .. ARGV[2])
+end
+
+local a = tonumber(src)
+local b = tonumber(dst or ARGV[4])
+local zscore = 0
+local raws = {}
+for i = 5, #ARGV, 5 do
+	local base = tonumber(ARGV[i])
+	local size = tonumber(ARGV[i + 1])
+	local max = tonumber(ARGV[i + 2])
+	local desc = ARGV[i + 3] == '1'
+	local raw = math.floor(a / base) % size
+	if ARGV[i + 4] ~= '' then
+		raw = tonumber(ARGV[i + 4])
+	elseif not rename and dst then
+		local other = math.floor(b / base) % size
+		local va, vb = raw, other
+		if desc then
+			va, vb = max - raw, max - other
+		end
+		local v
+		if ARGV[3] == 'sum' then
+			v = va + vb
+		elseif ARGV[3] == 'max' then
+			v = math.max(va, vb)
+		else
+			v = math.min(va, vb)
+		end
+		if v > max then
+			return redis.error_reply('OUTOFRANGE ' .. ((i - 5) / 5) .. ' ' .. string.format('%.17g', v))
+		end
+		raw = v
+		if desc then
+			raw = max - v
+		end
+	end
+	zscore = zscore + raw * base
+	raws[#raws + 1] = raw
+end
+
+local encoded = string.format('%.17g', zscore)
+for k, key in ipairs({KEYS[1], select(6, unpack(KEYS))}) do
+	local score = encoded
+	if k > 1 then
+		score = string.format('%.17g', raws[k - 1])
+	end
+	redis.call('ZREM', key, ARGV[1])
+	redis.call('ZADD', key, score, ARGV[2])
+end
+
+if rename then
+	for _, pair in ipairs({{KEYS[2], KEYS[3]}, {KEYS[4], KEYS[5]}}) do
+		if redis.call('EXISTS', pair[1]) == 1 then
+			redis.call('RENAME', pair[1], pair[2])
+		end
+	end
+else
+	local meta = redis.call('HGETALL', KEYS[2])
+	for i = 1, #meta, 2 do
+		redis.call('HSETNX', KEYS[3], meta[i], meta[i + 1])
+	end
+	if redis.call('EXISTS', KEYS[4]) == 1 then
+		redis.call('ZUNIONSTORE', KEYS[5], 2, KEYS[5], KEYS[4])
+	end
+	redis.call('DEL', KEYS[2], KEYS[4])
+end
+return encoded
+`)
+
+// RenameMember moves a member's scores, metadata and history to a new member name in one atomic
+// step, e.g. when user IDs are migrated. It fails with ErrMemberNotFound if the member is not in the
+// set and with ErrMemberExists if the new name is already taken; use MergeMembers to combine two
+// existing members.
+func (mfs *MultiFieldSet) RenameMember(ctx context.Context, oldName, newName string) error {
+	if _, err := mfs.moveMember(ctx, oldName, newName, "rename"); err != nil {
+		return mfs.runOnError(ctx, "RenameMember", oldName, err)
+	}
+	return nil
+}
+
+// MergeMembers combines src into dst and removes src, in one atomic step, e.g. for account merges.
+// Field values are combined with strategy as in MergeFrom. Metadata fields of src that dst doesn't
+// have are copied and both histories are kept. If dst is not in the set, src is renamed to dst. It
+// returns the new zscore of dst.
+func (mfs *MultiFieldSet) MergeMembers(ctx context.Context, src, dst string, strategy MergeStrategy) (*big.Int, error) {
+	if strategy.String() == "unknown" {
+		return nil, mfs.runOnError(ctx, "MergeMembers", src, fmt.Errorf("unknown merge strategy %d", strategy))
+	}
+	zscore, err := mfs.moveMember(ctx, src, dst, strategy.String())
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "MergeMembers", src, err)
+	}
+	return zscore, nil
+}
+
+// moveMember runs the move script with the given mode, "rename" or a merge strategy.
+func (mfs *MultiFieldSet) moveMember(ctx context.Context, src, dst, mode string) (*big.Int, error) {
+	if src == dst {
+		return nil, errors.New("source and destination are the same member")
+	}
+
+	keys := []string{mfs.key, mfs.metaKey(src), mfs.metaKey(dst), mfs.historyKey(src), mfs.historyKey(dst)}
+	args := []interface{}{src, dst, mode, mfs.defaultZScore.String()}
+	for _, field := range mfs.fields {
+		if mfs.maintainFieldIndexes {
+			keys = append(keys, mfs.fieldIndexKey(field))
+		}
+		inverted, replacement := "0", ""
+		if field.inverted {
+			inverted = "1"
+		}
+		// The salt depends on the member's name, so the destination gets its own
+		if field == mfs.salt {
+			replacement = mfs.saltRaw(dst).String()
+		}
+		args = append(args,
+			uint64(1)<<field.shiftValue,
+			uint64(1)<<field.bits,
+			field.maxAbsolute.String(),
+			inverted,
+			replacement,
+		)
+	}
+
+	var encoded string
+	err := mfs.write(ctx, func(client redis.UniversalClient) error {
+		var err error
+		encoded, err = moveMemberScript.Run(ctx, client, mfs.writeGuard(), keys, args...).Text()
+		return err
+	})
+	if err != nil {
+		switch msg := err.Error(); {
+		case strings.HasPrefix(msg, "MISSING "):
+			return nil, ErrMemberNotFound
+		case strings.HasPrefix(msg, "EXISTS "):
+			return nil, fmt.Errorf("%w: %s", ErrMemberExists, dst)
+		default:
+			return nil, mfs.mergeError(err)
+		}
+	}
+
+	zscore, err := strconv.ParseFloat(encoded, 64)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetInt64(int64(zscore)), nil
+}
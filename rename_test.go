@@ -0,0 +1,107 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRenameMember(t *testing.T) {
+	ctx := context.Background()
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{History: &HistoryOptions{}, MaintainFieldIndexes: true})
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 42, "deaths": 3}, "user:1"); err != nil {
+		t.Fatalf("IncreaseScore failed: %v", err)
+	}
+	if err := mfs.SetMemberMeta(ctx, "user:1", map[string]string{"name": "Alice"}); err != nil {
+		t.Fatalf("SetMemberMeta failed: %v", err)
+	}
+
+	if err := mfs.RenameMember(ctx, "user:1", "user:2"); err != nil {
+		t.Fatalf("RenameMember failed: %v", err)
+	}
+
+	if exists, err := mfs.MemberExists(ctx, "user:1"); err != nil || exists {
+		t.Errorf("MemberExists(user:1) = %v, %v, expected the old name to be gone", exists, err)
+	}
+	scores, err := mfs.GetScores(ctx, "user:2")
+	if err != nil {
+		t.Fatalf("GetScores failed: %v", err)
+	}
+	if scores[0].Int64() != 42 || scores[1].Int64() != 3 {
+		t.Errorf("GetScores(user:2) = %v, expected points 42 and deaths 3", scores)
+	}
+	if rank, err := mfs.GetFieldRank(ctx, "deaths", "user:2"); err != nil || rank != 0 {
+		t.Errorf("GetFieldRank(deaths, user:2) = %d, %v, expected 0", rank, err)
+	}
+	if meta, err := mfs.GetMemberMeta(ctx, "user:2"); err != nil || meta["name"] != "Alice" {
+		t.Errorf("GetMemberMeta(user:2) = %v, %v, expected the name to move", meta, err)
+	}
+	history, err := mfs.GetHistory(ctx, "user:2", "points", time.Time{}, time.Now().Add(time.Minute))
+	if err != nil || len(history) != 1 {
+		t.Errorf("GetHistory(user:2) = %v, %v, expected the history to move", history, err)
+	}
+
+	if err := mfs.RenameMember(ctx, "user:1", "user:3"); !errors.Is(err, ErrMemberNotFound) {
+		t.Errorf("RenameMember of a missing member = %v, expected ErrMemberNotFound", err)
+	}
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 1}, "user:3"); err != nil {
+		t.Fatalf("IncreaseScore failed: %v", err)
+	}
+	if err := mfs.RenameMember(ctx, "user:2", "user:3"); !errors.Is(err, ErrMemberExists) {
+		t.Errorf("RenameMember onto an existing member = %v, expected ErrMemberExists", err)
+	}
+}
+
+func TestMergeMembers(t *testing.T) {
+	ctx := context.Background()
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{History: &HistoryOptions{}})
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 40, "deaths": 3}, "old"); err != nil {
+		t.Fatalf("IncreaseScore failed: %v", err)
+	}
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 2, "deaths": 5}, "new"); err != nil {
+		t.Fatalf("IncreaseScore failed: %v", err)
+	}
+	if err := mfs.SetMemberMeta(ctx, "old", map[string]string{"name": "Old", "country": "NL"}); err != nil {
+		t.Fatalf("SetMemberMeta failed: %v", err)
+	}
+	if err := mfs.SetMemberMeta(ctx, "new", map[string]string{"name": "New"}); err != nil {
+		t.Fatalf("SetMemberMeta failed: %v", err)
+	}
+
+	zscore, err := mfs.MergeMembers(ctx, "old", "new", MergeSum)
+	if err != nil {
+		t.Fatalf("MergeMembers failed: %v", err)
+	}
+	if values := mfs.Decode(zscore); values["points"] != 42 || values["deaths"] != 8 {
+		t.Errorf("MergeMembers zscore decodes to %v, expected points 42 and deaths 8", values)
+	}
+	if exists, err := mfs.MemberExists(ctx, "old"); err != nil || exists {
+		t.Errorf("MemberExists(old) = %v, %v, expected the source to be removed", exists, err)
+	}
+	meta, err := mfs.GetMemberMeta(ctx, "new")
+	if err != nil || meta["name"] != "New" || meta["country"] != "NL" {
+		t.Errorf("GetMemberMeta(new) = %v, %v, expected the destination's name and the source's country", meta, err)
+	}
+	history, err := mfs.GetHistory(ctx, "new", "points", time.Time{}, time.Now().Add(time.Minute))
+	if err != nil || len(history) != 2 {
+		t.Errorf("GetHistory(new) = %v, %v, expected both histories", history, err)
+	}
+
+	// A sum beyond the field's bits changes nothing
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"deaths": 32}, "new"); err != nil {
+		t.Fatalf("IncreaseScore failed: %v", err)
+	}
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"deaths": 100}, "other"); err != nil {
+		t.Fatalf("IncreaseScore failed: %v", err)
+	}
+	if _, err := mfs.MergeMembers(ctx, "other", "new", MergeSum); !errors.Is(err, ErrScoreOutOfRange) {
+		t.Errorf("MergeMembers overflowing deaths = %v, expected ErrScoreOutOfRange", err)
+	}
+	if _, err := mfs.MergeMembers(ctx, "other", "new", MergeMax); err != nil {
+		t.Fatalf("MergeMembers with MergeMax failed: %v", err)
+	}
+	if scores, _ := mfs.GetScores(ctx, "new"); scores[0].Int64() != 42 || scores[1].Int64() != 100 {
+		t.Errorf("GetScores(new) = %v, expected points 42 and deaths 100", scores)
+	}
+}
@@ -0,0 +1,85 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// writeConcern holds the replication required by WaitForReplicas.
+type writeConcern struct {
+	replicas int
+	timeout  time.Duration
+}
+
+// writeConcernKey is the context key of a writeConcern.
+type writeConcernKey struct{}
+
+// WaitForReplicas returns a context under which every write of a set, such as IncreaseScore,
+// AddMember or Freeze, is followed by a WAIT for at least replicas replicas to acknowledge it, so
+// critical updates like the final results of a season survive a failover. The WAIT blocks for up to
+// timeout, or forever for a timeout of 0. A write acknowledged by fewer replicas fails with a
+// *ReplicationError, though it was applied on the primary: retrying an Incremental update then
+// applies it twice.
+//
+// In Redis Cluster the WAIT runs on the primary holding the set's key, so writes to keys in other
+// slots, such as the recorded layout or derived sets without a HashTagKeyBuilder, aren't covered.
+// Ring clients are not supported.
+func WaitForReplicas(ctx context.Context, replicas int, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, writeConcernKey{}, writeConcern{replicas: replicas, timeout: timeout})
+}
+
+// waitKey returns the key written before a WAIT.
+func (mfs *MultiFieldSet) waitKey() string {
+	return mfs.derivedKey("wait")
+}
+
+// waitForReplicas waits for the replicas required by ctx, if any, to acknowledge the writes made
+// so far on the primary of the set's key.
+//
+// WAIT only covers the writes made on its own connection, which the pool may not have used for
+// them, so it is pipelined after a write to the wait key: replicas apply the replication stream in
+// order, so acknowledging that write acknowledges every earlier one.
+func (mfs *MultiFieldSet) waitForReplicas(ctx context.Context) error {
+	concern, ok := ctx.Value(writeConcernKey{}).(writeConcern)
+	if !ok || concern.replicas <= 0 {
+		return nil
+	}
+
+	var acked *redis.Cmd
+	err := mfs.primary(ctx, func(client redis.UniversalClient) error {
+		var node redis.Cmdable
+		switch c := client.(type) {
+		case *redis.ClusterClient:
+			master, err := c.MasterForKey(ctx, mfs.key)
+			if err != nil {
+				return err
+			}
+			node = master
+		case *redis.Ring:
+			return errors.New("WaitForReplicas is not supported with a Ring client")
+		default:
+			node = c
+		}
+
+		_, err := node.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, mfs.waitKey(), 1, time.Minute)
+			acked = pipe.Do(ctx, "wait", concern.replicas, concern.timeout.Milliseconds())
+			return nil
+		})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	n, err := acked.Int()
+	if err != nil {
+		return err
+	}
+	if n < concern.replicas {
+		return &ReplicationError{Replicas: concern.replicas, Acknowledged: n}
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/alicebob/miniredis/v2/server"
+	"github.com/go-redis/redis/v8"
+)
+
+// fakeWait registers a WAIT command on srv, which miniredis lacks, replying with the number of
+// replicas stored in acked. It returns the number of WAIT calls.
+func fakeWait(t *testing.T, srv *miniredis.Miniredis, acked *atomic.Int64) *atomic.Int64 {
+	t.Helper()
+	var calls atomic.Int64
+	err := srv.Server().Register("WAIT", func(c *server.Peer, cmd string, args []string) {
+		calls.Add(1)
+		c.WriteInt(int(acked.Load()))
+	})
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	return &calls
+}
+
+func TestWaitForReplicas(t *testing.T) {
+	client, server := newTestClient(t)
+	var acked atomic.Int64
+	calls := fakeWait(t, server, &acked)
+	mfs, err := New(MultiFieldSetOptions{
+		Name:   "test",
+		Fields: []Field{{Name: "points", Sort: Descending, MaxValue: 1000, UpdateType: Incremental}},
+		Client: client,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	ctx := context.Background()
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 10}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	if calls.Load() != 0 {
+		t.Errorf("WAIT called %d times without WaitForReplicas", calls.Load())
+	}
+
+	acked.Store(1)
+	waitCtx := WaitForReplicas(ctx, 1, 100*time.Millisecond)
+	if _, err := mfs.IncreaseScore(waitCtx, map[string]float64{"points": 10}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore() with a replica error = %v", err)
+	}
+	if calls.Load() == 0 {
+		t.Errorf("WAIT not called with WaitForReplicas")
+	}
+
+	// The write is applied on the primary even if no replica acknowledges it
+	acked.Store(0)
+	_, err = mfs.IncreaseScore(waitCtx, map[string]float64{"points": 10}, "alice")
+	var replicationErr *ReplicationError
+	if !errors.Is(err, ErrNotReplicated) || !errors.As(err, &replicationErr) || replicationErr.Acknowledged != 0 {
+		t.Errorf("IncreaseScore() without replicas error = %v, expected a ReplicationError", err)
+	}
+	if scores, err := mfs.GetScores(ctx, "alice"); err != nil || FieldValue(scores, "points") != 30 {
+		t.Errorf("GetScores() = %v, %v, expected 30 points", scores, err)
+	}
+}
+
+func TestWaitForReplicas_Failover(t *testing.T) {
+	client, server := newTestClient(t)
+	var acked atomic.Int64
+	acked.Store(1)
+	fakeWait(t, server, &acked)
+	mfs, err := New(MultiFieldSetOptions{
+		Name:        "test",
+		Fields:      []Field{{Name: "points", Sort: Descending, MaxValue: 1000, UpdateType: Incremental}},
+		Client:      client,
+		RetryPolicy: &RetryPolicy{MaxAttempts: 50, InitialBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	// The primary is demoted while Sentinel promotes a replica, then writes go through again
+	server.SetError("READONLY You can't write against a read only replica.")
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		server.SetError("")
+	}()
+	ctx := WaitForReplicas(context.Background(), 1, time.Second)
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 10}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore() during failover error = %v", err)
+	}
+	if scores, err := mfs.GetScores(ctx, "alice"); err != nil || FieldValue(scores, "points") != 10 {
+		t.Errorf("GetScores() = %v, %v, expected 10 points", scores, err)
+	}
+}
+
+func TestWaitForReplicas_Cluster(t *testing.T) {
+	server := miniredis.RunT(t)
+	var acked atomic.Int64
+	acked.Store(2)
+	calls := fakeWait(t, server, &acked)
+	client := redis.NewClusterClient(&redis.ClusterOptions{Addrs: []string{server.Addr()}})
+	t.Cleanup(func() { client.Close() })
+	mfs, err := New(MultiFieldSetOptions{
+		Name:       "test",
+		Fields:     []Field{{Name: "points", Sort: Descending, MaxValue: 1000, UpdateType: Incremental}},
+		Client:     client,
+		KeyBuilder: HashTagKeyBuilder{},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	ctx := WaitForReplicas(context.Background(), 2, time.Second)
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 10}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	if calls.Load() == 0 {
+		t.Errorf("WAIT not called on the cluster primary")
+	}
+}
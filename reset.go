@@ -0,0 +1,97 @@
+package zmultifield
+
+import (
+	"context"
+	"math/big"
+	"strconv"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// resetFieldsScript returns the named fields of a member to their defaults in one atomic step.
+// Like the merge script it decodes with arithmetic, which is exact below 2^53.
+//
+// KEYS[1] is the main set and KEYS[2..n] the field indexes, if maintained, in field order.
+// ARGV[1] is the member and ARGV[2] the zscore used when the member doesn't exist, followed by four
+// values per field: 2^shift, 2^bits, the field's default raw value and 1 if the field is reset.
+// The script returns the new zscore.
+var resetFieldsScript = newWriteScript(`
+local zscore = tonumber(redis.call('ZSCORE', KEYS[1], ARGV[1]) or ARGV[2])
+local raws = {}
+for i = 3, #ARGV, 4 do
+	local base = tonumber(ARGV[i])
+	local size = tonumber(ARGV[i + 1])
+	local raw = math.floor(zscore / base) % size
+	if ARGV[i + 3] == '1' then
+		local default = tonumber(ARGV[i + 2])
+		zscore = zscore + (default - raw) * base
+		raw = default
+	end
+	raws[#raws + 1] = raw
+end
+
+local encoded = string.format('%.17g', zscore)
+redis.call('ZADD', KEYS[1], encoded, ARGV[1])
+for i = 2, #KEYS do
+	redis.call('ZADD', KEYS[i], string.format('%.17g', raws[i - 1]), ARGV[1])
+end
+return encoded
+`)
+
+// ResetFields atomically returns the named fields of a member to their default values while keeping
+// every other field intact, e.g. to clear weekly points but keep lifetime wins. A member that isn't
+// in the set is added with default scores. Resetting an Average field also resets its count. It
+// returns the member's new zscore.
+func (mfs *MultiFieldSet) ResetFields(ctx context.Context, member string, fieldNames ...string) (*big.Int, error) {
+	reset := make(map[string]bool, len(fieldNames))
+	for _, name := range fieldNames {
+		if mfs.GetFieldByName(name) == nil {
+			return nil, mfs.runOnError(ctx, "ResetFields", member, fieldNotFoundError(name))
+		}
+		reset[name] = true
+		if field := mfs.GetFieldByName(name); field.count != nil {
+			reset[field.count.Name] = true
+		}
+	}
+
+	keys := []string{mfs.key}
+	args := []interface{}{member, mfs.defaultZScore.String()}
+	for _, field := range mfs.fields {
+		if mfs.maintainFieldIndexes {
+			keys = append(keys, mfs.fieldIndexKey(field))
+		}
+		value, flag := field.defaultScore(), "0"
+		if reset[field.Name] {
+			flag = "1"
+		}
+		if field == mfs.updatedAt {
+			value, flag = updatedAtRaw(), "1"
+		}
+		// Members added by the reset need their salt, which the default zscore lacks
+		if field == mfs.salt {
+			value, flag = mfs.saltRaw(member), "1"
+		}
+		args = append(args,
+			uint64(1)<<field.shiftValue,
+			uint64(1)<<field.bits,
+			value.String(),
+			flag,
+		)
+	}
+
+	var encoded string
+	err := mfs.write(ctx, func(client redis.UniversalClient) error {
+		var err error
+		encoded, err = resetFieldsScript.Run(ctx, client, mfs.writeGuard(), keys, args...).Text()
+		return err
+	})
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "ResetFields", member, err)
+	}
+
+	zscore, err := strconv.ParseFloat(encoded, 64)
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "ResetFields", member, err)
+	}
+	return new(big.Int).SetInt64(int64(zscore)), nil
+}
@@ -0,0 +1,52 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestResetFields(t *testing.T) {
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{MaintainFieldIndexes: true})
+	ctx := context.Background()
+
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 120, "deaths": 7}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+
+	zscore, err := mfs.ResetFields(ctx, "alice", "points")
+	if err != nil {
+		t.Fatalf("ResetFields() error = %v", err)
+	}
+
+	scores := mfs.CalculateScoresFromZScore(zscore)
+	if scores["points"].Int64() != 0 || scores["deaths"].Int64() != 7 {
+		t.Errorf("ResetFields(points) = %v/%v, expected 0/7", scores["points"], scores["deaths"])
+	}
+
+	stored, err := mfs.GetScoreForField(ctx, "deaths", "alice")
+	if err != nil || stored.Int64() != 7 {
+		t.Errorf("GetScoreForField(deaths) = %v, %v, expected 7", stored, err)
+	}
+
+	index, err := mfs.client.ZScore(ctx, mfs.fieldIndexKey(mfs.fields[0]), "alice").Result()
+	if err != nil || index != 1023 {
+		t.Errorf("points index = %v, %v, expected 1023", index, err)
+	}
+
+	// A missing member is created with default scores
+	zscore, err = mfs.ResetFields(ctx, "bob", "deaths")
+	if err != nil {
+		t.Fatalf("ResetFields() for new member error = %v", err)
+	}
+	if zscore.Cmp(mfs.defaultZScore) != 0 {
+		t.Errorf("ResetFields() for new member = %v, expected %v", zscore, mfs.defaultZScore)
+	}
+	if rank, err := mfs.GetFieldRank(ctx, "points", "bob"); err != nil || rank != 1 {
+		t.Errorf("GetFieldRank(points, bob) = %d, %v, expected 1", rank, err)
+	}
+
+	if _, err := mfs.ResetFields(ctx, "alice", "missing"); !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("ResetFields(missing) error = %v, expected ErrFieldNotFound", err)
+	}
+}
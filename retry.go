@@ -0,0 +1,154 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RetryPolicy configures how Redis calls made by a MultiFieldSet are retried on transient errors.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first. Values below 2 disable retries.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between retries. Zero means no cap.
+	MaxBackoff time.Duration
+	// Multiplier grows the delay after every retry. Values below 1 default to 2.
+	Multiplier float64
+	// Retryable reports whether an error is worth retrying. Defaults to DefaultRetryable. Writes
+	// that aren't idempotent, such as fast increments, pops, transfers and buffered flushes, are
+	// only retried on errors showing they were never applied, since a timeout or a connection lost
+	// after sending may hide a write that ran.
+	Retryable func(err error) bool
+}
+
+// DefaultRetryable reports whether err is a transient network or failover error. Missing members,
+// validation errors and cancelled contexts are never retried.
+func DefaultRetryable(err error) bool {
+	if err == nil || err == redis.Nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	return refusedError(err)
+}
+
+// refusedError reports whether err is a reply Redis sends instead of running a command, while it
+// is loading, failing over or only a replica.
+func refusedError(err error) bool {
+	msg := err.Error()
+	for _, prefix := range []string{"LOADING ", "READONLY ", "CLUSTERDOWN ", "TRYAGAIN ", "MASTERDOWN "} {
+		if strings.HasPrefix(msg, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// notApplied reports whether err shows that a command was never applied: Redis refused to run it,
+// or the connection to send it on couldn't be opened. Timeouts and connections lost after sending
+// may hide a command that ran.
+func notApplied(err error) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	return refusedError(err)
+}
+
+// backoff returns the delay before the given retry, starting at 1.
+func (p *RetryPolicy) backoff(retry int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 2
+	}
+
+	delay := float64(p.InitialBackoff)
+	for i := 1; i < retry; i++ {
+		delay *= multiplier
+		if p.MaxBackoff > 0 && delay >= float64(p.MaxBackoff) {
+			return p.MaxBackoff
+		}
+	}
+	return time.Duration(delay)
+}
+
+// retry runs fn until it succeeds, fails with a non-retryable error, the attempts are exhausted
+// or ctx is done.
+func (mfs *MultiFieldSet) retry(ctx context.Context, fn func() error) error {
+	return mfs.retryWhen(ctx, nil, fn)
+}
+
+// retryWhen is retry for errors that also satisfy also, if it is not nil.
+func (mfs *MultiFieldSet) retryWhen(ctx context.Context, also func(err error) bool, fn func() error) error {
+	policy := mfs.retryPolicy
+	err := fn()
+	if policy == nil {
+		return err
+	}
+
+	retryable := policy.Retryable
+	if retryable == nil {
+		retryable = DefaultRetryable
+	}
+
+	for attempt := 1; attempt < policy.MaxAttempts && err != nil && retryable(err) && (also == nil || also(err)); attempt++ {
+		timer := time.NewTimer(policy.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		err = fn()
+	}
+	return err
+}
+
+// write runs an operation against the primary client, retrying it according to the retry policy,
+// then waits for the replicas required by WaitForReplicas. The read cache is invalidated afterwards,
+// even if the operation failed, since it may still have been applied. Replies of guarded scripts
+// refused by a freeze are returned as ErrFrozen.
+func (mfs *MultiFieldSet) write(ctx context.Context, fn func(client redis.UniversalClient) error) error {
+	defer mfs.cache.invalidate()
+	if err := frozenError(mfs.primary(ctx, fn)); err != nil {
+		return err
+	}
+	return mfs.waitForReplicas(ctx)
+}
+
+// writeOnce is write for operations that aren't idempotent, such as an increment or a pop, which a
+// retry could apply twice: only errors showing the operation was never applied are retried.
+func (mfs *MultiFieldSet) writeOnce(ctx context.Context, fn func(client redis.UniversalClient) error) error {
+	defer mfs.cache.invalidate()
+	err := mfs.retryWhen(ctx, notApplied, func() error {
+		return fn(mfs.client)
+	})
+	if err := frozenError(err); err != nil {
+		return err
+	}
+	return mfs.waitForReplicas(ctx)
+}
+
+// primary runs a read-only operation against the primary client, retrying it according to the
+// retry policy. Unlike write it leaves the read cache alone.
+func (mfs *MultiFieldSet) primary(ctx context.Context, fn func(client redis.UniversalClient) error) error {
+	return mfs.retry(ctx, func() error {
+		return fn(mfs.client)
+	})
+}
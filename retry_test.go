@@ -0,0 +1,202 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+func TestDefaultRetryable(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected bool
+	}{
+		{nil, false},
+		{redis.Nil, false},
+		{context.Canceled, false},
+		{ErrFieldNotFound, false},
+		{io.EOF, true},
+		{errors.New("LOADING Redis is loading the dataset in memory"), true},
+		{errors.New("READONLY You can't write against a read only replica."), true},
+		{errors.New("ERR wrong number of arguments"), false},
+	}
+
+	for _, test := range tests {
+		if got := DefaultRetryable(test.err); got != test.expected {
+			t.Errorf("DefaultRetryable(%v) = %v, expected %v", test.err, got, test.expected)
+		}
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := &RetryPolicy{InitialBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond}
+
+	expected := []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond, 50 * time.Millisecond}
+	for i, want := range expected {
+		if got := policy.backoff(i + 1); got != want {
+			t.Errorf("backoff(%d) = %v, expected %v", i+1, got, want)
+		}
+	}
+}
+
+func TestRetryPolicy_Attempts(t *testing.T) {
+	client, server := newTestClient(t)
+	ctx := context.Background()
+
+	attempts := 0
+	mfs, err := New(MultiFieldSetOptions{
+		Name:   "board",
+		Fields: []Field{{Name: "points", Sort: Descending, MaxValue: 1000, UpdateType: Incremental}},
+		Client: client,
+		RetryPolicy: &RetryPolicy{
+			MaxAttempts:    3,
+			InitialBackoff: time.Millisecond,
+			Retryable: func(err error) bool {
+				attempts++
+				return DefaultRetryable(err)
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	server.SetError("TRYAGAIN Multiple keys request during rehashing of slot")
+	if _, err := mfs.GetRank(ctx, "alice"); err == nil {
+		t.Fatalf("GetRank() succeeded, expected error")
+	}
+	if attempts != 2 {
+		t.Errorf("Retryable called %d times, expected 2", attempts)
+	}
+
+	server.SetError("")
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 5}, "alice"); err != nil {
+		t.Errorf("IncreaseScore() error = %v", err)
+	}
+}
+
+func TestRetryPolicy_WriteOnce(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+	mfs, err := New(MultiFieldSetOptions{
+		Name:        "board",
+		Fields:      []Field{{Name: "points", Sort: Descending, MaxValue: 1000, UpdateType: Incremental}},
+		Client:      client,
+		RetryPolicy: &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	dial := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	read := &net.OpError{Op: "read", Net: "tcp", Err: errors.New("i/o timeout")}
+	tests := []struct {
+		err      error
+		expected int
+	}{
+		// The write may have run before the connection broke
+		{io.EOF, 1},
+		{read, 1},
+		// The write never reached Redis, or Redis refused it
+		{dial, 2},
+		{errors.New("TRYAGAIN Multiple keys request during rehashing of slot"), 2},
+	}
+	for _, test := range tests {
+		calls := 0
+		mfs.writeOnce(ctx, func(client redis.UniversalClient) error {
+			calls++
+			if calls == 1 {
+				return test.err
+			}
+			return nil
+		})
+		if calls != test.expected {
+			t.Errorf("writeOnce() failing with %v ran %d times, expected %d", test.err, calls, test.expected)
+		}
+	}
+}
+
+// timeoutAfterWrite is a redis.Hook failing the next script or transaction with a read timeout once
+// Redis has applied it, as when the connection breaks before the reply arrives.
+type timeoutAfterWrite struct {
+	armed bool
+}
+
+var errReadTimeout = &net.OpError{Op: "read", Net: "tcp", Err: errors.New("i/o timeout")}
+
+func (h *timeoutAfterWrite) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+func (h *timeoutAfterWrite) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	if name := cmd.Name(); (name == "evalsha" || name == "eval") && h.armed && cmd.Err() == nil {
+		h.armed = false
+		return errReadTimeout
+	}
+	return nil
+}
+
+func (h *timeoutAfterWrite) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+func (h *timeoutAfterWrite) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	for _, cmd := range cmds {
+		if cmd.Name() == "exec" && h.armed && cmd.Err() == nil {
+			h.armed = false
+			return errReadTimeout
+		}
+	}
+	return nil
+}
+
+func TestRetryPolicy_TimeoutAfterWrite(t *testing.T) {
+	for _, strategy := range []UpdateStrategy{UpdateScripted, UpdateOptimistic} {
+		client, _ := newTestClient(t)
+		hook := &timeoutAfterWrite{}
+		client.AddHook(hook)
+		mfs, err := New(MultiFieldSetOptions{
+			Name: "board",
+			Fields: []Field{
+				{Name: "points", Sort: Descending, MaxValue: 1000, UpdateType: Incremental, HalfLife: time.Hour},
+			},
+			Client:         client,
+			UpdateStrategy: strategy,
+			RetryPolicy:    &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond},
+		})
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		ctx := context.Background()
+
+		// The increment is applied once, and its timeout is returned rather than retried
+		hook.armed = true
+		_, err = mfs.IncreaseScore(WithIdempotencyKey(ctx, "first"), map[string]float64{"points": 400}, "alice")
+		if !errors.Is(err, errReadTimeout) {
+			t.Errorf("%v: IncreaseScore() error = %v, expected the timeout", strategy, err)
+		}
+		scores, err := mfs.GetScores(ctx, "alice")
+		if err != nil {
+			t.Fatalf("GetScores() error = %v", err)
+		}
+		if got := FieldValue(scores, "points"); got != 400 {
+			t.Errorf("%v: points after a timed out IncreaseScore() = %v, expected 400", strategy, got)
+		}
+
+		hook.armed = true
+		if _, err := mfs.DecayBy(ctx, time.Hour); !errors.Is(err, errReadTimeout) {
+			t.Errorf("%v: DecayBy() error = %v, expected the timeout", strategy, err)
+		}
+		if scores, err = mfs.GetScores(ctx, "alice"); err != nil {
+			t.Fatalf("GetScores() error = %v", err)
+		}
+		if got := FieldValue(scores, "points"); got != 200 {
+			t.Errorf("%v: points after a timed out DecayBy() = %v, expected 200", strategy, got)
+		}
+	}
+}
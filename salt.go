@@ -0,0 +1,75 @@
+package zmultifield
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// SaltField is the name of the field maintained when Salt is enabled.
+const SaltField = "salt"
+
+// defaultSaltBits is the width of the salt field when SaltOptions.Bits is zero.
+const defaultSaltBits = 8
+
+// SaltOptions configures the salt field, see MultiFieldSetOptions.Salt.
+type SaltOptions struct {
+	// Secret keys the noise, so the salt of a member can't be computed from its name. Required.
+	Secret []byte
+	// Bits is the width of the salt field, 8 by default and at most 32. The salt takes these bits
+	// of the score's precision.
+	Bits int
+}
+
+// saltFieldDef returns the definition of the salt field for opts.
+func saltFieldDef(opts *SaltOptions) Field {
+	bits := opts.Bits
+	if bits == 0 {
+		bits = defaultSaltBits
+	}
+	return Field{
+		Name:       SaltField,
+		Sort:       Ascending,
+		MaxValue:   float64(uint64(1)<<bits - 1),
+		UpdateType: Replace,
+	}
+}
+
+// withSalt adds the salt field to fields as the least significant field of layout, below
+// updatedAt, rejecting a user field with the same name.
+func withSalt(fields []Field, opts *SaltOptions, layout Layout) ([]Field, error) {
+	if len(opts.Secret) == 0 {
+		return nil, errors.New("salt secret is required")
+	}
+	if opts.Bits < 0 || opts.Bits > 32 {
+		return nil, fmt.Errorf("salt bits must be between 1 and 32, got %d", opts.Bits)
+	}
+	for _, f := range fields {
+		if f.Name == SaltField {
+			return nil, errors.New("field name salt is reserved when Salt is enabled")
+		}
+	}
+	if layout.LeastSignificantFirst {
+		return append([]Field{saltFieldDef(opts)}, fields...), nil
+	}
+	return append(append([]Field(nil), fields...), saltFieldDef(opts)), nil
+}
+
+// saltRaw returns the raw salt of member: the first bytes of an HMAC-SHA256 of the member keyed
+// with the secret, truncated to the field.
+func (mfs *MultiFieldSet) saltRaw(member string) *big.Int {
+	mac := hmac.New(sha256.New, mfs.saltSecret)
+	mac.Write([]byte(member))
+	sum := binary.BigEndian.Uint64(mac.Sum(nil))
+	return new(big.Int).SetUint64(sum & mfs.salt.mask64)
+}
+
+// stampSalt sets the salt field in scores to the salt of member, if the set is salted.
+func (mfs *MultiFieldSet) stampSalt(scores []*big.Int, member string) {
+	if mfs.salt != nil {
+		scores[mfs.salt.position] = mfs.saltRaw(member)
+	}
+}
@@ -0,0 +1,139 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"testing"
+)
+
+func TestSalt(t *testing.T) {
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{Salt: &SaltOptions{Secret: []byte("s3cret")}})
+	ctx := context.Background()
+
+	var members []string
+	for i := 0; i < 8; i++ {
+		member := fmt.Sprintf("player%d", i)
+		members = append(members, member)
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 10}, member); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+	}
+	// Bulk loads are salted too
+	if _, err := mfs.BulkLoad(ctx, SliceIterator([]MemberScores{{Member: "bulk", Scores: []FieldScore{{Name: "points", Score: big.NewInt(10)}}}}), 10, 1, nil); err != nil {
+		t.Fatalf("BulkLoad() error = %v", err)
+	}
+	members = append(members, "bulk")
+
+	// Members tied on every other field are ordered by their salt, which never changes
+	sort.Slice(members, func(i, j int) bool { return mfs.saltRaw(members[i]).Cmp(mfs.saltRaw(members[j])) < 0 })
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"deaths": 0}, members[0]); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	top, err := mfs.GetTopMembers(ctx, 20)
+	if err != nil {
+		t.Fatalf("GetTopMembers() error = %v", err)
+	}
+	for i, m := range top {
+		if m.Member != members[i] {
+			t.Fatalf("rank %d = %s, expected %s", i, m.Member, members[i])
+		}
+		for _, score := range m.Scores {
+			if score.Name == SaltField {
+				t.Fatalf("GetTopMembers() returned the salt field")
+			}
+		}
+	}
+
+	scores, err := mfs.GetScores(ctx, members[0])
+	if err != nil {
+		t.Fatalf("GetScores() error = %v", err)
+	}
+	if len(scores) != 2 || scores[0].Name != "points" || scores[0].Score.Int64() != 10 {
+		t.Errorf("GetScores() = %v, expected points and deaths only", scores)
+	}
+
+	for _, opts := range []*SaltOptions{{}, {Secret: []byte("x"), Bits: 40}} {
+		client, _ := newTestClient(t)
+		_, err := New(MultiFieldSetOptions{Name: "bad", Client: client, Fields: []Field{{Name: "points", MaxValue: 10}}, Salt: opts})
+		if err == nil {
+			t.Errorf("New() with salt options %+v succeeded, expected an error", opts)
+		}
+	}
+}
+
+func TestSalt_TransferAndMerge(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+	newSet := func(name string) *MultiFieldSet {
+		mfs, err := New(MultiFieldSetOptions{Name: name, Client: client, Salt: &SaltOptions{Secret: []byte("s3cret")}, Fields: []Field{
+			{Name: "points", Sort: Descending, MaxValue: 1000, UpdateType: Incremental},
+		}})
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		return mfs
+	}
+	day1, day2, total := newSet("day1"), newSet("day2"), newSet("total")
+	// Salts are stamped per member, whichever set or operation wrote them
+	checkSalts := func(mfs *MultiFieldSet, members ...string) {
+		t.Helper()
+		for _, member := range members {
+			score, err := client.ZScore(ctx, mfs.key, member).Result()
+			if err != nil {
+				t.Fatalf("ZScore(%s) error = %v", member, err)
+			}
+			zscore, err := mfs.zscoreOf(member, score)
+			if err != nil {
+				t.Fatalf("zscoreOf(%s) error = %v", member, err)
+			}
+			if salt := mfs.extractFieldScore(mfs.salt, zscore); salt.Cmp(mfs.saltRaw(member)) != 0 {
+				t.Errorf("%s salt of %s = %v, expected %v", mfs.name, member, salt, mfs.saltRaw(member))
+			}
+		}
+	}
+
+	for _, set := range []*MultiFieldSet{day1, day2} {
+		for _, member := range []string{"alice", "bob"} {
+			if _, err := set.IncreaseScore(ctx, map[string]float64{"points": 100}, member); err != nil {
+				t.Fatalf("IncreaseScore() error = %v", err)
+			}
+		}
+	}
+	if _, _, err := day1.TransferScore(ctx, "alice", "carol", map[string]float64{"points": 30}); err != nil {
+		t.Fatalf("TransferScore() error = %v", err)
+	}
+	checkSalts(day1, "alice", "carol")
+
+	if _, err := total.MergeFrom(ctx, []*MultiFieldSet{day1, day2}, total.key, MergeSum); err != nil {
+		t.Fatalf("MergeFrom(MergeSum) error = %v", err)
+	}
+	checkSalts(total, "alice", "bob", "carol")
+	if scores, err := total.GetScores(ctx, "bob"); err != nil || scores[0].Score.Int64() != 200 {
+		t.Errorf("GetScores(bob) = %v, %v, expected 200 points", scores, err)
+	}
+
+	// Members created or renamed by a reset, rename or member merge get their own salt too
+	if _, err := total.ResetFields(ctx, "dave", "points"); err != nil {
+		t.Fatalf("ResetFields() error = %v", err)
+	}
+	if err := total.RenameMember(ctx, "carol", "erin"); err != nil {
+		t.Fatalf("RenameMember() error = %v", err)
+	}
+	if _, err := total.MergeMembers(ctx, "bob", "alice", MergeSum); err != nil {
+		t.Fatalf("MergeMembers() error = %v", err)
+	}
+	checkSalts(total, "alice", "dave", "erin")
+
+	other, err := New(MultiFieldSetOptions{Name: "other", Client: client, Salt: &SaltOptions{Secret: []byte("other")}, Fields: []Field{
+		{Name: "points", Sort: Descending, MaxValue: 1000, UpdateType: Incremental},
+	}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := total.MergeFrom(ctx, []*MultiFieldSet{other}, total.key, MergeSum); !errors.Is(err, ErrIncompatibleSets) {
+		t.Errorf("MergeFrom() with another salt secret error = %v, expected ErrIncompatibleSets", err)
+	}
+}
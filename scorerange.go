@@ -0,0 +1,53 @@
+package zmultifield
+
+import "math"
+
+// Range bounds the display value of a field, inclusively, for BuildScoreRange. Use math.Inf for
+// an open bound.
+type Range struct {
+	Min, Max float64
+}
+
+// AtLeast returns the Range of values of at least min.
+func AtLeast(min float64) Range {
+	return Range{Min: min, Max: math.Inf(1)}
+}
+
+// AtMost returns the Range of values of at most max.
+func AtMost(max float64) Range {
+	return Range{Min: math.Inf(-1), Max: max}
+}
+
+// ScoreRange is a range of packed scores returned by BuildScoreRange.
+type ScoreRange struct {
+	// Min and Max are the bounds of the range in the syntax of ZRANGEBYSCORE and ZCOUNT, e.g. for
+	// GetMembersInRange and GetCountInRange. A range no member can match is "+inf" to "-inf".
+	Min, Max string
+	// Exact is false if the range also holds members that don't match the bounds of some fields,
+	// which must then be checked on the decoded scores of every member in the range.
+	Exact bool
+}
+
+// BuildScoreRange returns the range of packed scores of the members whose fields lie within
+// ranges, for composing raw Redis queries, such as ZRANGEBYSCORE in a script or pipeline, without
+// reimplementing the set's bit layout. Fractional bounds are rounded inwards and bounds beyond a
+// field's capacity are clamped.
+//
+// A range of scores only selects exactly the bounds on the most significant field, and on the next
+// fields for as long as the previous ones are pinned to a single value; the range then covers the
+// other bounds without enforcing them and Exact is false. Filters passed to Where and Count are
+// resolved the same way, and check the remaining bounds while scanning the range.
+func (mfs *MultiFieldSet) BuildScoreRange(ranges map[string]Range) (ScoreRange, error) {
+	var f Filter
+	for name, r := range ranges {
+		f = f.And(F(name).Between(r.Min, r.Max))
+	}
+	c, err := mfs.compileFilter(f)
+	if err != nil {
+		return ScoreRange{}, err
+	}
+	if c.empty {
+		return ScoreRange{Min: "+inf", Max: "-inf", Exact: true}, nil
+	}
+	return ScoreRange{Min: c.min, Max: c.max, Exact: len(c.scanned) == 0}, nil
+}
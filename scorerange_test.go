@@ -0,0 +1,66 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestBuildScoreRange(t *testing.T) {
+	mfs := newTestSet(t)
+	ctx := context.Background()
+	players := map[string][2]float64{
+		"alice": {50, 1},
+		"bob":   {40, 5},
+		"carol": {40, 9},
+		"dave":  {20, 7},
+	}
+	for member, s := range players {
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": s[0], "deaths": s[1]}, member); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		ranges   map[string]Range
+		expected []string
+		exact    bool
+	}{
+		{"leading field", map[string]Range{"points": AtLeast(30)}, []string{"alice", "bob", "carol"}, true},
+		{"pinned leading field", map[string]Range{"points": {Min: 40, Max: 40}, "deaths": AtMost(6)}, []string{"bob"}, true},
+		{"trailing field", map[string]Range{"deaths": AtMost(6)}, []string{"alice", "bob", "carol", "dave"}, false},
+		{"no bounds", nil, []string{"alice", "bob", "carol", "dave"}, true},
+		{"empty", map[string]Range{"points": {Min: 60, Max: 10}}, nil, true},
+	}
+	for _, tt := range tests {
+		r, err := mfs.BuildScoreRange(tt.ranges)
+		if err != nil {
+			t.Fatalf("%s: BuildScoreRange() error = %v", tt.name, err)
+		}
+		if r.Exact != tt.exact {
+			t.Errorf("%s: Exact = %v, expected %v", tt.name, r.Exact, tt.exact)
+		}
+		members, err := mfs.GetMembersInRange(ctx, -1, 0, r.Min, r.Max)
+		if err != nil {
+			t.Fatalf("%s: GetMembersInRange() error = %v", tt.name, err)
+		}
+		if len(members) != len(tt.expected) {
+			t.Errorf("%s: GetMembersInRange() = %v, expected %v", tt.name, members, tt.expected)
+			continue
+		}
+		for i, m := range members {
+			if m.Member != tt.expected[i] {
+				t.Errorf("%s: GetMembersInRange() = %v, expected %v", tt.name, members, tt.expected)
+				break
+			}
+		}
+		if count, err := mfs.GetCountInRange(ctx, r.Min, r.Max); err != nil || count != int64(len(tt.expected)) {
+			t.Errorf("%s: GetCountInRange() = %d, %v, expected %d", tt.name, count, err, len(tt.expected))
+		}
+	}
+
+	if _, err := mfs.BuildScoreRange(map[string]Range{"kills": AtLeast(1)}); !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("BuildScoreRange() with an unknown field error = %v, expected ErrFieldNotFound", err)
+	}
+}
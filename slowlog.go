@@ -0,0 +1,47 @@
+package zmultifield
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// defaultSlowThreshold is the latency above which operations are logged when SlowThreshold is not
+// set.
+const defaultSlowThreshold = 100 * time.Millisecond
+
+// slowCountTimeout bounds the ZCARD reading the number of members of a set with a slow operation.
+const slowCountTimeout = time.Second
+
+// Logger receives structured log entries as a message followed by alternating keys and values.
+// *slog.Logger implements it. Implementations must be safe for concurrent use.
+type Logger interface {
+	Warn(msg string, args ...any)
+}
+
+// logSlow logs an operation that took longer than the slow threshold, with the set's name, its
+// number of members and the operation's error, if any. The number of members is read with ZCARD
+// only for slow operations, and omitted if the read fails.
+func (mfs *MultiFieldSet) logSlow(op string, duration time.Duration, err error) {
+	if mfs.logger == nil || duration < mfs.slowThreshold {
+		return
+	}
+
+	args := []any{"set", mfs.name, "op", op, "duration", duration}
+	ctx, cancel := context.WithTimeout(context.Background(), slowCountTimeout)
+	defer cancel()
+	var members int64
+	countErr := mfs.read(ctx, func(client redis.UniversalClient) error {
+		var err error
+		members, err = client.ZCard(ctx, mfs.key).Result()
+		return err
+	})
+	if countErr == nil {
+		args = append(args, "members", members)
+	}
+	if err != nil {
+		args = append(args, "error", err)
+	}
+	mfs.logger.Warn("slow operation", args...)
+}
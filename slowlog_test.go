@@ -0,0 +1,56 @@
+package zmultifield
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestSlowOperationLogging(t *testing.T) {
+	var buf bytes.Buffer
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{
+		Logger:        slog.New(slog.NewJSONHandler(&buf, nil)),
+		SlowThreshold: time.Nanosecond,
+	})
+	ctx := context.Background()
+
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 10}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	if _, err := mfs.GetTopMembers(ctx, 10); err != nil {
+		t.Fatalf("GetTopMembers() error = %v", err)
+	}
+
+	var entries []map[string]any
+	for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		var entry map[string]any
+		if err := json.Unmarshal(line, &entry); err != nil {
+			t.Fatalf("Unmarshal(%s) error = %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("logged %d entries, expected 2: %s", len(entries), buf.String())
+	}
+	for i, op := range []string{"IncreaseScore", "GetMembers"} {
+		entry := entries[i]
+		if entry["msg"] != "slow operation" || entry["op"] != op || entry["set"] != "test" || entry["members"] != 1.0 || entry["duration"] == nil {
+			t.Errorf("entry %d = %v, expected a slow %s of a set of one member", i, entry, op)
+		}
+	}
+}
+
+func TestSlowOperationLogging_Threshold(t *testing.T) {
+	var buf bytes.Buffer
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{Logger: slog.New(slog.NewJSONHandler(&buf, nil)), SlowThreshold: time.Hour})
+
+	if _, err := mfs.IncreaseScore(context.Background(), map[string]float64{"points": 10}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("logged %s, expected nothing below the threshold", buf.String())
+	}
+}
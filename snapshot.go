@@ -0,0 +1,252 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// snapshotScript copies the main set under a snapshot key and records the snapshot's metadata in
+// one atomic step, replacing any snapshot with the same label.
+//
+// KEYS[1] is the main set, KEYS[2] the snapshot, KEYS[3] its metadata hash and KEYS[4] the sorted
+// set of labels by creation time. ARGV[1] is the label and ARGV[2] the creation time in epoch
+// milliseconds. The script returns the number of members copied.
+var snapshotScript = redis.NewScript(`
+local n = redis.call('ZUNIONSTORE', KEYS[2], 1, KEYS[1])
+redis.call('DEL', KEYS[3])
+redis.call('HSET', KEYS[3], 'created_at', ARGV[2], 'members', n)
+redis.call('ZADD', KEYS[4], ARGV[2], ARGV[1])
+return n
+`)
+
+// SnapshotInfo describes a snapshot taken with Snapshot.
+type SnapshotInfo struct {
+	Label     string
+	CreatedAt time.Time
+	Members   int64
+}
+
+// MemberDiff describes how a member changed between two snapshots. Ranks are 0-based and -1 means
+// the member wasn't in that snapshot; a member that was missing is compared against default scores.
+type MemberDiff struct {
+	Member  string
+	OldRank int64
+	NewRank int64
+	// Deltas holds the change of every field's display value, new minus old.
+	Deltas map[string]int64
+}
+
+// RankChange returns how many places the member moved up, negative if it moved down, or 0 if it is
+// missing from either snapshot.
+func (d MemberDiff) RankChange() int64 {
+	if d.OldRank < 0 || d.NewRank < 0 {
+		return 0
+	}
+	return d.OldRank - d.NewRank
+}
+
+// SnapshotDiff is the result of Diff. Members lists every member whose scores or rank changed,
+// ordered by new rank, followed by the members that were removed, ordered by old rank.
+type SnapshotDiff struct {
+	From    SnapshotInfo
+	To      SnapshotInfo
+	Members []MemberDiff
+}
+
+// snapshotKey returns the key of the snapshot with the given label.
+func (mfs *MultiFieldSet) snapshotKey(label string) string {
+	return mfs.derivedKey("snapshot:" + label)
+}
+
+// snapshotInfoKey returns the key of the metadata hash of the snapshot with the given label.
+func (mfs *MultiFieldSet) snapshotInfoKey(label string) string {
+	return mfs.derivedKey("snapshot:" + label + ":info")
+}
+
+// snapshotsKey returns the key of the sorted set of snapshot labels by creation time.
+func (mfs *MultiFieldSet) snapshotsKey() string {
+	return mfs.derivedKey("snapshots")
+}
+
+// Snapshot copies the set's current members and scores under label, e.g. at the end of every week,
+// replacing an earlier snapshot with the same label. Snapshots are kept, even by Clear, until
+// DeleteSnapshot is called. In Redis Cluster the set needs a HashTagKeyBuilder, as the copy is made
+// server side.
+func (mfs *MultiFieldSet) Snapshot(ctx context.Context, label string) (*SnapshotInfo, error) {
+	if label == "" {
+		return nil, mfs.runOnError(ctx, "Snapshot", "", errors.New("snapshot label is required"))
+	}
+
+	info := &SnapshotInfo{Label: label, CreatedAt: time.UnixMilli(time.Now().UnixMilli())}
+	keys := []string{mfs.key, mfs.snapshotKey(label), mfs.snapshotInfoKey(label), mfs.snapshotsKey()}
+	err := mfs.withLock(ctx, "Snapshot", func(ctx context.Context) error {
+		return mfs.write(ctx, func(client redis.UniversalClient) error {
+			var err error
+			info.Members, err = snapshotScript.Run(ctx, client, keys, label, info.CreatedAt.UnixMilli()).Int64()
+			return err
+		})
+	})
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "Snapshot", "", err)
+	}
+	mfs.emitSeason(ctx, SeasonSnapshotted, label)
+	return info, nil
+}
+
+// ListSnapshots returns the snapshots of the set, oldest first.
+func (mfs *MultiFieldSet) ListSnapshots(ctx context.Context) (_ []SnapshotInfo, err error) {
+	defer mfs.observeRead("ListSnapshots", time.Now(), &err)
+
+	var labels []string
+	err = mfs.read(ctx, func(client redis.UniversalClient) error {
+		labels, err = client.ZRange(ctx, mfs.snapshotsKey(), 0, -1).Result()
+		return err
+	})
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "ListSnapshots", "", err)
+	}
+
+	snapshots := make([]SnapshotInfo, 0, len(labels))
+	for _, label := range labels {
+		info, err := mfs.snapshotInfo(ctx, label)
+		if errors.Is(err, ErrSnapshotNotFound) {
+			continue
+		} else if err != nil {
+			return nil, mfs.runOnError(ctx, "ListSnapshots", "", err)
+		}
+		snapshots = append(snapshots, *info)
+	}
+	return snapshots, nil
+}
+
+// DeleteSnapshot deletes the snapshot with the given label. Deleting a missing snapshot is not an
+// error.
+func (mfs *MultiFieldSet) DeleteSnapshot(ctx context.Context, label string) error {
+	err := mfs.withLock(ctx, "DeleteSnapshot", func(ctx context.Context) error {
+		return mfs.write(ctx, func(client redis.UniversalClient) error {
+			_, err := client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.Del(ctx, mfs.snapshotKey(label), mfs.snapshotInfoKey(label))
+				pipe.ZRem(ctx, mfs.snapshotsKey(), label)
+				return nil
+			})
+			return err
+		})
+	})
+	return mfs.runOnError(ctx, "DeleteSnapshot", "", err)
+}
+
+// snapshotInfo reads the metadata of the snapshot with the given label.
+func (mfs *MultiFieldSet) snapshotInfo(ctx context.Context, label string) (*SnapshotInfo, error) {
+	var fields map[string]string
+	err := mfs.read(ctx, func(client redis.UniversalClient) error {
+		var err error
+		fields, err = client.HGetAll(ctx, mfs.snapshotInfoKey(label)).Result()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrSnapshotNotFound, label)
+	}
+
+	createdAt, err := strconv.ParseInt(fields["created_at"], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	members, err := strconv.ParseInt(fields["members"], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &SnapshotInfo{Label: label, CreatedAt: time.UnixMilli(createdAt), Members: members}, nil
+}
+
+// Diff compares two snapshots and returns the field deltas and rank movement of every member that
+// changed, e.g. to show the biggest movers of the week. An empty label stands for the live set, so
+// Diff(ctx, "week-41", "") compares a snapshot with the current standings. Both sides are read in
+// full, so Diff is meant for sets that fit comfortably in memory.
+func (mfs *MultiFieldSet) Diff(ctx context.Context, fromLabel, toLabel string) (_ *SnapshotDiff, err error) {
+	defer mfs.observeRead("Diff", time.Now(), &err)
+
+	from, fromMembers, err := mfs.readSnapshot(ctx, fromLabel)
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "Diff", "", err)
+	}
+	to, toMembers, err := mfs.readSnapshot(ctx, toLabel)
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "Diff", "", err)
+	}
+
+	defaults := mfs.Decode(mfs.defaultZScore)
+	oldRanks := make(map[string]int64, len(fromMembers))
+	for i, z := range fromMembers {
+		oldRanks[memberName(z)] = int64(i)
+	}
+
+	diff := &SnapshotDiff{From: *from, To: *to}
+	seen := make(map[string]bool, len(toMembers))
+	for newRank, z := range toMembers {
+		member := memberName(z)
+		seen[member] = true
+		oldRank, old := int64(-1), defaults
+		if rank, ok := oldRanks[member]; ok {
+			oldRank, old = rank, mfs.DecodeUint64(uint64(fromMembers[rank].Score))
+		}
+		if d, changed := memberDiff(member, oldRank, int64(newRank), old, mfs.DecodeUint64(uint64(z.Score))); changed {
+			diff.Members = append(diff.Members, d)
+		}
+	}
+	for oldRank, z := range fromMembers {
+		member := memberName(z)
+		if seen[member] {
+			continue
+		}
+		d, _ := memberDiff(member, int64(oldRank), -1, mfs.DecodeUint64(uint64(z.Score)), defaults)
+		diff.Members = append(diff.Members, d)
+	}
+	return diff, nil
+}
+
+// memberDiff builds the MemberDiff of a member and reports whether anything changed.
+func memberDiff(member string, oldRank, newRank int64, oldValues, newValues map[string]int64) (MemberDiff, bool) {
+	d := MemberDiff{Member: member, OldRank: oldRank, NewRank: newRank, Deltas: make(map[string]int64, len(newValues))}
+	changed := oldRank != newRank
+	for name, value := range newValues {
+		d.Deltas[name] = value - oldValues[name]
+		changed = changed || d.Deltas[name] != 0
+	}
+	return d, changed
+}
+
+// readSnapshot reads the metadata and members, best first, of the snapshot with the given label,
+// or of the live set if label is empty.
+func (mfs *MultiFieldSet) readSnapshot(ctx context.Context, label string) (*SnapshotInfo, []redis.Z, error) {
+	key := mfs.key
+	info := &SnapshotInfo{CreatedAt: time.Now()}
+	if label != "" {
+		var err error
+		if info, err = mfs.snapshotInfo(ctx, label); err != nil {
+			return nil, nil, err
+		}
+		key = mfs.snapshotKey(label)
+	}
+
+	var members []redis.Z
+	err := mfs.read(ctx, func(client redis.UniversalClient) error {
+		var err error
+		members, err = client.ZRangeWithScores(ctx, key, 0, -1).Result()
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if label == "" {
+		info.Members = int64(len(members))
+	}
+	return info, members, nil
+}
@@ -0,0 +1,98 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSnapshotDiff(t *testing.T) {
+	mfs := newTestSet(t)
+	ctx := context.Background()
+
+	for member, points := range map[string]float64{"alice": 30, "bob": 20, "carol": 10} {
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": points}, member); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+	}
+	info, err := mfs.Snapshot(ctx, "week-1")
+	if err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+	if info.Members != 3 {
+		t.Errorf("Snapshot() members = %d, expected 3", info.Members)
+	}
+
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 25, "deaths": 2}, "carol"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 5}, "dave"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	if _, err := mfs.RemoveMember(ctx, "bob"); err != nil {
+		t.Fatalf("RemoveMember() error = %v", err)
+	}
+	if _, err := mfs.Snapshot(ctx, "week-2"); err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+
+	diff, err := mfs.Diff(ctx, "week-1", "week-2")
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+	if diff.From.Label != "week-1" || diff.To.Label != "week-2" || diff.To.Members != 3 {
+		t.Errorf("Diff() snapshots = %+v and %+v", diff.From, diff.To)
+	}
+
+	// carol moved from 2 to 0 past alice, dave is new and bob was removed
+	if len(diff.Members) != 4 {
+		t.Fatalf("Diff() members = %+v, expected carol, alice, dave and bob", diff.Members)
+	}
+	carol, alice, dave, bob := diff.Members[0], diff.Members[1], diff.Members[2], diff.Members[3]
+	if carol.Member != "carol" || carol.RankChange() != 2 || carol.Deltas["points"] != 25 || carol.Deltas["deaths"] != 2 {
+		t.Errorf("carol diff = %+v, expected rank change 2 and deltas points 25, deaths 2", carol)
+	}
+	if alice.Member != "alice" || alice.RankChange() != -1 || alice.Deltas["points"] != 0 {
+		t.Errorf("alice diff = %+v, expected rank change -1 without score changes", alice)
+	}
+	if dave.Member != "dave" || dave.OldRank != -1 || dave.NewRank != 2 || dave.Deltas["points"] != 5 {
+		t.Errorf("dave diff = %+v, expected a new member at rank 2 with 5 points", dave)
+	}
+	if bob.Member != "bob" || bob.OldRank != 1 || bob.NewRank != -1 || bob.Deltas["points"] != -20 {
+		t.Errorf("bob diff = %+v, expected a removed member that had 20 points", bob)
+	}
+
+	// The live set compares equal to the latest snapshot
+	if diff, err := mfs.Diff(ctx, "week-2", ""); err != nil || len(diff.Members) != 0 {
+		t.Errorf("Diff(week-2, live) = %+v, %v, expected no changes", diff, err)
+	}
+
+	if _, err := mfs.Diff(ctx, "week-0", "week-2"); !errors.Is(err, ErrSnapshotNotFound) {
+		t.Errorf("Diff() with a missing snapshot error = %v, expected ErrSnapshotNotFound", err)
+	}
+}
+
+func TestListAndDeleteSnapshots(t *testing.T) {
+	mfs := newTestSet(t)
+	ctx := context.Background()
+
+	for _, label := range []string{"a", "b"} {
+		if _, err := mfs.Snapshot(ctx, label); err != nil {
+			t.Fatalf("Snapshot() error = %v", err)
+		}
+	}
+	if err := mfs.DeleteSnapshot(ctx, "a"); err != nil {
+		t.Fatalf("DeleteSnapshot() error = %v", err)
+	}
+
+	snapshots, err := mfs.ListSnapshots(ctx)
+	if err != nil {
+		t.Fatalf("ListSnapshots() error = %v", err)
+	}
+	if len(snapshots) != 1 || snapshots[0].Label != "b" || snapshots[0].Members != 0 {
+		t.Errorf("ListSnapshots() = %+v, expected only the empty snapshot b", snapshots)
+	}
+	if _, err := mfs.Snapshot(ctx, ""); err == nil {
+		t.Error("Snapshot() without a label succeeded, expected an error")
+	}
+}
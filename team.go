@@ -0,0 +1,182 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// TeamAggregatorOptions configures a TeamAggregator.
+type TeamAggregatorOptions struct {
+	// Members is the set of individual members whose updates are aggregated.
+	Members *MultiFieldSet
+	// Teams is the set holding the team totals, keyed by team.
+	Teams *MultiFieldSet
+	// Fields lists the fields summed into the team totals. Each must exist in both sets and be
+	// Incremental in Teams. Defaults to every field of Members that Teams has as Incremental.
+	Fields []string
+	// TeamOf returns the team of a member, or "" if it has none.
+	TeamOf func(ctx context.Context, member string) (string, error)
+	// TeamHash is the key of a Redis hash mapping members to teams, used when TeamOf is nil.
+	TeamHash string
+}
+
+// TeamAggregator keeps team totals in a second MultiFieldSet up to date as members are updated,
+// replacing nightly batch recomputation of team boards.
+type TeamAggregator struct {
+	members *MultiFieldSet
+	teams   *MultiFieldSet
+	fields  []string
+	teamOf  func(ctx context.Context, member string) (string, error)
+}
+
+// NewTeamAggregator creates a TeamAggregator and registers it as an after-update hook on Members.
+// Every update of a member then adds the change of its aggregated fields to its team's totals.
+// Updates of members and teams are written conditionally and retried up to OptimisticRetries times
+// on conflicts, so concurrent updates are never lost or counted twice. Failures are reported
+// to the error hooks of Members with op "TeamAggregate", as the member update has already been
+// written. Writes to Members that don't know the member's previous scores, such as AddMember,
+// ResetMember or RemoveMember, are not aggregated; Recompute brings the totals back in line.
+func NewTeamAggregator(opts TeamAggregatorOptions) (*TeamAggregator, error) {
+	if opts.Members == nil || opts.Teams == nil {
+		return nil, errors.New("members and teams sets are required")
+	}
+	if opts.TeamOf == nil && opts.TeamHash == "" {
+		return nil, errors.New("team mapping is required")
+	}
+
+	fields := opts.Fields
+	if fields == nil {
+		for _, field := range opts.Members.fields {
+			if team := opts.Teams.GetFieldByName(field.Name); team != nil && team.UpdateType == Incremental {
+				fields = append(fields, field.Name)
+			}
+		}
+	}
+	for _, name := range fields {
+		if opts.Members.GetFieldByName(name) == nil {
+			return nil, fieldNotFoundError(name)
+		}
+		team := opts.Teams.GetFieldByName(name)
+		if team == nil {
+			return nil, fieldNotFoundError(name)
+		}
+		if team.UpdateType != Incremental {
+			return nil, fmt.Errorf("team field %s must be incremental", name)
+		}
+	}
+	if len(fields) == 0 {
+		return nil, errors.New("no fields to aggregate")
+	}
+
+	a := &TeamAggregator{members: opts.Members, teams: opts.Teams, fields: fields, teamOf: opts.TeamOf}
+	if a.teamOf == nil {
+		hash := opts.TeamHash
+		a.teamOf = func(ctx context.Context, member string) (string, error) {
+			var team string
+			err := a.members.primary(ctx, func(client redis.UniversalClient) error {
+				var err error
+				team, err = client.HGet(ctx, hash, member).Result()
+				return err
+			})
+			if err == redis.Nil {
+				return "", nil
+			}
+			return team, err
+		}
+	}
+	opts.Members.hooks.mu.Lock()
+	opts.Members.hooks.exact = true
+	opts.Members.hooks.mu.Unlock()
+	opts.Members.AfterUpdate(a.afterUpdate)
+	return a, nil
+}
+
+// afterUpdate adds the change of the aggregated fields of an update to the member's team.
+func (a *TeamAggregator) afterUpdate(ctx context.Context, event *UpdateEvent) {
+	if event.OldScores == nil {
+		return
+	}
+	deltas := make(map[string]float64, len(a.fields))
+	for _, name := range a.fields {
+		before, after := scoreByName(event.OldScores, name), scoreByName(event.NewScores, name)
+		if delta := new(big.Int).Sub(after, before); delta.Sign() != 0 {
+			deltas[name] = float64(delta.Int64())
+		}
+	}
+	if len(deltas) == 0 {
+		return
+	}
+
+	team, err := a.teamOf(ctx, event.Member)
+	if err != nil {
+		a.members.runOnError(ctx, "TeamAggregate", event.Member, err)
+		return
+	}
+	if team == "" {
+		return
+	}
+	if _, err := a.teams.increaseScoreWith(ctx, deltas, team, false, true); err != nil {
+		a.members.runOnError(ctx, "TeamAggregate", event.Member, err)
+	}
+}
+
+// Recompute rebuilds the team totals from the current scores of every member, e.g. once to
+// backfill an existing set or after writes that aren't aggregated. Members without a team are
+// skipped. Updates running concurrently with Recompute may be lost from the totals.
+func (a *TeamAggregator) Recompute(ctx context.Context) (int64, error) {
+	totals := make(map[string]map[string]int64)
+	var order []string
+	var err error
+	scanErr := a.members.scanEntries(ctx, func(z redis.Z, zscore *big.Int) bool {
+		member := memberName(z)
+		var team string
+		if team, err = a.teamOf(ctx, member); err != nil {
+			return false
+		}
+		if team == "" {
+			return true
+		}
+		sums, ok := totals[team]
+		if !ok {
+			sums = make(map[string]int64, len(a.fields))
+			totals[team] = sums
+			order = append(order, team)
+		}
+		for _, score := range a.members.zscoreToAllFieldScores(zscore) {
+			if a.aggregates(score.Name) {
+				sums[score.Name] += score.Score.Int64()
+			}
+		}
+		return true
+	})
+	if scanErr != nil {
+		err = scanErr
+	}
+	if err != nil {
+		return 0, a.teams.runOnError(ctx, "Recompute", "", err)
+	}
+
+	teams := make([]MemberScores, 0, len(order))
+	for _, team := range order {
+		scores := make([]FieldScore, 0, len(a.fields))
+		for _, name := range a.fields {
+			scores = append(scores, FieldScore{Name: name, Score: big.NewInt(totals[team][name])})
+		}
+		teams = append(teams, MemberScores{Member: team, Scores: scores})
+	}
+	return a.teams.Rebuild(ctx, SliceIterator(teams))
+}
+
+// aggregates reports whether the named field is summed into the team totals.
+func (a *TeamAggregator) aggregates(name string) bool {
+	for _, field := range a.fields {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
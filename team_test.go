@@ -0,0 +1,75 @@
+package zmultifield
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+func TestTeamAggregator(t *testing.T) {
+	members := newTestSet(t)
+	ctx := context.Background()
+	teams, err := New(MultiFieldSetOptions{
+		Name:   "teams",
+		Fields: []Field{{Name: "points", Sort: Descending, MaxValue: 100000, UpdateType: Incremental}},
+		Client: members.client,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if err := members.client.HSet(ctx, "teams:by-member", "alice", "red", "bob", "red", "carol", "blue").Err(); err != nil {
+		t.Fatalf("HSet() error = %v", err)
+	}
+	// carol scored before the aggregator existed
+	if _, err := members.IncreaseScore(ctx, map[string]float64{"points": 5}, "carol"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+
+	aggregator, err := NewTeamAggregator(TeamAggregatorOptions{Members: members, Teams: teams, TeamHash: "teams:by-member"})
+	if err != nil {
+		t.Fatalf("NewTeamAggregator() error = %v", err)
+	}
+
+	// Five updates each keep every write within OptimisticRetries conflicts
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		for _, member := range []string{"alice", "bob", "dave"} {
+			wg.Add(1)
+			go func(member string) {
+				defer wg.Done()
+				if _, err := members.IncreaseScore(ctx, map[string]float64{"points": 1, "deaths": 1}, member); err != nil {
+					t.Errorf("IncreaseScore() error = %v", err)
+				}
+			}(member)
+		}
+	}
+	wg.Wait()
+
+	var sum int64
+	for _, member := range []string{"alice", "bob"} {
+		points, err := members.GetScoreForField(ctx, "points", member)
+		if err != nil {
+			t.Fatalf("GetScoreForField() error = %v", err)
+		}
+		sum += points.Int64()
+	}
+	if red, err := teams.GetScoreForField(ctx, "points", "red"); err != nil || sum != 10 || red.Int64() != sum {
+		t.Errorf("red points = %v, %v, expected %d", red, err, sum)
+	}
+	if exists, _ := teams.MemberExists(ctx, "blue"); exists {
+		t.Error("blue team exists before Recompute")
+	}
+
+	if n, err := aggregator.Recompute(ctx); err != nil || n != 2 {
+		t.Fatalf("Recompute() = %d, %v, expected 2 teams", n, err)
+	}
+	top, err := teams.GetTopMembers(ctx, 10)
+	if err != nil || len(top) != 2 || top[0].Member != "red" || top[1].Member != "blue" || top[1].Scores[0].Score.Int64() != 5 {
+		t.Errorf("GetTopMembers() = %+v, %v, expected red ahead of blue with 5 points", top, err)
+	}
+
+	if _, err := NewTeamAggregator(TeamAggregatorOptions{Members: members, Teams: teams}); err == nil {
+		t.Error("NewTeamAggregator() without a team mapping succeeded")
+	}
+}
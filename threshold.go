@@ -0,0 +1,128 @@
+package zmultifield
+
+import (
+	"context"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// rawRangeAtLeast returns the raw range of values whose display value is at least value.
+func (mf *multiField) rawRangeAtLeast(value float64) (rawMin, rawMax *big.Int, ok bool) {
+	return mf.rawRangeWithin(value, math.Inf(1))
+}
+
+// rawRangeBelow returns the raw range of values whose display value is below value.
+func (mf *multiField) rawRangeBelow(value float64) (rawMin, rawMax *big.Int, ok bool) {
+	return mf.rawRangeWithin(math.Inf(-1), math.Ceil(value)-1)
+}
+
+// CountByFieldAtLeast returns the number of members whose value for the given field is at least
+// value. Like GetMembersByFieldRange it is resolved by Redis for the most significant field or when
+// MaintainFieldIndexes is enabled, and by scanning the set otherwise. Hidden members aren't
+// counted.
+func (mfs *MultiFieldSet) CountByFieldAtLeast(ctx context.Context, fieldName string, value float64) (_ int64, err error) {
+	defer mfs.observeRead("CountByFieldAtLeast", time.Now(), &err)
+
+	field := mfs.GetFieldByName(fieldName)
+	if field == nil {
+		return 0, fieldNotFoundError(fieldName)
+	}
+	rawMin, rawMax, ok := field.rawRangeAtLeast(value)
+	if !ok {
+		return 0, nil
+	}
+
+	hidden, err := mfs.hiddenMembers(ctx)
+	if err != nil {
+		return 0, mfs.runOnError(ctx, "CountByFieldAtLeast", "", err)
+	}
+
+	var count, hiddenCount int64
+	switch {
+	case field.leading:
+		lo, hi := field.zscoreBounds(rawMin, rawMax)
+		err = mfs.read(ctx, func(client redis.UniversalClient) error {
+			count, err = client.ZCount(ctx, mfs.key, lo.String(), hi.String()).Result()
+			return err
+		})
+	case mfs.maintainFieldIndexes:
+		err = mfs.read(ctx, func(client redis.UniversalClient) error {
+			count, err = client.ZCount(ctx, mfs.fieldIndexKey(field), rawMin.String(), rawMax.String()).Result()
+			return err
+		})
+	default:
+		err = mfs.scanEntries(ctx, func(z redis.Z, zscore *big.Int) bool {
+			raw := mfs.extractFieldScore(field, zscore)
+			if !hidden[memberName(z)] && raw.Cmp(rawMin) >= 0 && raw.Cmp(rawMax) <= 0 {
+				count++
+			}
+			return true
+		})
+	}
+	if err == nil && (field.leading || mfs.maintainFieldIndexes) {
+		hiddenCount, err = mfs.countHiddenInRange(ctx, hidden, field, rawMin, rawMax)
+	}
+	if err != nil {
+		return 0, mfs.runOnError(ctx, "CountByFieldAtLeast", "", err)
+	}
+	return count - hiddenCount, nil
+}
+
+// RemoveByFieldBelow removes every member whose value for the given field is below value, along
+// with its companion data as RemoveMember does, and returns the number of members removed. Members
+// are selected first and then removed in batches, so a member updated in between is removed based
+// on its earlier value.
+func (mfs *MultiFieldSet) RemoveByFieldBelow(ctx context.Context, fieldName string, value float64) (int64, error) {
+	field := mfs.GetFieldByName(fieldName)
+	if field == nil {
+		return 0, mfs.runOnError(ctx, "RemoveByFieldBelow", "", fieldNotFoundError(fieldName))
+	}
+	rawMin, rawMax, ok := field.rawRangeBelow(value)
+	if !ok {
+		return 0, nil
+	}
+
+	var members []string
+	var err error
+	switch {
+	case field.leading:
+		lo, hi := field.zscoreBounds(rawMin, rawMax)
+		err = mfs.primary(ctx, func(client redis.UniversalClient) error {
+			members, err = client.ZRangeByScore(ctx, mfs.key, &redis.ZRangeBy{Min: lo.String(), Max: hi.String()}).Result()
+			return err
+		})
+	case mfs.maintainFieldIndexes:
+		err = mfs.primary(ctx, func(client redis.UniversalClient) error {
+			members, err = client.ZRangeByScore(ctx, mfs.fieldIndexKey(field), &redis.ZRangeBy{Min: rawMin.String(), Max: rawMax.String()}).Result()
+			return err
+		})
+	default:
+		err = mfs.scanEntries(ctx, func(z redis.Z, zscore *big.Int) bool {
+			raw := mfs.extractFieldScore(field, zscore)
+			if raw.Cmp(rawMin) >= 0 && raw.Cmp(rawMax) <= 0 {
+				members = append(members, memberName(z))
+			}
+			return true
+		})
+	}
+	if err != nil {
+		return 0, mfs.runOnError(ctx, "RemoveByFieldBelow", "", err)
+	}
+
+	var removed int64
+	for start := 0; start < len(members); start += scanBatchSize {
+		end := start + scanBatchSize
+		if end > len(members) {
+			end = len(members)
+		}
+		n, err := mfs.RemoveMember(ctx, members[start:end]...)
+		if err != nil {
+			return removed, err
+		}
+		removed += n
+	}
+	return removed, nil
+}
@@ -0,0 +1,78 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCountAndRemoveByField(t *testing.T) {
+	for _, indexes := range []bool{false, true} {
+		mfs := newTestSetWithOptions(t, MultiFieldSetOptions{MaintainFieldIndexes: indexes})
+		ctx := context.Background()
+
+		players := map[string][2]float64{
+			"alice": {50, 1},
+			"bob":   {20, 5},
+			"carol": {10, 9},
+			"dave":  {0, 3},
+		}
+		for member, s := range players {
+			if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": s[0], "deaths": s[1]}, member); err != nil {
+				t.Fatalf("IncreaseScore() error = %v", err)
+			}
+		}
+
+		// points is the leading, descending field and deaths an ascending non-leading field
+		counts := []struct {
+			field    string
+			value    float64
+			expected int64
+		}{
+			{"points", 20, 2},
+			{"points", 0, 4},
+			{"points", 2000, 0},
+			{"deaths", 3, 3},
+			{"deaths", 9.5, 0},
+		}
+		for _, c := range counts {
+			count, err := mfs.CountByFieldAtLeast(ctx, c.field, c.value)
+			if err != nil {
+				t.Fatalf("CountByFieldAtLeast() error = %v", err)
+			}
+			if count != c.expected {
+				t.Errorf("indexes=%v: CountByFieldAtLeast(%s, %v) = %d, expected %d", indexes, c.field, c.value, count, c.expected)
+			}
+		}
+
+		removed, err := mfs.RemoveByFieldBelow(ctx, "points", 15)
+		if err != nil {
+			t.Fatalf("RemoveByFieldBelow() error = %v", err)
+		}
+		if removed != 2 {
+			t.Errorf("indexes=%v: RemoveByFieldBelow(points, 15) = %d, expected 2", indexes, removed)
+		}
+		removed, err = mfs.RemoveByFieldBelow(ctx, "deaths", 5)
+		if err != nil {
+			t.Fatalf("RemoveByFieldBelow() error = %v", err)
+		}
+		if removed != 1 {
+			t.Errorf("indexes=%v: RemoveByFieldBelow(deaths, 5) = %d, expected 1", indexes, removed)
+		}
+
+		members, err := mfs.GetTopMembers(ctx, 10)
+		if err != nil {
+			t.Fatalf("GetTopMembers() error = %v", err)
+		}
+		if len(members) != 1 || members[0].Member != "bob" {
+			t.Errorf("indexes=%v: remaining members = %+v, expected bob", indexes, members)
+		}
+	}
+}
+
+func TestCountByFieldAtLeast_UnknownField(t *testing.T) {
+	mfs := newTestSet(t)
+	if _, err := mfs.CountByFieldAtLeast(context.Background(), "kills", 1); !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("CountByFieldAtLeast() error = %v, expected %v", err, ErrFieldNotFound)
+	}
+}
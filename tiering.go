@@ -0,0 +1,365 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Tier is a bracket of a ranked ladder, such as Gold, see MultiFieldSetOptions.Tiers. A tier is
+// defined by TopPercent, by Field and Min, or by neither for a catch-all bottom tier.
+type Tier struct {
+	Name string
+	// TopPercent places the best TopPercent percent of the members in the tier, as of the last
+	// ComputeTiers. Percentages count from the top of the set, so they grow down the ladder: 1 for
+	// Gold and 10 for Silver put the next 9% in Silver.
+	TopPercent float64
+	// Field and Min place the members whose value for Field is at least Min in the tier.
+	Field string
+	Min   float64
+}
+
+// TierBoundary describes where ComputeTiers placed the cutoff of a percentile tier.
+type TierBoundary struct {
+	Tier string
+	// Members is the number of members ranked high enough for the tier.
+	Members int64
+	// Scores are the scores of the worst member in the tier, e.g. to show what it takes to reach
+	// it, or nil if the set is too small for the tier to have members.
+	Scores []FieldScore
+}
+
+// TierChangedEvent describes a member moving to another tier, either through an update or because
+// ComputeTiers moved the cutoffs. An empty tier name means the member is in no tier.
+type TierChangedEvent struct {
+	Set     string
+	Member  string
+	OldTier string
+	NewTier string
+}
+
+// EventType implements Event.
+func (e TierChangedEvent) EventType() string { return EventTypeTierChange }
+
+// EventKey implements Event.
+func (e TierChangedEvent) EventKey() string { return e.Member }
+
+// validateTiers checks that tiers have unique names, a single valid definition each and that only
+// the last one is a catch-all.
+func validateTiers(codec *Codec, tiers []Tier) error {
+	seen := make(map[string]bool, len(tiers))
+	for i, tier := range tiers {
+		if tier.Name == "" {
+			return errors.New("tier name is required")
+		}
+		if seen[tier.Name] {
+			return fmt.Errorf("duplicate tier %s", tier.Name)
+		}
+		seen[tier.Name] = true
+		switch {
+		case tier.TopPercent != 0 && tier.Field != "":
+			return fmt.Errorf("tier %s sets both TopPercent and Field", tier.Name)
+		case tier.TopPercent < 0 || tier.TopPercent > 100 || math.IsNaN(tier.TopPercent):
+			return fmt.Errorf("tier %s has TopPercent %v outside (0, 100]", tier.Name, tier.TopPercent)
+		case tier.Field != "" && codec.GetFieldByName(tier.Field) == nil:
+			return fmt.Errorf("tier %s: %w", tier.Name, fieldNotFoundError(tier.Field))
+		case tier.TopPercent == 0 && tier.Field == "" && i != len(tiers)-1:
+			return fmt.Errorf("catch-all tier %s must be the last tier", tier.Name)
+		}
+	}
+	return nil
+}
+
+// tiersKey returns the key of the hash holding the zscore cutoffs of the percentile tiers.
+func (mfs *MultiFieldSet) tiersKey() string {
+	return mfs.derivedKey("tiers")
+}
+
+// tierCutoffs are the zscores of the worst members of the percentile tiers. A tier without a cutoff
+// has no members.
+type tierCutoffs struct {
+	computed bool
+	cutoffs  map[string]*big.Int
+}
+
+// tierCutoffField returns the field of the tiers hash holding a tier's cutoff.
+func tierCutoffField(tier string) string {
+	return "tier:" + tier
+}
+
+// parseTierCutoffs reads the cutoffs from the fields of the tiers hash.
+func parseTierCutoffs(fields map[string]string) tierCutoffs {
+	c := tierCutoffs{computed: fields["computed_at"] != "", cutoffs: make(map[string]*big.Int)}
+	for name, value := range fields {
+		tier, ok := strings.CutPrefix(name, "tier:")
+		if !ok {
+			continue
+		}
+		if cutoff, ok := new(big.Int).SetString(value, 10); ok {
+			c.cutoffs[tier] = cutoff
+		}
+	}
+	return c
+}
+
+// loadTierCutoffs reads the cutoffs stored by the last ComputeTiers from the primary.
+func (mfs *MultiFieldSet) loadTierCutoffs(ctx context.Context) (tierCutoffs, error) {
+	var fields map[string]string
+	err := mfs.primary(ctx, func(client redis.UniversalClient) error {
+		var err error
+		fields, err = client.HGetAll(ctx, mfs.tiersKey()).Result()
+		return err
+	})
+	if err != nil {
+		return tierCutoffs{}, err
+	}
+	return parseTierCutoffs(fields), nil
+}
+
+// tierOf returns the name of the first tier a member with zscore belongs to, or an empty string.
+func (mfs *MultiFieldSet) tierOf(zscore *big.Int, c tierCutoffs) string {
+	for _, tier := range mfs.tiers {
+		switch {
+		case tier.TopPercent > 0:
+			if cutoff, ok := c.cutoffs[tier.Name]; ok && zscore.Cmp(cutoff) <= 0 {
+				return tier.Name
+			}
+		case tier.Field != "":
+			field := mfs.GetFieldByName(tier.Field)
+			value := new(big.Float).SetInt(field.toDisplay(mfs.extractFieldScore(field, zscore)))
+			if value.Cmp(big.NewFloat(tier.Min)) >= 0 {
+				return tier.Name
+			}
+		default:
+			return tier.Name
+		}
+	}
+	return ""
+}
+
+// GetTier returns the name of the tier a member is in, or an empty string if it is in no tier.
+// Percentile tiers use the cutoffs of the last ComputeTiers, so no member is in them before it
+// first runs. It fails with ErrMemberNotFound if the member isn't in the set and requires Tiers.
+func (mfs *MultiFieldSet) GetTier(ctx context.Context, member string) (_ string, err error) {
+	defer mfs.observeRead("GetTier", time.Now(), &err)
+
+	if len(mfs.tiers) == 0 {
+		return "", mfs.runOnError(ctx, "GetTier", member, ErrNoTiers)
+	}
+	var scoreCmd *redis.FloatCmd
+	var cutoffsCmd *redis.StringStringMapCmd
+	err = mfs.read(ctx, func(client redis.UniversalClient) error {
+		_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			scoreCmd = pipe.ZScore(ctx, mfs.key, member)
+			cutoffsCmd = pipe.HGetAll(ctx, mfs.tiersKey())
+			return nil
+		})
+		return err
+	})
+	if err == redis.Nil {
+		return "", mfs.runOnError(ctx, "GetTier", member, ErrMemberNotFound)
+	} else if err != nil {
+		return "", mfs.runOnError(ctx, "GetTier", member, err)
+	}
+	zscore, err := mfs.zscoreOf(member, scoreCmd.Val())
+	if err != nil {
+		return "", mfs.runOnError(ctx, "GetTier", member, err)
+	}
+	return mfs.tierOf(zscore, parseTierCutoffs(cutoffsCmd.Val())), nil
+}
+
+// ComputeTiers places the cutoffs of the percentile tiers at the members currently ranked at their
+// percentages and stores them for GetTier and updates in every process, e.g. every few minutes
+// with TierTask. Members moved to another tier by the new cutoffs get a TierChangedEvent, except
+// on the first run, when they had no tier to move from. It runs under the set's administrative
+// lock, so concurrent runs fail with a LockedError, and requires Tiers.
+func (mfs *MultiFieldSet) ComputeTiers(ctx context.Context) ([]TierBoundary, error) {
+	if len(mfs.tiers) == 0 {
+		return nil, mfs.runOnError(ctx, "ComputeTiers", "", ErrNoTiers)
+	}
+	var boundaries []TierBoundary
+	err := mfs.withLock(ctx, "ComputeTiers", func(ctx context.Context) error {
+		var err error
+		boundaries, err = mfs.computeTiers(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "ComputeTiers", "", err)
+	}
+	return boundaries, nil
+}
+
+// computeTiers implements ComputeTiers while holding the lock.
+func (mfs *MultiFieldSet) computeTiers(ctx context.Context) ([]TierBoundary, error) {
+	old, err := mfs.loadTierCutoffs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var cardinality int64
+	err = mfs.primary(ctx, func(client redis.UniversalClient) error {
+		cardinality, err = client.ZCard(ctx, mfs.key).Result()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var boundaries []TierBoundary
+	var cmds []*redis.ZSliceCmd
+	err = mfs.primary(ctx, func(client redis.UniversalClient) error {
+		_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			boundaries, cmds = boundaries[:0], cmds[:0]
+			for _, tier := range mfs.tiers {
+				if tier.TopPercent == 0 {
+					continue
+				}
+				members := int64(math.Ceil(float64(cardinality) * tier.TopPercent / 100))
+				boundaries = append(boundaries, TierBoundary{Tier: tier.Name, Members: members})
+				var cmd *redis.ZSliceCmd
+				if members > 0 {
+					cmd = pipe.ZRangeWithScores(ctx, mfs.key, members-1, members-1)
+				}
+				cmds = append(cmds, cmd)
+			}
+			return nil
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	current := tierCutoffs{computed: true, cutoffs: make(map[string]*big.Int)}
+	fields := []interface{}{"computed_at", time.Now().UnixMilli()}
+	for i := range boundaries {
+		if cmds[i] == nil || len(cmds[i].Val()) == 0 {
+			continue
+		}
+		results := cmds[i].Val()
+		cutoff, err := mfs.zscoreOf(memberName(results[0]), results[0].Score)
+		if err != nil {
+			return nil, err
+		}
+		current.cutoffs[boundaries[i].Tier] = cutoff
+		boundaries[i].Scores = mfs.withComputed(mfs.zscoreToAllFieldScores(cutoff))
+		fields = append(fields, tierCutoffField(boundaries[i].Tier), cutoff.String())
+	}
+
+	err = mfs.write(ctx, func(client redis.UniversalClient) error {
+		return mfs.txPipelined(ctx, client, func(pipe redis.Pipeliner) error {
+			pipe.Del(ctx, mfs.tiersKey())
+			pipe.HSet(ctx, mfs.tiersKey(), fields...)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if old.computed {
+		if err := mfs.emitMovedTiers(ctx, old, current); err != nil {
+			return nil, err
+		}
+	}
+	return boundaries, nil
+}
+
+// emitMovedTiers emits a TierChangedEvent for every member whose tier differs between the old and
+// the current cutoffs. Only the members between a tier's old and current cutoff can have moved.
+func (mfs *MultiFieldSet) emitMovedTiers(ctx context.Context, old, current tierCutoffs) error {
+	if !mfs.watchesTiers() {
+		return nil
+	}
+	seen := make(map[string]bool)
+	for _, tier := range mfs.tiers {
+		from, to := old.cutoffs[tier.Name], current.cutoffs[tier.Name]
+		if from == nil && to == nil || from != nil && to != nil && from.Cmp(to) == 0 {
+			continue
+		}
+		min, max := "-inf", ""
+		switch {
+		case from == nil:
+			max = to.String()
+		case to == nil:
+			max = from.String()
+		case from.Cmp(to) < 0:
+			min, max = "("+from.String(), to.String()
+		default:
+			min, max = "("+to.String(), from.String()
+		}
+
+		var results []redis.Z
+		err := mfs.primary(ctx, func(client redis.UniversalClient) error {
+			var err error
+			results, err = client.ZRangeByScoreWithScores(ctx, mfs.key, &redis.ZRangeBy{Min: min, Max: max}).Result()
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		for _, z := range results {
+			member := memberName(z)
+			if seen[member] {
+				continue
+			}
+			seen[member] = true
+			zscore, err := mfs.zscoreOf(member, z.Score)
+			if err != nil {
+				return err
+			}
+			mfs.runTierChanged(ctx, member, mfs.tierOf(zscore, old), mfs.tierOf(zscore, current))
+		}
+	}
+	return nil
+}
+
+// updateTier emits a TierChangedEvent if an update moved the member to another tier. It reads the
+// current cutoffs, reporting a failure to the error hooks.
+func (mfs *MultiFieldSet) updateTier(ctx context.Context, event *UpdateEvent, result *UpdateResult) {
+	if len(mfs.tiers) == 0 || !mfs.watchesTiers() {
+		return
+	}
+	c, err := mfs.loadTierCutoffs(ctx)
+	if err != nil {
+		mfs.runOnError(ctx, "UpdateTier", event.Member, err)
+		return
+	}
+	oldTier := ""
+	if result.OldRank >= 0 {
+		raws, err := mfs.displayScoresToRaw(event.OldScores)
+		if err != nil {
+			mfs.runOnError(ctx, "UpdateTier", event.Member, err)
+			return
+		}
+		oldTier = mfs.tierOf(mfs.scoresToZScore(raws), c)
+	}
+	mfs.runTierChanged(ctx, event.Member, oldTier, mfs.tierOf(result.ZScore, c))
+}
+
+// runTierChanged runs the tier-changed hooks and emits a TierChangedEvent if the tiers differ.
+func (mfs *MultiFieldSet) runTierChanged(ctx context.Context, member, oldTier, newTier string) {
+	if oldTier == newTier {
+		return
+	}
+	event := TierChangedEvent{Set: mfs.name, Member: member, OldTier: oldTier, NewTier: newTier}
+	mfs.hooks.mu.RLock()
+	for _, hook := range mfs.hooks.tierChanged {
+		hook(ctx, event)
+	}
+	mfs.hooks.mu.RUnlock()
+	mfs.emit(ctx, member, event)
+}
+
+// watchesTiers reports whether tier changes have a hook or an event sink to go to.
+func (mfs *MultiFieldSet) watchesTiers() bool {
+	mfs.hooks.mu.RLock()
+	defer mfs.hooks.mu.RUnlock()
+	return len(mfs.hooks.tierChanged) > 0 || mfs.eventSink != nil
+}
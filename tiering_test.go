@@ -0,0 +1,139 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestTiers(t *testing.T) {
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{Tiers: []Tier{
+		{Name: "Gold", TopPercent: 10},
+		{Name: "Silver", TopPercent: 50},
+		{Name: "Bronze"},
+	}})
+	ctx := context.Background()
+	var changes []TierChangedEvent
+	mfs.OnTierChanged(func(ctx context.Context, event TierChangedEvent) {
+		changes = append(changes, event)
+	})
+	for i := 1; i <= 10; i++ {
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": float64(10 * i)}, fmt.Sprintf("m%d", i)); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+	}
+	// Members entering the set enter the catch-all tier
+	if len(changes) != 10 || changes[0] != (TierChangedEvent{Set: "test", Member: "m1", NewTier: "Bronze"}) {
+		t.Errorf("updates emitted %v, expected every member to enter Bronze", changes)
+	}
+	changes = nil
+	// Before the first computation percentile tiers are empty
+	if tier, err := mfs.GetTier(ctx, "m10"); err != nil || tier != "Bronze" {
+		t.Errorf("GetTier(m10) = %q, %v before ComputeTiers, expected Bronze", tier, err)
+	}
+
+	boundaries, err := mfs.ComputeTiers(ctx)
+	if err != nil {
+		t.Fatalf("ComputeTiers() error = %v", err)
+	}
+	if len(boundaries) != 2 || boundaries[0].Members != 1 || boundaries[1].Members != 5 || FieldValue(boundaries[1].Scores, "points") != 60 {
+		t.Errorf("ComputeTiers() = %+v, expected Gold for 1 member and Silver for 5 down to 60 points", boundaries)
+	}
+	for member, want := range map[string]string{"m10": "Gold", "m6": "Silver", "m5": "Bronze"} {
+		if tier, err := mfs.GetTier(ctx, member); err != nil || tier != want {
+			t.Errorf("GetTier(%s) = %q, %v, expected %s", member, tier, err, want)
+		}
+	}
+	if len(changes) != 0 {
+		t.Errorf("first ComputeTiers emitted %v, expected no changes", changes)
+	}
+
+	// An update moves m1 past the Gold cutoff right away
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 200}, "m1"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	if want := []TierChangedEvent{{Set: "test", Member: "m1", OldTier: "Bronze", NewTier: "Gold"}}; !reflect.DeepEqual(changes, want) {
+		t.Errorf("update emitted %v, expected %v", changes, want)
+	}
+
+	// Recomputing pushes the previous leader to Silver and the last of Silver out
+	changes = nil
+	if _, err := mfs.ComputeTiers(ctx); err != nil {
+		t.Fatalf("ComputeTiers() error = %v", err)
+	}
+	want := []TierChangedEvent{
+		{Set: "test", Member: "m10", OldTier: "Gold", NewTier: "Silver"},
+		{Set: "test", Member: "m6", OldTier: "Silver", NewTier: "Bronze"},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("ComputeTiers emitted %v, expected %v", changes, want)
+	}
+	for _, w := range want {
+		found := false
+		for _, c := range changes {
+			found = found || c == w
+		}
+		if !found {
+			t.Errorf("ComputeTiers emitted %v, expected %v among them", changes, w)
+		}
+	}
+
+	if _, err := mfs.GetTier(ctx, "nobody"); !errors.Is(err, ErrMemberNotFound) {
+		t.Errorf("GetTier() of a missing member error = %v, expected ErrMemberNotFound", err)
+	}
+}
+
+func TestTiers_FieldThresholds(t *testing.T) {
+	sink := &recordingSink{}
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{EventSink: sink, Tiers: []Tier{
+		{Name: "Veteran", Field: "points", Min: 500},
+		{Name: "Regular", Field: "points", Min: 100},
+	}})
+	ctx := context.Background()
+
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 50}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	if tier, err := mfs.GetTier(ctx, "alice"); err != nil || tier != "" {
+		t.Errorf("GetTier(alice) = %q, %v, expected no tier", tier, err)
+	}
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 450}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	if tier, err := mfs.GetTier(ctx, "alice"); err != nil || tier != "Veteran" {
+		t.Errorf("GetTier(alice) = %q, %v, expected Veteran", tier, err)
+	}
+	var changes []Event
+	for _, e := range sink.events {
+		if e.EventType() == EventTypeTierChange {
+			changes = append(changes, e)
+		}
+	}
+	if want := []Event{TierChangedEvent{Set: "test", Member: "alice", OldTier: "", NewTier: "Veteran"}}; !reflect.DeepEqual(changes, want) {
+		t.Errorf("sink got tier changes %v, expected %v", changes, want)
+	}
+}
+
+func TestTiers_Validation(t *testing.T) {
+	client, _ := newTestClient(t)
+	fields := []Field{{Name: "points", Sort: Descending, MaxValue: 1000}}
+	for name, tiers := range map[string][]Tier{
+		"unnamed":          {{TopPercent: 1}},
+		"duplicate":        {{Name: "Gold", TopPercent: 1}, {Name: "Gold", TopPercent: 5}},
+		"both definitions": {{Name: "Gold", TopPercent: 1, Field: "points"}},
+		"percent too high": {{Name: "Gold", TopPercent: 150}},
+		"unknown field":    {{Name: "Gold", Field: "kills", Min: 5}},
+		"early catch-all":  {{Name: "Bronze"}, {Name: "Gold", TopPercent: 1}},
+	} {
+		if _, err := New(MultiFieldSetOptions{Name: "test", Fields: fields, Client: client, Tiers: tiers}); err == nil {
+			t.Errorf("New() with %s tiers succeeded", name)
+		}
+	}
+
+	mfs := newTestSet(t)
+	if _, err := mfs.GetTier(context.Background(), "alice"); !errors.Is(err, ErrNoTiers) {
+		t.Errorf("GetTier() without tiers error = %v, expected ErrNoTiers", err)
+	}
+}
@@ -0,0 +1,203 @@
+package zmultifield
+
+import (
+	"container/heap"
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// GetTopMembersByField returns the top members ordered by a single field instead of the composite
+// ordering. "Top" follows the field's sort order, so descending fields return the highest values
+// first and ascending fields the lowest. Ties keep the composite ordering.
+//
+// When MaintainFieldIndexes is enabled the members are read from the field's index and ties are
+// ordered by member name. Otherwise the set is scanned in batches and the best limit members are
+// kept in a bounded heap, so memory use is proportional to limit rather than to the size of the set.
+// Hidden members are skipped.
+func (mfs *MultiFieldSet) GetTopMembersByField(ctx context.Context, fieldName string, limit int64) (_ []MemberScores, err error) {
+	defer mfs.observeRead("GetTopMembersByField", time.Now(), &err)
+
+	field := mfs.GetFieldByName(fieldName)
+	if field == nil {
+		return nil, fieldNotFoundError(fieldName)
+	}
+	if limit <= 0 {
+		return []MemberScores{}, nil
+	}
+
+	hidden, err := mfs.hiddenMembers(ctx)
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "GetTopMembersByField", "", err)
+	}
+	if mfs.maintainFieldIndexes {
+		// Read enough members to make up for the hidden ones
+		members, err := mfs.getMembersFromIndex(ctx, field, "-inf", "+inf", limit+int64(len(hidden)), 0)
+		if err != nil {
+			return nil, mfs.runOnError(ctx, "GetTopMembersByField", "", err)
+		}
+		visible := members[:0]
+		for _, m := range members {
+			if !hidden[m.Member] && int64(len(visible)) < limit {
+				visible = append(visible, m)
+			}
+		}
+		return visible, nil
+	}
+
+	top := &fieldHeap{}
+	err = mfs.scanEntries(ctx, func(z redis.Z, zscore *big.Int) bool {
+		if !hidden[memberName(z)] {
+			top.offer(fieldHeapEntry{z: z, raw: mfs.extractFieldScore(field, zscore)}, limit)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "GetTopMembersByField", "", err)
+	}
+	members, err := mfs.decodeMembers(top.sorted())
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "GetTopMembersByField", "", err)
+	}
+	return members, nil
+}
+
+// fieldHeapEntry is a set entry ranked by the raw value of a single field.
+type fieldHeapEntry struct {
+	z   redis.Z
+	raw *big.Int
+}
+
+// less reports whether e ranks above other: a smaller raw value wins, ties keep the composite
+// order of zscore and member.
+func (e fieldHeapEntry) less(other fieldHeapEntry) bool {
+	if c := e.raw.Cmp(other.raw); c != 0 {
+		return c < 0
+	}
+	if e.z.Score != other.z.Score {
+		return e.z.Score < other.z.Score
+	}
+	return memberName(e.z) < memberName(other.z)
+}
+
+// fieldHeap is a max-heap of fieldHeapEntry whose root is the worst ranked entry kept so far.
+type fieldHeap []fieldHeapEntry
+
+func (h fieldHeap) Len() int            { return len(h) }
+func (h fieldHeap) Less(i, j int) bool  { return h[j].less(h[i]) }
+func (h fieldHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *fieldHeap) Push(x interface{}) { *h = append(*h, x.(fieldHeapEntry)) }
+func (h *fieldHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// offer adds entry if fewer than limit entries are kept or it ranks above the worst of them,
+// which it then replaces.
+func (h *fieldHeap) offer(entry fieldHeapEntry, limit int64) {
+	if int64(h.Len()) < limit {
+		heap.Push(h, entry)
+	} else if entry.less((*h)[0]) {
+		(*h)[0] = entry
+		heap.Fix(h, 0)
+	}
+}
+
+// sorted empties the heap and returns its entries, best first.
+func (h *fieldHeap) sorted() []redis.Z {
+	results := make([]redis.Z, h.Len())
+	for i := len(results) - 1; i >= 0; i-- {
+		results[i] = heap.Pop(h).(fieldHeapEntry).z
+	}
+	return results
+}
+
+// TopK returns the top k members by a single field like GetTopMembersByField, e.g. the 100
+// members with the fewest deaths, but streams the set with ZSCAN instead of reading it by rank,
+// even if field indexes are maintained. Unlike a walk by rank, ZSCAN isn't thrown off by members
+// moving during the scan: every member present for its whole duration is seen, with the score it
+// held at some point of it. Memory use is proportional to k. Hidden members are skipped.
+func (mfs *MultiFieldSet) TopK(ctx context.Context, k int64, byField string) (_ []MemberScores, err error) {
+	defer mfs.observeRead("TopK", time.Now(), &err)
+
+	field := mfs.GetFieldByName(byField)
+	if field == nil {
+		return nil, fieldNotFoundError(byField)
+	}
+	if k <= 0 {
+		return []MemberScores{}, nil
+	}
+
+	hidden, err := mfs.hiddenMembers(ctx)
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "TopK", "", err)
+	}
+
+	top := &fieldHeap{}
+	kept := make(map[string]bool)
+	var cursor uint64
+	zscore := new(big.Int)
+	for {
+		var pairs []string
+		err = mfs.read(ctx, func(client redis.UniversalClient) error {
+			pairs, cursor, err = client.ZScan(ctx, mfs.key, cursor, "", scanBatchSize).Result()
+			return err
+		})
+		if err != nil {
+			return nil, mfs.runOnError(ctx, "TopK", "", err)
+		}
+		entries, err := parseZSlice(pairs)
+		if err != nil {
+			return nil, mfs.runOnError(ctx, "TopK", "", err)
+		}
+		for _, z := range entries {
+			member := memberName(z)
+			if hidden[member] {
+				continue
+			}
+			if err := mfs.checkZScore(member, z.Score); err != nil {
+				return nil, mfs.runOnError(ctx, "TopK", member, err)
+			}
+			entry := fieldHeapEntry{z: z, raw: mfs.extractFieldScore(field, zscore.SetInt64(int64(z.Score)))}
+			// ZSCAN may return a member more than once, keep only its latest entry
+			if kept[member] {
+				i := top.index(member)
+				(*top)[i] = entry
+				heap.Fix(top, i)
+				continue
+			}
+			if int64(top.Len()) < k {
+				heap.Push(top, entry)
+				kept[member] = true
+			} else if entry.less((*top)[0]) {
+				delete(kept, memberName((*top)[0].z))
+				(*top)[0] = entry
+				heap.Fix(top, 0)
+				kept[member] = true
+			}
+		}
+		if cursor == 0 {
+			break
+		}
+	}
+	members, err := mfs.decodeMembers(top.sorted())
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "TopK", "", err)
+	}
+	return members, nil
+}
+
+// index returns the position of member in the heap, or -1 if it isn't in it.
+func (h fieldHeap) index(member string) int {
+	for i, entry := range h {
+		if memberName(entry.z) == member {
+			return i
+		}
+	}
+	return -1
+}
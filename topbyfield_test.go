@@ -0,0 +1,97 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestGetTopMembersByField(t *testing.T) {
+	mfs := newTestSet(t)
+	ctx := context.Background()
+
+	players := map[string]map[string]float64{
+		"alice": {"points": 100, "deaths": 5},
+		"bob":   {"points": 50, "deaths": 1},
+		"carol": {"points": 75, "deaths": 9},
+		"dave":  {"points": 10, "deaths": 1},
+	}
+	for member, fields := range players {
+		if _, err := mfs.IncreaseScore(ctx, fields, member); err != nil {
+			t.Fatalf("IncreaseScore(%s) error = %v", member, err)
+		}
+	}
+
+	tests := []struct {
+		field    string
+		limit    int64
+		expected []string
+	}{
+		{"points", 2, []string{"alice", "carol"}},
+		{"deaths", 3, []string{"bob", "dave", "alice"}},
+		{"deaths", 10, []string{"bob", "dave", "alice", "carol"}},
+		{"deaths", 0, []string{}},
+	}
+
+	for _, test := range tests {
+		members, err := mfs.GetTopMembersByField(ctx, test.field, test.limit)
+		if err != nil {
+			t.Fatalf("GetTopMembersByField(%s) error = %v", test.field, err)
+		}
+		if len(members) != len(test.expected) {
+			t.Errorf("GetTopMembersByField(%s, %d) returned %d members, expected %d", test.field, test.limit, len(members), len(test.expected))
+			continue
+		}
+		for i, m := range members {
+			if m.Member != test.expected[i] {
+				t.Errorf("GetTopMembersByField(%s, %d)[%d] = %s, expected %s", test.field, test.limit, i, m.Member, test.expected[i])
+			}
+		}
+	}
+}
+
+func TestTopK(t *testing.T) {
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{HideMembers: true})
+	ctx := context.Background()
+
+	for i := 0; i < 300; i++ {
+		fields := map[string]float64{"points": float64(i * 7 % 1000), "deaths": float64(i % 50)}
+		if _, err := mfs.IncreaseScore(ctx, fields, fmt.Sprintf("player%03d", i)); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+	}
+
+	for _, field := range []string{"points", "deaths"} {
+		expected, err := mfs.GetTopMembersByField(ctx, field, 20)
+		if err != nil {
+			t.Fatalf("GetTopMembersByField() error = %v", err)
+		}
+		got, err := mfs.TopK(ctx, 20, field)
+		if err != nil {
+			t.Fatalf("TopK() error = %v", err)
+		}
+		if len(got) != len(expected) {
+			t.Fatalf("TopK(%s) returned %d members, expected %d", field, len(got), len(expected))
+		}
+		for i := range got {
+			if got[i].Member != expected[i].Member {
+				t.Errorf("TopK(%s)[%d] = %s, expected %s", field, i, got[i].Member, expected[i].Member)
+			}
+		}
+	}
+
+	best, err := mfs.TopK(ctx, 1, "deaths")
+	if err != nil {
+		t.Fatalf("TopK() error = %v", err)
+	}
+	if err := mfs.Hide(ctx, best[0].Member); err != nil {
+		t.Fatalf("Hide() error = %v", err)
+	}
+	if next, err := mfs.TopK(ctx, 1, "deaths"); err != nil || next[0].Member == best[0].Member {
+		t.Errorf("TopK() = %v, %v, expected hidden member %s to be skipped", next, err, best[0].Member)
+	}
+	if _, err := mfs.TopK(ctx, 1, "missing"); !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("TopK() error = %v, expected ErrFieldNotFound", err)
+	}
+}
@@ -0,0 +1,184 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// transferScript moves field values from one member to another in one atomic step. Like the merge
+// script it decodes with arithmetic, which is exact below 2^53. Both members are checked before
+// anything is written: a value that would leave its field's range aborts the transfer with an
+// "OUTOFRANGE <position> <raw value>" error, and a missing member with "MISSING <member>".
+//
+// KEYS[1] is the main set and KEYS[2..n] the field indexes, if maintained, in field order. ARGV[1]
+// and ARGV[2] are the source and destination members, ARGV[3] the zscore used for a destination
+// that doesn't exist and ARGV[4] is 1 if the destination must already exist. They are followed by
+// six values per field: 2^shift, 2^bits, the maximum raw value, the raw amount added to the
+// destination and subtracted from the source, and the raw values written to the source and to the
+// destination instead, or empty strings. The script returns the new zscores of the source and the
+// destination.
+var transferScript = newWriteScript(`
+local function apply(member, sign, replacement)
+	local zscore = tonumber(redis.call('ZSCORE', KEYS[1], member) or ARGV[3])
+	local raws = {}
+	for i = 5, #ARGV, 6 do
+		local base = tonumber(ARGV[i])
+		local raw = math.floor(zscore / base) % tonumber(ARGV[i + 1])
+		local new = raw + sign * tonumber(ARGV[i + 3])
+		if ARGV[i + replacement] ~= '' then
+			new = tonumber(ARGV[i + replacement])
+		end
+		if new < 0 or new > tonumber(ARGV[i + 2]) then
+			return nil, redis.error_reply('OUTOFRANGE ' .. ((i - 5) / 6) .. ' ' .. string.format('%.17g', new))
+		end
+		zscore = zscore + (new - raw) * base
+		raws[#raws + 1] = new
+	end
+	return {zscore = zscore, raws = raws}
+end
+
+if not redis.call('ZSCORE', KEYS[1], ARGV[1]) then
+	return redis.error_reply('MISSING ' .. ARGV[1])
+end
+if ARGV[4] == '1' and not redis.call('ZSCORE', KEYS[1], ARGV[2]) then
+	return redis.error_reply('MISSING ' .. ARGV[2])
+end
+
+local from, err = apply(ARGV[1], -1, 4)
+if not from then
+	return err
+end
+local to
+to, err = apply(ARGV[2], 1, 5)
+if not to then
+	return err
+end
+
+local result = {}
+for _, update in ipairs({{ARGV[1], from}, {ARGV[2], to}}) do
+	local encoded = string.format('%.17g', update[2].zscore)
+	redis.call('ZADD', KEYS[1], encoded, update[1])
+	for i = 2, #KEYS do
+		redis.call('ZADD', KEYS[i], string.format('%.17g', update[2].raws[i - 1]), update[1])
+	end
+	result[#result + 1] = encoded
+end
+return result
+`)
+
+// TransferScore atomically subtracts the given amounts from one member's fields and adds them to
+// another's, e.g. for clan point donations or account merges. Both members are range checked before
+// anything is written, so a transfer that would take the source below zero or the destination
+// above the field's maximum fails with a ScoreOutOfRangeError and changes nothing. The source must
+// be in the set; the destination is added with default scores unless UpdateOnlyExisting is set.
+// Only incremental fields can be transferred; masked fields such as the salt are restamped for each
+// member. It returns the new zscores of both members.
+func (mfs *MultiFieldSet) TransferScore(ctx context.Context, from, to string, fields map[string]float64) (fromZScore, toZScore *big.Int, err error) {
+	fromZScore, toZScore, err = mfs.transferScore(ctx, from, to, fields)
+	if err != nil {
+		return nil, nil, mfs.runOnError(ctx, "TransferScore", from, err)
+	}
+	return fromZScore, toZScore, nil
+}
+
+// transferScore validates the amounts and runs the transfer script.
+func (mfs *MultiFieldSet) transferScore(ctx context.Context, from, to string, fields map[string]float64) (*big.Int, *big.Int, error) {
+	if from == to {
+		return nil, nil, errors.New("cannot transfer a score to the same member")
+	}
+
+	amounts := make(map[string]*big.Int, len(fields))
+	for name, value := range fields {
+		field := mfs.GetFieldByName(name)
+		if field == nil {
+			return nil, nil, fieldNotFoundError(name)
+		}
+		if field.UpdateType != Incremental {
+			return nil, nil, fmt.Errorf("field %s is not incremental and can't be transferred", name)
+		}
+		amount, err := toInt64(name, value)
+		if err != nil {
+			return nil, nil, err
+		}
+		amounts[name] = new(big.Int).Mul(big.NewInt(amount), field.multiplier)
+	}
+
+	keys := []string{mfs.key}
+	mustExist := "0"
+	if mfs.updateOnlyExisting {
+		mustExist = "1"
+	}
+	args := []interface{}{from, to, mfs.defaultZScore.String(), mustExist}
+	for _, field := range mfs.fields {
+		if mfs.maintainFieldIndexes {
+			keys = append(keys, mfs.fieldIndexKey(field))
+		}
+		amount, fromRaw, toRaw := "0", "", ""
+		if a, ok := amounts[field.Name]; ok {
+			amount = a.String()
+		}
+		if field == mfs.updatedAt {
+			fromRaw = updatedAtRaw().String()
+			toRaw = fromRaw
+		}
+		if field == mfs.salt {
+			fromRaw, toRaw = mfs.saltRaw(from).String(), mfs.saltRaw(to).String()
+		}
+		args = append(args,
+			uint64(1)<<field.shiftValue,
+			uint64(1)<<field.bits,
+			field.maxAbsolute.String(),
+			amount,
+			fromRaw,
+			toRaw,
+		)
+	}
+
+	var encoded []string
+	err := mfs.writeOnce(ctx, func(client redis.UniversalClient) error {
+		var err error
+		encoded, err = transferScript.Run(ctx, client, mfs.writeGuard(), keys, args...).StringSlice()
+		return err
+	})
+	if err != nil {
+		return nil, nil, mfs.transferError(err)
+	}
+
+	zscores := make([]*big.Int, len(encoded))
+	for i, s := range encoded {
+		zscore, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, nil, err
+		}
+		zscores[i] = new(big.Int).SetInt64(int64(zscore))
+	}
+	return zscores[0], zscores[1], nil
+}
+
+// transferError converts MISSING and OUTOFRANGE replies from the transfer script into
+// ErrMemberNotFound and ScoreOutOfRangeError.
+func (mfs *MultiFieldSet) transferError(err error) error {
+	msg := err.Error()
+	if strings.HasPrefix(msg, "MISSING ") {
+		return ErrMemberNotFound
+	}
+	if !strings.HasPrefix(msg, "OUTOFRANGE ") {
+		return err
+	}
+	var position int
+	var value string
+	if _, scanErr := fmt.Sscanf(msg, "OUTOFRANGE %d %s", &position, &value); scanErr != nil {
+		return err
+	}
+	raw, ok := new(big.Int).SetString(value, 10)
+	if !ok || position < 0 || position >= len(mfs.fields) {
+		return err
+	}
+	return outOfRangeError(mfs.fields[position], raw)
+}
@@ -0,0 +1,73 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestTransferScore(t *testing.T) {
+	ctx := context.Background()
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{MaintainFieldIndexes: true})
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 100, "deaths": 5}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore failed: %v", err)
+	}
+
+	fromZScore, toZScore, err := mfs.TransferScore(ctx, "alice", "clan", map[string]float64{"points": 30})
+	if err != nil {
+		t.Fatalf("TransferScore failed: %v", err)
+	}
+
+	for member, expected := range map[string][2]int64{"alice": {70, 5}, "clan": {30, 0}} {
+		scores, err := mfs.GetScores(ctx, member)
+		if err != nil {
+			t.Fatalf("GetScores failed: %v", err)
+		}
+		if scores[0].Int64() != expected[0] || scores[1].Int64() != expected[1] {
+			t.Errorf("GetScores(%s) = %v, expected points %d and deaths %d", member, scores, expected[0], expected[1])
+		}
+	}
+	if values := mfs.Decode(fromZScore); values["points"] != 70 {
+		t.Errorf("source zscore decodes to %v, expected points 70", values)
+	}
+	if values := mfs.Decode(toZScore); values["points"] != 30 {
+		t.Errorf("destination zscore decodes to %v, expected points 30", values)
+	}
+	if rank, err := mfs.GetFieldRank(ctx, "points", "alice"); err != nil || rank != 0 {
+		t.Errorf("GetFieldRank(points, alice) = %d, %v, expected 0", rank, err)
+	}
+}
+
+func TestTransferScore_Errors(t *testing.T) {
+	ctx := context.Background()
+	mfs := newTestSet(t)
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 10}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore failed: %v", err)
+	}
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 1020}, "bob"); err != nil {
+		t.Fatalf("IncreaseScore failed: %v", err)
+	}
+
+	_, _, err := mfs.TransferScore(ctx, "alice", "bob", map[string]float64{"points": 20})
+	var rangeErr *ScoreOutOfRangeError
+	if !errors.As(err, &rangeErr) || rangeErr.Value.Int64() != -10 {
+		t.Errorf("TransferScore overdrawing the source = %v, expected points -10 out of range", err)
+	}
+	_, _, err = mfs.TransferScore(ctx, "alice", "bob", map[string]float64{"points": 5})
+	if !errors.As(err, &rangeErr) || rangeErr.Value.Int64() != 1025 {
+		t.Errorf("TransferScore overfilling the destination = %v, expected points 1025 out of range", err)
+	}
+	if scores, _ := mfs.GetScores(ctx, "alice"); scores[0].Int64() != 10 {
+		t.Errorf("alice's points after failed transfers = %v, expected 10", scores[0])
+	}
+
+	if _, _, err := mfs.TransferScore(ctx, "nobody", "alice", map[string]float64{"points": 1}); !errors.Is(err, ErrMemberNotFound) {
+		t.Errorf("TransferScore from a missing member = %v, expected ErrMemberNotFound", err)
+	}
+	if _, _, err := mfs.TransferScore(ctx, "alice", "bob", map[string]float64{"missing": 1}); !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("TransferScore with an unknown field = %v, expected ErrFieldNotFound", err)
+	}
+	if _, _, err := mfs.TransferScore(ctx, "alice", "alice", map[string]float64{"points": 1}); err == nil {
+		t.Error("TransferScore to the same member succeeded, expected an error")
+	}
+}
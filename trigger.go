@@ -0,0 +1,91 @@
+package zmultifield
+
+import (
+	"context"
+	"math/big"
+)
+
+// TriggerEvent describes an update that made a field reach a registered threshold.
+type TriggerEvent struct {
+	Set       string
+	Member    string
+	Field     string
+	Threshold *big.Int
+	Before    *big.Int
+	After     *big.Int
+}
+
+// TriggerFunc is called when an update makes a field reach a threshold registered with
+// RegisterTrigger.
+type TriggerFunc func(ctx context.Context, event TriggerEvent)
+
+// trigger is a threshold registered on a field.
+type trigger struct {
+	field     string
+	threshold *big.Int
+	fn        TriggerFunc
+}
+
+// RegisterTrigger registers fn to be called whenever an update moves the named field from below
+// threshold to threshold or above, e.g. to grant an achievement at 100 wins. Values are compared as
+// returned by GetScores. Triggers are evaluated by IncreaseScore and its variants and by UpdateIf.
+//
+// While triggers are registered, updates only succeed if the member still holds the values they
+// were computed from, and are retried up to OptimisticRetries times otherwise, so every crossing
+// is observed by exactly one update and fn runs once for it, after the write.
+func (mfs *MultiFieldSet) RegisterTrigger(fieldName string, threshold float64, fn TriggerFunc) error {
+	if mfs.GetFieldByName(fieldName) == nil {
+		return fieldNotFoundError(fieldName)
+	}
+	value, err := toInt64(fieldName, threshold)
+	if err != nil {
+		return err
+	}
+
+	mfs.hooks.mu.Lock()
+	defer mfs.hooks.mu.Unlock()
+	mfs.hooks.triggers = append(mfs.hooks.triggers, trigger{field: fieldName, threshold: big.NewInt(value), fn: fn})
+	return nil
+}
+
+// exactUpdates reports whether updates must only be written if the member didn't change since it
+// was read, because triggers or other hooks compare the scores before and after each update.
+func (mfs *MultiFieldSet) exactUpdates() bool {
+	mfs.hooks.mu.RLock()
+	defer mfs.hooks.mu.RUnlock()
+	return len(mfs.hooks.triggers) > 0 || mfs.hooks.exact
+}
+
+// runTriggers calls the triggers whose threshold was reached by an update. Updates that don't know
+// the member's previous scores, such as AddMember, don't fire triggers.
+func (mfs *MultiFieldSet) runTriggers(ctx context.Context, event *UpdateEvent) {
+	if event.OldScores == nil {
+		return
+	}
+	mfs.hooks.mu.RLock()
+	defer mfs.hooks.mu.RUnlock()
+	for _, t := range mfs.hooks.triggers {
+		before, after := scoreByName(event.OldScores, t.field), scoreByName(event.NewScores, t.field)
+		if before == nil || after == nil || before.Cmp(t.threshold) >= 0 || after.Cmp(t.threshold) < 0 {
+			continue
+		}
+		t.fn(ctx, TriggerEvent{
+			Set:       mfs.name,
+			Member:    event.Member,
+			Field:     t.field,
+			Threshold: t.threshold,
+			Before:    before,
+			After:     after,
+		})
+	}
+}
+
+// scoreByName returns the score of the named field, or nil if scores doesn't include it.
+func scoreByName(scores []FieldScore, name string) *big.Int {
+	for _, s := range scores {
+		if s.Name == name {
+			return s.Score
+		}
+	}
+	return nil
+}
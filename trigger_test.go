@@ -0,0 +1,57 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestRegisterTrigger(t *testing.T) {
+	mfs := newTestSet(t)
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var events []TriggerEvent
+	if err := mfs.RegisterTrigger("points", 100, func(ctx context.Context, event TriggerEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	}); err != nil {
+		t.Fatalf("RegisterTrigger() error = %v", err)
+	}
+
+	for _, points := range []float64{60, 30, 20, 50} {
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": points}, "alice"); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+	}
+	if len(events) != 1 {
+		t.Fatalf("trigger fired %d times, expected once", len(events))
+	}
+	e := events[0]
+	if e.Member != "alice" || e.Field != "points" || e.Before.Int64() != 90 || e.After.Int64() != 110 || e.Threshold.Int64() != 100 {
+		t.Errorf("event = %+v, expected alice's points to go from 90 to 110", e)
+	}
+
+	// Concurrent updates crossing the threshold fire it exactly once
+	events = nil
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 20}, "bob"); err != nil && !errors.Is(err, ErrUpdateConflict) {
+				t.Errorf("IncreaseScore() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if len(events) != 1 {
+		t.Errorf("trigger fired %d times for concurrent updates, expected once", len(events))
+	}
+
+	if err := mfs.RegisterTrigger("missing", 1, func(context.Context, TriggerEvent) {}); !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("RegisterTrigger() error = %v, expected ErrFieldNotFound", err)
+	}
+}
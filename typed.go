@@ -0,0 +1,215 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// TypedSet is a MultiFieldSet whose fields are described by the struct type T. See NewFromStruct.
+type TypedSet[T any] struct {
+	*MultiFieldSet
+	fields []typedField
+}
+
+// typedField maps a struct field to a set field.
+type typedField struct {
+	name  string
+	index int
+	kind  reflect.Kind
+	field *multiField
+}
+
+// NewFromStruct creates a MultiFieldSet whose fields are derived from the `zmf` tags of T's fields.
+// A tag has the form `zmf:"name,desc,max=1000000,inc"`: the name defaults to the Go field name, the
+// sort order to asc, the update type to inc (or replace, keepmax, keepmin, bitor and average) and
+// max is required; min sets MinValue, priority sets Priority and samples sets MaxSamples. Fields tagged `zmf:"-"` or without a tag
+// are ignored. Tagged fields must be integers or floats. opts.Fields must be empty.
+func NewFromStruct[T any](opts MultiFieldSetOptions) (*TypedSet[T], error) {
+	if len(opts.Fields) != 0 {
+		return nil, errors.New("fields are derived from the struct and must not be set")
+	}
+
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%s is not a struct", typ)
+	}
+
+	var typed []typedField
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		tag, ok := sf.Tag.Lookup("zmf")
+		if !ok || tag == "-" || !sf.IsExported() {
+			continue
+		}
+		if !isNumericKind(sf.Type.Kind()) {
+			return nil, fmt.Errorf("field %s: type %s is not numeric", sf.Name, sf.Type)
+		}
+		field, err := parseFieldTag(sf.Name, tag)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", sf.Name, err)
+		}
+		opts.Fields = append(opts.Fields, field)
+		typed = append(typed, typedField{name: field.Name, index: i, kind: sf.Type.Kind()})
+	}
+
+	mfs, err := New(opts)
+	if err != nil {
+		return nil, err
+	}
+	for i := range typed {
+		typed[i].field = mfs.GetFieldByName(typed[i].name)
+	}
+	return &TypedSet[T]{MultiFieldSet: mfs, fields: typed}, nil
+}
+
+// parseFieldTag parses a `zmf` struct tag into a Field.
+func parseFieldTag(goName, tag string) (Field, error) {
+	parts := strings.Split(tag, ",")
+	field := Field{Name: parts[0], Sort: Ascending, UpdateType: Incremental, MaxValue: -1}
+	if field.Name == "" {
+		field.Name = goName
+	}
+
+	for _, part := range parts[1:] {
+		switch part = strings.TrimSpace(part); {
+		case part == "asc":
+			field.Sort = Ascending
+		case part == "desc":
+			field.Sort = Descending
+		case part == "inc":
+			field.UpdateType = Incremental
+		case part == "replace":
+			field.UpdateType = Replace
+		case part == "keepmax":
+			field.UpdateType = KeepMax
+		case part == "keepmin":
+			field.UpdateType = KeepMin
+		case part == "bitor":
+			field.UpdateType = BitOr
+		case part == "average":
+			field.UpdateType = Average
+		case strings.HasPrefix(part, "samples="):
+			samples, err := strconv.ParseFloat(strings.TrimPrefix(part, "samples="), 64)
+			if err != nil {
+				return Field{}, fmt.Errorf("invalid samples %q", part)
+			}
+			field.MaxSamples = samples
+		case strings.HasPrefix(part, "min="):
+			min, err := strconv.ParseFloat(strings.TrimPrefix(part, "min="), 64)
+			if err != nil {
+				return Field{}, fmt.Errorf("invalid min %q", part)
+			}
+			field.MinValue = min
+		case strings.HasPrefix(part, "priority="):
+			priority, err := strconv.Atoi(strings.TrimPrefix(part, "priority="))
+			if err != nil || priority < 1 {
+				return Field{}, fmt.Errorf("invalid priority %q", part)
+			}
+			field.Priority = priority
+		case strings.HasPrefix(part, "max="):
+			max, err := strconv.ParseFloat(strings.TrimPrefix(part, "max="), 64)
+			if err != nil || max < 0 {
+				return Field{}, fmt.Errorf("invalid max %q", part)
+			}
+			field.MaxValue = max
+		default:
+			return Field{}, fmt.Errorf("unknown tag option %q", part)
+		}
+	}
+
+	if field.MaxValue < 0 {
+		return Field{}, errors.New("max is required")
+	}
+	return field, nil
+}
+
+// isNumericKind reports whether values of kind k can hold a field score.
+func isNumericKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// GetTyped returns a member's scores as a T. Members that aren't in the set have default scores.
+func (ts *TypedSet[T]) GetTyped(ctx context.Context, member string) (T, error) {
+	var result T
+	var zscore float64
+	err := ts.read(ctx, func(client redis.UniversalClient) error {
+		var err error
+		zscore, err = client.ZScore(ctx, ts.key, member).Result()
+		return err
+	})
+	scores := ts.zscoreToAllFieldScores(ts.defaultZScore)
+	if err == nil {
+		current, err := ts.zscoreOf(member, zscore)
+		if err != nil {
+			return result, ts.runOnError(ctx, "GetTyped", member, err)
+		}
+		scores = ts.zscoreToAllFieldScores(current)
+	} else if err != redis.Nil {
+		return result, ts.runOnError(ctx, "GetTyped", member, err)
+	}
+
+	v := reflect.ValueOf(&result).Elem()
+	for _, tf := range ts.fields {
+		for _, score := range scores {
+			if score.Name == tf.name {
+				setNumeric(v.Field(tf.index), score.Score)
+			}
+		}
+	}
+	return result, nil
+}
+
+// IncreaseTyped applies the fields of delta to a member and returns its new zscore. Zero values of
+// Incremental and BitOr fields are skipped, as they change nothing; other fields are always applied.
+func (ts *TypedSet[T]) IncreaseTyped(ctx context.Context, member string, delta T) (*big.Int, error) {
+	v := reflect.ValueOf(delta)
+	fields := make(map[string]float64, len(ts.fields))
+	for _, tf := range ts.fields {
+		value := numericValue(v.Field(tf.index))
+		if value == 0 && (tf.field.UpdateType == Incremental || tf.field.UpdateType == BitOr) {
+			continue
+		}
+		fields[tf.name] = value
+	}
+	return ts.IncreaseScore(ctx, fields, member)
+}
+
+// setNumeric stores score in the numeric value v.
+func setNumeric(v reflect.Value, score *big.Int) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(score.Int64())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(score.Uint64())
+	case reflect.Float32, reflect.Float64:
+		f, _ := new(big.Float).SetInt(score).Float64()
+		v.SetFloat(f)
+	}
+}
+
+// numericValue returns the numeric value v as a float64.
+func numericValue(v reflect.Value) float64 {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return math.Trunc(v.Float())
+	}
+	return 0
+}
@@ -0,0 +1,79 @@
+package zmultifield
+
+import (
+	"context"
+	"testing"
+)
+
+type playerStats struct {
+	Points int64  `zmf:"points,desc,max=1000"`
+	Deaths uint32 `zmf:"deaths,max=100"`
+	Level  int    `zmf:",desc,max=50,replace"`
+	Note   string
+}
+
+func TestNewFromStruct(t *testing.T) {
+	client, _ := newTestClient(t)
+	ts, err := NewFromStruct[playerStats](MultiFieldSetOptions{Name: "typed", Client: client})
+	if err != nil {
+		t.Fatalf("NewFromStruct() error = %v", err)
+	}
+
+	info := ts.GetFieldsInfo()
+	if len(info) != 3 || info[2].Name != "Level" {
+		t.Fatalf("GetFieldsInfo() = %+v, expected points, deaths, Level", info)
+	}
+	level := ts.GetFieldByName("Level")
+	if level.Sort != Descending || level.UpdateType != Replace || level.MaxValue != 50 {
+		t.Errorf("Level field = %+v, expected desc replace max 50", level.Field)
+	}
+
+	ctx := context.Background()
+	if _, err := ts.IncreaseTyped(ctx, "alice", playerStats{Points: 10, Level: 3}); err != nil {
+		t.Fatalf("IncreaseTyped() error = %v", err)
+	}
+	if _, err := ts.IncreaseTyped(ctx, "alice", playerStats{Points: 5, Deaths: 2, Level: 4}); err != nil {
+		t.Fatalf("IncreaseTyped() error = %v", err)
+	}
+
+	got, err := ts.GetTyped(ctx, "alice")
+	if err != nil {
+		t.Fatalf("GetTyped() error = %v", err)
+	}
+	if expected := (playerStats{Points: 15, Deaths: 2, Level: 4}); got != expected {
+		t.Errorf("GetTyped() = %+v, expected %+v", got, expected)
+	}
+
+	missing, err := ts.GetTyped(ctx, "nobody")
+	if err != nil {
+		t.Fatalf("GetTyped(nobody) error = %v", err)
+	}
+	if missing != (playerStats{}) {
+		t.Errorf("GetTyped(nobody) = %+v, expected zero value", missing)
+	}
+}
+
+func TestNewFromStruct_InvalidTags(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	type noMax struct {
+		Points int `zmf:"points"`
+	}
+	if _, err := NewFromStruct[noMax](MultiFieldSetOptions{Name: "typed", Client: client}); err == nil {
+		t.Errorf("NewFromStruct() accepted a field without max")
+	}
+
+	type unknownOption struct {
+		Points int `zmf:"points,max=10,sideways"`
+	}
+	if _, err := NewFromStruct[unknownOption](MultiFieldSetOptions{Name: "typed", Client: client}); err == nil {
+		t.Errorf("NewFromStruct() accepted an unknown option")
+	}
+
+	type notNumeric struct {
+		Points string `zmf:"points,max=10"`
+	}
+	if _, err := NewFromStruct[notNumeric](MultiFieldSetOptions{Name: "typed", Client: client}); err == nil {
+		t.Errorf("NewFromStruct() accepted a string field")
+	}
+}
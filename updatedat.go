@@ -0,0 +1,70 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"time"
+)
+
+// UpdatedAtField is the name of the field maintained when TrackUpdatedAt is enabled.
+const UpdatedAtField = "updatedAt"
+
+// updatedAtMaxValue bounds the updatedAt field to 26 bits of epoch minutes, enough until 2097.
+const updatedAtMaxValue = 1<<26 - 1
+
+// updatedAtFieldDef returns the definition of the updatedAt field. It is the least significant
+// field and ascending, so among otherwise equal members the one updated first ranks higher.
+func updatedAtFieldDef() Field {
+	return Field{
+		Name:       UpdatedAtField,
+		Sort:       Ascending,
+		MaxValue:   updatedAtMaxValue,
+		UpdateType: Replace,
+	}
+}
+
+// withUpdatedAt adds the updatedAt field to fields as the least significant field of layout,
+// rejecting a user field with the same name.
+func withUpdatedAt(fields []Field, layout Layout) ([]Field, error) {
+	for _, f := range fields {
+		if f.Name == UpdatedAtField {
+			return nil, errors.New("field name updatedAt is reserved when TrackUpdatedAt is enabled")
+		}
+	}
+	if layout.LeastSignificantFirst {
+		return append([]Field{updatedAtFieldDef()}, fields...), nil
+	}
+	return append(append([]Field(nil), fields...), updatedAtFieldDef()), nil
+}
+
+// updatedAtRaw returns the raw updatedAt value for the current time.
+func updatedAtRaw() *big.Int {
+	return big.NewInt(time.Now().Unix() / 60)
+}
+
+// stampUpdatedAt sets the updatedAt field in scores to the current time, if it is tracked.
+func (mfs *MultiFieldSet) stampUpdatedAt(scores []*big.Int) {
+	if mfs.updatedAt != nil {
+		scores[mfs.updatedAt.position] = updatedAtRaw()
+	}
+}
+
+// GetUpdatedAt returns the time a member was last written, truncated to the minute, or
+// ErrMemberNotFound if the member is not in the set. It requires TrackUpdatedAt.
+func (mfs *MultiFieldSet) GetUpdatedAt(ctx context.Context, member string) (_ time.Time, err error) {
+	defer mfs.observeRead("GetUpdatedAt", time.Now(), &err)
+
+	if mfs.updatedAt == nil {
+		return time.Time{}, fieldNotFoundError(UpdatedAtField)
+	}
+	zscore, err := mfs.memberZScore(ctx, member)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if zscore == nil {
+		return time.Time{}, ErrMemberNotFound
+	}
+	minutes := mfs.extractFieldScore(mfs.updatedAt, zscore).Int64()
+	return time.Unix(minutes*60, 0), nil
+}
@@ -0,0 +1,61 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTrackUpdatedAt(t *testing.T) {
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{TrackUpdatedAt: true})
+	ctx := context.Background()
+
+	before := time.Now().Truncate(time.Minute)
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 10}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+
+	updatedAt, err := mfs.GetUpdatedAt(ctx, "alice")
+	if err != nil {
+		t.Fatalf("GetUpdatedAt() error = %v", err)
+	}
+	if updatedAt.Before(before) || updatedAt.After(time.Now()) {
+		t.Errorf("GetUpdatedAt() = %v, expected about %v", updatedAt, before)
+	}
+
+	scores, err := mfs.GetScores(ctx, "alice")
+	if err != nil {
+		t.Fatalf("GetScores() error = %v", err)
+	}
+	if len(scores) != 3 || scores[2].Name != UpdatedAtField || scores[2].Score.Int64() != updatedAt.Unix()/60 {
+		t.Errorf("GetScores() = %+v, expected updatedAt last", scores)
+	}
+	if scores[0].Score.Int64() != 10 {
+		t.Errorf("points = %v, expected 10", scores[0].Score)
+	}
+
+	if _, err := mfs.GetUpdatedAt(ctx, "nobody"); !errors.Is(err, ErrMemberNotFound) {
+		t.Errorf("GetUpdatedAt(nobody) error = %v, expected %v", err, ErrMemberNotFound)
+	}
+}
+
+func TestTrackUpdatedAt_ReservedName(t *testing.T) {
+	client, _ := newTestClient(t)
+	_, err := New(MultiFieldSetOptions{
+		Name:           "test",
+		Fields:         []Field{{Name: UpdatedAtField, Sort: Ascending, MaxValue: 10, UpdateType: Replace}},
+		Client:         client,
+		TrackUpdatedAt: true,
+	})
+	if err == nil {
+		t.Errorf("New() accepted a field named %s", UpdatedAtField)
+	}
+}
+
+func TestGetUpdatedAt_Disabled(t *testing.T) {
+	mfs := newTestSet(t)
+	if _, err := mfs.GetUpdatedAt(context.Background(), "alice"); !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("GetUpdatedAt() error = %v, expected %v", err, ErrFieldNotFound)
+	}
+}
@@ -0,0 +1,101 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// errMemberChanged is returned by commitUpdate when a conditional write was skipped because the
+// member was added or changed after it was read.
+var errMemberChanged = errors.New("member changed")
+
+// ExpectationError is returned by UpdateIf when a field of the member doesn't hold the expected
+// value. It matches ErrUpdateConflict with errors.Is.
+type ExpectationError struct {
+	Member   string
+	Field    string
+	Expected *big.Int
+	Actual   *big.Int
+}
+
+// Error implements the error interface.
+func (e *ExpectationError) Error() string {
+	return fmt.Sprintf("field %s of member %s is %v, expected %v", e.Field, e.Member, e.Actual, e.Expected)
+}
+
+// Is reports whether target is ErrUpdateConflict.
+func (e *ExpectationError) Is(target error) bool {
+	return target == ErrUpdateConflict
+}
+
+// UpdateIf applies updates like IncreaseScore, but only if the member's current field values match
+// expected, e.g. to make retried event deliveries idempotent. Fields that aren't listed in expected
+// may hold any value, and a member that isn't in the set has default values. It returns an
+// ExpectationError if a field doesn't match. The check and the write are atomic: if the member
+// changes in between, the check is repeated up to OptimisticRetries times before UpdateIf fails
+// with ErrUpdateConflict.
+func (mfs *MultiFieldSet) UpdateIf(ctx context.Context, member string, expected map[string]float64, updates map[string]float64) (_ *big.Int, err error) {
+	defer mfs.observeUpdate("UpdateIf", time.Now(), &err)
+
+	result, err := mfs.updateIf(ctx, member, expected, updates)
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "UpdateIf", member, err)
+	}
+	return result.ZScore, nil
+}
+
+// updateIf checks the expectation against the member's current zscore and writes the update only
+// if the zscore is still the same, retrying when it isn't.
+func (mfs *MultiFieldSet) updateIf(ctx context.Context, member string, expected, updates map[string]float64) (*UpdateResult, error) {
+	for name := range expected {
+		if mfs.GetFieldByName(name) == nil {
+			return nil, fieldNotFoundError(name)
+		}
+	}
+
+	for attempt := 0; attempt <= mfs.optimisticRetries; attempt++ {
+		currentZScore, err := mfs.memberZScore(ctx, member)
+		if err != nil {
+			return nil, err
+		}
+		if mfs.updateOnlyExisting && currentZScore == nil {
+			return nil, ErrMemberNotFound
+		}
+		if err := mfs.checkExpected(member, currentZScore, expected); err != nil {
+			return nil, err
+		}
+
+		scores, finalZScore, event, err := mfs.buildUpdate(member, currentZScore, updates)
+		if err != nil {
+			return nil, err
+		}
+		mode := writeIfAbsent
+		if currentZScore != nil {
+			mode = writeIfZScore(currentZScore)
+		}
+		result, err := mfs.commitUpdate(ctx, event, scores, finalZScore, mode, false)
+		if err != errMemberChanged {
+			return result, err
+		}
+	}
+	return nil, ErrUpdateConflict
+}
+
+// checkExpected returns an ExpectationError for the first field whose display value in zscore
+// differs from expected.
+func (mfs *MultiFieldSet) checkExpected(member string, zscore *big.Int, expected map[string]float64) error {
+	for _, score := range mfs.zscoreToAllFieldScores(zscore) {
+		value, ok := expected[score.Name]
+		if !ok {
+			continue
+		}
+		want := big.NewInt(int64(value))
+		if score.Score.Cmp(want) != 0 {
+			return &ExpectationError{Member: member, Field: score.Name, Expected: want, Actual: score.Score}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestUpdateIf(t *testing.T) {
+	ctx := context.Background()
+	mfs := newTestSet(t)
+
+	// A new member has default values
+	if _, err := mfs.UpdateIf(ctx, "alice", map[string]float64{"points": 0}, map[string]float64{"points": 10}); err != nil {
+		t.Fatalf("UpdateIf on a new member failed: %v", err)
+	}
+	if _, err := mfs.UpdateIf(ctx, "alice", map[string]float64{"points": 10}, map[string]float64{"points": 5, "deaths": 1}); err != nil {
+		t.Fatalf("UpdateIf with a matching expectation failed: %v", err)
+	}
+
+	// Retrying the same delivery no longer matches and leaves the member alone
+	_, err := mfs.UpdateIf(ctx, "alice", map[string]float64{"points": 10}, map[string]float64{"points": 5, "deaths": 1})
+	var expErr *ExpectationError
+	if !errors.As(err, &expErr) || !errors.Is(err, ErrUpdateConflict) {
+		t.Fatalf("UpdateIf with a stale expectation = %v, expected an ExpectationError", err)
+	}
+	if expErr.Field != "points" || expErr.Expected.Int64() != 10 || expErr.Actual.Int64() != 15 {
+		t.Errorf("ExpectationError = %+v, expected points 15 instead of 10", expErr)
+	}
+	scores, err := mfs.GetScores(ctx, "alice")
+	if err != nil {
+		t.Fatalf("GetScores failed: %v", err)
+	}
+	if scores[0].Int64() != 15 || scores[1].Int64() != 1 {
+		t.Errorf("GetScores = %v, expected points 15 and deaths 1", scores)
+	}
+
+	if _, err := mfs.UpdateIf(ctx, "alice", map[string]float64{"missing": 1}, nil); !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("UpdateIf with an unknown field = %v, expected ErrFieldNotFound", err)
+	}
+}
+
+func TestUpdateIf_RetriesWhenMemberChanges(t *testing.T) {
+	ctx := context.Background()
+	mfs := newTestSet(t)
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 10}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore failed: %v", err)
+	}
+
+	// The first attempt races with a write that adds a death but leaves points alone
+	raced := false
+	mfs.BeforeUpdate(func(ctx context.Context, event *UpdateEvent) error {
+		if !raced {
+			raced = true
+			return mfs.client.ZIncrBy(ctx, mfs.GetKey(), 1, "alice").Err()
+		}
+		return nil
+	})
+	if _, err := mfs.UpdateIf(ctx, "alice", map[string]float64{"points": 10}, map[string]float64{"points": 1}); err != nil {
+		t.Fatalf("UpdateIf failed: %v", err)
+	}
+
+	scores, err := mfs.GetScores(ctx, "alice")
+	if err != nil {
+		t.Fatalf("GetScores failed: %v", err)
+	}
+	if scores[0].Int64() != 11 || scores[1].Int64() != 1 {
+		t.Errorf("GetScores = %v, expected points 11 and the concurrent death kept", scores)
+	}
+}
@@ -0,0 +1,108 @@
+package zmultifield
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// validateUpdateType checks that a BitOr field is ascending without MinValue, so its raw value is
+// the bitmask itself.
+func validateUpdateType(f Field) error {
+	if f.UpdateType == BitOr && (f.Sort != Ascending || f.MinValue != 0) {
+		return fmt.Errorf("field %s: BitOr fields must be ascending without MinValue", f.Name)
+	}
+	return nil
+}
+
+// rawUpdate returns how an update of the field with value changes its raw value: the mode, one of
+// "inc", "set", "max", "min" or "or" as understood by the update scripts, and the raw operand.
+// Keeping the larger display value of an inverted field keeps the smaller raw value. A negative
+// BitOr operand is passed on as is, so the result fails the range check.
+func (mf *multiField) rawUpdate(value int64) (string, *big.Int, error) {
+	switch mf.UpdateType {
+	case Incremental:
+		return "inc", new(big.Int).Mul(big.NewInt(value), mf.multiplier), nil
+	case Replace:
+		return "set", mf.toRaw(big.NewInt(value)), nil
+	case KeepMax:
+		if mf.inverted {
+			return "min", mf.toRaw(big.NewInt(value)), nil
+		}
+		return "max", mf.toRaw(big.NewInt(value)), nil
+	case KeepMin:
+		if mf.inverted {
+			return "max", mf.toRaw(big.NewInt(value)), nil
+		}
+		return "min", mf.toRaw(big.NewInt(value)), nil
+	case BitOr:
+		return "or", big.NewInt(value), nil
+	case Average:
+		return "", nil, ErrAverageUnsupported
+	default:
+		return "", nil, ErrUnknownUpdateType
+	}
+}
+
+// bitOrLua defines bor(a, b) for the update scripts: the bitwise OR of integers below 2^53, which
+// the 32-bit bit library of Redis can't handle. A negative b is returned as is, failing the
+// scripts' range checks.
+const bitOrLua = `
+local function bor(a, b)
+	if b < 0 then
+		return b
+	end
+	local result = 0
+	local place = 1
+	while a > 0 or b > 0 do
+		if a % 2 == 1 or b % 2 == 1 then
+			result = result + place
+		end
+		a = math.floor(a / 2)
+		b = math.floor(b / 2)
+		place = place * 2
+	end
+	return result
+end
+`
+
+// applyRaw returns raw updated according to mode with operand, as returned by rawUpdate.
+func applyRaw(mode string, raw, operand *big.Int) *big.Int {
+	switch mode {
+	case "inc":
+		return new(big.Int).Add(raw, operand)
+	case "max":
+		if operand.Cmp(raw) > 0 {
+			return new(big.Int).Set(operand)
+		}
+	case "min":
+		if operand.Cmp(raw) < 0 {
+			return new(big.Int).Set(operand)
+		}
+	case "or":
+		if operand.Sign() < 0 {
+			return new(big.Int).Set(operand)
+		}
+		return new(big.Int).Or(raw, operand)
+	default:
+		return new(big.Int).Set(operand)
+	}
+	return raw
+}
+
+// coalesce combines two updates of a field with updateType into one with the same effect: values
+// of incremental fields are summed, Replace keeps the later one, KeepMax and KeepMin the larger and
+// smaller one and BitOr ORs them.
+func coalesce(updateType UpdateType, earlier, later int64) int64 {
+	switch updateType {
+	case Replace:
+		return later
+	case KeepMax:
+		return max(earlier, later)
+	case KeepMin:
+		return min(earlier, later)
+	case BitOr:
+		return earlier | later
+	default:
+		return earlier + later
+	}
+}
@@ -0,0 +1,193 @@
+package zmultifield
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// keepFields are fields of every update type that depends on the current value.
+var keepFields = []Field{
+	{Name: "best", Sort: Descending, MaxValue: 1000, UpdateType: KeepMax},
+	{Name: "lap", Sort: Ascending, MaxValue: 1000, UpdateType: KeepMin},
+	{Name: "achievements", Sort: Ascending, MaxValue: 255, UpdateType: BitOr},
+}
+
+// keepUpdates are applied in order by the tests, leaving best 70, lap 35 and achievements 7.
+var keepUpdates = []map[string]float64{
+	{"best": 50, "lap": 40, "achievements": 1},
+	{"best": 70, "lap": 45, "achievements": 4},
+	{"best": 60, "lap": 35, "achievements": 2},
+}
+
+// checkKeepScores checks that scores hold the result of keepUpdates.
+func checkKeepScores(t *testing.T, name string, scores []FieldScore) {
+	t.Helper()
+	best, lap, achievements := FieldValue(scores, "best"), FieldValue(scores, "lap"), FieldValue(scores, "achievements")
+	if best != 70 || lap != 35 || achievements != 7 {
+		t.Errorf("%s: best %v, lap %v, achievements %v, expected 70, 35 and 7", name, best, lap, achievements)
+	}
+}
+
+func TestUpdateTypes(t *testing.T) {
+	ctx := context.Background()
+	for _, strategy := range []UpdateStrategy{UpdateScripted, UpdateOptimistic} {
+		client, _ := newTestClient(t)
+		mfs, err := New(MultiFieldSetOptions{Name: "test", Fields: keepFields, Client: client, UpdateStrategy: strategy})
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		for _, update := range keepUpdates {
+			if _, err := mfs.IncreaseScore(ctx, update, "alice"); err != nil {
+				t.Fatalf("IncreaseScore() error = %v", err)
+			}
+		}
+		scores, err := mfs.GetScores(ctx, "alice")
+		if err != nil {
+			t.Fatalf("GetScores() error = %v", err)
+		}
+		checkKeepScores(t, "IncreaseScore", scores)
+
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"best": 2000}, "alice"); err == nil {
+			t.Errorf("%v: IncreaseScore() above the maximum succeeded", strategy)
+		}
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"achievements": -1}, "alice"); err == nil {
+			t.Errorf("%v: IncreaseScore() with a negative bitmask succeeded", strategy)
+		}
+	}
+}
+
+func TestUpdateTypes_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	client, _ := newTestClient(t)
+	mfs, err := New(MultiFieldSetOptions{Name: "test", Fields: keepFields, Client: client, OptimisticRetries: 100})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			update := map[string]float64{"best": float64(10 * (i + 1)), "lap": float64(100 - i), "achievements": float64(int(1) << i)}
+			if _, err := mfs.IncreaseScore(ctx, update, "alice"); err != nil {
+				t.Errorf("IncreaseScore() error = %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	scores, err := mfs.GetScores(ctx, "alice")
+	if err != nil {
+		t.Fatalf("GetScores() error = %v", err)
+	}
+	if FieldValue(scores, "best") != 80 || FieldValue(scores, "lap") != 93 || FieldValue(scores, "achievements") != 255 {
+		t.Errorf("GetScores() = %v, expected best 80, lap 93 and every achievement", scores)
+	}
+}
+
+func TestUpdateTypes_BufferedWriter(t *testing.T) {
+	ctx := context.Background()
+	client, _ := newTestClient(t)
+	mfs, err := New(MultiFieldSetOptions{Name: "test", Fields: keepFields, Client: client})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := mfs.IncreaseScore(ctx, keepUpdates[0], "alice"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+
+	// The later updates are coalesced, then applied to the first one
+	w := mfs.NewBufferedWriter(BufferedWriterOptions{Window: time.Hour})
+	for _, update := range keepUpdates[1:] {
+		if err := w.Add(ctx, update, "alice"); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+	if err := w.Close(ctx); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	scores, err := mfs.GetScores(ctx, "alice")
+	if err != nil {
+		t.Fatalf("GetScores() error = %v", err)
+	}
+	checkKeepScores(t, "BufferedWriter", scores)
+}
+
+func TestUpdateTypes_LexAndWeighted(t *testing.T) {
+	ctx := context.Background()
+	client, _ := newTestClient(t)
+	ls, err := NewLex(LexSetOptions{Name: "lex", Fields: keepFields, Client: client})
+	if err != nil {
+		t.Fatalf("NewLex() error = %v", err)
+	}
+	ws, err := NewWeighted(WeightedSetOptions{Name: "weighted", Fields: keepFields, Client: client})
+	if err != nil {
+		t.Fatalf("NewWeighted() error = %v", err)
+	}
+	for _, update := range keepUpdates {
+		if err := ls.IncreaseScore(ctx, update, "alice"); err != nil {
+			t.Fatalf("LexSet.IncreaseScore() error = %v", err)
+		}
+		if _, err := ws.IncreaseScore(ctx, update, "alice"); err != nil {
+			t.Fatalf("WeightedSet.IncreaseScore() error = %v", err)
+		}
+	}
+
+	lexTop, err := ls.GetTopMembers(ctx, 1)
+	if err != nil || len(lexTop) != 1 {
+		t.Fatalf("LexSet.GetTopMembers() = %v, %v, expected alice", lexTop, err)
+	}
+	checkKeepScores(t, "LexSet", lexTop[0].Scores)
+	weightedTop, err := ws.GetTopMembers(ctx, 1)
+	if err != nil || len(weightedTop) != 1 {
+		t.Fatalf("WeightedSet.GetTopMembers() = %v, %v, expected alice", weightedTop, err)
+	}
+	checkKeepScores(t, "WeightedSet", weightedTop[0].Scores)
+}
+func TestUpdateTypes_MaxScoreWithFields(t *testing.T) {
+	client, _ := newTestClient(t)
+	fields := append(keepFields[:len(keepFields):len(keepFields)],
+		Field{Name: "fastest", Sort: Descending, MaxValue: 1000, UpdateType: KeepMin},
+		Field{Name: "worst", Sort: Ascending, MaxValue: 1000, UpdateType: KeepMax},
+	)
+	mfs, err := New(MultiFieldSetOptions{Name: "test", Fields: fields, Client: client})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	// KeepMin fields default to their largest value, which the limit replaces rather than offsets
+	limits := map[string]float64{"best": 5, "lap": 5, "achievements": 5, "fastest": 5, "worst": 5}
+	for name, limit := range limits {
+		bound, err := mfs.MaxScoreWithFields(map[string]float64{name: limit})
+		if err != nil {
+			t.Fatalf("MaxScoreWithFields() error = %v", err)
+		}
+		if got := mfs.CalculateScoresFromZScore(bound)[name]; got.Int64() != 5 {
+			t.Errorf("MaxScoreWithFields({%s: 5}) decodes as %s: %v", name, name, got)
+		}
+	}
+	bound, err := mfs.MaxScoreWithFields(limits)
+	if err != nil {
+		t.Fatalf("MaxScoreWithFields() error = %v", err)
+	}
+	for name, got := range mfs.CalculateScoresFromZScore(bound) {
+		if got.Int64() != 5 {
+			t.Errorf("MaxScoreWithFields() of every field decodes %s as %v, expected 5", name, got)
+		}
+	}
+}
+
+func TestUpdateTypes_Validation(t *testing.T) {
+	client, _ := newTestClient(t)
+	for name, field := range map[string]Field{
+		"descending": {Name: "mask", Sort: Descending, MaxValue: 255, UpdateType: BitOr},
+		"min value":  {Name: "mask", Sort: Ascending, MinValue: 1, MaxValue: 255, UpdateType: BitOr},
+	} {
+		if _, err := New(MultiFieldSetOptions{Name: "test", Fields: []Field{field}, Client: client}); err == nil {
+			t.Errorf("New() with a %s BitOr field succeeded, expected an error", name)
+		}
+	}
+}
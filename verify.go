@@ -0,0 +1,145 @@
+package zmultifield
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// verifySampleSize is the number of members Verify samples from each end of the set.
+const verifySampleSize = 10
+
+// maxExactFloat is the largest integer up to which every integer is exactly representable as a
+// float64 score.
+const maxExactFloat = 1 << 53
+
+// VerifyReport describes the state of a set's key as found by Verify.
+type VerifyReport struct {
+	// Key is the Redis key of the sorted set.
+	Key string
+	// Type is the Redis type of the key, "none" if it doesn't exist.
+	Type string
+	// Members is the number of members, if the key is a sorted set.
+	Members int64
+	// Sampled is the number of members whose scores were checked.
+	Sampled int
+	// Problems lists every problem found, empty if the key is usable by the set.
+	Problems []VerifyProblem
+}
+
+// VerifyProblem describes a problem with the key or with one of its members.
+type VerifyProblem struct {
+	Member string
+	Score  float64
+	Reason string
+}
+
+// OK reports whether Verify found no problems.
+func (r *VerifyReport) OK() bool {
+	return len(r.Problems) == 0
+}
+
+// Verify checks that the set's key is usable, e.g. at startup: that it is a sorted set or doesn't
+// exist yet, and that the scores of the lowest and highest ranked members are integral and within
+// the schema's bit range. This catches keys already used by something else or written with another
+// schema, or with a layout other than the one recorded by CheckLayout. Only Redis errors are
+// returned as errors; problems with the data are listed in the report.
+func (mfs *MultiFieldSet) Verify(ctx context.Context) (_ *VerifyReport, err error) {
+	defer mfs.observeRead("Verify", time.Now(), &err)
+
+	report := &VerifyReport{Key: mfs.key}
+	var keyType *redis.StatusCmd
+	var layout *redis.StringCmd
+	err = mfs.read(ctx, func(client redis.UniversalClient) error {
+		_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			keyType = pipe.Type(ctx, mfs.key)
+			layout = pipe.Get(ctx, mfs.layoutKey())
+			return nil
+		})
+		if err == redis.Nil {
+			err = nil
+		}
+		return err
+	})
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "Verify", "", err)
+	}
+	report.Type = keyType.Val()
+	if recorded := layout.Val(); recorded != "" && recorded != mfs.LayoutSignature() {
+		report.Problems = append(report.Problems, VerifyProblem{
+			Reason: fmt.Sprintf("layout differs from the recorded layout %q", recorded),
+		})
+	}
+	switch report.Type {
+	case "none":
+		return report, nil
+	case "zset":
+	default:
+		report.Problems = append(report.Problems, VerifyProblem{
+			Reason: fmt.Sprintf("key holds a %s, not a sorted set", report.Type),
+		})
+		return report, nil
+	}
+
+	var card *redis.IntCmd
+	var lowest, highest *redis.ZSliceCmd
+	err = mfs.read(ctx, func(client redis.UniversalClient) error {
+		_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			card = pipe.ZCard(ctx, mfs.key)
+			lowest = pipe.ZRangeWithScores(ctx, mfs.key, 0, verifySampleSize-1)
+			highest = pipe.ZRangeWithScores(ctx, mfs.key, -verifySampleSize, -1)
+			return nil
+		})
+		return err
+	})
+	if err != nil {
+		return nil, mfs.runOnError(ctx, "Verify", "", err)
+	}
+	report.Members = card.Val()
+
+	seen := make(map[string]bool, 2*verifySampleSize)
+	for _, z := range append(lowest.Val(), highest.Val()...) {
+		member := memberName(z)
+		if seen[member] {
+			continue
+		}
+		seen[member] = true
+		report.Sampled++
+		if reason := mfs.checkScore(z.Score); reason != "" {
+			report.Problems = append(report.Problems, VerifyProblem{Member: member, Score: z.Score, Reason: reason})
+		}
+	}
+	return report, nil
+}
+
+// maxZScore returns the largest zscore the schema can produce, with every field at its maximum raw
+// value.
+func (c *Codec) maxZScore() *big.Int {
+	raws := make([]*big.Int, len(c.fields))
+	for i, field := range c.fields {
+		raws[i] = field.maxAbsolute
+	}
+	return c.scoresToZScore(raws)
+}
+
+// checkScore returns why score can't have been written by the set, or "" if it could have been.
+func (c *Codec) checkScore(score float64) string {
+	switch {
+	case math.IsNaN(score) || math.IsInf(score, 0):
+		return "score is not finite"
+	case score != math.Trunc(score):
+		return "score is not an integer"
+	case score < 0:
+		return "score is negative"
+	case score > maxExactFloat:
+		return "score exceeds 2^53 and has lost precision"
+	}
+	if score > c.maxScore {
+		return fmt.Sprintf("score exceeds the schema's maximum of %.0f", c.maxScore)
+	}
+	return ""
+}
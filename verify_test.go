@@ -0,0 +1,76 @@
+package zmultifield
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+)
+
+func TestVerify_HealthySet(t *testing.T) {
+	ctx := context.Background()
+	mfs := newTestSet(t)
+
+	report, err := mfs.Verify(ctx)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if !report.OK() || report.Type != "none" {
+		t.Errorf("Verify on a missing key = %+v, expected type none and no problems", report)
+	}
+
+	for _, member := range []string{"alice", "bob"} {
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 10}, member); err != nil {
+			t.Fatalf("IncreaseScore failed: %v", err)
+		}
+	}
+	report, err = mfs.Verify(ctx)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if !report.OK() || report.Type != "zset" || report.Members != 2 || report.Sampled != 2 {
+		t.Errorf("Verify = %+v, expected a zset with 2 sampled members and no problems", report)
+	}
+}
+
+func TestVerify_WrongType(t *testing.T) {
+	ctx := context.Background()
+	mfs := newTestSet(t)
+	if err := mfs.client.Set(ctx, mfs.GetKey(), "something else", 0).Err(); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	report, err := mfs.Verify(ctx)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if report.OK() || report.Type != "string" || !strings.Contains(report.Problems[0].Reason, "not a sorted set") {
+		t.Errorf("Verify = %+v, expected a problem about the key type", report)
+	}
+}
+
+func TestVerify_InvalidScores(t *testing.T) {
+	ctx := context.Background()
+	mfs := newTestSet(t)
+	// The schema uses 17 bits, so 1<<20 is out of range
+	err := mfs.client.ZAdd(ctx, mfs.GetKey(),
+		&redis.Z{Score: 1.5, Member: "fraction"},
+		&redis.Z{Score: 100, Member: "fine"},
+		&redis.Z{Score: 1 << 20, Member: "huge"},
+	).Err()
+	if err != nil {
+		t.Fatalf("ZAdd failed: %v", err)
+	}
+
+	report, err := mfs.Verify(ctx)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if len(report.Problems) != 2 {
+		t.Fatalf("Verify problems = %+v, expected 2", report.Problems)
+	}
+	if report.Problems[0].Member != "fraction" || report.Problems[1].Member != "huge" {
+		t.Errorf("Verify problems = %+v, expected fraction and huge", report.Problems)
+	}
+}
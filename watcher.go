@@ -0,0 +1,179 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// WatcherOptions configures WatchTopN.
+type WatcherOptions struct {
+	// N is the number of top members watched. It must be positive.
+	N int64
+	// PollInterval is how often the top N is read to detect changes. With KeyspaceNotifications it
+	// is only a safety net for lost messages. Defaults to one second.
+	PollInterval time.Duration
+	// KeyspaceNotifications additionally reads the top N whenever Redis publishes a keyspace
+	// notification for the set's key, so changes are delivered without waiting for the next poll.
+	// The server must have notify-keyspace-events including "Kz". On a cluster, notifications are
+	// only published by the node owning the key, which the subscription may not be connected to.
+	KeyspaceNotifications bool
+}
+
+// TopNChange describes a change of the top N members, delivered by WatchTopN.
+type TopNChange struct {
+	// Entries is the current top N, in leaderboard order.
+	Entries []LeaderboardEntry
+	// Previous is the top N before the change, or nil for the first delivery.
+	Previous []LeaderboardEntry
+	// Entered lists the members that are in Entries but weren't in Previous.
+	Entered []string
+	// Left lists the members that were in Previous but aren't in Entries.
+	Left []string
+}
+
+// WatchTopN calls fn from a background goroutine with the current top N, and again every time the
+// membership, ordering or scores of the top N change, e.g. to push live leaderboard updates to
+// WebSocket clients. Changes are detected by polling, and also by keyspace notifications if
+// enabled; bursts of writes between two reads are delivered as one change. Read errors are
+// reported to the error hooks. Watching stops when ctx is done or the returned function is called.
+func (mfs *MultiFieldSet) WatchTopN(ctx context.Context, opts WatcherOptions, fn func(ctx context.Context, change TopNChange)) (stop func(), err error) {
+	if opts.N <= 0 {
+		return nil, errors.New("watched top N must be positive")
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = time.Second
+	}
+
+	var pubsub *redis.PubSub
+	var events <-chan *redis.Message
+	if opts.KeyspaceNotifications {
+		pubsub = mfs.client.PSubscribe(ctx, "__keyspace@*__:"+escapePattern(mfs.key))
+		if _, err := pubsub.Receive(ctx); err != nil {
+			pubsub.Close()
+			return nil, err
+		}
+		events = pubsub.Channel()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(opts.PollInterval)
+		defer ticker.Stop()
+
+		var previous []LeaderboardEntry
+		first := true
+		for {
+			// GetMembers already reports errors to the error hooks; the next poll retries
+			if members, err := mfs.GetMembers(ctx, opts.N, 0); err == nil {
+				entries := make([]LeaderboardEntry, len(members))
+				for i, m := range members {
+					entries[i] = LeaderboardEntry{Rank: int64(i), Member: m.Member, Scores: m.Scores}
+				}
+				if first || !sameEntries(previous, entries) {
+					fn(ctx, topNChange(previous, entries))
+					previous = entries
+					first = false
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-events:
+				drainMessages(events)
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			cancel()
+			<-done
+			if pubsub != nil {
+				pubsub.Close()
+			}
+		})
+	}, nil
+}
+
+// topNChange returns the change from previous to entries.
+func topNChange(previous, entries []LeaderboardEntry) TopNChange {
+	change := TopNChange{Entries: entries, Previous: previous}
+	before := make(map[string]bool, len(previous))
+	for _, e := range previous {
+		before[e.Member] = true
+	}
+	after := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		after[e.Member] = true
+		if !before[e.Member] {
+			change.Entered = append(change.Entered, e.Member)
+		}
+	}
+	for _, e := range previous {
+		if !after[e.Member] {
+			change.Left = append(change.Left, e.Member)
+		}
+	}
+	return change
+}
+
+// sameEntries reports whether a and b hold the same members in the same order with the same scores.
+func sameEntries(a, b []LeaderboardEntry) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Member != b[i].Member || len(a[i].Scores) != len(b[i].Scores) {
+			return false
+		}
+		for j := range a[i].Scores {
+			if !sameScore(a[i].Scores[j], b[i].Scores[j]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// sameScore reports whether a and b hold the same score, comparing computed fields by value.
+func sameScore(a, b FieldScore) bool {
+	if a.Score == nil || b.Score == nil {
+		return a.Score == nil && b.Score == nil && a.Value == b.Value
+	}
+	return a.Score.Cmp(b.Score) == 0
+}
+
+// escapePattern escapes the glob characters of key for use in a PSUBSCRIBE pattern.
+func escapePattern(key string) string {
+	var b strings.Builder
+	for _, r := range key {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// drainMessages discards the messages already waiting on events, so a burst of writes triggers a
+// single read.
+func drainMessages(events <-chan *redis.Message) {
+	for {
+		select {
+		case <-events:
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package zmultifield
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// nextChange waits for the next change delivered on changes.
+func nextChange(t *testing.T, changes <-chan TopNChange) TopNChange {
+	t.Helper()
+	select {
+	case change := <-changes:
+		return change
+	case <-time.After(time.Second):
+		t.Fatal("no change delivered")
+		return TopNChange{}
+	}
+}
+
+func TestWatchTopNPolling(t *testing.T) {
+	mfs := newTestSet(t)
+	ctx := context.Background()
+
+	for member, points := range map[string]float64{"alice": 30, "bob": 20, "carol": 10} {
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": points}, member); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+	}
+
+	changes := make(chan TopNChange, 10)
+	stop, err := mfs.WatchTopN(ctx, WatcherOptions{N: 2, PollInterval: 5 * time.Millisecond}, func(ctx context.Context, change TopNChange) {
+		changes <- change
+	})
+	if err != nil {
+		t.Fatalf("WatchTopN() error = %v", err)
+	}
+	defer stop()
+
+	change := nextChange(t, changes)
+	if len(change.Entries) != 2 || change.Entries[0].Member != "alice" || change.Previous != nil {
+		t.Fatalf("initial change = %+v, expected alice and bob", change)
+	}
+
+	// Changes below the top N are not delivered
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"deaths": 1}, "carol"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 25}, "carol"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	change = nextChange(t, changes)
+	if change.Entries[0].Member != "carol" || len(change.Entered) != 1 || change.Entered[0] != "carol" ||
+		len(change.Left) != 1 || change.Left[0] != "bob" {
+		t.Errorf("change = %+v, expected carol to enter first and bob to leave", change)
+	}
+
+	// A score change within the top N is delivered without membership changes
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"deaths": 1}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	change = nextChange(t, changes)
+	if len(change.Entered) != 0 || len(change.Left) != 0 || change.Entries[1].Scores[1].Score.Int64() != 1 {
+		t.Errorf("change = %+v, expected alice's deaths to change", change)
+	}
+
+	stop()
+	select {
+	case change := <-changes:
+		t.Errorf("change %+v delivered after stop", change)
+	default:
+	}
+
+	if _, err := mfs.WatchTopN(ctx, WatcherOptions{}, func(context.Context, TopNChange) {}); err == nil {
+		t.Error("WatchTopN() with N = 0 succeeded")
+	}
+}
+
+func TestWatchTopNKeyspaceNotifications(t *testing.T) {
+	mfs := newTestSet(t)
+	ctx := context.Background()
+
+	changes := make(chan TopNChange, 10)
+	stop, err := mfs.WatchTopN(ctx, WatcherOptions{N: 3, PollInterval: time.Hour, KeyspaceNotifications: true}, func(ctx context.Context, change TopNChange) {
+		changes <- change
+	})
+	if err != nil {
+		t.Fatalf("WatchTopN() error = %v", err)
+	}
+	defer stop()
+	if change := nextChange(t, changes); len(change.Entries) != 0 {
+		t.Fatalf("initial change = %+v, expected an empty top N", change)
+	}
+
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 10}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore() error = %v", err)
+	}
+	// miniredis doesn't publish keyspace notifications, so publish the one Redis would send
+	if err := mfs.client.Publish(ctx, "__keyspace@0__:"+mfs.key, "zadd").Err(); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if change := nextChange(t, changes); len(change.Entered) != 1 || change.Entered[0] != "alice" {
+		t.Errorf("change = %+v, expected alice to enter", change)
+	}
+}
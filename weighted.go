@@ -0,0 +1,338 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// weightedUpdateScript applies field updates to a member of a WeightedSet and recomputes its
+// composite score in one atomic step. Nothing is written if any field would leave its range.
+//
+// KEYS[1] is the sorted set and KEYS[2..n] the value hashes of the fields in field order. ARGV[1] is
+// the member, followed by five values per field: the update mode ("inc", "set", "max", "min", "or"
+// or "keep"), the update value, the field's minimum, its maximum (empty if unbounded) and its signed
+// weight. A member starts at each field's minimum, except that a KeepMin field the member has no
+// value for yet takes the update value. Each field adds its weight times the value above its
+// minimum to the score. The script returns the new zscore.
+var weightedUpdateScript = redis.NewScript(bitOrLua + `
+local values = {}
+local zscore = 0
+for i = 2, #ARGV, 5 do
+	local n = (i + 3) / 5
+	local stored = redis.call('HGET', KEYS[n + 1], ARGV[1])
+	local min = tonumber(ARGV[i + 2])
+	local value = tonumber(stored) or min
+	local mode, operand = ARGV[i], tonumber(ARGV[i + 1])
+	if mode == 'inc' then
+		value = value + operand
+	elseif mode == 'set' or (mode == 'min' and not stored) then
+		value = operand
+	elseif mode == 'max' then
+		value = math.max(value, operand)
+	elseif mode == 'min' then
+		value = math.min(value, operand)
+	elseif mode == 'or' then
+		value = bor(value, operand)
+	end
+	local max = tonumber(ARGV[i + 3])
+	if value < min or (max and value > max) then
+		return redis.error_reply('OUTOFRANGE ' .. n .. ' ' .. string.format('%.17g', value))
+	end
+	values[n] = value
+	zscore = zscore - tonumber(ARGV[i + 4]) * (value - min)
+end
+
+for n, value in ipairs(values) do
+	redis.call('HSET', KEYS[n + 1], ARGV[1], string.format('%.17g', value))
+end
+local encoded = string.format('%.17g', zscore)
+redis.call('ZADD', KEYS[1], encoded, ARGV[1])
+return encoded
+`)
+
+// WeightedSet ranks members by a weighted sum of their field values instead of packing the fields
+// by strict priority, for leaderboards that blend several metrics into one score. It shares the
+// Field definitions and update semantics of MultiFieldSet: Weight scales each field's value above
+// its MinValue, 1 if unset, and descending fields raise the composite score while ascending fields
+// lower it.
+//
+// The composite score can't be unpacked, so each field's values are kept in a hash next to the
+// sorted set.
+type WeightedSet struct {
+	name       string
+	baseKey    string
+	key        string
+	keyBuilder KeyBuilder
+	fields     []Field
+	client     redis.UniversalClient
+}
+
+// WeightedSetOptions defines options for creating a new WeightedSet.
+type WeightedSetOptions struct {
+	Name   string
+	Fields []Field
+	Client redis.UniversalClient
+	// Namespace isolates the set's keys, e.g. per tenant or environment.
+	Namespace string
+	// KeyFunc combines Namespace and Name into the base key. Defaults to DefaultKeyFunc.
+	KeyFunc func(namespace, name string) string
+	// KeyBuilder derives the Redis keys used by the set. Defaults to DefaultKeyBuilder.
+	KeyBuilder KeyBuilder
+}
+
+// WeightedMember is a member of a WeightedSet with its composite score and field values.
+type WeightedMember struct {
+	MemberScores
+	Score float64
+}
+
+// NewWeighted creates a new WeightedSet instance.
+func NewWeighted(opts WeightedSetOptions) (*WeightedSet, error) {
+	if opts.Name == "" {
+		return nil, errors.New("name is required")
+	}
+
+	if len(opts.Fields) == 0 {
+		return nil, errors.New("at least one field is required")
+	}
+
+	if opts.Client == nil {
+		return nil, errors.New("Redis client is required")
+	}
+
+	fields := append([]Field(nil), opts.Fields...)
+	for i, f := range fields {
+		if f.Weight < 0 || math.IsNaN(f.Weight) || math.IsInf(f.Weight, 0) {
+			return nil, fmt.Errorf("invalid weight %v for field %s", f.Weight, f.Name)
+		}
+		if f.Weight == 0 {
+			fields[i].Weight = 1
+		}
+		if f.UpdateType == Average {
+			return nil, fmt.Errorf("field %s: %w", f.Name, ErrAverageUnsupported)
+		}
+		if err := validateMinValue(f); err != nil {
+			return nil, err
+		}
+		if f.UpdateType == BitOr && f.MinValue != 0 {
+			return nil, fmt.Errorf("field %s: BitOr fields can't have a MinValue", f.Name)
+		}
+	}
+
+	keyFunc := opts.KeyFunc
+	if keyFunc == nil {
+		keyFunc = DefaultKeyFunc
+	}
+	ws := &WeightedSet{
+		name:       opts.Name,
+		baseKey:    keyFunc(opts.Namespace, opts.Name),
+		keyBuilder: opts.KeyBuilder,
+		fields:     fields,
+		client:     opts.Client,
+	}
+	if ws.keyBuilder == nil {
+		ws.keyBuilder = DefaultKeyBuilder{}
+	}
+	ws.key = ws.keyBuilder.Key(ws.baseKey)
+	return ws, nil
+}
+
+// GetName returns the name of the sorted set.
+func (ws *WeightedSet) GetName() string {
+	return ws.name
+}
+
+// GetKey returns the Redis key of the sorted set.
+func (ws *WeightedSet) GetKey() string {
+	return ws.key
+}
+
+// valuesKey returns the key of the hash holding every member's value for field.
+func (ws *WeightedSet) valuesKey(field Field) string {
+	return ws.keyBuilder.DerivedKey(ws.baseKey, "weighted:"+field.Name)
+}
+
+// signedWeight returns the field's contribution per unit to the composite score.
+func signedWeight(field Field) float64 {
+	if field.Sort == Ascending {
+		return -field.Weight
+	}
+	return field.Weight
+}
+
+// IncreaseScore increases or replaces the given fields of a member, like MultiFieldSet.IncreaseScore,
+// and returns the member's new composite score.
+func (ws *WeightedSet) IncreaseScore(ctx context.Context, fields map[string]float64, member string) (float64, error) {
+	for name := range fields {
+		if ws.fieldByName(name) == nil {
+			return 0, fieldNotFoundError(name)
+		}
+	}
+
+	keys := []string{ws.key}
+	args := []interface{}{member}
+	for _, field := range ws.fields {
+		keys = append(keys, ws.valuesKey(field))
+
+		mode, value := "keep", int64(0)
+		if delta, ok := fields[field.Name]; ok {
+			v, err := toInt64(field.Name, delta)
+			if err != nil {
+				return 0, err
+			}
+			value = v
+			switch field.UpdateType {
+			case Incremental:
+				mode = "inc"
+			case Replace:
+				mode = "set"
+			case KeepMax:
+				mode = "max"
+			case KeepMin:
+				mode = "min"
+			case BitOr:
+				mode = "or"
+			default:
+				return 0, ErrUnknownUpdateType
+			}
+		}
+		var max interface{} = ""
+		if !math.IsInf(field.MaxValue, 1) {
+			max = int64(field.MaxValue)
+		}
+		args = append(args, mode, value, int64(field.MinValue), max, strconv.FormatFloat(signedWeight(field), 'g', -1, 64))
+	}
+
+	encoded, err := weightedUpdateScript.Run(ctx, ws.client, keys, args...).Text()
+	if err != nil {
+		return 0, ws.updateError(err)
+	}
+	zscore, err := strconv.ParseFloat(encoded, 64)
+	if err != nil {
+		return 0, err
+	}
+	return -zscore, nil
+}
+
+// updateError converts an OUTOFRANGE reply from the update script into a ScoreOutOfRangeError.
+func (ws *WeightedSet) updateError(err error) error {
+	if !strings.HasPrefix(err.Error(), "OUTOFRANGE ") {
+		return err
+	}
+	var position int
+	var value float64
+	if _, scanErr := fmt.Sscanf(err.Error(), "OUTOFRANGE %d %g", &position, &value); scanErr != nil {
+		return err
+	}
+	field := ws.fields[position-1]
+	return &ScoreOutOfRangeError{
+		Field: field.Name,
+		Value: big.NewInt(int64(value)),
+		Min:   big.NewInt(int64(field.MinValue)),
+		Max:   big.NewInt(int64(field.MaxValue)),
+	}
+}
+
+// fieldByName returns the named field or nil if there is none.
+func (ws *WeightedSet) fieldByName(name string) *Field {
+	for i := range ws.fields {
+		if ws.fields[i].Name == name {
+			return &ws.fields[i]
+		}
+	}
+	return nil
+}
+
+// GetScores returns all field values of a member. Members that aren't in the set have each field's
+// MinValue.
+func (ws *WeightedSet) GetScores(ctx context.Context, member string) ([]FieldScore, error) {
+	members, err := ws.withValues(ctx, []string{member})
+	if err != nil {
+		return nil, err
+	}
+	return members[0].Scores, nil
+}
+
+// GetRank returns the rank of a member, or ErrMemberNotFound if the member is not in the set.
+func (ws *WeightedSet) GetRank(ctx context.Context, member string) (int64, error) {
+	rank, err := ws.client.ZRank(ctx, ws.key, member).Result()
+	if err == redis.Nil {
+		return 0, ErrMemberNotFound
+	}
+	return rank, err
+}
+
+// GetTopMembers returns the members with the highest composite scores, best first, with their
+// field values.
+func (ws *WeightedSet) GetTopMembers(ctx context.Context, limit int64) ([]WeightedMember, error) {
+	results, err := ws.client.ZRangeWithScores(ctx, ws.key, 0, limit-1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(results))
+	for i, z := range results {
+		names[i] = memberName(z)
+	}
+	members, err := ws.withValues(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+	for i, z := range results {
+		members[i].Score = -z.Score
+	}
+	return members, nil
+}
+
+// withValues fetches the field values of members with one HMGET per field.
+func (ws *WeightedSet) withValues(ctx context.Context, members []string) ([]WeightedMember, error) {
+	result := make([]WeightedMember, len(members))
+	for i, member := range members {
+		result[i] = WeightedMember{MemberScores: MemberScores{Member: member, Scores: make([]FieldScore, len(ws.fields))}}
+	}
+	if len(members) == 0 {
+		return result, nil
+	}
+
+	cmds := make([]*redis.SliceCmd, len(ws.fields))
+	_, err := ws.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, field := range ws.fields {
+			cmds[i] = pipe.HMGet(ctx, ws.valuesKey(field), members...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i, field := range ws.fields {
+		for j, value := range cmds[i].Val() {
+			score := big.NewInt(int64(field.MinValue))
+			if s, ok := value.(string); ok {
+				f, err := strconv.ParseFloat(s, 64)
+				if err != nil {
+					return nil, err
+				}
+				score.SetInt64(int64(f))
+			}
+			result[j].Scores[i] = FieldScore{Name: field.Name, Score: score}
+		}
+	}
+	return result, nil
+}
+
+// Clear deletes every member of the set along with the field values.
+func (ws *WeightedSet) Clear(ctx context.Context) error {
+	keys := []string{ws.key}
+	for _, field := range ws.fields {
+		keys = append(keys, ws.valuesKey(field))
+	}
+	return ws.client.Del(ctx, keys...).Err()
+}
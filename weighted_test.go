@@ -0,0 +1,144 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func newTestWeightedSet(t *testing.T) *WeightedSet {
+	t.Helper()
+	client, _ := newTestClient(t)
+	ws, err := NewWeighted(WeightedSetOptions{
+		Name: "weighted",
+		Fields: []Field{
+			{Name: "wins", Sort: Descending, MaxValue: 1000, UpdateType: Incremental, Weight: 10},
+			{Name: "kills", Sort: Descending, MaxValue: 100000, UpdateType: Incremental, Weight: 1},
+			{Name: "deaths", Sort: Ascending, MaxValue: 100000, UpdateType: Incremental, Weight: 0.5},
+		},
+		Client: client,
+	})
+	if err != nil {
+		t.Fatalf("NewWeighted failed: %v", err)
+	}
+	return ws
+}
+
+func TestWeightedSet_BlendsFields(t *testing.T) {
+	ctx := context.Background()
+	ws := newTestWeightedSet(t)
+
+	// alice: 10*2 + 5 - 0.5*4 = 23, bob: 10*1 + 30 - 0.5*10 = 35
+	score, err := ws.IncreaseScore(ctx, map[string]float64{"wins": 2, "kills": 5, "deaths": 4}, "alice")
+	if err != nil {
+		t.Fatalf("IncreaseScore failed: %v", err)
+	}
+	if score != 23 {
+		t.Errorf("IncreaseScore = %v, expected 23", score)
+	}
+	if _, err := ws.IncreaseScore(ctx, map[string]float64{"wins": 1, "kills": 30, "deaths": 10}, "bob"); err != nil {
+		t.Fatalf("IncreaseScore failed: %v", err)
+	}
+
+	top, err := ws.GetTopMembers(ctx, 10)
+	if err != nil {
+		t.Fatalf("GetTopMembers failed: %v", err)
+	}
+	if len(top) != 2 || top[0].Member != "bob" || top[0].Score != 35 || top[1].Member != "alice" {
+		t.Fatalf("GetTopMembers = %+v, expected bob (35) then alice", top)
+	}
+	if top[0].Scores[1].Name != "kills" || top[0].Scores[1].Int64() != 30 {
+		t.Errorf("bob's kills = %v, expected 30", top[0].Scores[1])
+	}
+
+	// A second update is applied to the stored values, not the composite score
+	if score, err = ws.IncreaseScore(ctx, map[string]float64{"wins": 2}, "alice"); err != nil || score != 43 {
+		t.Errorf("IncreaseScore = %v, %v, expected 43", score, err)
+	}
+	if rank, err := ws.GetRank(ctx, "alice"); err != nil || rank != 0 {
+		t.Errorf("GetRank(alice) = %d, %v, expected 0", rank, err)
+	}
+	scores, err := ws.GetScores(ctx, "alice")
+	if err != nil {
+		t.Fatalf("GetScores failed: %v", err)
+	}
+	if scores[0].Int64() != 4 || scores[1].Int64() != 5 || scores[2].Int64() != 4 {
+		t.Errorf("GetScores = %v, expected wins 4, kills 5 and deaths 4", scores)
+	}
+}
+
+func TestWeightedSet_Errors(t *testing.T) {
+	ctx := context.Background()
+	ws := newTestWeightedSet(t)
+
+	if _, err := ws.IncreaseScore(ctx, map[string]float64{"missing": 1}, "alice"); !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("IncreaseScore with unknown field = %v, expected ErrFieldNotFound", err)
+	}
+
+	if _, err := ws.IncreaseScore(ctx, map[string]float64{"wins": 1, "deaths": 2}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore failed: %v", err)
+	}
+	_, err := ws.IncreaseScore(ctx, map[string]float64{"wins": 2000, "deaths": 1}, "alice")
+	var rangeErr *ScoreOutOfRangeError
+	if !errors.As(err, &rangeErr) || rangeErr.Field != "wins" {
+		t.Fatalf("IncreaseScore out of range = %v, expected a ScoreOutOfRangeError for wins", err)
+	}
+	// The rejected update must not have touched any field
+	scores, err := ws.GetScores(ctx, "alice")
+	if err != nil {
+		t.Fatalf("GetScores failed: %v", err)
+	}
+	if scores[0].Int64() != 1 || scores[2].Int64() != 2 {
+		t.Errorf("GetScores after rejected update = %v, expected wins 1 and deaths 2", scores)
+	}
+
+	if _, err := ws.GetRank(ctx, "nobody"); !errors.Is(err, ErrMemberNotFound) {
+		t.Errorf("GetRank(nobody) = %v, expected ErrMemberNotFound", err)
+	}
+
+	client, _ := newTestClient(t)
+	_, err = NewWeighted(WeightedSetOptions{
+		Name:   "bad",
+		Fields: []Field{{Name: "x", Sort: Descending, MaxValue: 10, Weight: -1}},
+		Client: client,
+	})
+	if err == nil {
+		t.Error("NewWeighted with a negative weight succeeded, expected an error")
+	}
+}
+
+func TestWeightedSet_MinValueAndDefaultWeight(t *testing.T) {
+	ctx := context.Background()
+	client, _ := newTestClient(t)
+	ws, err := NewWeighted(WeightedSetOptions{
+		Name: "rated",
+		Fields: []Field{
+			{Name: "rating", Sort: Descending, MinValue: 1000, MaxValue: 5000, UpdateType: Replace, Weight: 2},
+			{Name: "wins", Sort: Descending, MaxValue: 1000, UpdateType: Incremental},
+		},
+		Client: client,
+	})
+	if err != nil {
+		t.Fatalf("NewWeighted failed: %v", err)
+	}
+
+	// New members start at MinValue, which adds nothing to the score
+	scores, err := ws.GetScores(ctx, "alice")
+	if err != nil || scores[0].Int64() != 1000 || scores[1].Int64() != 0 {
+		t.Errorf("GetScores(alice) = %v, %v, expected rating 1000 and wins 0", scores, err)
+	}
+	// wins has no Weight, so each win counts once
+	if score, err := ws.IncreaseScore(ctx, map[string]float64{"wins": 3}, "alice"); err != nil || score != 3 {
+		t.Errorf("IncreaseScore(wins) = %v, %v, expected 3", score, err)
+	}
+	// 2*(1050-1000) + 3
+	if score, err := ws.IncreaseScore(ctx, map[string]float64{"rating": 1050}, "alice"); err != nil || score != 103 {
+		t.Errorf("IncreaseScore(rating) = %v, %v, expected 103", score, err)
+	}
+
+	_, err = ws.IncreaseScore(ctx, map[string]float64{"rating": 999}, "alice")
+	var rangeErr *ScoreOutOfRangeError
+	if !errors.As(err, &rangeErr) || rangeErr.Min.Int64() != 1000 {
+		t.Errorf("IncreaseScore below MinValue = %v, expected a ScoreOutOfRangeError with min 1000", err)
+	}
+}
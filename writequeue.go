@@ -0,0 +1,473 @@
+package zmultifield
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// defaultQueueCapacity is the capacity of the MemoryQueue used when WriteQueueOptions.Queue is nil.
+const defaultQueueCapacity = 10000
+
+// replayBatchSize is the number of queued updates ReplayQueued applies per transaction.
+const replayBatchSize = 100
+
+// QueuedUpdate is an update that couldn't be written to Redis and waits in a WriteQueue.
+type QueuedUpdate struct {
+	// Seq orders the updates of one writer and lets replays skip updates that were already applied.
+	Seq    uint64           `json:"seq"`
+	Member string           `json:"member"`
+	Fields map[string]int64 `json:"fields"`
+	// IdempotencyKey is the key of an update made under WithIdempotencyKey. The replay skips the
+	// update if the key was recorded meanwhile, and records it otherwise.
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
+}
+
+// WriteQueue stores queued updates in order until they are replayed. Implementations must be safe
+// for concurrent use.
+type WriteQueue interface {
+	// Append adds an update to the end of the queue.
+	Append(update QueuedUpdate) error
+	// Peek returns up to n updates from the front of the queue without removing them.
+	Peek(n int) ([]QueuedUpdate, error)
+	// Remove drops the updates at the front of the queue up to and including seq.
+	Remove(seq uint64) error
+	// Len returns the number of queued updates.
+	Len() int
+}
+
+// WriteQueueOptions makes IncreaseScore queue updates that fail with transient errors, such as a
+// lost connection, instead of dropping them. Queued updates are written by ReplayQueued.
+type WriteQueueOptions struct {
+	// Queue stores the updates. Defaults to a MemoryQueue of 10000 updates; use a FileQueue for
+	// updates to survive restarts.
+	Queue WriteQueue
+	// WriterID names this writer's sequence numbers in Redis so every update is applied exactly
+	// once. It must be unique among the writers of a set and, with a FileQueue, stable across
+	// restarts.
+	WriterID string
+}
+
+// writeQueue holds the queue of a set and the last sequence number handed out.
+type writeQueue struct {
+	queue    WriteQueue
+	writerID string
+
+	mu      sync.Mutex
+	lastSeq uint64
+}
+
+// newWriteQueue creates the writeQueue for opts.
+func newWriteQueue(opts *WriteQueueOptions) (*writeQueue, error) {
+	if opts.WriterID == "" {
+		return nil, errors.New("write queue writer ID is required")
+	}
+	q := &writeQueue{queue: opts.Queue, writerID: opts.WriterID}
+	if q.queue == nil {
+		q.queue = NewMemoryQueue(defaultQueueCapacity)
+	}
+	return q, nil
+}
+
+// nextSeq returns a sequence number larger than any handed out before, also by earlier processes
+// with the same WriterID, by basing it on the clock.
+func (q *writeQueue) nextSeq() uint64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	seq := uint64(time.Now().UnixNano())
+	if seq <= q.lastSeq {
+		seq = q.lastSeq + 1
+	}
+	q.lastSeq = seq
+	return seq
+}
+
+// replayKey returns the key of the hash holding the last replayed sequence number of every writer.
+func (mfs *MultiFieldSet) replayKey() string {
+	return mfs.derivedKey("replayed")
+}
+
+// increaseScoreOrQueue applies an update like increaseScore, but queues it if the set has a write
+// queue and the update fails with a transient error. While updates are queued, new updates are
+// queued behind them so that they are applied in order.
+func (mfs *MultiFieldSet) increaseScoreOrQueue(ctx context.Context, fields map[string]float64, member string) (*UpdateResult, error) {
+	if mfs.writeQueue == nil {
+		return mfs.increaseScore(ctx, fields, member, false)
+	}
+
+	var cause error
+	if mfs.writeQueue.queue.Len() == 0 {
+		result, err := mfs.increaseScore(ctx, fields, member, false)
+		if err == nil || !DefaultRetryable(err) {
+			return result, err
+		}
+		cause = err
+	}
+
+	update := QueuedUpdate{Member: member, Fields: make(map[string]int64, len(fields)), IdempotencyKey: idempotencyKey(ctx)}
+	for name, value := range fields {
+		field := mfs.GetFieldByName(name)
+		if field == nil {
+			return nil, fieldNotFoundError(name)
+		}
+		v, err := toInt64(name, value)
+		if err != nil {
+			return nil, err
+		}
+		if _, _, err := field.rawUpdate(v); err != nil {
+			// The replay script can't apply the update, e.g. of an Average field
+			if cause != nil {
+				return nil, cause
+			}
+			return nil, err
+		}
+		update.Fields[name] = v
+	}
+	update.Seq = mfs.writeQueue.nextSeq()
+	if err := mfs.writeQueue.queue.Append(update); err != nil {
+		if cause != nil {
+			return nil, fmt.Errorf("%w (queueing failed: %v)", cause, err)
+		}
+		return nil, err
+	}
+	if cause != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUpdateQueued, cause)
+	}
+	return nil, ErrUpdateQueued
+}
+
+// ReplayQueued writes the updates queued by IncreaseScore in order and returns the number of
+// updates replayed. Each batch is applied in a transaction together with the writer's last
+// sequence number, so updates that were applied before a failure are skipped instead of applied
+// twice. Updates queued under an idempotency key are skipped if the key was recorded meanwhile, e.g.
+// by a redelivery, and record it otherwise. Like buffered updates, replayed updates don't run hooks,
+// notifications or history, and updates that no longer fit a field are dropped and reported to the
+// error hooks.
+func (mfs *MultiFieldSet) ReplayQueued(ctx context.Context) (int, error) {
+	if mfs.writeQueue == nil {
+		return 0, nil
+	}
+
+	replayed := 0
+	for {
+		batch, err := mfs.writeQueue.queue.Peek(replayBatchSize)
+		if err != nil {
+			return replayed, mfs.runOnError(ctx, "ReplayQueued", "", err)
+		}
+		if len(batch) == 0 {
+			return replayed, nil
+		}
+		if err := mfs.replayBatch(ctx, batch); err != nil {
+			return replayed, mfs.runOnError(ctx, "ReplayQueued", "", err)
+		}
+		if err := mfs.writeQueue.queue.Remove(batch[len(batch)-1].Seq); err != nil {
+			return replayed, mfs.runOnError(ctx, "ReplayQueued", "", err)
+		}
+		replayed += len(batch)
+	}
+}
+
+// StartReplay calls ReplayQueued every interval in a background goroutine until ctx is done or
+// the returned stop function is called. Errors are reported to the error hooks.
+func (mfs *MultiFieldSet) StartReplay(ctx context.Context, interval time.Duration) (stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				// ReplayQueued already reports errors to the error hooks
+				_, _ = mfs.ReplayQueued(ctx)
+			}
+		}
+	}()
+	return cancel
+}
+
+// replayBatch applies the updates of batch that are newer than the writer's last replayed
+// sequence number and whose idempotency keys aren't recorded, retrying up to optimisticRetries
+// times if the sequence number or a key changes concurrently.
+func (mfs *MultiFieldSet) replayBatch(ctx context.Context, batch []QueuedUpdate) error {
+	q := mfs.writeQueue
+	replayKey := mfs.replayKey()
+	watched := []string{replayKey}
+	if mfs.freezable {
+		watched = append(watched, mfs.frozenKey())
+	}
+	for _, update := range batch {
+		if update.IdempotencyKey != "" {
+			watched = append(watched, mfs.idempotencyRecordKey(update.IdempotencyKey))
+		}
+	}
+	for attempt := 0; attempt <= mfs.optimisticRetries; attempt++ {
+		var skipped *redis.Cmd
+		err := mfs.write(ctx, func(client redis.UniversalClient) error {
+			return client.Watch(ctx, func(tx *redis.Tx) error {
+				if err := mfs.checkFrozen(ctx, tx); err != nil {
+					return err
+				}
+				last, err := tx.HGet(ctx, replayKey, q.writerID).Uint64()
+				if err != nil && err != redis.Nil {
+					return err
+				}
+				if batch[len(batch)-1].Seq <= last {
+					return nil
+				}
+
+				var members, records []string
+				var updates []*bufferedUpdate
+				recorded := make(map[string]bool)
+				for _, update := range batch {
+					if update.Seq <= last {
+						continue
+					}
+					if update.IdempotencyKey != "" {
+						record := mfs.idempotencyRecordKey(update.IdempotencyKey)
+						if !recorded[record] {
+							n, err := tx.Exists(ctx, record).Result()
+							if err != nil {
+								return err
+							}
+							recorded[record] = n > 0
+						}
+						if recorded[record] {
+							continue
+						}
+						recorded[record] = true
+						records = append(records, record)
+					}
+					members = append(members, update.Member)
+					updates = append(updates, mfs.queuedDeltas(update))
+				}
+
+				_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+					if len(members) > 0 {
+						keys, args := mfs.applyDeltasArgs(members, updates)
+						skipped = applyDeltasScript.Eval(ctx, pipe, mfs.auditGuard(), keys, args...)
+					}
+					for _, record := range records {
+						pipe.Set(ctx, record, 1, mfs.idempotencyTTL)
+					}
+					pipe.HSet(ctx, replayKey, q.writerID, batch[len(batch)-1].Seq)
+					return nil
+				})
+				return err
+			}, watched...)
+		})
+		if err == redis.TxFailedErr {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		if skipped != nil {
+			values, _ := skipped.Slice()
+			mfs.reportSkipped(ctx, "ReplayQueued", values)
+		}
+		return nil
+	}
+	return ErrUpdateConflict
+}
+
+// queuedDeltas converts a queued update into the deltas of the apply-deltas script.
+func (mfs *MultiFieldSet) queuedDeltas(update QueuedUpdate) *bufferedUpdate {
+	n := len(mfs.fields)
+	deltas := &bufferedUpdate{deltas: make([]int64, n), set: make([]bool, n)}
+	for name, value := range update.Fields {
+		if field := mfs.GetFieldByName(name); field != nil {
+			deltas.deltas[field.position] = value
+			deltas.set[field.position] = true
+		}
+	}
+	return deltas
+}
+
+// MemoryQueue is a WriteQueue held in memory. Queued updates are lost when the process exits.
+type MemoryQueue struct {
+	mu       sync.Mutex
+	updates  []QueuedUpdate
+	capacity int
+}
+
+// NewMemoryQueue creates a MemoryQueue holding up to capacity updates. Appending to a full queue
+// fails with ErrQueueFull.
+func NewMemoryQueue(capacity int) *MemoryQueue {
+	return &MemoryQueue{capacity: capacity}
+}
+
+// Append implements WriteQueue.
+func (q *MemoryQueue) Append(update QueuedUpdate) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.updates) >= q.capacity {
+		return ErrQueueFull
+	}
+	q.updates = append(q.updates, update)
+	return nil
+}
+
+// Peek implements WriteQueue.
+func (q *MemoryQueue) Peek(n int) ([]QueuedUpdate, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if n > len(q.updates) {
+		n = len(q.updates)
+	}
+	return append([]QueuedUpdate(nil), q.updates[:n]...), nil
+}
+
+// Remove implements WriteQueue.
+func (q *MemoryQueue) Remove(seq uint64) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.updates = removeThrough(q.updates, seq)
+	return nil
+}
+
+// Len implements WriteQueue.
+func (q *MemoryQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.updates)
+}
+
+// removeThrough drops the updates at the front of updates up to and including seq.
+func removeThrough(updates []QueuedUpdate, seq uint64) []QueuedUpdate {
+	i := 0
+	for i < len(updates) && updates[i].Seq <= seq {
+		i++
+	}
+	return append([]QueuedUpdate(nil), updates[i:]...)
+}
+
+// FileQueue is a WriteQueue that keeps its updates in a file, one JSON object per line, so they
+// survive restarts. Appends are synced to disk before they return.
+type FileQueue struct {
+	mu      sync.Mutex
+	path    string
+	file    *os.File
+	updates []QueuedUpdate
+}
+
+// OpenFileQueue opens the queue file at path, creating it if it doesn't exist, and loads the
+// updates queued by an earlier process.
+func OpenFileQueue(path string) (*FileQueue, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	q := &FileQueue{path: path, file: file}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var update QueuedUpdate
+		if err := json.Unmarshal(scanner.Bytes(), &update); err != nil {
+			// A line cut short by a crash during an append was never acknowledged
+			continue
+		}
+		q.updates = append(q.updates, update)
+	}
+	if err := scanner.Err(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return q, nil
+}
+
+// Append implements WriteQueue.
+func (q *FileQueue) Append(update QueuedUpdate) error {
+	line, err := json.Marshal(update)
+	if err != nil {
+		return err
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, err := q.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	if err := q.file.Sync(); err != nil {
+		return err
+	}
+	q.updates = append(q.updates, update)
+	return nil
+}
+
+// Peek implements WriteQueue.
+func (q *FileQueue) Peek(n int) ([]QueuedUpdate, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if n > len(q.updates) {
+		n = len(q.updates)
+	}
+	return append([]QueuedUpdate(nil), q.updates[:n]...), nil
+}
+
+// Remove implements WriteQueue. It rewrites the file with the remaining updates and swaps it in.
+func (q *FileQueue) Remove(seq uint64) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	remaining := removeThrough(q.updates, seq)
+
+	tmp, err := os.CreateTemp(filepath.Dir(q.path), filepath.Base(q.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	w := bufio.NewWriter(tmp)
+	for _, update := range remaining {
+		line, err := json.Marshal(update)
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+		w.Write(append(line, '\n'))
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), q.path); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(q.path, os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	q.file.Close()
+	q.file = file
+	q.updates = remaining
+	return nil
+}
+
+// Len implements WriteQueue.
+func (q *FileQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.updates)
+}
+
+// Close closes the queue file.
+func (q *FileQueue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.file.Close()
+}
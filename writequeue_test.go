@@ -0,0 +1,178 @@
+package zmultifield
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// queueTestFields are the fields of newTestSet, for tests that need the miniredis server.
+var queueTestFields = []Field{
+	{Name: "points", Sort: Descending, MaxValue: 1000, UpdateType: Incremental},
+	{Name: "deaths", Sort: Ascending, MaxValue: 100, UpdateType: Incremental},
+}
+
+func TestWriteQueue_ReplayAfterOutage(t *testing.T) {
+	ctx := context.Background()
+	client, server := newTestClient(t)
+	mfs, err := New(MultiFieldSetOptions{
+		Name:       "test",
+		Fields:     queueTestFields,
+		Client:     client,
+		WriteQueue: &WriteQueueOptions{WriterID: "worker-1"},
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 10}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore failed: %v", err)
+	}
+
+	server.Close()
+	for i := 0; i < 3; i++ {
+		if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 5}, "alice"); !errors.Is(err, ErrUpdateQueued) {
+			t.Fatalf("IncreaseScore during an outage = %v, expected ErrUpdateQueued", err)
+		}
+	}
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 0.5}, "alice"); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("IncreaseScore of an invalid value during an outage = %v, expected ErrInvalidValue", err)
+	}
+	if err := server.Restart(); err != nil {
+		t.Fatalf("Restart failed: %v", err)
+	}
+
+	// Updates keep being queued behind the backlog until it is replayed
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 1}, "bob"); !errors.Is(err, ErrUpdateQueued) {
+		t.Errorf("IncreaseScore with a backlog = %v, expected ErrUpdateQueued", err)
+	}
+
+	replayed, err := mfs.ReplayQueued(ctx)
+	if err != nil || replayed != 4 {
+		t.Fatalf("ReplayQueued = %d, %v, expected 4 replayed updates", replayed, err)
+	}
+	if score, _ := mfs.GetScoreForField(ctx, "points", "alice"); score.Int64() != 25 {
+		t.Errorf("alice has %v points, expected 25", score)
+	}
+	if score, _ := mfs.GetScoreForField(ctx, "points", "bob"); score.Int64() != 1 {
+		t.Errorf("bob has %v points, expected 1", score)
+	}
+
+	if _, err := mfs.IncreaseScore(ctx, map[string]float64{"points": 1}, "bob"); err != nil {
+		t.Errorf("IncreaseScore after the replay failed: %v", err)
+	}
+}
+
+func TestWriteQueue_ReplayIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	queue := NewMemoryQueue(10)
+	mfs := newTestSetWithOptions(t, MultiFieldSetOptions{WriteQueue: &WriteQueueOptions{Queue: queue, WriterID: "worker-1"}})
+
+	for seq := uint64(1); seq <= 2; seq++ {
+		if err := queue.Append(QueuedUpdate{Seq: seq, Member: "alice", Fields: map[string]int64{"deaths": 3}}); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+	// Apply the first update as if the process died before removing it from the queue
+	if err := mfs.replayBatch(ctx, []QueuedUpdate{{Seq: 1, Member: "alice", Fields: map[string]int64{"deaths": 3}}}); err != nil {
+		t.Fatalf("replayBatch failed: %v", err)
+	}
+
+	if replayed, err := mfs.ReplayQueued(ctx); err != nil || replayed != 2 {
+		t.Fatalf("ReplayQueued = %d, %v, expected 2", replayed, err)
+	}
+	if score, _ := mfs.GetScoreForField(ctx, "deaths", "alice"); score.Int64() != 6 {
+		t.Errorf("alice has %v deaths, expected 6 with the first update applied once", score)
+	}
+	if queue.Len() != 0 {
+		t.Errorf("queue holds %d updates after the replay, expected none", queue.Len())
+	}
+}
+
+func TestMemoryQueue_Full(t *testing.T) {
+	queue := NewMemoryQueue(1)
+	if err := queue.Append(QueuedUpdate{Seq: 1}); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	if err := queue.Append(QueuedUpdate{Seq: 2}); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Append to a full queue = %v, expected ErrQueueFull", err)
+	}
+}
+
+func TestFileQueue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.jsonl")
+	queue, err := OpenFileQueue(path)
+	if err != nil {
+		t.Fatalf("OpenFileQueue failed: %v", err)
+	}
+	for seq := uint64(1); seq <= 3; seq++ {
+		if err := queue.Append(QueuedUpdate{Seq: seq, Member: "alice", Fields: map[string]int64{"points": int64(seq)}}); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+	if err := queue.Remove(1); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if err := queue.Append(QueuedUpdate{Seq: 4, Member: "bob"}); err != nil {
+		t.Fatalf("Append after Remove failed: %v", err)
+	}
+	if err := queue.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	reopened, err := OpenFileQueue(path)
+	if err != nil {
+		t.Fatalf("OpenFileQueue failed: %v", err)
+	}
+	defer reopened.Close()
+	updates, err := reopened.Peek(10)
+	if err != nil {
+		t.Fatalf("Peek failed: %v", err)
+	}
+	if len(updates) != 3 || updates[0].Seq != 2 || updates[0].Fields["points"] != 2 || updates[2].Member != "bob" {
+		t.Errorf("reopened queue = %+v, expected updates 2 to 4", updates)
+	}
+}
+
+func TestWriteQueue_IdempotencyKey(t *testing.T) {
+	ctx := context.Background()
+	client, server := newTestClient(t)
+	mfs, err := New(MultiFieldSetOptions{
+		Name:       "test",
+		Fields:     queueTestFields,
+		Client:     client,
+		WriteQueue: &WriteQueueOptions{WriterID: "worker-1"},
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	server.Close()
+	for _, key := range []string{"msg-1", "msg-2", "msg-2"} {
+		keyed := WithIdempotencyKey(ctx, key)
+		if _, err := mfs.IncreaseScore(keyed, map[string]float64{"points": 5}, "alice"); !errors.Is(err, ErrUpdateQueued) {
+			t.Fatalf("IncreaseScore during an outage = %v, expected ErrUpdateQueued", err)
+		}
+	}
+	if err := server.Restart(); err != nil {
+		t.Fatalf("Restart failed: %v", err)
+	}
+	// Another writer applies a redelivery of msg-1 before the replay
+	other, err := New(MultiFieldSetOptions{Name: "test", Fields: queueTestFields, Client: client})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if _, err := other.IncreaseScore(WithIdempotencyKey(ctx, "msg-1"), map[string]float64{"points": 5}, "alice"); err != nil {
+		t.Fatalf("IncreaseScore failed: %v", err)
+	}
+
+	if replayed, err := mfs.ReplayQueued(ctx); err != nil || replayed != 3 {
+		t.Fatalf("ReplayQueued = %d, %v, expected 3", replayed, err)
+	}
+	if score, _ := mfs.GetScoreForField(ctx, "points", "alice"); score.Int64() != 10 {
+		t.Errorf("alice has %v points, expected 10 with each key applied once", score)
+	}
+	if _, err := mfs.IncreaseScore(WithIdempotencyKey(ctx, "msg-2"), map[string]float64{"points": 5}, "alice"); !errors.Is(err, ErrDuplicateUpdate) {
+		t.Errorf("IncreaseScore with a replayed key = %v, expected ErrDuplicateUpdate", err)
+	}
+}
@@ -0,0 +1,179 @@
+// Package zmfgrpc exposes multi-field sorted sets over gRPC, so services written in other languages
+// can share the same bit-packing logic.
+package zmfgrpc
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative zmf.proto
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/Rohan-Muslekar/ZMultiField"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Registry holds the sets served by a Server, keyed by name.
+type Registry struct {
+	mu   sync.RWMutex
+	sets map[string]*zmultifield.MultiFieldSet
+}
+
+// NewRegistry creates a registry containing sets.
+func NewRegistry(sets ...*zmultifield.MultiFieldSet) *Registry {
+	r := &Registry{sets: make(map[string]*zmultifield.MultiFieldSet)}
+	for _, mfs := range sets {
+		r.Register(mfs)
+	}
+	return r
+}
+
+// Register adds a set under its name, replacing any set with the same name.
+func (r *Registry) Register(mfs *zmultifield.MultiFieldSet) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sets[mfs.GetName()] = mfs
+}
+
+// Get returns the set registered under name.
+func (r *Registry) Get(name string) (*zmultifield.MultiFieldSet, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	mfs, ok := r.sets[name]
+	return mfs, ok
+}
+
+// Server implements MultiFieldSetServiceServer backed by a Registry.
+type Server struct {
+	UnimplementedMultiFieldSetServiceServer
+	registry *Registry
+}
+
+// NewServer creates a server for the sets in registry.
+func NewServer(registry *Registry) *Server {
+	return &Server{registry: registry}
+}
+
+// set returns the named set, or a NotFound status.
+func (s *Server) set(name string) (*zmultifield.MultiFieldSet, error) {
+	mfs, ok := s.registry.Get(name)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "unknown set %q", name)
+	}
+	return mfs, nil
+}
+
+// Update applies field deltas to a member and returns its new scores. An update queued for replay
+// by a set's write queue succeeds without scores, so clients don't retry and apply it twice.
+func (s *Server) Update(ctx context.Context, req *UpdateRequest) (*UpdateResponse, error) {
+	mfs, err := s.set(req.GetSet())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := mfs.IncreaseScore(ctx, req.GetDeltas(), req.GetMember()); errors.Is(err, zmultifield.ErrUpdateQueued) {
+		return &UpdateResponse{}, nil
+	} else if err != nil {
+		return nil, toStatus(err)
+	}
+	scores, err := mfs.GetScores(ctx, req.GetMember())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &UpdateResponse{Scores: toFieldScores(scores)}, nil
+}
+
+// GetScores returns the scores of a member.
+func (s *Server) GetScores(ctx context.Context, req *GetScoresRequest) (*GetScoresResponse, error) {
+	mfs, err := s.set(req.GetSet())
+	if err != nil {
+		return nil, err
+	}
+	scores, err := mfs.GetScores(ctx, req.GetMember())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &GetScoresResponse{Scores: toFieldScores(scores)}, nil
+}
+
+// GetTop returns the top members of a set.
+func (s *Server) GetTop(ctx context.Context, req *GetTopRequest) (*GetTopResponse, error) {
+	mfs, err := s.set(req.GetSet())
+	if err != nil {
+		return nil, err
+	}
+	if req.GetLimit() < 1 {
+		return nil, status.Error(codes.InvalidArgument, "limit must be positive")
+	}
+	members, err := mfs.GetTopMembers(ctx, req.GetLimit())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	entries := make([]*Entry, len(members))
+	for i, m := range members {
+		entries[i] = &Entry{Rank: int64(i), Member: m.Member, Scores: toFieldScores(m.Scores)}
+	}
+	return &GetTopResponse{Entries: entries}, nil
+}
+
+// GetRank returns the rank of a member.
+func (s *Server) GetRank(ctx context.Context, req *GetRankRequest) (*GetRankResponse, error) {
+	mfs, err := s.set(req.GetSet())
+	if err != nil {
+		return nil, err
+	}
+	rank, err := mfs.GetRank(ctx, req.GetMember())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &GetRankResponse{Rank: rank}, nil
+}
+
+// GetAround returns a member and its neighbours.
+func (s *Server) GetAround(ctx context.Context, req *GetAroundRequest) (*GetAroundResponse, error) {
+	mfs, err := s.set(req.GetSet())
+	if err != nil {
+		return nil, err
+	}
+	if req.GetRadius() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "radius must not be negative")
+	}
+	around, err := mfs.GetMembersAround(ctx, req.GetMember(), req.GetRadius())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	entries := make([]*Entry, len(around))
+	for i, e := range around {
+		entries[i] = &Entry{Rank: e.Rank, Member: e.Member, Scores: toFieldScores(e.Scores)}
+	}
+	return &GetAroundResponse{Entries: entries}, nil
+}
+
+// toFieldScores converts library scores to their protobuf form.
+func toFieldScores(scores []zmultifield.FieldScore) []*FieldScore {
+	converted := make([]*FieldScore, len(scores))
+	for i, fs := range scores {
+		converted[i] = &FieldScore{Name: fs.Name, Score: fs.Int64()}
+	}
+	return converted
+}
+
+// toStatus maps library errors to gRPC status codes.
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, zmultifield.ErrMemberNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, zmultifield.ErrFieldNotFound), errors.Is(err, zmultifield.ErrUnknownUpdateType),
+		errors.Is(err, zmultifield.ErrInvalidValue):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, zmultifield.ErrScoreOutOfRange):
+		return status.Error(codes.OutOfRange, err.Error())
+	case errors.Is(err, zmultifield.ErrUnrepresentableScore):
+		return status.Error(codes.DataLoss, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
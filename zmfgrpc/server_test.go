@@ -0,0 +1,132 @@
+package zmfgrpc
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/Rohan-Muslekar/ZMultiField"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestClient starts a server for a "board" set backed by miniredis and returns a client for it.
+func newTestClient(t *testing.T) MultiFieldSetServiceClient {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	mfs, err := zmultifield.New(zmultifield.MultiFieldSetOptions{
+		Name: "board",
+		Fields: []zmultifield.Field{
+			{Name: "points", Sort: zmultifield.Descending, MaxValue: 1000, UpdateType: zmultifield.Incremental},
+			{Name: "deaths", Sort: zmultifield.Ascending, MaxValue: 100, UpdateType: zmultifield.Incremental},
+		},
+		Client: rdb,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create MultiFieldSet: %v", err)
+	}
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	RegisterMultiFieldSetServiceServer(srv, NewServer(NewRegistry(mfs)))
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return NewMultiFieldSetServiceClient(conn)
+}
+
+func TestServer(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	for member, points := range map[string]float64{"alice": 30, "bob": 20, "carol": 10} {
+		resp, err := client.Update(ctx, &UpdateRequest{Set: "board", Member: member, Deltas: map[string]float64{"points": points}})
+		if err != nil {
+			t.Fatalf("Update() error = %v", err)
+		}
+		if resp.Scores[0].Score != int64(points) {
+			t.Errorf("Update() scores = %v, expected %v points", resp.Scores, points)
+		}
+	}
+
+	scores, err := client.GetScores(ctx, &GetScoresRequest{Set: "board", Member: "bob"})
+	if err != nil {
+		t.Fatalf("GetScores() error = %v", err)
+	}
+	if scores.Scores[0].Name != "points" || scores.Scores[0].Score != 20 {
+		t.Errorf("GetScores() = %v, expected 20 points", scores.Scores)
+	}
+
+	top, err := client.GetTop(ctx, &GetTopRequest{Set: "board", Limit: 2})
+	if err != nil {
+		t.Fatalf("GetTop() error = %v", err)
+	}
+	if len(top.Entries) != 2 || top.Entries[0].Member != "alice" || top.Entries[1].Rank != 1 {
+		t.Errorf("GetTop() = %v, expected alice then bob", top.Entries)
+	}
+
+	rank, err := client.GetRank(ctx, &GetRankRequest{Set: "board", Member: "carol"})
+	if err != nil {
+		t.Fatalf("GetRank() error = %v", err)
+	}
+	if rank.Rank != 2 {
+		t.Errorf("GetRank() = %d, expected 2", rank.Rank)
+	}
+
+	around, err := client.GetAround(ctx, &GetAroundRequest{Set: "board", Member: "carol", Radius: 1})
+	if err != nil {
+		t.Fatalf("GetAround() error = %v", err)
+	}
+	if len(around.Entries) != 2 || around.Entries[0].Member != "bob" || around.Entries[0].Rank != 1 {
+		t.Errorf("GetAround() = %v, expected bob and carol", around.Entries)
+	}
+}
+
+func TestServer_Errors(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		call func() error
+		code codes.Code
+	}{
+		{"unknown set", func() error {
+			_, err := client.GetRank(ctx, &GetRankRequest{Set: "missing", Member: "alice"})
+			return err
+		}, codes.NotFound},
+		{"unknown member", func() error {
+			_, err := client.GetRank(ctx, &GetRankRequest{Set: "board", Member: "nobody"})
+			return err
+		}, codes.NotFound},
+		{"unknown field", func() error {
+			_, err := client.Update(ctx, &UpdateRequest{Set: "board", Member: "alice", Deltas: map[string]float64{"kills": 1}})
+			return err
+		}, codes.InvalidArgument},
+		{"out of range", func() error {
+			_, err := client.Update(ctx, &UpdateRequest{Set: "board", Member: "alice", Deltas: map[string]float64{"points": 5000}})
+			return err
+		}, codes.OutOfRange},
+	}
+	for _, test := range tests {
+		if code := status.Code(test.call()); code != test.code {
+			t.Errorf("%s: code = %v, expected %v", test.name, code, test.code)
+		}
+	}
+}
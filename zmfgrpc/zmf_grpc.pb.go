@@ -0,0 +1,287 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: zmf.proto
+
+package zmfgrpc
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	MultiFieldSetService_Update_FullMethodName    = "/zmf.v1.MultiFieldSetService/Update"
+	MultiFieldSetService_GetScores_FullMethodName = "/zmf.v1.MultiFieldSetService/GetScores"
+	MultiFieldSetService_GetTop_FullMethodName    = "/zmf.v1.MultiFieldSetService/GetTop"
+	MultiFieldSetService_GetRank_FullMethodName   = "/zmf.v1.MultiFieldSetService/GetRank"
+	MultiFieldSetService_GetAround_FullMethodName = "/zmf.v1.MultiFieldSetService/GetAround"
+)
+
+// MultiFieldSetServiceClient is the client API for MultiFieldSetService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// MultiFieldSetService exposes the multi-field sorted sets registered with a server.
+type MultiFieldSetServiceClient interface {
+	// Update applies field deltas to a member, following each field's update type.
+	Update(ctx context.Context, in *UpdateRequest, opts ...grpc.CallOption) (*UpdateResponse, error)
+	// GetScores returns the scores of a member. Missing members have default scores.
+	GetScores(ctx context.Context, in *GetScoresRequest, opts ...grpc.CallOption) (*GetScoresResponse, error)
+	// GetTop returns the top members of a set.
+	GetTop(ctx context.Context, in *GetTopRequest, opts ...grpc.CallOption) (*GetTopResponse, error)
+	// GetRank returns the 0-based rank of a member.
+	GetRank(ctx context.Context, in *GetRankRequest, opts ...grpc.CallOption) (*GetRankResponse, error)
+	// GetAround returns a member and the members ranked directly above and below it.
+	GetAround(ctx context.Context, in *GetAroundRequest, opts ...grpc.CallOption) (*GetAroundResponse, error)
+}
+
+type multiFieldSetServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewMultiFieldSetServiceClient(cc grpc.ClientConnInterface) MultiFieldSetServiceClient {
+	return &multiFieldSetServiceClient{cc}
+}
+
+func (c *multiFieldSetServiceClient) Update(ctx context.Context, in *UpdateRequest, opts ...grpc.CallOption) (*UpdateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UpdateResponse)
+	err := c.cc.Invoke(ctx, MultiFieldSetService_Update_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *multiFieldSetServiceClient) GetScores(ctx context.Context, in *GetScoresRequest, opts ...grpc.CallOption) (*GetScoresResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetScoresResponse)
+	err := c.cc.Invoke(ctx, MultiFieldSetService_GetScores_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *multiFieldSetServiceClient) GetTop(ctx context.Context, in *GetTopRequest, opts ...grpc.CallOption) (*GetTopResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetTopResponse)
+	err := c.cc.Invoke(ctx, MultiFieldSetService_GetTop_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *multiFieldSetServiceClient) GetRank(ctx context.Context, in *GetRankRequest, opts ...grpc.CallOption) (*GetRankResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetRankResponse)
+	err := c.cc.Invoke(ctx, MultiFieldSetService_GetRank_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *multiFieldSetServiceClient) GetAround(ctx context.Context, in *GetAroundRequest, opts ...grpc.CallOption) (*GetAroundResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetAroundResponse)
+	err := c.cc.Invoke(ctx, MultiFieldSetService_GetAround_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MultiFieldSetServiceServer is the server API for MultiFieldSetService service.
+// All implementations must embed UnimplementedMultiFieldSetServiceServer
+// for forward compatibility.
+//
+// MultiFieldSetService exposes the multi-field sorted sets registered with a server.
+type MultiFieldSetServiceServer interface {
+	// Update applies field deltas to a member, following each field's update type.
+	Update(context.Context, *UpdateRequest) (*UpdateResponse, error)
+	// GetScores returns the scores of a member. Missing members have default scores.
+	GetScores(context.Context, *GetScoresRequest) (*GetScoresResponse, error)
+	// GetTop returns the top members of a set.
+	GetTop(context.Context, *GetTopRequest) (*GetTopResponse, error)
+	// GetRank returns the 0-based rank of a member.
+	GetRank(context.Context, *GetRankRequest) (*GetRankResponse, error)
+	// GetAround returns a member and the members ranked directly above and below it.
+	GetAround(context.Context, *GetAroundRequest) (*GetAroundResponse, error)
+	mustEmbedUnimplementedMultiFieldSetServiceServer()
+}
+
+// UnimplementedMultiFieldSetServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedMultiFieldSetServiceServer struct{}
+
+func (UnimplementedMultiFieldSetServiceServer) Update(context.Context, *UpdateRequest) (*UpdateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Update not implemented")
+}
+func (UnimplementedMultiFieldSetServiceServer) GetScores(context.Context, *GetScoresRequest) (*GetScoresResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetScores not implemented")
+}
+func (UnimplementedMultiFieldSetServiceServer) GetTop(context.Context, *GetTopRequest) (*GetTopResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTop not implemented")
+}
+func (UnimplementedMultiFieldSetServiceServer) GetRank(context.Context, *GetRankRequest) (*GetRankResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRank not implemented")
+}
+func (UnimplementedMultiFieldSetServiceServer) GetAround(context.Context, *GetAroundRequest) (*GetAroundResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAround not implemented")
+}
+func (UnimplementedMultiFieldSetServiceServer) mustEmbedUnimplementedMultiFieldSetServiceServer() {}
+func (UnimplementedMultiFieldSetServiceServer) testEmbeddedByValue()                              {}
+
+// UnsafeMultiFieldSetServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to MultiFieldSetServiceServer will
+// result in compilation errors.
+type UnsafeMultiFieldSetServiceServer interface {
+	mustEmbedUnimplementedMultiFieldSetServiceServer()
+}
+
+func RegisterMultiFieldSetServiceServer(s grpc.ServiceRegistrar, srv MultiFieldSetServiceServer) {
+	// If the following call pancis, it indicates UnimplementedMultiFieldSetServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&MultiFieldSetService_ServiceDesc, srv)
+}
+
+func _MultiFieldSetService_Update_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MultiFieldSetServiceServer).Update(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MultiFieldSetService_Update_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MultiFieldSetServiceServer).Update(ctx, req.(*UpdateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MultiFieldSetService_GetScores_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetScoresRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MultiFieldSetServiceServer).GetScores(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MultiFieldSetService_GetScores_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MultiFieldSetServiceServer).GetScores(ctx, req.(*GetScoresRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MultiFieldSetService_GetTop_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTopRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MultiFieldSetServiceServer).GetTop(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MultiFieldSetService_GetTop_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MultiFieldSetServiceServer).GetTop(ctx, req.(*GetTopRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MultiFieldSetService_GetRank_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRankRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MultiFieldSetServiceServer).GetRank(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MultiFieldSetService_GetRank_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MultiFieldSetServiceServer).GetRank(ctx, req.(*GetRankRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MultiFieldSetService_GetAround_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAroundRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MultiFieldSetServiceServer).GetAround(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MultiFieldSetService_GetAround_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MultiFieldSetServiceServer).GetAround(ctx, req.(*GetAroundRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// MultiFieldSetService_ServiceDesc is the grpc.ServiceDesc for MultiFieldSetService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var MultiFieldSetService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "zmf.v1.MultiFieldSetService",
+	HandlerType: (*MultiFieldSetServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Update",
+			Handler:    _MultiFieldSetService_Update_Handler,
+		},
+		{
+			MethodName: "GetScores",
+			Handler:    _MultiFieldSetService_GetScores_Handler,
+		},
+		{
+			MethodName: "GetTop",
+			Handler:    _MultiFieldSetService_GetTop_Handler,
+		},
+		{
+			MethodName: "GetRank",
+			Handler:    _MultiFieldSetService_GetRank_Handler,
+		},
+		{
+			MethodName: "GetAround",
+			Handler:    _MultiFieldSetService_GetAround_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "zmf.proto",
+}
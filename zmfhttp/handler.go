@@ -0,0 +1,247 @@
+// Package zmfhttp exposes multi-field sorted sets as a JSON HTTP API, so a leaderboard service can
+// be stood up without writing handlers by hand.
+//
+// The handler serves:
+//
+//	GET  /sets                                   names of the served sets
+//	GET  /sets/{set}/schema                      field definitions of a set
+//	GET  /sets/{set}/top?limit=10                top members with ranks
+//	GET  /sets/{set}/members/{member}            rank and scores of a member
+//	POST /sets/{set}/members/{member}/increment  apply {"field": delta, ...} to a member
+package zmfhttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/Rohan-Muslekar/ZMultiField"
+)
+
+// defaultTopLimit is the number of members returned by the top endpoint without a limit parameter.
+const defaultTopLimit = 10
+
+// maxTopLimit caps the limit parameter of the top endpoint.
+const maxTopLimit = 1000
+
+// Middleware wraps a handler, e.g. to authenticate requests.
+type Middleware func(http.Handler) http.Handler
+
+// Options configures a Handler.
+type Options struct {
+	// ReadAuth optionally wraps the read endpoints.
+	ReadAuth Middleware
+	// WriteAuth optionally wraps the increment endpoint.
+	WriteAuth Middleware
+}
+
+// Handler serves the JSON API for a fixed collection of sets.
+type Handler struct {
+	mux  *http.ServeMux
+	sets map[string]*zmultifield.MultiFieldSet
+}
+
+// NewHandler creates a handler serving sets, keyed by their names.
+func NewHandler(opts Options, sets ...*zmultifield.MultiFieldSet) *Handler {
+	h := &Handler{mux: http.NewServeMux(), sets: make(map[string]*zmultifield.MultiFieldSet)}
+	for _, mfs := range sets {
+		h.sets[mfs.GetName()] = mfs
+	}
+
+	read, write := opts.ReadAuth, opts.WriteAuth
+	if read == nil {
+		read = passThrough
+	}
+	if write == nil {
+		write = passThrough
+	}
+
+	h.mux.Handle("GET /sets", read(http.HandlerFunc(h.listSets)))
+	h.mux.Handle("GET /sets/{set}/schema", read(http.HandlerFunc(h.schema)))
+	h.mux.Handle("GET /sets/{set}/top", read(http.HandlerFunc(h.top)))
+	h.mux.Handle("GET /sets/{set}/members/{member}", read(http.HandlerFunc(h.member)))
+	h.mux.Handle("POST /sets/{set}/members/{member}/increment", write(http.HandlerFunc(h.increment)))
+	return h
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// BearerToken returns middleware that rejects requests without the header "Authorization: Bearer <token>".
+func BearerToken(token string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer "+token {
+				writeError(w, http.StatusUnauthorized, errors.New("unauthorized"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// passThrough is the middleware used when no auth is configured.
+func passThrough(next http.Handler) http.Handler {
+	return next
+}
+
+// FieldSchema describes a field in the schema endpoint.
+type FieldSchema struct {
+	Name       string   `json:"name"`
+	Sort       string   `json:"sort"`
+	UpdateType string   `json:"updateType"`
+	MaxValue   *float64 `json:"maxValue,omitempty"`
+	Bits       uint64   `json:"bits"`
+}
+
+// MemberDetails is the response of the member endpoint.
+type MemberDetails struct {
+	Member string                   `json:"member"`
+	Rank   int64                    `json:"rank"`
+	Scores []zmultifield.FieldScore `json:"scores"`
+}
+
+// listSets writes the sorted names of the served sets.
+func (h *Handler) listSets(w http.ResponseWriter, r *http.Request) {
+	names := make([]string, 0, len(h.sets))
+	for name := range h.sets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	writeJSON(w, http.StatusOK, names)
+}
+
+// schema writes the field definitions of a set.
+func (h *Handler) schema(w http.ResponseWriter, r *http.Request) {
+	mfs, ok := h.set(w, r)
+	if !ok {
+		return
+	}
+	fields := mfs.GetFieldsInfo()
+	schema := make([]FieldSchema, len(fields))
+	for i, f := range fields {
+		order := "asc"
+		if f.Sort == zmultifield.Descending {
+			order = "desc"
+		}
+		schema[i] = FieldSchema{Name: f.Name, Sort: order, UpdateType: f.UpdateType, Bits: f.Bits}
+		// an unbounded main field has no JSON representation
+		if !math.IsInf(f.MaxValue, 0) {
+			max := f.MaxValue
+			schema[i].MaxValue = &max
+		}
+	}
+	writeJSON(w, http.StatusOK, schema)
+}
+
+// top writes the top members of a set.
+func (h *Handler) top(w http.ResponseWriter, r *http.Request) {
+	mfs, ok := h.set(w, r)
+	if !ok {
+		return
+	}
+	limit := int64(defaultTopLimit)
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || n < 1 || n > maxTopLimit {
+			writeError(w, http.StatusBadRequest, errors.New("limit must be between 1 and 1000"))
+			return
+		}
+		limit = n
+	}
+
+	page, err := mfs.GetPage(r.Context(), 1, limit)
+	if err != nil {
+		writeLibraryError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// member writes the rank and scores of a member.
+func (h *Handler) member(w http.ResponseWriter, r *http.Request) {
+	mfs, ok := h.set(w, r)
+	if !ok {
+		return
+	}
+	h.writeMember(r.Context(), w, mfs, r.PathValue("member"))
+}
+
+// increment applies the deltas in the request body to a member and writes its new details. An
+// update queued for replay by a set's write queue is answered with 202 Accepted instead.
+func (h *Handler) increment(w http.ResponseWriter, r *http.Request) {
+	mfs, ok := h.set(w, r)
+	if !ok {
+		return
+	}
+	var deltas map[string]float64
+	if err := json.NewDecoder(r.Body).Decode(&deltas); err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("body must be a JSON object of field deltas"))
+		return
+	}
+
+	member := r.PathValue("member")
+	if _, err := mfs.IncreaseScore(r.Context(), deltas, member); errors.Is(err, zmultifield.ErrUpdateQueued) {
+		writeJSON(w, http.StatusAccepted, map[string]string{"status": "queued"})
+		return
+	} else if err != nil {
+		writeLibraryError(w, err)
+		return
+	}
+	h.writeMember(r.Context(), w, mfs, member)
+}
+
+// writeMember writes the details of a member.
+func (h *Handler) writeMember(ctx context.Context, w http.ResponseWriter, mfs *zmultifield.MultiFieldSet, member string) {
+	rank, err := mfs.GetRank(ctx, member)
+	if err != nil {
+		writeLibraryError(w, err)
+		return
+	}
+	scores, err := mfs.GetScores(ctx, member)
+	if err != nil {
+		writeLibraryError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, MemberDetails{Member: member, Rank: rank, Scores: scores})
+}
+
+// set returns the set named in the request path, writing a 404 if it isn't served.
+func (h *Handler) set(w http.ResponseWriter, r *http.Request) (*zmultifield.MultiFieldSet, bool) {
+	mfs, ok := h.sets[r.PathValue("set")]
+	if !ok {
+		writeError(w, http.StatusNotFound, errors.New("unknown set"))
+	}
+	return mfs, ok
+}
+
+// writeLibraryError writes err with a status code matching its cause.
+func writeLibraryError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, zmultifield.ErrMemberNotFound):
+		writeError(w, http.StatusNotFound, err)
+	case errors.Is(err, zmultifield.ErrFieldNotFound), errors.Is(err, zmultifield.ErrScoreOutOfRange),
+		errors.Is(err, zmultifield.ErrInvalidValue):
+		writeError(w, http.StatusBadRequest, err)
+	default:
+		writeError(w, http.StatusInternalServerError, err)
+	}
+}
+
+// writeError writes {"error": "..."} with the given status code.
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
+
+// writeJSON writes v as JSON with the given status code.
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,170 @@
+package zmfhttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Rohan-Muslekar/ZMultiField"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// newTestServer starts a server for a "board" set backed by miniredis.
+func newTestServer(t *testing.T, opts Options) *httptest.Server {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	mfs, err := zmultifield.New(zmultifield.MultiFieldSetOptions{
+		Name: "board",
+		Fields: []zmultifield.Field{
+			{Name: "points", Sort: zmultifield.Descending, MaxValue: 1000, UpdateType: zmultifield.Incremental},
+			{Name: "deaths", Sort: zmultifield.Ascending, MaxValue: 100, UpdateType: zmultifield.Incremental},
+		},
+		Client: rdb,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create MultiFieldSet: %v", err)
+	}
+
+	srv := httptest.NewServer(NewHandler(opts, mfs))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// do sends a request and decodes the JSON response into v, returning the status code.
+func do(t *testing.T, method, url, token, body string, v interface{}) int {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("NewRequest() error = %v", err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s error = %v", method, url, err)
+	}
+	defer resp.Body.Close()
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("decoding %s %s response: %v", method, url, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestHandler(t *testing.T) {
+	srv := newTestServer(t, Options{})
+
+	var details struct {
+		Member string
+		Rank   int64
+		Scores []struct {
+			Name  string
+			Score int64
+		}
+	}
+	if code := do(t, "POST", srv.URL+"/sets/board/members/alice/increment", "", `{"points": 30}`, &details); code != http.StatusOK {
+		t.Fatalf("increment status = %d, expected 200", code)
+	}
+	if details.Rank != 0 || details.Scores[0].Score != 30 {
+		t.Errorf("increment response = %+v, expected rank 0 with 30 points", details)
+	}
+	do(t, "POST", srv.URL+"/sets/board/members/bob/increment", "", `{"points": 50}`, nil)
+
+	if code := do(t, "GET", srv.URL+"/sets/board/members/alice", "", "", &details); code != http.StatusOK || details.Rank != 1 {
+		t.Errorf("member = %d %+v, expected alice at rank 1", code, details)
+	}
+
+	var raw struct {
+		Entries []struct {
+			Rank   int64
+			Member string
+		}
+		TotalMembers int64
+	}
+	if code := do(t, "GET", srv.URL+"/sets/board/top?limit=1", "", "", &raw); code != http.StatusOK {
+		t.Fatalf("top status = %d, expected 200", code)
+	}
+	if raw.TotalMembers != 2 || len(raw.Entries) != 1 || raw.Entries[0].Member != "bob" {
+		t.Errorf("top = %+v, expected bob of 2 members", raw)
+	}
+
+	var schema []FieldSchema
+	if code := do(t, "GET", srv.URL+"/sets/board/schema", "", "", &schema); code != http.StatusOK {
+		t.Fatalf("schema status = %d, expected 200", code)
+	}
+	if len(schema) != 2 || schema[0].Name != "points" || schema[0].Sort != "desc" || *schema[0].MaxValue != 1000 {
+		t.Errorf("schema = %+v, expected points desc with max 1000 first", schema)
+	}
+
+	var sets []string
+	if do(t, "GET", srv.URL+"/sets", "", "", &sets); len(sets) != 1 || sets[0] != "board" {
+		t.Errorf("sets = %v, expected [board]", sets)
+	}
+}
+
+func TestHandler_Errors(t *testing.T) {
+	srv := newTestServer(t, Options{})
+
+	tests := []struct {
+		method, path, body string
+		code               int
+	}{
+		{"GET", "/sets/missing/top", "", http.StatusNotFound},
+		{"GET", "/sets/board/members/nobody", "", http.StatusNotFound},
+		{"GET", "/sets/board/top?limit=0", "", http.StatusBadRequest},
+		{"POST", "/sets/board/members/alice/increment", `not json`, http.StatusBadRequest},
+		{"POST", "/sets/board/members/alice/increment", `{"kills": 1}`, http.StatusBadRequest},
+		{"POST", "/sets/board/members/alice/increment", `{"points": 5000}`, http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		var body map[string]string
+		if code := do(t, test.method, srv.URL+test.path, "", test.body, &body); code != test.code || body["error"] == "" {
+			t.Errorf("%s %s = %d %v, expected %d with an error", test.method, test.path, code, body, test.code)
+		}
+	}
+}
+
+func TestHandler_Auth(t *testing.T) {
+	srv := newTestServer(t, Options{WriteAuth: BearerToken("secret")})
+
+	if code := do(t, "POST", srv.URL+"/sets/board/members/alice/increment", "", `{"points": 1}`, nil); code != http.StatusUnauthorized {
+		t.Errorf("unauthenticated increment status = %d, expected 401", code)
+	}
+	if code := do(t, "POST", srv.URL+"/sets/board/members/alice/increment", "secret", `{"points": 1}`, nil); code != http.StatusOK {
+		t.Errorf("authenticated increment status = %d, expected 200", code)
+	}
+	if code := do(t, "GET", srv.URL+"/sets/board/top", "", "", nil); code != http.StatusOK {
+		t.Errorf("unauthenticated read status = %d, expected 200", code)
+	}
+}
+
+func TestHandler_QueuedUpdate(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	mfs, err := zmultifield.New(zmultifield.MultiFieldSetOptions{
+		Name:       "board",
+		Fields:     []zmultifield.Field{{Name: "points", Sort: zmultifield.Descending, MaxValue: 1000, UpdateType: zmultifield.Incremental}},
+		Client:     rdb,
+		WriteQueue: &zmultifield.WriteQueueOptions{WriterID: "test"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create MultiFieldSet: %v", err)
+	}
+	srv := httptest.NewServer(NewHandler(Options{}, mfs))
+	t.Cleanup(srv.Close)
+
+	mr.Close()
+	var body map[string]string
+	if code := do(t, "POST", srv.URL+"/sets/board/members/alice/increment", "", `{"points": 30}`, &body); code != http.StatusAccepted || body["status"] != "queued" {
+		t.Errorf("increment during an outage = %d %v, expected 202 queued", code, body)
+	}
+}
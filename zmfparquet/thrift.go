@@ -0,0 +1,89 @@
+package zmfparquet
+
+import "encoding/binary"
+
+// Thrift compact protocol field types.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes structs with the Thrift compact protocol, in which the Parquet metadata
+// and page headers are written.
+type thriftWriter struct {
+	buf []byte
+	// lastField holds the id of the last field written in each open struct.
+	lastField []int16
+}
+
+// field writes the header of a field of the current struct.
+func (w *thriftWriter) field(id int16, typ byte) {
+	last := &w.lastField[len(w.lastField)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		w.buf = append(w.buf, byte(delta)<<4|typ)
+	} else {
+		w.buf = append(w.buf, typ)
+		w.buf = binary.AppendVarint(w.buf, int64(id))
+	}
+	*last = id
+}
+
+// begin opens a struct, either at the top level or as the value of a field or list element.
+func (w *thriftWriter) begin() {
+	w.lastField = append(w.lastField, 0)
+}
+
+// end closes the current struct.
+func (w *thriftWriter) end() {
+	w.buf = append(w.buf, 0)
+	w.lastField = w.lastField[:len(w.lastField)-1]
+}
+
+// structField opens a struct as the value of a field.
+func (w *thriftWriter) structField(id int16) {
+	w.field(id, thriftStruct)
+	w.begin()
+}
+
+func (w *thriftWriter) i32(id int16, v int32) {
+	w.field(id, thriftI32)
+	w.buf = binary.AppendVarint(w.buf, int64(v))
+}
+
+func (w *thriftWriter) i64(id int16, v int64) {
+	w.field(id, thriftI64)
+	w.buf = binary.AppendVarint(w.buf, v)
+}
+
+func (w *thriftWriter) string(id int16, v string) {
+	w.field(id, thriftBinary)
+	w.str(v)
+}
+
+// str writes a string value without a field header, e.g. as a list element.
+func (w *thriftWriter) str(v string) {
+	w.buf = binary.AppendUvarint(w.buf, uint64(len(v)))
+	w.buf = append(w.buf, v...)
+}
+
+// list writes the header of a list field of n elements of type elem, which follow.
+func (w *thriftWriter) list(id int16, elem byte, n int) {
+	w.field(id, thriftList)
+	if n < 15 {
+		w.buf = append(w.buf, byte(n)<<4|elem)
+	} else {
+		w.buf = append(w.buf, 0xf0|elem)
+		w.buf = binary.AppendUvarint(w.buf, uint64(n))
+	}
+}
+
+// i32s writes a list field of i32 values, such as enums.
+func (w *thriftWriter) i32s(id int16, values ...int32) {
+	w.list(id, thriftI32, len(values))
+	for _, v := range values {
+		w.buf = binary.AppendVarint(w.buf, int64(v))
+	}
+}
@@ -0,0 +1,260 @@
+// Package zmfparquet writes ZMultiField exports as Parquet files for analytics ingestion.
+package zmfparquet
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/Rohan-Muslekar/ZMultiField"
+)
+
+// DefaultRowGroupSize is the number of rows per row group when Options.RowGroupSize is not set.
+const DefaultRowGroupSize = 100000
+
+// Parquet physical types, encodings and converted types used by the writer.
+const (
+	typeInt64     = 2
+	typeDouble    = 5
+	typeByteArray = 6
+
+	encodingPlain = 0
+	encodingRLE   = 3
+
+	convertedUTF8 = 0
+)
+
+// magic starts and ends every Parquet file.
+const magic = "PAR1"
+
+// Options configures a Writer.
+type Options struct {
+	// RowGroupSize is the number of rows buffered in memory and written as one row group.
+	// Defaults to DefaultRowGroupSize.
+	RowGroupSize int
+}
+
+// Writer writes the rows of a zmultifield export as a Parquet file with a member column, a rank
+// column and a column per field. Stored fields are INT64 columns and computed fields DOUBLE
+// columns. Columns are required, uncompressed and PLAIN encoded, so any Parquet or Arrow reader
+// can load the file. It implements zmultifield.ExportWriter:
+//
+//	f, err := os.Create("leaderboard.parquet")
+//	// ...
+//	n, err := leaderboard.Export(ctx, zmfparquet.NewWriter(f, zmfparquet.Options{}))
+type Writer struct {
+	w       io.Writer
+	opts    Options
+	offset  int64
+	columns []column
+	groups  []rowGroup
+	rows    int
+	total   int64
+	begun   bool
+	ended   bool
+}
+
+// column buffers the PLAIN encoded values of a column in the current row group.
+type column struct {
+	name string
+	typ  int32
+	utf8 bool
+	data []byte
+}
+
+// rowGroup records where a written row group's column chunks are.
+type rowGroup struct {
+	chunks []chunk
+	rows   int64
+	size   int64
+}
+
+// chunk records where a column chunk, made of a single data page, is.
+type chunk struct {
+	offset int64
+	size   int64
+}
+
+// NewWriter returns a Writer writing a Parquet file to w. The file is complete once End returns.
+func NewWriter(w io.Writer, opts Options) *Writer {
+	if opts.RowGroupSize <= 0 {
+		opts.RowGroupSize = DefaultRowGroupSize
+	}
+	return &Writer{w: w, opts: opts}
+}
+
+// Begin implements zmultifield.ExportWriter and writes the start of the file.
+func (w *Writer) Begin(columns []zmultifield.ExportColumn) error {
+	if w.begun {
+		return errors.New("zmfparquet: Begin called twice")
+	}
+
+	cols := []column{{name: "member", typ: typeByteArray, utf8: true}, {name: "rank", typ: typeInt64}}
+	seen := map[string]bool{"member": true, "rank": true}
+	for _, c := range columns {
+		if seen[c.Name] {
+			return fmt.Errorf("zmfparquet: duplicate column %s", c.Name)
+		}
+		seen[c.Name] = true
+		typ := int32(typeInt64)
+		if c.Computed {
+			typ = typeDouble
+		}
+		cols = append(cols, column{name: c.Name, typ: typ})
+	}
+	w.columns, w.begun = cols, true
+	return w.write([]byte(magic))
+}
+
+// Write implements zmultifield.ExportWriter, buffering the row and writing a row group once
+// RowGroupSize rows are buffered.
+func (w *Writer) Write(row zmultifield.ExportRow) error {
+	if !w.begun || w.ended {
+		return errors.New("zmfparquet: Write called outside of Begin and End")
+	}
+	if len(row.Scores) != len(w.columns)-2 {
+		return fmt.Errorf("zmfparquet: member %s has %d scores, expected %d", row.Member, len(row.Scores), len(w.columns)-2)
+	}
+	for i, score := range row.Scores {
+		if score.Score != nil && !score.Score.IsInt64() {
+			return fmt.Errorf("zmfparquet: score %v of field %s of member %s doesn't fit in an INT64 column", score.Score, score.Name, row.Member)
+		}
+		if (score.Score == nil) != (w.columns[i+2].typ == typeDouble) {
+			return fmt.Errorf("zmfparquet: field %s of member %s doesn't match its column", score.Name, row.Member)
+		}
+	}
+
+	member := &w.columns[0]
+	member.data = binary.LittleEndian.AppendUint32(member.data, uint32(len(row.Member)))
+	member.data = append(member.data, row.Member...)
+	w.columns[1].data = binary.LittleEndian.AppendUint64(w.columns[1].data, uint64(row.Rank))
+	for i, score := range row.Scores {
+		c := &w.columns[i+2]
+		if score.Score == nil {
+			c.data = binary.LittleEndian.AppendUint64(c.data, math.Float64bits(score.Value))
+		} else {
+			c.data = binary.LittleEndian.AppendUint64(c.data, uint64(score.Score.Int64()))
+		}
+	}
+
+	w.rows++
+	if w.rows >= w.opts.RowGroupSize {
+		return w.flush()
+	}
+	return nil
+}
+
+// End implements zmultifield.ExportWriter, writing the buffered rows and the file's footer. It
+// doesn't close the underlying writer.
+func (w *Writer) End() error {
+	if !w.begun || w.ended {
+		return errors.New("zmfparquet: End called without Begin or twice")
+	}
+	w.ended = true
+	if w.rows > 0 {
+		if err := w.flush(); err != nil {
+			return err
+		}
+	}
+
+	footer := w.footer()
+	footer = binary.LittleEndian.AppendUint32(footer, uint32(len(footer)))
+	return w.write(append(footer, magic...))
+}
+
+// flush writes the buffered rows as a row group with one data page per column.
+func (w *Writer) flush() error {
+	group := rowGroup{rows: int64(w.rows)}
+	for i := range w.columns {
+		c := &w.columns[i]
+		var t thriftWriter
+		t.begin()
+		t.i32(1, 0) // DATA_PAGE
+		t.i32(2, int32(len(c.data)))
+		t.i32(3, int32(len(c.data)))
+		t.structField(5)
+		t.i32(1, int32(w.rows))
+		t.i32(2, encodingPlain)
+		t.i32(3, encodingRLE)
+		t.i32(4, encodingRLE)
+		t.end()
+		t.end()
+
+		ch := chunk{offset: w.offset, size: int64(len(t.buf) + len(c.data))}
+		if err := w.write(t.buf); err != nil {
+			return err
+		}
+		if err := w.write(c.data); err != nil {
+			return err
+		}
+		group.chunks = append(group.chunks, ch)
+		group.size += ch.size
+		c.data = c.data[:0]
+	}
+	w.groups = append(w.groups, group)
+	w.total += int64(w.rows)
+	w.rows = 0
+	return nil
+}
+
+// footer returns the file's FileMetaData.
+func (w *Writer) footer() []byte {
+	var t thriftWriter
+	t.begin()
+	t.i32(1, 1) // version
+
+	t.list(2, thriftStruct, len(w.columns)+1)
+	t.begin()
+	t.string(4, "schema")
+	t.i32(5, int32(len(w.columns)))
+	t.end()
+	for _, c := range w.columns {
+		t.begin()
+		t.i32(1, c.typ)
+		t.i32(3, 0) // REQUIRED
+		t.string(4, c.name)
+		if c.utf8 {
+			t.i32(6, convertedUTF8)
+		}
+		t.end()
+	}
+
+	t.i64(3, w.total)
+	t.list(4, thriftStruct, len(w.groups))
+	for _, g := range w.groups {
+		t.begin()
+		t.list(1, thriftStruct, len(g.chunks))
+		for i, ch := range g.chunks {
+			c := w.columns[i]
+			t.begin()
+			t.i64(2, ch.offset)
+			t.structField(3)
+			t.i32(1, c.typ)
+			t.i32s(2, encodingPlain, encodingRLE)
+			t.list(3, thriftBinary, 1)
+			t.str(c.name)
+			t.i32(4, 0) // UNCOMPRESSED
+			t.i64(5, g.rows)
+			t.i64(6, ch.size)
+			t.i64(7, ch.size)
+			t.i64(9, ch.offset)
+			t.end()
+			t.end()
+		}
+		t.i64(2, g.size)
+		t.i64(3, g.rows)
+		t.end()
+	}
+	t.string(6, "zmultifield")
+	t.end()
+	return t.buf
+}
+
+// write writes p to the underlying writer, tracking the file offset.
+func (w *Writer) write(p []byte) error {
+	n, err := w.w.Write(p)
+	w.offset += int64(n)
+	return err
+}
@@ -0,0 +1,211 @@
+package zmfparquet
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/Rohan-Muslekar/ZMultiField"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// thriftReader decodes Thrift compact structs into maps from field ids to values, to check the
+// written metadata.
+type thriftReader struct {
+	buf []byte
+	pos int
+}
+
+func (r *thriftReader) varint() int64 {
+	v, n := binary.Varint(r.buf[r.pos:])
+	r.pos += n
+	return v
+}
+
+func (r *thriftReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.buf[r.pos:])
+	r.pos += n
+	return v
+}
+
+func (r *thriftReader) value(typ byte) any {
+	switch typ {
+	case thriftI32, thriftI64:
+		return r.varint()
+	case thriftBinary:
+		n := int(r.uvarint())
+		r.pos += n
+		return string(r.buf[r.pos-n : r.pos])
+	case thriftList:
+		header := r.buf[r.pos]
+		r.pos++
+		n := int(header >> 4)
+		if n == 15 {
+			n = int(r.uvarint())
+		}
+		list := make([]any, n)
+		for i := range list {
+			list[i] = r.value(header & 0x0f)
+		}
+		return list
+	case thriftStruct:
+		fields := map[int16]any{}
+		var last int16
+		for {
+			header := r.buf[r.pos]
+			r.pos++
+			if header == 0 {
+				return fields
+			}
+			id := last + int16(header>>4)
+			if header>>4 == 0 {
+				id = int16(r.varint())
+			}
+			fields[id] = r.value(header & 0x0f)
+			last = id
+		}
+	}
+	panic("unexpected thrift type")
+}
+
+// parsedFile is a Parquet file read back by readFile.
+type parsedFile struct {
+	rows    int64
+	columns []string
+	values  map[string][]any
+}
+
+// readFile checks the framing of a file written by Writer and decodes its columns.
+func readFile(t *testing.T, data []byte) parsedFile {
+	t.Helper()
+	if string(data[:4]) != magic || string(data[len(data)-4:]) != magic {
+		t.Fatalf("file isn't framed by %s", magic)
+	}
+	size := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	r := &thriftReader{buf: data[len(data)-8-size : len(data)-8]}
+	meta := r.value(thriftStruct).(map[int16]any)
+	if r.pos != size {
+		t.Fatalf("footer has %d bytes, decoded %d", size, r.pos)
+	}
+
+	file := parsedFile{rows: meta[3].(int64), values: map[string][]any{}}
+	schema := meta[2].([]any)
+	types := map[string]int64{}
+	for _, element := range schema[1:] {
+		e := element.(map[int16]any)
+		file.columns = append(file.columns, e[4].(string))
+		types[e[4].(string)] = e[1].(int64)
+	}
+
+	for _, group := range meta[4].([]any) {
+		for _, chunk := range group.(map[int16]any)[1].([]any) {
+			cmeta := chunk.(map[int16]any)[3].(map[int16]any)
+			name := cmeta[3].([]any)[0].(string)
+			page := &thriftReader{buf: data, pos: int(cmeta[9].(int64))}
+			header := page.value(thriftStruct).(map[int16]any)
+			values := data[page.pos : page.pos+int(header[3].(int64))]
+			for i := int64(0); i < header[5].(map[int16]any)[1].(int64); i++ {
+				switch types[name] {
+				case typeByteArray:
+					n := binary.LittleEndian.Uint32(values)
+					file.values[name] = append(file.values[name], string(values[4:4+n]))
+					values = values[4+n:]
+				case typeInt64:
+					file.values[name] = append(file.values[name], int64(binary.LittleEndian.Uint64(values)))
+					values = values[8:]
+				case typeDouble:
+					file.values[name] = append(file.values[name], math.Float64frombits(binary.LittleEndian.Uint64(values)))
+					values = values[8:]
+				}
+			}
+		}
+	}
+	return file
+}
+
+func TestWriter_Export(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	mfs, err := zmultifield.New(zmultifield.MultiFieldSetOptions{
+		Name: "leaderboard",
+		Fields: []zmultifield.Field{
+			{Name: "points", Sort: zmultifield.Descending, MaxValue: 1000, UpdateType: zmultifield.Incremental},
+			{Name: "deaths", Sort: zmultifield.Ascending, MaxValue: 100, UpdateType: zmultifield.Incremental},
+		},
+		Client:         client,
+		ComputedFields: []zmultifield.ComputedField{zmultifield.Ratio("kd", "points", "deaths")},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	ctx := context.Background()
+	for member, scores := range map[string]map[string]float64{
+		"alice": {"points": 30, "deaths": 4},
+		"bob":   {"points": 50, "deaths": 1},
+		"carol": {"points": 10},
+	} {
+		if _, err := mfs.IncreaseScore(ctx, scores, member); err != nil {
+			t.Fatalf("IncreaseScore() error = %v", err)
+		}
+	}
+
+	// Two rows per row group, so the file has two row groups
+	var buf bytes.Buffer
+	n, err := mfs.Export(ctx, NewWriter(&buf, Options{RowGroupSize: 2}))
+	if err != nil || n != 3 {
+		t.Fatalf("Export() = %d, %v, expected 3 members", n, err)
+	}
+
+	file := readFile(t, buf.Bytes())
+	if file.rows != 3 || len(file.columns) != 5 {
+		t.Fatalf("file has %d rows and columns %v, expected 3 rows and 5 columns", file.rows, file.columns)
+	}
+	want := map[string][]any{
+		"member": {"bob", "alice", "carol"},
+		"rank":   {int64(0), int64(1), int64(2)},
+		"points": {int64(50), int64(30), int64(10)},
+		"deaths": {int64(1), int64(4), int64(0)},
+		"kd":     {50.0, 7.5, 10.0},
+	}
+	for name, values := range want {
+		got := file.values[name]
+		if len(got) != len(values) {
+			t.Errorf("column %s = %v, expected %v", name, got, values)
+			continue
+		}
+		for i := range values {
+			if got[i] != values[i] {
+				t.Errorf("column %s = %v, expected %v", name, got, values)
+				break
+			}
+		}
+	}
+}
+
+func TestWriter_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, Options{})
+	if err := w.Begin([]zmultifield.ExportColumn{{Name: "points"}}); err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	if err := w.End(); err != nil {
+		t.Fatalf("End() error = %v", err)
+	}
+	if file := readFile(t, buf.Bytes()); file.rows != 0 || len(file.columns) != 3 {
+		t.Errorf("file has %d rows and columns %v, expected no rows and 3 columns", file.rows, file.columns)
+	}
+}
+
+func TestWriter_Validation(t *testing.T) {
+	w := NewWriter(&bytes.Buffer{}, Options{})
+	if err := w.Begin([]zmultifield.ExportColumn{{Name: "rank"}}); err == nil {
+		t.Errorf("Begin() with a rank field succeeded, expected an error")
+	}
+	if err := w.Write(zmultifield.ExportRow{Member: "alice"}); err == nil {
+		t.Errorf("Write() with missing scores succeeded, expected an error")
+	}
+}
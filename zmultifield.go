@@ -6,6 +6,7 @@ package zmultifield
 import (
 	"math/big"
 	"math/bits"
+	"time"
 )
 
 // SortOrder defines the sorting order for a field.
@@ -26,6 +27,19 @@ const (
 	Incremental UpdateType = 1
 	// Replace indicates a field's value should be replaced with the given value.
 	Replace UpdateType = 2
+	// KeepMax indicates a field should keep the larger of its value and the given value, e.g. a
+	// best score.
+	KeepMax UpdateType = 3
+	// KeepMin indicates a field should keep the smaller of its value and the given value, e.g. a
+	// best lap time. New members start at the field's maximum so their first update sets it.
+	KeepMin UpdateType = 4
+	// BitOr indicates the given value should be ORed into the field, e.g. a bitmask of unlocked
+	// achievements. BitOr fields must be ascending and have no MinValue.
+	BitOr UpdateType = 5
+	// Average indicates the field holds the running mean of the given values, e.g. an average
+	// match score. A hidden field counts the values, see Field.MaxSamples. Only MultiFieldSet
+	// supports it, and only through updates that read the member first, such as IncreaseScore.
+	Average UpdateType = 6
 )
 
 // Field defines the properties for a single field within a multi-field sorted set.
@@ -34,6 +48,23 @@ type Field struct {
 	Sort       SortOrder
 	MaxValue   float64
 	UpdateType UpdateType
+	// MinValue is the smallest value of the field, e.g. 1000 for ratings in [1000, 5000]. Values
+	// are stored relative to it, so the field only takes the bits needed for MaxValue - MinValue.
+	// It may be negative. New members start at MinValue, and decay halves the distance to it.
+	// WeightedSet weighs the value above it.
+	MinValue float64
+	// Priority places the field explicitly, 1 being the most significant, so the layout doesn't
+	// depend on the order of the Fields slice. Fields without a Priority are packed below those
+	// with one, in slice order. Priorities must be distinct.
+	Priority int
+	// MaxSamples sizes the hidden count of an Average field, 65535 by default. Once that many
+	// values were averaged the count stops growing, and the mean becomes a moving average.
+	MaxSamples float64
+	// HalfLife marks the field as decaying: ApplyDecay halves its value every HalfLife.
+	HalfLife time.Duration
+	// Weight scales the field in the composite score of a WeightedSet, 1 if unset. It is ignored
+	// by MultiFieldSet.
+	Weight float64
 }
 
 // FieldInfo provides detailed information about a field's properties and bit allocation.
@@ -42,6 +73,7 @@ type FieldInfo struct {
 	Sort         SortOrder
 	UpdateType   string
 	MaxValue     float64
+	MinValue     float64
 	Bits         uint64
 	ShiftValue   uint64
 	Mask         *big.Int
@@ -51,16 +83,40 @@ type FieldInfo struct {
 	MaxAbsolute  *big.Int
 }
 
-// fieldScore represents a field's name and its score.
-type fieldScore struct {
+// FieldScore represents a field's name and its score.
+type FieldScore struct {
 	Name  string
 	Score *big.Int
+	// Value is the value of a ComputedField, whose Score is nil.
+	Value float64
+}
+
+// Int64 returns the score as an int64, truncating the value of a computed field. The result is
+// undefined if it doesn't fit.
+func (fs FieldScore) Int64() int64 {
+	if fs.Score == nil {
+		return int64(fs.Value)
+	}
+	return fs.Score.Int64()
+}
+
+// Float64 returns the score as the nearest float64.
+func (fs FieldScore) Float64() float64 {
+	if fs.Score == nil {
+		return fs.Value
+	}
+	f, _ := new(big.Float).SetInt(fs.Score).Float64()
+	return f
 }
 
 // MemberScores represents a member and its scores for all fields.
 type MemberScores struct {
 	Member string
-	Scores []fieldScore
+	Scores []FieldScore
+	// Rank is the member's 0-based rank, set by reads with WithRanks.
+	Rank int64
+	// Meta is the member's metadata, set by reads with WithMeta.
+	Meta map[string]string
 }
 
 // BitCount returns the number of bits required to represent a value.
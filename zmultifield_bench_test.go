@@ -1,8 +1,11 @@
 package zmultifield
 
 import (
+	"fmt"
 	"math/big"
 	"testing"
+
+	"github.com/go-redis/redis/v8"
 )
 
 // Benchmark scoresToZScore to see how fast we can combine multiple fields into one score
@@ -161,3 +164,86 @@ func BenchmarkZScoreToAllFieldScores(b *testing.B) {
 		mfs.zscoreToAllFieldScores(zscore)
 	}
 }
+
+// Benchmark decodeMembers to see how many allocations a bulk read of 10k members costs
+func BenchmarkDecodeMembers(b *testing.B) {
+	mfs := newBenchmarkDecodeSet(b)
+	results := newBenchmarkDecodeResults(mfs, 10000)
+
+	// Reset timer for fair benchmarking
+	b.ResetTimer()
+
+	// Run the benchmark
+	for i := 0; i < b.N; i++ {
+		mfs.decodeMembers(results)
+	}
+}
+
+// Benchmark decodeMembersInto reusing the previous result to see the cost of repeated bulk reads
+func BenchmarkDecodeMembersInto(b *testing.B) {
+	mfs := newBenchmarkDecodeSet(b)
+	results := newBenchmarkDecodeResults(mfs, 10000)
+	members, _ := mfs.decodeMembers(results)
+
+	// Reset timer for fair benchmarking
+	b.ResetTimer()
+
+	// Run the benchmark
+	for i := 0; i < b.N; i++ {
+		members, _ = mfs.decodeMembersInto(members, results)
+	}
+}
+
+// newBenchmarkDecodeSet creates the three field set used by the decode benchmarks
+func newBenchmarkDecodeSet(b *testing.B) *MultiFieldSet {
+	// Create test fields
+	fields := []Field{
+		{
+			Name:       "field1",
+			Sort:       Descending,
+			MaxValue:   100000,
+			UpdateType: Incremental,
+		},
+		{
+			Name:       "field2",
+			Sort:       Ascending,
+			MaxValue:   50000,
+			UpdateType: Incremental,
+		},
+		{
+			Name:       "field3",
+			Sort:       Descending,
+			MaxValue:   1000,
+			UpdateType: Incremental,
+		},
+	}
+
+	// Create a MultiFieldSet with mock Redis client
+	mfs, err := New(MultiFieldSetOptions{
+		Name:   "benchmark",
+		Fields: fields,
+		Client: newMockRedisClient(),
+	})
+
+	if err != nil {
+		b.Fatalf("Failed to create MultiFieldSet: %v", err)
+	}
+	return mfs
+}
+
+// newBenchmarkDecodeResults creates n sorted set entries as returned by Redis
+func newBenchmarkDecodeResults(mfs *MultiFieldSet, n int) []redis.Z {
+	results := make([]redis.Z, n)
+	for i := range results {
+		scores := []*big.Int{
+			big.NewInt(int64(i % 100000)), // field1
+			big.NewInt(int64(i % 50000)),  // field2
+			big.NewInt(int64(i % 1000)),   // field3
+		}
+		results[i] = redis.Z{
+			Score:  float64(mfs.scoresToZScore(scores).Int64()),
+			Member: fmt.Sprintf("member%d", i),
+		}
+	}
+	return results
+}